@@ -0,0 +1,36 @@
+// Package bmp implements a decoder and an encoder for Windows BMP images.
+//
+// Uncompressed BMP images with a BITMAPINFOHEADER (or one of its larger
+// successors, BITMAPV4HEADER and BITMAPV5HEADER) and 24 or 32 bits per pixel
+// are decoded into *image.NRGBA images, both in bottom-up and top-down row order.
+// Indexed bitmaps with 1, 4 or 8 bits per pixel, uncompressed or RLE8
+// compressed, are decoded into *image.Paletted images.
+package bmp
+
+import (
+	"image"
+)
+
+const (
+	// bmpMaxPixels guards against allocating huge images for corrupt headers.
+	bmpMaxPixels = 400_000_000
+	bmpMagic     = "BM"
+
+	fileHeaderSize = 14 //size in bytes
+
+	infoHeaderSize   = 40 //BITMAPINFOHEADER
+	v2InfoHeaderSize = 52 //BITMAPV2INFOHEADER, adds the RGB masks
+	v3InfoHeaderSize = 56 //BITMAPV3INFOHEADER, adds the alpha mask
+	v4InfoHeaderSize = 108
+	v5InfoHeaderSize = 124
+)
+
+const (
+	compressionRGB       = 0
+	compressionRLE8      = 1
+	compressionBitfields = 3
+)
+
+func init() {
+	image.RegisterFormat("bmp", bmpMagic, Decode, DecodeConfig)
+}
@@ -0,0 +1,385 @@
+package bmp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+type bmpHeader struct {
+	offset      int
+	infoSize    int
+	width       int
+	height      int
+	topDown     bool
+	bpp         int
+	compression uint32
+	masks       [4]uint32 //red, green, blue and alpha
+	palette     color.Palette
+}
+
+type decoder struct {
+	m   image.Image
+	buf *bufio.Reader
+	h   bmpHeader
+	// read is the number of bytes consumed from the start of the file.
+	read int
+	err  error
+}
+
+// readFull reads exactly len(p) bytes, counting them in d.read.
+func (d *decoder) readFull(p []byte) error {
+	n, err := io.ReadFull(d.buf, p)
+	d.read += n
+
+	return err
+}
+
+func (d *decoder) decodeHeader() {
+	h := make([]byte, fileHeaderSize+4)
+	if err := d.readFull(h); err != nil || string(h[:2]) != bmpMagic {
+		d.err = fmt.Errorf("image not valid bmp file")
+		return
+	}
+
+	d.h.offset = int(binary.LittleEndian.Uint32(h[10:14]))
+	d.h.infoSize = int(binary.LittleEndian.Uint32(h[14:18]))
+
+	switch d.h.infoSize {
+	case infoHeaderSize, v2InfoHeaderSize, v3InfoHeaderSize, v4InfoHeaderSize, v5InfoHeaderSize:
+	default:
+		d.err = fmt.Errorf("unsupported bmp info header size %d", d.h.infoSize)
+		return
+	}
+
+	info := make([]byte, d.h.infoSize)
+	copy(info, h[14:])
+	if err := d.readFull(info[4:]); err != nil {
+		d.err = fmt.Errorf("invalid bmp header: %w", io.ErrUnexpectedEOF)
+		return
+	}
+
+	width := int64(int32(binary.LittleEndian.Uint32(info[4:8])))
+	height := int64(int32(binary.LittleEndian.Uint32(info[8:12])))
+	planes := binary.LittleEndian.Uint16(info[12:14])
+	d.h.bpp = int(binary.LittleEndian.Uint16(info[14:16]))
+	d.h.compression = binary.LittleEndian.Uint32(info[16:20])
+
+	//a negative height marks a top-down bitmap
+	if height < 0 {
+		height, d.h.topDown = -height, true
+	}
+
+	if width <= 0 || height <= 0 || width > bmpMaxPixels/height {
+		d.err = fmt.Errorf("invalid bmp image size: %dx%d, must be non-empty and at most %d pixels", width, height, bmpMaxPixels)
+		return
+	}
+	d.h.width, d.h.height = int(width), int(height)
+
+	if planes != 1 {
+		d.err = fmt.Errorf("invalid bmp header: %d planes, must be 1", planes)
+		return
+	}
+
+	switch {
+	case d.h.compression == compressionRGB && (d.h.bpp == 24 || d.h.bpp == 32):
+	case d.h.compression == compressionBitfields && d.h.bpp == 32:
+		d.decodeMasks(info)
+	case d.h.compression == compressionRGB && (d.h.bpp == 1 || d.h.bpp == 4 || d.h.bpp == 8):
+		d.decodePalette(info)
+	case d.h.compression == compressionRLE8 && d.h.bpp == 8:
+		if d.h.topDown {
+			d.err = fmt.Errorf("invalid bmp header: RLE8 bitmaps cannot be top-down")
+			return
+		}
+		d.decodePalette(info)
+	default:
+		d.err = fmt.Errorf("unsupported bmp format: %d bits per pixel with compression %d", d.h.bpp, d.h.compression)
+		return
+	}
+	if d.err != nil {
+		return
+	}
+
+	if d.h.offset < d.read {
+		d.err = fmt.Errorf("invalid bmp header: pixel offset %d inside the header", d.h.offset)
+		return
+	}
+}
+
+// decodeMasks reads the color masks of a BI_BITFIELDS bitmap, which follow a
+// BITMAPINFOHEADER or are part of the larger info headers. Only the byte
+// aligned BGRA layout is supported.
+func (d *decoder) decodeMasks(info []byte) {
+	if d.h.infoSize == infoHeaderSize {
+		masks := make([]byte, 12)
+		if err := d.readFull(masks); err != nil {
+			d.err = fmt.Errorf("invalid bmp header: %w", io.ErrUnexpectedEOF)
+			return
+		}
+		info = append(info, masks...)
+	}
+
+	for i := range d.h.masks {
+		if o := infoHeaderSize + 4*i; o+4 <= len(info) {
+			d.h.masks[i] = binary.LittleEndian.Uint32(info[o : o+4])
+		}
+	}
+
+	m := d.h.masks
+	if m[0] != 0x00ff0000 || m[1] != 0x0000ff00 || m[2] != 0x000000ff || (m[3] != 0 && m[3] != 0xff000000) {
+		d.err = fmt.Errorf("unsupported bmp bitfield masks %08x", m)
+		return
+	}
+}
+
+// decodePalette reads the color table following the info header. It holds
+// the number of colors given in the header, or 2^bpp colors if that is zero.
+func (d *decoder) decodePalette(info []byte) {
+	n := int(binary.LittleEndian.Uint32(info[32:36]))
+	if n == 0 {
+		n = 1 << d.h.bpp
+	}
+	if n > 1<<d.h.bpp {
+		d.err = fmt.Errorf("invalid bmp header: %d colors for %d bits per pixel", n, d.h.bpp)
+		return
+	}
+
+	table := make([]byte, 4*n)
+	if err := d.readFull(table); err != nil {
+		d.err = fmt.Errorf("invalid bmp color table: %w", io.ErrUnexpectedEOF)
+		return
+	}
+
+	d.h.palette = make(color.Palette, n)
+	for i := range d.h.palette {
+		c := table[4*i : 4*i+4 : 4*i+4]
+		d.h.palette[i] = color.RGBA{c[2], c[1], c[0], 0xff}
+	}
+}
+
+func (d *decoder) decode() {
+	if d.err != nil {
+		return
+	}
+
+	//skip everything between the headers and the pixels
+	if _, err := d.buf.Discard(d.h.offset - d.read); err != nil {
+		d.err = fmt.Errorf("invalid bmp header: pixel offset %d beyond the end of the file", d.h.offset)
+		return
+	}
+	d.read = d.h.offset
+
+	switch {
+	case d.h.compression == compressionRLE8:
+		d.decodeRLE8()
+	case d.h.bpp <= 8:
+		d.decodePaletted()
+	default:
+		d.decodeTrueColor()
+	}
+}
+
+// row returns the image row y of the file row r.
+func (d *decoder) row(r int) int {
+	if d.h.topDown {
+		return r
+	}
+
+	return d.h.height - 1 - r
+}
+
+// decodePaletted decodes 1, 4 and 8 bit indices, packed most significant bits first.
+func (d *decoder) decodePaletted() {
+	m := image.NewPaletted(image.Rect(0, 0, d.h.width, d.h.height), d.h.palette)
+	row := make([]byte, ((d.h.bpp*d.h.width+31)/32)*4)
+	mask := byte(1<<d.h.bpp - 1)
+
+	for r := 0; r < d.h.height; r++ {
+		if err := d.readFull(row); err != nil {
+			d.err = fmt.Errorf("truncated bmp pixel array at row %d: %w", r, io.ErrUnexpectedEOF)
+			return
+		}
+
+		p := m.Pix[m.PixOffset(0, d.row(r)):m.PixOffset(d.h.width, d.row(r))]
+		for x := range p {
+			bit := x * d.h.bpp
+			p[x] = row[bit/8] >> (8 - d.h.bpp - bit%8) & mask
+		}
+		if !d.validIndices(p) {
+			return
+		}
+	}
+
+	d.m = m
+}
+
+// validIndices reports whether all indices are inside the palette.
+func (d *decoder) validIndices(indices []byte) bool {
+	for _, i := range indices {
+		if int(i) >= len(d.h.palette) {
+			d.err = fmt.Errorf("invalid bmp pixel: index %d exceeds the %d colors of the palette", i, len(d.h.palette))
+			return false
+		}
+	}
+
+	return true
+}
+
+// decodeRLE8 decodes run-length encoded 8 bit indices. Pixels skipped by delta
+// escapes or an early end of the bitmap keep the index 0.
+func (d *decoder) decodeRLE8() {
+	m := image.NewPaletted(image.Rect(0, 0, d.h.width, d.h.height), d.h.palette)
+	x, y := 0, d.h.height-1
+	code := make([]byte, 2)
+	pixels := make([]byte, 256)
+
+	for {
+		if err := d.readFull(code); err != nil {
+			if err == io.EOF && y < 0 {
+				//all rows are written, only the end of the bitmap is missing
+				break
+			}
+			d.err = fmt.Errorf("truncated bmp RLE8 data: %w", io.ErrUnexpectedEOF)
+			return
+		}
+
+		n := int(code[0])
+		switch {
+		case n > 0:
+			//encoded mode: n times the index code[1]
+			pixels = pixels[:n]
+			for i := range pixels {
+				pixels[i] = code[1]
+			}
+
+		case code[1] == 0:
+			//end of line
+			x, y = 0, y-1
+			continue
+
+		case code[1] == 1:
+			//end of bitmap
+			d.m = m
+			return
+
+		case code[1] == 2:
+			//delta: move right and up by the next two bytes
+			if err := d.readFull(code); err != nil {
+				d.err = fmt.Errorf("truncated bmp RLE8 data: %w", io.ErrUnexpectedEOF)
+				return
+			}
+			x, y = x+int(code[0]), y-int(code[1])
+			if x > d.h.width || y < 0 {
+				d.err = fmt.Errorf("invalid bmp RLE8 data: delta moves outside the image")
+				return
+			}
+			continue
+
+		default:
+			//absolute mode: code[1] literal indices, padded to an even length
+			n = int(code[1])
+			pixels = pixels[:n+n%2]
+			if err := d.readFull(pixels); err != nil {
+				d.err = fmt.Errorf("truncated bmp RLE8 data: %w", io.ErrUnexpectedEOF)
+				return
+			}
+			pixels = pixels[:n]
+		}
+
+		if y < 0 || x+n > d.h.width {
+			d.err = fmt.Errorf("invalid bmp RLE8 data: run of %d pixels at x=%d outside the image", n, x)
+			return
+		}
+		if !d.validIndices(pixels) {
+			return
+		}
+
+		i := m.PixOffset(x, y)
+		copy(m.Pix[i:i+n], pixels)
+		x += n
+	}
+
+	d.m = m
+}
+
+func (d *decoder) decodeTrueColor() {
+	m := image.NewNRGBA(image.Rect(0, 0, d.h.width, d.h.height))
+	stride := ((d.h.bpp*d.h.width + 31) / 32) * 4
+	row := make([]byte, stride)
+	size := d.h.bpp / 8
+
+	//32 bit BI_RGB pixels are either BGRX or BGRA, all zero alpha values mark BGRX
+	alpha := d.h.bpp == 32 && (d.h.compression == compressionRGB || d.h.masks[3] != 0)
+	zeroAlpha := true
+
+	for r := 0; r < d.h.height; r++ {
+		if err := d.readFull(row); err != nil {
+			d.err = fmt.Errorf("truncated bmp pixel array at row %d: %w", r, io.ErrUnexpectedEOF)
+			return
+		}
+
+		di := m.PixOffset(0, d.row(r))
+		for si := 0; si < size*d.h.width; si, di = si+size, di+4 {
+			s := row[si : si+size : si+size]
+			p := m.Pix[di : di+4 : di+4]
+			p[0], p[1], p[2], p[3] = s[2], s[1], s[0], 0xff
+			if alpha {
+				p[3] = s[3]
+				zeroAlpha = zeroAlpha && s[3] == 0
+			}
+		}
+	}
+
+	if alpha && zeroAlpha && d.h.compression == compressionRGB {
+		for i := 3; i < len(m.Pix); i += 4 {
+			m.Pix[i] = 0xff
+		}
+	}
+
+	d.m = m
+}
+
+// DecodeConfig returns the color model and dimensions of a BMP image without
+// decoding the entire image.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	d := decoder{
+		buf: bufio.NewReader(r),
+	}
+
+	d.decodeHeader()
+	if d.err != nil {
+		return image.Config{}, d.err
+	}
+
+	var model color.Model = color.NRGBAModel
+	if d.h.palette != nil {
+		model = d.h.palette
+	}
+
+	return image.Config{
+		ColorModel: model,
+		Width:      d.h.width,
+		Height:     d.h.height,
+	}, nil
+}
+
+// Decode reads a BMP image from r and returns it as an *image.Paletted image
+// for bitmaps with a color table, and as an *image.NRGBA image otherwise.
+func Decode(r io.Reader) (image.Image, error) {
+	d := decoder{
+		buf: bufio.NewReader(r),
+	}
+
+	d.decodeHeader()
+	d.decode()
+
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	return d.m, nil
+}
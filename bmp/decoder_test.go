@@ -0,0 +1,243 @@
+package bmp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/LukiDS/image/imgtest"
+)
+
+// bmpFile describes a BMP file for generateBMP. Zero values are replaced by
+// the values of a valid file.
+type bmpFile struct {
+	magic       string
+	offset      int
+	infoSize    int
+	width       int32
+	height      int32
+	planes      uint16
+	bpp         uint16
+	compression uint32
+	masks       []uint32
+	colors      uint32
+	palette     []byte
+	pixels      []byte
+}
+
+// generateBMP writes the headers of f followed by its masks, palette and pixels.
+func generateBMP(t testing.TB, f bmpFile) []byte {
+	t.Helper()
+
+	if f.magic == "" {
+		f.magic = bmpMagic
+	}
+	if f.infoSize == 0 {
+		f.infoSize = infoHeaderSize
+	}
+	if f.planes == 0 {
+		f.planes = 1
+	}
+
+	//OS/2 headers are shorter than the fields written below, so truncate afterwards
+	info := make([]byte, f.infoSize+infoHeaderSize)
+	binary.LittleEndian.PutUint32(info[0:], uint32(f.infoSize))
+	binary.LittleEndian.PutUint32(info[4:], uint32(f.width))
+	binary.LittleEndian.PutUint32(info[8:], uint32(f.height))
+	binary.LittleEndian.PutUint16(info[12:], f.planes)
+	binary.LittleEndian.PutUint16(info[14:], f.bpp)
+	binary.LittleEndian.PutUint32(info[16:], f.compression)
+	binary.LittleEndian.PutUint32(info[20:], uint32(len(f.pixels)))
+	binary.LittleEndian.PutUint32(info[32:], f.colors)
+
+	var masks []byte
+	for i, m := range f.masks {
+		if o := infoHeaderSize + 4*i; o+4 <= f.infoSize {
+			binary.LittleEndian.PutUint32(info[o:], m)
+		} else {
+			masks = append(masks, byte(m), byte(m>>8), byte(m>>16), byte(m>>24))
+		}
+	}
+
+	info = info[:f.infoSize]
+
+	if f.offset == 0 {
+		f.offset = fileHeaderSize + len(info) + len(masks) + len(f.palette)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(f.magic)
+	binary.Write(&buf, binary.LittleEndian, uint32(f.offset+len(f.pixels)))
+	binary.Write(&buf, binary.LittleEndian, uint32(0))
+	binary.Write(&buf, binary.LittleEndian, uint32(f.offset))
+	buf.Write(info)
+	buf.Write(masks)
+	buf.Write(f.palette)
+	for buf.Len() < f.offset {
+		buf.WriteByte(0)
+	}
+	buf.Write(f.pixels)
+
+	return buf.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+	green := color.NRGBA{0, 255, 0, 255}
+	blue := color.NRGBA{0, 0, 255, 255}
+	gray := color.NRGBA{1, 2, 3, 255}
+
+	tests := []struct {
+		name     string
+		file     bmpFile
+		expected []color.NRGBA
+		size     image.Point
+	}{
+		{
+			name: "should decode a bottom-up 24 bit image with row padding",
+			file: bmpFile{width: 3, height: 2, bpp: 24, pixels: []byte{
+				/* bottom row */ 0x03, 0x02, 0x01, 0x00, 0x00, 0xff, 0x00, 0xff, 0x00 /* padding */, 0, 0, 0,
+				/* top row    */ 0xff, 0x00, 0x00, 0x00, 0x00, 0xff, 0x03, 0x02, 0x01 /* padding */, 0, 0, 0,
+			}},
+			expected: []color.NRGBA{blue, red, gray, gray, red, green},
+			size:     image.Pt(3, 2),
+		},
+		{
+			name: "should decode a top-down 24 bit image",
+			file: bmpFile{width: 1, height: -2, bpp: 24, pixels: []byte{
+				0x00, 0x00, 0xff /* padding */, 0,
+				0x00, 0xff, 0x00 /* padding */, 0,
+			}},
+			expected: []color.NRGBA{red, green},
+			size:     image.Pt(1, 2),
+		},
+		{
+			name: "should decode 32 bit BGRX as opaque",
+			file: bmpFile{width: 2, height: 1, bpp: 32, pixels: []byte{
+				0x00, 0x00, 0xff, 0x00, 0x03, 0x02, 0x01, 0x00,
+			}},
+			expected: []color.NRGBA{red, gray},
+			size:     image.Pt(2, 1),
+		},
+		{
+			name: "should decode 32 bit BGRA with alpha",
+			file: bmpFile{width: 2, height: 1, bpp: 32, pixels: []byte{
+				0x00, 0x00, 0xff, 0x80, 0x03, 0x02, 0x01, 0x00,
+			}},
+			expected: []color.NRGBA{{255, 0, 0, 128}, {1, 2, 3, 0}},
+			size:     image.Pt(2, 1),
+		},
+		{
+			name: "should decode BI_BITFIELDS with an alpha mask in a BITMAPV5HEADER",
+			file: bmpFile{infoSize: v5InfoHeaderSize, width: 2, height: -1, bpp: 32, compression: compressionBitfields,
+				masks: []uint32{0x00ff0000, 0x0000ff00, 0x000000ff, 0xff000000},
+				pixels: []byte{
+					0x00, 0x00, 0xff, 0x00, 0x03, 0x02, 0x01, 0x40,
+				}},
+			expected: []color.NRGBA{{255, 0, 0, 0}, {1, 2, 3, 0x40}},
+			size:     image.Pt(2, 1),
+		},
+		{
+			name: "should decode BI_BITFIELDS without an alpha mask as opaque",
+			file: bmpFile{width: 1, height: 1, bpp: 32, compression: compressionBitfields,
+				masks:  []uint32{0x00ff0000, 0x0000ff00, 0x000000ff},
+				pixels: []byte{0x03, 0x02, 0x01, 0x80},
+			},
+			expected: []color.NRGBA{gray},
+			size:     image.Pt(1, 1),
+		},
+		{
+			name: "should skip the gap between the headers and the pixels",
+			file: bmpFile{offset: 200, width: 1, height: 1, bpp: 24, pixels: []byte{
+				0x03, 0x02, 0x01, 0x00,
+			}},
+			expected: []color.NRGBA{gray},
+			size:     image.Pt(1, 1),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := generateBMP(t, test.file)
+			actual, err := Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("Decode: unexpected error: %v\n", err)
+			}
+
+			expected := image.NewNRGBA(image.Rectangle{Max: test.size})
+			for i, c := range test.expected {
+				expected.SetNRGBA(i%test.size.X, i/test.size.X, c)
+			}
+			imgtest.AssertEqual(t, expected, actual)
+			imgtest.AssertColorModel(t, expected.ColorModel(), actual)
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	pixels := []byte{0, 0, 0, 0}
+
+	tests := []struct {
+		name  string
+		data  []byte
+		error string
+	}{
+		{name: "empty file", data: nil, error: "not valid bmp"},
+		{name: "wrong magic", data: generateBMP(t, bmpFile{magic: "MB", width: 1, height: 1, bpp: 24, pixels: pixels}), error: "not valid bmp"},
+		{name: "OS/2 info header", data: generateBMP(t, bmpFile{infoSize: 12, width: 1, height: 1, bpp: 24, pixels: pixels}), error: "info header size 12"},
+		{name: "truncated info header", data: generateBMP(t, bmpFile{width: 1, height: 1, bpp: 24})[:30], error: "unexpected EOF"},
+		{name: "zero width", data: generateBMP(t, bmpFile{width: 0, height: 1, bpp: 24, pixels: pixels}), error: "image size"},
+		{name: "negative width", data: generateBMP(t, bmpFile{width: -1, height: 1, bpp: 24, pixels: pixels}), error: "image size"},
+		{name: "zero height", data: generateBMP(t, bmpFile{width: 1, height: 0, bpp: 24, pixels: pixels}), error: "image size"},
+		{name: "absurd size", data: generateBMP(t, bmpFile{width: 1 << 30, height: -(1 << 30), bpp: 24, pixels: pixels}), error: "image size"},
+		{name: "minimal int32 height", data: generateBMP(t, bmpFile{width: 1, height: -1 << 31, bpp: 24, pixels: pixels}), error: "image size"},
+		{name: "two planes", data: generateBMP(t, bmpFile{planes: 2, width: 1, height: 1, bpp: 24, pixels: pixels}), error: "planes"},
+		{name: "16 bits per pixel", data: generateBMP(t, bmpFile{width: 1, height: 1, bpp: 16, pixels: pixels}), error: "unsupported bmp format"},
+		{name: "unknown compression", data: generateBMP(t, bmpFile{width: 1, height: 1, bpp: 24, compression: 9, pixels: pixels}), error: "unsupported bmp format"},
+		{name: "unsupported masks", data: generateBMP(t, bmpFile{width: 1, height: 1, bpp: 32, compression: compressionBitfields, masks: []uint32{0xff, 0xff00, 0xff0000}, pixels: pixels}), error: "bitfield masks"},
+		{name: "offset inside the header", data: generateBMP(t, bmpFile{offset: 20, width: 1, height: 1, bpp: 24, pixels: pixels}), error: "pixel offset 20"},
+		{name: "offset beyond the file", data: generateBMP(t, bmpFile{offset: 1 << 20, width: 1, height: 1, bpp: 24})[:60], error: "beyond the end"},
+		{name: "truncated pixels", data: generateBMP(t, bmpFile{width: 2, height: 2, bpp: 24, pixels: make([]byte, 15)}), error: "truncated"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Decode(bytes.NewReader(test.data))
+			if err == nil || !strings.Contains(err.Error(), test.error) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Decode(%s)\n", test.name) +
+					fmt.Sprintf("Expected error:\t containing %q\n", test.error) +
+					fmt.Sprintf("Actual error:\t %v\n", err)
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+func TestDecodeConfig(t *testing.T) {
+	data := generateBMP(t, bmpFile{width: 640, height: -480, bpp: 32})
+
+	actual, err := DecodeConfig(bytes.NewReader(data))
+	expected := image.Config{ColorModel: color.NRGBAModel, Width: 640, Height: 480}
+	if err != nil || actual != expected {
+		t.Errorf("DecodeConfig() = (%+v, %v), expected %+v\n", actual, err, expected)
+	}
+
+	imgtest.RequireFormat(t, bytes.NewReader(generateBMP(t, bmpFile{width: 1, height: 1, bpp: 24, pixels: []byte{1, 2, 3, 0}})), "bmp", image.Rect(0, 0, 1, 1))
+}
+
+func BenchmarkDecode(b *testing.B) {
+	data := generateBMP(b, bmpFile{width: 1024, height: 768, bpp: 24, pixels: make([]byte, 3*1024*768)})
+
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Decode(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package bmp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/LukiDS/image/imgconv"
+)
+
+// Options are the encoding parameters.
+type Options struct {
+	// Alpha writes images with transparent pixels as 32 bit BGRA with a
+	// BITMAPV5HEADER, which keeps the alpha channel. Opaque images, and all
+	// images if Alpha is false, are written as 24 bit BGR with a
+	// BITMAPINFOHEADER, which every BMP reader supports.
+	Alpha bool
+}
+
+// pixelsPerMeter is the resolution written to the info header, 72 DPI.
+const pixelsPerMeter = 2835
+
+// lcsSRGB is the LCS_sRGB color space tag of a BITMAPV5HEADER.
+const lcsSRGB = 0x73524742
+
+// lcsGMImages is the LCS_GM_IMAGES rendering intent of a BITMAPV5HEADER.
+const lcsGMImages = 4
+
+type encoder struct {
+	w        *bufio.Writer
+	m        *image.NRGBA
+	bpp      int
+	infoSize int
+	err      error
+}
+
+// Encode writes the image m to w in BMP format. The rows are written
+// bottom-up. Transparent pixels are only kept if opts.Alpha is set;
+// otherwise their color is written as is and their alpha value is dropped.
+func Encode(w io.Writer, m image.Image, opts Options) error {
+	width := m.Bounds().Dx()
+	height := m.Bounds().Dy()
+	if width <= 0 || height <= 0 || width > bmpMaxPixels/height {
+		return fmt.Errorf("invalid image size: %dx%d, must be non-empty and at most %d pixels", width, height, bmpMaxPixels)
+	}
+
+	e := encoder{
+		w:        bufio.NewWriter(w),
+		m:        imgconv.ToNRGBA(m),
+		bpp:      24,
+		infoSize: infoHeaderSize,
+	}
+
+	if opts.Alpha && !e.m.Opaque() {
+		e.bpp, e.infoSize = 32, v5InfoHeaderSize
+	}
+
+	e.encodeHeader()
+	e.encode()
+
+	if e.err != nil {
+		return e.err
+	}
+
+	return e.w.Flush()
+}
+
+func (e *encoder) stride() int {
+	return ((e.bpp*e.m.Rect.Dx() + 31) / 32) * 4
+}
+
+func (e *encoder) encodeHeader() {
+	offset := fileHeaderSize + e.infoSize
+	imageSize := e.stride() * e.m.Rect.Dy()
+	if int64(imageSize) > 0xffffffff-int64(offset) {
+		e.err = fmt.Errorf("invalid image size: %dx%d, the file must be smaller than 4 GiB", e.m.Rect.Dx(), e.m.Rect.Dy())
+		return
+	}
+
+	h := make([]byte, offset)
+	copy(h, bmpMagic)
+	binary.LittleEndian.PutUint32(h[2:], uint32(offset+imageSize))
+	binary.LittleEndian.PutUint32(h[10:], uint32(offset))
+
+	info := h[fileHeaderSize:]
+	binary.LittleEndian.PutUint32(info[0:], uint32(e.infoSize))
+	binary.LittleEndian.PutUint32(info[4:], uint32(e.m.Rect.Dx()))
+	binary.LittleEndian.PutUint32(info[8:], uint32(e.m.Rect.Dy()))
+	binary.LittleEndian.PutUint16(info[12:], 1)
+	binary.LittleEndian.PutUint16(info[14:], uint16(e.bpp))
+	binary.LittleEndian.PutUint32(info[16:], compressionRGB)
+	binary.LittleEndian.PutUint32(info[20:], uint32(imageSize))
+	binary.LittleEndian.PutUint32(info[24:], pixelsPerMeter)
+	binary.LittleEndian.PutUint32(info[28:], pixelsPerMeter)
+
+	if e.infoSize == v5InfoHeaderSize {
+		binary.LittleEndian.PutUint32(info[16:], compressionBitfields)
+		binary.LittleEndian.PutUint32(info[40:], 0x00ff0000)
+		binary.LittleEndian.PutUint32(info[44:], 0x0000ff00)
+		binary.LittleEndian.PutUint32(info[48:], 0x000000ff)
+		binary.LittleEndian.PutUint32(info[52:], 0xff000000)
+		binary.LittleEndian.PutUint32(info[56:], lcsSRGB)
+		binary.LittleEndian.PutUint32(info[108:], lcsGMImages)
+	}
+
+	_, e.err = e.w.Write(h)
+}
+
+func (e *encoder) encode() {
+	if e.err != nil {
+		return
+	}
+
+	b := e.m.Bounds()
+	row := make([]byte, e.stride())
+	size := e.bpp / 8
+
+	for y := b.Max.Y - 1; y >= b.Min.Y; y-- {
+		si := e.m.PixOffset(b.Min.X, y)
+		for di := 0; di < size*b.Dx(); si, di = si+4, di+size {
+			s := e.m.Pix[si : si+4 : si+4]
+			d := row[di : di+size : di+size]
+			d[0], d[1], d[2] = s[2], s[1], s[0]
+			if size == 4 {
+				d[3] = s[3]
+			}
+		}
+
+		if _, e.err = e.w.Write(row); e.err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package bmp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/LukiDS/image/imgconv"
+	"github.com/LukiDS/image/imgtest"
+)
+
+func TestEncode(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	m.SetNRGBA(0, 0, color.NRGBA{255, 0, 0, 255})
+	m.SetNRGBA(1, 0, color.NRGBA{0, 255, 0, 255})
+	m.SetNRGBA(0, 1, color.NRGBA{0, 0, 255, 255})
+	m.SetNRGBA(1, 1, color.NRGBA{255, 255, 255, 255})
+
+	//a 2x2 24 bit BMP with a BITMAPINFOHEADER and 2 bytes of padding per row
+	expected := []byte{
+		/* file header  */ 'B', 'M', 70, 0, 0, 0, 0, 0, 0, 0, 54, 0, 0, 0,
+		/* info header  */ 40, 0, 0, 0, 2, 0, 0, 0, 2, 0, 0, 0, 1, 0, 24, 0,
+		/* compression  */ 0, 0, 0, 0,
+		/* image size   */ 16, 0, 0, 0,
+		/* resolution   */ 0x13, 0x0b, 0, 0, 0x13, 0x0b, 0, 0,
+		/* colors       */ 0, 0, 0, 0, 0, 0, 0, 0,
+		/* bottom row   */ 0xff, 0x00, 0x00, 0xff, 0xff, 0xff, 0, 0,
+		/* top row      */ 0x00, 0x00, 0xff, 0x00, 0xff, 0x00, 0, 0,
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, m, Options{}); err != nil {
+		t.Fatalf("Encode: unexpected error: %v\n", err)
+	}
+
+	if actual := buf.Bytes(); !bytes.Equal(actual, expected) {
+		format := fmt.Sprintf("\n") +
+			fmt.Sprintf("Encode(2x2, {})\n") +
+			fmt.Sprintf("Expected bytes:\t % x\n", expected) +
+			fmt.Sprintf("Actual bytes:\t % x\n", actual)
+		t.Errorf(format)
+	}
+}
+
+func TestEncodeAlpha(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	m.SetNRGBA(0, 0, color.NRGBA{255, 0, 0, 255})
+	m.SetNRGBA(1, 0, color.NRGBA{0, 255, 0, 128})
+	m.SetNRGBA(2, 0, color.NRGBA{1, 2, 3, 0})
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, m, Options{Alpha: true}); err != nil {
+		t.Fatalf("Encode: unexpected error: %v\n", err)
+	}
+	data := buf.Bytes()
+
+	if size := binary.LittleEndian.Uint32(data[2:]); int(size) != len(data) || len(data) != fileHeaderSize+v5InfoHeaderSize+12 {
+		t.Errorf("Encode: file size %d in the header, %d written, expected %d\n", size, len(data), fileHeaderSize+v5InfoHeaderSize+12)
+	}
+
+	fields := []struct {
+		name     string
+		offset   int
+		expected uint32
+	}{
+		{name: "pixel offset", offset: 10, expected: fileHeaderSize + v5InfoHeaderSize},
+		{name: "info header size", offset: 14, expected: v5InfoHeaderSize},
+		{name: "compression", offset: 30, expected: compressionBitfields},
+		{name: "red mask", offset: 54, expected: 0x00ff0000},
+		{name: "green mask", offset: 58, expected: 0x0000ff00},
+		{name: "blue mask", offset: 62, expected: 0x000000ff},
+		{name: "alpha mask", offset: 66, expected: 0xff000000},
+		{name: "color space", offset: 70, expected: lcsSRGB},
+	}
+	for _, f := range fields {
+		if actual := binary.LittleEndian.Uint32(data[f.offset:]); actual != f.expected {
+			t.Errorf("Encode: %s is %#x, expected %#x\n", f.name, actual, f.expected)
+		}
+	}
+
+	expected := []byte{0x00, 0x00, 0xff, 0xff, 0x00, 0xff, 0x00, 0x80, 0x03, 0x02, 0x01, 0x00}
+	if actual := data[fileHeaderSize+v5InfoHeaderSize:]; !bytes.Equal(actual, expected) {
+		t.Errorf("Encode: pixels are % x, expected % x\n", actual, expected)
+	}
+
+	//opaque images are always written with 24 bits
+	buf.Reset()
+	if err := Encode(&buf, imgconv.UniformNRGBA(color.White, image.Rect(0, 0, 3, 1)), Options{Alpha: true}); err != nil {
+		t.Fatalf("Encode: unexpected error: %v\n", err)
+	}
+	if bpp := binary.LittleEndian.Uint16(buf.Bytes()[28:]); bpp != 24 {
+		t.Errorf("Encode(opaque, {Alpha: true}): %d bits per pixel, expected 24\n", bpp)
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	for _, r := range []image.Rectangle{image.Rect(0, 0, 1, 1), image.Rect(-3, 4, 10, 9), image.Rect(0, 0, 17, 3), image.Rect(5, 5, 9, 25)} {
+		m := image.NewNRGBA(r)
+		rnd.Read(m.Pix)
+
+		for _, opts := range []Options{{}, {Alpha: true}} {
+			t.Run(fmt.Sprintf("%v/Alpha=%t", r, opts.Alpha), func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := Encode(&buf, m, opts); err != nil {
+					t.Fatalf("Encode: unexpected error: %v\n", err)
+				}
+
+				actual, err := Decode(&buf)
+				if err != nil {
+					t.Fatalf("Decode: unexpected error: %v\n", err)
+				}
+
+				expected, _ := imgconv.Crop(m, r)
+				if !opts.Alpha {
+					for i := 3; i < len(expected.Pix); i += 4 {
+						expected.Pix[i] = 0xff
+					}
+				}
+				imgtest.AssertEqual(t, expected, actual)
+				imgtest.AssertColorModel(t, expected.ColorModel(), actual)
+			})
+		}
+	}
+}
+
+func TestEncodeEmptyImage(t *testing.T) {
+	if err := Encode(io.Discard, image.NewNRGBA(image.Rect(0, 0, 5, 0)), Options{}); err == nil {
+		t.Errorf("Encode(empty image): expected an error\n")
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	m := image.NewNRGBA(image.Rect(0, 0, 1024, 768))
+	rand.New(rand.NewSource(1)).Read(m.Pix)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := Encode(io.Discard, m, Options{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
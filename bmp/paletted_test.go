@@ -0,0 +1,152 @@
+package bmp
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/LukiDS/image/imgtest"
+)
+
+func TestDecodeWithTestFiles(t *testing.T) {
+	filenames, err := filepath.Glob("../testdata/bmp/*.bmp")
+	if err != nil {
+		t.Fatalf("could not find files: %v\n", err)
+	}
+
+	for _, name := range filenames {
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			bmpFile, err := os.Open(name)
+			if err != nil {
+				t.Fatalf("could not read file: %v\n", err)
+			}
+			defer bmpFile.Close()
+
+			img, err := Decode(bmpFile)
+			if err != nil {
+				t.Fatalf("could not decode file: %v\n", err)
+			}
+
+			pngFile, err := os.Open(strings.TrimSuffix(name, filepath.Ext(name)) + ".png")
+			if err != nil {
+				t.Fatalf("could not read file: %v\n", err)
+			}
+			defer pngFile.Close()
+
+			ref, err := png.Decode(pngFile)
+			if err != nil {
+				t.Fatalf("could not decode file: %v\n", err)
+			}
+
+			if _, ok := img.(*image.Paletted); !ok {
+				t.Fatalf("File %s: expected an *image.Paletted image, got %T\n", name, img)
+			}
+			imgtest.AssertEqual(t, ref, img)
+		})
+	}
+}
+
+func TestDecodePaletted(t *testing.T) {
+	palette := []byte{0, 0, 0, 0, 0xff, 0xff, 0xff, 0}
+
+	tests := []struct {
+		name  string
+		file  bmpFile
+		error string
+	}{
+		{name: "too many colors", file: bmpFile{width: 1, height: 1, bpp: 1, colors: 3, palette: append(palette, 0, 0, 0, 0), pixels: []byte{0, 0, 0, 0}}, error: "3 colors for 1 bits per pixel"},
+		{name: "truncated color table", file: bmpFile{width: 1, height: 1, bpp: 8, palette: palette}, error: "color table"},
+		{name: "index outside the palette", file: bmpFile{width: 2, height: 1, bpp: 4, colors: 2, palette: palette, pixels: []byte{0x12, 0, 0, 0}}, error: "index 2 exceeds"},
+		{name: "truncated pixels", file: bmpFile{width: 33, height: 2, bpp: 1, palette: palette, pixels: []byte{0, 0, 0, 0, 0, 0, 0, 0}}, error: "truncated"},
+		{name: "top-down RLE8", file: bmpFile{width: 1, height: -1, bpp: 8, compression: compressionRLE8, colors: 2, palette: palette, pixels: []byte{1, 0, 0, 1}}, error: "top-down"},
+		{name: "RLE4", file: bmpFile{width: 1, height: 1, bpp: 4, compression: 2, colors: 2, palette: palette, pixels: []byte{1, 0, 0, 1}}, error: "unsupported"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Decode(bytes.NewReader(generateBMP(t, test.file)))
+			if err == nil || !strings.Contains(err.Error(), test.error) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Decode(%s)\n", test.name) +
+					fmt.Sprintf("Expected error:\t containing %q\n", test.error) +
+					fmt.Sprintf("Actual error:\t %v\n", err)
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+func TestDecodeRLE8(t *testing.T) {
+	palette := []byte{0, 0, 0, 0, 0xff, 0xff, 0xff, 0, 0x80, 0x80, 0x80, 0}
+
+	tests := []struct {
+		name     string
+		rle      []byte
+		expected []uint8
+		error    string
+	}{
+		{name: "run and end of bitmap", rle: []byte{4, 1, 0, 0, 4, 2, 0, 1}, expected: []uint8{2, 2, 2, 2, 1, 1, 1, 1}},
+		{name: "missing end of bitmap after the last row", rle: []byte{4, 1, 0, 0, 4, 2, 0, 0}, expected: []uint8{2, 2, 2, 2, 1, 1, 1, 1}},
+		{name: "early end of bitmap", rle: []byte{2, 1, 0, 1}, expected: []uint8{0, 0, 0, 0, 1, 1, 0, 0}},
+		{name: "delta to the end of a row", rle: []byte{0, 2, 4, 1, 0, 1}, expected: []uint8{0, 0, 0, 0, 0, 0, 0, 0}},
+		{name: "run longer than the row", rle: []byte{5, 1, 0, 1}, error: "outside the image"},
+		{name: "absolute run longer than the row", rle: []byte{3, 1, 0, 3, 1, 1, 1, 0}, error: "outside the image"},
+		{name: "run above the top row", rle: []byte{0, 0, 0, 0, 1, 1, 0, 1}, error: "outside the image"},
+		{name: "delta beyond the right edge", rle: []byte{0, 2, 5, 0, 0, 1}, error: "delta moves outside"},
+		{name: "delta beyond the top row", rle: []byte{0, 2, 0, 2, 0, 1}, error: "delta moves outside"},
+		{name: "index outside the palette", rle: []byte{1, 3, 0, 1}, error: "index 3 exceeds"},
+		{name: "truncated run", rle: []byte{4}, error: "truncated"},
+		{name: "truncated absolute run", rle: []byte{0, 4, 1, 1}, error: "truncated"},
+		{name: "truncated delta", rle: []byte{0, 2, 1}, error: "truncated"},
+		{name: "missing end of bitmap", rle: []byte{4, 1, 0, 0}, error: "truncated"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := generateBMP(t, bmpFile{width: 4, height: 2, bpp: 8, compression: compressionRLE8, colors: 3, palette: palette, pixels: test.rle})
+			actual, err := Decode(bytes.NewReader(data))
+			if test.error != "" {
+				if err == nil || !strings.Contains(err.Error(), test.error) {
+					t.Errorf("Decode(%v): expected an error containing %q, got %v\n", test.rle, test.error, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode(%v): unexpected error: %v\n", test.rle, err)
+			}
+
+			if p := actual.(*image.Paletted).Pix; !bytes.Equal(p, test.expected) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Decode(%v)\n", test.rle) +
+					fmt.Sprintf("Expected indices:\t %v\n", test.expected) +
+					fmt.Sprintf("Actual indices:\t %v\n", p)
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+func TestDecodePalettedConfig(t *testing.T) {
+	data := generateBMP(t, bmpFile{width: 5, height: 3, bpp: 4, colors: 2, palette: []byte{0, 0, 0xff, 0, 0xff, 0, 0, 0}})
+
+	actual, err := DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("DecodeConfig: unexpected error: %v\n", err)
+	}
+
+	expected := color.Palette{color.RGBA{0xff, 0, 0, 0xff}, color.RGBA{0, 0, 0xff, 0xff}}
+	palette, ok := actual.ColorModel.(color.Palette)
+	if !ok || len(palette) != len(expected) || palette[0] != expected[0] || palette[1] != expected[1] || actual.Width != 5 || actual.Height != 3 {
+		t.Errorf("DecodeConfig() = %+v, expected a 5x3 image with the palette %v\n", actual, expected)
+	}
+}
+
+func TestDecodeCorruptFiles(t *testing.T) {
+	imgtest.DecodeCorrupt(t, "../testdata/bmp/*.bmp", 2000, 0, Decode)
+}
@@ -0,0 +1,254 @@
+// Command qoibench compares the QOI and PNG codecs on a directory of images.
+//
+// Usage:
+//
+//	qoibench [-runs n] [-json] dir
+//
+// Every PNG file below dir, like the ones of the qoi_benchmark_suite, is
+// decoded and then encoded to and decoded from QOI and PNG, n times each.
+// The average encode and decode times and the encoded sizes are printed for
+// every file, followed by the totals over all files.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/LukiDS/image/imgconv"
+	"github.com/LukiDS/image/qoi"
+)
+
+// stats holds the measurements of a codec.
+type stats struct {
+	DecodeTime time.Duration `json:"decode_ns"`
+	EncodeTime time.Duration `json:"encode_ns"`
+	Size       int64         `json:"size"`
+}
+
+// add adds the measurements of s to t.
+func (t *stats) add(s stats) {
+	t.DecodeTime += s.DecodeTime
+	t.EncodeTime += s.EncodeTime
+	t.Size += s.Size
+}
+
+// result holds the measurements of a file.
+type result struct {
+	File   string `json:"file"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	PNG    stats  `json:"png"`
+	QOI    stats  `json:"qoi"`
+}
+
+// summary holds the totals over all files.
+type summary struct {
+	Files  int   `json:"files"`
+	Pixels int64 `json:"pixels"`
+	PNG    stats `json:"png"`
+	QOI    stats `json:"qoi"`
+}
+
+// aggregate returns the totals of results.
+func aggregate(results []result) summary {
+	var s summary
+	for _, r := range results {
+		s.Files++
+		s.Pixels += int64(r.Width) * int64(r.Height)
+		s.PNG.add(r.PNG)
+		s.QOI.add(r.QOI)
+	}
+
+	return s
+}
+
+// codec is an encoder and a decoder under test.
+type codec struct {
+	encode func(io.Writer, image.Image) error
+	decode func(io.Reader) (image.Image, error)
+}
+
+var (
+	pngCodec = codec{encode: png.Encode, decode: png.Decode}
+	qoiCodec = codec{encode: qoi.Encode, decode: qoi.Decode}
+)
+
+// bench holds the buffers reused by all measurements.
+type bench struct {
+	runs int
+	buf  bytes.Buffer
+	rd   bytes.Reader
+}
+
+// measure encodes and decodes m with c b.runs times each and returns the
+// average times and the encoded size.
+func (b *bench) measure(c codec, m image.Image) (stats, error) {
+	var s stats
+
+	start := time.Now()
+	for i := 0; i < b.runs; i++ {
+		b.buf.Reset()
+		if err := c.encode(&b.buf, m); err != nil {
+			return s, fmt.Errorf("encode: %w", err)
+		}
+	}
+	s.EncodeTime = time.Since(start) / time.Duration(b.runs)
+	s.Size = int64(b.buf.Len())
+
+	start = time.Now()
+	for i := 0; i < b.runs; i++ {
+		b.rd.Reset(b.buf.Bytes())
+		if _, err := c.decode(&b.rd); err != nil {
+			return s, fmt.Errorf("decode: %w", err)
+		}
+	}
+	s.DecodeTime = time.Since(start) / time.Duration(b.runs)
+
+	return s, nil
+}
+
+// file measures both codecs on the PNG image data. The image is converted
+// to NRGBA first, which both encoders handle without a further conversion.
+func (b *bench) file(name string, data []byte) (result, error) {
+	r := result{File: name}
+
+	m, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return r, fmt.Errorf("%s: %w", name, err)
+	}
+	nrgba := imgconv.ToNRGBA(m)
+	r.Width, r.Height = nrgba.Bounds().Dx(), nrgba.Bounds().Dy()
+
+	if r.PNG, err = b.measure(pngCodec, nrgba); err != nil {
+		return r, fmt.Errorf("%s: png %w", name, err)
+	}
+	if r.QOI, err = b.measure(qoiCodec, nrgba); err != nil {
+		return r, fmt.Errorf("%s: qoi %w", name, err)
+	}
+
+	return r, nil
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run benchmarks the directory given by args and returns the exit status.
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("qoibench", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	runs := flags.Int("runs", 1, "number of encodes and decodes per file and codec")
+	asJSON := flags.Bool("json", false, "write JSON instead of a table")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: qoibench [-runs n] [-json] dir\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 || *runs < 1 {
+		flags.Usage()
+		return 2
+	}
+
+	dir := flags.Arg(0)
+	b := bench{runs: *runs}
+	var results []result
+	status := 0
+
+	err := filepath.WalkDir(dir, func(path string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if e.IsDir() || !strings.EqualFold(filepath.Ext(path), ".png") {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		r, err := b.file(name, data)
+		if err != nil {
+			//skip the file, but report it
+			fmt.Fprintf(stderr, "qoibench: %v\n", err)
+			status = 1
+			return nil
+		}
+		results = append(results, r)
+
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "qoibench: %v\n", err)
+		return 1
+	}
+
+	if *asJSON {
+		out := struct {
+			Files []result `json:"results"`
+			Total summary  `json:"total"`
+		}{results, aggregate(results)}
+		if out.Files == nil {
+			out.Files = []result{}
+		}
+
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(out); err != nil {
+			fmt.Fprintf(stderr, "qoibench: %v\n", err)
+			return 1
+		}
+		return status
+	}
+
+	printTable(stdout, results, aggregate(results))
+
+	return status
+}
+
+// printTable writes the results and their totals as an aligned table. The
+// files are named relative to the benchmarked directory.
+func printTable(w io.Writer, results []result, total summary) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "file\tpixels\tpng dec ms\tpng enc ms\tpng kb\tqoi dec ms\tqoi enc ms\tqoi kb\tqoi/png\t\n")
+
+	line := func(name string, pixels int64, p, q stats) {
+		ratio := 0.0
+		if p.Size > 0 {
+			ratio = float64(q.Size) / float64(p.Size)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%.3f\t%.3f\t%d\t%.3f\t%.3f\t%d\t%.3f\t\n", name, pixels,
+			ms(p.DecodeTime), ms(p.EncodeTime), p.Size/1024,
+			ms(q.DecodeTime), ms(q.EncodeTime), q.Size/1024, ratio)
+	}
+	for _, r := range results {
+		line(r.File, int64(r.Width)*int64(r.Height), r.PNG, r.QOI)
+	}
+	line(fmt.Sprintf("total (%d files)", total.Files), total.Pixels, total.PNG, total.QOI)
+
+	tw.Flush()
+}
+
+// ms returns d in milliseconds.
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
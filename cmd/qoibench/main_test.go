@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// generatePNG returns a PNG image of the given size filled with c.
+func generatePNG(t testing.TB, w, h int, c color.NRGBA) []byte {
+	t.Helper()
+
+	m := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < len(m.Pix); i += 4 {
+		m.Pix[i], m.Pix[i+1], m.Pix[i+2], m.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, m); err != nil {
+		t.Fatalf("could not encode image: %v\n", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestAggregate(t *testing.T) {
+	results := []result{
+		{File: "a.png", Width: 2, Height: 3, PNG: stats{DecodeTime: 1, EncodeTime: 2, Size: 100}, QOI: stats{DecodeTime: 3, EncodeTime: 4, Size: 30}},
+		{File: "b.png", Width: 5, Height: 1, PNG: stats{DecodeTime: 10, EncodeTime: 20, Size: 200}, QOI: stats{DecodeTime: 30, EncodeTime: 40, Size: 50}},
+	}
+
+	expected := summary{
+		Files:  2,
+		Pixels: 11,
+		PNG:    stats{DecodeTime: 11, EncodeTime: 22, Size: 300},
+		QOI:    stats{DecodeTime: 33, EncodeTime: 44, Size: 80},
+	}
+	if actual := aggregate(results); actual != expected {
+		t.Errorf("aggregate() = %+v, expected %+v\n", actual, expected)
+	}
+
+	if actual := aggregate(nil); actual != (summary{}) {
+		t.Errorf("aggregate(nil) = %+v, expected zero totals\n", actual)
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"a.png":       generatePNG(t, 2, 3, color.NRGBA{1, 2, 3, 0xff}),
+		"sub/b.PNG":   generatePNG(t, 5, 1, color.NRGBA{4, 5, 6, 0x80}),
+		"sub/c.txt":   []byte("not an image"),
+		"sub/bad.png": []byte("not a png"),
+	}
+	for name, data := range files {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0o777); err != nil {
+			t.Fatalf("could not create directory: %v\n", err)
+		}
+		if err := os.WriteFile(name, data, 0o666); err != nil {
+			t.Fatalf("could not write file: %v\n", err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if status := run([]string{"-json", "-runs", "2", dir}, &stdout, &stderr); status != 1 || !strings.Contains(stderr.String(), "bad.png") {
+		t.Fatalf("run() = %d, %q, expected status 1 reporting bad.png\n", status, stderr.String())
+	}
+
+	var out struct {
+		Files []result `json:"results"`
+		Total summary  `json:"total"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		t.Fatalf("could not decode output: %v\n%s", err, stdout.String())
+	}
+
+	if len(out.Files) != 2 || out.Files[0].File != "a.png" || out.Files[1].File != filepath.Join("sub", "b.PNG") {
+		t.Fatalf("run() measured %+v, expected a.png and sub/b.PNG\n", out.Files)
+	}
+	if out.Total != aggregate(out.Files) || out.Total.Pixels != 11 {
+		t.Errorf("run() totals = %+v, expected %+v with 11 pixels\n", out.Total, aggregate(out.Files))
+	}
+	for _, r := range out.Files {
+		//a uniform image is the header, a single color chunk, a run and the end marker
+		if r.QOI.Size < 14+1+1+8 || r.QOI.Size > 14+5+1+8 {
+			t.Errorf("run() measured a QOI size of %d for %s, expected a header, the chunks and the end marker\n", r.QOI.Size, r.File)
+		}
+		if r.PNG.Size <= 0 || r.PNG.EncodeTime <= 0 || r.QOI.DecodeTime < 0 || r.QOI.EncodeTime > time.Second {
+			t.Errorf("run() measured %+v for %s\n", r, r.File)
+		}
+	}
+
+	stdout.Reset()
+	if status := run([]string{dir}, &stdout, &stderr); status != 1 || !strings.Contains(stdout.String(), "total (2 files)") {
+		t.Errorf("run() = %d, printed %q, expected the totals of 2 files\n", status, stdout.String())
+	}
+
+	if status := run([]string{"-runs", "0", dir}, &stdout, &stderr); status != 2 {
+		t.Errorf("run(-runs 0) = %d, expected 2\n", status)
+	}
+}
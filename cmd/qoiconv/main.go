@@ -0,0 +1,167 @@
+// Command qoiconv converts images between QOI and the other formats supported
+// by this module and the standard library.
+//
+// Usage:
+//
+//	qoiconv [-f] [-from format] [-to format] input output
+//
+// The input format is sniffed with image.Decode, and the output format is
+// inferred from the file extension, which -to overrides. -from names the
+// format of inputs without a magic number, like wbmp. The file name "-" reads
+// the input from stdin or writes the output to stdout; the output format must
+// then be given with -to. The files are converted with the convert package,
+// and outputs are never left partially written.
+//
+// Existing outputs are only overwritten with -f. qoiconv exits with status 1
+// if the conversion fails and with status 2 for invalid arguments.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/LukiDS/image/convert"
+)
+
+// errUsage marks invalid arguments, which are reported with the usage.
+var errUsage = errors.New("invalid arguments")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run converts the image given by args and returns the exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("qoiconv", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	force := flags.Bool("f", false, "overwrite an existing output")
+	from := flags.String("from", "", "format of an input without a magic number, like wbmp")
+	to := flags.String("to", "", "format of the output, instead of its extension")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: qoiconv [-f] [-from format] [-to format] input output\n")
+		flags.PrintDefaults()
+		fmt.Fprintf(stderr, "output formats: %s\n", strings.Join(formatNames(), ", "))
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+
+	c := converter{
+		input:  flags.Arg(0),
+		output: flags.Arg(1),
+		opts:   convert.Options{Overwrite: *force, From: extension(*from), To: extension(*to)},
+		stdin:  stdin,
+		stdout: stdout,
+	}
+	if err := c.convert(); err != nil {
+		fmt.Fprintf(stderr, "qoiconv: %v\n", err)
+		if errors.Is(err, errUsage) {
+			return 2
+		}
+		return 1
+	}
+
+	return 0
+}
+
+// formatNames returns the sorted names of the formats which can be written,
+// which are the extensions known by the convert package.
+func formatNames() []string {
+	var names []string
+	for _, ext := range convert.Extensions() {
+		names = append(names, strings.TrimPrefix(ext, "."))
+	}
+
+	return names
+}
+
+// extension returns the file extension of the format name, or "" if name is
+// empty.
+func extension(name string) string {
+	if name == "" {
+		return ""
+	}
+
+	return "." + name
+}
+
+type converter struct {
+	input, output string
+	opts          convert.Options
+	stdin         io.Reader
+	stdout        io.Writer
+}
+
+func (c *converter) convert() error {
+	var err error
+	if c.input != "-" && c.output != "-" {
+		err = convert.Convert(c.input, c.output, c.opts)
+	} else {
+		err = c.stream()
+	}
+
+	switch {
+	case errors.Is(err, convert.ErrUnknownFormat):
+		return fmt.Errorf("%w: %v, use -to", errUsage, err)
+	case errors.Is(err, fs.ErrExist):
+		return fmt.Errorf("%s already exists, use -f to overwrite it", c.output)
+	}
+
+	return err
+}
+
+// stream converts an image read from stdin or written to stdout.
+func (c *converter) stream() error {
+	m, err := c.decode()
+	if err != nil {
+		return err
+	}
+
+	if c.output != "-" {
+		return convert.WriteFile(c.output, m, c.opts)
+	}
+
+	w := bufio.NewWriter(c.stdout)
+	if err := convert.Encode(w, m, c.opts.To, c.opts); err != nil {
+		return fmt.Errorf("encode %s: %w", c.output, err)
+	}
+
+	return w.Flush()
+}
+
+// decode reads the input image, from stdin if the input is "-".
+func (c *converter) decode() (image.Image, error) {
+	r, from := c.stdin, c.opts.From
+	if c.input != "-" {
+		f, err := os.Open(c.input)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+
+		if from == "" {
+			from = filepath.Ext(c.input)
+		}
+	}
+
+	m, err := convert.Decode(bufio.NewReader(r), from)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", c.input, err)
+	}
+
+	return m, nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/LukiDS/image/imgconv"
+	"github.com/LukiDS/image/imgtest"
+	"github.com/LukiDS/image/qoi"
+	"github.com/LukiDS/image/wbmp"
+)
+
+var testFiles = []string{"dice", "kodim10", "kodim23", "qoi_logo", "testcard", "testcard_rgba", "wikipedia_008"}
+
+func TestRunWithTestFiles(t *testing.T) {
+	for _, name := range testFiles {
+		for _, conv := range []struct{ from, to string }{{"png", "qoi"}, {"qoi", "png"}} {
+			t.Run(name+"."+conv.from, func(t *testing.T) {
+				input := filepath.Join("../../testdata", name+"."+conv.from)
+				output := filepath.Join(t.TempDir(), name+"."+conv.to)
+
+				var stdout, stderr bytes.Buffer
+				if status := run([]string{input, output}, nil, &stdout, &stderr); status != 0 {
+					t.Fatalf("run(%s, %s) = %d, expected 0\nstderr: %s", input, output, status, stderr.String())
+				}
+
+				//the output must decode to the same image as the reference file
+				expected := decodeFile(t, filepath.Join("../../testdata", name+"."+conv.to))
+				actual := decodeFile(t, output)
+				imgtest.AssertEqual(t, expected, actual)
+			})
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.qoi")
+	if err := os.WriteFile(existing, []byte("keep"), 0o666); err != nil {
+		t.Fatalf("could not write file: %v\n", err)
+	}
+	corrupt := filepath.Join(dir, "corrupt.png")
+	if err := os.WriteFile(corrupt, []byte("\x89PNG not really"), 0o666); err != nil {
+		t.Fatalf("could not write file: %v\n", err)
+	}
+	dice := "../../testdata/dice.png"
+
+	tests := []struct {
+		name   string
+		args   []string
+		status int
+		error  string
+		output string //must exist after run, if set
+	}{
+		{name: "missing output", args: []string{dice}, status: 2, error: "usage"},
+		{name: "unknown flag", args: []string{"-x", dice, "out.qoi"}, status: 2, error: "usage"},
+		{name: "unknown output extension", args: []string{dice, filepath.Join(dir, "out.xyz")}, status: 2, error: "use -to"},
+		{name: "unknown format flag", args: []string{"-to", "webp", dice, filepath.Join(dir, "out.xyz")}, status: 2, error: `unknown output format ".webp"`},
+		{name: "read only format", args: []string{dice, filepath.Join(dir, "out.tga")}, status: 2, error: `unknown output format ".tga", use -to`},
+		{name: "missing input", args: []string{filepath.Join(dir, "missing.png"), filepath.Join(dir, "out.qoi")}, status: 1, error: "no such file"},
+		{name: "corrupt input", args: []string{corrupt, filepath.Join(dir, "corrupt.qoi")}, status: 1, error: "decode " + corrupt},
+		{name: "existing output", args: []string{dice, existing}, status: 1, error: "use -f"},
+		{name: "wrong input format", args: []string{"-from", "wbmp", dice, filepath.Join(dir, "wrong.png")}, status: 1, error: "wbmp"},
+		{name: "overwrite with -f", args: []string{"-f", dice, existing}, status: 0, output: existing},
+		{name: "output format from -to", args: []string{"-to", "qoi", dice, filepath.Join(dir, "dice.bin")}, status: 0, output: filepath.Join(dir, "dice.bin")},
+		{name: "sniff unknown input extension", args: []string{filepath.Join(dir, "dice.bin"), filepath.Join(dir, "sniffed.png")}, status: 0, output: filepath.Join(dir, "sniffed.png")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			status := run(test.args, nil, &stdout, &stderr)
+			if status != test.status || !strings.Contains(stderr.String(), test.error) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("run(%q)\n", test.args) +
+					fmt.Sprintf("Expected:\t status %d, error containing %q\n", test.status, test.error) +
+					fmt.Sprintf("Actual:\t status %d, error %q\n", status, stderr.String())
+				t.Errorf(format)
+			}
+
+			if test.output != "" {
+				expected := decodeFile(t, dice)
+				imgtest.AssertEqual(t, expected, decodeFile(t, test.output))
+			}
+		})
+	}
+
+	//failed conversions must not leave an output behind
+	for _, name := range []string{"corrupt.qoi", "wrong.png"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("output %s exists after a failed conversion: %v\n", name, err)
+		}
+	}
+	if data, err := os.ReadFile(existing); err != nil || string(data) == "keep" {
+		t.Errorf("output %s was not overwritten with -f: %v\n", existing, err)
+	}
+}
+
+func TestRunStdio(t *testing.T) {
+	input, err := os.ReadFile("../../testdata/qoi_logo.qoi")
+	if err != nil {
+		t.Fatalf("could not read file: %v\n", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if status := run([]string{"-to", "png", "-", "-"}, bytes.NewReader(input), &stdout, &stderr); status != 0 {
+		t.Fatalf("run(-, -) = %d, expected 0\nstderr: %s", status, stderr.String())
+	}
+
+	actual, err := png.Decode(&stdout)
+	if err != nil {
+		t.Fatalf("could not decode output: %v\n", err)
+	}
+	expected, err := qoi.Decode(bytes.NewReader(input))
+	if err != nil {
+		t.Fatalf("could not decode file: %v\n", err)
+	}
+	imgtest.AssertEqual(t, expected, actual)
+
+	//stdin can be written to a file, also in a format without magic number
+	var wbmpData bytes.Buffer
+	if err := wbmp.Encode(&wbmpData, image.NewGray(image.Rect(0, 0, 3, 2))); err != nil {
+		t.Fatalf("could not encode image: %v\n", err)
+	}
+	output := filepath.Join(t.TempDir(), "out.qoi")
+	if status := run([]string{"-from", "wbmp", "-", output}, bytes.NewReader(wbmpData.Bytes()), &stdout, &stderr); status != 0 {
+		t.Fatalf("run(-from wbmp, -, %s) = %d, expected 0\nstderr: %s", output, status, stderr.String())
+	}
+	imgtest.AssertEqual(t, image.NewGray(image.Rect(0, 0, 3, 2)), decodeFile(t, output))
+
+	stderr.Reset()
+	if status := run([]string{"-", "-"}, bytes.NewReader(input), &stdout, &stderr); status != 2 || !strings.Contains(stderr.String(), "use -to") {
+		t.Errorf("run(-, -) without -to = %d, %q, expected status 2 asking for -to\n", status, stderr.String())
+	}
+}
+
+func TestRunConvertsColorModel(t *testing.T) {
+	//the outputs are converted by the convert package, so transparent pixels
+	//are flattened for PPM and GIF outputs hold a palette
+	input := "../../testdata/testcard_rgba.png"
+	dir := t.TempDir()
+	for _, name := range []string{"out.ppm", "out.gif"} {
+		output := filepath.Join(dir, name)
+		var stdout, stderr bytes.Buffer
+		if status := run([]string{input, output}, nil, &stdout, &stderr); status != 0 {
+			t.Fatalf("run(%s, %s) = %d, expected 0\nstderr: %s", input, output, status, stderr.String())
+		}
+	}
+
+	expected := imgconv.Flatten(decodeFile(t, input), color.Black)
+	imgtest.AssertEqual(t, expected, decodeFile(t, filepath.Join(dir, "out.ppm")))
+	if m := decodeFile(t, filepath.Join(dir, "out.gif")); m.Bounds() != expected.Bounds() {
+		t.Errorf("gif output has bounds %v, expected %v\n", m.Bounds(), expected.Bounds())
+	}
+}
+
+// decodeFile decodes the image file name in any registered format.
+func decodeFile(t testing.TB, name string) image.Image {
+	t.Helper()
+
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatalf("could not read file: %v\n", err)
+	}
+	defer f.Close()
+
+	m, _, err := image.Decode(f)
+	if err != nil {
+		t.Fatalf("could not decode file %s: %v\n", name, err)
+	}
+
+	return m
+}
@@ -0,0 +1,139 @@
+// Command qoidiff compares two images pixel by pixel.
+//
+// Usage:
+//
+//	qoidiff [-out diff.png] a b
+//
+// The images may be in any format known to image.Decode. qoidiff reports
+// whether they match exactly and otherwise the number of differing pixels,
+// the largest difference of each channel and the bounding box of all
+// differences. With -out a PNG image highlighting the changed pixels is
+// written; see imgcmp.Diff.
+//
+// qoidiff exits with status 0 if the images are identical, 1 if they differ
+// and 2 if an image cannot be read or the arguments are invalid.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"os"
+
+	_ "github.com/LukiDS/image/formats"
+	"github.com/LukiDS/image/imgcmp"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run compares the images given by args and returns the exit status.
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("qoidiff", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	out := flags.String("out", "", "write a PNG image highlighting the differences")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: qoidiff [-out diff.png] a b\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+
+	status, err := diff(stdout, flags.Arg(0), flags.Arg(1), *out)
+	if err != nil {
+		fmt.Fprintf(stderr, "qoidiff: %v\n", err)
+	}
+
+	return status
+}
+
+// diff compares the image files a and b, prints the result to w and returns
+// the exit status.
+func diff(w io.Writer, a, b, out string) (int, error) {
+	ma, err := decodeFile(a)
+	if err != nil {
+		return 2, err
+	}
+	mb, err := decodeFile(b)
+	if err != nil {
+		return 2, err
+	}
+
+	if ma.Bounds() != mb.Bounds() {
+		fmt.Fprintf(w, "different bounds: %v and %v\n", ma.Bounds(), mb.Bounds())
+		return 1, nil
+	}
+
+	var r imgcmp.Report
+	if out == "" {
+		r, err = imgcmp.Compare(ma, mb)
+	} else {
+		var m *image.NRGBA
+		if m, r, err = imgcmp.Diff(ma, mb, nil); err == nil {
+			err = writePNG(out, m)
+		}
+	}
+	if err != nil {
+		return 2, err
+	}
+
+	if r.Equal() {
+		fmt.Fprintf(w, "identical: %dx%d pixels\n", ma.Bounds().Dx(), ma.Bounds().Dy())
+		return 0, nil
+	}
+
+	total := ma.Bounds().Dx() * ma.Bounds().Dy()
+	fmt.Fprintf(w, "different: %d of %d pixels (%.2f%%)\n", r.Differing, total, 100*float64(r.Differing)/float64(total))
+	fmt.Fprintf(w, "first:     %v\n", r.First)
+	fmt.Fprintf(w, "max delta: R %d, G %d, B %d, A %d\n", r.MaxDelta[0], r.MaxDelta[1], r.MaxDelta[2], r.MaxDelta[3])
+	fmt.Fprintf(w, "bounds:    %v\n", r.Bounds)
+
+	return 1, nil
+}
+
+// decodeFile decodes the image file name in any registered format.
+func decodeFile(name string) (image.Image, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m, _, err := image.Decode(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", name, err)
+	}
+
+	return m, nil
+}
+
+// writePNG writes m to the file name in PNG format.
+func writePNG(name string, m image.Image) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	err = png.Encode(w, m)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeImage writes m as PNG into dir and returns the file name.
+func writeImage(t testing.TB, dir, name string, m image.Image) string {
+	t.Helper()
+
+	name = filepath.Join(dir, name)
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatalf("could not create file: %v\n", err)
+	}
+	defer f.Close()
+
+	if err := png.Encode(f, m); err != nil {
+		t.Fatalf("could not encode image: %v\n", err)
+	}
+
+	return name
+}
+
+func TestRunWithTestFiles(t *testing.T) {
+	for _, name := range []string{"dice", "kodim10", "qoi_logo", "testcard_rgba"} {
+		t.Run(name, func(t *testing.T) {
+			a := filepath.Join("../../testdata", name+".qoi")
+			b := filepath.Join("../../testdata", name+".png")
+
+			var stdout, stderr bytes.Buffer
+			if status := run([]string{a, b}, &stdout, &stderr); status != 0 || !strings.HasPrefix(stdout.String(), "identical") {
+				t.Errorf("run(%s, %s) = %d, %q, %q, expected identical images\n", a, b, status, stdout.String(), stderr.String())
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+
+	m := image.NewNRGBA(image.Rect(0, 0, 4, 3))
+	for i := range m.Pix {
+		m.Pix[i] = 0x80
+	}
+	changed := image.NewNRGBA(m.Rect)
+	copy(changed.Pix, m.Pix)
+	changed.SetNRGBA(1, 0, color.NRGBA{0x85, 0x80, 0x80, 0x80})
+	changed.SetNRGBA(2, 2, color.NRGBA{0x80, 0x70, 0x80, 0x82})
+
+	a := writeImage(t, dir, "a.png", m)
+	b := writeImage(t, dir, "b.png", changed)
+	small := writeImage(t, dir, "small.png", image.NewNRGBA(image.Rect(0, 0, 2, 2)))
+	out := filepath.Join(dir, "diff.png")
+
+	tests := []struct {
+		name   string
+		args   []string
+		status int
+		stdout []string
+		stderr string
+	}{
+		{name: "identical", args: []string{a, a}, status: 0, stdout: []string{"identical: 4x3"}},
+		{
+			name:   "different",
+			args:   []string{"-out", out, a, b},
+			status: 1,
+			stdout: []string{"different: 2 of 12 pixels", "first:     (1,0)", "max delta: R 5, G 16, B 0, A 2", "bounds:    (1,0)-(3,3)"},
+		},
+		{name: "different bounds", args: []string{a, small}, status: 1, stdout: []string{"different bounds"}},
+		{name: "missing file", args: []string{a, filepath.Join(dir, "missing.png")}, status: 2, stderr: "no such file"},
+		{name: "not an image", args: []string{a, "main.go"}, status: 2, stderr: "decode main.go"},
+		{name: "missing argument", args: []string{a}, status: 2, stderr: "usage"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			status := run(test.args, &stdout, &stderr)
+
+			ok := status == test.status && strings.Contains(stderr.String(), test.stderr)
+			for _, s := range test.stdout {
+				ok = ok && strings.Contains(stdout.String(), s)
+			}
+			if !ok {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("run(%q)\n", test.args) +
+					fmt.Sprintf("Expected:\t status %d, output containing %q, error containing %q\n", test.status, test.stdout, test.stderr) +
+					fmt.Sprintf("Actual:\t status %d, output %q, error %q\n", status, stdout.String(), stderr.String())
+				t.Errorf(format)
+			}
+		})
+	}
+
+	//the difference image highlights exactly the changed pixels
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatalf("could not read file: %v\n", err)
+	}
+	defer f.Close()
+	d, err := png.Decode(f)
+	if err != nil {
+		t.Fatalf("could not decode file: %v\n", err)
+	}
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 4; x++ {
+			r, g, _, _ := d.At(x, y).RGBA()
+			if highlighted := r > g; highlighted != ((x == 1 && y == 0) || (x == 2 && y == 2)) {
+				t.Errorf("difference image pixel at x=%d, y=%d is %v, highlighted: %v\n", x, y, d.At(x, y), highlighted)
+			}
+		}
+	}
+}
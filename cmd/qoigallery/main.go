@@ -0,0 +1,66 @@
+// Command qoigallery generates a static HTML gallery of QOI images.
+//
+// Usage:
+//
+//	qoigallery [-out dir] [-max-thumb n] [-thumb-format png|qoi] [-title title] dir
+//
+// Every .qoi file below dir gets a thumbnail in the thumbs directory of the
+// output directory, and index.html shows them in a grid linking to the
+// originals; see the gallery package. qoigallery exits with status 1 if the
+// gallery cannot be written or an image is corrupt, and with status 2 for
+// invalid arguments.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/LukiDS/image/gallery"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run generates the gallery given by args and returns the exit status.
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("qoigallery", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	out := flags.String("out", "gallery", "output directory")
+	var opts gallery.Options
+	flags.IntVar(&opts.MaxThumb, "max-thumb", 256, "largest width and height of the thumbnails")
+	flags.StringVar(&opts.ThumbFormat, "thumb-format", "png", "format of the thumbnails, png or qoi")
+	flags.StringVar(&opts.Title, "title", "", "title of the page, instead of the directory name")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: qoigallery [-out dir] [-max-thumb n] [-thumb-format png|qoi] [-title title] dir\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 || opts.MaxThumb <= 0 {
+		flags.Usage()
+		return 2
+	}
+
+	entries, err := gallery.Generate(context.Background(), flags.Arg(0), *out, opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "qoigallery: %v\n", err)
+		return 1
+	}
+
+	status := 0
+	for _, e := range entries {
+		if e.Err != nil {
+			fmt.Fprintf(stderr, "qoigallery: %s: %v\n", e.Name, e.Err)
+			status = 1
+		}
+	}
+	fmt.Fprintf(stdout, "wrote %d images to %s\n", len(entries), *out)
+
+	return status
+}
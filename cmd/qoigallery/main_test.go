@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	src := t.TempDir()
+	data, err := os.ReadFile("../../testdata/testcard.qoi")
+	if err != nil {
+		t.Fatalf("could not read file: %v\n", err)
+	}
+	for name, content := range map[string][]byte{"a.qoi": data, "b.qoi": data[:50]} {
+		if err := os.WriteFile(filepath.Join(src, name), content, 0o666); err != nil {
+			t.Fatalf("could not write file: %v\n", err)
+		}
+	}
+	out := filepath.Join(t.TempDir(), "out")
+
+	var stdout, stderr bytes.Buffer
+	status := run([]string{"-out", out, "-max-thumb", "32", "-thumb-format", "qoi", src}, &stdout, &stderr)
+	if status != 1 || !strings.Contains(stdout.String(), "wrote 2 images") || !strings.Contains(stderr.String(), "b.qoi") {
+		t.Errorf("run() = %d, %q, %q, expected status 1 reporting b.qoi\n", status, stdout.String(), stderr.String())
+	}
+	for _, name := range []string{"index.html", filepath.Join("thumbs", "a.qoi")} {
+		if _, err := os.Stat(filepath.Join(out, name)); err != nil {
+			t.Errorf("run() did not write %s: %v\n", name, err)
+		}
+	}
+
+	for _, args := range [][]string{nil, {"-max-thumb", "0", src}, {"-thumb-format"}} {
+		if status := run(args, &stdout, &stderr); status != 2 {
+			t.Errorf("run(%q) = %d, expected 2\n", args, status)
+		}
+	}
+	if status := run([]string{"-thumb-format", "gif", "-out", out, src}, &stdout, &stderr); status != 1 {
+		t.Errorf("run(-thumb-format gif) = %d, expected 1\n", status)
+	}
+}
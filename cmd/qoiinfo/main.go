@@ -0,0 +1,287 @@
+// Command qoiinfo prints the header and the encoded size of QOI images.
+//
+// Usage:
+//
+//	qoiinfo [-chunks] [-json] file...
+//
+// With -chunks the number of chunks, their encoded bytes and the pixels they
+// produce are listed for each chunk type. With -json the information is
+// written as one JSON object per file instead of text.
+//
+// The chunks are walked without decoding the pixels, so corrupt files are
+// reported with everything parsed before the error and the byte offset at
+// which it occurred. qoiinfo exits with status 1 if any file is invalid and
+// with status 2 for invalid arguments.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	qoiMagic      = "qoif"
+	qoiHeaderSize = 14
+	qoiMaxPixels  = 400_000_000
+)
+
+var qoiEndMarker = []byte{0, 0, 0, 0, 0, 0, 0, 1}
+
+// chunk types, in the order they are printed
+var chunkTypes = []string{"RGB", "RGBA", "INDEX", "DIFF", "LUMA", "RUN"}
+
+// chunkStats holds the statistics of a chunk type.
+type chunkStats struct {
+	Count  int64 `json:"count"`
+	Bytes  int64 `json:"bytes"`
+	Pixels int64 `json:"pixels"`
+}
+
+// info holds everything known about a QOI file. Size is the number of bytes
+// read and Offset the position of the first error.
+type info struct {
+	File       string                 `json:"file"`
+	Width      int                    `json:"width"`
+	Height     int                    `json:"height"`
+	Channels   int                    `json:"channels"`
+	Colorspace int                    `json:"colorspace"`
+	Size       int64                  `json:"size"`
+	Pixels     int64                  `json:"pixels"`
+	Chunks     map[string]*chunkStats `json:"chunks,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	Offset     *int64                 `json:"offset,omitempty"`
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run inspects the files given by args and returns the exit status.
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("qoiinfo", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	chunks := flags.Bool("chunks", false, "list the chunk type histogram")
+	flags.BoolVar(chunks, "stats", false, "alias of -chunks")
+	asJSON := flags.Bool("json", false, "write JSON instead of text")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: qoiinfo [-chunks] [-json] file...\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	status := 0
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	for _, name := range flags.Args() {
+		in := inspectFile(name)
+		if in.Error != "" {
+			status = 1
+		}
+		if !*chunks {
+			in.Chunks = nil
+		}
+
+		if *asJSON {
+			if err := enc.Encode(in); err != nil {
+				fmt.Fprintf(stderr, "qoiinfo: %v\n", err)
+				return 1
+			}
+			continue
+		}
+		printInfo(stdout, in)
+	}
+
+	return status
+}
+
+// inspectFile inspects the QOI file name.
+func inspectFile(name string) info {
+	f, err := os.Open(name)
+	if err != nil {
+		return info{File: name, Error: err.Error()}
+	}
+	defer f.Close()
+
+	in, err := inspect(f)
+	in.File = name
+	if err != nil {
+		in.Error = err.Error()
+	}
+
+	return in
+}
+
+// offsetReader counts the bytes read from a bufio.Reader. An end of file
+// inside a read is reported as io.ErrUnexpectedEOF.
+type offsetReader struct {
+	r      *bufio.Reader
+	offset int64
+}
+
+func (r *offsetReader) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil {
+		r.offset++
+	}
+
+	return b, err
+}
+
+func (r *offsetReader) Read(p []byte) (int, error) {
+	n, err := io.ReadFull(r.r, p)
+	r.offset += int64(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return n, err
+}
+
+// inspect walks the header, the chunks and the end marker of a QOI image.
+// After an error the returned info holds the values parsed so far and the
+// offset of the header field or chunk which is invalid.
+func inspect(rd io.Reader) (info, error) {
+	r := &offsetReader{r: bufio.NewReader(rd)}
+	in := info{Chunks: make(map[string]*chunkStats, len(chunkTypes))}
+	for _, t := range chunkTypes {
+		in.Chunks[t] = &chunkStats{}
+	}
+
+	fail := func(offset int64, err error) (info, error) {
+		in.Size = r.offset
+		in.Offset = &offset
+		return in, fmt.Errorf("%w at offset %d", err, offset)
+	}
+
+	h := make([]byte, qoiHeaderSize)
+	if _, err := r.Read(h); err != nil {
+		return fail(0, fmt.Errorf("truncated header: %w", err))
+	}
+	if string(h[:4]) != qoiMagic {
+		return fail(0, fmt.Errorf("invalid magic %q", h[:4]))
+	}
+
+	in.Width = int(binary.BigEndian.Uint32(h[4:8]))
+	in.Height = int(binary.BigEndian.Uint32(h[8:12]))
+	in.Channels = int(h[12])
+	in.Colorspace = int(h[13])
+
+	if in.Width <= 0 || in.Height <= 0 || in.Width > qoiMaxPixels/in.Height {
+		return fail(4, fmt.Errorf("invalid image size %dx%d", in.Width, in.Height))
+	}
+	if in.Channels != 3 && in.Channels != 4 {
+		return fail(12, fmt.Errorf("invalid channels %d", in.Channels))
+	}
+	if in.Colorspace > 1 {
+		return fail(13, fmt.Errorf("invalid colorspace %d", in.Colorspace))
+	}
+
+	total := int64(in.Width) * int64(in.Height)
+	data := make([]byte, 4)
+	for in.Pixels < total {
+		offset := r.offset
+		b, err := r.ReadByte()
+		if err != nil {
+			return fail(offset, fmt.Errorf("truncated chunk data after %d of %d pixels: %w", in.Pixels, total, io.ErrUnexpectedEOF))
+		}
+
+		t, size, pixels := chunkType(b)
+		if _, err := r.Read(data[:size-1]); err != nil {
+			return fail(offset, fmt.Errorf("truncated %s chunk: %w", t, err))
+		}
+
+		//runs beyond the last pixel are clipped, like the decoder does
+		if in.Pixels+pixels > total {
+			pixels = total - in.Pixels
+		}
+
+		s := in.Chunks[t]
+		s.Count++
+		s.Bytes += int64(size)
+		s.Pixels += pixels
+		in.Pixels += pixels
+	}
+
+	offset := r.offset
+	marker := make([]byte, len(qoiEndMarker))
+	if _, err := r.Read(marker); err != nil {
+		return fail(offset, fmt.Errorf("truncated end marker: %w", err))
+	}
+	if !bytes.Equal(marker, qoiEndMarker) {
+		return fail(offset, fmt.Errorf("invalid end marker % x", marker))
+	}
+	if _, err := r.ReadByte(); err != io.EOF {
+		return fail(r.offset-1, fmt.Errorf("trailing data after the end marker"))
+	}
+
+	in.Size = r.offset
+	return in, nil
+}
+
+// chunkType returns the type of the chunk starting with the tag b, its size
+// in bytes and the number of pixels it produces.
+func chunkType(b byte) (string, int, int64) {
+	switch {
+	case b == 0xfe:
+		return "RGB", 4, 1
+	case b == 0xff:
+		return "RGBA", 5, 1
+	case b>>6 == 0:
+		return "INDEX", 1, 1
+	case b>>6 == 1:
+		return "DIFF", 1, 1
+	case b>>6 == 2:
+		return "LUMA", 2, 1
+	default:
+		return "RUN", 1, int64(b&0x3f) + 1
+	}
+}
+
+// printInfo writes the information about a file as text.
+func printInfo(w io.Writer, in info) {
+	fmt.Fprintf(w, "%s:\n", in.File)
+	if in.Width > 0 && in.Height > 0 {
+		fmt.Fprintf(w, "  size:        %dx%d\n", in.Width, in.Height)
+		fmt.Fprintf(w, "  channels:    %d (%s)\n", in.Channels, map[int]string{3: "RGB", 4: "RGBA"}[in.Channels])
+		fmt.Fprintf(w, "  colorspace:  %d (%s)\n", in.Colorspace, map[int]string{0: "sRGB with linear alpha", 1: "all channels linear"}[in.Colorspace])
+	}
+	if in.Size > 0 {
+		fmt.Fprintf(w, "  file size:   %d bytes\n", in.Size)
+	}
+	if in.Size > qoiHeaderSize {
+		encoded := in.Size - qoiHeaderSize
+		if in.Error == "" {
+			encoded -= int64(len(qoiEndMarker))
+		}
+		fmt.Fprintf(w, "  encoded:     %d bytes for %d pixels\n", encoded, in.Pixels)
+		if in.Error == "" {
+			raw := int64(in.Width) * int64(in.Height) * int64(in.Channels)
+			fmt.Fprintf(w, "  bits/pixel:  %.3f (%.1f%% of %d raw bytes)\n", 8*float64(in.Size)/float64(in.Pixels), 100*float64(in.Size)/float64(raw), raw)
+		}
+	}
+
+	if in.Chunks != nil && in.Width > 0 {
+		fmt.Fprintf(w, "  %-6s %10s %10s %10s\n", "chunk", "count", "bytes", "pixels")
+		for _, t := range chunkTypes {
+			s := in.Chunks[t]
+			fmt.Fprintf(w, "  %-6s %10d %10d %10d\n", t, s.Count, s.Bytes, s.Pixels)
+		}
+	}
+
+	if in.Error != "" {
+		fmt.Fprintf(w, "  error:       %s\n", in.Error)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/LukiDS/image/qoi"
+)
+
+func TestInspectWithTestFiles(t *testing.T) {
+	filenames, err := filepath.Glob("../../testdata/*.qoi")
+	if err != nil || len(filenames) == 0 {
+		t.Fatalf("could not find files: %v\n", err)
+	}
+
+	for _, name := range filenames {
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			data, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatalf("could not read file: %v\n", err)
+			}
+
+			in, err := inspect(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("inspect(%s): unexpected error: %v\n", name, err)
+			}
+
+			config, err := qoi.DecodeConfig(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("could not decode file: %v\n", err)
+			}
+			if in.Width != config.Width || in.Height != config.Height || in.Channels != int(data[12]) || in.Colorspace != int(data[13]) {
+				t.Errorf("inspect(%s) = %dx%d, %d channels, colorspace %d, expected %dx%d, %d channels, colorspace %d\n",
+					name, in.Width, in.Height, in.Channels, in.Colorspace, config.Width, config.Height, data[12], data[13])
+			}
+
+			//the chunks must add up to the image and the file
+			var bytes, pixels int64
+			for _, s := range in.Chunks {
+				bytes += s.Bytes
+				pixels += s.Pixels
+			}
+			total := int64(config.Width) * int64(config.Height)
+			if in.Size != int64(len(data)) || bytes != in.Size-qoiHeaderSize-int64(len(qoiEndMarker)) || pixels != total || in.Pixels != total {
+				t.Errorf("inspect(%s) = size %d, %d chunk bytes, %d chunk pixels, %d pixels, expected size %d and %d pixels\n",
+					name, in.Size, bytes, pixels, in.Pixels, len(data), total)
+			}
+		})
+	}
+}
+
+func TestInspectCorruptFiles(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/qoi_logo.qoi")
+	if err != nil {
+		t.Fatalf("could not read file: %v\n", err)
+	}
+	header := func(width, height uint32, channels, colorspace byte) []byte {
+		h := []byte("qoif\x00\x00\x00\x00\x00\x00\x00\x00")
+		h[4], h[5], h[6], h[7] = byte(width>>24), byte(width>>16), byte(width>>8), byte(width)
+		h[8], h[9], h[10], h[11] = byte(height>>24), byte(height>>16), byte(height>>8), byte(height)
+		return append(h, channels, colorspace)
+	}
+
+	tests := []struct {
+		name   string
+		data   []byte
+		error  string
+		offset int64
+		pixels int64
+	}{
+		{name: "empty file", data: nil, error: "truncated header", offset: 0},
+		{name: "wrong magic", data: append([]byte("qoix"), data[4:]...), error: "invalid magic", offset: 0},
+		{name: "empty image", data: header(0, 1, 4, 0), error: "invalid image size", offset: 4},
+		{name: "2 channels", data: header(1, 1, 2, 0), error: "invalid channels 2", offset: 12},
+		{name: "colorspace 2", data: header(1, 1, 4, 2), error: "invalid colorspace 2", offset: 13},
+		{name: "truncated chunk", data: append(header(2, 1, 4, 0), 0xff, 1, 2), error: "truncated RGBA chunk", offset: 14, pixels: 0},
+		{name: "missing chunks", data: append(header(3, 1, 4, 0), 0xc0), error: "after 1 of 3 pixels", offset: 15, pixels: 1},
+		{name: "truncated end marker", data: append(header(1, 1, 4, 0), 0xc0, 0, 0, 0), error: "truncated end marker", offset: 15, pixels: 1},
+		{name: "invalid end marker", data: append(header(1, 1, 4, 0), 0xc0, 0, 0, 0, 0, 0, 0, 0, 2), error: "invalid end marker", offset: 15, pixels: 1},
+		{name: "trailing data", data: append(data, 0), error: "trailing data", offset: int64(len(data)), pixels: 448 * 220},
+		{name: "truncated logo", data: data[:len(data)/2], error: "truncated", offset: -1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			in, err := inspect(bytes.NewReader(test.data))
+			if err == nil || !strings.Contains(err.Error(), test.error) || in.Offset == nil ||
+				(test.offset >= 0 && (*in.Offset != test.offset || in.Pixels != test.pixels)) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("inspect(%s)\n", test.name) +
+					fmt.Sprintf("Expected error:\t containing %q at offset %d after %d pixels\n", test.error, test.offset, test.pixels) +
+					fmt.Sprintf("Actual error:\t %v (%+v)\n", err, in)
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	data, err := os.ReadFile("../../testdata/dice.qoi")
+	if err != nil {
+		t.Fatalf("could not read file: %v\n", err)
+	}
+	truncated := filepath.Join(dir, "truncated.qoi")
+	if err := os.WriteFile(truncated, data[:3000], 0o666); err != nil {
+		t.Fatalf("could not write file: %v\n", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if status := run([]string{"-chunks", "../../testdata/dice.qoi"}, &stdout, &stderr); status != 0 {
+		t.Fatalf("run(dice.qoi) = %d, expected 0\nstderr: %s", status, stderr.String())
+	}
+	for _, s := range []string{"800x600", "4 (RGBA)", "519653 bytes", "bits/pixel", "RUN"} {
+		if !strings.Contains(stdout.String(), s) {
+			t.Errorf("run(dice.qoi) printed %q, expected it to contain %q\n", stdout.String(), s)
+		}
+	}
+
+	stdout.Reset()
+	if status := run([]string{"-json", "../../testdata/dice.qoi", truncated}, &stdout, &stderr); status != 1 {
+		t.Fatalf("run(dice.qoi, truncated.qoi) = %d, expected 1\nstderr: %s", status, stderr.String())
+	}
+	dec := json.NewDecoder(&stdout)
+	var valid, corrupt info
+	if err := dec.Decode(&valid); err != nil || valid.Width != 800 || valid.Error != "" || valid.Offset != nil || valid.Chunks != nil {
+		t.Errorf("run(-json dice.qoi) = %+v, %v, expected a valid 800x600 image without chunks\n", valid, err)
+	}
+	if err := dec.Decode(&corrupt); err != nil || corrupt.Width != 800 || corrupt.Size != 3000 || corrupt.Offset == nil || !strings.Contains(corrupt.Error, "truncated") {
+		t.Errorf("run(-json truncated.qoi) = %+v, %v, expected the header, the size and an error\n", corrupt, err)
+	}
+
+	if status := run(nil, &stdout, &stderr); status != 2 || !strings.Contains(stderr.String(), "usage") {
+		t.Errorf("run() = %d, %q, expected status 2 with the usage\n", status, stderr.String())
+	}
+}
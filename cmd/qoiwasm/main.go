@@ -0,0 +1,24 @@
+//go:build js && wasm
+
+// Command qoiwasm is a minimal WebAssembly entry point which defines the global
+// JavaScript object qoi of the qoiwasm package and keeps running to serve its
+// calls. Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o qoi.wasm ./cmd/qoiwasm
+//
+// and load it next to the wasm_exec.js of the Go distribution:
+//
+//	const go = new Go();
+//	const {instance} = await WebAssembly.instantiateStreaming(fetch("qoi.wasm"), go.importObject);
+//	go.run(instance);
+//	const imageData = await qoi.decode(new Uint8Array(await file.arrayBuffer()));
+//	canvas.getContext("2d").putImageData(imageData, 0, 0);
+package main
+
+import "github.com/LukiDS/image/qoiwasm"
+
+func main() {
+	qoiwasm.Register()
+
+	select {}
+}
@@ -0,0 +1,268 @@
+// Package convert converts image files between the formats of this module and
+// the standard library, choosing the encoder by the extension of the output.
+package convert
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/LukiDS/image/bmp"
+	_ "github.com/LukiDS/image/formats"
+	"github.com/LukiDS/image/imgconv"
+	"github.com/LukiDS/image/internal/atomicfile"
+	"github.com/LukiDS/image/pfm"
+	"github.com/LukiDS/image/pnm"
+	"github.com/LukiDS/image/qoi"
+	"github.com/LukiDS/image/wbmp"
+)
+
+// ErrUnknownFormat is returned for output files with an extension no encoder
+// is known for.
+var ErrUnknownFormat = errors.New("unknown output format")
+
+// Options are the conversion parameters.
+type Options struct {
+	// Background is the color transparent pixels are composited over for
+	// formats without an alpha channel: JPEG, PPM, PGM, PFM and WBMP.
+	// Nil means black.
+	Background color.Color
+
+	// Overwrite allows replacing an existing output file. Without it Convert
+	// fails with an error wrapping fs.ErrExist.
+	Overwrite bool
+
+	// From and To replace the extensions of the source and the destination
+	// file, which choose their formats, e.g. ".qoi". Empty means the
+	// extension of the file name.
+	From, To string
+}
+
+// encodeFunc writes m to w, converting it to a color model the format can hold.
+type encodeFunc func(w io.Writer, m image.Image, bg color.Color) error
+
+// encoders maps the lower case file extensions to their encoder.
+var encoders = map[string]encodeFunc{
+	".bmp": func(w io.Writer, m image.Image, bg color.Color) error {
+		return bmp.Encode(w, m, bmp.Options{Alpha: true})
+	},
+	".gif": func(w io.Writer, m image.Image, bg color.Color) error {
+		if _, ok := m.(*image.Paletted); !ok {
+			m = imgconv.ToPaletted(m, 256)
+		}
+		return gif.Encode(w, m, nil)
+	},
+	".jpeg": encodeJPEG,
+	".jpg":  encodeJPEG,
+	".pam": func(w io.Writer, m image.Image, bg color.Color) error {
+		return pnm.Encode(w, m, pnm.Options{PAM: true})
+	},
+	".pfm": func(w io.Writer, m image.Image, bg color.Color) error {
+		return pfm.Encode(w, imgconv.Flatten(m, bg))
+	},
+	".pgm": func(w io.Writer, m image.Image, bg color.Color) error {
+		switch m.(type) {
+		case *image.Gray, *image.Gray16:
+		default:
+			m = imgconv.ToGray(imgconv.Flatten(m, bg))
+		}
+		return pnm.Encode(w, m, pnm.Options{})
+	},
+	".png": func(w io.Writer, m image.Image, bg color.Color) error {
+		return png.Encode(w, m)
+	},
+	".ppm": func(w io.Writer, m image.Image, bg color.Color) error {
+		//the pnm encoder writes gray images as PGM
+		switch m.(type) {
+		case *image.Gray:
+			m = imgconv.ToNRGBA(m)
+		case *image.Gray16:
+			m = imgconv.ToNRGBA64(m)
+		}
+		return pnm.Encode(w, m, pnm.Options{Background: bg})
+	},
+	".qoi": func(w io.Writer, m image.Image, bg color.Color) error {
+		return qoi.Encode(w, m)
+	},
+	".wbmp": func(w io.Writer, m image.Image, bg color.Color) error {
+		return wbmp.Encode(w, imgconv.Flatten(m, bg))
+	},
+}
+
+func encodeJPEG(w io.Writer, m image.Image, bg color.Color) error {
+	return jpeg.Encode(w, imgconv.Flatten(m, bg), nil)
+}
+
+// Extensions returns the sorted output file extensions Convert knows an
+// encoder for.
+func Extensions() []string {
+	exts := make([]string, 0, len(encoders))
+	for ext := range encoders {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+
+	return exts
+}
+
+// Convert reads the image file src and writes it to dst. The format of src is
+// sniffed like Decode does, and the format of dst is chosen by its extension,
+// see Extensions. The image is converted to a color model the format can hold,
+// e.g. flattened over opts.Background for JPEG.
+//
+// The image is written to a temporary file in the directory of dst, which is
+// renamed to dst once complete, so dst is never left partially written.
+// src and dst must not be the same file.
+func Convert(src, dst string, opts Options) error {
+	ext, err := dstExt(dst, opts)
+	if err != nil {
+		return err
+	}
+	if err := checkPaths(src, dst, opts.Overwrite); err != nil {
+		return err
+	}
+
+	from := opts.From
+	if from == "" {
+		from = filepath.Ext(src)
+	}
+	m, err := decode(src, from)
+	if err != nil {
+		return err
+	}
+
+	return write(dst, ext, m, opts)
+}
+
+// WriteFile writes m to the file dst like Convert, for images which are not
+// read from a file.
+func WriteFile(dst string, m image.Image, opts Options) error {
+	ext, err := dstExt(dst, opts)
+	if err != nil {
+		return err
+	}
+	if !opts.Overwrite {
+		_, err := os.Stat(dst)
+		if err == nil {
+			return fmt.Errorf("convert %s: %w", dst, fs.ErrExist)
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	return write(dst, ext, m, opts)
+}
+
+// Decode reads an image from r. Its format is sniffed with image.Decode, which
+// knows every format registered by the formats package and the GIF, JPEG and
+// PNG formats of the standard library. WBMP images have no magic number and
+// are read if ext, the extension of their file, is ".wbmp"; XBM images cannot
+// be read.
+func Decode(r io.Reader, ext string) (image.Image, error) {
+	if strings.ToLower(ext) == ".wbmp" {
+		return wbmp.Decode(r)
+	}
+
+	m, _, err := image.Decode(r)
+	return m, err
+}
+
+// Encode writes m to w in the format of the file extension ext, converting it
+// like Convert does.
+func Encode(w io.Writer, m image.Image, ext string, opts Options) error {
+	encode, ok := encoders[strings.ToLower(ext)]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownFormat, ext)
+	}
+
+	bg := opts.Background
+	if bg == nil {
+		bg = color.Black
+	}
+
+	return encode(w, m, bg)
+}
+
+// dstExt returns the extension choosing the format of dst, which must have an
+// encoder.
+func dstExt(dst string, opts Options) (string, error) {
+	ext := opts.To
+	if ext == "" {
+		ext = filepath.Ext(dst)
+	}
+	if _, ok := encoders[strings.ToLower(ext)]; !ok {
+		return "", fmt.Errorf("convert %s: %w %q", dst, ErrUnknownFormat, ext)
+	}
+
+	return ext, nil
+}
+
+// write encodes m to the file dst through a temporary file.
+func write(dst, ext string, m image.Image, opts Options) error {
+	return atomicfile.WriteFile(dst, func(w io.Writer) error {
+		if err := Encode(w, m, ext, opts); err != nil {
+			return fmt.Errorf("encode %s: %w", dst, err)
+		}
+		return nil
+	})
+}
+
+// checkPaths rejects a dst which is src, or which exists unless overwrite is set.
+func checkPaths(src, dst string, overwrite bool) error {
+	srcAbs, err := filepath.Abs(src)
+	if err != nil {
+		return err
+	}
+	dstAbs, err := filepath.Abs(dst)
+	if err != nil {
+		return err
+	}
+	if srcAbs == dstAbs {
+		return fmt.Errorf("convert %s: source and destination are the same file", src)
+	}
+
+	dstInfo, err := os.Stat(dst)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	//links and different spellings of a path may still name the same file
+	if srcInfo, err := os.Stat(src); err == nil && os.SameFile(srcInfo, dstInfo) {
+		return fmt.Errorf("convert %s: source and destination are the same file", src)
+	}
+	if !overwrite {
+		return fmt.Errorf("convert %s: %w", dst, fs.ErrExist)
+	}
+
+	return nil
+}
+
+func decode(src, ext string) (image.Image, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m, err := Decode(bufio.NewReader(f), ext)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", src, err)
+	}
+
+	return m, nil
+}
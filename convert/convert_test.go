@@ -0,0 +1,247 @@
+package convert
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LukiDS/image/imgcmp"
+	"github.com/LukiDS/image/imgconv"
+)
+
+func TestConvert(t *testing.T) {
+	sources := []string{
+		"../testdata/qoi_logo.qoi",
+		"../testdata/qoi_logo.png",
+		"../testdata/bmp/pal8.bmp",
+		"../testdata/sgi/rgba8_rle.sgi",
+		"../testdata/tga/gray8.tga",
+	}
+
+	tests := []struct {
+		ext string
+		//lossless formats must return the flattened or unchanged source
+		lossless bool
+		flatten  bool
+	}{
+		{ext: ".qoi", lossless: true},
+		{ext: ".png", lossless: true},
+		{ext: ".bmp", lossless: true},
+		{ext: ".pam", lossless: true},
+		{ext: ".ppm", lossless: true, flatten: true},
+		{ext: ".pfm", lossless: true, flatten: true},
+		{ext: ".gif"},
+		{ext: ".jpg"},
+		{ext: ".pgm"},
+		{ext: ".wbmp"},
+	}
+
+	dir := t.TempDir()
+	for _, src := range sources {
+		expected, err := decode(src, "")
+		if err != nil {
+			t.Fatalf("could not decode file: %v\n", err)
+		}
+
+		for _, tt := range tests {
+			t.Run(filepath.Base(src)+tt.ext, func(t *testing.T) {
+				dst := filepath.Join(dir, filepath.Base(src)+tt.ext)
+				if err := Convert(src, dst, Options{}); err != nil {
+					t.Fatalf("\nConvert(%s, %s) = %v\n", src, dst, err)
+				}
+
+				actual, err := decode(dst, tt.ext)
+				if err != nil {
+					t.Fatalf("could not decode the converted file: %v\n", err)
+				}
+				if expected.Bounds().Size() != actual.Bounds().Size() {
+					t.Fatalf("\nExpected size:\t %v\nActual size:\t %v\n", expected.Bounds().Size(), actual.Bounds().Size())
+				}
+				if !tt.lossless {
+					return
+				}
+
+				ref := expected
+				if tt.flatten {
+					ref = imgconv.Flatten(expected, color.Black)
+				}
+				report, err := imgcmp.Compare(ref, actual)
+				if err != nil || !report.Equal() {
+					format := fmt.Sprintf("\n") +
+						fmt.Sprintf("Convert(%s, %s)\n", src, dst) +
+						fmt.Sprintf("Compare = (%+v,%v)\n", report, err)
+					t.Errorf(format)
+				}
+			})
+		}
+	}
+
+	//no temporary files are left behind
+	tmps, err := filepath.Glob(filepath.Join(dir, ".*.tmp"))
+	if err != nil || len(tmps) != 0 {
+		t.Errorf("\ntemporary files left: %v %v\n", tmps, err)
+	}
+}
+
+func TestConvertBackground(t *testing.T) {
+	dir := t.TempDir()
+	m := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	m.Pix[3] = 0xff
+	src := filepath.Join(dir, "pixels.qoi")
+	if err := os.WriteFile(src, encodeQOI(t, m), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(dir, "pixels.ppm")
+	if err := Convert(src, dst, Options{Background: color.White}); err != nil {
+		t.Fatalf("\nConvert(%s, %s) = %v\n", src, dst, err)
+	}
+
+	actual, err := decode(dst, "")
+	if err != nil {
+		t.Fatalf("could not decode the converted file: %v\n", err)
+	}
+	expected := []color.NRGBA{{0, 0, 0, 0xff}, {0xff, 0xff, 0xff, 0xff}}
+	for x, c := range expected {
+		if a := color.NRGBAModel.Convert(actual.At(x, 0)); a != c {
+			t.Errorf("\nPixel %d\nExpected:\t %v\nActual:\t %v\n", x, c, a)
+		}
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.png")
+	if err := os.WriteFile(existing, []byte("not an image"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.png")
+	if err := os.Symlink(existing, link); err != nil {
+		t.Skipf("could not create symlink: %v\n", err)
+	}
+	corrupt := filepath.Join(dir, "corrupt.qoi")
+	if err := os.WriteFile(corrupt, []byte("qoif"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		src, dst string
+		opts     Options
+		target   error
+	}{
+		{name: "Unknown extension", src: "../testdata/qoi_logo.qoi", dst: filepath.Join(dir, "logo.xyz"), target: ErrUnknownFormat},
+		{name: "No extension", src: "../testdata/qoi_logo.qoi", dst: filepath.Join(dir, "logo"), target: ErrUnknownFormat},
+		{name: "Same path", src: existing, dst: existing, opts: Options{Overwrite: true}},
+		{name: "Same cleaned path", src: existing, dst: filepath.Join(dir, "..", filepath.Base(dir), "existing.png"), opts: Options{Overwrite: true}},
+		{name: "Same file", src: existing, dst: link, opts: Options{Overwrite: true}},
+		{name: "Existing output", src: "../testdata/qoi_logo.qoi", dst: existing, target: fs.ErrExist},
+		{name: "Missing input", src: filepath.Join(dir, "missing.qoi"), dst: filepath.Join(dir, "missing.png"), target: fs.ErrNotExist},
+		{name: "Corrupt input", src: corrupt, dst: filepath.Join(dir, "corrupt.png")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Convert(tt.src, tt.dst, tt.opts)
+			if err == nil || (tt.target != nil && !errors.Is(err, tt.target)) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Convert(%s, %s) = %v\n", tt.src, tt.dst, err) +
+					fmt.Sprintf("Expected error:\t %v\n", tt.target)
+				t.Fatalf(format)
+			}
+
+			if !tt.opts.Overwrite && tt.target != fs.ErrExist {
+				if _, err := os.Stat(tt.dst); !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("\nfailed conversion created %s\n", tt.dst)
+				}
+			}
+		})
+	}
+
+	data, err := os.ReadFile(existing)
+	if err != nil || string(data) != "not an image" {
+		t.Errorf("\nfailed conversions changed %s\n", existing)
+	}
+
+	if err := Convert("../testdata/qoi_logo.qoi", existing, Options{Overwrite: true}); err != nil {
+		t.Errorf("\nConvert with Overwrite = %v\n", err)
+	}
+}
+
+func TestConvertFormatOptions(t *testing.T) {
+	dir := t.TempDir()
+	m := image.NewGray(image.Rect(0, 0, 3, 2))
+	m.Pix[1] = 0xff
+	src := filepath.Join(dir, "pixels")
+	if err := WriteFile(src, m, Options{To: ".wbmp"}); err != nil {
+		t.Fatalf("\nWriteFile(%s, {To: .wbmp}) = %v\n", src, err)
+	}
+
+	//the WBMP source can only be read with From
+	dst := filepath.Join(dir, "pixels.png")
+	if err := Convert(src, dst, Options{}); err == nil {
+		t.Errorf("\nConvert(%s, %s) without From succeeded\n", src, dst)
+	}
+	if err := Convert(src, dst, Options{From: ".wbmp"}); err != nil {
+		t.Fatalf("\nConvert(%s, %s, {From: .wbmp}) = %v\n", src, dst, err)
+	}
+
+	actual, err := decode(dst, "")
+	if err != nil {
+		t.Fatalf("could not decode the converted file: %v\n", err)
+	}
+	if report, err := imgcmp.Compare(m, actual); err != nil || !report.Equal() {
+		t.Errorf("\nCompare = (%+v,%v)\n", report, err)
+	}
+
+	if err := WriteFile(dst, m, Options{}); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("\nWriteFile(%s) = %v, expected %v\n", dst, err, fs.ErrExist)
+	}
+	if err := WriteFile(dst, m, Options{To: ".xyz", Overwrite: true}); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("\nWriteFile(%s, {To: .xyz}) = %v, expected %v\n", dst, err, ErrUnknownFormat)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, m, ".QOI", Options{}); err != nil {
+		t.Fatalf("\nEncode(.QOI) = %v\n", err)
+	}
+	actual, err := Decode(&buf, ".qoi")
+	if err != nil {
+		t.Fatalf("\nDecode(.qoi) = %v\n", err)
+	}
+	if report, err := imgcmp.Compare(m, actual); err != nil || !report.Equal() {
+		t.Errorf("\nCompare = (%+v,%v)\n", report, err)
+	}
+
+	if err := Encode(&buf, m, ".tga", Options{}); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("\nEncode(.tga) = %v, expected %v\n", err, ErrUnknownFormat)
+	}
+}
+
+func TestExtensions(t *testing.T) {
+	expected := "[.bmp .gif .jpeg .jpg .pam .pfm .pgm .png .ppm .qoi .wbmp]"
+	if actual := fmt.Sprint(Extensions()); actual != expected {
+		t.Errorf("\nExpected:\t %s\nActual:\t %s\n", expected, actual)
+	}
+}
+
+func encodeQOI(t testing.TB, m image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := encoders[".qoi"](&buf, m, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
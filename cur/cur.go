@@ -0,0 +1,47 @@
+// Package cur implements a decoder for Windows cursor (CUR) files.
+//
+// A cursor file holds one or more images of different sizes together with
+// the hotspot of every image, the pixel which marks the pointer position.
+// Images are stored either as PNG files or as headerless BMP files followed
+// by a 1 bit AND mask, which marks transparent pixels. Both are decoded into
+// *image.NRGBA images.
+//
+// Icon (ICO) files share the layout, but are rejected, since their
+// directory entries hold color information instead of hotspots.
+package cur
+
+import (
+	"image"
+
+	//uncompressed TGA files may start like cursors with a zero image count,
+	//importing tga registers it first, so image.Decode detects them as TGA
+	_ "github.com/LukiDS/image/tga"
+)
+
+const (
+	// curMaxSize guards against reading huge files into memory.
+	curMaxSize = 64 << 20
+
+	dirHeaderSize = 6  //size in bytes
+	dirEntrySize  = 16 //size in bytes
+
+	typeIcon   = 1
+	typeCursor = 2
+)
+
+// bitmap header fields
+const (
+	bmpFileHeaderSize = 14
+	bmpInfoHeaderSize = 40
+
+	compressionRGB       = 0
+	compressionBitfields = 3
+)
+
+const pngMagic = "\x89PNG\r\n\x1a\n"
+
+func init() {
+	//the magic matches any low byte of the image count, files without images
+	//are rejected by the decoder
+	image.RegisterFormat("cur", "\x00\x00\x02\x00?", Decode, DecodeConfig)
+}
@@ -0,0 +1,251 @@
+package cur
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+
+	"github.com/LukiDS/image/bmp"
+	"github.com/LukiDS/image/imgconv"
+)
+
+type dirEntry struct {
+	width   int
+	height  int
+	hotSpot image.Point
+	size    int
+	offset  int
+}
+
+type decoder struct {
+	buf     *bufio.Reader
+	entries []dirEntry
+	err     error
+}
+
+func (d *decoder) decodeHeader() {
+	h := make([]byte, dirHeaderSize)
+	if _, err := io.ReadFull(d.buf, h); err != nil || binary.LittleEndian.Uint16(h[0:2]) != 0 {
+		d.err = fmt.Errorf("image not valid cur file")
+		return
+	}
+
+	switch typ := binary.LittleEndian.Uint16(h[2:4]); typ {
+	case typeCursor:
+	case typeIcon:
+		d.err = fmt.Errorf("image is an ico file, not a cur file")
+		return
+	default:
+		d.err = fmt.Errorf("image not valid cur file: resource type %d", typ)
+		return
+	}
+
+	n := int(binary.LittleEndian.Uint16(h[4:6]))
+	if n == 0 {
+		d.err = fmt.Errorf("invalid cur file: no images")
+		return
+	}
+
+	dir := make([]byte, n*dirEntrySize)
+	if _, err := io.ReadFull(d.buf, dir); err != nil {
+		d.err = fmt.Errorf("invalid cur directory: %w", io.ErrUnexpectedEOF)
+		return
+	}
+
+	d.entries = make([]dirEntry, n)
+	for i := range d.entries {
+		e := dir[i*dirEntrySize : (i+1)*dirEntrySize]
+		//a size of 0 means 256 pixels
+		d.entries[i] = dirEntry{
+			width:   (int(e[0])+255)%256 + 1,
+			height:  (int(e[1])+255)%256 + 1,
+			hotSpot: image.Pt(int(binary.LittleEndian.Uint16(e[4:6])), int(binary.LittleEndian.Uint16(e[6:8]))),
+			size:    int(binary.LittleEndian.Uint32(e[8:12])),
+			offset:  int(binary.LittleEndian.Uint32(e[12:16])),
+		}
+	}
+}
+
+// largest returns the index of the first entry with the most pixels.
+func (d *decoder) largest() int {
+	best := 0
+	for i, e := range d.entries {
+		if e.width*e.height > d.entries[best].width*d.entries[best].height {
+			best = i
+		}
+	}
+
+	return best
+}
+
+// decodeImages decodes the images of all entries from the rest of the file.
+func (d *decoder) decodeImages() []image.Image {
+	if d.err != nil {
+		return nil
+	}
+
+	start := dirHeaderSize + len(d.entries)*dirEntrySize
+	rest, err := io.ReadAll(io.LimitReader(d.buf, curMaxSize))
+	if err != nil {
+		d.err = err
+		return nil
+	}
+
+	images := make([]image.Image, len(d.entries))
+	for i, e := range d.entries {
+		if e.offset < start || e.size > len(rest) || e.offset-start > len(rest)-e.size {
+			d.err = fmt.Errorf("invalid cur directory: image %d at offset %d with %d bytes outside the file", i, e.offset, e.size)
+			return nil
+		}
+
+		data := rest[e.offset-start : e.offset-start+e.size]
+		if bytes.HasPrefix(data, []byte(pngMagic)) {
+			m, err := png.Decode(bytes.NewReader(data))
+			if err != nil {
+				d.err = fmt.Errorf("invalid cur image %d: %w", i, err)
+				return nil
+			}
+			images[i] = imgconv.ToNRGBA(m)
+			continue
+		}
+
+		if images[i], err = decodeDIB(data); err != nil {
+			d.err = fmt.Errorf("invalid cur image %d: %w", i, err)
+			return nil
+		}
+	}
+
+	return images
+}
+
+// decodeDIB decodes a BMP without file header whose height counts both the
+// color bitmap and the AND mask following it. The file header is recreated
+// so that the bmp package decodes the color bitmap, before the mask is applied.
+func decodeDIB(data []byte) (*image.NRGBA, error) {
+	if len(data) < bmpInfoHeaderSize {
+		return nil, fmt.Errorf("truncated bitmap header")
+	}
+
+	info := append([]byte(nil), data[:bmpInfoHeaderSize]...)
+	infoSize := int(binary.LittleEndian.Uint32(info[0:4]))
+	height := int(int32(binary.LittleEndian.Uint32(info[8:12])))
+	bpp := int(binary.LittleEndian.Uint16(info[14:16]))
+	compression := binary.LittleEndian.Uint32(info[16:20])
+	colors := int(binary.LittleEndian.Uint32(info[32:36]))
+
+	if height <= 0 || height%2 != 0 || infoSize < bmpInfoHeaderSize || infoSize > len(data) {
+		return nil, fmt.Errorf("invalid bitmap header")
+	}
+	//compressed bitmaps are not allowed, since the mask follows the pixels
+	if compression != compressionRGB && compression != compressionBitfields {
+		return nil, fmt.Errorf("unsupported bitmap compression %d", compression)
+	}
+	binary.LittleEndian.PutUint32(info[8:12], uint32(height/2))
+
+	//the pixels follow the info header, the bitfield masks and the color table
+	offset := infoSize
+	if compression == compressionBitfields && infoSize == bmpInfoHeaderSize {
+		offset += 12
+	}
+	if bpp <= 8 {
+		if colors == 0 || colors > 1<<bpp {
+			colors = 1 << bpp
+		}
+		offset += 4 * colors
+	}
+
+	h := make([]byte, bmpFileHeaderSize)
+	h[0], h[1] = 'B', 'M'
+	binary.LittleEndian.PutUint32(h[2:6], uint32(len(h)+len(data)))
+	binary.LittleEndian.PutUint32(h[10:14], uint32(len(h)+offset))
+
+	src, err := bmp.Decode(io.MultiReader(bytes.NewReader(h), bytes.NewReader(info), bytes.NewReader(data[bmpInfoHeaderSize:])))
+	if err != nil {
+		return nil, err
+	}
+	m := imgconv.ToNRGBA(src)
+
+	//rows of the AND mask are padded to 32 bits and stored bottom-up like the pixels
+	b := m.Bounds()
+	pixelSize := ((bpp*b.Dx() + 31) / 32) * 4 * b.Dy()
+	maskStride := ((b.Dx() + 31) / 32) * 4
+	if offset+pixelSize+maskStride*b.Dy() > len(data) {
+		return nil, fmt.Errorf("truncated AND mask: %w", io.ErrUnexpectedEOF)
+	}
+
+	for y := 0; y < b.Dy(); y++ {
+		row := data[offset+pixelSize+(b.Dy()-1-y)*maskStride:]
+		i := m.PixOffset(0, y)
+		for x := 0; x < b.Dx(); x, i = x+1, i+4 {
+			if row[x/8]&(0x80>>(x%8)) != 0 {
+				m.Pix[i+3] = 0
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// DecodeConfig returns the color model and dimensions of the largest image
+// in a cursor file, as given by its directory, without decoding the images.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	d := decoder{
+		buf: bufio.NewReader(r),
+	}
+
+	d.decodeHeader()
+	if d.err != nil {
+		return image.Config{}, d.err
+	}
+
+	e := d.entries[d.largest()]
+	return image.Config{
+		ColorModel: color.NRGBAModel,
+		Width:      e.width,
+		Height:     e.height,
+	}, nil
+}
+
+// Decode reads a cursor file from r and returns its largest image.
+func Decode(r io.Reader) (image.Image, error) {
+	d := decoder{
+		buf: bufio.NewReader(r),
+	}
+
+	d.decodeHeader()
+	images := d.decodeImages()
+
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	return images[d.largest()], nil
+}
+
+// DecodeAll reads a cursor file from r and returns all of its images in
+// directory order together with their hotspots. The hotspot with the same
+// index as an image is its pointer position, relative to the top left corner.
+func DecodeAll(r io.Reader) ([]image.Image, []image.Point, error) {
+	d := decoder{
+		buf: bufio.NewReader(r),
+	}
+
+	d.decodeHeader()
+	images := d.decodeImages()
+
+	if d.err != nil {
+		return nil, nil, d.err
+	}
+
+	hotSpots := make([]image.Point, len(d.entries))
+	for i, e := range d.entries {
+		hotSpots[i] = e.hotSpot
+	}
+
+	return images, hotSpots, nil
+}
@@ -0,0 +1,252 @@
+package cur
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/LukiDS/image/imgtest"
+)
+
+// curEntry describes an image of a cursor file for generateCUR.
+type curEntry struct {
+	width   byte
+	height  byte
+	hotSpot image.Point
+	data    []byte
+}
+
+// generateCUR writes a cursor file of the resource type typ containing the entries.
+func generateCUR(t testing.TB, typ uint16, entries ...curEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, []uint16{0, typ, uint16(len(entries))})
+
+	offset := dirHeaderSize + len(entries)*dirEntrySize
+	for _, e := range entries {
+		buf.Write([]byte{e.width, e.height, 0, 0})
+		binary.Write(&buf, binary.LittleEndian, []uint16{uint16(e.hotSpot.X), uint16(e.hotSpot.Y)})
+		binary.Write(&buf, binary.LittleEndian, []uint32{uint32(len(e.data)), uint32(offset)})
+		offset += len(e.data)
+	}
+	for _, e := range entries {
+		buf.Write(e.data)
+	}
+
+	return buf.Bytes()
+}
+
+// generateDIB returns a 24 bit bitmap without file header, followed by its AND mask.
+func generateDIB(width, height int, pixels, mask []byte) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, []uint32{bmpInfoHeaderSize, uint32(width), uint32(2 * height)})
+	binary.Write(&buf, binary.LittleEndian, []uint16{1, 24})
+	binary.Write(&buf, binary.LittleEndian, []uint32{compressionRGB, 0, 0, 0, 0, 0})
+	buf.Write(pixels)
+	buf.Write(mask)
+
+	return buf.Bytes()
+}
+
+func TestDecodeWithTestFiles(t *testing.T) {
+	filenames, err := filepath.Glob("../testdata/cur/*.cur")
+	if err != nil {
+		t.Fatalf("could not find files: %v\n", err)
+	}
+
+	for _, name := range filenames {
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			curFile, err := os.Open(name)
+			if err != nil {
+				t.Fatalf("could not read file: %v\n", err)
+			}
+			defer curFile.Close()
+
+			img, err := Decode(curFile)
+			if err != nil {
+				t.Fatalf("could not decode file: %v\n", err)
+			}
+
+			pngFile, err := os.Open(strings.TrimSuffix(name, filepath.Ext(name)) + ".png")
+			if err != nil {
+				t.Fatalf("could not read file: %v\n", err)
+			}
+			defer pngFile.Close()
+
+			ref, err := png.Decode(pngFile)
+			if err != nil {
+				t.Fatalf("could not decode file: %v\n", err)
+			}
+
+			if _, ok := img.(*image.NRGBA); !ok {
+				t.Fatalf("File %s: expected an *image.NRGBA image, got %T\n", name, img)
+			}
+			imgtest.AssertEqual(t, ref, img)
+		})
+	}
+}
+
+func TestDecodeAll(t *testing.T) {
+	tests := []struct {
+		name     string
+		sizes    []image.Point
+		hotSpots []image.Point
+	}{
+		{name: "arrow_mono.cur", sizes: []image.Point{{32, 32}}, hotSpots: []image.Point{{0, 0}}},
+		{name: "circle_32bpp.cur", sizes: []image.Point{{32, 32}}, hotSpots: []image.Point{{16, 16}}},
+		{name: "sizes.cur", sizes: []image.Point{{16, 16}, {48, 48}}, hotSpots: []image.Point{{3, 4}, {9, 12}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("../testdata/cur", test.name))
+			if err != nil {
+				t.Fatalf("could not read file: %v\n", err)
+			}
+
+			images, hotSpots, err := DecodeAll(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("DecodeAll: unexpected error: %v\n", err)
+			}
+
+			if len(images) != len(test.sizes) || fmt.Sprint(hotSpots) != fmt.Sprint(test.hotSpots) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("DecodeAll(%s)\n", test.name) +
+					fmt.Sprintf("Expected:\t %d images with hotspots %v\n", len(test.sizes), test.hotSpots) +
+					fmt.Sprintf("Actual:\t %d images with hotspots %v\n", len(images), hotSpots)
+				t.Fatalf(format)
+			}
+
+			for i, m := range images {
+				if m.Bounds().Size() != test.sizes[i] {
+					t.Errorf("DecodeAll(%s): image %d has size %v, expected %v\n", test.name, i, m.Bounds().Size(), test.sizes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeMask(t *testing.T) {
+	//2x2 pixels, rows of 6 bytes padded to 8, stored bottom-up
+	pixels := []byte{
+		/* bottom row */ 0x03, 0x02, 0x01, 0x00, 0x00, 0xff, 0, 0,
+		/* top row    */ 0xff, 0x00, 0x00, 0x00, 0xff, 0x00, 0, 0,
+	}
+	mask := []byte{
+		/* bottom row */ 0x40, 0, 0, 0,
+		/* top row    */ 0x80, 0, 0, 0,
+	}
+	data := generateCUR(t, typeCursor, curEntry{width: 2, height: 2, hotSpot: image.Pt(1, 0), data: generateDIB(2, 2, pixels, mask)})
+
+	actual, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Decode: unexpected error: %v\n", err)
+	}
+
+	expected := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	expected.SetNRGBA(0, 0, color.NRGBA{0, 0, 255, 0})
+	expected.SetNRGBA(1, 0, color.NRGBA{0, 255, 0, 255})
+	expected.SetNRGBA(0, 1, color.NRGBA{1, 2, 3, 255})
+	expected.SetNRGBA(1, 1, color.NRGBA{255, 0, 0, 0})
+	imgtest.AssertEqual(t, expected, actual)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	valid := generateDIB(1, 1, make([]byte, 4), make([]byte, 4))
+	pngData := func() []byte {
+		var buf bytes.Buffer
+		png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 1, 1)))
+		return buf.Bytes()
+	}()
+	rle := generateDIB(1, 1, make([]byte, 4), make([]byte, 4))
+	rle[16] = 1
+	odd := generateDIB(1, 1, make([]byte, 4), make([]byte, 4))
+	odd[8] = 3
+
+	tests := []struct {
+		name  string
+		data  []byte
+		error string
+	}{
+		{name: "empty file", data: nil, error: "not valid cur"},
+		{name: "icon", data: generateCUR(t, typeIcon, curEntry{width: 1, height: 1, data: valid}), error: "ico file"},
+		{name: "unknown type", data: generateCUR(t, 3, curEntry{width: 1, height: 1, data: valid}), error: "resource type 3"},
+		{name: "no images", data: generateCUR(t, typeCursor), error: "no images"},
+		{name: "truncated directory", data: generateCUR(t, typeCursor, curEntry{width: 1, height: 1, data: valid})[:12], error: "unexpected EOF"},
+		{name: "image beyond the file", data: generateCUR(t, typeCursor, curEntry{width: 1, height: 1, data: valid})[:30], error: "outside the file"},
+		{name: "truncated bitmap header", data: generateCUR(t, typeCursor, curEntry{width: 1, height: 1, data: valid[:20]}), error: "truncated bitmap header"},
+		{name: "odd bitmap height", data: generateCUR(t, typeCursor, curEntry{width: 1, height: 1, data: odd}), error: "invalid bitmap header"},
+		{name: "compressed bitmap", data: generateCUR(t, typeCursor, curEntry{width: 1, height: 1, data: rle}), error: "compression 1"},
+		{name: "truncated mask", data: generateCUR(t, typeCursor, curEntry{width: 1, height: 1, data: valid[:len(valid)-2]}), error: "truncated AND mask"},
+		{name: "corrupt png", data: generateCUR(t, typeCursor, curEntry{width: 1, height: 1, data: pngData[:20]}), error: "invalid cur image 0"},
+		{name: "second image invalid", data: generateCUR(t, typeCursor, curEntry{width: 1, height: 1, data: valid}, curEntry{width: 1, height: 1, data: odd}), error: "invalid cur image 1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Decode(bytes.NewReader(test.data))
+			if err == nil || !strings.Contains(err.Error(), test.error) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Decode(%s)\n", test.name) +
+					fmt.Sprintf("Expected error:\t containing %q\n", test.error) +
+					fmt.Sprintf("Actual error:\t %v\n", err)
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+func TestDecodeConfig(t *testing.T) {
+	data := generateCUR(t, typeCursor, curEntry{width: 32, height: 32}, curEntry{width: 0, height: 0}, curEntry{width: 48, height: 48})
+
+	actual, err := DecodeConfig(bytes.NewReader(data))
+	expected := image.Config{ColorModel: color.NRGBAModel, Width: 256, Height: 256}
+	if err != nil || actual != expected {
+		t.Errorf("DecodeConfig() = (%+v, %v), expected %+v\n", actual, err, expected)
+	}
+
+	data, err = os.ReadFile("../testdata/cur/sizes.cur")
+	if err != nil {
+		t.Fatalf("could not read file: %v\n", err)
+	}
+	imgtest.RequireFormat(t, bytes.NewReader(data), "cur", image.Rect(0, 0, 48, 48))
+
+	//a zero image count is rejected, unless the file is an uncompressed TGA
+	data = generateCUR(t, typeCursor)
+	if _, format, err := image.DecodeConfig(bytes.NewReader(data)); format != "cur" || err == nil || !strings.Contains(err.Error(), "no images") {
+		t.Errorf("image.DecodeConfig(no images) = (%q, %v), expected a cur error about no images\n", format, err)
+	}
+	tga := []byte{0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 1, 0, 24, 0x20, 1, 2, 3}
+	imgtest.RequireFormat(t, bytes.NewReader(tga), "tga", image.Rect(0, 0, 1, 1))
+}
+
+func TestDecodeCorruptFiles(t *testing.T) {
+	imgtest.DecodeAllCorrupt(t, "../testdata/cur/*.cur", 2000, 0, func(r io.Reader) ([]image.Image, error) {
+		images, _, err := DecodeAll(r)
+		return images, err
+	})
+}
+
+func BenchmarkDecode(b *testing.B) {
+	data, err := os.ReadFile("../testdata/cur/circle_32bpp.cur")
+	if err != nil {
+		b.Fatalf("could not read file: %v\n", err)
+	}
+
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Decode(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+// Package formats registers the decoders of all format packages of this module
+// with the image package. Import it for its side effect,
+//
+//	import _ "github.com/LukiDS/image/formats"
+//
+// to let image.Decode and image.DecodeConfig recognize every format. Programs
+// which only need some formats should import those packages instead, e.g. the
+// qoi package, which does not depend on any other format package.
+//
+// The wbmp and xbm formats have no magic number to detect them by, so they are
+// not registered; use their Decode functions directly.
+package formats
+
+import (
+	_ "github.com/LukiDS/image/bmp"
+	_ "github.com/LukiDS/image/cur"
+	_ "github.com/LukiDS/image/hdr"
+	_ "github.com/LukiDS/image/pcx"
+	_ "github.com/LukiDS/image/pfm"
+	_ "github.com/LukiDS/image/pnm"
+	_ "github.com/LukiDS/image/qoi"
+	_ "github.com/LukiDS/image/sgi"
+	_ "github.com/LukiDS/image/tga"
+)
+
+// names holds the format names registered by the imported packages.
+var names = []string{"bmp", "cur", "hdr", "pcx", "pfm", "pnm", "qoi", "sgi", "tga"}
+
+// Names returns the sorted names of the formats registered by this package,
+// as returned by image.Decode. The image package offers no way to list all
+// registered formats, so formats registered elsewhere, like the ones of the
+// standard library, are not included.
+func Names() []string {
+	return append([]string(nil), names...)
+}
@@ -0,0 +1,85 @@
+package formats
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"os"
+	"sort"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name          string
+		file          string
+		data          []byte
+		width, height int
+	}{
+		{name: "bmp", file: "../testdata/bmp/pal8.bmp"},
+		{name: "cur", file: "../testdata/cur/arrow_mono.cur"},
+		{name: "hdr", file: "../testdata/hdr/gradient_rle.hdr"},
+		{name: "pcx", file: "../testdata/pcx/rgb24.pcx"},
+		{name: "pfm", file: "../testdata/pfm/color_le.pfm"},
+		{name: "pnm", data: []byte("P5\n2 1\n255\n\x00\xff"), width: 2, height: 1},
+		{name: "qoi", file: "../testdata/qoi_logo.qoi"},
+		{name: "sgi", file: "../testdata/sgi/rgb8_rle.sgi"},
+		//uncompressed TGA files start with the same four bytes as CUR files
+		{name: "tga", file: "../testdata/tga/rgb24.tga"},
+	}
+
+	var tested []string
+	for _, tt := range tests {
+		tested = append(tested, tt.name)
+
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.data
+			if tt.file != "" {
+				var err error
+				if data, err = os.ReadFile(tt.file); err != nil {
+					t.Fatalf("could not read file: %v\n", err)
+				}
+			}
+
+			config, name, err := image.DecodeConfig(bytes.NewReader(data))
+			if err != nil || name != tt.name {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("DecodeConfig(%s) = (%+v,%q,%v)\n", tt.name, config, name, err) +
+					fmt.Sprintf("Expected format:\t %s\n", tt.name)
+				t.Fatalf(format)
+			}
+
+			m, name, err := image.Decode(bytes.NewReader(data))
+			if err != nil || name != tt.name {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Decode(%s) = (%T,%q,%v)\n", tt.name, m, name, err) +
+					fmt.Sprintf("Expected format:\t %s\n", tt.name)
+				t.Fatalf(format)
+			}
+
+			if b := m.Bounds(); b.Dx() != config.Width || b.Dy() != config.Height {
+				t.Errorf("\nDecode returned a %v image, DecodeConfig a %dx%d image\n", b, config.Width, config.Height)
+			}
+			if tt.width != 0 && (config.Width != tt.width || config.Height != tt.height) {
+				t.Errorf("\nExpected size:\t %dx%d\nActual size:\t %dx%d\n", tt.width, tt.height, config.Width, config.Height)
+			}
+		})
+	}
+
+	if fmt.Sprint(tested) != fmt.Sprint(Names()) {
+		t.Errorf("\nExpected names:\t %v\nActual names:\t %v\n", tested, Names())
+	}
+}
+
+func TestNames(t *testing.T) {
+	names := Names()
+	if !sort.StringsAreSorted(names) {
+		t.Errorf("\nnames are not sorted: %v\n", names)
+	}
+
+	//the returned slice is a copy
+	names[0] = ""
+	if Names()[0] == "" {
+		t.Errorf("\nNames returned its internal slice\n")
+	}
+}
@@ -0,0 +1,305 @@
+// Package gallery generates static HTML galleries of QOI images.
+//
+// Generate writes a thumbnail of every QOI image of a directory tree and an
+// index.html showing the thumbnails in a grid, each linked to its original
+// and labeled with its dimensions, file size and compression ratio. Files
+// which cannot be decoded are listed in the page with their error.
+package gallery
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"html/template"
+	"image"
+	"image/png"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/LukiDS/image/imgconv"
+	"github.com/LukiDS/image/qoi"
+)
+
+// Options are the parameters of Generate.
+type Options struct {
+	// MaxThumb is the largest width and height of the thumbnails.
+	// Zero means 256.
+	MaxThumb int
+
+	// ThumbFormat is the format of the thumbnails, "png" or "qoi". Empty
+	// means "png". Browsers cannot show QOI thumbnails yet, but other QOI
+	// viewers load them faster.
+	ThumbFormat string
+
+	// Title is the title of the page. Empty means the name of the source
+	// directory.
+	Title string
+
+	// Workers is the number of images processed at the same time. Zero or
+	// less means runtime.GOMAXPROCS(0).
+	Workers int
+}
+
+// Entry describes an image of the gallery.
+type Entry struct {
+	// Name is the slash-separated path of the image in the source directory.
+	Name string
+	// Link is the slash-separated path of the image relative to the page.
+	Link string
+	// Thumb is the slash-separated path of the thumbnail relative to the
+	// page. It is empty if the image could not be decoded.
+	Thumb string
+
+	Width  int
+	Height int
+	// Size is the file size in bytes.
+	Size int64
+
+	// Err is the reason the image could not be decoded.
+	Err error
+}
+
+// Ratio returns the file size relative to the size of the uncompressed
+// RGBA pixels.
+func (e Entry) Ratio() float64 {
+	raw := 4 * float64(e.Width) * float64(e.Height)
+	if raw == 0 {
+		return 0
+	}
+
+	return float64(e.Size) / raw
+}
+
+// Generate writes thumbnails of the .qoi files below src into the thumbs
+// directory of out and an index.html listing them into out, which is created
+// if necessary. It returns the entries of the page sorted by name.
+// Undecodable files are reported in their entry, not as an error; the
+// returned error is set if the gallery cannot be written or ctx is done.
+func Generate(ctx context.Context, src, out string, opts Options) ([]Entry, error) {
+	if opts.MaxThumb == 0 {
+		opts.MaxThumb = 256
+	}
+	if opts.MaxThumb < 0 {
+		return nil, fmt.Errorf("gallery: invalid thumbnail size %d", opts.MaxThumb)
+	}
+	switch opts.ThumbFormat {
+	case "":
+		opts.ThumbFormat = "png"
+	case "png", "qoi":
+	default:
+		return nil, fmt.Errorf("gallery: unknown thumbnail format %q", opts.ThumbFormat)
+	}
+	if opts.Title == "" {
+		opts.Title = filepath.Base(src)
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	var names []string
+	err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(p), ".qoi") {
+			names = append(names, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gallery: %w", err)
+	}
+	sort.Strings(names)
+
+	entries := make([]Entry, len(names))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				entries[i] = process(src, out, names[i], opts)
+			}
+		}()
+	}
+
+feed:
+	for i := range names {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("gallery: %w", err)
+	}
+
+	if err := writeIndex(filepath.Join(out, "index.html"), opts.Title, entries); err != nil {
+		return nil, fmt.Errorf("gallery: %w", err)
+	}
+
+	return entries, nil
+}
+
+// process reads the metadata of the image file and writes its thumbnail.
+func process(src, out, file string, opts Options) Entry {
+	var e Entry
+	name, err := filepath.Rel(src, file)
+	if err != nil {
+		e.Err = err
+		return e
+	}
+	e.Name = filepath.ToSlash(name)
+
+	if link, err := filepath.Rel(out, file); err == nil {
+		e.Link = filepath.ToSlash(link)
+	} else if abs, err := filepath.Abs(file); err == nil {
+		e.Link = "file://" + filepath.ToSlash(abs)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		e.Err = err
+		return e
+	}
+	defer f.Close()
+
+	if info, err := f.Stat(); err == nil {
+		e.Size = info.Size()
+	}
+
+	config, err := qoi.DecodeConfig(bufio.NewReader(f))
+	if err != nil {
+		e.Err = err
+		return e
+	}
+	e.Width, e.Height = config.Width, config.Height
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		e.Err = err
+		return e
+	}
+	m, err := qoi.Decode(f)
+	if err != nil {
+		e.Err = err
+		return e
+	}
+
+	thumb := filepath.Join("thumbs", strings.TrimSuffix(name, filepath.Ext(name))+"."+opts.ThumbFormat)
+	if err := writeThumb(filepath.Join(out, thumb), m, opts); err != nil {
+		e.Err = err
+		return e
+	}
+	e.Thumb = filepath.ToSlash(thumb)
+
+	return e
+}
+
+// writeThumb scales m down to fit into opts.MaxThumb and writes it to name.
+func writeThumb(name string, m image.Image, opts Options) error {
+	t, err := imgconv.Thumbnail(m, opts.MaxThumb, opts.MaxThumb)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(name), 0o777); err != nil {
+		return err
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	if opts.ThumbFormat == "qoi" {
+		err = qoi.Encode(w, t)
+	} else {
+		err = png.Encode(w, t)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// writeIndex writes the page listing entries to name.
+func writeIndex(name, title string, entries []Entry) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0o777); err != nil {
+		return err
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	err = indexTemplate.Execute(w, struct {
+		Title   string
+		Entries []Entry
+	}{title, entries})
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+var indexTemplate = template.Must(template.New("index").Funcs(template.FuncMap{
+	"percent": func(f float64) string { return fmt.Sprintf("%.1f%%", 100*f) },
+	"kb":      func(n int64) string { return fmt.Sprintf("%.1f KB", float64(n)/1024) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; background: #222; color: #ddd; }
+.grid { display: grid; grid-template-columns: repeat(auto-fill, minmax(280px, 1fr)); gap: 16px; }
+.entry { background: #333; padding: 8px; text-align: center; word-break: break-all; }
+.entry img { max-width: 100%; background: repeating-conic-gradient(#888 0 25%, #aaa 0 50%) 0 0 / 16px 16px; }
+.entry .meta { font-size: small; color: #aaa; }
+.entry.corrupt { background: #633; }
+a { color: #9cf; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{len .Entries}} images</p>
+<div class="grid">
+{{- range .Entries}}
+{{- if .Err}}
+<div class="entry corrupt">
+<a href="{{.Link}}">{{.Name}}</a>
+<div class="meta">corrupt: {{.Err}}</div>
+</div>
+{{- else}}
+<div class="entry">
+<a href="{{.Link}}"><img src="{{.Thumb}}" alt="{{.Name}}"></a>
+<div><a href="{{.Link}}">{{.Name}}</a></div>
+<div class="meta">{{.Width}}x{{.Height}}, {{kb .Size}}, {{percent .Ratio}} of RGBA</div>
+</div>
+{{- end}}
+{{- end}}
+</div>
+</body>
+</html>
+`))
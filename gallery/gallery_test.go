@@ -0,0 +1,142 @@
+package gallery
+
+import (
+	"context"
+	"errors"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// copyFile copies the file src into dir under the slash-separated name.
+func copyFile(t testing.TB, src, dir, name string) {
+	t.Helper()
+
+	data, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatalf("could not read file: %v\n", err)
+	}
+	name = filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(name), 0o777); err != nil {
+		t.Fatalf("could not create directory: %v\n", err)
+	}
+	if err := os.WriteFile(name, data, 0o666); err != nil {
+		t.Fatalf("could not write file: %v\n", err)
+	}
+}
+
+// newSource returns a directory with two testdata images, a corrupt image
+// and a file which is not an image.
+func newSource(t testing.TB) string {
+	t.Helper()
+
+	src := t.TempDir()
+	copyFile(t, "../testdata/dice.qoi", src, "dice.qoi")
+	copyFile(t, "../testdata/qoi_logo.qoi", src, "sub/logo.QOI")
+	if err := os.WriteFile(filepath.Join(src, "sub", "broken.qoi"), []byte("qoif\x00\x00"), 0o666); err != nil {
+		t.Fatalf("could not write file: %v\n", err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "notes.txt"), []byte("<b>"), 0o666); err != nil {
+		t.Fatalf("could not write file: %v\n", err)
+	}
+
+	return src
+}
+
+func TestGenerate(t *testing.T) {
+	src := newSource(t)
+	out := filepath.Join(t.TempDir(), "gallery")
+
+	for _, format := range []string{"png", "qoi"} {
+		t.Run(format, func(t *testing.T) {
+			entries, err := Generate(context.Background(), src, out, Options{MaxThumb: 100, ThumbFormat: format, Title: "Captures & more", Workers: 2})
+			if err != nil {
+				t.Fatalf("Generate: unexpected error: %v\n", err)
+			}
+
+			if len(entries) != 3 || entries[0].Name != "dice.qoi" || entries[1].Name != "sub/broken.qoi" || entries[2].Name != "sub/logo.QOI" {
+				t.Fatalf("Generate returned %+v, expected dice.qoi, sub/broken.qoi and sub/logo.QOI\n", entries)
+			}
+			if entries[1].Err == nil || entries[1].Thumb != "" {
+				t.Errorf("entry of the corrupt file = %+v, expected an error and no thumbnail\n", entries[1])
+			}
+
+			for _, e := range []Entry{entries[0], entries[2]} {
+				info, err := os.Stat(filepath.Join(src, filepath.FromSlash(e.Name)))
+				if err != nil {
+					t.Fatalf("could not stat file: %v\n", err)
+				}
+				if e.Err != nil || e.Size != info.Size() || e.Ratio() <= 0 || e.Ratio() >= 1 {
+					t.Errorf("entry %+v, expected the size %d and a ratio in (0, 1)\n", e, info.Size())
+				}
+
+				//the link leads from the page to the original
+				if _, err := os.Stat(filepath.Join(out, filepath.FromSlash(e.Link))); err != nil {
+					t.Errorf("link %q of %s does not resolve: %v\n", e.Link, e.Name, err)
+				}
+
+				f, err := os.Open(filepath.Join(out, filepath.FromSlash(e.Thumb)))
+				if err != nil {
+					t.Fatalf("could not read thumbnail: %v\n", err)
+				}
+				config, format, err := image.DecodeConfig(f)
+				f.Close()
+				if err != nil || format != filepath.Ext(e.Thumb)[1:] || config.Width > 100 || config.Height > 100 || (config.Width != 100 && config.Height != 100) {
+					t.Errorf("thumbnail %s = %+v, %q, %v, expected a %s image fitting 100x100\n", e.Thumb, config, format, err, filepath.Ext(e.Thumb)[1:])
+				}
+			}
+
+			index, err := os.ReadFile(filepath.Join(out, "index.html"))
+			if err != nil {
+				t.Fatalf("could not read index: %v\n", err)
+			}
+			for _, s := range []string{
+				"<title>Captures &amp; more</title>",
+				`<img src="` + entries[0].Thumb + `" alt="dice.qoi">`,
+				"800x600",
+				"448x220",
+				`class="entry corrupt"`,
+				"sub/broken.qoi",
+			} {
+				if !strings.Contains(string(index), s) {
+					t.Errorf("index.html does not contain %q:\n%s", s, index)
+				}
+			}
+			if strings.Contains(string(index), "notes.txt") {
+				t.Errorf("index.html lists notes.txt:\n%s", index)
+			}
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	src := newSource(t)
+
+	if _, err := Generate(context.Background(), src, t.TempDir(), Options{ThumbFormat: "gif"}); err == nil {
+		t.Errorf("Generate(gif) returned no error\n")
+	}
+	if _, err := Generate(context.Background(), src, t.TempDir(), Options{MaxThumb: -1}); err == nil {
+		t.Errorf("Generate(MaxThumb -1) returned no error\n")
+	}
+	if _, err := Generate(context.Background(), filepath.Join(src, "missing"), t.TempDir(), Options{}); err == nil {
+		t.Errorf("Generate(missing directory) returned no error\n")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Generate(ctx, src, t.TempDir(), Options{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Generate(canceled) = %v, expected context.Canceled\n", err)
+	}
+}
+
+func TestEntryRatio(t *testing.T) {
+	e := Entry{Width: 10, Height: 5, Size: 100}
+	if r := e.Ratio(); r != 0.5 {
+		t.Errorf("Ratio() = %v, expected 0.5\n", r)
+	}
+	if r := (Entry{Size: 100}).Ratio(); r != 0 {
+		t.Errorf("Ratio() without dimensions = %v, expected 0\n", r)
+	}
+}
@@ -0,0 +1,397 @@
+package hdr
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Header holds the variables of a Radiance header.
+type Header struct {
+	// Format is the pixel format, "32-bit_rle_rgbe" if the header has none.
+	Format string
+
+	// Exposure is the product of all EXPOSURE variables, or 1 if there are
+	// none. Dividing the pixel values by Exposure gives the original radiance.
+	Exposure float64
+
+	// Software is the program which created the file, if given.
+	Software string
+
+	// Vars holds the value of every variable by name. Variables appearing
+	// several times keep their last value.
+	Vars map[string]string
+}
+
+type hdrHeader struct {
+	Header
+	width  int
+	height int
+	// columns is set if the scanlines are image columns instead of rows.
+	columns bool
+	// topDown and leftRight give the direction in which scanlines and
+	// the pixels of a scanline advance.
+	topDown   bool
+	leftRight bool
+}
+
+// scanlines returns the number and length of the scanlines.
+func (h hdrHeader) scanlines() (n, length int) {
+	if h.columns {
+		return h.width, h.height
+	}
+
+	return h.height, h.width
+}
+
+type decoder struct {
+	m   *RGB
+	buf *bufio.Reader
+	h   hdrHeader
+	err error
+	// line holds the RGBE values of the scanline being read.
+	line []byte
+}
+
+// readLine returns the next header line without its line ending.
+func (d *decoder) readLine() (string, error) {
+	line, err := d.buf.ReadSlice('\n')
+	switch {
+	case errors.Is(err, bufio.ErrBufferFull):
+		return "", fmt.Errorf("invalid hdr header: line too long")
+	case err != nil:
+		return "", fmt.Errorf("invalid hdr header: %w", io.ErrUnexpectedEOF)
+	}
+
+	return string(bytes.TrimRight(line, "\r\n")), nil
+}
+
+func (d *decoder) decodeHeader() {
+	line, err := d.readLine()
+	if err != nil || !strings.HasPrefix(line, hdrMagic) {
+		d.err = fmt.Errorf("image not valid hdr file")
+		return
+	}
+
+	d.h.Format = formatRGBE
+	d.h.Exposure = 1
+	d.h.Vars = make(map[string]string)
+
+	//the variables end with an empty line
+	for {
+		if line, d.err = d.readLine(); d.err != nil {
+			return
+		}
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			//Radiance keeps the commands which created the image in the header
+			continue
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		d.h.Vars[name] = value
+
+		switch name {
+		case "FORMAT":
+			d.h.Format = value
+		case "EXPOSURE":
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil || v <= 0 || math.IsInf(v, 0) {
+				d.err = fmt.Errorf("invalid hdr header: exposure %q", value)
+				return
+			}
+			d.h.Exposure *= v
+		case "SOFTWARE":
+			d.h.Software = value
+		}
+	}
+
+	switch d.h.Format {
+	case formatRGBE:
+	case formatXYZE:
+		d.err = fmt.Errorf("unsupported hdr format %s", d.h.Format)
+		return
+	default:
+		d.err = fmt.Errorf("invalid hdr header: format %q", d.h.Format)
+		return
+	}
+
+	if line, d.err = d.readLine(); d.err != nil {
+		return
+	}
+	d.decodeResolution(line)
+}
+
+// decodeResolution parses the resolution line, e.g. "-Y 480 +X 640" for the
+// standard orientation of rows from top to bottom with pixels from left to
+// right. If X comes first, the scanlines are columns.
+func (d *decoder) decodeResolution(line string) {
+	f := strings.Fields(line)
+	if len(f) != 4 || len(f[0]) != 2 || len(f[2]) != 2 || f[0][1] == f[2][1] {
+		d.err = fmt.Errorf("invalid hdr resolution %q", line)
+		return
+	}
+
+	for i := 0; i < 4; i += 2 {
+		v, err := strconv.Atoi(f[i+1])
+		if err != nil {
+			d.err = fmt.Errorf("invalid hdr resolution %q", line)
+			return
+		}
+
+		switch f[i] {
+		case "-Y", "+Y":
+			d.h.height, d.h.topDown = v, f[i][0] == '-'
+		case "-X", "+X":
+			d.h.width, d.h.leftRight = v, f[i][0] == '+'
+		default:
+			d.err = fmt.Errorf("invalid hdr resolution %q", line)
+			return
+		}
+	}
+	d.h.columns = f[0][1] == 'X'
+
+	if d.h.width <= 0 || d.h.height <= 0 || d.h.width > hdrMaxPixels/d.h.height {
+		d.err = fmt.Errorf("invalid hdr image size: %dx%d, must be non-empty and at most %d pixels", d.h.width, d.h.height, hdrMaxPixels)
+		return
+	}
+}
+
+func (d *decoder) decode() {
+	if d.err != nil {
+		return
+	}
+
+	//the mantissas are scaled by 2^(exponent-128) / 256, with 0 for a zero exponent
+	var scale [256]float32
+	for e := 1; e < 256; e++ {
+		scale[e] = float32(math.Ldexp(1, e-136))
+	}
+
+	//the pixels are appended in the order of the scanlines, to a buffer which
+	//grows with the decoded data
+	n, length := d.h.scanlines()
+	capacity := d.h.width * d.h.height
+	if capacity > hdrInitialPixels {
+		capacity = hdrInitialPixels
+	}
+	pix := make([]float32, 0, 3*capacity)
+	for s := 0; s < n; s++ {
+		line := d.readScanline(length)
+		if d.err != nil {
+			return
+		}
+
+		for i := 0; i < length; i++ {
+			p := line[4*i : 4*i+4 : 4*i+4]
+			f := scale[p[3]]
+			pix = append(pix, (float32(p[0])+0.5)*f, (float32(p[1])+0.5)*f, (float32(p[2])+0.5)*f)
+		}
+	}
+
+	d.m = d.orient(pix)
+}
+
+// orient returns the image of the pixels in pix, which are in the order of the
+// scanlines.
+func (d *decoder) orient(pix []float32) *RGB {
+	r := image.Rect(0, 0, d.h.width, d.h.height)
+	if !d.h.columns && d.h.topDown && d.h.leftRight {
+		return &RGB{Pix: pix, Stride: 3 * d.h.width, Rect: r}
+	}
+
+	m := NewRGB(r)
+	n, length := d.h.scanlines()
+	for s := 0; s < n; s++ {
+		for i := 0; i < length; i++ {
+			x, y := d.position(s, i)
+			j, k := m.PixOffset(x, y), 3*(s*length+i)
+			copy(m.Pix[j:j+3], pix[k:k+3])
+		}
+	}
+
+	return m
+}
+
+// position returns the image coordinates of the pixel i of scanline s.
+func (d *decoder) position(s, i int) (x, y int) {
+	if d.h.columns {
+		x, y = s, i
+	} else {
+		x, y = i, s
+	}
+
+	if !d.h.leftRight {
+		x = d.h.width - 1 - x
+	}
+	if !d.h.topDown {
+		y = d.h.height - 1 - y
+	}
+
+	return x, y
+}
+
+// readScanline reads the RGBE values of a scanline of n pixels into d.line and
+// returns them. Run-length encoded scanlines start with the bytes 2, 2 and the
+// big endian scanline length, followed by the runs of every component in turn.
+func (d *decoder) readScanline(n int) []byte {
+	if n < minRLELength || n > maxRLELength {
+		return d.readFlat(n, 0)
+	}
+
+	if cap(d.line) < 4*n {
+		d.line = make([]byte, 4*n)
+	}
+	line := d.line[:4*n]
+	if _, err := io.ReadFull(d.buf, line[:4]); err != nil {
+		d.err = fmt.Errorf("truncated hdr pixel data: %w", io.ErrUnexpectedEOF)
+		return nil
+	}
+	if line[0] != 2 || line[1] != 2 || line[2]&0x80 != 0 {
+		return d.readFlat(n, 1)
+	}
+	if l := int(line[2])<<8 | int(line[3]); l != n {
+		d.err = fmt.Errorf("invalid hdr scanline: length %d, expected %d", l, n)
+		return nil
+	}
+
+	literal := make([]byte, 128)
+	for c := 0; c < 4; c++ {
+		for i := 0; i < n; {
+			count, err := d.buf.ReadByte()
+			if err != nil {
+				d.err = fmt.Errorf("truncated hdr pixel data: %w", io.ErrUnexpectedEOF)
+				return nil
+			}
+
+			//counts above 128 are runs of a single value
+			run := count > 128
+			if run {
+				count -= 128
+			}
+			if count == 0 || int(count) > n-i {
+				d.err = fmt.Errorf("invalid hdr scanline: run of %d values exceeds the scanline", count)
+				return nil
+			}
+
+			v := literal[:1]
+			if !run {
+				v = literal[:count]
+			}
+			if _, err := io.ReadFull(d.buf, v); err != nil {
+				d.err = fmt.Errorf("truncated hdr pixel data: %w", io.ErrUnexpectedEOF)
+				return nil
+			}
+
+			for j := 0; j < int(count); j, i = j+1, i+1 {
+				if run {
+					line[4*i+c] = v[0]
+				} else {
+					line[4*i+c] = v[j]
+				}
+			}
+		}
+	}
+
+	return line
+}
+
+// readFlat reads a scanline of n RGBE values into d.line and returns them.
+// The first read pixels are already in d.line. The old run-length encoding is
+// supported: a pixel with the values 1, 1, 1 repeats the previous pixel as
+// often as its exponent says, and consecutive repeats shift their counts 8 bits
+// further left. The line grows with the decoded pixels, since scanlines which
+// are not run-length encoded may be as long as the header claims.
+func (d *decoder) readFlat(n, read int) []byte {
+	line := d.line[:4*read]
+	var p [4]byte
+	shift := 0
+	for i := 0; i < n; {
+		if i < read {
+			copy(p[:], line[4*i:])
+		} else if _, err := io.ReadFull(d.buf, p[:]); err != nil {
+			d.err = fmt.Errorf("truncated hdr pixel data: %w", io.ErrUnexpectedEOF)
+			return nil
+		}
+
+		if p[0] != 1 || p[1] != 1 || p[2] != 1 {
+			if i >= read {
+				line = append(line, p[:]...)
+			}
+			i, shift = i+1, 0
+			continue
+		}
+
+		count := int(p[3]) << shift
+		if i == 0 || shift > 16 || count > n-i {
+			d.err = fmt.Errorf("invalid hdr scanline: repeat of %d pixels exceeds the scanline", count)
+			return nil
+		}
+		copy(p[:], line[4*i-4:])
+		for j := 0; j < count; j++ {
+			line = append(line, p[:]...)
+		}
+		i, shift = i+count, shift+8
+	}
+	d.line = line
+
+	return line
+}
+
+// DecodeConfig returns the color model and dimensions of a Radiance image
+// without decoding the entire image.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	d := decoder{
+		buf: bufio.NewReader(r),
+	}
+
+	d.decodeHeader()
+	if d.err != nil {
+		return image.Config{}, d.err
+	}
+
+	return image.Config{
+		ColorModel: ColorModel,
+		Width:      d.h.width,
+		Height:     d.h.height,
+	}, nil
+}
+
+// Decode reads a Radiance image from r and returns it as an *RGB image.
+func Decode(r io.Reader) (image.Image, error) {
+	m, _, err := DecodeWithHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// DecodeWithHeader is like Decode, but returns the *RGB image together with
+// the variables of its header.
+func DecodeWithHeader(r io.Reader) (*RGB, Header, error) {
+	d := decoder{
+		buf: bufio.NewReader(r),
+	}
+
+	d.decodeHeader()
+	d.decode()
+
+	if d.err != nil {
+		return nil, Header{}, d.err
+	}
+
+	return d.m, d.h.Header, nil
+}
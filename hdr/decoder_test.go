@@ -0,0 +1,249 @@
+package hdr
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/LukiDS/image/imgtest"
+)
+
+// generateHDR writes a Radiance header with the resolution line res followed by pixels.
+func generateHDR(res string, pixels ...byte) []byte {
+	return append([]byte("#?RADIANCE\nFORMAT=32-bit_rle_rgbe\n\n"+res+"\n"), pixels...)
+}
+
+func TestDecodeWithTestFiles(t *testing.T) {
+	tests := []struct {
+		x, y     int
+		expected Color
+	}{
+		{x: 0, y: 0, expected: Color{128.5 / 128, 128.5 / 128, 128.5 / 128}},
+		{x: 149, y: 3, expected: Color{128.5 / 128, 128.5 / 128, 128.5 / 128}},
+		{x: 150, y: 0, expected: Color{150.5 / 64, 0.5 / 64, 105.5 / 64}},
+		{x: 299, y: 2, expected: Color{43.5 / 64, 100.5 / 64, 212.5 / 64}},
+	}
+
+	var images []*RGB
+	for _, name := range []string{"gradient_flat.hdr", "gradient_rle.hdr"} {
+		data, err := os.ReadFile(filepath.Join("../testdata/hdr", name))
+		if err != nil {
+			t.Fatalf("could not read file: %v\n", err)
+		}
+
+		m, h, err := DecodeWithHeader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("File %s: could not decode file: %v\n", name, err)
+		}
+
+		if m.Bounds() != image.Rect(0, 0, 300, 4) || h.Exposure != 1 || h.Software != "gen" || h.Format != formatRGBE || h.Vars["EXPOSURE"] != "0.5" {
+			t.Fatalf("File %s: DecodeWithHeader() = (%v, %+v), expected a 300x4 image with exposure 1\n", name, m.Bounds(), h)
+		}
+
+		for _, test := range tests {
+			if actual := m.RGBAt(test.x, test.y); actual != test.expected {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("File %s: RGBAt(%d, %d)\n", name, test.x, test.y) +
+					fmt.Sprintf("Expected:\t %v\n", test.expected) +
+					fmt.Sprintf("Actual:\t %v\n", actual)
+				t.Errorf(format)
+			}
+		}
+		images = append(images, m)
+	}
+
+	//both files hold the same pixels
+	for i := range images[0].Pix {
+		if images[0].Pix[i] != images[1].Pix[i] {
+			t.Fatalf("flat and run-length encoded files differ at index %d: %v != %v\n", i, images[0].Pix[i], images[1].Pix[i])
+		}
+	}
+}
+
+func TestDecodeOrientation(t *testing.T) {
+	//four pixels in file order with the red values 1, 2, 3 and 4
+	pixels := []byte{128, 0, 0, 129, 128, 0, 0, 130, 192, 0, 0, 130, 128, 0, 0, 131}
+
+	tests := []struct {
+		res      string
+		size     image.Point
+		expected []float32 //red values of the image rows
+	}{
+		{res: "-Y 2 +X 2", size: image.Pt(2, 2), expected: []float32{1, 2, 3, 4}},
+		{res: "+Y 2 +X 2", size: image.Pt(2, 2), expected: []float32{3, 4, 1, 2}},
+		{res: "-Y 2 -X 2", size: image.Pt(2, 2), expected: []float32{2, 1, 4, 3}},
+		{res: "+Y 2 -X 2", size: image.Pt(2, 2), expected: []float32{4, 3, 2, 1}},
+		{res: "+X 2 -Y 2", size: image.Pt(2, 2), expected: []float32{1, 3, 2, 4}},
+		{res: "-X 2 +Y 2", size: image.Pt(2, 2), expected: []float32{4, 2, 3, 1}},
+		{res: "-Y 1 +X 4", size: image.Pt(4, 1), expected: []float32{1, 2, 3, 4}},
+		{res: "+X 4 -Y 1", size: image.Pt(4, 1), expected: []float32{1, 2, 3, 4}},
+		{res: "-X 1 +Y 4", size: image.Pt(1, 4), expected: []float32{4, 3, 2, 1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.res, func(t *testing.T) {
+			m, err := Decode(bytes.NewReader(generateHDR(test.res, pixels...)))
+			if err != nil {
+				t.Fatalf("Decode: unexpected error: %v\n", err)
+			}
+
+			rgb := m.(*RGB)
+			if rgb.Bounds().Size() != test.size {
+				t.Fatalf("Decode(%s): size %v, expected %v\n", test.res, rgb.Bounds().Size(), test.size)
+			}
+
+			var actual []float32
+			for y := 0; y < test.size.Y; y++ {
+				for x := 0; x < test.size.X; x++ {
+					//round away the half step added to the mantissas
+					actual = append(actual, float32(int(rgb.RGBAt(x, y).R)))
+				}
+			}
+
+			if fmt.Sprint(actual) != fmt.Sprint(test.expected) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Decode(%s)\n", test.res) +
+					fmt.Sprintf("Expected:\t %v\n", test.expected) +
+					fmt.Sprintf("Actual:\t %v\n", actual)
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+func TestDecodeOldRLE(t *testing.T) {
+	//a pixel repeated 2 + (1 << 8) times fills the scanline of 259 pixels
+	data := generateHDR("-Y 1 +X 259", 128, 64, 32, 129, 1, 1, 1, 2, 1, 1, 1, 1)
+
+	m, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Decode: unexpected error: %v\n", err)
+	}
+
+	expected := Color{128.5 / 128, 64.5 / 128, 32.5 / 128}
+	for x := 0; x < 259; x++ {
+		if actual := m.(*RGB).RGBAt(x, 0); actual != expected {
+			t.Fatalf("Decode(old RLE): pixel %d is %v, expected %v\n", x, actual, expected)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	rle := func(runs ...byte) []byte {
+		return generateHDR("-Y 1 +X 8", append([]byte{2, 2, 0, 8}, runs...)...)
+	}
+	run := []byte{0x88, 0}
+
+	tests := []struct {
+		name  string
+		data  []byte
+		error string
+	}{
+		{name: "empty file", data: nil, error: "not valid hdr"},
+		{name: "wrong magic", data: []byte("RADIANCE\n\n-Y 1 +X 1\n"), error: "not valid hdr"},
+		{name: "xyze", data: []byte("#?RADIANCE\nFORMAT=32-bit_rle_xyze\n\n-Y 1 +X 1\n"), error: "unsupported hdr format"},
+		{name: "unknown format", data: []byte("#?RADIANCE\nFORMAT=rgb\n\n-Y 1 +X 1\n"), error: "format \"rgb\""},
+		{name: "invalid exposure", data: []byte("#?RADIANCE\nEXPOSURE=-1\n\n-Y 1 +X 1\n"), error: "exposure"},
+		{name: "missing resolution", data: []byte("#?RADIANCE\nFORMAT=32-bit_rle_rgbe\n\n"), error: "unexpected EOF"},
+		{name: "line too long", data: []byte("#?RADIANCE\n" + strings.Repeat("#", 5000) + "\n"), error: "line too long"},
+		{name: "short resolution", data: generateHDR("-Y 1"), error: "resolution"},
+		{name: "same axis twice", data: generateHDR("-Y 1 +Y 1"), error: "resolution"},
+		{name: "unknown axis", data: generateHDR("-Z 1 +X 1"), error: "resolution"},
+		{name: "zero size", data: generateHDR("-Y 0 +X 1"), error: "image size"},
+		{name: "absurd size", data: generateHDR("-Y 100000 +X 100000"), error: "image size"},
+		{name: "truncated flat pixels", data: generateHDR("-Y 1 +X 2", 1, 2, 3, 4), error: "truncated"},
+		{name: "repeat without a previous pixel", data: generateHDR("-Y 1 +X 2", 1, 1, 1, 1), error: "repeat"},
+		{name: "repeat exceeding the scanline", data: generateHDR("-Y 1 +X 2", 1, 2, 3, 4, 1, 1, 1, 2), error: "repeat"},
+		{name: "scanline length mismatch", data: generateHDR("-Y 1 +X 8", 2, 2, 0, 9), error: "length 9"},
+		{name: "run exceeding the scanline", data: rle(0x89, 0), error: "exceeds"},
+		{name: "literal exceeding the scanline", data: rle(9, 0, 0, 0, 0, 0, 0, 0, 0, 0), error: "exceeds"},
+		{name: "zero count", data: rle(0), error: "exceeds"},
+		{name: "truncated runs", data: rle(append(run, run...)...), error: "truncated"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Decode(bytes.NewReader(test.data))
+			if err == nil || !strings.Contains(err.Error(), test.error) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Decode(%s)\n", test.name) +
+					fmt.Sprintf("Expected error:\t containing %q\n", test.error) +
+					fmt.Sprintf("Actual error:\t %v\n", err)
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+func TestDecodeTruncatedLargeImage(t *testing.T) {
+	//images of 400M pixels taking 4.8GB, with run-length encoded, flat and
+	//column scanlines, but without pixels
+	for _, res := range []string{"-Y 20000 +X 20000", "-Y 1 +X 400000000", "+X 20000 -Y 20000"} {
+		data := generateHDR(res, 2, 2, 0x4e, 0x20)
+
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, err := Decode(bytes.NewReader(data))
+		runtime.ReadMemStats(&after)
+
+		if err == nil || !strings.Contains(err.Error(), "truncated") {
+			t.Errorf("Decode(%s): %v, expected a truncated error\n", res, err)
+		}
+
+		//the image grows with the decoded scanlines
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 64<<20 {
+			t.Errorf("Decode(%s) allocated %d bytes for a %d byte input\n", res, allocated, len(data))
+		}
+	}
+}
+
+func TestDecodeConfig(t *testing.T) {
+	actual, err := DecodeConfig(bytes.NewReader(generateHDR("+X 640 -Y 480")))
+	expected := image.Config{ColorModel: ColorModel, Width: 640, Height: 480}
+	if err != nil || actual.Width != expected.Width || actual.Height != expected.Height || actual.ColorModel == nil {
+		t.Errorf("DecodeConfig() = (%+v, %v), expected %+v\n", actual, err, expected)
+	}
+
+	imgtest.RequireFormat(t, bytes.NewReader(generateHDR("-Y 1 +X 1", 128, 128, 128, 129)), "hdr", image.Rect(0, 0, 1, 1))
+}
+
+func TestColor(t *testing.T) {
+	tests := []struct {
+		color    Color
+		expected [4]uint32
+	}{
+		{color: Color{0, 0.5, 1}, expected: [4]uint32{0, 0x8000, 0xffff, 0xffff}},
+		{color: Color{-1, 2, 1e30}, expected: [4]uint32{0, 0xffff, 0xffff, 0xffff}},
+	}
+
+	for _, test := range tests {
+		r, g, b, a := test.color.RGBA()
+		if actual := [4]uint32{r, g, b, a}; actual != test.expected {
+			t.Errorf("%v.RGBA() = %v, expected %v\n", test.color, actual, test.expected)
+		}
+	}
+}
+
+func TestDecodeCorruptFiles(t *testing.T) {
+	imgtest.DecodeCorrupt(t, "../testdata/hdr/*.hdr", 2000, 0, Decode)
+}
+
+func BenchmarkDecode(b *testing.B) {
+	data, err := os.ReadFile("../testdata/hdr/gradient_rle.hdr")
+	if err != nil {
+		b.Fatalf("could not read file: %v\n", err)
+	}
+
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Decode(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+// Package hdr implements a decoder for Radiance HDR (RGBE) images.
+//
+// Radiance images store linear, unbounded RGB values with a shared exponent
+// per pixel, as uncompressed or run-length encoded scanlines. They are
+// decoded into *RGB images, which keep the float values. The header
+// variables, e.g. the exposure, are returned by DecodeWithHeader.
+//
+// Only the RGBE pixel format is supported, files in the XYZE format are
+// rejected.
+package hdr
+
+import (
+	"image"
+)
+
+const (
+	// hdrMaxPixels limits the size of the images claimed by headers.
+	hdrMaxPixels = 400_000_000
+
+	// hdrInitialPixels caps the pixels allocated before any data is decoded,
+	// so a crafted header cannot claim gigabytes for a file of a few bytes.
+	hdrInitialPixels = 1 << 20
+
+	hdrMagic = "#?"
+
+	formatRGBE = "32-bit_rle_rgbe"
+	formatXYZE = "32-bit_rle_xyze"
+)
+
+// scanlines shorter or longer than these limits cannot be run-length encoded
+const (
+	minRLELength = 8
+	maxRLELength = 0x7fff
+)
+
+func init() {
+	image.RegisterFormat("hdr", hdrMagic, Decode, DecodeConfig)
+}
@@ -0,0 +1,106 @@
+package hdr
+
+import (
+	"image"
+	"image/color"
+)
+
+// Color is a linear RGB color with float32 components. The components are
+// not limited to [0, 1], since they describe radiance values.
+type Color struct {
+	R, G, B float32
+}
+
+// RGBA returns the color clamped to [0, 1] and scaled to 16 bits, without
+// any tone mapping. The color is always opaque.
+func (c Color) RGBA() (r, g, b, a uint32) {
+	return clamp16(c.R), clamp16(c.G), clamp16(c.B), 0xffff
+}
+
+func clamp16(v float32) uint32 {
+	switch {
+	case v >= 1:
+		return 0xffff
+	case v > 0:
+		return uint32(v*0xffff + 0.5)
+	default:
+		//also covers NaN
+		return 0
+	}
+}
+
+// ColorModel converts any color to a Color, treating its components as
+// linear values. Transparent colors are not premultiplied back, so they
+// become darker.
+var ColorModel color.Model = color.ModelFunc(colorModel)
+
+func colorModel(c color.Color) color.Color {
+	if c, ok := c.(Color); ok {
+		return c
+	}
+
+	r, g, b, _ := c.RGBA()
+	return Color{float32(r) / 0xffff, float32(g) / 0xffff, float32(b) / 0xffff}
+}
+
+// RGB is an in-memory image of Color values.
+type RGB struct {
+	// Pix holds the image's pixels as R, G, B triples. The pixel at (x, y)
+	// starts at Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)*3].
+	Pix []float32
+	// Stride is the Pix stride (in float32 values, not bytes) between
+	// vertically adjacent pixels.
+	Stride int
+	// Rect is the image's bounds.
+	Rect image.Rectangle
+}
+
+// NewRGB returns a new RGB image with the given bounds.
+func NewRGB(r image.Rectangle) *RGB {
+	return &RGB{
+		Pix:    make([]float32, 3*r.Dx()*r.Dy()),
+		Stride: 3 * r.Dx(),
+		Rect:   r,
+	}
+}
+
+func (p *RGB) ColorModel() color.Model {
+	return ColorModel
+}
+
+func (p *RGB) Bounds() image.Rectangle {
+	return p.Rect
+}
+
+func (p *RGB) At(x, y int) color.Color {
+	return p.RGBAt(x, y)
+}
+
+// RGBAt returns the color of the pixel at (x, y), or the zero Color if the
+// point is outside of the image.
+func (p *RGB) RGBAt(x, y int) Color {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return Color{}
+	}
+
+	i := p.PixOffset(x, y)
+	s := p.Pix[i : i+3 : i+3]
+	return Color{s[0], s[1], s[2]}
+}
+
+// SetRGB sets the color of the pixel at (x, y), if the point is inside of the image.
+func (p *RGB) SetRGB(x, y int, c Color) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+
+	i := p.PixOffset(x, y)
+	s := p.Pix[i : i+3 : i+3]
+	s[0], s[1], s[2] = c.R, c.G, c.B
+}
+
+// PixOffset returns the index of the first element of Pix that corresponds
+// to the pixel at (x, y).
+func (p *RGB) PixOffset(x, y int) int {
+	return (y-p.Rect.Min.Y)*p.Stride + (x-p.Rect.Min.X)*3
+}
@@ -0,0 +1,183 @@
+// Package httpqoi serves QOI images over HTTP with content negotiation.
+//
+// Clients which list image/qoi in their Accept header receive the QOI files
+// as they are stored. All other clients, which includes every browser today,
+// receive the images transcoded to PNG. Wildcards like image/* do not select
+// QOI, since clients sending them rarely understand it.
+package httpqoi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/LukiDS/image/qoi"
+)
+
+// ContentType is the media type of QOI images.
+const ContentType = "image/qoi"
+
+// Handler serves the .qoi files of FS. The ETag of a response is derived
+// from the contents of the QOI file, with a suffix for the PNG variant, so
+// conditional requests with If-None-Match work for both variants. Range and
+// HEAD requests are handled by http.ServeContent.
+//
+// If a file cannot be transcoded, the handler responds with 500 Internal
+// Server Error and logs the cause to ErrorLog, or the standard logger if
+// ErrorLog is nil.
+type Handler struct {
+	// FS holds the files, which are looked up by the URL path without its
+	// leading slash.
+	FS fs.FS
+
+	// Next serves all requests for files without the .qoi extension.
+	// Nil means they are answered with 404 Not Found.
+	Next http.Handler
+
+	// ErrorLog logs the causes of failed requests.
+	ErrorLog *log.Logger
+}
+
+// FileServer returns a handler serving all files of fsys, where .qoi files
+// are negotiated by a Handler and all other files are served by
+// http.FileServer.
+func FileServer(fsys fs.FS) http.Handler {
+	return &Handler{FS: fsys, Next: http.FileServer(http.FS(fsys))}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if !strings.EqualFold(path.Ext(name), ".qoi") {
+		if h.Next != nil {
+			h.Next.ServeHTTP(w, r)
+			return
+		}
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	f, err := h.FS.Open(name)
+	if err != nil {
+		h.serveError(w, name, err)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		h.serveError(w, name, err)
+		return
+	}
+	if info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		h.serveError(w, name, err)
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	tag := hex.EncodeToString(sum[:16])
+	contentType := ContentType
+	if !acceptsQOI(r.Header.Get("Accept")) {
+		tag += "-png"
+		contentType = "image/png"
+	}
+	etag := strconv.Quote(tag)
+
+	header := w.Header()
+	header.Add("Vary", "Accept")
+	header.Set("ETag", etag)
+	header.Set("Content-Type", contentType)
+
+	//answer conditional requests before spending time on transcoding
+	if matchETag(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if contentType != ContentType {
+		var buf bytes.Buffer
+		if err := qoi.ToPNG(&buf, bytes.NewReader(data)); err != nil {
+			header.Del("ETag")
+			header.Del("Content-Type")
+			h.logf("httpqoi: transcoding %s: %v", name, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		data = buf.Bytes()
+	}
+
+	http.ServeContent(w, r, name, info.ModTime(), bytes.NewReader(data))
+}
+
+// serveError responds to a failure to read the file name.
+func (h *Handler) serveError(w http.ResponseWriter, name string, err error) {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	case errors.Is(err, fs.ErrPermission):
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	default:
+		h.logf("httpqoi: reading %s: %v", name, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) logf(format string, args ...interface{}) {
+	if h.ErrorLog != nil {
+		h.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// acceptsQOI reports whether the Accept header lists image/qoi with a
+// non-zero quality.
+func acceptsQOI(accept string) bool {
+	for _, r := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(r))
+		if err != nil || mediaType != ContentType {
+			continue
+		}
+		if q, ok := params["q"]; ok {
+			if v, err := strconv.ParseFloat(q, 64); err != nil || v <= 0 {
+				continue
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// matchETag reports whether the If-None-Match header matches etag, using
+// the weak comparison required for If-None-Match.
+func matchETag(ifNoneMatch, etag string) bool {
+	for _, t := range strings.Split(ifNoneMatch, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == "*" || t == etag {
+			return true
+		}
+	}
+
+	return false
+}
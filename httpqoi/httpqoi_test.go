@@ -0,0 +1,197 @@
+package httpqoi
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/LukiDS/image/qoi"
+)
+
+// newHandler returns a handler serving the QOI logo, a corrupt QOI file and
+// a text file, with its error log written to logs.
+func newHandler(t testing.TB, logs *bytes.Buffer) *Handler {
+	t.Helper()
+
+	data, err := os.ReadFile("../testdata/qoi_logo.qoi")
+	if err != nil {
+		t.Fatalf("could not read file: %v\n", err)
+	}
+	modTime := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	fsys := fstest.MapFS{
+		"images/logo.qoi":    {Data: data, ModTime: modTime},
+		"images/corrupt.qoi": {Data: data[:100], ModTime: modTime},
+		"readme.txt":         {Data: []byte("hello"), ModTime: modTime},
+		"archive.qoi/a.txt":  {Data: []byte("not an image"), ModTime: modTime},
+	}
+
+	return &Handler{
+		FS:       fsys,
+		Next:     http.FileServer(http.FS(fsys)),
+		ErrorLog: log.New(logs, "", 0),
+	}
+}
+
+// serve sends a request to h and returns the response.
+func serve(h http.Handler, method, target string, header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestServeQOI(t *testing.T) {
+	var logs bytes.Buffer
+	h := newHandler(t, &logs)
+	data, _ := os.ReadFile("../testdata/qoi_logo.qoi")
+
+	for _, accept := range []string{"image/qoi", "image/png, image/qoi;q=0.5", "text/html, IMAGE/QOI"} {
+		w := serve(h, http.MethodGet, "/images/logo.qoi", map[string]string{"Accept": accept})
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != ContentType || !bytes.Equal(w.Body.Bytes(), data) {
+			t.Errorf("GET with Accept %q = %d %q, %d bytes, expected the QOI file\n", accept, w.Code, w.Header().Get("Content-Type"), w.Body.Len())
+		}
+		if w.Header().Get("Content-Length") != fmt.Sprint(len(data)) || w.Header().Get("Vary") != "Accept" {
+			t.Errorf("GET with Accept %q has the headers %v\n", accept, w.Header())
+		}
+	}
+}
+
+func TestServePNG(t *testing.T) {
+	var logs bytes.Buffer
+	h := newHandler(t, &logs)
+
+	expected, err := qoi.Decode(bytes.NewReader(mustRead(t, "../testdata/qoi_logo.qoi")))
+	if err != nil {
+		t.Fatalf("could not decode file: %v\n", err)
+	}
+
+	for _, accept := range []string{"", "image/*", "*/*", "image/qoi;q=0", "image/webp,image/apng,image/*,*/*;q=0.8"} {
+		w := serve(h, http.MethodGet, "/images/logo.qoi", map[string]string{"Accept": accept})
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" {
+			t.Fatalf("GET with Accept %q = %d %q, expected a PNG image\n", accept, w.Code, w.Header().Get("Content-Type"))
+		}
+		if w.Header().Get("Content-Length") != fmt.Sprint(w.Body.Len()) {
+			t.Errorf("GET with Accept %q: Content-Length %q for %d bytes\n", accept, w.Header().Get("Content-Length"), w.Body.Len())
+		}
+
+		m, err := png.Decode(w.Body)
+		if err != nil {
+			t.Fatalf("GET with Accept %q: could not decode the body: %v\n", accept, err)
+		}
+		if m.Bounds() != expected.Bounds() || m.At(200, 100) != expected.At(200, 100) {
+			t.Errorf("GET with Accept %q returned a different image\n", accept)
+		}
+	}
+}
+
+func TestServeConditional(t *testing.T) {
+	var logs bytes.Buffer
+	h := newHandler(t, &logs)
+
+	qoiTag := serve(h, http.MethodGet, "/images/logo.qoi", map[string]string{"Accept": "image/qoi"}).Header().Get("ETag")
+	pngTag := serve(h, http.MethodGet, "/images/logo.qoi", nil).Header().Get("ETag")
+	if qoiTag == "" || pngTag == "" || qoiTag == pngTag {
+		t.Fatalf("ETags %q and %q, expected two different tags\n", qoiTag, pngTag)
+	}
+
+	tests := []struct {
+		name     string
+		header   map[string]string
+		expected int
+	}{
+		{name: "matching QOI", header: map[string]string{"Accept": "image/qoi", "If-None-Match": qoiTag}, expected: http.StatusNotModified},
+		{name: "matching PNG", header: map[string]string{"If-None-Match": pngTag}, expected: http.StatusNotModified},
+		{name: "weak tag in a list", header: map[string]string{"If-None-Match": `"x", W/` + pngTag}, expected: http.StatusNotModified},
+		{name: "tag of the other variant", header: map[string]string{"If-None-Match": qoiTag}, expected: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		w := serve(h, http.MethodGet, "/images/logo.qoi", test.header)
+		if w.Code != test.expected || (w.Code == http.StatusNotModified && w.Body.Len() != 0) {
+			t.Errorf("GET %s = %d with %d bytes, expected %d\n", test.name, w.Code, w.Body.Len(), test.expected)
+		}
+	}
+}
+
+func TestServeHead(t *testing.T) {
+	var logs bytes.Buffer
+	h := newHandler(t, &logs)
+
+	for _, accept := range []string{"image/qoi", ""} {
+		get := serve(h, http.MethodGet, "/images/logo.qoi", map[string]string{"Accept": accept})
+		head := serve(h, http.MethodHead, "/images/logo.qoi", map[string]string{"Accept": accept})
+		if head.Code != http.StatusOK || head.Body.Len() != 0 || head.Header().Get("Content-Length") != get.Header().Get("Content-Length") ||
+			head.Header().Get("ETag") != get.Header().Get("ETag") {
+			t.Errorf("HEAD with Accept %q = %d, %d bytes, headers %v, expected the headers of GET %v\n", accept, head.Code, head.Body.Len(), head.Header(), get.Header())
+		}
+	}
+}
+
+func TestServeErrors(t *testing.T) {
+	var logs bytes.Buffer
+	h := newHandler(t, &logs)
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		accept   string
+		expected int
+		body     string
+	}{
+		{name: "corrupt file", method: http.MethodGet, target: "/images/corrupt.qoi", expected: http.StatusInternalServerError},
+		{name: "corrupt file as QOI", method: http.MethodGet, target: "/images/corrupt.qoi", accept: "image/qoi", expected: http.StatusOK},
+		{name: "missing file", method: http.MethodGet, target: "/images/missing.qoi", expected: http.StatusNotFound},
+		{name: "directory", method: http.MethodGet, target: "/archive.qoi", expected: http.StatusNotFound},
+		{name: "escaping path", method: http.MethodGet, target: "/../images/logo.qoi", expected: http.StatusOK},
+		{name: "post", method: http.MethodPost, target: "/images/logo.qoi", expected: http.StatusMethodNotAllowed},
+		{name: "other file", method: http.MethodGet, target: "/readme.txt", expected: http.StatusOK, body: "hello"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serve(h, test.method, test.target, map[string]string{"Accept": test.accept})
+			if w.Code != test.expected || !strings.Contains(w.Body.String(), test.body) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("%s %s\n", test.method, test.target) +
+					fmt.Sprintf("Expected:\t %d with a body containing %q\n", test.expected, test.body) +
+					fmt.Sprintf("Actual:\t %d with the body %q\n", w.Code, w.Body.String())
+				t.Errorf(format)
+			}
+		})
+	}
+
+	if !strings.Contains(logs.String(), "transcoding images/corrupt.qoi") {
+		t.Errorf("logged %q, expected the cause of the failed transcoding\n", logs.String())
+	}
+	if w := serve(&Handler{FS: h.FS}, http.MethodGet, "/readme.txt", nil); w.Code != http.StatusNotFound {
+		t.Errorf("GET /readme.txt without Next = %d, expected 404\n", w.Code)
+	}
+	if w := serve(FileServer(h.FS), http.MethodGet, "/readme.txt", nil); w.Code != http.StatusOK || w.Body.String() != "hello" {
+		t.Errorf("GET /readme.txt from FileServer = %d %q, expected the file\n", w.Code, w.Body.String())
+	}
+}
+
+func mustRead(t testing.TB, name string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("could not read file: %v\n", err)
+	}
+
+	return data
+}
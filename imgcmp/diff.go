@@ -0,0 +1,73 @@
+package imgcmp
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/LukiDS/image/imgconv"
+)
+
+// DiffOptions are the parameters of the difference image.
+type DiffOptions struct {
+	// Highlight is the color of changed pixels. Nil means red.
+	Highlight color.Color
+}
+
+// Diff compares a and b like Compare and renders the differences into an
+// opaque image with the bounds of a: unchanged pixels show the luminance of
+// a, washed out to a light gray, and changed pixels are blended towards the
+// highlight color by at least a quarter and up to fully for the largest
+// channel delta of 255, so even the smallest change stays visible.
+// opts may be nil for the defaults.
+func Diff(a, b image.Image, opts *DiffOptions) (*image.NRGBA, Report, error) {
+	if err := validate(a, b); err != nil {
+		return nil, Report{}, err
+	}
+
+	var highlight color.Color = color.NRGBA{0xff, 0, 0, 0xff}
+	if opts != nil && opts.Highlight != nil {
+		highlight = opts.Highlight
+	}
+	hc := color.NRGBAModel.Convert(highlight).(color.NRGBA)
+
+	gray := imgconv.ToGray(a)
+	m := image.NewNRGBA(a.Bounds())
+	for y := m.Rect.Min.Y; y < m.Rect.Max.Y; y++ {
+		src := gray.Pix[gray.PixOffset(m.Rect.Min.X, y):]
+		dst := m.Pix[m.PixOffset(m.Rect.Min.X, y):m.PixOffset(m.Rect.Max.X, y)]
+		for i, j := 0, 0; i < len(dst); i, j = i+4, j+1 {
+			v := dimmed(src[j])
+			dst[i], dst[i+1], dst[i+2], dst[i+3] = v, v, v, 0xff
+		}
+	}
+
+	var r Report
+	err := compare(a, b, func(x, y int, delta [4]uint8) {
+		r.add(x, y, delta)
+
+		//t is the weight of the highlight color in 1/1020, at least 255/1020
+		t := 255 + 3*uint32(maxOf(delta))
+		p := m.Pix[m.PixOffset(x, y):]
+		for i, h := range []uint8{hc.R, hc.G, hc.B} {
+			p[i] = uint8((uint32(h)*t + uint32(p[i])*(1020-t) + 510) / 1020)
+		}
+	})
+
+	return m, r, err
+}
+
+// dimmed maps the luminance v into the upper quarter of the gray values.
+func dimmed(v uint8) uint8 {
+	return 0xc0 + v/4
+}
+
+func maxOf(delta [4]uint8) uint8 {
+	m := delta[0]
+	for _, d := range delta[1:] {
+		if d > m {
+			m = d
+		}
+	}
+
+	return m
+}
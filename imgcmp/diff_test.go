@@ -0,0 +1,215 @@
+package imgcmp
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LukiDS/image/imgconv"
+)
+
+func TestDiff(t *testing.T) {
+	//a gray row: black, mid gray, white and mid gray
+	a := image.NewGray(image.Rect(1, 1, 5, 2))
+	a.Pix = []uint8{0, 0x80, 0xff, 0x80}
+
+	b := image.NewNRGBA(image.Rect(1, 1, 5, 2))
+	b.Pix = []uint8{
+		0, 0, 0, 0xff,
+		0x81, 0x80, 0x80, 0xff, //delta 1
+		0xff, 0xff, 0xff, 0xff,
+		0x80, 0x80, 0x80, 0x7f, //alpha delta 128
+	}
+
+	tests := []struct {
+		name     string
+		opts     *DiffOptions
+		expected []uint8
+	}{
+		{
+			name: "default highlight",
+			opts: nil,
+			expected: []uint8{
+				0xc0, 0xc0, 0xc0, 0xff, //unchanged black
+				0xe8, 0xa7, 0xa7, 0xff, //258/1020 from 0xe0 towards red
+				0xff, 0xff, 0xff, 0xff, //unchanged white
+				0xf3, 0x54, 0x54, 0xff, //639/1020 from 0xe0 towards red
+			},
+		},
+		{
+			name: "custom highlight",
+			opts: &DiffOptions{Highlight: color.Gray{0}},
+			expected: []uint8{
+				0xc0, 0xc0, 0xc0, 0xff,
+				0xa7, 0xa7, 0xa7, 0xff,
+				0xff, 0xff, 0xff, 0xff,
+				0x54, 0x54, 0x54, 0xff,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, r, err := Diff(a, b, test.opts)
+			if err != nil {
+				t.Fatalf("Diff: unexpected error: %v\n", err)
+			}
+
+			expectedReport := Report{Differing: 2, First: image.Pt(2, 1), MaxDelta: [4]uint8{1, 0, 0, 0x80}, Bounds: image.Rect(2, 1, 5, 2)}
+			if r != expectedReport {
+				t.Errorf("Diff(%s) report = %+v, expected %+v\n", test.name, r, expectedReport)
+			}
+
+			expected := &image.NRGBA{Pix: test.expected, Stride: 16, Rect: a.Rect}
+			if m.Rect != expected.Rect {
+				t.Fatalf("Diff(%s) bounds = %v, expected %v\n", test.name, m.Rect, expected.Rect)
+			}
+			for x := m.Rect.Min.X; x < m.Rect.Max.X; x++ {
+				if e, a := expected.NRGBAAt(x, 1), m.NRGBAAt(x, 1); e != a {
+					format := fmt.Sprintf("\n") +
+						fmt.Sprintf("Diff(%s)\n", test.name) +
+						fmt.Sprintf("Assert image:\t different pixel at x=%d, y=1: Expected: %+v - Actual: %+v\n", x, e, a)
+					t.Errorf(format)
+				}
+			}
+		})
+	}
+
+	if _, _, err := Diff(a, image.NewNRGBA(image.Rect(0, 0, 4, 1)), nil); err == nil {
+		t.Errorf("Diff() of different bounds returned no error\n")
+	}
+}
+
+// diffPairs returns crafted image pairs for the golden tests of Diff.
+func diffPairs(t testing.TB) []struct {
+	name string
+	a, b image.Image
+	opts *DiffOptions
+} {
+	t.Helper()
+
+	//a single pixel of a gradient changes by the smallest possible delta
+	gradient := generateImage(t, 0, 0, 16, 8)
+	nudged := imgconv.CloneNRGBA(gradient)
+	nudged.Pix[nudged.PixOffset(5, 3)]++
+
+	//a block fades in from transparent and a row is inverted
+	photo := generateImage(t, 0, 0, 16, 16)
+	edited := imgconv.CloneNRGBA(photo)
+	for y := 4; y < 8; y++ {
+		for x := 4; x < 8; x++ {
+			edited.Pix[edited.PixOffset(x, y)+3] = uint8(0x40 * (x - 4))
+		}
+	}
+	for x := 0; x < 16; x++ {
+		c := edited.NRGBAAt(x, 12)
+		edited.SetNRGBA(x, 12, color.NRGBA{^c.R, ^c.G, ^c.B, c.A})
+	}
+
+	//images with an offset origin and a gray source
+	gray := imgconv.ToGray(generateImage(t, -4, 2, 8, 8))
+	shifted := imgconv.ToNRGBA(gray)
+	for x := -4; x < 4; x++ {
+		c := shifted.NRGBAAt(x, 2+(x+4))
+		c.G += 0x20
+		shifted.SetNRGBA(x, 2+(x+4), c)
+	}
+
+	return []struct {
+		name string
+		a, b image.Image
+		opts *DiffOptions
+	}{
+		{name: "nudged_pixel", a: gradient, b: nudged},
+		{name: "edited_blocks", a: photo, b: edited},
+		{name: "edited_blocks_blue", a: photo, b: edited, opts: &DiffOptions{Highlight: color.NRGBA{0, 0, 0xff, 0xff}}},
+		{name: "offset_diagonal", a: gray, b: shifted},
+	}
+}
+
+func TestDiffGolden(t *testing.T) {
+	for _, tt := range diffPairs(t) {
+		t.Run(tt.name, func(t *testing.T) {
+			m, r, err := Diff(tt.a, tt.b, tt.opts)
+			if err != nil {
+				t.Fatalf("Diff: unexpected error: %v\n", err)
+			}
+
+			assertDiffRules(t, tt.a, tt.b, m, tt.opts)
+
+			expectedReport, err := Compare(tt.a, tt.b)
+			if err != nil || r != expectedReport {
+				t.Errorf("\nDiff(%s) report = %+v, expected %+v\n", tt.name, r, expectedReport)
+			}
+
+			name := filepath.Join("../testdata/imgcmp", tt.name+".png")
+			f, err := os.Open(name)
+			if err != nil {
+				t.Fatalf("could not read file: %v\n", err)
+			}
+			defer f.Close()
+
+			golden, err := png.Decode(f)
+			if err != nil {
+				t.Fatalf("could not decode file: %v\n", err)
+			}
+
+			//the golden images are stored with their origin at (0, 0)
+			if equal, report := EqualTolerant(golden, imgconv.NormalizeBounds(m), 0); !equal {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Diff(%s) differs from %s\n", tt.name, name) +
+					fmt.Sprintf("Assert image:\t %+v\n", report)
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+// assertDiffRules checks the rendering rules of Diff pixel by pixel: unchanged
+// pixels are opaque light grays of the luminance of a, changed pixels lie
+// between that gray and the highlight color, at least a quarter of the way.
+func assertDiffRules(t testing.TB, a, b image.Image, m *image.NRGBA, opts *DiffOptions) {
+	t.Helper()
+
+	var highlight color.Color = color.NRGBA{0xff, 0, 0, 0xff}
+	if opts != nil && opts.Highlight != nil {
+		highlight = opts.Highlight
+	}
+	hc := color.NRGBAModel.Convert(highlight).(color.NRGBA)
+
+	na, nb, gray := imgconv.ToNRGBA(a), imgconv.ToNRGBA(b), imgconv.ToGray(a)
+	bounds := a.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := m.NRGBAAt(x, y)
+			v := 0xc0 + gray.GrayAt(x, y).Y/4
+			if c.A != 0xff {
+				t.Fatalf("\nDiff pixel at x=%d, y=%d is not opaque: %+v\n", x, y, c)
+			}
+
+			if na.NRGBAAt(x, y) == nb.NRGBAAt(x, y) {
+				if c != (color.NRGBA{v, v, v, 0xff}) {
+					t.Fatalf("\nunchanged pixel at x=%d, y=%d is %+v, expected the gray %#x\n", x, y, c, v)
+				}
+				continue
+			}
+
+			for i, ch := range [][2]uint8{{c.R, hc.R}, {c.G, hc.G}, {c.B, hc.B}} {
+				got, h := int(ch[0]), int(ch[1])
+				//a quarter of the way from the gray, rounded towards it
+				quarter := int(v) + (h-int(v))/4
+				lo, hi := quarter, h
+				if h < int(v) {
+					lo, hi = h, quarter
+				}
+				if got < lo || got > hi {
+					t.Fatalf("\nchanged pixel at x=%d, y=%d channel %d is %#x, expected in [%#x, %#x]\n", x, y, i, got, lo, hi)
+				}
+			}
+		}
+	}
+}
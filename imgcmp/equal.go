@@ -0,0 +1,60 @@
+package imgcmp
+
+import (
+	"errors"
+	"image"
+
+	"github.com/LukiDS/image/imgconv"
+)
+
+// Equal reports whether a and b have the same bounds and hold the same colors,
+// compared as in Compare. Two nil images are equal, a nil image never equals
+// a non-nil one, and two empty images are equal if their bounds are.
+func Equal(a, b image.Image) bool {
+	equal, _ := EqualTolerant(a, b, 0)
+	return equal
+}
+
+// EqualTolerant is like Equal, but pixels whose channels all differ by at most
+// maxPerChannelDelta count as equal. The Report only holds the pixels beyond
+// the tolerance, except for MaxDelta, which covers all pixels, so it also
+// tells how close images within the tolerance are.
+//
+// Images which cannot be compared pixel by pixel, because one of them is nil
+// or their bounds differ, are not equal and return a zero Report; use Compare
+// to learn the reason.
+func EqualTolerant(a, b image.Image, maxPerChannelDelta uint8) (bool, Report) {
+	if isNil(a) || isNil(b) {
+		return isNil(a) && isNil(b), Report{}
+	}
+	if a.Bounds() != b.Bounds() {
+		return false, Report{}
+	}
+	if a.Bounds().Empty() {
+		return true, Report{}
+	}
+
+	var r Report
+	err := compare(a, b, func(x, y int, delta [4]uint8) {
+		for c, d := range delta {
+			if d > r.MaxDelta[c] {
+				r.MaxDelta[c] = d
+			}
+		}
+		if delta[0] > maxPerChannelDelta || delta[1] > maxPerChannelDelta ||
+			delta[2] > maxPerChannelDelta || delta[3] > maxPerChannelDelta {
+			r.add(x, y, delta)
+		}
+	})
+	if err != nil {
+		//too large to convert
+		return false, Report{}
+	}
+
+	return r.Equal(), r
+}
+
+// isNil reports whether m is nil, including nil pointers of image types.
+func isNil(m image.Image) bool {
+	return errors.Is(imgconv.Validate(m, imgconv.DefaultMaxPixels), imgconv.ErrNilImage)
+}
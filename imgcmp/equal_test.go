@@ -0,0 +1,148 @@
+package imgcmp
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestEqualTolerant(t *testing.T) {
+	a := generateImage(t, 2, 3, 4, 3)
+
+	//channel deltas of 1, 3 and 6
+	b := generateImage(t, 2, 3, 4, 3)
+	b.SetNRGBA(2, 3, color.NRGBA{0x14 + 1, 0x3c, 0x05, 0xff})
+	b.SetNRGBA(4, 4, color.NRGBA{0x28, 0x50 - 3, 0x08, 0xff})
+	b.SetNRGBA(5, 5, color.NRGBA{0x32, 0x64, 0x0a, 0xff - 6})
+
+	gray := image.NewGray(image.Rect(0, 0, 2, 1))
+	gray.Pix = []uint8{0x40, 0xff}
+	rgba64 := image.NewRGBA64(image.Rect(0, 0, 2, 1))
+	rgba64.SetRGBA64(0, 0, color.RGBA64{0x4040, 0x4040, 0x4040, 0xffff})
+	rgba64.SetRGBA64(1, 0, color.RGBA64{0xffff, 0xffff, 0xffff, 0xffff})
+
+	//Gray16 images which only differ in the low byte of a sample
+	gray16 := image.NewGray16(image.Rect(0, 0, 2, 1))
+	gray16.Pix = []uint8{0x40, 0x40, 0xff, 0xff}
+	lowByte := image.NewGray16(image.Rect(0, 0, 2, 1))
+	lowByte.Pix = []uint8{0x40, 0x41, 0xff, 0xff}
+
+	var nilNRGBA *image.NRGBA
+
+	tests := []struct {
+		name      string
+		a, b      image.Image
+		tolerance uint8
+		expected  bool
+		report    Report
+	}{
+		{
+			name:      "identical images",
+			a:         a,
+			b:         generateImage(t, 2, 3, 4, 3),
+			tolerance: 0,
+			expected:  true,
+		},
+		{
+			name:      "different color models",
+			a:         gray,
+			b:         rgba64,
+			tolerance: 0,
+			expected:  true,
+		},
+		{
+			name:      "16 bit samples differing in the low byte",
+			a:         gray16,
+			b:         lowByte,
+			tolerance: 0,
+			expected:  false,
+			report:    Report{Differing: 1, First: image.Pt(0, 0), MaxDelta: [4]uint8{1, 1, 1, 0}, Bounds: image.Rect(0, 0, 1, 1)},
+		},
+		{
+			name:      "16 bit samples within a tolerance of 1",
+			a:         gray16,
+			b:         lowByte,
+			tolerance: 1,
+			expected:  true,
+			report:    Report{MaxDelta: [4]uint8{1, 1, 1, 0}},
+		},
+		{
+			name:      "no tolerance",
+			a:         a,
+			b:         b,
+			tolerance: 0,
+			expected:  false,
+			report:    Report{Differing: 3, First: image.Pt(2, 3), MaxDelta: [4]uint8{1, 3, 0, 6}, Bounds: image.Rect(2, 3, 6, 6)},
+		},
+		{
+			name:      "tolerance of 3",
+			a:         a,
+			b:         b,
+			tolerance: 3,
+			expected:  false,
+			report:    Report{Differing: 1, First: image.Pt(5, 5), MaxDelta: [4]uint8{1, 3, 0, 6}, Bounds: image.Rect(5, 5, 6, 6)},
+		},
+		{
+			name:      "tolerance of 6",
+			a:         a,
+			b:         b,
+			tolerance: 6,
+			expected:  true,
+			report:    Report{MaxDelta: [4]uint8{1, 3, 0, 6}},
+		},
+		{
+			name:      "different bounds",
+			a:         a,
+			b:         generateImage(t, 0, 0, 4, 3),
+			tolerance: 255,
+			expected:  false,
+		},
+		{
+			name:     "nil and image",
+			a:        nil,
+			b:        a,
+			expected: false,
+		},
+		{
+			name:     "nil pointer and image",
+			a:        a,
+			b:        nilNRGBA,
+			expected: false,
+		},
+		{
+			name:     "nil and nil pointer",
+			a:        nil,
+			b:        nilNRGBA,
+			expected: true,
+		},
+		{
+			name:     "empty images",
+			a:        image.NewNRGBA(image.Rect(1, 1, 1, 5)),
+			b:        image.NewGray(image.Rect(1, 1, 1, 5)),
+			expected: true,
+		},
+		{
+			name:     "infinite images",
+			a:        image.NewUniform(color.White),
+			b:        image.NewUniform(color.White),
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			equal, report := EqualTolerant(tt.a, tt.b, tt.tolerance)
+			if equal != tt.expected || report != tt.report {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("EqualTolerant(%s, %d) = (%t,%+v)\n", tt.name, tt.tolerance, equal, report) +
+					fmt.Sprintf("Expected:\t (%t,%+v)\n", tt.expected, tt.report)
+				t.Errorf(format)
+			}
+
+			if tt.tolerance == 0 && Equal(tt.a, tt.b) != tt.expected {
+				t.Errorf("\nEqual(%s) = %t\n", tt.name, !tt.expected)
+			}
+		})
+	}
+}
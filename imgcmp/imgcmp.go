@@ -0,0 +1,181 @@
+// Package imgcmp compares images pixel by pixel.
+//
+// Colors are compared as non-premultiplied NRGBA values, so images of
+// different color models compare equal if they hold the same colors. Images
+// with 8 bit color models are compared at that depth; if either image has a
+// deeper one, like Gray16 or NRGBA64, the colors are compared as 16 bit
+// NRGBA64 values, so samples differing only in their low byte differ as well.
+// Compare and Diff require both images to have the same bounds, while Equal
+// and EqualTolerant report such images as different.
+package imgcmp
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/LukiDS/image/imgconv"
+)
+
+// Report describes the differences between two images.
+type Report struct {
+	// Differing is the number of pixels which differ in any channel.
+	Differing int
+	// First is the first differing pixel in row order.
+	First image.Point
+	// MaxDelta is the largest difference of the R, G, B and A channels.
+	MaxDelta [4]uint8
+	// Bounds is the smallest rectangle holding all differing pixels. It is
+	// empty if the images are equal.
+	Bounds image.Rectangle
+}
+
+// Equal reports whether no pixel differs.
+func (r Report) Equal() bool {
+	return r.Differing == 0
+}
+
+// validate checks that a and b can be compared.
+func validate(a, b image.Image) error {
+	for _, m := range []image.Image{a, b} {
+		if err := imgconv.Validate(m, imgconv.DefaultMaxPixels); err != nil {
+			return err
+		}
+	}
+	if a.Bounds() != b.Bounds() {
+		return fmt.Errorf("different bounds: %v and %v", a.Bounds(), b.Bounds())
+	}
+
+	return nil
+}
+
+// Compare compares the colors of a and b and reports their differences.
+// It returns an error if an image is nil or empty, or if their bounds differ.
+// The deltas of 16 bit colors are scaled to 8 bits and rounded up, so every
+// differing sample has a delta of at least 1.
+func Compare(a, b image.Image) (Report, error) {
+	var r Report
+	err := compare(a, b, func(x, y int, delta [4]uint8) {
+		r.add(x, y, delta)
+	})
+
+	return r, err
+}
+
+// compare calls f for every pixel of a and b whose colors differ, comparing
+// 16 bit colors unless both images are 8 bit.
+func compare(a, b image.Image, f func(x, y int, delta [4]uint8)) error {
+	if err := validate(a, b); err != nil {
+		return err
+	}
+	if is8Bit(a.ColorModel()) && is8Bit(b.ColorModel()) {
+		return compare8(a, b, f)
+	}
+
+	bounds := a.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			ca, cb := nrgba64At(a, x, y), nrgba64At(b, x, y)
+			if ca == cb {
+				continue
+			}
+
+			f(x, y, [4]uint8{
+				absDiff16(ca.R, cb.R),
+				absDiff16(ca.G, cb.G),
+				absDiff16(ca.B, cb.B),
+				absDiff16(ca.A, cb.A),
+			})
+		}
+	}
+
+	return nil
+}
+
+// compare8 calls f for every pixel of a and b whose colors differ as 8 bit
+// NRGBA values.
+func compare8(a, b image.Image, f func(x, y int, delta [4]uint8)) error {
+	if err := validate(a, b); err != nil {
+		return err
+	}
+
+	na, nb := imgconv.ToNRGBA(a), imgconv.ToNRGBA(b)
+	bounds := a.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		pa := na.Pix[na.PixOffset(bounds.Min.X, y):na.PixOffset(bounds.Max.X, y)]
+		pb := nb.Pix[nb.PixOffset(bounds.Min.X, y):nb.PixOffset(bounds.Max.X, y)]
+		for i := 0; i < len(pa); i += 4 {
+			sa, sb := pa[i:i+4:i+4], pb[i:i+4:i+4]
+			if sa[0] == sb[0] && sa[1] == sb[1] && sa[2] == sb[2] && sa[3] == sb[3] {
+				continue
+			}
+
+			var delta [4]uint8
+			for c := range delta {
+				delta[c] = absDiff(sa[c], sb[c])
+			}
+			f(bounds.Min.X+i/4, y, delta)
+		}
+	}
+
+	return nil
+}
+
+// is8Bit reports whether the colors of the model m have 8 bit samples.
+// Unknown models are assumed to be deeper.
+func is8Bit(m color.Model) bool {
+	switch m {
+	case color.RGBAModel, color.NRGBAModel, color.GrayModel, color.AlphaModel,
+		color.YCbCrModel, color.NYCbCrAModel, color.CMYKModel:
+		return true
+	}
+	_, ok := m.(color.Palette)
+
+	return ok
+}
+
+// nrgba64At returns the color of m at (x, y) as NRGBA64. 8 bit NRGBA colors
+// are widened exactly, since converting them through their premultiplied
+// RGBA values may round the color channels of translucent pixels.
+func nrgba64At(m image.Image, x, y int) color.NRGBA64 {
+	if n, ok := m.(*image.NRGBA); ok {
+		c := n.NRGBAAt(x, y)
+		return color.NRGBA64{uint16(c.R) * 0x101, uint16(c.G) * 0x101, uint16(c.B) * 0x101, uint16(c.A) * 0x101}
+	}
+
+	return color.NRGBA64Model.Convert(m.At(x, y)).(color.NRGBA64)
+}
+
+// add records the differing pixel (x, y).
+func (r *Report) add(x, y int, delta [4]uint8) {
+	if r.Differing == 0 {
+		r.First = image.Pt(x, y)
+	}
+	r.Differing++
+	r.Bounds = r.Bounds.Union(image.Rect(x, y, x+1, y+1))
+
+	for c, d := range delta {
+		if d > r.MaxDelta[c] {
+			r.MaxDelta[c] = d
+		}
+	}
+}
+
+// absDiff16 returns the difference of the 16 bit samples a and b scaled to
+// 8 bits, rounded up.
+func absDiff16(a, b uint16) uint8 {
+	d := uint32(a) - uint32(b)
+	if a < b {
+		d = uint32(b) - uint32(a)
+	}
+
+	return uint8((d + 0x100) / 0x101)
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+
+	return b - a
+}
@@ -0,0 +1,157 @@
+package imgcmp
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// generateImage returns a w x h NRGBA image with the origin at (x0, y0)
+// whose pixel colors depend on their position.
+func generateImage(t testing.TB, x0, y0, w, h int) *image.NRGBA {
+	t.Helper()
+
+	m := image.NewNRGBA(image.Rect(x0, y0, x0+w, y0+h))
+	for y := y0; y < y0+h; y++ {
+		for x := x0; x < x0+w; x++ {
+			m.SetNRGBA(x, y, color.NRGBA{uint8(10 * x), uint8(20 * y), uint8(x + y), 0xff})
+		}
+	}
+
+	return m
+}
+
+func TestCompare(t *testing.T) {
+	a := generateImage(t, 2, 3, 4, 3)
+
+	b := generateImage(t, 2, 3, 4, 3)
+	b.SetNRGBA(3, 4, color.NRGBA{0, 0, 0, 0xff})
+	b.SetNRGBA(5, 5, color.NRGBA{10*5 + 7, 20*5 - 2, 5 + 5, 0xf0})
+
+	gray := image.NewGray(image.Rect(0, 0, 2, 2))
+	gray.Pix = []uint8{0, 0x40, 0x80, 0xff}
+	nrgba := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	for i, v := range gray.Pix {
+		nrgba.Pix[4*i], nrgba.Pix[4*i+1], nrgba.Pix[4*i+2], nrgba.Pix[4*i+3] = v, v, v, 0xff
+	}
+
+	//16 bit images differing only in the low byte of a sample
+	gray16 := image.NewGray16(image.Rect(0, 0, 2, 2))
+	for i := range gray.Pix {
+		gray16.Pix[2*i], gray16.Pix[2*i+1] = gray.Pix[i], gray.Pix[i]
+	}
+	lowByte := image.NewGray16(gray16.Rect)
+	copy(lowByte.Pix, gray16.Pix)
+	lowByte.Pix[3]++
+
+	tests := []struct {
+		name     string
+		a, b     image.Image
+		expected Report
+	}{
+		{
+			name:     "identical images",
+			a:        a,
+			b:        generateImage(t, 2, 3, 4, 3),
+			expected: Report{},
+		},
+		{
+			name:     "different color models",
+			a:        gray,
+			b:        nrgba,
+			expected: Report{},
+		},
+		{
+			name:     "8 and 16 bit color models",
+			a:        gray,
+			b:        gray16,
+			expected: Report{},
+		},
+		{
+			name: "16 bit samples differing in the low byte",
+			a:    gray16,
+			b:    lowByte,
+			expected: Report{
+				Differing: 1,
+				First:     image.Pt(1, 0),
+				MaxDelta:  [4]uint8{1, 1, 1, 0},
+				Bounds:    image.Rect(1, 0, 2, 1),
+			},
+		},
+		{
+			name: "two differing pixels",
+			a:    a,
+			b:    b,
+			expected: Report{
+				Differing: 2,
+				First:     image.Pt(3, 4),
+				MaxDelta:  [4]uint8{30, 80, 7, 0x0f},
+				Bounds:    image.Rect(3, 4, 6, 6),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := Compare(test.a, test.b)
+			if err != nil || actual != test.expected || actual.Equal() != (test.expected.Differing == 0) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Compare(%s)\n", test.name) +
+					fmt.Sprintf("Expected:\t %+v\n", test.expected) +
+					fmt.Sprintf("Actual:\t %+v, %v\n", actual, err)
+				t.Errorf(format)
+			}
+
+			//the comparison is symmetric
+			if reverse, err := Compare(test.b, test.a); err != nil || reverse != actual {
+				t.Errorf("Compare(%s) reversed = %+v, %v, expected %+v\n", test.name, reverse, err, actual)
+			}
+		})
+	}
+}
+
+func TestCompareErrors(t *testing.T) {
+	a := generateImage(t, 0, 0, 2, 2)
+	var nilImage *image.NRGBA
+
+	tests := []struct {
+		name  string
+		a, b  image.Image
+		error string
+	}{
+		{name: "nil image", a: a, b: nil, error: "nil image"},
+		{name: "nil pointer", a: nilImage, b: a, error: "nil image"},
+		{name: "empty image", a: image.NewNRGBA(image.Rectangle{}), b: a, error: "empty image"},
+		{name: "different size", a: a, b: generateImage(t, 0, 0, 2, 3), error: "different bounds"},
+		{name: "different origin", a: a, b: generateImage(t, 1, 0, 2, 2), error: "different bounds"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Compare(test.a, test.b)
+			if err == nil || !strings.Contains(err.Error(), test.error) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Compare(%s)\n", test.name) +
+					fmt.Sprintf("Expected error:\t containing %q\n", test.error) +
+					fmt.Sprintf("Actual error:\t %v\n", err)
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+func BenchmarkCompare(b *testing.B) {
+	m1 := generateImage(b, 0, 0, 512, 512)
+	m2 := generateImage(b, 0, 0, 512, 512)
+	m2.Pix[len(m2.Pix)/2] ^= 1
+
+	b.SetBytes(int64(len(m1.Pix)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Compare(m1, m2); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
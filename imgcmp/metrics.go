@@ -0,0 +1,84 @@
+package imgcmp
+
+import (
+	"image"
+	"math"
+)
+
+// MetricOptions are the parameters of the quality metrics.
+type MetricOptions struct {
+	// Alpha includes the alpha channel in the combined metrics. By default
+	// only the R, G and B channels are combined.
+	Alpha bool
+}
+
+// ChannelMSE returns the mean squared error of the R, G, B and A channels of
+// a and b. The channels are compared as non-premultiplied 8 bit values, so the
+// error of each channel lies in [0, 255²]. Like for Compare, a and b must have
+// the same bounds.
+func ChannelMSE(a, b image.Image) ([4]float64, error) {
+	var sums [4]float64
+	err := compare8(a, b, func(x, y int, delta [4]uint8) {
+		for c, d := range delta {
+			sums[c] += float64(d) * float64(d)
+		}
+	})
+	if err != nil {
+		return [4]float64{}, err
+	}
+
+	pixels := float64(a.Bounds().Dx()) * float64(a.Bounds().Dy())
+	for c := range sums {
+		sums[c] /= pixels
+	}
+
+	return sums, nil
+}
+
+// MSE returns the mean squared error of the color channels of a and b, the
+// mean of the R, G and B values of ChannelMSE. The alpha channel is excluded,
+// but the colors are not premultiplied, so transparent pixels of different
+// colors still count. Use MetricOptions.MSE to include the alpha channel.
+func MSE(a, b image.Image) (float64, error) {
+	return MetricOptions{}.MSE(a, b)
+}
+
+// PSNR returns the peak signal-to-noise ratio in decibels of the color
+// channels of a and b, 10*log10(255² / MSE(a, b)). Identical images have an
+// infinite PSNR of math.Inf(1).
+func PSNR(a, b image.Image) (float64, error) {
+	return MetricOptions{}.PSNR(a, b)
+}
+
+// MSE is like the function MSE, but combines the channels selected by o.
+func (o MetricOptions) MSE(a, b image.Image) (float64, error) {
+	mse, err := ChannelMSE(a, b)
+	if err != nil {
+		return 0, err
+	}
+
+	if o.Alpha {
+		return (mse[0] + mse[1] + mse[2] + mse[3]) / 4, nil
+	}
+
+	return (mse[0] + mse[1] + mse[2]) / 3, nil
+}
+
+// PSNR is like the function PSNR, but combines the channels selected by o.
+func (o MetricOptions) PSNR(a, b image.Image) (float64, error) {
+	mse, err := o.MSE(a, b)
+	if err != nil {
+		return 0, err
+	}
+
+	return psnr(mse), nil
+}
+
+// psnr returns the PSNR of 8 bit samples with the mean squared error mse.
+func psnr(mse float64) float64 {
+	if mse == 0 {
+		return math.Inf(1)
+	}
+
+	return 10 * math.Log10(255*255/mse)
+}
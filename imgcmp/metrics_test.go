@@ -0,0 +1,134 @@
+package imgcmp
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	a := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	a.SetNRGBA(0, 0, color.NRGBA{0, 0, 0, 0xff})
+	a.SetNRGBA(1, 0, color.NRGBA{100, 100, 100, 0xff})
+
+	//deltas: R 10 at x=0, B 30 and A 50 at x=1
+	b := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	b.SetNRGBA(0, 0, color.NRGBA{10, 0, 0, 0xff})
+	b.SetNRGBA(1, 0, color.NRGBA{100, 100, 70, 205})
+
+	//a single channel of one of four pixels differs by 2
+	gray := image.NewGray(image.Rect(0, 0, 2, 2))
+	gray2 := image.NewGray(image.Rect(0, 0, 2, 2))
+	gray2.Pix[3] = 2
+
+	tests := []struct {
+		name      string
+		a, b      image.Image
+		channels  [4]float64
+		mse       float64
+		mseAlpha  float64
+		psnr      float64
+		psnrAlpha float64
+	}{
+		{
+			name:      "color and alpha",
+			a:         a,
+			b:         b,
+			channels:  [4]float64{50, 0, 450, 1250},
+			mse:       500.0 / 3,
+			mseAlpha:  437.5,
+			psnr:      25.91231611251554,
+			psnrAlpha: 21.721023035095783,
+		},
+		{
+			name:      "gray",
+			a:         gray,
+			b:         gray2,
+			channels:  [4]float64{1, 1, 1, 0},
+			mse:       1,
+			mseAlpha:  0.75,
+			psnr:      48.1308036086791,
+			psnrAlpha: 49.3801909747621,
+		},
+		{
+			name:      "identical images",
+			a:         a,
+			b:         a,
+			psnr:      math.Inf(1),
+			psnrAlpha: math.Inf(1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//the metrics are symmetric
+			for _, pair := range [][2]image.Image{{tt.a, tt.b}, {tt.b, tt.a}} {
+				channels, err := ChannelMSE(pair[0], pair[1])
+				mse, err1 := MSE(pair[0], pair[1])
+				mseAlpha, err2 := MetricOptions{Alpha: true}.MSE(pair[0], pair[1])
+				psnr, err3 := PSNR(pair[0], pair[1])
+				psnrAlpha, err4 := MetricOptions{Alpha: true}.PSNR(pair[0], pair[1])
+				for _, err := range []error{err, err1, err2, err3, err4} {
+					if err != nil {
+						t.Fatalf("unexpected error: %v\n", err)
+					}
+				}
+
+				for _, c := range []struct {
+					name             string
+					actual, expected float64
+				}{
+					{"ChannelMSE R", channels[0], tt.channels[0]},
+					{"ChannelMSE G", channels[1], tt.channels[1]},
+					{"ChannelMSE B", channels[2], tt.channels[2]},
+					{"ChannelMSE A", channels[3], tt.channels[3]},
+					{"MSE", mse, tt.mse},
+					{"MSE with alpha", mseAlpha, tt.mseAlpha},
+					{"PSNR", psnr, tt.psnr},
+					{"PSNR with alpha", psnrAlpha, tt.psnrAlpha},
+				} {
+					if !almostEqual(c.actual, c.expected) {
+						format := fmt.Sprintf("\n") +
+							fmt.Sprintf("%s(%s)\n", c.name, tt.name) +
+							fmt.Sprintf("Expected:\t %v\n", c.expected) +
+							fmt.Sprintf("Actual:\t %v\n", c.actual)
+						t.Errorf(format)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMetricsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b image.Image
+	}{
+		{name: "different bounds", a: generateImage(t, 0, 0, 2, 2), b: generateImage(t, 1, 0, 2, 2)},
+		{name: "nil image", a: generateImage(t, 0, 0, 2, 2), b: nil},
+		{name: "empty images", a: image.NewGray(image.Rectangle{}), b: image.NewGray(image.Rectangle{})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := MSE(tt.a, tt.b); err == nil {
+				t.Errorf("\nMSE(%s):\nExpected error:\t true\n", tt.name)
+			}
+			if _, err := PSNR(tt.a, tt.b); err == nil {
+				t.Errorf("\nPSNR(%s):\nExpected error:\t true\n", tt.name)
+			}
+		})
+	}
+}
+
+// almostEqual reports whether a and b are equal up to rounding errors.
+func almostEqual(a, b float64) bool {
+	if math.IsInf(a, 1) || math.IsInf(b, 1) {
+		return a == b
+	}
+
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b))
+}
@@ -0,0 +1,172 @@
+package imgcmp
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/LukiDS/image/imgconv"
+)
+
+// SSIMWindow selects the window SSIM computes the local statistics in.
+type SSIMWindow int
+
+const (
+	// WindowGaussian is an 11x11 circular-symmetric Gaussian window with a
+	// standard deviation of 1.5 pixels, as used by Wang et al. (2004).
+	WindowGaussian SSIMWindow = iota
+	// WindowUniform is an 8x8 window with equal weights.
+	WindowUniform
+)
+
+// SSIMOptions are the parameters of SSIM.
+type SSIMOptions struct {
+	Window SSIMWindow
+}
+
+// The stabilizers C1 = (K1*L)² and C2 = (K2*L)² with K1 = 0.01, K2 = 0.03 and
+// the dynamic range L = 255.
+const (
+	ssimC1 = (0.01 * 255) * (0.01 * 255)
+	ssimC2 = (0.03 * 255) * (0.03 * 255)
+)
+
+// SSIM returns the mean structural similarity index of a and b, a value in
+// [-1, 1] which is 1 for identical images. opts may be nil for the defaults.
+//
+// It implements the SSIM of Wang, Bovik, Sheikh and Simoncelli, "Image
+// Quality Assessment: From Error Visibility to Structural Similarity" (2004)
+// like their reference implementation: the index is computed on the luminance
+// Y = 0.299 R + 0.587 G + 0.114 B of the 8 bit, non-premultiplied colors, so
+// the alpha channel is ignored. The local means, variances and covariance are
+// weighted by the window, which is only placed where it lies entirely inside
+// the image, and the indices of all window positions are averaged.
+// The images are not downsampled.
+//
+// Since no window position is left for images smaller than the window in
+// either dimension, SSIM returns an error for them, as it does for images of
+// different bounds.
+func SSIM(a, b image.Image, opts *SSIMOptions) (float64, error) {
+	if opts == nil {
+		opts = &SSIMOptions{}
+	}
+
+	var kernel []float64
+	switch opts.Window {
+	case WindowGaussian:
+		kernel = gaussianKernel(11, 1.5)
+	case WindowUniform:
+		kernel = uniformKernel(8)
+	default:
+		return 0, fmt.Errorf("unknown SSIM window %d", opts.Window)
+	}
+
+	if err := validate(a, b); err != nil {
+		return 0, err
+	}
+	w, h := a.Bounds().Dx(), a.Bounds().Dy()
+	if w < len(kernel) || h < len(kernel) {
+		return 0, fmt.Errorf("image of size %dx%d smaller than the %dx%d SSIM window", w, h, len(kernel), len(kernel))
+	}
+
+	x, y := luminance(a), luminance(b)
+	xx, yy, xy := make([]float64, len(x)), make([]float64, len(x)), make([]float64, len(x))
+	for i := range x {
+		xx[i], yy[i], xy[i] = x[i]*x[i], y[i]*y[i], x[i]*y[i]
+	}
+
+	muX := filterValid(x, w, h, kernel)
+	muY := filterValid(y, w, h, kernel)
+	meanXX := filterValid(xx, w, h, kernel)
+	meanYY := filterValid(yy, w, h, kernel)
+	meanXY := filterValid(xy, w, h, kernel)
+
+	var sum float64
+	for i := range muX {
+		mx, my := muX[i], muY[i]
+		varX := meanXX[i] - mx*mx
+		varY := meanYY[i] - my*my
+		cov := meanXY[i] - mx*my
+
+		sum += ((2*mx*my + ssimC1) * (2*cov + ssimC2)) /
+			((mx*mx + my*my + ssimC1) * (varX + varY + ssimC2))
+	}
+
+	return sum / float64(len(muX)), nil
+}
+
+// luminance returns the luma of the pixels of m in row order.
+func luminance(m image.Image) []float64 {
+	n := imgconv.ToNRGBA(m)
+	b := n.Bounds()
+
+	l := make([]float64, 0, b.Dx()*b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		p := n.Pix[n.PixOffset(b.Min.X, y):n.PixOffset(b.Max.X, y)]
+		for i := 0; i < len(p); i += 4 {
+			l = append(l, 0.299*float64(p[i])+0.587*float64(p[i+1])+0.114*float64(p[i+2]))
+		}
+	}
+
+	return l
+}
+
+// gaussianKernel returns the normalized one-dimensional Gaussian of the given
+// size and standard deviation. The two-dimensional window is its outer product.
+func gaussianKernel(size int, sigma float64) []float64 {
+	k := make([]float64, size)
+	var sum float64
+	for i := range k {
+		d := float64(i) - float64(size-1)/2
+		k[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		sum += k[i]
+	}
+	for i := range k {
+		k[i] /= sum
+	}
+
+	return k
+}
+
+func uniformKernel(size int) []float64 {
+	k := make([]float64, size)
+	for i := range k {
+		k[i] = 1 / float64(size)
+	}
+
+	return k
+}
+
+// filterValid filters the w x h samples s with the separable window given by
+// kernel, keeping only the positions where the window lies inside the image.
+func filterValid(s []float64, w, h int, kernel []float64) []float64 {
+	k := len(kernel)
+	ow, oh := w-k+1, h-k+1
+
+	//horizontal pass: h rows of ow samples
+	rows := make([]float64, ow*h)
+	for y := 0; y < h; y++ {
+		row := s[y*w : (y+1)*w]
+		for x := 0; x < ow; x++ {
+			var v float64
+			for i, f := range kernel {
+				v += f * row[x+i]
+			}
+			rows[y*ow+x] = v
+		}
+	}
+
+	//vertical pass: oh rows of ow samples
+	out := make([]float64, ow*oh)
+	for y := 0; y < oh; y++ {
+		for i, f := range kernel {
+			row := rows[(y+i)*ow : (y+i+1)*ow]
+			o := out[y*ow : (y+1)*ow]
+			for x := range o {
+				o[x] += f * row[x]
+			}
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,241 @@
+package imgcmp
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/LukiDS/image/imgconv"
+	"github.com/LukiDS/image/qoi"
+)
+
+// generateBlurred returns a 64x64 crop of a photo and a blurred copy of it,
+// which is downscaled to size x size and upscaled again.
+func generateBlurred(t testing.TB, size int) (*image.NRGBA, *image.NRGBA) {
+	t.Helper()
+
+	f, err := os.Open("../testdata/kodim23.qoi")
+	if err != nil {
+		t.Fatalf("could not read file: %v\n", err)
+	}
+	defer f.Close()
+
+	m, err := qoi.Decode(f)
+	if err != nil {
+		t.Fatalf("could not decode file: %v\n", err)
+	}
+
+	crop, err := imgconv.Crop(m, image.Rect(200, 100, 264, 164))
+	if err != nil {
+		t.Fatal(err)
+	}
+	crop = imgconv.NormalizeBounds(crop)
+
+	small, err := imgconv.ResizeBox(crop, size, size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blurred, err := imgconv.ResizeBilinear(small, 64, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return crop, blurred
+}
+
+// generateStripes returns a 16x20 gray image whose rows alternate between the
+// values even and odd.
+func generateStripes(even, odd uint8) *image.Gray {
+	m := image.NewGray(image.Rect(0, 0, 16, 20))
+	for y := 0; y < 20; y++ {
+		v := even
+		if y%2 != 0 {
+			v = odd
+		}
+		for x := 0; x < 16; x++ {
+			m.SetGray(x, y, color.Gray{v})
+		}
+	}
+
+	return m
+}
+
+// stripesSSIM returns the SSIM of two images of generateStripes by equation
+// (13) of Wang et al. (2004). Every window position weights the rows of one
+// parity with p and the others with 1-p, where p depends on the window and on
+// the parity of the position. So the local means are p*even + (1-p)*odd and the
+// variances and the covariance are p*(1-p) times the products of even-odd.
+// The 20 rows hold as many positions of either parity for the 11x11 Gaussian
+// window, and p is 1/2 for the 8x8 uniform window, so the index is the mean
+// of the indices for p and 1-p.
+func stripesSSIM(p float64, a, b [2]float64) float64 {
+	index := func(p float64) float64 {
+		muX, muY := p*a[0]+(1-p)*a[1], p*b[0]+(1-p)*b[1]
+		dx, dy := a[0]-a[1], b[0]-b[1]
+		varX, varY, cov := p*(1-p)*dx*dx, p*(1-p)*dy*dy, p*(1-p)*dx*dy
+
+		return ((2*muX*muY + ssimC1) * (2*cov + ssimC2)) /
+			((muX*muX + muY*muY + ssimC1) * (varX + varY + ssimC2))
+	}
+
+	return (index(p) + index(1-p)) / 2
+}
+
+func TestSSIM(t *testing.T) {
+	photo, _ := generateBlurred(t, 16)
+
+	//the weight of the rows at odd distances from the center of the Gaussian
+	//window of the paper, with a standard deviation of 1.5
+	var odd, sum float64
+	for d := -5; d <= 5; d++ {
+		g := math.Exp(-float64(d*d) / (2 * 1.5 * 1.5))
+		sum += g
+		if d%2 != 0 {
+			odd += g
+		}
+	}
+	p := odd / sum
+
+	stripes := generateStripes(40, 200)
+	flat := generateStripes(60, 140)
+	inverted := generateStripes(140, 60)
+
+	//constant images have no variance, the index is the luminance term
+	//(2*50*60 + C1) / (50² + 60² + C1)
+	dark := imgconv.UniformNRGBA(color.Gray{50}, image.Rect(0, 0, 12, 12))
+	light := imgconv.UniformNRGBA(color.Gray{60}, image.Rect(0, 0, 12, 12))
+
+	transparent := imgconv.CloneNRGBA(photo)
+	for i := 3; i < len(transparent.Pix); i += 4 {
+		transparent.Pix[i] = 0
+	}
+
+	tests := []struct {
+		name     string
+		a, b     image.Image
+		opts     *SSIMOptions
+		expected float64
+	}{
+		{
+			name:     "lower contrast stripes",
+			a:        stripes,
+			b:        flat,
+			expected: stripesSSIM(p, [2]float64{40, 200}, [2]float64{60, 140}),
+		},
+		{
+			name:     "lower contrast stripes with uniform window",
+			a:        stripes,
+			b:        flat,
+			opts:     &SSIMOptions{Window: WindowUniform},
+			expected: stripesSSIM(0.5, [2]float64{40, 200}, [2]float64{60, 140}),
+		},
+		{
+			name:     "inverted stripes",
+			a:        stripes,
+			b:        inverted,
+			expected: stripesSSIM(p, [2]float64{40, 200}, [2]float64{140, 60}),
+		},
+		{
+			name:     "inverted stripes with uniform window",
+			a:        stripes,
+			b:        inverted,
+			opts:     &SSIMOptions{Window: WindowUniform},
+			expected: stripesSSIM(0.5, [2]float64{40, 200}, [2]float64{140, 60}),
+		},
+		{
+			name:     "identical images",
+			a:        photo,
+			b:        imgconv.ToRGBA(photo),
+			expected: 1,
+		},
+		{
+			name:     "alpha is ignored",
+			a:        photo,
+			b:        transparent,
+			expected: 1,
+		},
+		{
+			name:     "constant images",
+			a:        dark,
+			b:        light,
+			expected: (2*50*60 + ssimC1) / (50*50 + 60*60 + ssimC1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, pair := range [][2]image.Image{{tt.a, tt.b}, {tt.b, tt.a}} {
+				actual, err := SSIM(pair[0], pair[1], tt.opts)
+				if err != nil || !almostEqual(actual, tt.expected) {
+					format := fmt.Sprintf("\n") +
+						fmt.Sprintf("SSIM(%s) = (%v,%v)\n", tt.name, actual, err) +
+						fmt.Sprintf("Expected:\t %v\n", tt.expected)
+					t.Errorf(format)
+				}
+			}
+		})
+	}
+}
+
+func TestSSIMBlur(t *testing.T) {
+	//stronger blur must lower the index of the photo, for either window
+	for _, opts := range []*SSIMOptions{nil, {Window: WindowUniform}} {
+		last := 1.0
+		for _, size := range []int{32, 16, 8} {
+			photo, blurred := generateBlurred(t, size)
+			actual, err := SSIM(photo, blurred, opts)
+			if err != nil || actual >= last || actual <= 0 {
+				t.Errorf("\nSSIM(photo, blurred to %dx%d, %+v) = (%v,%v)\nExpected:\t between 0 and %v\n", size, size, opts, actual, err, last)
+			}
+			last = actual
+		}
+	}
+}
+
+func TestSSIMErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b image.Image
+		opts *SSIMOptions
+	}{
+		{name: "different bounds", a: generateImage(t, 0, 0, 16, 16), b: generateImage(t, 0, 0, 16, 17)},
+		{name: "narrower than the window", a: generateImage(t, 0, 0, 10, 16), b: generateImage(t, 0, 0, 10, 16)},
+		{name: "lower than the window", a: generateImage(t, 0, 0, 8, 7), b: generateImage(t, 0, 0, 8, 7), opts: &SSIMOptions{Window: WindowUniform}},
+		{name: "unknown window", a: generateImage(t, 0, 0, 16, 16), b: generateImage(t, 0, 0, 16, 16), opts: &SSIMOptions{Window: 7}},
+		{name: "nil image", a: nil, b: generateImage(t, 0, 0, 16, 16)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual, err := SSIM(tt.a, tt.b, tt.opts); err == nil {
+				t.Errorf("\nSSIM(%s) = (%v,%v)\nExpected error:\t true\n", tt.name, actual, err)
+			}
+		})
+	}
+
+	//the smallest images fitting the windows
+	for _, opts := range []*SSIMOptions{nil, {Window: WindowUniform}} {
+		size := 11
+		if opts != nil {
+			size = 8
+		}
+		m := generateImage(t, 3, 3, size, size)
+		if actual, err := SSIM(m, m, opts); err != nil || actual != 1 {
+			t.Errorf("\nSSIM(%dx%d) = (%v,%v)\nExpected:\t 1\n", size, size, actual, err)
+		}
+	}
+}
+
+func BenchmarkSSIM(b *testing.B) {
+	photo, blurred := generateBlurred(b, 16)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := SSIM(photo, blurred, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
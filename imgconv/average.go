@@ -0,0 +1,105 @@
+package imgconv
+
+import (
+	"image"
+	"image/color"
+)
+
+// AverageColor returns the mean color of any image m. The colors are weighted
+// by their alpha value, so transparent pixels do not darken the result, while
+// the alpha value is the plain mean of all pixels.
+// Empty and fully transparent images return transparent black.
+func AverageColor(m image.Image) color.NRGBA {
+	src := ToNRGBA(m)
+	b := src.Bounds()
+
+	var r, g, bl, a uint64
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		i := src.PixOffset(b.Min.X, y)
+		for x := b.Min.X; x < b.Max.X; x, i = x+1, i+4 {
+			s := src.Pix[i : i+4 : i+4]
+			sa := uint64(s[3])
+			r += uint64(s[0]) * sa
+			g += uint64(s[1]) * sa
+			bl += uint64(s[2]) * sa
+			a += sa
+		}
+	}
+
+	if a == 0 {
+		return color.NRGBA{}
+	}
+
+	n := uint64(b.Dx() * b.Dy())
+	return color.NRGBA{
+		R: uint8((r + a/2) / a),
+		G: uint8((g + a/2) / a),
+		B: uint8((bl + a/2) / a),
+		A: uint8((a + n/2) / n),
+	}
+}
+
+// DominantColor returns the most common color of any image m. The color
+// channels are quantized into buckets bins each, which is clamped to [1, 256],
+// and the mean color of the pixels in the most populated bin is returned.
+// Fully transparent pixels are ignored. If several bins are equally populated,
+// the one with the darkest quantized color, comparing red, then green, then
+// blue, wins. Empty and fully transparent images return transparent black.
+func DominantColor(m image.Image, buckets int) color.NRGBA {
+	if buckets < 1 {
+		buckets = 1
+	}
+	if buckets > 256 {
+		buckets = 256
+	}
+
+	type bin struct {
+		n          uint64
+		r, g, b, a uint64
+	}
+
+	src := ToNRGBA(m)
+	b := src.Bounds()
+	bins := make(map[int]*bin)
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		i := src.PixOffset(b.Min.X, y)
+		for x := b.Min.X; x < b.Max.X; x, i = x+1, i+4 {
+			s := src.Pix[i : i+4 : i+4]
+			if s[3] == 0 {
+				continue
+			}
+
+			key := (int(s[0])*buckets/256*buckets+int(s[1])*buckets/256)*buckets + int(s[2])*buckets/256
+			bn, ok := bins[key]
+			if !ok {
+				bn = new(bin)
+				bins[key] = bn
+			}
+			bn.n++
+			bn.r += uint64(s[0])
+			bn.g += uint64(s[1])
+			bn.b += uint64(s[2])
+			bn.a += uint64(s[3])
+		}
+	}
+
+	bestKey := -1
+	for key, bn := range bins {
+		if bestKey < 0 || bn.n > bins[bestKey].n || (bn.n == bins[bestKey].n && key < bestKey) {
+			bestKey = key
+		}
+	}
+
+	if bestKey < 0 {
+		return color.NRGBA{}
+	}
+
+	bn := bins[bestKey]
+	return color.NRGBA{
+		R: uint8((bn.r + bn.n/2) / bn.n),
+		G: uint8((bn.g + bn.n/2) / bn.n),
+		B: uint8((bn.b + bn.n/2) / bn.n),
+		A: uint8((bn.a + bn.n/2) / bn.n),
+	}
+}
@@ -0,0 +1,125 @@
+package imgconv
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestAverageColor(t *testing.T) {
+	tests := []struct {
+		name     string
+		m        image.Image
+		expected color.NRGBA
+	}{
+		{
+			name:     "should return the color of a single pixel",
+			m:        UniformNRGBA(color.NRGBA{12, 34, 56, 78}, image.Rect(5, 5, 6, 6)),
+			expected: color.NRGBA{12, 34, 56, 78},
+		},
+		{
+			name:     "should average opaque colors",
+			m:        generateStripesNRGBA(t, image.Rect(0, 0, 4, 3), []color.NRGBA{{0, 0, 0, 255}, {255, 100, 11, 255}}),
+			expected: color.NRGBA{128, 50, 6, 255},
+		},
+		{
+			name:     "should ignore the color of transparent pixels",
+			m:        generateStripesNRGBA(t, image.Rect(0, 0, 4, 3), []color.NRGBA{{255, 0, 0, 255}, {0, 255, 0, 0}}),
+			expected: color.NRGBA{255, 0, 0, 128},
+		},
+		{
+			name:     "should weight colors by alpha",
+			m:        generateStripesNRGBA(t, image.Rect(0, 0, 4, 1), []color.NRGBA{{200, 0, 0, 255}, {0, 0, 100, 85}}),
+			expected: color.NRGBA{150, 0, 25, 170},
+		},
+		{
+			name:     "should return transparent black for transparent images",
+			m:        image.NewNRGBA(image.Rect(0, 0, 3, 3)),
+			expected: color.NRGBA{},
+		},
+		{
+			name:     "should return transparent black for empty images",
+			m:        image.NewNRGBA(image.Rectangle{}),
+			expected: color.NRGBA{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := AverageColor(test.m); actual != test.expected {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("AverageColor(%v)\n", test.m.Bounds()) +
+					fmt.Sprintf("Expected value:\t %v\n", test.expected) +
+					fmt.Sprintf("Actual value:\t %v\n", actual)
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+func TestDominantColor(t *testing.T) {
+	tests := []struct {
+		name     string
+		m        image.Image
+		buckets  int
+		expected color.NRGBA
+	}{
+		{
+			name:     "should return the color of a single pixel",
+			m:        UniformNRGBA(color.NRGBA{12, 34, 56, 78}, image.Rect(5, 5, 6, 6)),
+			buckets:  8,
+			expected: color.NRGBA{12, 34, 56, 78},
+		},
+		{
+			name:     "should return the most common color",
+			m:        generateStripesNRGBA(t, image.Rect(0, 0, 5, 2), []color.NRGBA{{255, 0, 0, 255}, {0, 0, 255, 255}, {0, 0, 255, 255}, {0, 255, 0, 255}, {1, 1, 1, 255}}),
+			buckets:  8,
+			expected: color.NRGBA{0, 0, 255, 255},
+		},
+		{
+			name: "should return the centroid of the most populated bin",
+			m: generateStripesNRGBA(t, image.Rect(0, 0, 5, 1), []color.NRGBA{
+				{200, 10, 10, 255}, {210, 20, 10, 255}, {220, 30, 10, 255}, {0, 0, 0, 255}, {0, 0, 0, 255},
+			}),
+			buckets:  4,
+			expected: color.NRGBA{210, 20, 10, 255},
+		},
+		{
+			name:     "should prefer the darker bin on ties",
+			m:        generateStripesNRGBA(t, image.Rect(0, 0, 2, 2), []color.NRGBA{{255, 255, 255, 255}, {0, 0, 0, 255}}),
+			buckets:  2,
+			expected: color.NRGBA{0, 0, 0, 255},
+		},
+		{
+			name:     "should ignore transparent pixels",
+			m:        generateStripesNRGBA(t, image.Rect(0, 0, 3, 1), []color.NRGBA{{0, 0, 0, 0}, {0, 0, 0, 0}, {9, 9, 9, 9}}),
+			buckets:  16,
+			expected: color.NRGBA{9, 9, 9, 9},
+		},
+		{
+			name:     "should clamp the number of buckets",
+			m:        generateStripesNRGBA(t, image.Rect(0, 0, 2, 1), []color.NRGBA{{0, 0, 0, 255}, {255, 255, 255, 255}}),
+			buckets:  0,
+			expected: color.NRGBA{128, 128, 128, 255},
+		},
+		{
+			name:     "should return transparent black for transparent images",
+			m:        image.NewNRGBA(image.Rect(0, 0, 3, 3)),
+			buckets:  8,
+			expected: color.NRGBA{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := DominantColor(test.m, test.buckets); actual != test.expected {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("DominantColor(%v, %d)\n", test.m.Bounds(), test.buckets) +
+					fmt.Sprintf("Expected value:\t %v\n", test.expected) +
+					fmt.Sprintf("Actual value:\t %v\n", actual)
+				t.Errorf(format)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package imgconv
+
+import (
+	"fmt"
+	"image"
+)
+
+// ChannelOrder is the byte order of the four channels of a pixel.
+type ChannelOrder int
+
+const (
+	// OrderRGBA is the order of image.NRGBA and image.RGBA.
+	OrderRGBA ChannelOrder = iota
+	// OrderBGRA is used by Windows GDI and many GPU upload paths.
+	OrderBGRA
+	// OrderARGB stores the alpha value first.
+	OrderARGB
+	// OrderABGR is the reverse of OrderRGBA.
+	OrderABGR
+)
+
+// offsets returns the byte offsets of the red, green, blue and alpha channel.
+func (o ChannelOrder) offsets() ([4]int, bool) {
+	switch o {
+	case OrderRGBA:
+		return [4]int{0, 1, 2, 3}, true
+	case OrderBGRA:
+		return [4]int{2, 1, 0, 3}, true
+	case OrderARGB:
+		return [4]int{1, 2, 3, 0}, true
+	case OrderABGR:
+		return [4]int{3, 2, 1, 0}, true
+	default:
+		return [4]int{}, false
+	}
+}
+
+// SwapRB returns a copy of m with the red and blue byte of every pixel
+// swapped, converting between RGBA and BGRA byte order.
+func SwapRB(m *image.NRGBA) *image.NRGBA {
+	img := CloneNRGBA(m)
+	SwapRBInPlace(img)
+
+	return img
+}
+
+// SwapRBInPlace swaps the red and blue byte of every pixel of m in place.
+// Applying it twice restores m.
+func SwapRBInPlace(m *image.NRGBA) {
+	swapRB(m.Pix, m.Stride, m.PixOffset(m.Rect.Min.X, m.Rect.Min.Y), m.Rect)
+}
+
+// SwapRBRGBA is like SwapRB for alpha-premultiplied images.
+func SwapRBRGBA(m *image.RGBA) *image.RGBA {
+	img := image.NewRGBA(m.Bounds())
+	convertRGBA(img, m)
+	swapRB(img.Pix, img.Stride, 0, img.Rect)
+
+	return img
+}
+
+func swapRB(pix []byte, stride, offset int, r image.Rectangle) {
+	rowSize := 4 * r.Dx()
+	for y := 0; y < r.Dy(); y++ {
+		row := pix[offset+y*stride : offset+y*stride+rowSize]
+		for i := 0; i < rowSize; i += 4 {
+			p := row[i : i+4 : i+4]
+			p[0], p[2] = p[2], p[0]
+		}
+	}
+}
+
+// ReorderChannels changes the byte order of the 4 bytes per pixel buffer pix
+// from the order from to the order to in place.
+// It returns an error if an order is unknown or len(pix) is not a multiple of 4.
+func ReorderChannels(pix []byte, from, to ChannelOrder) error {
+	src, ok := from.offsets()
+	if !ok {
+		return fmt.Errorf("invalid channel order: %d", from)
+	}
+	dst, ok := to.offsets()
+	if !ok {
+		return fmt.Errorf("invalid channel order: %d", to)
+	}
+	if len(pix)%4 != 0 {
+		return fmt.Errorf("invalid buffer length %d, must be a multiple of 4", len(pix))
+	}
+
+	if from == to {
+		return nil
+	}
+	if (from == OrderRGBA && to == OrderBGRA) || (from == OrderBGRA && to == OrderRGBA) {
+		swapRB(pix, len(pix), 0, image.Rect(0, 0, len(pix)/4, 1))
+		return nil
+	}
+
+	for i := 0; i < len(pix); i += 4 {
+		p := pix[i : i+4 : i+4]
+		var c [4]byte
+		for ch := 0; ch < 4; ch++ {
+			c[dst[ch]] = p[src[ch]]
+		}
+		p[0], p[1], p[2], p[3] = c[0], c[1], c[2], c[3]
+	}
+
+	return nil
+}
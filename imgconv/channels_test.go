@@ -0,0 +1,108 @@
+package imgconv
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSwapRB(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(-1, 2, 2, 3))
+	m.SetNRGBA(-1, 2, color.NRGBA{1, 2, 3, 4})
+	m.SetNRGBA(0, 2, color.NRGBA{255, 0, 128, 255})
+	m.SetNRGBA(1, 2, color.NRGBA{0, 0, 0, 0})
+
+	actual := SwapRB(m)
+	expected := []color.NRGBA{{3, 2, 1, 4}, {128, 0, 255, 255}, {0, 0, 0, 0}}
+	for i, e := range expected {
+		if c := actual.NRGBAAt(i-1, 2); c != e {
+			t.Errorf("SwapRB: pixel %d is %v, expected %v\n", i, c, e)
+		}
+	}
+
+	if m.NRGBAAt(-1, 2) != (color.NRGBA{1, 2, 3, 4}) {
+		t.Errorf("SwapRB changed the source image\n")
+	}
+}
+
+func TestSwapRBInPlaceTwice(t *testing.T) {
+	parent := generatePaddedNRGBA(t, image.Rect(0, 0, 13, 9), 8, 1)
+	expected := &image.NRGBA{Pix: clonePix(parent.Pix), Stride: parent.Stride, Rect: parent.Rect}
+	sub := parent.SubImage(image.Rect(2, 3, 11, 7)).(*image.NRGBA)
+
+	SwapRBInPlace(sub)
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 13; x++ {
+			c, e := parent.NRGBAAt(x, y), expected.NRGBAAt(x, y)
+			if (image.Point{x, y}).In(sub.Rect) {
+				e.R, e.B = e.B, e.R
+			}
+			if c != e {
+				t.Fatalf("SwapRBInPlace: pixel at x=%d, y=%d is %v, expected %v\n", x, y, c, e)
+			}
+		}
+	}
+
+	SwapRBInPlace(sub)
+	if !bytes.Equal(parent.Pix, expected.Pix) {
+		t.Errorf("SwapRBInPlace twice is not the identity\n")
+	}
+}
+
+func TestSwapRBRGBA(t *testing.T) {
+	m := generateRandomRGBA(t, image.Rect(3, 1, 9, 8), 1)
+
+	actual := SwapRBRGBA(SwapRBRGBA(m))
+	assertConvertedImage(t, m, actual, color.RGBAModel, "\nSwapRBRGBA(SwapRBRGBA)\n")
+
+	once := SwapRBRGBA(m)
+	if c, e := once.RGBAAt(4, 2), m.RGBAAt(4, 2); c.R != e.B || c.B != e.R || c.G != e.G || c.A != e.A {
+		t.Errorf("SwapRBRGBA: pixel is %v, expected swapped %v\n", c, e)
+	}
+}
+
+func TestReorderChannels(t *testing.T) {
+	pixels := map[ChannelOrder][]byte{
+		OrderRGBA: {1, 2, 3, 4, 5, 6, 7, 8},
+		OrderBGRA: {3, 2, 1, 4, 7, 6, 5, 8},
+		OrderARGB: {4, 1, 2, 3, 8, 5, 6, 7},
+		OrderABGR: {4, 3, 2, 1, 8, 7, 6, 5},
+	}
+
+	for from, src := range pixels {
+		for to, expected := range pixels {
+			t.Run(fmt.Sprintf("%d to %d", from, to), func(t *testing.T) {
+				actual := clonePix(src)
+				if err := ReorderChannels(actual, from, to); err != nil {
+					t.Fatalf("ReorderChannels: unexpected error: %v\n", err)
+				}
+				if !bytes.Equal(actual, expected) {
+					t.Errorf("ReorderChannels(%v, %d, %d) = %v, expected %v\n", src, from, to, actual, expected)
+				}
+			})
+		}
+	}
+}
+
+func TestReorderChannelsErrors(t *testing.T) {
+	if err := ReorderChannels(make([]byte, 6), OrderRGBA, OrderBGRA); err == nil {
+		t.Errorf("ReorderChannels(6 bytes): expected an error\n")
+	}
+	if err := ReorderChannels(make([]byte, 8), ChannelOrder(4), OrderBGRA); err == nil {
+		t.Errorf("ReorderChannels(ChannelOrder(4)): expected an error\n")
+	}
+	if err := ReorderChannels(make([]byte, 8), OrderRGBA, ChannelOrder(-1)); err == nil {
+		t.Errorf("ReorderChannels(ChannelOrder(-1)): expected an error\n")
+	}
+}
+
+func BenchmarkSwapRBInPlace(b *testing.B) {
+	m := generateRandomNRGBA(b, image.Rect(0, 0, 1920, 1080), 1)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		SwapRBInPlace(m)
+	}
+}
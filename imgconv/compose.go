@@ -0,0 +1,71 @@
+package imgconv
+
+import (
+	"image"
+)
+
+// ComposeOver composites src over dst with the source-over operator, placing
+// the top left corner of src at the point at of dst. src is clipped to the
+// bounds of dst. The colors are blended before alpha-premultiplication with
+// rounding to nearest, so dst keeps the full precision of non-premultiplied
+// colors. Opaque source pixels are copied and transparent ones are skipped.
+func ComposeOver(dst *image.NRGBA, src image.Image, at image.Point) error {
+	if dst == nil || src == nil {
+		return ErrNilImage
+	}
+
+	sb := src.Bounds()
+	offset := at.Sub(sb.Min)
+	r := sb.Add(offset).Intersect(dst.Bounds())
+	if r.Empty() {
+		return nil
+	}
+
+	s := ToNRGBA(subImage(src, r.Sub(offset)))
+	sr := r.Sub(offset)
+	rowSize := 4 * r.Dx()
+
+	for y := 0; y < r.Dy(); y++ {
+		si := s.PixOffset(sr.Min.X, sr.Min.Y+y)
+		di := dst.PixOffset(r.Min.X, r.Min.Y+y)
+		sRow := s.Pix[si : si+rowSize : si+rowSize]
+		dRow := dst.Pix[di : di+rowSize : di+rowSize]
+
+		if opaqueRow(sRow) {
+			copy(dRow, sRow)
+			continue
+		}
+
+		for i := 0; i < rowSize; i += 4 {
+			sp := sRow[i : i+4 : i+4]
+			dp := dRow[i : i+4 : i+4]
+			switch sa := uint32(sp[3]); sa {
+			case 0:
+			case 0xff:
+				dp[0], dp[1], dp[2], dp[3] = sp[0], sp[1], sp[2], 0xff
+			default:
+				//alpha in units of 0xff*0xff
+				sw := sa * 0xff
+				dw := uint32(dp[3]) * (0xff - sa)
+				a := sw + dw
+				dp[0] = uint8((uint32(sp[0])*sw + uint32(dp[0])*dw + a/2) / a)
+				dp[1] = uint8((uint32(sp[1])*sw + uint32(dp[1])*dw + a/2) / a)
+				dp[2] = uint8((uint32(sp[2])*sw + uint32(dp[2])*dw + a/2) / a)
+				dp[3] = uint8((a + 0x7f) / 0xff)
+			}
+		}
+	}
+
+	return nil
+}
+
+// opaqueRow reports whether every pixel of the NRGBA row is opaque.
+func opaqueRow(row []byte) bool {
+	for i := 3; i < len(row); i += 4 {
+		if row[i] != 0xff {
+			return false
+		}
+	}
+
+	return true
+}
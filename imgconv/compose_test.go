@@ -0,0 +1,127 @@
+package imgconv
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestComposeOver(t *testing.T) {
+	tests := []struct {
+		name string
+		args struct {
+			dst image.Rectangle
+			src image.Image
+			at  image.Point
+		}
+	}{
+		{
+			name: "should compose inside the destination",
+			args: struct {
+				dst image.Rectangle
+				src image.Image
+				at  image.Point
+			}{dst: image.Rect(0, 0, 32, 32), src: generateRandomNRGBA(t, image.Rect(0, 0, 10, 12), 1), at: image.Pt(5, 7)},
+		},
+		{
+			name: "should clip at the top left",
+			args: struct {
+				dst image.Rectangle
+				src image.Image
+				at  image.Point
+			}{dst: image.Rect(-4, 2, 20, 20), src: generateRandomRGBA(t, image.Rect(3, 3, 13, 15), 2), at: image.Pt(-8, -1)},
+		},
+		{
+			name: "should clip at the bottom right",
+			args: struct {
+				dst image.Rectangle
+				src image.Image
+				at  image.Point
+			}{dst: image.Rect(0, 0, 16, 16), src: generateRandomYCbCr(t, image.Rect(0, 0, 10, 10), image.YCbCrSubsampleRatio420, 3), at: image.Pt(10, 12)},
+		},
+		{
+			name: "should compose a sub image",
+			args: struct {
+				dst image.Rectangle
+				src image.Image
+				at  image.Point
+			}{dst: image.Rect(0, 0, 16, 16), src: generateRandomNRGBA(t, image.Rect(0, 0, 20, 20), 4).SubImage(image.Rect(5, 6, 15, 13)), at: image.Pt(1, 2)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dst := generateRandomNRGBA(t, test.args.dst, 10)
+
+			//premultiplied reference with image/draw
+			expected := Premultiply(dst)
+			sb := test.args.src.Bounds()
+			r := sb.Add(test.args.at.Sub(sb.Min))
+			draw.Draw(expected, r, test.args.src, sb.Min, draw.Over)
+
+			if err := ComposeOver(dst, test.args.src, test.args.at); err != nil {
+				t.Fatalf("ComposeOver: unexpected error: %v\n", err)
+			}
+
+			actual := Premultiply(dst)
+			for y := test.args.dst.Min.Y; y < test.args.dst.Max.Y; y++ {
+				for x := test.args.dst.Min.X; x < test.args.dst.Max.X; x++ {
+					c, e := actual.RGBAAt(x, y), expected.RGBAAt(x, y)
+					if !within(c.R, e.R, 1) || !within(c.G, e.G, 1) || !within(c.B, e.B, 1) || !within(c.A, e.A, 1) {
+						format := fmt.Sprintf("\n") +
+							fmt.Sprintf("ComposeOver(%v, %T, %v)\n", test.args.dst, test.args.src, test.args.at) +
+							fmt.Sprintf("Expected pixel at x=%d, y=%d:\t %v\n", x, y, e) +
+							fmt.Sprintf("Actual pixel at x=%d, y=%d:\t %v\n", x, y, c)
+						t.Fatalf(format)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestComposeOverKnownValues(t *testing.T) {
+	dst := UniformNRGBA(color.NRGBA{0, 0, 255, 255}, image.Rect(0, 0, 3, 1))
+	src := generateStripesNRGBA(t, image.Rect(0, 0, 3, 1), []color.NRGBA{{255, 0, 0, 0}, {255, 0, 0, 128}, {255, 0, 0, 255}})
+
+	if err := ComposeOver(dst, src, image.Pt(0, 0)); err != nil {
+		t.Fatalf("ComposeOver: unexpected error: %v\n", err)
+	}
+
+	expected := []color.NRGBA{{0, 0, 255, 255}, {128, 0, 127, 255}, {255, 0, 0, 255}}
+	for x, e := range expected {
+		if c := dst.NRGBAAt(x, 0); c != e {
+			t.Errorf("ComposeOver: pixel %d is %v, expected %v\n", x, c, e)
+		}
+	}
+
+	//over a transparent destination the translucent source is kept exactly
+	dst = image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	src = generateStripesNRGBA(t, image.Rect(0, 0, 3, 1), []color.NRGBA{{10, 20, 30, 1}, {255, 0, 0, 128}, {40, 50, 60, 254}})
+	if err := ComposeOver(dst, src, image.Pt(0, 0)); err != nil {
+		t.Fatalf("ComposeOver: unexpected error: %v\n", err)
+	}
+	assertConvertedImage(t, src, dst, color.NRGBAModel, "\nComposeOver(transparent)\n")
+}
+
+func TestComposeOverOutside(t *testing.T) {
+	dst := generateRandomNRGBA(t, image.Rect(0, 0, 8, 8), 1)
+	expected := CloneNRGBA(dst)
+
+	if err := ComposeOver(dst, generateRandomNRGBA(t, image.Rect(0, 0, 4, 4), 2), image.Pt(8, 0)); err != nil {
+		t.Fatalf("ComposeOver: unexpected error: %v\n", err)
+	}
+	assertConvertedImage(t, expected, dst, color.NRGBAModel, "\nComposeOver(outside)\n")
+
+	if err := ComposeOver(dst, nil, image.Pt(0, 0)); !errors.Is(err, ErrNilImage) {
+		t.Errorf("ComposeOver(nil) = %v, expected %v\n", err, ErrNilImage)
+	}
+}
+
+func within(a, b uint8, tolerance int) bool {
+	d := int(a) - int(b)
+	return d >= -tolerance && d <= tolerance
+}
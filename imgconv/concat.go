@@ -0,0 +1,85 @@
+package imgconv
+
+import (
+	"fmt"
+	"image"
+)
+
+// ConcatH places the images imgs side by side from left to right and returns
+// the result as an *image.NRGBA image with its origin at (0, 0).
+// All images must have the same height; use ConcatHPadded otherwise.
+// A single image results in a converted copy of it.
+func ConcatH(imgs ...image.Image) (*image.NRGBA, error) {
+	return concat(imgs, true, false)
+}
+
+// ConcatHPadded is like ConcatH, but accepts images of different heights.
+// The images are aligned at the top and padded with transparent pixels.
+func ConcatHPadded(imgs ...image.Image) (*image.NRGBA, error) {
+	return concat(imgs, true, true)
+}
+
+// ConcatV places the images imgs below each other from top to bottom and
+// returns the result as an *image.NRGBA image with its origin at (0, 0).
+// All images must have the same width; use ConcatVPadded otherwise.
+// A single image results in a converted copy of it.
+func ConcatV(imgs ...image.Image) (*image.NRGBA, error) {
+	return concat(imgs, false, false)
+}
+
+// ConcatVPadded is like ConcatV, but accepts images of different widths.
+// The images are aligned at the left and padded with transparent pixels.
+func ConcatVPadded(imgs ...image.Image) (*image.NRGBA, error) {
+	return concat(imgs, false, true)
+}
+
+func concat(imgs []image.Image, horizontal, pad bool) (*image.NRGBA, error) {
+	if len(imgs) == 0 {
+		return nil, fmt.Errorf("no images to concatenate")
+	}
+
+	//length along the concatenation and the largest size across it
+	length, size := 0, 0
+	for idx, m := range imgs {
+		if m == nil {
+			return nil, fmt.Errorf("%w: image %d", ErrNilImage, idx)
+		}
+
+		l, s := m.Bounds().Dx(), m.Bounds().Dy()
+		if !horizontal {
+			l, s = s, l
+		}
+
+		if idx > 0 && s != size && !pad {
+			return nil, fmt.Errorf("image %d has a different size %v than image 0 %v", idx, m.Bounds().Size(), imgs[0].Bounds().Size())
+		}
+		if s > size {
+			size = s
+		}
+		if l > maxPixels-length {
+			return nil, fmt.Errorf("%w: concatenated length exceeds %d pixels", ErrImageTooLarge, maxPixels)
+		}
+		length += l
+	}
+
+	r := image.Rect(0, 0, length, size)
+	if !horizontal {
+		r = image.Rect(0, 0, size, length)
+	}
+	if !validSize(r) {
+		return nil, fmt.Errorf("%w: %v", ErrImageTooLarge, r)
+	}
+
+	img := image.NewNRGBA(r)
+	pt := image.Point{}
+	for _, m := range imgs {
+		convertNRGBAAt(img, m, pt)
+		if horizontal {
+			pt.X += m.Bounds().Dx()
+		} else {
+			pt.Y += m.Bounds().Dy()
+		}
+	}
+
+	return img, nil
+}
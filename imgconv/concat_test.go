@@ -0,0 +1,102 @@
+package imgconv
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestConcat(t *testing.T) {
+	a := generateRandomNRGBA(t, image.Rect(-3, 2, 2, 6), 1)
+	b := generateRandomRGBA(t, image.Rect(10, 10, 13, 14), 2)
+	c := generateRandomYCbCr(t, image.Rect(0, 0, 7, 4), image.YCbCrSubsampleRatio420, 3)
+	tall := generateRandomNRGBA(t, image.Rect(0, 0, 5, 9), 4)
+	wide := generateRandomNRGBA(t, image.Rect(0, 0, 9, 2), 5)
+
+	tests := []struct {
+		name     string
+		concat   func(...image.Image) (*image.NRGBA, error)
+		imgs     []image.Image
+		size     image.Point
+		expected []image.Point
+	}{
+		{name: "ConcatH", concat: ConcatH, imgs: []image.Image{a, b, c}, size: image.Pt(15, 4), expected: []image.Point{{0, 0}, {5, 0}, {8, 0}}},
+		{name: "ConcatH single image", concat: ConcatH, imgs: []image.Image{b}, size: image.Pt(3, 4), expected: []image.Point{{0, 0}}},
+		{name: "ConcatHPadded", concat: ConcatHPadded, imgs: []image.Image{a, tall, wide}, size: image.Pt(19, 9), expected: []image.Point{{0, 0}, {5, 0}, {10, 0}}},
+		{name: "ConcatV", concat: ConcatV, imgs: []image.Image{a, tall}, size: image.Pt(5, 13), expected: []image.Point{{0, 0}, {0, 4}}},
+		{name: "ConcatVPadded", concat: ConcatVPadded, imgs: []image.Image{b, wide, a}, size: image.Pt(9, 10), expected: []image.Point{{0, 0}, {0, 4}, {0, 6}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := test.concat(test.imgs...)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v\n", test.name, err)
+			}
+
+			if actual.Bounds() != (image.Rectangle{Max: test.size}) {
+				t.Fatalf("%s: expected bounds %v, got %v\n", test.name, image.Rectangle{Max: test.size}, actual.Bounds())
+			}
+
+			//every pixel belongs to exactly one source or is transparent padding
+			for y := 0; y < test.size.Y; y++ {
+				for x := 0; x < test.size.X; x++ {
+					expected := color.NRGBA{}
+					for idx, m := range test.imgs {
+						p := image.Pt(x, y).Sub(test.expected[idx]).Add(m.Bounds().Min)
+						if p.In(m.Bounds()) {
+							expected = color.NRGBAModel.Convert(m.At(p.X, p.Y)).(color.NRGBA)
+						}
+					}
+					if c := actual.NRGBAAt(x, y); c != expected {
+						format := fmt.Sprintf("\n") +
+							fmt.Sprintf("%s\n", test.name) +
+							fmt.Sprintf("Expected pixel at x=%d, y=%d:\t %v\n", x, y, expected) +
+							fmt.Sprintf("Actual pixel at x=%d, y=%d:\t %v\n", x, y, c)
+						t.Fatalf(format)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestConcatErrors(t *testing.T) {
+	a := generateRandomNRGBA(t, image.Rect(0, 0, 4, 4), 1)
+	b := generateRandomNRGBA(t, image.Rect(0, 0, 4, 5), 2)
+
+	if _, err := ConcatH(); err == nil {
+		t.Errorf("ConcatH(): expected an error\n")
+	}
+	if _, err := ConcatVPadded(); err == nil {
+		t.Errorf("ConcatVPadded(): expected an error\n")
+	}
+	if _, err := ConcatH(a, b); err == nil {
+		t.Errorf("ConcatH(4x4, 4x5): expected an error\n")
+	}
+	if _, err := ConcatV(a, b); err != nil {
+		t.Errorf("ConcatV(4x4, 4x5): unexpected error: %v\n", err)
+	}
+	if _, err := ConcatV(a, nil); !errors.Is(err, ErrNilImage) {
+		t.Errorf("ConcatV(a, nil) = %v, expected %v\n", err, ErrNilImage)
+	}
+
+	wide := boundedImage{image.NewUniform(color.White), image.Rect(0, 0, maxPixels/2+1, 1)}
+	if _, err := ConcatH(wide, wide); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("ConcatH(huge) = %v, expected %v\n", err, ErrImageTooLarge)
+	}
+}
+
+func TestConcatReturnsCopy(t *testing.T) {
+	m := generateRandomNRGBA(t, image.Rect(0, 0, 4, 4), 1)
+
+	actual, err := ConcatH(m)
+	if err != nil {
+		t.Fatalf("ConcatH: unexpected error: %v\n", err)
+	}
+	if &actual.Pix[0] == &m.Pix[0] {
+		t.Errorf("ConcatH(single image) returned the source pixels\n")
+	}
+}
@@ -0,0 +1,76 @@
+package imgconv
+
+import (
+	"context"
+	"image"
+)
+
+// bandPixels is the number of pixels converted between two checks of the context.
+const bandPixels = 1 << 18
+
+// ToNRGBAContext is like ToNRGBA, but converts m in bands of rows and
+// returns ctx.Err() as soon as ctx is done between two bands.
+func ToNRGBAContext(ctx context.Context, m image.Image) (*image.NRGBA, error) {
+	if img, ok := m.(*image.NRGBA); ok {
+		return img, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	img := image.NewNRGBA(m.Bounds())
+	err := convertBands(ctx, m, func(r image.Rectangle, band image.Image) {
+		convertNRGBA(img.SubImage(r).(*image.NRGBA), band)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return img, nil
+}
+
+// ToRGBAContext is like ToRGBA, but converts m in bands of rows and
+// returns ctx.Err() as soon as ctx is done between two bands.
+func ToRGBAContext(ctx context.Context, m image.Image) (*image.RGBA, error) {
+	if img, ok := m.(*image.RGBA); ok {
+		return img, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	img := image.NewRGBA(m.Bounds())
+	err := convertBands(ctx, m, func(r image.Rectangle, band image.Image) {
+		convertRGBA(img.SubImage(r).(*image.RGBA), band)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return img, nil
+}
+
+// convertBands calls convert for consecutive bands of rows of m with about
+// bandPixels pixels each and stops early if ctx is done.
+func convertBands(ctx context.Context, m image.Image, convert func(r image.Rectangle, band image.Image)) error {
+	b := m.Bounds()
+	if b.Empty() {
+		return nil
+	}
+
+	rows := bandPixels / b.Dx()
+	if rows < 1 {
+		rows = 1
+	}
+
+	for y := b.Min.Y; y < b.Max.Y; y += rows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		r := image.Rect(b.Min.X, y, b.Max.X, y+rows).Intersect(b)
+		convert(r, subImage(m, r))
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package imgconv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestToNRGBAContext(t *testing.T) {
+	bounds := image.Rect(-3, 5, 700, 900)
+
+	for _, src := range []image.Image{
+		generateRandomRGBA(t, bounds, 1),
+		generateRandomYCbCr(t, bounds, image.YCbCrSubsampleRatio420, 2),
+		customImage{generateRandomNRGBA(t, bounds, 3)},
+		generateRandomNRGBA(t, image.Rect(0, 0, 3*bandPixels, 2), 4),
+	} {
+		t.Run(fmt.Sprintf("%T %v", src, src.Bounds()), func(t *testing.T) {
+			actual, err := ToNRGBAContext(context.Background(), src)
+			if err != nil {
+				t.Fatalf("ToNRGBAContext: unexpected error: %v\n", err)
+			}
+			assertConvertedImage(t, src, actual, color.NRGBAModel, fmt.Sprintf("\nToNRGBAContext(%T)\n", src))
+
+			actualRGBA, err := ToRGBAContext(context.Background(), src)
+			if err != nil {
+				t.Fatalf("ToRGBAContext: unexpected error: %v\n", err)
+			}
+			assertConvertedImage(t, src, actualRGBA, color.RGBAModel, fmt.Sprintf("\nToRGBAContext(%T)\n", src))
+		})
+	}
+}
+
+func TestToNRGBAContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	m := generateRandomRGBA(t, image.Rect(0, 0, 16, 16), 1)
+	if _, err := ToNRGBAContext(ctx, m); !errors.Is(err, context.Canceled) {
+		t.Errorf("ToNRGBAContext(canceled) = %v, expected %v\n", err, context.Canceled)
+	}
+	if _, err := ToRGBAContext(ctx, generateRandomNRGBA(t, image.Rect(0, 0, 16, 16), 1)); !errors.Is(err, context.Canceled) {
+		t.Errorf("ToRGBAContext(canceled) = %v, expected %v\n", err, context.Canceled)
+	}
+}
+
+func TestToNRGBAContextCanceledDuringConversion(t *testing.T) {
+	//the generic path takes about a second for this image
+	m := boundedImage{image.NewUniform(color.RGBA{10, 20, 30, 40}), image.Rect(0, 0, 4000, 4000)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := ToNRGBAContext(ctx, m)
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ToNRGBAContext = %v, expected %v\n", err, context.DeadlineExceeded)
+	}
+	if elapsed > time.Second {
+		t.Errorf("ToNRGBAContext returned %v after the deadline, expected a fast return\n", elapsed)
+	}
+}
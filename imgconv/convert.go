@@ -0,0 +1,115 @@
+package imgconv
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// Convert converts any image m to the concrete stdlib image type matching model.
+// Supported models are color.NRGBAModel, color.RGBAModel, color.NRGBA64Model,
+// color.RGBA64Model, color.GrayModel, color.Gray16Model, color.AlphaModel,
+// color.CMYKModel and color.YCbCrModel, which produces 4:4:4 subsampled images.
+// Other models return an error.
+//
+// If m already has the requested type, m itself is returned without copying.
+func Convert(m image.Image, model color.Model) (image.Image, error) {
+	return convert(m, model, false)
+}
+
+// ConvertCopy is like Convert, but always returns a newly allocated image
+// that does not share its pixels with m.
+func ConvertCopy(m image.Image, model color.Model) (image.Image, error) {
+	return convert(m, model, true)
+}
+
+func convert(m image.Image, model color.Model, clone bool) (image.Image, error) {
+	switch model {
+	case color.NRGBAModel:
+		if clone {
+			return CloneNRGBA(m), nil
+		}
+		return ToNRGBA(m), nil
+
+	case color.RGBAModel:
+		if src, ok := m.(*image.RGBA); ok && clone {
+			return &image.RGBA{Pix: clonePix(src.Pix), Stride: src.Stride, Rect: src.Rect}, nil
+		}
+		return ToRGBA(m), nil
+
+	case color.NRGBA64Model:
+		if src, ok := m.(*image.NRGBA64); ok && clone {
+			return &image.NRGBA64{Pix: clonePix(src.Pix), Stride: src.Stride, Rect: src.Rect}, nil
+		}
+		return ToNRGBA64(m), nil
+
+	case color.RGBA64Model:
+		if src, ok := m.(*image.RGBA64); ok && clone {
+			return &image.RGBA64{Pix: clonePix(src.Pix), Stride: src.Stride, Rect: src.Rect}, nil
+		}
+		return ToRGBA64(m), nil
+
+	case color.GrayModel:
+		if src, ok := m.(*image.Gray); ok && clone {
+			return &image.Gray{Pix: clonePix(src.Pix), Stride: src.Stride, Rect: src.Rect}, nil
+		}
+		return ToGray(m), nil
+
+	case color.Gray16Model:
+		if src, ok := m.(*image.Gray16); ok && clone {
+			return &image.Gray16{Pix: clonePix(src.Pix), Stride: src.Stride, Rect: src.Rect}, nil
+		}
+		return ToGray16(m), nil
+
+	case color.AlphaModel:
+		if src, ok := m.(*image.Alpha); ok && !clone {
+			return src, nil
+		}
+		return ExtractAlpha(m), nil
+
+	case color.CMYKModel:
+		if src, ok := m.(*image.CMYK); ok {
+			if clone {
+				return &image.CMYK{Pix: clonePix(src.Pix), Stride: src.Stride, Rect: src.Rect}, nil
+			}
+			return src, nil
+		}
+		return toCMYK(m), nil
+
+	case color.YCbCrModel:
+		if src, ok := m.(*image.YCbCr); ok {
+			if clone {
+				img := *src
+				img.Y = clonePix(src.Y)
+				img.Cb = clonePix(src.Cb)
+				img.Cr = clonePix(src.Cr)
+				return &img, nil
+			}
+			return src, nil
+		}
+		return ToYCbCr(m, image.YCbCrSubsampleRatio444), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported color model")
+	}
+}
+
+// toCMYK converts any image m to an *image.CMYK image.
+func toCMYK(m image.Image) *image.CMYK {
+	b := m.Bounds()
+	img := image.NewCMYK(b)
+
+	for x := b.Min.X; x < b.Max.X; x++ {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			px := m.At(x, y)
+			px = color.CMYKModel.Convert(px)
+			img.Set(x, y, px)
+		}
+	}
+
+	return img
+}
+
+func clonePix(pix []byte) []byte {
+	return append([]byte(nil), pix...)
+}
@@ -0,0 +1,119 @@
+package imgconv
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	bounds := image.Rect(-3, 5, 14, 16)
+	src := generateRandomNRGBA(t, bounds, 1)
+
+	tests := []struct {
+		name         string
+		model        color.Model
+		expectedType image.Image
+	}{
+		{name: "should convert to nrgba", model: color.NRGBAModel, expectedType: &image.NRGBA{}},
+		{name: "should convert to rgba", model: color.RGBAModel, expectedType: &image.RGBA{}},
+		{name: "should convert to nrgba64", model: color.NRGBA64Model, expectedType: &image.NRGBA64{}},
+		{name: "should convert to rgba64", model: color.RGBA64Model, expectedType: &image.RGBA64{}},
+		{name: "should convert to gray", model: color.GrayModel, expectedType: &image.Gray{}},
+		{name: "should convert to gray16", model: color.Gray16Model, expectedType: &image.Gray16{}},
+		{name: "should convert to alpha", model: color.AlphaModel, expectedType: &image.Alpha{}},
+		{name: "should convert to cmyk", model: color.CMYKModel, expectedType: &image.CMYK{}},
+		{name: "should convert to ycbcr", model: color.YCbCrModel, expectedType: &image.YCbCr{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := Convert(src, test.model)
+			if err != nil {
+				t.Fatalf("Convert(%T, model) = (%v)\n", src, err)
+			}
+
+			format := fmt.Sprintf("\nConvert(%T, %T)\n", src, test.expectedType)
+			if fmt.Sprintf("%T", actual) != fmt.Sprintf("%T", test.expectedType) {
+				t.Fatalf("%sExpected type:\t %T\nActual type:\t %T\n", format, test.expectedType, actual)
+			}
+
+			if test.model == color.NRGBA64Model {
+				//nrgba images are widened by byte replication
+				for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+					for x := bounds.Min.X; x < bounds.Max.X; x++ {
+						c := src.NRGBAAt(x, y)
+						expected := color.NRGBA64{uint16(c.R) * 0x101, uint16(c.G) * 0x101, uint16(c.B) * 0x101, uint16(c.A) * 0x101}
+						if expected != actual.At(x, y) {
+							t.Fatalf("%sdifferent pixel at x=%d, y=%d\n", format, x, y)
+						}
+					}
+				}
+			} else if test.model == color.YCbCrModel {
+				//YCbCr images return the same color model but store converted values
+				for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+					for x := bounds.Min.X; x < bounds.Max.X; x++ {
+						expected := color.YCbCrModel.Convert(src.At(x, y))
+						if expected != actual.At(x, y) {
+							t.Fatalf("%sdifferent pixel at x=%d, y=%d\n", format, x, y)
+						}
+					}
+				}
+			} else {
+				assertConvertedImage(t, src, actual, test.model, format)
+			}
+
+			identity, err := Convert(actual, test.model)
+			if err != nil || identity != actual {
+				t.Errorf("%sExpected identity conversion to return the same image\n", format)
+			}
+
+			clone, err := ConvertCopy(actual, test.model)
+			if err != nil || clone == actual {
+				t.Fatalf("%sExpected ConvertCopy to return a new image\n", format)
+			}
+
+			assertConvertedImage(t, actual, clone, test.model, format)
+		})
+	}
+}
+
+func TestConvertCopyDoesNotSharePixels(t *testing.T) {
+	src := image.NewGray(image.Rect(0, 0, 4, 4))
+
+	actual, err := ConvertCopy(src, color.GrayModel)
+	if err != nil {
+		t.Fatalf("ConvertCopy(*image.Gray, color.GrayModel) = (%v)\n", err)
+	}
+
+	actual.(*image.Gray).Pix[0] = 255
+	if src.Pix[0] != 0 {
+		t.Errorf("Mutating the copy changed the source image\n")
+	}
+}
+
+func TestConvertUnsupportedModel(t *testing.T) {
+	tests := []struct {
+		name  string
+		model color.Model
+	}{
+		{
+			name:  "should return an error for a palette",
+			model: color.Palette{color.Black, color.White},
+		},
+		{
+			name:  "should return an error for a custom model",
+			model: color.ModelFunc(func(c color.Color) color.Color { return c }),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := Convert(image.NewNRGBA(image.Rect(0, 0, 1, 1)), test.model)
+			if err == nil || actual != nil {
+				t.Errorf("Convert(*image.NRGBA, %T) = (%v, %v), expected an error\n", test.model, actual, err)
+			}
+		})
+	}
+}
@@ -1,46 +1,705 @@
 package imgconv
 
 import (
+	"fmt"
 	"image"
 	"image/color"
+	"image/draw"
 )
 
+// maxPixels is the largest number of pixels an image may have to be
+// converted by the Try functions and resized. It matches the limit of the qoi package.
+const maxPixels = 400_000_000
+
 // ToNRGBA converts any image m to an *image.NRGBA image.
 // Any Image may be converted, but images that are not image.NRGBA might be converted lossily.
+// If m already is an *image.NRGBA, m itself is returned without copying;
+// use CloneNRGBA to get an image that does not share its pixels with m.
 func ToNRGBA(m image.Image) *image.NRGBA {
-	if m.ColorModel() == color.NRGBAModel {
-		return m.(*image.NRGBA)
+	if img, ok := m.(*image.NRGBA); ok {
+		return img
+	}
+	if img, ok := convertRegistered(m); ok {
+		return img
 	}
 
 	img := image.NewNRGBA(m.Bounds())
+	convertNRGBA(img, m)
+
+	return img
+}
+
+// UniformNRGBA returns an *image.NRGBA image with bounds r filled with the color c.
+func UniformNRGBA(c color.Color, r image.Rectangle) *image.NRGBA {
+	img := image.NewNRGBA(r)
+	if r.Empty() {
+		return img
+	}
+
+	px := color.NRGBAModel.Convert(c).(color.NRGBA)
+	copy(img.Pix, []byte{px.R, px.G, px.B, px.A})
+	for i := 4; i < len(img.Pix); i *= 2 {
+		copy(img.Pix[i:], img.Pix[:i])
+	}
+
+	return img
+}
+
+// validSize reports whether r contains at most maxPixels pixels.
+func validSize(r image.Rectangle) bool {
+	w, h := r.Dx(), r.Dy()
+	return w <= 0 || h <= 0 || w <= maxPixels/h
+}
+
+// convertNRGBA writes the pixels of m converted to NRGBA into img.
+// img must have the same bounds as m.
+func convertNRGBA(img *image.NRGBA, m image.Image) {
+	b := m.Bounds()
+
+	switch src := m.(type) {
+	case *image.NRGBA:
+		copyRows(img.Pix, img.Stride, img.PixOffset(b.Min.X, b.Min.Y), src.Pix, src.Stride, src.PixOffset(b.Min.X, b.Min.Y), 4*b.Dx(), b.Dy())
+
+	case *image.RGBA:
+		sRow := src.PixOffset(b.Min.X, b.Min.Y)
+		dRow := img.PixOffset(b.Min.X, b.Min.Y)
+		for y := b.Min.Y; y < b.Max.Y; y, sRow, dRow = y+1, sRow+src.Stride, dRow+img.Stride {
+			si, di := sRow, dRow
+			for x := b.Min.X; x < b.Max.X; x, si, di = x+1, si+4, di+4 {
+				s := src.Pix[si : si+4 : si+4]
+				d := img.Pix[di : di+4 : di+4]
+				switch a := s[3]; a {
+				case 0xff:
+					d[0], d[1], d[2], d[3] = s[0], s[1], s[2], 0xff
+				case 0:
+					d[0], d[1], d[2], d[3] = 0, 0, 0, 0
+				default:
+					//same rounding as color.NRGBAModel
+					a16 := uint32(a) * 0x101
+					d[0] = uint8((uint32(s[0]) * 0x101 * 0xffff / a16) >> 8)
+					d[1] = uint8((uint32(s[1]) * 0x101 * 0xffff / a16) >> 8)
+					d[2] = uint8((uint32(s[2]) * 0x101 * 0xffff / a16) >> 8)
+					d[3] = a
+				}
+			}
+		}
+
+	case *image.Gray:
+		sRow := src.PixOffset(b.Min.X, b.Min.Y)
+		dRow := img.PixOffset(b.Min.X, b.Min.Y)
+		for y := b.Min.Y; y < b.Max.Y; y, sRow, dRow = y+1, sRow+src.Stride, dRow+img.Stride {
+			si, di := sRow, dRow
+			for x := b.Min.X; x < b.Max.X; x, si, di = x+1, si+1, di+4 {
+				v := src.Pix[si]
+				d := img.Pix[di : di+4 : di+4]
+				d[0], d[1], d[2], d[3] = v, v, v, 0xff
+			}
+		}
+
+	case *image.Gray16:
+		sRow := src.PixOffset(b.Min.X, b.Min.Y)
+		dRow := img.PixOffset(b.Min.X, b.Min.Y)
+		for y := b.Min.Y; y < b.Max.Y; y, sRow, dRow = y+1, sRow+src.Stride, dRow+img.Stride {
+			si, di := sRow, dRow
+			for x := b.Min.X; x < b.Max.X; x, si, di = x+1, si+2, di+4 {
+				//high byte, same as color.NRGBAModel
+				v := src.Pix[si]
+				d := img.Pix[di : di+4 : di+4]
+				d[0], d[1], d[2], d[3] = v, v, v, 0xff
+			}
+		}
 
-	for x := m.Bounds().Min.X; x < m.Bounds().Max.X; x++ {
-		for y := m.Bounds().Min.Y; y < m.Bounds().Max.Y; y++ {
-			px := m.At(x, y)
-			px = color.NRGBAModel.Convert(px)
-			img.Set(x, y, px)
+	case *image.YCbCr:
+		dRow := img.PixOffset(b.Min.X, b.Min.Y)
+		for y := b.Min.Y; y < b.Max.Y; y, dRow = y+1, dRow+img.Stride {
+			di := dRow
+			for x := b.Min.X; x < b.Max.X; x, di = x+1, di+4 {
+				yi := src.YOffset(x, y)
+				ci := src.COffset(x, y)
+				r, g, b := color.YCbCrToRGB(src.Y[yi], src.Cb[ci], src.Cr[ci])
+				d := img.Pix[di : di+4 : di+4]
+				d[0], d[1], d[2], d[3] = r, g, b, 0xff
+			}
+		}
+
+	case *image.Alpha:
+		//premultiplied white, same as color.NRGBAModel
+		sRow := src.PixOffset(b.Min.X, b.Min.Y)
+		dRow := img.PixOffset(b.Min.X, b.Min.Y)
+		for y := b.Min.Y; y < b.Max.Y; y, sRow, dRow = y+1, sRow+src.Stride, dRow+img.Stride {
+			si, di := sRow, dRow
+			for x := b.Min.X; x < b.Max.X; x, si, di = x+1, si+1, di+4 {
+				d := img.Pix[di : di+4 : di+4]
+				if a := src.Pix[si]; a == 0 {
+					d[0], d[1], d[2], d[3] = 0, 0, 0, 0
+				} else {
+					d[0], d[1], d[2], d[3] = 0xff, 0xff, 0xff, a
+				}
+			}
+		}
+
+	case *image.Alpha16:
+		//premultiplied white with the high byte of alpha, same as color.NRGBAModel,
+		//so alpha values below 0x100 result in transparent white
+		sRow := src.PixOffset(b.Min.X, b.Min.Y)
+		dRow := img.PixOffset(b.Min.X, b.Min.Y)
+		for y := b.Min.Y; y < b.Max.Y; y, sRow, dRow = y+1, sRow+src.Stride, dRow+img.Stride {
+			si, di := sRow, dRow
+			for x := b.Min.X; x < b.Max.X; x, si, di = x+1, si+2, di+4 {
+				d := img.Pix[di : di+4 : di+4]
+				if hi, lo := src.Pix[si], src.Pix[si+1]; hi == 0 && lo == 0 {
+					d[0], d[1], d[2], d[3] = 0, 0, 0, 0
+				} else {
+					d[0], d[1], d[2], d[3] = 0xff, 0xff, 0xff, hi
+				}
+			}
+		}
+
+	case *image.Paletted:
+		lut := paletteNRGBA(src.Palette)
+		sRow := src.PixOffset(b.Min.X, b.Min.Y)
+		dRow := img.PixOffset(b.Min.X, b.Min.Y)
+		for y := b.Min.Y; y < b.Max.Y; y, sRow, dRow = y+1, sRow+src.Stride, dRow+img.Stride {
+			si, di := sRow, dRow
+			for x := b.Min.X; x < b.Max.X; x, si, di = x+1, si+1, di+4 {
+				c := &lut[src.Pix[si]]
+				d := img.Pix[di : di+4 : di+4]
+				d[0], d[1], d[2], d[3] = c.R, c.G, c.B, c.A
+			}
+		}
+
+	default:
+		if c, ok := convertRegistered(m); ok {
+			copyRows(img.Pix, img.Stride, img.PixOffset(b.Min.X, b.Min.Y), c.Pix, c.Stride, c.PixOffset(b.Min.X, b.Min.Y), 4*b.Dx(), b.Dy())
+			return
+		}
+
+		for x := b.Min.X; x < b.Max.X; x++ {
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				px := m.At(x, y)
+				px = color.NRGBAModel.Convert(px)
+				img.Set(x, y, px)
+			}
 		}
 	}
+}
+
+// paletteNRGBA converts the palette p to a lookup table for every possible index.
+// Indices without a palette entry map to transparent black, while
+// image.Paletted.At would panic for them.
+func paletteNRGBA(p color.Palette) *[256]color.NRGBA {
+	lut := new([256]color.NRGBA)
+	for idx, c := range p {
+		if idx >= len(lut) {
+			break
+		}
+		lut[idx] = color.NRGBAModel.Convert(c).(color.NRGBA)
+	}
+
+	return lut
+}
+
+// CloneNRGBA returns a copy of any image m as a newly allocated *image.NRGBA image.
+// Unlike ToNRGBA, the returned image never shares its pixels with m.
+func CloneNRGBA(m image.Image) *image.NRGBA {
+	img := image.NewNRGBA(m.Bounds())
+	convertNRGBA(img, m)
 
 	return img
 }
 
+// ToNRGBABuf is like CloneNRGBA, but uses dst as the pixel buffer of the
+// returned image instead of allocating one. The returned image aliases dst,
+// so dst must not be modified or reused while the image is in use.
+// It returns an error wrapping ErrBufferTooSmall if dst is shorter than
+// 4 bytes per pixel of m, and the errors of Validate for invalid images.
+func ToNRGBABuf(dst []byte, m image.Image) (*image.NRGBA, error) {
+	//small enough to be inlined, so img can stay on the stack of the caller
+	return convertNRGBABuf(new(image.NRGBA), dst, m)
+}
+
+// convertNRGBABuf converts m into img with dst as pixel buffer and returns img.
+func convertNRGBABuf(img *image.NRGBA, dst []byte, m image.Image) (*image.NRGBA, error) {
+	b, n, err := bufSize(dst, m)
+	if err != nil {
+		return nil, err
+	}
+
+	*img = image.NRGBA{Pix: dst[:n:n], Stride: 4 * b.Dx(), Rect: b}
+	convertNRGBA(img, m)
+
+	return img, nil
+}
+
 // ToRGBA converts any image m to an *image.RGBA image.
 // Any Image may be converted, but images that are not image.RGBA might be converted lossily.
+// If m already is an *image.RGBA, m itself is returned without copying.
 func ToRGBA(m image.Image) *image.RGBA {
-	if m.ColorModel() == color.RGBAModel {
-		return m.(*image.RGBA)
+	if img, ok := m.(*image.RGBA); ok {
+		return img
 	}
 
 	img := image.NewRGBA(m.Bounds())
+	convertRGBA(img, m)
+
+	return img
+}
+
+// convertRGBA writes the pixels of m converted to RGBA into img.
+// img must have the same bounds as m.
+func convertRGBA(img *image.RGBA, m image.Image) {
+	b := m.Bounds()
+
+	switch src := m.(type) {
+	case *image.RGBA:
+		copyRows(img.Pix, img.Stride, img.PixOffset(b.Min.X, b.Min.Y), src.Pix, src.Stride, src.PixOffset(b.Min.X, b.Min.Y), 4*b.Dx(), b.Dy())
+
+	case *image.NRGBA:
+		sRow := src.PixOffset(b.Min.X, b.Min.Y)
+		dRow := img.PixOffset(b.Min.X, b.Min.Y)
+		for y := b.Min.Y; y < b.Max.Y; y, sRow, dRow = y+1, sRow+src.Stride, dRow+img.Stride {
+			si, di := sRow, dRow
+			for x := b.Min.X; x < b.Max.X; x, si, di = x+1, si+4, di+4 {
+				s := src.Pix[si : si+4 : si+4]
+				d := img.Pix[di : di+4 : di+4]
+				switch a := uint32(s[3]); a {
+				case 0xff:
+					d[0], d[1], d[2], d[3] = s[0], s[1], s[2], 0xff
+				default:
+					//same rounding as color.RGBAModel
+					d[0] = uint8((uint32(s[0]) * 0x101 * a / 0xff) >> 8)
+					d[1] = uint8((uint32(s[1]) * 0x101 * a / 0xff) >> 8)
+					d[2] = uint8((uint32(s[2]) * 0x101 * a / 0xff) >> 8)
+					d[3] = s[3]
+				}
+			}
+		}
+
+	case *image.Alpha:
+		sRow := src.PixOffset(b.Min.X, b.Min.Y)
+		dRow := img.PixOffset(b.Min.X, b.Min.Y)
+		for y := b.Min.Y; y < b.Max.Y; y, sRow, dRow = y+1, sRow+src.Stride, dRow+img.Stride {
+			si, di := sRow, dRow
+			for x := b.Min.X; x < b.Max.X; x, si, di = x+1, si+1, di+4 {
+				a := src.Pix[si]
+				d := img.Pix[di : di+4 : di+4]
+				d[0], d[1], d[2], d[3] = a, a, a, a
+			}
+		}
+
+	case *image.Alpha16:
+		sRow := src.PixOffset(b.Min.X, b.Min.Y)
+		dRow := img.PixOffset(b.Min.X, b.Min.Y)
+		for y := b.Min.Y; y < b.Max.Y; y, sRow, dRow = y+1, sRow+src.Stride, dRow+img.Stride {
+			si, di := sRow, dRow
+			for x := b.Min.X; x < b.Max.X; x, si, di = x+1, si+2, di+4 {
+				//high byte, same as color.RGBAModel
+				a := src.Pix[si]
+				d := img.Pix[di : di+4 : di+4]
+				d[0], d[1], d[2], d[3] = a, a, a, a
+			}
+		}
+
+	default:
+		for x := b.Min.X; x < b.Max.X; x++ {
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				px := m.At(x, y)
+				px = color.RGBAModel.Convert(px)
+				img.Set(x, y, px)
+			}
+		}
+	}
+}
+
+// ToRGBABuf is like ToNRGBABuf, but returns an *image.RGBA image.
+func ToRGBABuf(dst []byte, m image.Image) (*image.RGBA, error) {
+	return convertRGBABuf(new(image.RGBA), dst, m)
+}
 
-	for x := m.Bounds().Min.X; x < m.Bounds().Max.X; x++ {
-		for y := m.Bounds().Min.Y; y < m.Bounds().Max.Y; y++ {
-			px := m.At(x, y)
-			px = color.RGBAModel.Convert(px)
-			img.Set(x, y, px)
+// convertRGBABuf converts m into img with dst as pixel buffer and returns img.
+func convertRGBABuf(img *image.RGBA, dst []byte, m image.Image) (*image.RGBA, error) {
+	b, n, err := bufSize(dst, m)
+	if err != nil {
+		return nil, err
+	}
+
+	*img = image.RGBA{Pix: dst[:n:n], Stride: 4 * b.Dx(), Rect: b}
+	convertRGBA(img, m)
+
+	return img, nil
+}
+
+// bufSize validates m and returns its bounds and the number of bytes
+// a 4 bytes per pixel image of m needs, if dst is large enough.
+func bufSize(dst []byte, m image.Image) (image.Rectangle, int, error) {
+	if err := Validate(m, maxPixels); err != nil {
+		return image.Rectangle{}, 0, err
+	}
+
+	b := m.Bounds()
+	n := 4 * b.Dx() * b.Dy()
+	if len(dst) < n {
+		return image.Rectangle{}, 0, fmt.Errorf("%w: %d bytes, need %d", ErrBufferTooSmall, len(dst), n)
+	}
+
+	return b, n, nil
+}
+
+// ConvertInto converts src into the caller provided image dst, using the
+// color model of dst. dst and src must have the same bounds.
+// This allows reusing dst for many conversions instead of allocating a new image.
+func ConvertInto(dst draw.Image, src image.Image) error {
+	if dst.Bounds() != src.Bounds() {
+		return fmt.Errorf("image bounds differ: %v != %v", dst.Bounds(), src.Bounds())
+	}
+
+	switch img := dst.(type) {
+	case *image.NRGBA:
+		convertNRGBA(img, src)
+
+	case *image.RGBA:
+		convertRGBA(img, src)
+
+	default:
+		b := src.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				dst.Set(x, y, src.At(x, y))
+			}
+		}
+	}
+
+	return nil
+}
+
+// copyRows copies rows of rowSize bytes from src to dst.
+func copyRows(dst []byte, dstStride, dstOffset int, src []byte, srcStride, srcOffset int, rowSize, rows int) {
+	if rowSize <= 0 {
+		return
+	}
+
+	for y := 0; y < rows; y++ {
+		copy(dst[dstOffset:dstOffset+rowSize], src[srcOffset:srcOffset+rowSize])
+		dstOffset += dstStride
+		srcOffset += srcStride
+	}
+}
+
+// ToRGBA64 converts any image m to an *image.RGBA64 image.
+// Any Image may be converted, but images that are not image.RGBA64 might be converted lossily.
+func ToRGBA64(m image.Image) *image.RGBA64 {
+	if img, ok := m.(*image.RGBA64); ok {
+		return img
+	}
+
+	b := m.Bounds()
+	img := image.NewRGBA64(b)
+
+	switch src := m.(type) {
+	case *image.NRGBA64:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			si := src.PixOffset(b.Min.X, y)
+			di := img.PixOffset(b.Min.X, y)
+			for x := b.Min.X; x < b.Max.X; x, si, di = x+1, si+8, di+8 {
+				s := src.Pix[si : si+8 : si+8]
+				d := img.Pix[di : di+8 : di+8]
+				a := uint32(s[6])<<8 | uint32(s[7])
+				for c := 0; c < 6; c += 2 {
+					v := (uint32(s[c])<<8 | uint32(s[c+1])) * a / 0xffff
+					d[c+0] = uint8(v >> 8)
+					d[c+1] = uint8(v)
+				}
+				d[6] = s[6]
+				d[7] = s[7]
+			}
+		}
+
+	case *image.NRGBA:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			si := src.PixOffset(b.Min.X, y)
+			di := img.PixOffset(b.Min.X, y)
+			for x := b.Min.X; x < b.Max.X; x, si, di = x+1, si+4, di+8 {
+				s := src.Pix[si : si+4 : si+4]
+				d := img.Pix[di : di+8 : di+8]
+				a := uint32(s[3])
+				for c := 0; c < 3; c++ {
+					v := uint32(s[c]) * 0x101 * a / 0xff
+					d[2*c+0] = uint8(v >> 8)
+					d[2*c+1] = uint8(v)
+				}
+				d[6] = s[3]
+				d[7] = s[3]
+			}
+		}
+
+	default:
+		for x := b.Min.X; x < b.Max.X; x++ {
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				px := m.At(x, y)
+				px = color.RGBA64Model.Convert(px)
+				img.Set(x, y, px)
+			}
+		}
+	}
+
+	return img
+}
+
+// ToNRGBA64 converts any image m to an *image.NRGBA64 image.
+// *image.NRGBA images are widened losslessly by byte replication, while
+// color.NRGBA64Model would round trip through alpha-premultiplied values.
+// If m already is an *image.NRGBA64, m itself is returned without copying.
+func ToNRGBA64(m image.Image) *image.NRGBA64 {
+	if img, ok := m.(*image.NRGBA64); ok {
+		return img
+	}
+
+	b := m.Bounds()
+	img := image.NewNRGBA64(b)
+
+	switch src := m.(type) {
+	case *image.NRGBA:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			si := src.PixOffset(b.Min.X, y)
+			di := img.PixOffset(b.Min.X, y)
+			for x := b.Min.X; x < b.Max.X; x, si, di = x+1, si+4, di+8 {
+				s := src.Pix[si : si+4 : si+4]
+				d := img.Pix[di : di+8 : di+8]
+				d[0], d[1] = s[0], s[0]
+				d[2], d[3] = s[1], s[1]
+				d[4], d[5] = s[2], s[2]
+				d[6], d[7] = s[3], s[3]
+			}
+		}
+
+	default:
+		for x := b.Min.X; x < b.Max.X; x++ {
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				px := m.At(x, y)
+				px = color.NRGBA64Model.Convert(px)
+				img.Set(x, y, px)
+			}
+		}
+	}
+
+	return img
+}
+
+// ToNRGBAFrom64 reduces the 16-bit image m to an *image.NRGBA image.
+// Unlike color.NRGBAModel, which keeps the high byte, every channel is rounded
+// to the nearest 8-bit value. If dither is true, the rounding error is diffused
+// to the neighbouring pixels with Floyd–Steinberg error diffusion, which turns
+// the bands of smooth 16-bit gradients into fine patterns.
+func ToNRGBAFrom64(m *image.NRGBA64, dither bool) *image.NRGBA {
+	img := image.NewNRGBA(m.Bounds())
+	if dither {
+		ditherFloydSteinberg64(img, m)
+		return img
+	}
+
+	b := m.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		si := m.PixOffset(b.Min.X, y)
+		di := img.PixOffset(b.Min.X, y)
+		for x := b.Min.X; x < b.Max.X; x, si, di = x+1, si+8, di+4 {
+			s := m.Pix[si : si+8 : si+8]
+			d := img.Pix[di : di+4 : di+4]
+			d[0] = round16To8(uint32(s[0])<<8 | uint32(s[1]))
+			d[1] = round16To8(uint32(s[2])<<8 | uint32(s[3]))
+			d[2] = round16To8(uint32(s[4])<<8 | uint32(s[5]))
+			d[3] = round16To8(uint32(s[6])<<8 | uint32(s[7]))
+		}
+	}
+
+	return img
+}
+
+// round16To8 returns the 8-bit value nearest to the 16-bit value v.
+func round16To8(v uint32) uint8 {
+	return uint8((v*0xff + 0x7fff) / 0xffff)
+}
+
+// ToGray converts any image m to an *image.Gray image using the same
+// luminance weights as color.GrayModel.
+func ToGray(m image.Image) *image.Gray {
+	return ToGrayLuma(m, LumaRec601)
+}
+
+// ToGrayLuma converts any image m to an *image.Gray image using the
+// luminance weights l.
+func ToGrayLuma(m image.Image, l Luma) *image.Gray {
+	if img, ok := m.(*image.Gray); ok {
+		return img
+	}
+
+	wr, wg, wb := l.weights()
+	b := m.Bounds()
+	img := image.NewGray(b)
+
+	switch src := m.(type) {
+	case *image.NRGBA:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			si := src.PixOffset(b.Min.X, y)
+			di := img.PixOffset(b.Min.X, y)
+			for x := b.Min.X; x < b.Max.X; x, si, di = x+1, si+4, di+1 {
+				s := src.Pix[si : si+4 : si+4]
+				a := uint32(s[3])
+				r := uint32(s[0]) * 0x101 * a / 0xff
+				g := uint32(s[1]) * 0x101 * a / 0xff
+				b := uint32(s[2]) * 0x101 * a / 0xff
+				img.Pix[di] = uint8((wr*r + wg*g + wb*b + 1<<15) >> 24)
+			}
+		}
+
+	case *image.RGBA:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			si := src.PixOffset(b.Min.X, y)
+			di := img.PixOffset(b.Min.X, y)
+			for x := b.Min.X; x < b.Max.X; x, si, di = x+1, si+4, di+1 {
+				s := src.Pix[si : si+4 : si+4]
+				r := uint32(s[0]) * 0x101
+				g := uint32(s[1]) * 0x101
+				b := uint32(s[2]) * 0x101
+				img.Pix[di] = uint8((wr*r + wg*g + wb*b + 1<<15) >> 24)
+			}
+		}
+
+	default:
+		for x := b.Min.X; x < b.Max.X; x++ {
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				r, g, b, _ := m.At(x, y).RGBA()
+				img.SetGray(x, y, color.Gray{uint8((wr*r + wg*g + wb*b + 1<<15) >> 24)})
+			}
+		}
+	}
+
+	return img
+}
+
+// ToGray16 converts any image m to an *image.Gray16 image using the same
+// luminance weights as color.Gray16Model.
+func ToGray16(m image.Image) *image.Gray16 {
+	return ToGray16Luma(m, LumaRec601)
+}
+
+// ToGray16Luma converts any image m to an *image.Gray16 image using the
+// luminance weights l.
+func ToGray16Luma(m image.Image, l Luma) *image.Gray16 {
+	if img, ok := m.(*image.Gray16); ok {
+		return img
+	}
+
+	wr, wg, wb := l.weights()
+	b := m.Bounds()
+	img := image.NewGray16(b)
+
+	switch src := m.(type) {
+	case *image.NRGBA64:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			si := src.PixOffset(b.Min.X, y)
+			di := img.PixOffset(b.Min.X, y)
+			for x := b.Min.X; x < b.Max.X; x, si, di = x+1, si+8, di+2 {
+				s := src.Pix[si : si+8 : si+8]
+				a := uint32(s[6])<<8 | uint32(s[7])
+				r := (uint32(s[0])<<8 | uint32(s[1])) * a / 0xffff
+				g := (uint32(s[2])<<8 | uint32(s[3])) * a / 0xffff
+				b := (uint32(s[4])<<8 | uint32(s[5])) * a / 0xffff
+				v := (wr*r + wg*g + wb*b + 1<<15) >> 16
+				img.Pix[di+0] = uint8(v >> 8)
+				img.Pix[di+1] = uint8(v)
+			}
+		}
+
+	case *image.RGBA64:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			si := src.PixOffset(b.Min.X, y)
+			di := img.PixOffset(b.Min.X, y)
+			for x := b.Min.X; x < b.Max.X; x, si, di = x+1, si+8, di+2 {
+				s := src.Pix[si : si+8 : si+8]
+				r := uint32(s[0])<<8 | uint32(s[1])
+				g := uint32(s[2])<<8 | uint32(s[3])
+				b := uint32(s[4])<<8 | uint32(s[5])
+				v := (wr*r + wg*g + wb*b + 1<<15) >> 16
+				img.Pix[di+0] = uint8(v >> 8)
+				img.Pix[di+1] = uint8(v)
+			}
+		}
+
+	case *image.Gray:
+		//the weights add up to 1, so gray values stay the same
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			si := src.PixOffset(b.Min.X, y)
+			di := img.PixOffset(b.Min.X, y)
+			for x := b.Min.X; x < b.Max.X; x, si, di = x+1, si+1, di+2 {
+				img.Pix[di+0] = src.Pix[si]
+				img.Pix[di+1] = src.Pix[si]
+			}
+		}
+
+	default:
+		for x := b.Min.X; x < b.Max.X; x++ {
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				r, g, b, _ := m.At(x, y).RGBA()
+				img.SetGray16(x, y, color.Gray16{uint16((wr*r + wg*g + wb*b + 1<<15) >> 16)})
+			}
 		}
 	}
 
 	return img
 }
+
+// ExtractAlpha returns the alpha channel of any image m as an *image.Alpha image.
+func ExtractAlpha(m image.Image) *image.Alpha {
+	b := m.Bounds()
+	img := image.NewAlpha(b)
+
+	switch src := m.(type) {
+	case *image.NRGBA:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			si := src.PixOffset(b.Min.X, y) + 3
+			di := img.PixOffset(b.Min.X, y)
+			for x := b.Min.X; x < b.Max.X; x, si, di = x+1, si+4, di+1 {
+				img.Pix[di] = src.Pix[si]
+			}
+		}
+
+	case *image.RGBA:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			si := src.PixOffset(b.Min.X, y) + 3
+			di := img.PixOffset(b.Min.X, y)
+			for x := b.Min.X; x < b.Max.X; x, si, di = x+1, si+4, di+1 {
+				img.Pix[di] = src.Pix[si]
+			}
+		}
+
+	case *image.Alpha:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			copy(img.Pix[img.PixOffset(b.Min.X, y):], src.Pix[src.PixOffset(b.Min.X, y):src.PixOffset(b.Max.X, y)])
+		}
+
+	case *image.Gray, *image.Gray16, *image.YCbCr, *image.CMYK:
+		fill(img.Pix, 0xff)
+
+	default:
+		for x := b.Min.X; x < b.Max.X; x++ {
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				_, _, _, a := m.At(x, y).RGBA()
+				img.SetAlpha(x, y, color.Alpha{uint8(a >> 8)})
+			}
+		}
+	}
+
+	return img
+}
+
+// fill sets every byte of pix to v.
+func fill(pix []byte, v byte) {
+	if len(pix) == 0 {
+		return
+	}
+
+	pix[0] = v
+	for i := 1; i < len(pix); i *= 2 {
+		copy(pix[i:], pix[:i])
+	}
+}
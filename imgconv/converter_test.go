@@ -0,0 +1,1011 @@
+package imgconv
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"math/rand"
+	"testing"
+)
+
+func TestToNRGBA(t *testing.T) {
+	bounds := image.Rect(-3, 5, 14, 16)
+
+	gray := image.NewGray(bounds)
+	rand.New(rand.NewSource(3)).Read(gray.Pix)
+
+	gray16 := image.NewGray16(bounds)
+	rand.New(rand.NewSource(4)).Read(gray16.Pix)
+
+	//every combination of color value and alpha
+	allRGBA := image.NewRGBA(image.Rect(0, 0, 256, 256))
+	for a := 0; a < 256; a++ {
+		for c := 0; c <= a; c++ {
+			allRGBA.SetRGBA(c, a, color.RGBA{uint8(c), uint8(a - c), uint8(c / 2), uint8(a)})
+		}
+	}
+
+	tests := []struct {
+		name string
+		args struct {
+			m image.Image
+		}
+	}{
+		{
+			name: "should convert rgba image",
+			args: struct{ m image.Image }{
+				m: generateRandomRGBA(t, bounds, 1),
+			},
+		},
+		{
+			name: "should convert rgba sub image",
+			args: struct{ m image.Image }{
+				m: generateRandomRGBA(t, bounds, 2).SubImage(image.Rect(0, 7, 5, 12)),
+			},
+		},
+		{
+			name: "should convert gray image",
+			args: struct{ m image.Image }{
+				m: gray,
+			},
+		},
+		{
+			name: "should convert gray sub image",
+			args: struct{ m image.Image }{
+				m: gray.SubImage(image.Rect(0, 7, 5, 12)),
+			},
+		},
+		{
+			name: "should convert gray16 image",
+			args: struct{ m image.Image }{
+				m: gray16,
+			},
+		},
+		{
+			name: "should convert gray16 sub image",
+			args: struct{ m image.Image }{
+				m: gray16.SubImage(image.Rect(0, 7, 5, 12)),
+			},
+		},
+		{
+			name: "should convert every rgba value",
+			args: struct{ m image.Image }{
+				m: allRGBA,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := ToNRGBA(test.args.m)
+
+			format := fmt.Sprintf("\nToNRGBA(%T)\n", test.args.m)
+			assertConvertedImage(t, test.args.m, actual, color.NRGBAModel, format)
+		})
+	}
+}
+
+func TestToNRGBAFromYCbCr(t *testing.T) {
+	ratios := []image.YCbCrSubsampleRatio{
+		image.YCbCrSubsampleRatio444,
+		image.YCbCrSubsampleRatio422,
+		image.YCbCrSubsampleRatio420,
+		image.YCbCrSubsampleRatio440,
+		image.YCbCrSubsampleRatio411,
+		image.YCbCrSubsampleRatio410,
+	}
+	rects := []image.Rectangle{
+		image.Rect(0, 0, 16, 16),
+		image.Rect(0, 0, 13, 7),
+		image.Rect(-3, -5, 10, 4),
+		image.Rect(1, 1, 2, 2),
+	}
+
+	for _, ratio := range ratios {
+		for idx, r := range rects {
+			m := generateRandomYCbCr(t, r, ratio, int64(idx))
+			sub := m.SubImage(image.Rect(r.Min.X+1, r.Min.Y, r.Max.X, r.Max.Y-1)).(*image.YCbCr)
+
+			for _, src := range []*image.YCbCr{m, sub} {
+				t.Run(fmt.Sprintf("%v %v", ratio, src.Bounds()), func(t *testing.T) {
+					actual := ToNRGBA(src)
+
+					for y := src.Bounds().Min.Y; y < src.Bounds().Max.Y; y++ {
+						for x := src.Bounds().Min.X; x < src.Bounds().Max.X; x++ {
+							c := src.YCbCrAt(x, y)
+							r, g, b := color.YCbCrToRGB(c.Y, c.Cb, c.Cr)
+							if expected := (color.NRGBA{r, g, b, 255}); actual.NRGBAAt(x, y) != expected {
+								t.Fatalf("different pixel at x=%d, y=%d: Expected: %+v - Actual: %+v\n", x, y, expected, actual.NRGBAAt(x, y))
+							}
+						}
+					}
+
+					format := fmt.Sprintf("\nToNRGBA(%T)\n", src)
+					assertConvertedImage(t, src, actual, color.NRGBAModel, format)
+				})
+			}
+		}
+	}
+}
+
+func TestToNRGBAFromPaletted(t *testing.T) {
+	palette := color.Palette{
+		color.NRGBA{255, 0, 0, 255},
+		color.RGBA{0, 100, 0, 200},
+		color.Gray{77},
+		color.NRGBA64{0x1234, 0x5678, 0x9abc, 0x8000},
+		color.Transparent,
+	}
+
+	m := image.NewPaletted(image.Rect(-3, 2, 17, 13), palette)
+	rng := rand.New(rand.NewSource(1))
+	for i := range m.Pix {
+		m.Pix[i] = uint8(rng.Intn(len(palette)))
+	}
+
+	for _, src := range []image.Image{m, m.SubImage(image.Rect(0, 4, 9, 11))} {
+		t.Run(fmt.Sprintf("%v", src.Bounds()), func(t *testing.T) {
+			actual := ToNRGBA(src)
+			expected := ToNRGBA(customImage{src})
+
+			format := fmt.Sprintf("\nToNRGBA(%T)\n", src)
+			assertConvertedImage(t, expected, actual, color.NRGBAModel, format)
+		})
+	}
+}
+
+func TestToNRGBAFromPalettedOutOfRange(t *testing.T) {
+	m := image.NewPaletted(image.Rect(0, 0, 3, 1), color.Palette{color.White})
+	m.Pix = []uint8{0, 1, 255}
+
+	actual := ToNRGBA(m)
+	expected := []color.NRGBA{{255, 255, 255, 255}, {}, {}}
+	for x, e := range expected {
+		if c := actual.NRGBAAt(x, 0); c != e {
+			t.Errorf("ToNRGBA(*image.Paletted): index %d: expected %v, got %v\n", m.Pix[x], e, c)
+		}
+	}
+}
+
+func TestConvertAlphaImages(t *testing.T) {
+	//every 16-bit alpha value
+	alpha16 := image.NewAlpha16(image.Rect(-7, 3, 249, 259))
+	for i := 0; i < 1<<16; i++ {
+		alpha16.Pix[2*i], alpha16.Pix[2*i+1] = uint8(i>>8), uint8(i)
+	}
+
+	alpha := image.NewAlpha(image.Rect(2, -1, 18, 15))
+	for i := range alpha.Pix {
+		alpha.Pix[i] = uint8(i)
+	}
+
+	for _, src := range []image.Image{
+		alpha16,
+		alpha16.SubImage(image.Rect(0, 10, 100, 40)),
+		alpha,
+		alpha.SubImage(image.Rect(5, 0, 9, 13)),
+	} {
+		t.Run(fmt.Sprintf("%T %v", src, src.Bounds()), func(t *testing.T) {
+			format := fmt.Sprintf("\nToNRGBA(%T)\n", src)
+			assertConvertedImage(t, src, ToNRGBA(src), color.NRGBAModel, format)
+
+			format = fmt.Sprintf("\nToRGBA(%T)\n", src)
+			assertConvertedImage(t, src, ToRGBA(src), color.RGBAModel, format)
+		})
+	}
+}
+
+func TestToNRGBAReturnsNRGBAImage(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+
+	if actual := ToNRGBA(m); actual != m {
+		t.Errorf("ToNRGBA(*image.NRGBA) = %p, expected %p\n", actual, m)
+	}
+}
+
+func TestConvertCustomImageTypes(t *testing.T) {
+	bounds := image.Rect(-3, 5, 14, 16)
+	nrgba := customImage{generateRandomNRGBA(t, bounds, 1)}
+	rgba := customImage{generateRandomRGBA(t, bounds, 2)}
+
+	tests := []struct {
+		name    string
+		convert func(image.Image) image.Image
+		model   color.Model
+	}{
+		{
+			name:    "should convert custom nrgba image with ToNRGBA",
+			convert: func(m image.Image) image.Image { return ToNRGBA(m) },
+			model:   color.NRGBAModel,
+		},
+		{
+			name:    "should convert custom rgba image with ToRGBA",
+			convert: func(m image.Image) image.Image { return ToRGBA(m) },
+			model:   color.RGBAModel,
+		},
+		{
+			name:    "should convert custom image with CloneNRGBA",
+			convert: func(m image.Image) image.Image { return CloneNRGBA(m) },
+			model:   color.NRGBAModel,
+		},
+	}
+
+	for _, test := range tests {
+		for _, m := range []image.Image{nrgba, rgba} {
+			t.Run(fmt.Sprintf("%s from %v", test.name, m.ColorModel() == color.NRGBAModel), func(t *testing.T) {
+				actual := test.convert(m)
+
+				format := fmt.Sprintf("\n%s\n", test.name)
+				assertConvertedImage(t, m, actual, test.model, format)
+			})
+		}
+	}
+}
+
+func TestCloneNRGBA(t *testing.T) {
+	bounds := image.Rect(-3, 5, 14, 16)
+
+	tests := []struct {
+		name string
+		args struct {
+			m image.Image
+		}
+	}{
+		{
+			name: "should clone nrgba image",
+			args: struct{ m image.Image }{
+				m: generateRandomNRGBA(t, bounds, 1),
+			},
+		},
+		{
+			name: "should clone nrgba sub image",
+			args: struct{ m image.Image }{
+				m: generateRandomNRGBA(t, bounds, 2).SubImage(image.Rect(0, 7, 5, 12)),
+			},
+		},
+		{
+			name: "should clone rgba image",
+			args: struct{ m image.Image }{
+				m: generateRandomRGBA(t, bounds, 3),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := CloneNRGBA(test.args.m)
+
+			format := fmt.Sprintf("\nCloneNRGBA(%T)\n", test.args.m)
+			assertConvertedImage(t, test.args.m, actual, color.NRGBAModel, format)
+
+			pt := test.args.m.Bounds().Min
+			expected := test.args.m.At(pt.X, pt.Y)
+			actual.SetNRGBA(pt.X, pt.Y, color.NRGBA{1, 2, 3, 4})
+			actual.Pix[len(actual.Pix)-1]++
+
+			if test.args.m.At(pt.X, pt.Y) != expected {
+				t.Errorf("%sMutating the clone changed the source image\n", format)
+			}
+		})
+	}
+}
+
+func TestToRGBA(t *testing.T) {
+	bounds := image.Rect(-3, 5, 14, 16)
+
+	tests := []struct {
+		name string
+		args struct {
+			m image.Image
+		}
+	}{
+		{
+			name: "should convert nrgba image",
+			args: struct{ m image.Image }{
+				m: generateRandomNRGBA(t, bounds, 1),
+			},
+		},
+		{
+			name: "should convert nrgba sub image",
+			args: struct{ m image.Image }{
+				m: generateRandomNRGBA(t, bounds, 2).SubImage(image.Rect(0, 7, 5, 12)),
+			},
+		},
+		{
+			name: "should convert ycbcr image",
+			args: struct{ m image.Image }{
+				m: generateRandomYCbCr(t, bounds, image.YCbCrSubsampleRatio420, 3),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := ToRGBA(test.args.m)
+
+			format := fmt.Sprintf("\nToRGBA(%T)\n", test.args.m)
+			assertConvertedImage(t, test.args.m, actual, color.RGBAModel, format)
+		})
+	}
+}
+
+func TestConvertInto(t *testing.T) {
+	bounds := image.Rect(-3, 5, 14, 16)
+
+	tests := []struct {
+		name string
+		args struct {
+			dst draw.Image
+			src image.Image
+		}
+		expectError bool
+	}{
+		{
+			name: "should convert nrgba into rgba",
+			args: struct {
+				dst draw.Image
+				src image.Image
+			}{
+				dst: image.NewRGBA(bounds),
+				src: generateRandomNRGBA(t, bounds, 1),
+			},
+		},
+		{
+			name: "should convert ycbcr into nrgba",
+			args: struct {
+				dst draw.Image
+				src image.Image
+			}{
+				dst: image.NewNRGBA(bounds),
+				src: generateRandomYCbCr(t, bounds, image.YCbCrSubsampleRatio420, 2),
+			},
+		},
+		{
+			name: "should copy nrgba into nrgba",
+			args: struct {
+				dst draw.Image
+				src image.Image
+			}{
+				dst: generateRandomNRGBA(t, bounds, 3),
+				src: generateRandomNRGBA(t, bounds, 4),
+			},
+		},
+		{
+			name: "should convert rgba into gray",
+			args: struct {
+				dst draw.Image
+				src image.Image
+			}{
+				dst: image.NewGray(bounds),
+				src: generateRandomRGBA(t, bounds, 5),
+			},
+		},
+		{
+			name: "should return an error if bounds differ",
+			args: struct {
+				dst draw.Image
+				src image.Image
+			}{
+				dst: image.NewNRGBA(image.Rect(0, 0, 17, 11)),
+				src: generateRandomNRGBA(t, bounds, 6),
+			},
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ConvertInto(test.args.dst, test.args.src)
+			if actualError := err != nil; actualError != test.expectError {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("ConvertInto(%T, %T) = (%v)\n", test.args.dst, test.args.src, err) +
+					fmt.Sprintf("Expected error:\t %t\n", test.expectError) +
+					fmt.Sprintf("Actual error:\t %t\n", actualError)
+				t.Fatalf(format)
+			}
+
+			if test.expectError {
+				return
+			}
+
+			format := fmt.Sprintf("\nConvertInto(%T, %T)\n", test.args.dst, test.args.src)
+			assertConvertedImage(t, test.args.src, test.args.dst, test.args.dst.ColorModel(), format)
+		})
+	}
+}
+
+func TestConvertIntoDoesNotAllocate(t *testing.T) {
+	bounds := image.Rect(0, 0, 64, 48)
+
+	tests := []struct {
+		name string
+		dst  draw.Image
+		src  image.Image
+	}{
+		{
+			name: "nrgba into rgba",
+			dst:  image.NewRGBA(bounds),
+			src:  generateRandomNRGBA(t, bounds, 1),
+		},
+		{
+			name: "ycbcr into nrgba",
+			dst:  image.NewNRGBA(bounds),
+			src:  generateRandomYCbCr(t, bounds, image.YCbCrSubsampleRatio420, 2),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			allocs := testing.AllocsPerRun(10, func() {
+				err := ConvertInto(test.dst, test.src)
+				if err != nil {
+					t.Fatal(err)
+				}
+			})
+
+			if allocs > 0 {
+				t.Errorf("expected no allocations, got %v\n", allocs)
+			}
+		})
+	}
+}
+
+func TestConvertPaddedImages(t *testing.T) {
+	bounds := image.Rect(-5, -7, 6, 2)
+
+	nrgba := generatePaddedNRGBA(t, bounds, 12, 1)
+	rgba := ToRGBA(nrgba)
+	paddedRGBA := &image.RGBA{Pix: make([]byte, len(nrgba.Pix)), Stride: nrgba.Stride, Rect: bounds}
+	err := ConvertInto(paddedRGBA, rgba)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		src     image.Image
+		convert func(image.Image) image.Image
+		model   color.Model
+	}{
+		{
+			name:    "should clone padded nrgba image",
+			src:     nrgba,
+			convert: func(m image.Image) image.Image { return CloneNRGBA(m) },
+			model:   color.NRGBAModel,
+		},
+		{
+			name:    "should clone padded nrgba sub image",
+			src:     nrgba.SubImage(image.Rect(-3, -6, 4, 0)),
+			convert: func(m image.Image) image.Image { return CloneNRGBA(m) },
+			model:   color.NRGBAModel,
+		},
+		{
+			name:    "should convert padded nrgba image to rgba",
+			src:     nrgba,
+			convert: func(m image.Image) image.Image { return ToRGBA(m) },
+			model:   color.RGBAModel,
+		},
+		{
+			name:    "should convert padded rgba image to nrgba",
+			src:     paddedRGBA,
+			convert: func(m image.Image) image.Image { return ToNRGBA(m) },
+			model:   color.NRGBAModel,
+		},
+		{
+			name: "should copy padded rgba sub image into rgba",
+			src:  paddedRGBA.SubImage(image.Rect(-3, -6, 4, 0)),
+			convert: func(m image.Image) image.Image {
+				img := image.NewRGBA(m.Bounds())
+				ConvertInto(img, m)
+				return img
+			},
+			model: color.RGBAModel,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := test.convert(test.src)
+
+			format := fmt.Sprintf("\n%s\n", test.name)
+			assertConvertedImage(t, test.src, actual, test.model, format)
+		})
+	}
+}
+
+func TestUniformNRGBA(t *testing.T) {
+	tests := []struct {
+		name string
+		args struct {
+			c color.Color
+			r image.Rectangle
+		}
+	}{
+		{
+			name: "should fill image with color",
+			args: struct {
+				c color.Color
+				r image.Rectangle
+			}{
+				c: color.NRGBA{10, 20, 30, 40},
+				r: image.Rect(-3, 5, 14, 16),
+			},
+		},
+		{
+			name: "should fill image with converted color",
+			args: struct {
+				c color.Color
+				r image.Rectangle
+			}{
+				c: color.RGBA{10, 20, 30, 40},
+				r: image.Rect(0, 0, 1, 1),
+			},
+		},
+		{
+			name: "should return empty image",
+			args: struct {
+				c color.Color
+				r image.Rectangle
+			}{
+				c: color.White,
+				r: image.Rect(0, 0, 0, 5),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := UniformNRGBA(test.args.c, test.args.r)
+
+			format := fmt.Sprintf("\nUniformNRGBA(%v, %v)\n", test.args.c, test.args.r)
+			assertConvertedImage(t, boundedImage{image.NewUniform(test.args.c), test.args.r}, actual, color.NRGBAModel, format)
+		})
+	}
+}
+
+func TestToRGBA64(t *testing.T) {
+	for seed := int64(0); seed < 8; seed++ {
+		bounds := image.Rect(-int(seed), int(seed), 9+int(seed), 7+int(seed))
+
+		nrgba := generateRandomNRGBA(t, bounds, seed)
+		nrgba64 := image.NewNRGBA64(bounds)
+		rand.New(rand.NewSource(seed)).Read(nrgba64.Pix)
+
+		//make sure the alpha edge cases are covered
+		nrgba.Pix[3], nrgba.Pix[7] = 0, 255
+		nrgba64.Pix[6], nrgba64.Pix[7] = 0, 0
+
+		for _, m := range []image.Image{nrgba, nrgba64, nrgba.SubImage(image.Rect(0, 2+int(seed), 4, 5+int(seed)))} {
+			t.Run(fmt.Sprintf("%T seed %d", m, seed), func(t *testing.T) {
+				actual := ToRGBA64(m)
+
+				format := fmt.Sprintf("\nToRGBA64(%T)\n", m)
+				assertConvertedImage(t, m, actual, color.RGBA64Model, format)
+			})
+		}
+	}
+}
+
+func TestToGray(t *testing.T) {
+	bounds := image.Rect(-3, 5, 14, 16)
+
+	tests := []struct {
+		name string
+		args struct {
+			m image.Image
+		}
+	}{
+		{
+			name: "should convert nrgba image",
+			args: struct{ m image.Image }{
+				m: generateRandomNRGBA(t, bounds, 1),
+			},
+		},
+		{
+			name: "should convert rgba image",
+			args: struct{ m image.Image }{
+				m: generateRandomRGBA(t, bounds, 2),
+			},
+		},
+		{
+			name: "should convert nrgba sub image",
+			args: struct{ m image.Image }{
+				m: generateRandomNRGBA(t, bounds, 3).SubImage(image.Rect(0, 7, 5, 12)),
+			},
+		},
+		{
+			name: "should convert ycbcr image",
+			args: struct{ m image.Image }{
+				m: generateRandomYCbCr(t, bounds, image.YCbCrSubsampleRatio420, 4),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := ToGray(test.args.m)
+
+			format := fmt.Sprintf("\nToGray(%T)\n", test.args.m)
+			assertConvertedImage(t, test.args.m, actual, color.GrayModel, format)
+		})
+	}
+}
+
+func TestToGrayReturnsGrayImage(t *testing.T) {
+	m := image.NewGray(image.Rect(0, 0, 2, 2))
+
+	if actual := ToGray(m); actual != m {
+		t.Errorf("ToGray(*image.Gray) = %p, expected %p\n", actual, m)
+	}
+}
+
+func TestToGray16(t *testing.T) {
+	bounds := image.Rect(-3, 5, 14, 16)
+
+	nrgba64 := image.NewNRGBA64(bounds)
+	rand.New(rand.NewSource(1)).Read(nrgba64.Pix)
+
+	rgba64 := image.NewRGBA64(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			rgba64.Set(x, y, nrgba64.At(x, y))
+		}
+	}
+
+	gray := image.NewGray(bounds)
+	rand.New(rand.NewSource(2)).Read(gray.Pix)
+
+	tests := []struct {
+		name string
+		args struct {
+			m image.Image
+		}
+	}{
+		{
+			name: "should convert nrgba64 image",
+			args: struct{ m image.Image }{
+				m: nrgba64,
+			},
+		},
+		{
+			name: "should convert rgba64 image",
+			args: struct{ m image.Image }{
+				m: rgba64,
+			},
+		},
+		{
+			name: "should convert gray image",
+			args: struct{ m image.Image }{
+				m: gray,
+			},
+		},
+		{
+			name: "should convert nrgba64 sub image",
+			args: struct{ m image.Image }{
+				m: nrgba64.SubImage(image.Rect(0, 7, 5, 12)),
+			},
+		},
+		{
+			name: "should convert nrgba image",
+			args: struct{ m image.Image }{
+				m: generateRandomNRGBA(t, bounds, 3),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := ToGray16(test.args.m)
+
+			format := fmt.Sprintf("\nToGray16(%T)\n", test.args.m)
+			assertConvertedImage(t, test.args.m, actual, color.Gray16Model, format)
+		})
+	}
+}
+
+func TestExtractAlpha(t *testing.T) {
+	bounds := image.Rect(-3, 5, 14, 16)
+
+	nrgba64 := image.NewNRGBA64(bounds)
+	rand.New(rand.NewSource(1)).Read(nrgba64.Pix)
+
+	alpha := image.NewAlpha(bounds)
+	rand.New(rand.NewSource(2)).Read(alpha.Pix)
+
+	tests := []struct {
+		name string
+		args struct {
+			m image.Image
+		}
+	}{
+		{
+			name: "should extract alpha of nrgba image",
+			args: struct{ m image.Image }{
+				m: generateRandomNRGBA(t, bounds, 1),
+			},
+		},
+		{
+			name: "should extract alpha of rgba image",
+			args: struct{ m image.Image }{
+				m: generateRandomRGBA(t, bounds, 2),
+			},
+		},
+		{
+			name: "should extract alpha of nrgba sub image",
+			args: struct{ m image.Image }{
+				m: generateRandomNRGBA(t, bounds, 3).SubImage(image.Rect(0, 7, 5, 12)),
+			},
+		},
+		{
+			name: "should extract alpha of alpha sub image",
+			args: struct{ m image.Image }{
+				m: alpha.SubImage(image.Rect(0, 7, 5, 12)),
+			},
+		},
+		{
+			name: "should extract opaque alpha of ycbcr image",
+			args: struct{ m image.Image }{
+				m: generateRandomYCbCr(t, bounds, image.YCbCrSubsampleRatio444, 4),
+			},
+		},
+		{
+			name: "should extract opaque alpha of gray image",
+			args: struct{ m image.Image }{
+				m: image.NewGray(bounds),
+			},
+		},
+		{
+			name: "should extract alpha of nrgba64 image",
+			args: struct{ m image.Image }{
+				m: nrgba64,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := ExtractAlpha(test.args.m)
+
+			format := fmt.Sprintf("\nExtractAlpha(%T)\n", test.args.m)
+			assertConvertedImage(t, test.args.m, actual, color.AlphaModel, format)
+		})
+	}
+}
+
+/*
+	Utils, Stubs, Asserts
+*/
+
+// customImage wraps an image so that it reports the color model of a
+// stdlib image without being of the same concrete type.
+type customImage struct {
+	m image.Image
+}
+
+func (c customImage) ColorModel() color.Model { return c.m.ColorModel() }
+func (c customImage) Bounds() image.Rectangle { return c.m.Bounds() }
+func (c customImage) At(x, y int) color.Color { return c.m.At(x, y) }
+
+// boundedImage limits an image to the bounds r.
+type boundedImage struct {
+	image.Image
+	r image.Rectangle
+}
+
+func (b boundedImage) Bounds() image.Rectangle { return b.r }
+
+func generateRandomNRGBA(t testing.TB, r image.Rectangle, seed int64) *image.NRGBA {
+	t.Helper()
+
+	m := image.NewNRGBA(r)
+	rand.New(rand.NewSource(seed)).Read(m.Pix)
+
+	return m
+}
+
+// generatePaddedNRGBA returns a random image whose rows are followed by padding bytes.
+func generatePaddedNRGBA(t testing.TB, r image.Rectangle, padding int, seed int64) *image.NRGBA {
+	t.Helper()
+
+	stride := 4*r.Dx() + padding
+	m := &image.NRGBA{Pix: make([]byte, stride*r.Dy()), Stride: stride, Rect: r}
+	rand.New(rand.NewSource(seed)).Read(m.Pix)
+
+	return m
+}
+
+func generateRandomRGBA(t testing.TB, r image.Rectangle, seed int64) *image.RGBA {
+	t.Helper()
+
+	m := image.NewRGBA(r)
+	rand.New(rand.NewSource(seed)).Read(m.Pix)
+
+	//premultiplied colors must not exceed their alpha value
+	for i := 0; i < len(m.Pix); i += 4 {
+		a := m.Pix[i+3]
+		for c := 0; c < 3; c++ {
+			if m.Pix[i+c] > a {
+				m.Pix[i+c] = a
+			}
+		}
+	}
+
+	return m
+}
+
+func generateRandomYCbCr(t testing.TB, r image.Rectangle, ratio image.YCbCrSubsampleRatio, seed int64) *image.YCbCr {
+	t.Helper()
+
+	m := image.NewYCbCr(r, ratio)
+	rnd := rand.New(rand.NewSource(seed))
+	rnd.Read(m.Y)
+	rnd.Read(m.Cb)
+	rnd.Read(m.Cr)
+
+	return m
+}
+
+func assertConvertedImage(t testing.TB, src, actual image.Image, model color.Model, format string) {
+	t.Helper()
+
+	if src.Bounds() != actual.Bounds() {
+		f := fmt.Sprintf("%s", format) +
+			fmt.Sprintf("Assert image:\t different image dimensions: Expected: %+v - Actual: %+v\n", src.Bounds(), actual.Bounds())
+		t.Fatalf(f)
+	}
+
+	for y := src.Bounds().Min.Y; y < src.Bounds().Max.Y; y++ {
+		for x := src.Bounds().Min.X; x < src.Bounds().Max.X; x++ {
+			expected := model.Convert(src.At(x, y))
+			if px := model.Convert(actual.At(x, y)); expected != px {
+				f := fmt.Sprintf("%s", format) +
+					fmt.Sprintf("Assert image:\t different pixel at x=%d, y=%d: Expected: %+v - Actual: %+v\n", x, y, expected, px)
+				t.Fatalf(f)
+			}
+		}
+	}
+}
+
+func BenchmarkToGray16FromNRGBA64(b *testing.B) {
+	m := image.NewNRGBA64(image.Rect(0, 0, 1920, 1080))
+	rand.New(rand.NewSource(1)).Read(m.Pix)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ToGray16(m)
+	}
+}
+
+func BenchmarkToGray16Generic(b *testing.B) {
+	m := image.NewNRGBA64(image.Rect(0, 0, 1920, 1080))
+	rand.New(rand.NewSource(1)).Read(m.Pix)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		img := image.NewGray16(m.Bounds())
+		for x := m.Bounds().Min.X; x < m.Bounds().Max.X; x++ {
+			for y := m.Bounds().Min.Y; y < m.Bounds().Max.Y; y++ {
+				img.Set(x, y, color.Gray16Model.Convert(m.At(x, y)))
+			}
+		}
+	}
+}
+
+func BenchmarkToNRGBAFromRGBA(b *testing.B) {
+	m := generateRandomRGBA(b, image.Rect(0, 0, 1920, 1080), 1)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ToNRGBA(m)
+	}
+}
+
+func BenchmarkToNRGBAGeneric(b *testing.B) {
+	m := generateRandomRGBA(b, image.Rect(0, 0, 1920, 1080), 1)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		img := image.NewNRGBA(m.Bounds())
+		for x := m.Bounds().Min.X; x < m.Bounds().Max.X; x++ {
+			for y := m.Bounds().Min.Y; y < m.Bounds().Max.Y; y++ {
+				img.Set(x, y, color.NRGBAModel.Convert(m.At(x, y)))
+			}
+		}
+	}
+}
+
+func BenchmarkToNRGBAFromYCbCr(b *testing.B) {
+	m := generateRandomYCbCr(b, image.Rect(0, 0, 1920, 1080), image.YCbCrSubsampleRatio420, 1)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ToNRGBA(m)
+	}
+}
+
+func BenchmarkToNRGBAFromPaletted(b *testing.B) {
+	m := image.NewPaletted(image.Rect(0, 0, 1920, 1080), palette.Plan9)
+	rand.New(rand.NewSource(1)).Read(m.Pix)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ToNRGBA(m)
+	}
+}
+
+func BenchmarkToNRGBAFromPalettedGeneric(b *testing.B) {
+	m := image.NewPaletted(image.Rect(0, 0, 1920, 1080), palette.Plan9)
+	rand.New(rand.NewSource(1)).Read(m.Pix)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ToNRGBA(customImage{m})
+	}
+}
+
+func BenchmarkToNRGBAFromAlpha(b *testing.B) {
+	m := image.NewAlpha(image.Rect(0, 0, 1920, 1080))
+	rand.New(rand.NewSource(1)).Read(m.Pix)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ToNRGBA(m)
+	}
+}
+
+func BenchmarkToNRGBAFromAlphaGeneric(b *testing.B) {
+	m := image.NewAlpha(image.Rect(0, 0, 1920, 1080))
+	rand.New(rand.NewSource(1)).Read(m.Pix)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ToNRGBA(customImage{m})
+	}
+}
+
+func BenchmarkToNRGBAFromGray(b *testing.B) {
+	m := image.NewGray(image.Rect(0, 0, 1920, 1080))
+	rand.New(rand.NewSource(1)).Read(m.Pix)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ToNRGBA(m)
+	}
+}
+
+func BenchmarkToNRGBAFromGray16(b *testing.B) {
+	m := image.NewGray16(image.Rect(0, 0, 1920, 1080))
+	rand.New(rand.NewSource(1)).Read(m.Pix)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ToNRGBA(m)
+	}
+}
+
+func BenchmarkCloneNRGBASubImage(b *testing.B) {
+	m := generateRandomNRGBA(b, image.Rect(0, 0, 3840, 2160), 1)
+	sub := m.SubImage(image.Rect(100, 100, 2020, 1180))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		CloneNRGBA(sub)
+	}
+}
+
+func BenchmarkToNRGBASubImageGeneric(b *testing.B) {
+	m := generateRandomNRGBA(b, image.Rect(0, 0, 3840, 2160), 1)
+	sub := m.SubImage(image.Rect(100, 100, 2020, 1180))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		img := image.NewNRGBA(sub.Bounds())
+		for x := sub.Bounds().Min.X; x < sub.Bounds().Max.X; x++ {
+			for y := sub.Bounds().Min.Y; y < sub.Bounds().Max.Y; y++ {
+				img.Set(x, y, sub.At(x, y))
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package imgconv
+
+import (
+	"fmt"
+	"image"
+)
+
+// Crop returns the part of any image m inside r as a newly allocated
+// *image.NRGBA image with its origin at (0, 0). r is clipped to the bounds of m.
+// Unlike SubImage, the returned image never shares its pixels with m,
+// so m can be garbage collected while the cropped image is still in use.
+func Crop(m image.Image, r image.Rectangle) (*image.NRGBA, error) {
+	img, err := ConvertRegion(m, r)
+	if err != nil {
+		return nil, err
+	}
+
+	img.Rect = img.Rect.Sub(img.Rect.Min)
+
+	return img, nil
+}
+
+// ConvertRegion is like Crop, but the returned image keeps the coordinates
+// of m, so its bounds are r clipped to the bounds of m.
+// Only the pixels inside r are converted.
+func ConvertRegion(m image.Image, r image.Rectangle) (*image.NRGBA, error) {
+	r = r.Intersect(m.Bounds())
+	if r.Empty() {
+		return nil, fmt.Errorf("region does not overlap the image bounds %v", m.Bounds())
+	}
+
+	img := image.NewNRGBA(r)
+	convertNRGBA(img, subImage(m, r))
+
+	return img, nil
+}
+
+// NormalizeBounds returns any image m as an *image.NRGBA image with its
+// origin at (0, 0) and a stride of exactly 4 bytes per pixel, so pixel (x, y)
+// of the result equals pixel (Min.X+x, Min.Y+y) of m and starts at Pix[4*(y*w+x)].
+// An *image.NRGBA image which is already tightly packed is rewrapped without
+// copying and shares its pixels with m; any other image is converted to a
+// newly allocated image.
+func NormalizeBounds(m image.Image) *image.NRGBA {
+	b := m.Bounds()
+	if b.Empty() {
+		return image.NewNRGBA(image.Rectangle{})
+	}
+
+	r := b.Sub(b.Min)
+	if src, ok := m.(*image.NRGBA); ok && src.Stride == 4*r.Dx() {
+		i := src.PixOffset(b.Min.X, b.Min.Y)
+		return &image.NRGBA{Pix: src.Pix[i : i+4*r.Dx()*r.Dy()], Stride: src.Stride, Rect: r}
+	}
+
+	img := image.NewNRGBA(r)
+	convertNRGBAAt(img, m, image.Point{})
+
+	return img
+}
+
+// subImage returns the part of m inside r, which must be inside the bounds of m.
+// Images without a SubImage method are wrapped to restrict their bounds.
+func subImage(m image.Image, r image.Rectangle) image.Image {
+	if s, ok := m.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return s.SubImage(r)
+	}
+
+	return croppedImage{Image: m, r: r}
+}
+
+// croppedImage restricts the bounds of an image without a SubImage method.
+type croppedImage struct {
+	image.Image
+	r image.Rectangle
+}
+
+func (m croppedImage) Bounds() image.Rectangle {
+	return m.r
+}
@@ -0,0 +1,216 @@
+package imgconv
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"math/rand"
+	"testing"
+)
+
+func TestCrop(t *testing.T) {
+	bounds := image.Rect(-4, 3, 20, 17)
+
+	tests := []struct {
+		name string
+		args struct {
+			m image.Image
+			r image.Rectangle
+		}
+		expected image.Rectangle
+	}{
+		{
+			name: "should crop an nrgba image",
+			args: struct {
+				m image.Image
+				r image.Rectangle
+			}{m: generateRandomNRGBA(t, bounds, 1), r: image.Rect(0, 5, 7, 9)},
+			expected: image.Rect(0, 5, 7, 9),
+		},
+		{
+			name: "should crop an rgba image",
+			args: struct {
+				m image.Image
+				r image.Rectangle
+			}{m: generateRandomRGBA(t, bounds, 2), r: image.Rect(-4, 3, 2, 4)},
+			expected: image.Rect(-4, 3, 2, 4),
+		},
+		{
+			name: "should crop a ycbcr image",
+			args: struct {
+				m image.Image
+				r image.Rectangle
+			}{m: generateRandomYCbCr(t, bounds, image.YCbCrSubsampleRatio420, 3), r: image.Rect(1, 4, 10, 15)},
+			expected: image.Rect(1, 4, 10, 15),
+		},
+		{
+			name: "should crop a custom image",
+			args: struct {
+				m image.Image
+				r image.Rectangle
+			}{m: customImage{generateRandomNRGBA(t, bounds, 4)}, r: image.Rect(3, 3, 5, 8)},
+			expected: image.Rect(3, 3, 5, 8),
+		},
+		{
+			name: "should clip the rectangle to the image bounds",
+			args: struct {
+				m image.Image
+				r image.Rectangle
+			}{m: generateRandomNRGBA(t, bounds, 5), r: image.Rect(-100, 10, 5, 100)},
+			expected: image.Rect(-4, 10, 5, 17),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := Crop(test.args.m, test.args.r)
+			if err != nil {
+				t.Fatalf("Crop: unexpected error: %v\n", err)
+			}
+
+			format := fmt.Sprintf("\nCrop(%T, %v)\n", test.args.m, test.args.r)
+			if actual.Bounds() != image.Rect(0, 0, test.expected.Dx(), test.expected.Dy()) {
+				t.Fatalf("%sExpected bounds %v, got %v\n", format, image.Rect(0, 0, test.expected.Dx(), test.expected.Dy()), actual.Bounds())
+			}
+
+			if len(actual.Pix) != 4*test.expected.Dx()*test.expected.Dy() {
+				t.Fatalf("%sExpected %d bytes, got %d\n", format, 4*test.expected.Dx()*test.expected.Dy(), len(actual.Pix))
+			}
+
+			expected := CloneNRGBA(test.args.m)
+			expected = expected.SubImage(test.expected).(*image.NRGBA)
+			expected = CloneNRGBA(expected)
+			expected.Rect = actual.Rect
+
+			assertConvertedImage(t, expected, actual, color.NRGBAModel, format)
+		})
+	}
+}
+
+func TestCropIsIndependent(t *testing.T) {
+	m := generateRandomNRGBA(t, image.Rect(0, 0, 8, 8), 1)
+	expected := CloneNRGBA(m)
+
+	actual, err := Crop(m, image.Rect(2, 2, 6, 6))
+	if err != nil {
+		t.Fatalf("Crop: unexpected error: %v\n", err)
+	}
+
+	for i := range actual.Pix {
+		actual.Pix[i] = ^actual.Pix[i]
+	}
+
+	assertConvertedImage(t, expected, m, color.NRGBAModel, "\nCrop: source changed after mutating the crop\n")
+}
+
+func TestCropEmptyIntersection(t *testing.T) {
+	m := generateRandomNRGBA(t, image.Rect(0, 0, 8, 8), 1)
+	for _, r := range []image.Rectangle{image.Rect(8, 0, 10, 8), image.Rect(-5, -5, 0, 0), {}} {
+		if _, err := Crop(m, r); err == nil {
+			t.Errorf("Crop(%v): expected an error\n", r)
+		}
+	}
+}
+
+func TestConvertRegion(t *testing.T) {
+	bounds := image.Rect(-4, 3, 20, 17)
+
+	gray := image.NewGray(bounds)
+	rand.New(rand.NewSource(3)).Read(gray.Pix)
+
+	paletted := image.NewPaletted(bounds, palette.WebSafe)
+	rand.New(rand.NewSource(4)).Read(paletted.Pix)
+
+	regions := []image.Rectangle{
+		image.Rect(0, 5, 7, 9),
+		image.Rect(-4, 3, 20, 17),
+		image.Rect(-10, 10, 5, 30),
+		image.Rect(19, 16, 25, 25),
+	}
+
+	for _, src := range []image.Image{
+		generateRandomNRGBA(t, bounds, 1),
+		generateRandomRGBA(t, bounds, 2),
+		generateRandomYCbCr(t, bounds, image.YCbCrSubsampleRatio422, 5),
+		gray,
+		paletted,
+		customImage{generateRandomNRGBA(t, bounds, 6)},
+	} {
+		for _, r := range regions {
+			t.Run(fmt.Sprintf("%T %v", src, r), func(t *testing.T) {
+				actual, err := ConvertRegion(src, r)
+				if err != nil {
+					t.Fatalf("ConvertRegion: unexpected error: %v\n", err)
+				}
+
+				expected := ToNRGBA(src).SubImage(r)
+				if actual.Bounds() != expected.Bounds() {
+					t.Fatalf("ConvertRegion: expected bounds %v, got %v\n", expected.Bounds(), actual.Bounds())
+				}
+
+				format := fmt.Sprintf("\nConvertRegion(%T, %v)\n", src, r)
+				assertConvertedImage(t, expected, actual, color.NRGBAModel, format)
+			})
+		}
+	}
+
+	if _, err := ConvertRegion(gray, image.Rect(20, 0, 30, 10)); err == nil {
+		t.Errorf("ConvertRegion(outside): expected an error\n")
+	}
+}
+
+func TestNormalizeBounds(t *testing.T) {
+	padded := generatePaddedNRGBA(t, image.Rect(-5, -3, 7, 6), 8, 1)
+
+	gray := image.NewGray(image.Rect(-2, -9, 13, -1))
+	rand.New(rand.NewSource(2)).Read(gray.Pix)
+
+	tests := []struct {
+		name   string
+		m      image.Image
+		shared bool
+	}{
+		{name: "NRGBA at origin", m: generateRandomNRGBA(t, image.Rect(0, 0, 9, 4), 1), shared: true},
+		{name: "NRGBA with negative origin", m: generateRandomNRGBA(t, image.Rect(-7, -2, 5, 3), 2), shared: true},
+		{name: "NRGBA with padded stride", m: padded, shared: false},
+		{name: "NRGBA sub image", m: generateRandomNRGBA(t, image.Rect(0, 0, 10, 10), 3).SubImage(image.Rect(2, 3, 7, 8)), shared: false},
+		{name: "Gray with negative origin", m: gray, shared: false},
+		{name: "YCbCr", m: generateRandomYCbCr(t, image.Rect(3, 5, 12, 14), image.YCbCrSubsampleRatio420, 4), shared: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := test.m.Bounds()
+			actual := NormalizeBounds(test.m)
+
+			if actual.Bounds() != image.Rect(0, 0, b.Dx(), b.Dy()) || actual.Stride != 4*b.Dx() {
+				t.Fatalf("NormalizeBounds(%v): got bounds %v with stride %d\n", b, actual.Bounds(), actual.Stride)
+			}
+
+			for y := 0; y < b.Dy(); y++ {
+				for x := 0; x < b.Dx(); x++ {
+					expected := color.NRGBAModel.Convert(test.m.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+					i := 4 * (y*b.Dx() + x)
+					if c := (color.NRGBA{actual.Pix[i], actual.Pix[i+1], actual.Pix[i+2], actual.Pix[i+3]}); c != expected {
+						format := fmt.Sprintf("\n") +
+							fmt.Sprintf("NormalizeBounds(%v)\n", b) +
+							fmt.Sprintf("Expected pixel at x=%d, y=%d:\t %v\n", x, y, expected) +
+							fmt.Sprintf("Actual pixel at x=%d, y=%d:\t %v\n", x, y, c)
+						t.Fatalf(format)
+					}
+				}
+			}
+
+			src, ok := test.m.(*image.NRGBA)
+			shared := ok && &actual.Pix[0] == &src.Pix[src.PixOffset(b.Min.X, b.Min.Y)]
+			if shared != test.shared {
+				t.Errorf("NormalizeBounds(%v): shares pixels = %t, expected %t\n", b, shared, test.shared)
+			}
+		})
+	}
+
+	if actual := NormalizeBounds(image.NewNRGBA(image.Rect(3, 3, 3, 8))); !actual.Bounds().Empty() {
+		t.Errorf("NormalizeBounds(empty image) = %v, expected an empty image\n", actual.Bounds())
+	}
+}
@@ -0,0 +1,201 @@
+package imgconv
+
+import (
+	"image"
+	"image/color"
+)
+
+// Dither selects the dithering algorithm used when mapping pixels to a palette.
+type Dither int
+
+const (
+	// DitherNone maps every pixel to its nearest palette entry.
+	DitherNone Dither = iota
+	// DitherFloydSteinberg diffuses the quantization error to the
+	// neighbouring pixels using a serpentine scan.
+	DitherFloydSteinberg
+	// DitherBayer4x4 applies an ordered dither with a 4x4 Bayer matrix.
+	// The same color at the same position always maps to the same palette entry.
+	DitherBayer4x4
+	// DitherBayer8x8 applies an ordered dither with an 8x8 Bayer matrix.
+	// The same color at the same position always maps to the same palette entry.
+	DitherBayer8x8
+)
+
+var bayer4x4 = [][]int32{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+var bayer8x8 = [][]int32{
+	{0, 32, 8, 40, 2, 34, 10, 42},
+	{48, 16, 56, 24, 50, 18, 58, 26},
+	{12, 44, 4, 36, 14, 46, 6, 38},
+	{60, 28, 52, 20, 62, 30, 54, 22},
+	{3, 35, 11, 43, 1, 33, 9, 41},
+	{51, 19, 59, 27, 49, 17, 57, 25},
+	{15, 47, 7, 39, 13, 45, 5, 37},
+	{63, 31, 55, 23, 61, 29, 53, 21},
+}
+
+// ditherFloydSteinberg maps src to the palette of dst with Floyd–Steinberg
+// error diffusion of the color channels. Rows are scanned in alternating
+// directions. The error is computed from the clamped color value, so it can
+// never exceed the range of a channel. Fully transparent pixels are neither
+// dithered nor diffuse any error.
+func ditherFloydSteinberg(dst *image.Paletted, src *image.NRGBA, pal *nrgbaPalette) {
+	b := src.Bounds()
+	width := b.Dx()
+
+	//errors are scaled by 16, with one guard pixel on each side
+	errCur := make([]int32, (width+2)*3)
+	errNext := make([]int32, (width+2)*3)
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		dir := 1
+		start, end := 0, width
+		if (y-b.Min.Y)%2 == 1 {
+			dir = -1
+			start, end = width-1, -1
+		}
+
+		si := src.PixOffset(b.Min.X, y)
+		di := dst.PixOffset(b.Min.X, y)
+		for x := start; x != end; x += dir {
+			s := src.Pix[si+4*x : si+4*x+4 : si+4*x+4]
+			if s[3] == 0 {
+				dst.Pix[di+x] = pal.index(color.NRGBA{s[0], s[1], s[2], s[3]})
+				continue
+			}
+
+			ei := (x + 1) * 3
+			var v [3]int32
+			for c := 0; c < 3; c++ {
+				v[c] = clamp8(int32(s[c]) + errCur[ei+c]/16)
+			}
+
+			idx := pal.index(color.NRGBA{uint8(v[0]), uint8(v[1]), uint8(v[2]), s[3]})
+			dst.Pix[di+x] = idx
+
+			p := pal.colors[idx]
+			e := [3]int32{v[0] - int32(p.R), v[1] - int32(p.G), v[2] - int32(p.B)}
+			for c := 0; c < 3; c++ {
+				errCur[ei+dir*3+c] += e[c] * 7
+				errNext[ei-dir*3+c] += e[c] * 3
+				errNext[ei+c] += e[c] * 5
+				errNext[ei+dir*3+c] += e[c] * 1
+			}
+		}
+
+		errCur, errNext = errNext, errCur
+		for i := range errNext {
+			errNext[i] = 0
+		}
+	}
+}
+
+// ditherFloydSteinberg64 reduces src to 8 bits per channel with Floyd–Steinberg
+// error diffusion of all four channels, using the same serpentine scan as
+// ditherFloydSteinberg. The error is kept in 16-bit units.
+func ditherFloydSteinberg64(dst *image.NRGBA, src *image.NRGBA64) {
+	b := src.Bounds()
+	width := b.Dx()
+
+	//errors are scaled by 16, with one guard pixel on each side
+	errCur := make([]int32, (width+2)*4)
+	errNext := make([]int32, (width+2)*4)
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		dir := 1
+		start, end := 0, width
+		if (y-b.Min.Y)%2 == 1 {
+			dir = -1
+			start, end = width-1, -1
+		}
+
+		si := src.PixOffset(b.Min.X, y)
+		di := dst.PixOffset(b.Min.X, y)
+		for x := start; x != end; x += dir {
+			s := src.Pix[si+8*x : si+8*x+8 : si+8*x+8]
+			d := dst.Pix[di+4*x : di+4*x+4 : di+4*x+4]
+
+			ei := (x + 1) * 4
+			for c := 0; c < 4; c++ {
+				v := clamp16((int32(s[2*c])<<8 | int32(s[2*c+1])) + errCur[ei+c]/16)
+				q := round16To8(uint32(v))
+				d[c] = q
+
+				e := v - int32(q)*0x101
+				errCur[ei+dir*4+c] += e * 7
+				errNext[ei-dir*4+c] += e * 3
+				errNext[ei+c] += e * 5
+				errNext[ei+dir*4+c] += e * 1
+			}
+		}
+
+		errCur, errNext = errNext, errCur
+		for i := range errNext {
+			errNext[i] = 0
+		}
+	}
+}
+
+// ditherOrdered maps src to the palette of dst after adding a threshold
+// taken from matrix at the absolute pixel position, so the pattern tiles
+// across the image. The threshold spread is derived from the palette size.
+// Fully transparent pixels are not dithered.
+func ditherOrdered(dst *image.Paletted, src *image.NRGBA, pal *nrgbaPalette, matrix [][]int32) {
+	n := int32(len(matrix))
+	mask := int(n - 1)
+
+	//levels per channel if the palette colors were spread evenly
+	levels := int32(2)
+	for levels*levels*levels < int32(len(pal.colors)) {
+		levels++
+	}
+	spread := 256 / levels
+
+	b := src.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := matrix[y&mask]
+		si := src.PixOffset(b.Min.X, y)
+		di := dst.PixOffset(b.Min.X, y)
+		for x := b.Min.X; x < b.Max.X; x, si, di = x+1, si+4, di+1 {
+			s := src.Pix[si : si+4 : si+4]
+			if s[3] == 0 {
+				dst.Pix[di] = pal.index(color.NRGBA{s[0], s[1], s[2], s[3]})
+				continue
+			}
+
+			offset := (2*row[x&mask]+1)*spread/(2*n*n) - spread/2
+			dst.Pix[di] = pal.index(color.NRGBA{
+				R: uint8(clamp8(int32(s[0]) + offset)),
+				G: uint8(clamp8(int32(s[1]) + offset)),
+				B: uint8(clamp8(int32(s[2]) + offset)),
+				A: s[3],
+			})
+		}
+	}
+}
+
+func clamp8(v int32) int32 {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return v
+}
+
+func clamp16(v int32) int32 {
+	if v < 0 {
+		return 0
+	}
+	if v > 0xffff {
+		return 0xffff
+	}
+	return v
+}
@@ -0,0 +1,275 @@
+package imgconv
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestToPalettedDitheredGradient(t *testing.T) {
+	m := generateGradientNRGBA(t, image.Rect(0, 0, 256, 32))
+
+	plain := ToPaletted(m, 4)
+	dithered := ToPalettedDithered(m, 4, DitherFloydSteinberg)
+
+	plainError := blockMeanError(t, m, plain, 8)
+	ditheredError := blockMeanError(t, m, dithered, 8)
+
+	if ditheredError >= plainError {
+		format := fmt.Sprintf("\n") +
+			fmt.Sprintf("Expected dithered block error to be lower than plain block error\n") +
+			fmt.Sprintf("Plain error:\t %f\n", plainError) +
+			fmt.Sprintf("Dithered error:\t %f\n", ditheredError)
+		t.Errorf(format)
+	}
+}
+
+func TestToPalettedDithered(t *testing.T) {
+	tests := []struct {
+		name string
+		args struct {
+			m         image.Image
+			maxColors int
+		}
+	}{
+		{
+			name: "should return an exact image for few colors",
+			args: struct {
+				m         image.Image
+				maxColors int
+			}{
+				m:         generateStripesNRGBA(t, image.Rect(-3, 1, 9, 7), []color.NRGBA{{255, 0, 0, 255}, {0, 0, 255, 100}, {0, 0, 0, 0}}),
+				maxColors: 3,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := ToPalettedDithered(test.args.m, test.args.maxColors, DitherFloydSteinberg)
+
+			format := fmt.Sprintf("\nToPalettedDithered(%T, %d, DitherFloydSteinberg)\n", test.args.m, test.args.maxColors)
+			assertConvertedImage(t, test.args.m, actual, color.NRGBAModel, format)
+		})
+	}
+}
+
+func TestToPalettedDitheredTransparentPixels(t *testing.T) {
+	m := generateGradientNRGBA(t, image.Rect(0, 0, 64, 8))
+	for x := 0; x < 64; x += 3 {
+		m.SetNRGBA(x, 4, color.NRGBA{uint8(4 * x), uint8(4 * x), uint8(4 * x), 0})
+	}
+
+	plain := ToPaletted(m, 4)
+	dithered := ToPalettedDithered(m, 4, DitherFloydSteinberg)
+
+	for x := 0; x < 64; x += 3 {
+		if plain.ColorIndexAt(x, 4) != dithered.ColorIndexAt(x, 4) {
+			t.Errorf("transparent pixel at x=%d, y=4 was dithered\n", x)
+		}
+	}
+}
+
+func TestToPalettedDitheredBayerIsDeterministic(t *testing.T) {
+	for _, dither := range []Dither{DitherBayer4x4, DitherBayer8x8} {
+		t.Run(fmt.Sprintf("dither %d", dither), func(t *testing.T) {
+			frame1 := generateGradientNRGBA(t, image.Rect(0, 0, 64, 16))
+			frame2 := generateGradientNRGBA(t, image.Rect(0, 0, 64, 16))
+
+			actual1 := ToPalettedDithered(frame1, 8, dither)
+			actual2 := ToPalettedDithered(frame2, 8, dither)
+
+			if !bytes.Equal(actual1.Pix, actual2.Pix) {
+				t.Errorf("identical frames were dithered differently\n")
+			}
+		})
+	}
+}
+
+func TestDitherOrderedTiles(t *testing.T) {
+	tests := []struct {
+		name   string
+		matrix [][]int32
+	}{
+		{
+			name:   "should tile 4x4 pattern",
+			matrix: bayer4x4,
+		},
+		{
+			name:   "should tile 8x8 pattern",
+			matrix: bayer8x8,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n := len(test.matrix)
+			p := color.Palette{color.NRGBA{0, 0, 0, 255}, color.NRGBA{255, 255, 255, 255}}
+
+			src := image.NewNRGBA(image.Rect(0, 0, 3*n+1, 2*n+3))
+			for i := 0; i < len(src.Pix); i += 4 {
+				src.Pix[i+0], src.Pix[i+1], src.Pix[i+2], src.Pix[i+3] = 128, 128, 128, 255
+			}
+
+			dst := image.NewPaletted(src.Bounds(), p)
+			ditherOrdered(dst, src, newNRGBAPalette(p), test.matrix)
+
+			seen := [2]bool{}
+			for y := 0; y < src.Bounds().Dy(); y++ {
+				for x := 0; x < src.Bounds().Dx(); x++ {
+					seen[dst.ColorIndexAt(x, y)] = true
+					if dst.ColorIndexAt(x, y) != dst.ColorIndexAt(x%n, y%n) {
+						t.Fatalf("pattern does not tile at x=%d, y=%d\n", x, y)
+					}
+				}
+			}
+
+			if !seen[0] || !seen[1] {
+				t.Errorf("expected a mix of both palette colors, got %v\n", seen)
+			}
+		})
+	}
+}
+
+func generateGradientNRGBA(t testing.TB, r image.Rectangle) *image.NRGBA {
+	t.Helper()
+
+	m := image.NewNRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			v := uint8((x - r.Min.X) * 255 / (r.Dx() - 1))
+			m.SetNRGBA(x, y, color.NRGBA{v, v, v, 255})
+		}
+	}
+
+	return m
+}
+
+// blockMeanError returns the mean absolute difference of the red channel
+// averaged over blocks of size x size pixels.
+func blockMeanError(t testing.TB, expected, actual image.Image, size int) float64 {
+	t.Helper()
+
+	b := expected.Bounds()
+	total, blocks := 0.0, 0
+	for by := b.Min.Y; by+size <= b.Max.Y; by += size {
+		for bx := b.Min.X; bx+size <= b.Max.X; bx += size {
+			sumExpected, sumActual := 0, 0
+			for y := by; y < by+size; y++ {
+				for x := bx; x < bx+size; x++ {
+					sumExpected += int(color.NRGBAModel.Convert(expected.At(x, y)).(color.NRGBA).R)
+					sumActual += int(color.NRGBAModel.Convert(actual.At(x, y)).(color.NRGBA).R)
+				}
+			}
+
+			diff := float64(sumExpected-sumActual) / float64(size*size)
+			if diff < 0 {
+				diff = -diff
+			}
+			total += diff
+			blocks++
+		}
+	}
+
+	return total / float64(blocks)
+}
+
+func TestToNRGBAFrom64(t *testing.T) {
+	m := image.NewNRGBA64(image.Rect(0, 0, 3, 1))
+	m.SetNRGBA64(0, 0, color.NRGBA64{0x00ff, 0x007f, 0x0081, 0xffff})
+	m.SetNRGBA64(1, 0, color.NRGBA64{0x1234, 0xff7e, 0xff80, 0x8080})
+	m.SetNRGBA64(2, 0, color.NRGBA64{0, 0xffff, 0x7fff, 0})
+
+	expected := []color.NRGBA{{1, 0, 1, 255}, {18, 254, 255, 128}, {0, 255, 127, 0}}
+
+	actual := ToNRGBAFrom64(m, false)
+	for x, e := range expected {
+		if c := actual.NRGBAAt(x, 0); c != e {
+			format := fmt.Sprintf("\n") +
+				fmt.Sprintf("ToNRGBAFrom64(%v, false)\n", m.NRGBA64At(x, 0)) +
+				fmt.Sprintf("Expected value:\t %v\n", e) +
+				fmt.Sprintf("Actual value:\t %v\n", c)
+			t.Errorf(format)
+		}
+	}
+}
+
+func TestToNRGBAFrom64Ramp(t *testing.T) {
+	//a shallow 16-bit ramp spanning only a few 8-bit values
+	m := image.NewNRGBA64(image.Rect(-4, 2, 252, 34))
+	for y := m.Rect.Min.Y; y < m.Rect.Max.Y; y++ {
+		for x := m.Rect.Min.X; x < m.Rect.Max.X; x++ {
+			v := uint16(0x1000 + 4*(x-m.Rect.Min.X))
+			m.SetNRGBA64(x, y, color.NRGBA64{v, v, v, 0xffff})
+		}
+	}
+
+	plain := ToNRGBAFrom64(m, false)
+	dithered := ToNRGBAFrom64(m, true)
+
+	if plain.Bounds() != m.Bounds() || dithered.Bounds() != m.Bounds() {
+		t.Fatalf("ToNRGBAFrom64: expected bounds %v, got %v and %v\n", m.Bounds(), plain.Bounds(), dithered.Bounds())
+	}
+
+	//the plain path quantizes into bands: every row is monotonic
+	plainChanges, ditheredChanges := 0, 0
+	for y := m.Rect.Min.Y; y < m.Rect.Max.Y; y++ {
+		for x := m.Rect.Min.X + 1; x < m.Rect.Max.X; x++ {
+			p, q := plain.NRGBAAt(x-1, y).R, plain.NRGBAAt(x, y).R
+			if q < p {
+				t.Fatalf("ToNRGBAFrom64(ramp, false): pixel at x=%d, y=%d is not monotonic\n", x, y)
+			}
+			if q != p {
+				plainChanges++
+			}
+			if dithered.NRGBAAt(x-1, y).R != dithered.NRGBAAt(x, y).R {
+				ditheredChanges++
+			}
+		}
+	}
+
+	if ditheredChanges <= 4*plainChanges {
+		t.Errorf("ToNRGBAFrom64(ramp, true): expected intermediate patterns, got %d value changes compared to %d bands\n", ditheredChanges, plainChanges)
+	}
+
+	plainError := blockMeanError64(t, m, plain, 8)
+	ditheredError := blockMeanError64(t, m, dithered, 8)
+	if ditheredError >= plainError {
+		format := fmt.Sprintf("\n") +
+			fmt.Sprintf("Expected dithered block error to be lower than plain block error\n") +
+			fmt.Sprintf("Plain error:\t %f\n", plainError) +
+			fmt.Sprintf("Dithered error:\t %f\n", ditheredError)
+		t.Errorf(format)
+	}
+}
+
+// blockMeanError64 returns the mean absolute difference of the red channel
+// averaged over size x size blocks, in 8-bit units.
+func blockMeanError64(t testing.TB, src *image.NRGBA64, actual *image.NRGBA, size int) float64 {
+	t.Helper()
+
+	b := src.Bounds()
+	sum, n := 0.0, 0
+	for by := b.Min.Y; by+size <= b.Max.Y; by += size {
+		for bx := b.Min.X; bx+size <= b.Max.X; bx += size {
+			var s, a float64
+			for y := by; y < by+size; y++ {
+				for x := bx; x < bx+size; x++ {
+					s += float64(src.NRGBA64At(x, y).R) / 0x101
+					a += float64(actual.NRGBAAt(x, y).R)
+				}
+			}
+
+			d := (s - a) / float64(size*size)
+			if d < 0 {
+				d = -d
+			}
+			sum += d
+			n++
+		}
+	}
+
+	return sum / float64(n)
+}
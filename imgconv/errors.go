@@ -0,0 +1,68 @@
+package imgconv
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"reflect"
+)
+
+var (
+	// ErrNilImage is returned for nil images, including nil pointers of image types.
+	ErrNilImage = errors.New("nil image")
+	// ErrEmptyImage is returned for images with empty bounds.
+	ErrEmptyImage = errors.New("empty image")
+	// ErrImageTooLarge is returned for images with more pixels than allowed.
+	ErrImageTooLarge = errors.New("image too large")
+	// ErrBufferTooSmall is returned if a caller provided buffer cannot hold the converted image.
+	ErrBufferTooSmall = errors.New("buffer too small")
+)
+
+// DefaultMaxPixels is the pixel limit of the Try functions.
+const DefaultMaxPixels = maxPixels
+
+// Validate checks that m can be converted without panicking or allocating
+// more than limit pixels. The returned error wraps ErrNilImage,
+// ErrEmptyImage or ErrImageTooLarge and can be tested with errors.Is.
+func Validate(m image.Image, limit int) error {
+	if m == nil {
+		return ErrNilImage
+	}
+	if v := reflect.ValueOf(m); v.Kind() == reflect.Ptr && v.IsNil() {
+		return fmt.Errorf("%w: %T", ErrNilImage, m)
+	}
+
+	b := m.Bounds()
+	if b.Empty() {
+		return fmt.Errorf("%w: %v", ErrEmptyImage, b)
+	}
+
+	//Dx and Dy overflow for bounds spanning more than the int range
+	w, h := b.Dx(), b.Dy()
+	if w <= 0 || h <= 0 || w > limit/h {
+		return fmt.Errorf("%w: %v", ErrImageTooLarge, b)
+	}
+
+	return nil
+}
+
+// TryToNRGBA is like ToNRGBA, but returns an error instead of panicking or
+// trying to convert invalid images: nil images, images with empty bounds and
+// images with more than DefaultMaxPixels pixels, like image.Uniform, whose
+// bounds are practically infinite. Use Validate for a different limit.
+func TryToNRGBA(m image.Image) (*image.NRGBA, error) {
+	if err := Validate(m, DefaultMaxPixels); err != nil {
+		return nil, err
+	}
+
+	return ToNRGBA(m), nil
+}
+
+// TryToRGBA is like ToRGBA, but validates m like TryToNRGBA.
+func TryToRGBA(m image.Image) (*image.RGBA, error) {
+	if err := Validate(m, DefaultMaxPixels); err != nil {
+		return nil, err
+	}
+
+	return ToRGBA(m), nil
+}
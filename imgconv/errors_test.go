@@ -0,0 +1,206 @@
+package imgconv
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestTryToNRGBA(t *testing.T) {
+	var nilNRGBA *image.NRGBA
+
+	tests := []struct {
+		name string
+		args struct {
+			m image.Image
+		}
+		expected error
+	}{
+		{
+			name: "should return an error for nil images",
+			args: struct{ m image.Image }{
+				m: nil,
+			},
+			expected: ErrNilImage,
+		},
+		{
+			name: "should return an error for nil pointers",
+			args: struct{ m image.Image }{
+				m: nilNRGBA,
+			},
+			expected: ErrNilImage,
+		},
+		{
+			name: "should return an error for empty images",
+			args: struct{ m image.Image }{
+				m: image.NewNRGBA(image.Rectangle{}),
+			},
+			expected: ErrEmptyImage,
+		},
+		{
+			name: "should return an error for images with inverted bounds",
+			args: struct{ m image.Image }{
+				m: boundedImage{image.NewUniform(color.White), image.Rectangle{Min: image.Pt(5, 5), Max: image.Pt(1, 9)}},
+			},
+			expected: ErrEmptyImage,
+		},
+		{
+			name: "should return an error for uniform images",
+			args: struct{ m image.Image }{
+				m: image.NewUniform(color.White),
+			},
+			expected: ErrImageTooLarge,
+		},
+		{
+			name: "should return an error for huge images",
+			args: struct{ m image.Image }{
+				m: customImage{image.NewUniform(color.White)},
+			},
+			expected: ErrImageTooLarge,
+		},
+		{
+			name: "should return an error for bounds overflowing int",
+			args: struct{ m image.Image }{
+				m: boundedImage{image.NewUniform(color.White), image.Rect(math.MinInt, 0, math.MaxInt, 1)},
+			},
+			expected: ErrImageTooLarge,
+		},
+		{
+			name: "should return an error for images one pixel over the limit",
+			args: struct{ m image.Image }{
+				m: boundedImage{image.NewUniform(color.White), image.Rect(0, 0, maxPixels+1, 1)},
+			},
+			expected: ErrImageTooLarge,
+		},
+		{
+			name: "should convert bounded images",
+			args: struct{ m image.Image }{
+				m: generateRandomRGBA(t, image.Rect(-3, 5, 14, 16), 1),
+			},
+		},
+		{
+			name: "should convert single pixel images",
+			args: struct{ m image.Image }{
+				m: boundedImage{image.NewUniform(color.White), image.Rect(7, 7, 8, 8)},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := TryToNRGBA(test.args.m)
+			if !errors.Is(err, test.expected) || (err == nil) != (test.expected == nil) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("TryToNRGBA(%T) = (%v)\n", test.args.m, err) +
+					fmt.Sprintf("Expected error:\t %v\n", test.expected) +
+					fmt.Sprintf("Actual error:\t %v\n", err)
+				t.Fatalf(format)
+			}
+
+			if _, err := TryToRGBA(test.args.m); !errors.Is(err, test.expected) || (err == nil) != (test.expected == nil) {
+				t.Fatalf("TryToRGBA(%T) = (%v), expected %v\n", test.args.m, err, test.expected)
+			}
+
+			if test.expected == nil {
+				format := fmt.Sprintf("\nTryToNRGBA(%T)\n", test.args.m)
+				assertConvertedImage(t, test.args.m, actual, color.NRGBAModel, format)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	m := boundedImage{image.NewUniform(color.White), image.Rect(0, 0, 100, 100)}
+
+	if err := Validate(m, 10000); err != nil {
+		t.Errorf("Validate(100x100, 10000): unexpected error: %v\n", err)
+	}
+	if err := Validate(m, 9999); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("Validate(100x100, 9999) = %v, expected %v\n", err, ErrImageTooLarge)
+	}
+	if err := Validate(m, 0); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("Validate(100x100, 0) = %v, expected %v\n", err, ErrImageTooLarge)
+	}
+}
+
+func TestToNRGBABuf(t *testing.T) {
+	bounds := image.Rect(-2, 3, 30, 21)
+	n := 4 * bounds.Dx() * bounds.Dy()
+
+	tests := []struct {
+		name string
+		src  image.Image
+	}{
+		{name: "nrgba", src: generatePaddedNRGBA(t, bounds, 12, 1)},
+		{name: "rgba", src: generateRandomRGBA(t, bounds, 2)},
+		{name: "ycbcr", src: generateRandomYCbCr(t, bounds, image.YCbCrSubsampleRatio420, 3)},
+		{name: "custom", src: customImage{generateRandomNRGBA(t, bounds, 4)}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buf := make([]byte, n+100)
+
+			actual, err := ToNRGBABuf(buf, test.src)
+			if err != nil {
+				t.Fatalf("ToNRGBABuf: unexpected error: %v\n", err)
+			}
+			if &actual.Pix[0] != &buf[0] || len(actual.Pix) != n {
+				t.Fatalf("ToNRGBABuf: expected the image to alias the first %d bytes of the buffer\n", n)
+			}
+			assertConvertedImage(t, test.src, actual, color.NRGBAModel, fmt.Sprintf("\nToNRGBABuf(%T)\n", test.src))
+
+			actualRGBA, err := ToRGBABuf(buf, test.src)
+			if err != nil {
+				t.Fatalf("ToRGBABuf: unexpected error: %v\n", err)
+			}
+			if &actualRGBA.Pix[0] != &buf[0] {
+				t.Fatalf("ToRGBABuf: expected the image to alias the buffer\n")
+			}
+			assertConvertedImage(t, test.src, actualRGBA, color.RGBAModel, fmt.Sprintf("\nToRGBABuf(%T)\n", test.src))
+		})
+	}
+}
+
+func TestToNRGBABufErrors(t *testing.T) {
+	m := generateRandomNRGBA(t, image.Rect(0, 0, 4, 4), 1)
+
+	if _, err := ToNRGBABuf(make([]byte, 63), m); !errors.Is(err, ErrBufferTooSmall) {
+		t.Errorf("ToNRGBABuf(63 bytes) = %v, expected %v\n", err, ErrBufferTooSmall)
+	}
+	if _, err := ToRGBABuf(nil, m); !errors.Is(err, ErrBufferTooSmall) {
+		t.Errorf("ToRGBABuf(nil) = %v, expected %v\n", err, ErrBufferTooSmall)
+	}
+	if _, err := ToNRGBABuf(make([]byte, 64), nil); !errors.Is(err, ErrNilImage) {
+		t.Errorf("ToNRGBABuf(nil image) = %v, expected %v\n", err, ErrNilImage)
+	}
+	if _, err := ToNRGBABuf(make([]byte, 64), image.NewUniform(color.White)); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("ToNRGBABuf(uniform image) = %v, expected %v\n", err, ErrImageTooLarge)
+	}
+}
+
+func TestToNRGBABufDoesNotAllocate(t *testing.T) {
+	bounds := image.Rect(0, 0, 64, 48)
+	buf := make([]byte, 4*bounds.Dx()*bounds.Dy())
+
+	for _, src := range []image.Image{generateRandomRGBA(t, bounds, 1), generateRandomYCbCr(t, bounds, image.YCbCrSubsampleRatio420, 2)} {
+		t.Run(fmt.Sprintf("%T", src), func(t *testing.T) {
+			allocs := testing.AllocsPerRun(10, func() {
+				img, err := ToNRGBABuf(buf, src)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if img.Bounds() != bounds {
+					t.Fatal("unexpected bounds")
+				}
+			})
+
+			if allocs > 0 {
+				t.Errorf("expected no allocations, got %v\n", allocs)
+			}
+		})
+	}
+}
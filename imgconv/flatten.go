@@ -0,0 +1,40 @@
+package imgconv
+
+import (
+	"image"
+	"image/color"
+)
+
+// Flatten composites any image m over the background color bg and returns an
+// opaque *image.NRGBA image with the same bounds as m. bg is converted to
+// color.NRGBA and its alpha value is ignored, so the result is always opaque.
+// Every channel is blended as (c*a + bg*(255-a)) / 255, rounded to nearest.
+func Flatten(m image.Image, bg color.Color) *image.NRGBA {
+	img := CloneNRGBA(m)
+	c := color.NRGBAModel.Convert(bg).(color.NRGBA)
+
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		i := img.PixOffset(b.Min.X, y)
+		for x := b.Min.X; x < b.Max.X; x, i = x+1, i+4 {
+			d := img.Pix[i : i+4 : i+4]
+			switch a := uint32(d[3]); a {
+			case 0xff:
+			case 0:
+				d[0], d[1], d[2], d[3] = c.R, c.G, c.B, 0xff
+			default:
+				d[0] = blend8(d[0], c.R, a)
+				d[1] = blend8(d[1], c.G, a)
+				d[2] = blend8(d[2], c.B, a)
+				d[3] = 0xff
+			}
+		}
+	}
+
+	return img
+}
+
+// blend8 returns the color fg with alpha a composited over the opaque color bg.
+func blend8(fg, bg uint8, a uint32) uint8 {
+	return uint8((uint32(fg)*a + uint32(bg)*(0xff-a) + 0x7f) / 0xff)
+}
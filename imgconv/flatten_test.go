@@ -0,0 +1,95 @@
+package imgconv
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFlatten(t *testing.T) {
+	white := color.White
+
+	tests := []struct {
+		name string
+		args struct {
+			c  color.NRGBA
+			bg color.Color
+		}
+		expected color.NRGBA
+	}{
+		{
+			name: "should replace a transparent pixel with the background",
+			args: struct {
+				c  color.NRGBA
+				bg color.Color
+			}{c: color.NRGBA{12, 34, 56, 0}, bg: white},
+			expected: color.NRGBA{255, 255, 255, 255},
+		},
+		{
+			name: "should blend a half transparent pixel",
+			args: struct {
+				c  color.NRGBA
+				bg color.Color
+			}{c: color.NRGBA{255, 0, 100, 128}, bg: white},
+			//(255*128 + 255*127) / 255 = 255, (0*128 + 255*127) / 255 = 127, (100*128 + 255*127) / 255 = 177.2
+			expected: color.NRGBA{255, 127, 177, 255},
+		},
+		{
+			name: "should blend a half transparent pixel over a color",
+			args: struct {
+				c  color.NRGBA
+				bg color.Color
+			}{c: color.NRGBA{200, 10, 0, 128}, bg: color.NRGBA{0, 100, 51, 255}},
+			//(200*128) / 255 = 100.4, (10*128 + 100*127) / 255 = 54.8, (51*127) / 255 = 25.4
+			expected: color.NRGBA{100, 55, 25, 255},
+		},
+		{
+			name: "should keep an opaque pixel",
+			args: struct {
+				c  color.NRGBA
+				bg color.Color
+			}{c: color.NRGBA{12, 34, 56, 255}, bg: white},
+			expected: color.NRGBA{12, 34, 56, 255},
+		},
+		{
+			name: "should ignore the background alpha",
+			args: struct {
+				c  color.NRGBA
+				bg color.Color
+			}{c: color.NRGBA{0, 0, 0, 0}, bg: color.NRGBA{10, 20, 30, 40}},
+			expected: color.NRGBA{10, 20, 30, 255},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := UniformNRGBA(test.args.c, image.Rect(-1, 2, 3, 5))
+
+			actual := Flatten(m, test.args.bg)
+			if actual.Bounds() != m.Bounds() {
+				t.Fatalf("Flatten: expected bounds %v, got %v\n", m.Bounds(), actual.Bounds())
+			}
+
+			if c := actual.NRGBAAt(1, 3); c != test.expected {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Flatten(%v, %v)\n", test.args.c, test.args.bg) +
+					fmt.Sprintf("Expected value:\t %v\n", test.expected) +
+					fmt.Sprintf("Actual value:\t %v\n", c)
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+func TestFlattenIsOpaque(t *testing.T) {
+	m := generateRandomNRGBA(t, image.Rect(3, 3, 40, 20), 1)
+	src := CloneNRGBA(m)
+
+	actual := Flatten(m, color.Black)
+	if !actual.Opaque() {
+		t.Fatalf("Flatten: expected an opaque image\n")
+	}
+
+	assertConvertedImage(t, src, m, color.NRGBAModel, "\nFlatten: source image was changed\n")
+}
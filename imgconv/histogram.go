@@ -0,0 +1,105 @@
+package imgconv
+
+import (
+	"image"
+	"image/color"
+)
+
+// ColorHistogram holds the number of pixels per 8-bit value of every channel.
+// R, G and B count the colors before alpha-premultiplication, like color.NRGBA,
+// and Y counts the luminance as computed by ToGray.
+// 16-bit values are counted in the bin of their high byte.
+type ColorHistogram struct {
+	R, G, B, A, Y [256]int
+}
+
+// Histogram counts the channel values of every pixel of any image m.
+func Histogram(m image.Image) *ColorHistogram {
+	h := new(ColorHistogram)
+	b := m.Bounds()
+
+	switch src := m.(type) {
+	case *image.NRGBA:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			i := src.PixOffset(b.Min.X, y)
+			for x := b.Min.X; x < b.Max.X; x, i = x+1, i+4 {
+				s := src.Pix[i : i+4 : i+4]
+				h.R[s[0]]++
+				h.G[s[1]]++
+				h.B[s[2]]++
+				h.A[s[3]]++
+
+				a := uint32(s[3])
+				h.Y[luma601(uint32(s[0])*0x101*a/0xff, uint32(s[1])*0x101*a/0xff, uint32(s[2])*0x101*a/0xff)]++
+			}
+		}
+
+	case *image.RGBA:
+		lut := unpremultiplyTable()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			i := src.PixOffset(b.Min.X, y)
+			for x := b.Min.X; x < b.Max.X; x, i = x+1, i+4 {
+				s := src.Pix[i : i+4 : i+4]
+				t := &lut[s[3]]
+				h.R[t[s[0]]]++
+				h.G[t[s[1]]]++
+				h.B[t[s[2]]]++
+				h.A[s[3]]++
+				h.Y[luma601(uint32(s[0])*0x101, uint32(s[1])*0x101, uint32(s[2])*0x101)]++
+			}
+		}
+
+	case *image.Gray:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			i := src.PixOffset(b.Min.X, y)
+			for _, v := range src.Pix[i : i+b.Dx()] {
+				h.Y[v]++
+			}
+		}
+		h.R, h.G, h.B = h.Y, h.Y, h.Y
+		h.A[0xff] = b.Dx() * b.Dy()
+
+	case *image.Gray16:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			i := src.PixOffset(b.Min.X, y)
+			for x := b.Min.X; x < b.Max.X; x, i = x+1, i+2 {
+				h.Y[src.Pix[i]]++
+			}
+		}
+		h.R, h.G, h.B = h.Y, h.Y, h.Y
+		h.A[0xff] = b.Dx() * b.Dy()
+
+	default:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				px := m.At(x, y)
+				c := color.NRGBAModel.Convert(px).(color.NRGBA)
+				h.R[c.R]++
+				h.G[c.G]++
+				h.B[c.B]++
+				h.A[c.A]++
+				h.Y[color.GrayModel.Convert(px).(color.Gray).Y]++
+			}
+		}
+	}
+
+	return h
+}
+
+// CumulativeHistogram returns the running sum of hist, so the value at index v
+// is the number of pixels with a value of at most v.
+func CumulativeHistogram(hist [256]int) [256]int {
+	sum := 0
+	for v, n := range hist {
+		sum += n
+		hist[v] = sum
+	}
+
+	return hist
+}
+
+// luma601 returns the 8-bit luminance of the 16-bit alpha-premultiplied color
+// with the same weights and rounding as color.GrayModel.
+func luma601(r, g, b uint32) uint8 {
+	return uint8((19595*r + 38470*g + 7471*b + 1<<15) >> 24)
+}
@@ -0,0 +1,106 @@
+package imgconv
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+func TestHistogram(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+	half := color.NRGBA{10, 20, 30, 128}
+
+	t.Run("should count a solid color", func(t *testing.T) {
+		m := UniformNRGBA(red, image.Rect(-2, 3, 8, 9))
+		h := Histogram(m)
+
+		if h.R[255] != 60 || h.G[0] != 60 || h.B[0] != 60 || h.A[255] != 60 || h.Y[76] != 60 {
+			t.Errorf("Histogram(solid red): expected all 60 pixels in R[255], G[0], B[0], A[255] and Y[76]\n")
+		}
+	})
+
+	t.Run("should count a two color checkerboard", func(t *testing.T) {
+		m := generateCheckerboardNRGBA(t, image.Rect(0, 0, 8, 6), 1, red, half)
+		h := Histogram(m)
+
+		expected := map[string][2]int{
+			"R": {h.R[255], h.R[10]},
+			"G": {h.G[0], h.G[20]},
+			"B": {h.B[0], h.B[30]},
+			"A": {h.A[255], h.A[128]},
+		}
+		for name, counts := range expected {
+			if counts != [2]int{24, 24} {
+				t.Errorf("Histogram(checkerboard): expected 24 pixels of each color in %s, got %v\n", name, counts)
+			}
+		}
+	})
+
+	t.Run("should count 16-bit values by their high byte", func(t *testing.T) {
+		m := image.NewGray16(image.Rect(0, 0, 3, 1))
+		m.SetGray16(0, 0, color.Gray16{0x12ff})
+		m.SetGray16(1, 0, color.Gray16{0x1200})
+		m.SetGray16(2, 0, color.Gray16{0xffff})
+
+		for _, src := range []image.Image{m, customImage{m}} {
+			h := Histogram(src)
+			if h.Y[0x12] != 2 || h.Y[0xff] != 1 || h.R[0x12] != 2 || h.A[0xff] != 3 {
+				t.Errorf("Histogram(%T): expected 16-bit values in the bin of their high byte\n", src)
+			}
+		}
+	})
+}
+
+func TestHistogramFastPaths(t *testing.T) {
+	bounds := image.Rect(-3, 5, 40, 31)
+
+	gray := image.NewGray(bounds)
+	rand.New(rand.NewSource(3)).Read(gray.Pix)
+
+	gray16 := image.NewGray16(bounds)
+	rand.New(rand.NewSource(4)).Read(gray16.Pix)
+
+	for _, src := range []image.Image{
+		generateRandomNRGBA(t, bounds, 1),
+		generateRandomRGBA(t, bounds, 2),
+		gray,
+		gray16,
+		generateRandomNRGBA(t, bounds, 5).SubImage(image.Rect(0, 7, 20, 12)),
+	} {
+		t.Run(fmt.Sprintf("%T %v", src, src.Bounds()), func(t *testing.T) {
+			actual := Histogram(src)
+			expected := Histogram(customImage{src})
+
+			if *actual != *expected {
+				t.Errorf("Histogram(%T) differs from the generic path\n", src)
+			}
+		})
+	}
+}
+
+func TestCumulativeHistogram(t *testing.T) {
+	var hist [256]int
+	hist[0], hist[10], hist[255] = 3, 4, 5
+
+	actual := CumulativeHistogram(hist)
+	for v, expected := range map[int]int{0: 3, 9: 3, 10: 7, 254: 7, 255: 12} {
+		if actual[v] != expected {
+			t.Errorf("CumulativeHistogram[%d] = %d, expected %d\n", v, actual[v], expected)
+		}
+	}
+
+	if hist[10] != 4 {
+		t.Errorf("CumulativeHistogram changed its argument\n")
+	}
+}
+
+func BenchmarkHistogram(b *testing.B) {
+	m := generateRandomNRGBA(b, image.Rect(0, 0, 1920, 1080), 1)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Histogram(m)
+	}
+}
@@ -0,0 +1,27 @@
+package imgconv
+
+// Luma selects the weights used to compute the luminance of a color.
+type Luma int
+
+const (
+	// LumaRec601 uses the ITU-R BT.601 weights 0.299, 0.587 and 0.114,
+	// the same as color.GrayModel and color.Gray16Model.
+	LumaRec601 Luma = iota
+	// LumaRec709 uses the ITU-R BT.709 weights 0.2126, 0.7152 and 0.0722.
+	LumaRec709
+	// LumaAverage uses the average of the red, green and blue values.
+	LumaAverage
+)
+
+// weights returns the red, green and blue weights of l scaled to 1<<16.
+// The weights of every Luma add up to exactly 1<<16.
+func (l Luma) weights() (uint32, uint32, uint32) {
+	switch l {
+	case LumaRec709:
+		return 13933, 46871, 4732
+	case LumaAverage:
+		return 21845, 21846, 21845
+	default:
+		return 19595, 38470, 7471
+	}
+}
@@ -0,0 +1,74 @@
+package imgconv
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestToGrayLuma(t *testing.T) {
+	tests := []struct {
+		name       string
+		luma       Luma
+		c          color.NRGBA
+		expected   uint8
+		expected16 uint16
+	}{
+		{name: "rec601 red", luma: LumaRec601, c: color.NRGBA{255, 0, 0, 255}, expected: 76, expected16: 19595},
+		{name: "rec601 green", luma: LumaRec601, c: color.NRGBA{0, 255, 0, 255}, expected: 150, expected16: 38469},
+		{name: "rec601 blue", luma: LumaRec601, c: color.NRGBA{0, 0, 255, 255}, expected: 29, expected16: 7471},
+		{name: "rec601 white", luma: LumaRec601, c: color.NRGBA{255, 255, 255, 255}, expected: 255, expected16: 65535},
+		{name: "rec601 mixed", luma: LumaRec601, c: color.NRGBA{10, 200, 30, 255}, expected: 124, expected16: 31819},
+		{name: "rec709 red", luma: LumaRec709, c: color.NRGBA{255, 0, 0, 255}, expected: 54, expected16: 13933},
+		{name: "rec709 green", luma: LumaRec709, c: color.NRGBA{0, 255, 0, 255}, expected: 183, expected16: 46870},
+		{name: "rec709 blue", luma: LumaRec709, c: color.NRGBA{0, 0, 255, 255}, expected: 18, expected16: 4732},
+		{name: "rec709 white", luma: LumaRec709, c: color.NRGBA{255, 255, 255, 255}, expected: 255, expected16: 65535},
+		{name: "rec709 mixed", luma: LumaRec709, c: color.NRGBA{10, 200, 30, 255}, expected: 147, expected16: 37864},
+		{name: "average red", luma: LumaAverage, c: color.NRGBA{255, 0, 0, 255}, expected: 85, expected16: 21845},
+		{name: "average white", luma: LumaAverage, c: color.NRGBA{255, 255, 255, 255}, expected: 255, expected16: 65535},
+		{name: "average mixed", luma: LumaAverage, c: color.NRGBA{10, 200, 30, 255}, expected: 80, expected16: 20560},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := UniformNRGBA(test.c, image.Rect(0, 0, 2, 2))
+
+			for _, src := range []image.Image{m, ToRGBA(m), ToNRGBA64(m), customImage{m}} {
+				if actual := ToGrayLuma(src, test.luma).GrayAt(1, 1).Y; actual != test.expected {
+					format := fmt.Sprintf("\n") +
+						fmt.Sprintf("ToGrayLuma(%T, %d)\n", src, test.luma) +
+						fmt.Sprintf("Expected value:\t %d\n", test.expected) +
+						fmt.Sprintf("Actual value:\t %d\n", actual)
+					t.Errorf(format)
+				}
+			}
+
+			for _, src := range []image.Image{ToNRGBA64(m), ToRGBA64(m), customImage{m}} {
+				if actual := ToGray16Luma(src, test.luma).Gray16At(1, 1).Y; actual != test.expected16 {
+					format := fmt.Sprintf("\n") +
+						fmt.Sprintf("ToGray16Luma(%T, %d)\n", src, test.luma) +
+						fmt.Sprintf("Expected value:\t %d\n", test.expected16) +
+						fmt.Sprintf("Actual value:\t %d\n", actual)
+					t.Errorf(format)
+				}
+			}
+		})
+	}
+}
+
+func TestToGrayLumaKeepsGrayValues(t *testing.T) {
+	m := image.NewGray(image.Rect(0, 0, 256, 1))
+	for x := 0; x < 256; x++ {
+		m.Pix[x] = uint8(x)
+	}
+
+	for _, luma := range []Luma{LumaRec601, LumaRec709, LumaAverage} {
+		actual := ToGrayLuma(ToNRGBA(m), luma)
+		for x := 0; x < 256; x++ {
+			if actual.Pix[x] != uint8(x) {
+				t.Fatalf("ToGrayLuma(%d): gray value %d became %d\n", luma, x, actual.Pix[x])
+			}
+		}
+	}
+}
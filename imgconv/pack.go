@@ -0,0 +1,84 @@
+package imgconv
+
+import (
+	"fmt"
+	"image"
+	"sort"
+)
+
+// Pack packs the images imgs into a single *image.NRGBA atlas of at most
+// maxW x maxH pixels using a shelf heuristic: the images are sorted by
+// height and placed left to right on rows, starting a new row when the
+// current one is full. padding transparent pixels are kept between
+// neighbouring images to avoid bleeding when sampling the atlas.
+//
+// The atlas has its origin at (0, 0) and is cropped to the used area.
+// The returned rectangles hold the placement of each image in input order;
+// empty images are placed at an empty rectangle at the origin.
+// An error is returned if the images do not fit.
+func Pack(imgs []image.Image, maxW, maxH int, padding int) (*image.NRGBA, []image.Rectangle, error) {
+	if len(imgs) == 0 {
+		return nil, nil, fmt.Errorf("no images to pack")
+	}
+	if maxW <= 0 || maxH <= 0 || padding < 0 {
+		return nil, nil, fmt.Errorf("invalid atlas size %dx%d or padding %d", maxW, maxH, padding)
+	}
+	if !validSize(image.Rect(0, 0, maxW, maxH)) {
+		return nil, nil, fmt.Errorf("%w: %dx%d", ErrImageTooLarge, maxW, maxH)
+	}
+
+	order := make([]int, 0, len(imgs))
+	for idx, m := range imgs {
+		if m == nil {
+			return nil, nil, fmt.Errorf("%w: image %d", ErrNilImage, idx)
+		}
+		if !m.Bounds().Empty() {
+			order = append(order, idx)
+		}
+	}
+
+	//tallest first keeps the wasted space on each shelf small
+	sort.SliceStable(order, func(i, j int) bool {
+		return imgs[order[i]].Bounds().Dy() > imgs[order[j]].Bounds().Dy()
+	})
+
+	places := make([]image.Rectangle, len(imgs))
+	size := image.Point{}
+	x, y, shelf := 0, 0, 0
+	for _, idx := range order {
+		s := imgs[idx].Bounds().Size()
+		if s.X > maxW || s.Y > maxH {
+			return nil, nil, fmt.Errorf("image %d of size %v does not fit into %dx%d", idx, s, maxW, maxH)
+		}
+
+		if x > 0 && x+padding+s.X > maxW {
+			//start a new shelf below the tallest image of the current one
+			x, y, shelf = 0, y+shelf+padding, 0
+		}
+		if x > 0 {
+			x += padding
+		}
+		if y+s.Y > maxH {
+			return nil, nil, fmt.Errorf("images do not fit into %dx%d", maxW, maxH)
+		}
+
+		places[idx] = image.Rectangle{Min: image.Pt(x, y), Max: image.Pt(x+s.X, y+s.Y)}
+		x += s.X
+		if s.Y > shelf {
+			shelf = s.Y
+		}
+		if x > size.X {
+			size.X = x
+		}
+		if y+s.Y > size.Y {
+			size.Y = y + s.Y
+		}
+	}
+
+	img := image.NewNRGBA(image.Rectangle{Max: size})
+	for _, idx := range order {
+		convertNRGBAAt(img, imgs[idx], places[idx].Min)
+	}
+
+	return img, places, nil
+}
@@ -0,0 +1,147 @@
+package imgconv
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math/rand"
+	"testing"
+)
+
+func TestPack(t *testing.T) {
+	type args struct {
+		count   int
+		maxW    int
+		maxH    int
+		padding int
+	}
+
+	tests := []struct {
+		name string
+		args args
+	}{
+		{name: "single image", args: args{count: 1, maxW: 64, maxH: 64, padding: 0}},
+		{name: "no padding", args: args{count: 50, maxW: 128, maxH: 512, padding: 0}},
+		{name: "padding 1", args: args{count: 50, maxW: 128, maxH: 512, padding: 1}},
+		{name: "padding 4", args: args{count: 100, maxW: 200, maxH: 1024, padding: 4}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rnd := rand.New(rand.NewSource(int64(test.args.count)))
+			imgs := make([]image.Image, test.args.count)
+			for idx := range imgs {
+				min := image.Pt(rnd.Intn(20)-10, rnd.Intn(20)-10)
+				r := image.Rectangle{Min: min, Max: min.Add(image.Pt(1+rnd.Intn(24), 1+rnd.Intn(24)))}
+				imgs[idx] = generateRandomNRGBA(t, r, int64(idx))
+			}
+
+			atlas, places, err := Pack(imgs, test.args.maxW, test.args.maxH, test.args.padding)
+			if err != nil {
+				t.Fatalf("Pack: unexpected error: %v\n", err)
+			}
+			if len(places) != len(imgs) {
+				t.Fatalf("Pack: expected %d placements, got %d\n", len(imgs), len(places))
+			}
+
+			atlasBounds := image.Rect(0, 0, test.args.maxW, test.args.maxH)
+			if !atlas.Bounds().In(atlasBounds) || atlas.Bounds().Min != (image.Point{}) {
+				t.Fatalf("Pack: atlas bounds %v exceed %v\n", atlas.Bounds(), atlasBounds)
+			}
+
+			for i, p := range places {
+				if p.Size() != imgs[i].Bounds().Size() {
+					t.Fatalf("Pack: placement %d has size %v, expected %v\n", i, p.Size(), imgs[i].Bounds().Size())
+				}
+				if !p.In(atlas.Bounds()) {
+					t.Fatalf("Pack: placement %d %v is outside of the atlas %v\n", i, p, atlas.Bounds())
+				}
+
+				//grow by the padding so touching sprites are detected as well
+				grown := image.Rectangle{Min: p.Min.Sub(image.Pt(test.args.padding, test.args.padding)), Max: p.Max.Add(image.Pt(test.args.padding, test.args.padding))}
+				for j := i + 1; j < len(places); j++ {
+					if grown.Overlaps(places[j]) {
+						t.Fatalf("Pack: placement %d %v overlaps placement %d %v with padding %d\n", i, p, j, places[j], test.args.padding)
+					}
+				}
+
+				actual, err := Crop(atlas, p)
+				if err != nil {
+					t.Fatalf("Crop: unexpected error: %v\n", err)
+				}
+				expected, _ := Crop(imgs[i], imgs[i].Bounds())
+				if !equalPix(actual, expected) {
+					format := fmt.Sprintf("\n") +
+						fmt.Sprintf("Crop(Pack(...), %v)\n", p) +
+						fmt.Sprintf("Pixels of sprite %d differ from the source\n", i)
+					t.Fatalf(format)
+				}
+			}
+		})
+	}
+}
+
+func TestPackErrors(t *testing.T) {
+	small := generateRandomNRGBA(t, image.Rect(0, 0, 8, 8), 1)
+
+	tests := []struct {
+		name string
+		imgs []image.Image
+		maxW int
+		maxH int
+	}{
+		{name: "no images", imgs: nil, maxW: 64, maxH: 64},
+		{name: "invalid size", imgs: []image.Image{small}, maxW: 0, maxH: 64},
+		{name: "image too wide", imgs: []image.Image{small}, maxW: 7, maxH: 64},
+		{name: "images do not fit", imgs: []image.Image{small, small, small, small, small}, maxW: 16, maxH: 16},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, err := Pack(test.imgs, test.maxW, test.maxH, 0); err == nil {
+				t.Errorf("Pack: expected an error\n")
+			}
+		})
+	}
+
+	if _, _, err := Pack([]image.Image{small, nil}, 64, 64, 0); !errors.Is(err, ErrNilImage) {
+		t.Errorf("Pack(nil image) = %v, expected %v\n", err, ErrNilImage)
+	}
+
+	//four 8x8 images fit exactly without padding, but not with padding
+	if _, _, err := Pack([]image.Image{small, small, small, small}, 16, 16, 0); err != nil {
+		t.Errorf("Pack(4x 8x8, 16x16): unexpected error: %v\n", err)
+	}
+	if _, _, err := Pack([]image.Image{small, small, small, small}, 16, 16, 1); err == nil {
+		t.Errorf("Pack(4x 8x8, 16x16, padding 1): expected an error\n")
+	}
+}
+
+func equalPix(a, b *image.NRGBA) bool {
+	if a.Bounds().Size() != b.Bounds().Size() {
+		return false
+	}
+	for y := 0; y < a.Rect.Dy(); y++ {
+		for x := 0; x < a.Rect.Dx(); x++ {
+			if a.NRGBAAt(a.Rect.Min.X+x, a.Rect.Min.Y+y) != b.NRGBAAt(b.Rect.Min.X+x, b.Rect.Min.Y+y) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func BenchmarkPack(b *testing.B) {
+	rnd := rand.New(rand.NewSource(1))
+	imgs := make([]image.Image, 500)
+	for idx := range imgs {
+		imgs[idx] = generateRandomNRGBA(b, image.Rect(0, 0, 8+rnd.Intn(24), 8+rnd.Intn(24)), int64(idx))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := Pack(imgs, 1024, 1024, 1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
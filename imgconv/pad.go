@@ -0,0 +1,78 @@
+package imgconv
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// Anchor selects where Pad places the source image within the new bounds.
+type Anchor int
+
+// The anchors name the side or corner of the new bounds the image touches.
+// AnchorCenter centers the image in both directions.
+const (
+	AnchorTopLeft Anchor = iota
+	AnchorTop
+	AnchorTopRight
+	AnchorLeft
+	AnchorCenter
+	AnchorRight
+	AnchorBottomLeft
+	AnchorBottom
+	AnchorBottomRight
+)
+
+// Pad returns an *image.NRGBA image with bounds r, containing any image m at
+// the position anchor and filled with the color fill everywhere else.
+// If the free space cannot be split evenly for AnchorCenter and the other
+// centered anchors, the extra pixel is on the right and bottom side.
+// r must be at least as large as m; Pad never crops.
+func Pad(m image.Image, r image.Rectangle, fill color.Color, anchor Anchor) (*image.NRGBA, error) {
+	if anchor < AnchorTopLeft || anchor > AnchorBottomRight {
+		return nil, fmt.Errorf("invalid anchor: %d", anchor)
+	}
+
+	dx, dy := r.Dx()-m.Bounds().Dx(), r.Dy()-m.Bounds().Dy()
+	if dx < 0 || dy < 0 {
+		return nil, fmt.Errorf("bounds %v smaller than the image bounds %v", r, m.Bounds())
+	}
+
+	//column and row of the anchor: 0 start, 1 center, 2 end
+	col, row := int(anchor)%3, int(anchor)/3
+	pt := r.Min.Add(image.Pt(dx*col/2, dy*row/2))
+
+	return PadAt(m, r, fill, pt)
+}
+
+// PadAt is like Pad, but places the top left corner of m at pt.
+// m placed at pt must be inside r.
+func PadAt(m image.Image, r image.Rectangle, fill color.Color, pt image.Point) (*image.NRGBA, error) {
+	b := m.Bounds()
+	place := b.Sub(b.Min).Add(pt)
+	if !place.In(r) {
+		return nil, fmt.Errorf("image placed at %v exceeds the bounds %v", place, r)
+	}
+	if !validSize(r) {
+		return nil, fmt.Errorf("%w: %v", ErrImageTooLarge, r)
+	}
+
+	img := UniformNRGBA(fill, r)
+	convertNRGBAAt(img, m, pt)
+
+	return img, nil
+}
+
+// convertNRGBAAt converts m into img with the top left corner of m at pt.
+// m placed at pt must be inside the bounds of img.
+func convertNRGBAAt(img *image.NRGBA, m image.Image, pt image.Point) {
+	b := m.Bounds()
+	if b.Empty() {
+		return
+	}
+
+	//a view of the placed pixels with the bounds of m
+	dst := img.SubImage(b.Sub(b.Min).Add(pt)).(*image.NRGBA)
+	dst.Rect = b
+	convertNRGBA(dst, m)
+}
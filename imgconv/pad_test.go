@@ -0,0 +1,91 @@
+package imgconv
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestPad(t *testing.T) {
+	fill := color.NRGBA{1, 2, 3, 4}
+	m := generateRandomNRGBA(t, image.Rect(-5, 3, -2, 5), 1)
+	r := image.Rect(10, 20, 18, 25)
+
+	tests := []struct {
+		anchor   Anchor
+		expected image.Point
+	}{
+		{anchor: AnchorTopLeft, expected: image.Pt(10, 20)},
+		{anchor: AnchorTop, expected: image.Pt(12, 20)},
+		{anchor: AnchorTopRight, expected: image.Pt(15, 20)},
+		{anchor: AnchorLeft, expected: image.Pt(10, 21)},
+		{anchor: AnchorCenter, expected: image.Pt(12, 21)},
+		{anchor: AnchorRight, expected: image.Pt(15, 21)},
+		{anchor: AnchorBottomLeft, expected: image.Pt(10, 23)},
+		{anchor: AnchorBottom, expected: image.Pt(12, 23)},
+		{anchor: AnchorBottomRight, expected: image.Pt(15, 23)},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("anchor %d", test.anchor), func(t *testing.T) {
+			actual, err := Pad(m, r, fill, test.anchor)
+			if err != nil {
+				t.Fatalf("Pad: unexpected error: %v\n", err)
+			}
+			assertPadded(t, m, actual, r, fill, test.expected)
+		})
+	}
+}
+
+func TestPadAt(t *testing.T) {
+	m := generateRandomRGBA(t, image.Rect(0, 0, 4, 4), 1)
+	r := image.Rect(0, 0, 10, 10)
+
+	actual, err := PadAt(m, r, color.Transparent, image.Pt(6, 1))
+	if err != nil {
+		t.Fatalf("PadAt: unexpected error: %v\n", err)
+	}
+	assertPadded(t, m, actual, r, color.NRGBA{}, image.Pt(6, 1))
+
+	for _, pt := range []image.Point{{7, 1}, {-1, 0}, {0, 7}} {
+		if _, err := PadAt(m, r, color.Transparent, pt); err == nil {
+			t.Errorf("PadAt(%v): expected an error\n", pt)
+		}
+	}
+}
+
+func TestPadErrors(t *testing.T) {
+	m := generateRandomNRGBA(t, image.Rect(0, 0, 4, 4), 1)
+
+	for _, r := range []image.Rectangle{image.Rect(0, 0, 3, 8), image.Rect(0, 0, 8, 3), {}} {
+		if _, err := Pad(m, r, color.White, AnchorCenter); err == nil {
+			t.Errorf("Pad(%v): expected an error\n", r)
+		}
+	}
+
+	if _, err := Pad(m, image.Rect(0, 0, 8, 8), color.White, Anchor(9)); err == nil {
+		t.Errorf("Pad(Anchor(9)): expected an error\n")
+	}
+}
+
+func assertPadded(t testing.TB, m image.Image, actual *image.NRGBA, r image.Rectangle, fill color.NRGBA, pt image.Point) {
+	t.Helper()
+
+	if actual.Bounds() != r {
+		t.Fatalf("expected bounds %v, got %v\n", r, actual.Bounds())
+	}
+
+	offset := pt.Sub(m.Bounds().Min)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			expected := fill
+			if p := image.Pt(x, y).Sub(offset); p.In(m.Bounds()) {
+				expected = color.NRGBAModel.Convert(m.At(p.X, p.Y)).(color.NRGBA)
+			}
+			if c := actual.NRGBAAt(x, y); c != expected {
+				t.Fatalf("different pixel at x=%d, y=%d: Expected: %+v - Actual: %+v\n", x, y, expected, c)
+			}
+		}
+	}
+}
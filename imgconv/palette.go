@@ -0,0 +1,272 @@
+package imgconv
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"sort"
+)
+
+const maxPaletteSize = 256
+
+// MedianCutQuantizer builds an adaptive palette with the median cut
+// algorithm. It implements draw.Quantizer and can be used with gif.Encode.
+type MedianCutQuantizer struct{}
+
+var _ draw.Quantizer = MedianCutQuantizer{}
+
+// Quantize appends up to cap(p) - len(p) colors to p and returns the updated palette.
+// Images with fewer distinct colors than requested get an exact palette.
+func (q MedianCutQuantizer) Quantize(p color.Palette, m image.Image) color.Palette {
+	n := cap(p) - len(p)
+	if n <= 0 {
+		return p
+	}
+
+	for _, c := range medianCut(ToNRGBA(m), n) {
+		p = append(p, c)
+	}
+
+	return p
+}
+
+// ToPaletted converts any image m to an *image.Paletted image with an adaptive
+// palette of at most maxColors colors. maxColors is clamped to [1, 256].
+// Every pixel is mapped to its nearest palette entry.
+func ToPaletted(m image.Image, maxColors int) *image.Paletted {
+	return ToPalettedDithered(m, maxColors, DitherNone)
+}
+
+// ToPalettedDithered is like ToPaletted, but applies the dithering
+// algorithm dither while mapping the pixels to the palette.
+func ToPalettedDithered(m image.Image, maxColors int, dither Dither) *image.Paletted {
+	if maxColors < 1 {
+		maxColors = 1
+	}
+	if maxColors > maxPaletteSize {
+		maxColors = maxPaletteSize
+	}
+
+	src := ToNRGBA(m)
+	p := MedianCutQuantizer{}.Quantize(make(color.Palette, 0, maxColors), src)
+
+	img := image.NewPaletted(src.Bounds(), p)
+	pal := newNRGBAPalette(p)
+
+	switch dither {
+	case DitherFloydSteinberg:
+		ditherFloydSteinberg(img, src, pal)
+	case DitherBayer4x4:
+		ditherOrdered(img, src, pal, bayer4x4)
+	case DitherBayer8x8:
+		ditherOrdered(img, src, pal, bayer8x8)
+	default:
+		mapNearest(img, src, pal)
+	}
+
+	return img
+}
+
+// mapNearest maps every pixel of src to its nearest palette entry.
+func mapNearest(dst *image.Paletted, src *image.NRGBA, pal *nrgbaPalette) {
+	b := src.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		si := src.PixOffset(b.Min.X, y)
+		di := dst.PixOffset(b.Min.X, y)
+		for x := b.Min.X; x < b.Max.X; x, si, di = x+1, si+4, di+1 {
+			s := src.Pix[si : si+4 : si+4]
+			dst.Pix[di] = pal.index(color.NRGBA{s[0], s[1], s[2], s[3]})
+		}
+	}
+}
+
+// nrgbaPalette looks up the nearest palette entry of a color and caches the
+// result, since images usually contain the same colors many times.
+type nrgbaPalette struct {
+	colors []color.NRGBA
+	cache  map[color.NRGBA]uint8
+}
+
+func newNRGBAPalette(p color.Palette) *nrgbaPalette {
+	colors := make([]color.NRGBA, len(p))
+	for idx, c := range p {
+		colors[idx] = color.NRGBAModel.Convert(c).(color.NRGBA)
+	}
+
+	return &nrgbaPalette{
+		colors: colors,
+		cache:  make(map[color.NRGBA]uint8),
+	}
+}
+
+func (p *nrgbaPalette) index(c color.NRGBA) uint8 {
+	if idx, ok := p.cache[c]; ok {
+		return idx
+	}
+
+	idx, best := 0, -1
+	for i, pc := range p.colors {
+		dr := int(c.R) - int(pc.R)
+		dg := int(c.G) - int(pc.G)
+		db := int(c.B) - int(pc.B)
+		da := int(c.A) - int(pc.A)
+		d := dr*dr + dg*dg + db*db + da*da
+		if best < 0 || d < best {
+			idx, best = i, d
+			if d == 0 {
+				break
+			}
+		}
+	}
+
+	p.cache[c] = uint8(idx)
+	return uint8(idx)
+}
+
+type colorCount struct {
+	c color.NRGBA
+	n int
+}
+
+// medianCut returns at most n colors representing the image m.
+// The colors are returned in first seen order if m contains at most n distinct colors.
+func medianCut(m *image.NRGBA, n int) []color.NRGBA {
+	b := m.Bounds()
+	counts := make(map[color.NRGBA]int)
+	order := make([]color.NRGBA, 0)
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		i := m.PixOffset(b.Min.X, y)
+		for x := b.Min.X; x < b.Max.X; x, i = x+1, i+4 {
+			s := m.Pix[i : i+4 : i+4]
+			c := color.NRGBA{s[0], s[1], s[2], s[3]}
+			if _, ok := counts[c]; !ok {
+				order = append(order, c)
+			}
+			counts[c]++
+		}
+	}
+
+	if len(order) <= n {
+		return order
+	}
+
+	box := make([]colorCount, len(order))
+	for idx, c := range order {
+		box[idx] = colorCount{c: c, n: counts[c]}
+	}
+
+	boxes := [][]colorCount{box}
+	for len(boxes) < n {
+		idx, ch, maxRange := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			c, r := widestChannel(box)
+			if r > maxRange {
+				idx, ch, maxRange = i, c, r
+			}
+		}
+
+		if idx < 0 {
+			break
+		}
+
+		lo, hi := splitBox(boxes[idx], ch)
+		boxes[idx] = lo
+		boxes = append(boxes, hi)
+	}
+
+	colors := make([]color.NRGBA, len(boxes))
+	for idx, box := range boxes {
+		colors[idx] = boxAverage(box)
+	}
+
+	return colors
+}
+
+func channel(c color.NRGBA, ch int) uint8 {
+	switch ch {
+	case 0:
+		return c.R
+	case 1:
+		return c.G
+	case 2:
+		return c.B
+	default:
+		return c.A
+	}
+}
+
+// widestChannel returns the channel with the largest value range in box and that range.
+func widestChannel(box []colorCount) (int, int) {
+	ch, maxRange := 0, 0
+	for c := 0; c < 4; c++ {
+		lo, hi := uint8(255), uint8(0)
+		for _, cc := range box {
+			v := channel(cc.c, c)
+			if v < lo {
+				lo = v
+			}
+			if v > hi {
+				hi = v
+			}
+		}
+
+		if r := int(hi) - int(lo); r > maxRange {
+			ch, maxRange = c, r
+		}
+	}
+
+	return ch, maxRange
+}
+
+// splitBox sorts box along channel ch and splits it at the weighted median.
+func splitBox(box []colorCount, ch int) ([]colorCount, []colorCount) {
+	sort.Slice(box, func(i, j int) bool {
+		for c := 0; c < 4; c++ {
+			vi := channel(box[i].c, (ch+c)%4)
+			vj := channel(box[j].c, (ch+c)%4)
+			if vi != vj {
+				return vi < vj
+			}
+		}
+		return false
+	})
+
+	total := 0
+	for _, cc := range box {
+		total += cc.n
+	}
+
+	split, sum := 1, 0
+	for idx, cc := range box[:len(box)-1] {
+		sum += cc.n
+		split = idx + 1
+		if 2*sum >= total {
+			break
+		}
+	}
+
+	return box[:split:split], box[split:]
+}
+
+// boxAverage returns the pixel count weighted average of box.
+func boxAverage(box []colorCount) color.NRGBA {
+	var r, g, b, a, total int
+	for _, cc := range box {
+		r += int(cc.c.R) * cc.n
+		g += int(cc.c.G) * cc.n
+		b += int(cc.c.B) * cc.n
+		a += int(cc.c.A) * cc.n
+		total += cc.n
+	}
+
+	return color.NRGBA{
+		R: uint8((r + total/2) / total),
+		G: uint8((g + total/2) / total),
+		B: uint8((b + total/2) / total),
+		A: uint8((a + total/2) / total),
+	}
+}
@@ -0,0 +1,154 @@
+package imgconv
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+func TestToPaletted(t *testing.T) {
+	tests := []struct {
+		name string
+		args struct {
+			m         image.Image
+			maxColors int
+		}
+		expectExact bool
+	}{
+		{
+			name: "should return an exact palette for few colors",
+			args: struct {
+				m         image.Image
+				maxColors int
+			}{
+				m:         generateStripesNRGBA(t, image.Rect(-2, 3, 10, 9), []color.NRGBA{{255, 0, 0, 255}, {0, 255, 0, 128}, {0, 0, 0, 0}, {1, 2, 3, 4}}),
+				maxColors: 4,
+			},
+			expectExact: true,
+		},
+		{
+			name: "should return an exact palette with room to spare",
+			args: struct {
+				m         image.Image
+				maxColors int
+			}{
+				m:         generateStripesNRGBA(t, image.Rect(0, 0, 8, 8), []color.NRGBA{{10, 20, 30, 255}, {40, 50, 60, 255}}),
+				maxColors: 256,
+			},
+			expectExact: true,
+		},
+		{
+			name: "should cap the palette for many colors",
+			args: struct {
+				m         image.Image
+				maxColors int
+			}{
+				m:         generateRandomNRGBA(t, image.Rect(0, 0, 32, 32), 1),
+				maxColors: 16,
+			},
+		},
+		{
+			name: "should clamp maxColors to 256",
+			args: struct {
+				m         image.Image
+				maxColors int
+			}{
+				m:         generateRandomNRGBA(t, image.Rect(0, 0, 64, 64), 2),
+				maxColors: 1000,
+			},
+		},
+		{
+			name: "should clamp maxColors to 1",
+			args: struct {
+				m         image.Image
+				maxColors int
+			}{
+				m:         generateRandomNRGBA(t, image.Rect(0, 0, 4, 4), 3),
+				maxColors: 0,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := ToPaletted(test.args.m, test.args.maxColors)
+			format := fmt.Sprintf("\nToPaletted(%T, %d)\n", test.args.m, test.args.maxColors)
+
+			maxColors := test.args.maxColors
+			if maxColors < 1 {
+				maxColors = 1
+			}
+			if maxColors > 256 {
+				maxColors = 256
+			}
+
+			if len(actual.Palette) > maxColors {
+				t.Fatalf("%sExpected at most %d colors, got %d\n", format, maxColors, len(actual.Palette))
+			}
+
+			if actual.Bounds() != test.args.m.Bounds() {
+				t.Fatalf("%sExpected bounds %v, got %v\n", format, test.args.m.Bounds(), actual.Bounds())
+			}
+
+			if test.expectExact {
+				assertConvertedImage(t, test.args.m, actual, color.NRGBAModel, format)
+			}
+
+			assertNearestPaletteIndex(t, test.args.m, actual, format)
+		})
+	}
+}
+
+func TestMedianCutQuantizerWithGIF(t *testing.T) {
+	m := generateStripesNRGBA(t, image.Rect(0, 0, 8, 8), []color.NRGBA{{255, 0, 0, 255}, {0, 0, 255, 255}, {0, 255, 0, 255}})
+
+	buf := bytes.NewBuffer(nil)
+	err := gif.Encode(buf, m, &gif.Options{NumColors: 256, Quantizer: MedianCutQuantizer{}})
+	if err != nil {
+		t.Fatalf("could not encode gif: %v\n", err)
+	}
+
+	actual, err := gif.Decode(buf)
+	if err != nil {
+		t.Fatalf("could not decode gif: %v\n", err)
+	}
+
+	assertConvertedImage(t, m, actual, color.NRGBAModel, "\ngif.Encode with MedianCutQuantizer\n")
+}
+
+func generateStripesNRGBA(t testing.TB, r image.Rectangle, colors []color.NRGBA) *image.NRGBA {
+	t.Helper()
+
+	m := image.NewNRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			m.SetNRGBA(x, y, colors[(x-r.Min.X)%len(colors)])
+		}
+	}
+
+	return m
+}
+
+func assertNearestPaletteIndex(t testing.TB, src image.Image, actual *image.Paletted, format string) {
+	t.Helper()
+
+	dist := func(a, b color.NRGBA) int {
+		dr, dg, db, da := int(a.R)-int(b.R), int(a.G)-int(b.G), int(a.B)-int(b.B), int(a.A)-int(b.A)
+		return dr*dr + dg*dg + db*db + da*da
+	}
+
+	for y := src.Bounds().Min.Y; y < src.Bounds().Max.Y; y++ {
+		for x := src.Bounds().Min.X; x < src.Bounds().Max.X; x++ {
+			c := color.NRGBAModel.Convert(src.At(x, y)).(color.NRGBA)
+			chosen := dist(c, actual.Palette[actual.ColorIndexAt(x, y)].(color.NRGBA))
+			for _, p := range actual.Palette {
+				if d := dist(c, p.(color.NRGBA)); d < chosen {
+					t.Fatalf("%sAssert image:\t pixel at x=%d, y=%d is not mapped to the nearest palette color\n", format, x, y)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package imgconv
+
+import (
+	"image"
+	"sync"
+)
+
+var (
+	premulOnce   sync.Once
+	premulTable  *[256][256]uint8
+	unpremulOnce sync.Once
+	unpremulLUT  *[256][256]uint8
+)
+
+// premultiplyTable returns a lookup table indexed by [alpha][color] with the
+// same rounding as color.RGBAModel: (c * 0x101 * a / 0xff) >> 8.
+func premultiplyTable() *[256][256]uint8 {
+	premulOnce.Do(func() {
+		premulTable = new([256][256]uint8)
+		for a := uint32(0); a < 256; a++ {
+			for c := uint32(0); c < 256; c++ {
+				premulTable[a][c] = uint8((c * 0x101 * a / 0xff) >> 8)
+			}
+		}
+	})
+
+	return premulTable
+}
+
+// unpremultiplyTable returns a lookup table indexed by [alpha][color] with the
+// same rounding as color.NRGBAModel: (c * 0x101 * 0xffff / (a * 0x101)) >> 8.
+// Colors greater than alpha are not valid premultiplied values and are clamped to 255.
+func unpremultiplyTable() *[256][256]uint8 {
+	unpremulOnce.Do(func() {
+		unpremulLUT = new([256][256]uint8)
+		for a := uint32(1); a < 256; a++ {
+			for c := uint32(0); c < 256; c++ {
+				v := (c * 0x101 * 0xffff / (a * 0x101)) >> 8
+				if v > 0xff {
+					v = 0xff
+				}
+				unpremulLUT[a][c] = uint8(v)
+			}
+		}
+	})
+
+	return unpremulLUT
+}
+
+// Premultiply returns a new *image.RGBA image with the colors of src
+// multiplied by their alpha value. The rounding is the same as color.RGBAModel,
+// so alpha 0 results in zero colors and alpha 255 keeps the colors unchanged.
+func Premultiply(src *image.NRGBA) *image.RGBA {
+	img := image.NewRGBA(src.Bounds())
+	premultiply(img.Pix, img.Stride, 0, src.Pix, src.Stride, src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y), src.Rect)
+
+	return img
+}
+
+// PremultiplyInPlace premultiplies the colors of m in place and returns an
+// *image.RGBA image sharing its pixels with m. m must not be used afterwards.
+func PremultiplyInPlace(m *image.NRGBA) *image.RGBA {
+	i := m.PixOffset(m.Rect.Min.X, m.Rect.Min.Y)
+	premultiply(m.Pix, m.Stride, i, m.Pix, m.Stride, i, m.Rect)
+
+	return &image.RGBA{Pix: m.Pix, Stride: m.Stride, Rect: m.Rect}
+}
+
+// Unpremultiply returns a new *image.NRGBA image with the colors of src
+// divided by their alpha value. The rounding is the same as color.NRGBAModel,
+// so alpha 0 results in zero colors and alpha 255 keeps the colors unchanged.
+func Unpremultiply(src *image.RGBA) *image.NRGBA {
+	img := image.NewNRGBA(src.Bounds())
+	unpremultiply(img.Pix, img.Stride, 0, src.Pix, src.Stride, src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y), src.Rect)
+
+	return img
+}
+
+// UnpremultiplyInPlace divides the colors of m by their alpha value in place
+// and returns an *image.NRGBA image sharing its pixels with m. m must not be used afterwards.
+func UnpremultiplyInPlace(m *image.RGBA) *image.NRGBA {
+	i := m.PixOffset(m.Rect.Min.X, m.Rect.Min.Y)
+	unpremultiply(m.Pix, m.Stride, i, m.Pix, m.Stride, i, m.Rect)
+
+	return &image.NRGBA{Pix: m.Pix, Stride: m.Stride, Rect: m.Rect}
+}
+
+func premultiply(dst []byte, dstStride, dstOffset int, src []byte, srcStride, srcOffset int, r image.Rectangle) {
+	lut := premultiplyTable()
+	rowSize := 4 * r.Dx()
+
+	for y := 0; y < r.Dy(); y++ {
+		d := dst[dstOffset+y*dstStride : dstOffset+y*dstStride+rowSize]
+		s := src[srcOffset+y*srcStride : srcOffset+y*srcStride+rowSize]
+		for i := 0; i < rowSize; i += 4 {
+			a := s[i+3]
+			t := &lut[a]
+			d[i+0] = t[s[i+0]]
+			d[i+1] = t[s[i+1]]
+			d[i+2] = t[s[i+2]]
+			d[i+3] = a
+		}
+	}
+}
+
+func unpremultiply(dst []byte, dstStride, dstOffset int, src []byte, srcStride, srcOffset int, r image.Rectangle) {
+	lut := unpremultiplyTable()
+	rowSize := 4 * r.Dx()
+
+	for y := 0; y < r.Dy(); y++ {
+		d := dst[dstOffset+y*dstStride : dstOffset+y*dstStride+rowSize]
+		s := src[srcOffset+y*srcStride : srcOffset+y*srcStride+rowSize]
+		for i := 0; i < rowSize; i += 4 {
+			a := s[i+3]
+			t := &lut[a]
+			d[i+0] = t[s[i+0]]
+			d[i+1] = t[s[i+1]]
+			d[i+2] = t[s[i+2]]
+			d[i+3] = a
+		}
+	}
+}
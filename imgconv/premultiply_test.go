@@ -0,0 +1,118 @@
+package imgconv
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestPremultiply(t *testing.T) {
+	bounds := image.Rect(-3, 5, 14, 16)
+
+	tests := []struct {
+		name string
+		args struct {
+			m *image.NRGBA
+		}
+	}{
+		{
+			name: "should premultiply nrgba image",
+			args: struct{ m *image.NRGBA }{
+				m: generateRandomNRGBA(t, bounds, 1),
+			},
+		},
+		{
+			name: "should premultiply nrgba sub image",
+			args: struct{ m *image.NRGBA }{
+				m: generateRandomNRGBA(t, bounds, 2).SubImage(image.Rect(0, 7, 5, 12)).(*image.NRGBA),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			format := fmt.Sprintf("\nPremultiply(%v)\n", test.args.m.Bounds())
+			assertConvertedImage(t, test.args.m, Premultiply(test.args.m), color.RGBAModel, format)
+
+			clone := CloneNRGBA(test.args.m)
+			format = fmt.Sprintf("\nPremultiplyInPlace(%v)\n", test.args.m.Bounds())
+			assertConvertedImage(t, test.args.m, PremultiplyInPlace(clone), color.RGBAModel, format)
+		})
+	}
+}
+
+func TestUnpremultiply(t *testing.T) {
+	bounds := image.Rect(-3, 5, 14, 16)
+
+	tests := []struct {
+		name string
+		args struct {
+			m *image.RGBA
+		}
+	}{
+		{
+			name: "should unpremultiply rgba image",
+			args: struct{ m *image.RGBA }{
+				m: generateRandomRGBA(t, bounds, 1),
+			},
+		},
+		{
+			name: "should unpremultiply rgba sub image",
+			args: struct{ m *image.RGBA }{
+				m: generateRandomRGBA(t, bounds, 2).SubImage(image.Rect(0, 7, 5, 12)).(*image.RGBA),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			format := fmt.Sprintf("\nUnpremultiply(%v)\n", test.args.m.Bounds())
+			assertConvertedImage(t, test.args.m, Unpremultiply(test.args.m), color.NRGBAModel, format)
+
+			clone := &image.RGBA{Pix: clonePix(test.args.m.Pix), Stride: test.args.m.Stride, Rect: test.args.m.Rect}
+			format = fmt.Sprintf("\nUnpremultiplyInPlace(%v)\n", test.args.m.Bounds())
+			assertConvertedImage(t, test.args.m, UnpremultiplyInPlace(clone), color.NRGBAModel, format)
+		})
+	}
+}
+
+func TestPremultiplyRoundTripError(t *testing.T) {
+	//every combination of color value and alpha
+	m := image.NewNRGBA(image.Rect(0, 0, 256, 256))
+	for a := 0; a < 256; a++ {
+		for c := 0; c < 256; c++ {
+			m.SetNRGBA(c, a, color.NRGBA{uint8(c), uint8(c), uint8(c), uint8(a)})
+		}
+	}
+
+	actual := Unpremultiply(Premultiply(m))
+
+	for a := 0; a < 256; a++ {
+		maxError := 0
+		for c := 0; c < 256; c++ {
+			px := actual.NRGBAAt(c, a)
+			if px.A != uint8(a) {
+				t.Fatalf("alpha changed at a=%d, c=%d: %d\n", a, c, px.A)
+			}
+
+			diff := int(px.R) - c
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff > maxError {
+				maxError = diff
+			}
+		}
+
+		switch {
+		case a == 0 && maxError != 255:
+			//alpha 0 always results in zero colors
+			t.Errorf("alpha %d: expected all colors to become zero, max error %d\n", a, maxError)
+		case a == 255 && maxError != 0:
+			t.Errorf("alpha %d: expected exact round trip, max error %d\n", a, maxError)
+		case a > 0 && maxError > 255/a+1:
+			t.Errorf("alpha %d: max error %d exceeds %d\n", a, maxError, 255/a+1)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package imgconv
+
+import (
+	"image"
+	"reflect"
+	"sync"
+)
+
+// ConverterFunc converts m to an *image.NRGBA image with the same bounds as m.
+// It returns false if it cannot convert images of the type of m.
+type ConverterFunc func(m image.Image) (*image.NRGBA, bool)
+
+type registeredConverter struct {
+	f ConverterFunc
+}
+
+var (
+	convertersMu sync.RWMutex
+	converters   []*registeredConverter
+)
+
+// RegisterConverter adds f to the converters used by ToNRGBA, CloneNRGBA and
+// the other conversions to NRGBA for image types outside of the image package.
+// The types of the image package always use the built-in conversion, so
+// registered converters cannot change their behavior.
+//
+// Converters are consulted in registration order before the generic
+// conversion through the At method, and the first one returning true wins.
+// Results with different bounds than the source image are ignored.
+// ToNRGBA returns the result of a converter without copying.
+//
+// The returned function removes f again, for example at the end of a test.
+func RegisterConverter(f ConverterFunc) (unregister func()) {
+	c := &registeredConverter{f: f}
+
+	convertersMu.Lock()
+	converters = append(converters, c)
+	convertersMu.Unlock()
+
+	return func() {
+		convertersMu.Lock()
+		defer convertersMu.Unlock()
+
+		for idx, rc := range converters {
+			if rc == c {
+				converters = append(converters[:idx:idx], converters[idx+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// convertRegistered converts m with the first registered converter accepting it.
+func convertRegistered(m image.Image) (*image.NRGBA, bool) {
+	convertersMu.RLock()
+	registered := converters
+	convertersMu.RUnlock()
+
+	if len(registered) == 0 || isImagePackageType(m) {
+		return nil, false
+	}
+
+	for _, c := range registered {
+		if img, ok := c.f(m); ok && img != nil && img.Bounds() == m.Bounds() {
+			return img, true
+		}
+	}
+
+	return nil, false
+}
+
+// isImagePackageType reports whether m is one of the types of the image package.
+func isImagePackageType(m image.Image) bool {
+	t := reflect.TypeOf(m)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t.PkgPath() == "image"
+}
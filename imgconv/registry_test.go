@@ -0,0 +1,141 @@
+package imgconv
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+// planarImage is a non-stdlib image type with one plane per channel.
+type planarImage struct {
+	r      image.Rectangle
+	planes [4][]uint8
+}
+
+func (p *planarImage) ColorModel() color.Model { return color.NRGBAModel }
+func (p *planarImage) Bounds() image.Rectangle { return p.r }
+func (p *planarImage) At(x, y int) color.Color {
+	i := (y-p.r.Min.Y)*p.r.Dx() + (x - p.r.Min.X)
+	return color.NRGBA{p.planes[0][i], p.planes[1][i], p.planes[2][i], p.planes[3][i]}
+}
+
+func convertPlanar(m image.Image) (*image.NRGBA, bool) {
+	p, ok := m.(*planarImage)
+	if !ok {
+		return nil, false
+	}
+
+	img := image.NewNRGBA(p.r)
+	for i := range p.planes[0] {
+		img.Pix[4*i+0] = p.planes[0][i]
+		img.Pix[4*i+1] = p.planes[1][i]
+		img.Pix[4*i+2] = p.planes[2][i]
+		img.Pix[4*i+3] = p.planes[3][i]
+	}
+
+	return img, true
+}
+
+func generatePlanarImage(t testing.TB, r image.Rectangle, seed int64) *planarImage {
+	t.Helper()
+
+	src := generateRandomNRGBA(t, r, seed)
+	p := &planarImage{r: r}
+	for c := range p.planes {
+		p.planes[c] = make([]uint8, r.Dx()*r.Dy())
+		for i := range p.planes[c] {
+			p.planes[c][i] = src.Pix[4*i+c]
+		}
+	}
+
+	return p
+}
+
+func TestRegisterConverter(t *testing.T) {
+	m := generatePlanarImage(t, image.Rect(-2, 3, 9, 11), 1)
+
+	calls := 0
+	unregister := RegisterConverter(func(m image.Image) (*image.NRGBA, bool) {
+		calls++
+		return convertPlanar(m)
+	})
+	defer unregister()
+
+	actual := ToNRGBA(m)
+	if calls != 1 {
+		t.Fatalf("ToNRGBA: expected the converter to be called once, got %d calls\n", calls)
+	}
+	assertConvertedImage(t, m, actual, color.NRGBAModel, fmt.Sprintf("\nToNRGBA(%T)\n", m))
+
+	clone := CloneNRGBA(m)
+	if calls != 2 {
+		t.Fatalf("CloneNRGBA: expected the converter to be called, got %d calls\n", calls)
+	}
+	assertConvertedImage(t, m, clone, color.NRGBAModel, fmt.Sprintf("\nCloneNRGBA(%T)\n", m))
+
+	unregister()
+	ToNRGBA(m)
+	if calls != 2 {
+		t.Errorf("ToNRGBA: converter was called after unregistering\n")
+	}
+}
+
+func TestRegisterConverterOrder(t *testing.T) {
+	m := generatePlanarImage(t, image.Rect(0, 0, 4, 4), 1)
+	var order []string
+
+	register := func(name string, accept bool) func() {
+		return RegisterConverter(func(m image.Image) (*image.NRGBA, bool) {
+			order = append(order, name)
+			if !accept {
+				return nil, false
+			}
+			return UniformNRGBA(color.NRGBA{uint8(len(order)), 0, 0, 255}, m.Bounds()), true
+		})
+	}
+
+	defer register("declines", false)()
+	defer register("wrong bounds", true)()
+	defer RegisterConverter(func(m image.Image) (*image.NRGBA, bool) {
+		order = append(order, "ignored")
+		return image.NewNRGBA(image.Rect(0, 0, 1, 1)), true
+	})()
+	defer register("accepts", true)()
+	defer register("never called", true)()
+
+	actual := ToNRGBA(m)
+
+	expected := []string{"declines", "wrong bounds"}
+	if fmt.Sprint(order[:2]) != fmt.Sprint(expected) || order[len(order)-1] == "never called" {
+		t.Fatalf("ToNRGBA: unexpected converter order %v\n", order)
+	}
+	if c := actual.NRGBAAt(0, 0); c.R != uint8(len(order)) {
+		t.Errorf("ToNRGBA: expected the result of the first accepting converter, got %v\n", c)
+	}
+}
+
+func TestRegisterConverterKeepsBuiltinTypes(t *testing.T) {
+	defer RegisterConverter(func(m image.Image) (*image.NRGBA, bool) {
+		return UniformNRGBA(color.NRGBA{1, 2, 3, 4}, m.Bounds()), true
+	})()
+
+	cmyk := image.NewCMYK(image.Rect(0, 0, 4, 4))
+	rand.New(rand.NewSource(2)).Read(cmyk.Pix)
+
+	for _, src := range []image.Image{
+		generateRandomRGBA(t, image.Rect(0, 0, 4, 4), 1),
+		cmyk,
+		image.NewUniform(color.White),
+	} {
+		t.Run(fmt.Sprintf("%T", src), func(t *testing.T) {
+			if _, ok := convertRegistered(src); ok {
+				t.Fatalf("convertRegistered(%T): expected the built-in conversion\n", src)
+			}
+		})
+	}
+
+	actual := ToNRGBA(cmyk)
+	assertConvertedImage(t, cmyk, actual, color.NRGBAModel, "\nToNRGBA(*image.CMYK)\n")
+}
@@ -0,0 +1,311 @@
+package imgconv
+
+import (
+	"fmt"
+	"image"
+)
+
+// ResizeNearest scales m to a w x h *image.NRGBA image with nearest neighbor
+// sampling. The destination pixel x samples the source pixel floor((x+0.5)*sx),
+// where sx is the ratio of the source and the destination width, so upscaling
+// by an integer factor repeats every pixel exactly. The same applies to y.
+// m is checked with Validate and DefaultMaxPixels first.
+// The returned image has its origin at (0, 0).
+func ResizeNearest(m image.Image, w, h int) (*image.NRGBA, error) {
+	if w <= 0 || h <= 0 {
+		return nil, fmt.Errorf("invalid size: %dx%d", w, h)
+	}
+	if !validSize(image.Rect(0, 0, w, h)) {
+		return nil, fmt.Errorf("%w: %dx%d", ErrImageTooLarge, w, h)
+	}
+
+	if err := Validate(m, DefaultMaxPixels); err != nil {
+		return nil, err
+	}
+
+	src := ToNRGBA(m)
+	b := src.Bounds()
+
+	xs := nearestOffsets(b.Dx(), w)
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		sy := b.Min.Y + (2*y+1)*b.Dy()/(2*h)
+		si := src.PixOffset(b.Min.X, sy)
+		di := img.PixOffset(0, y)
+		for _, sx := range xs {
+			s := src.Pix[si+4*sx : si+4*sx+4 : si+4*sx+4]
+			d := img.Pix[di : di+4 : di+4]
+			d[0], d[1], d[2], d[3] = s[0], s[1], s[2], s[3]
+			di += 4
+		}
+	}
+
+	return img, nil
+}
+
+// nearestOffsets returns the source column sampled by every destination column.
+func nearestOffsets(srcSize, dstSize int) []int {
+	offsets := make([]int, dstSize)
+	for x := range offsets {
+		offsets[x] = (2*x + 1) * srcSize / (2 * dstSize)
+	}
+
+	return offsets
+}
+
+// ResizeBilinear scales m to a w x h *image.NRGBA image with bilinear
+// interpolation. Source pixels are sampled at their centers and clamped at the
+// borders. The colors are interpolated premultiplied by their alpha value, so
+// transparent pixels do not bleed their (invisible) color into the result and
+// leave no dark halo around transparent edges.
+// Bilinear interpolation reads at most 2x2 source pixels per destination pixel,
+// so downscaling by more than a factor of two skips source pixels.
+// m is checked with Validate and DefaultMaxPixels first.
+// The returned image has its origin at (0, 0).
+func ResizeBilinear(m image.Image, w, h int) (*image.NRGBA, error) {
+	if w <= 0 || h <= 0 {
+		return nil, fmt.Errorf("invalid size: %dx%d", w, h)
+	}
+	if !validSize(image.Rect(0, 0, w, h)) {
+		return nil, fmt.Errorf("%w: %dx%d", ErrImageTooLarge, w, h)
+	}
+
+	if err := Validate(m, DefaultMaxPixels); err != nil {
+		return nil, err
+	}
+
+	src := ToNRGBA(m)
+	b := src.Bounds()
+
+	xs := bilinearWeights(b.Dx(), w)
+	ys := bilinearWeights(b.Dy(), h)
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+
+	for y, wy := range ys {
+		r0 := src.PixOffset(b.Min.X, b.Min.Y+wy.i0)
+		r1 := src.PixOffset(b.Min.X, b.Min.Y+wy.i1)
+		di := img.PixOffset(0, y)
+		for _, wx := range xs {
+			var sum [4]int64
+			sum = bilinearAdd(sum, src.Pix[r0+4*wx.i0:r0+4*wx.i0+4], (fixedOne-wx.f)*(fixedOne-wy.f))
+			sum = bilinearAdd(sum, src.Pix[r0+4*wx.i1:r0+4*wx.i1+4], wx.f*(fixedOne-wy.f))
+			sum = bilinearAdd(sum, src.Pix[r1+4*wx.i0:r1+4*wx.i0+4], (fixedOne-wx.f)*wy.f)
+			sum = bilinearAdd(sum, src.Pix[r1+4*wx.i1:r1+4*wx.i1+4], wx.f*wy.f)
+
+			d := img.Pix[di : di+4 : di+4]
+			if a := sum[3]; a > 0 {
+				d[0] = uint8((sum[0] + a/2) / a)
+				d[1] = uint8((sum[1] + a/2) / a)
+				d[2] = uint8((sum[2] + a/2) / a)
+				d[3] = uint8((a + fixedOne*fixedOne/2) / (fixedOne * fixedOne))
+			}
+			di += 4
+		}
+	}
+
+	return img, nil
+}
+
+// fixedOne is 1.0 in the 16.16 fixed point format of the bilinear weights.
+const fixedOne = 1 << 16
+
+// bilinearWeight holds the two neighboring source pixels of a destination pixel
+// and the fixed point weight f of the second one.
+type bilinearWeight struct {
+	i0, i1 int
+	f      int64
+}
+
+func bilinearWeights(srcSize, dstSize int) []bilinearWeight {
+	weights := make([]bilinearWeight, dstSize)
+	last := int64(srcSize-1) * fixedOne
+
+	for x := range weights {
+		// center of the destination pixel x in source coordinates: (x+0.5)*srcSize/dstSize - 0.5
+		p := (int64(2*x+1)*int64(srcSize)*fixedOne)/int64(2*dstSize) - fixedOne/2
+		if p < 0 {
+			p = 0
+		}
+		if p > last {
+			p = last
+		}
+
+		i0 := int(p / fixedOne)
+		i1 := i0 + 1
+		if i1 >= srcSize {
+			i1 = srcSize - 1
+		}
+		weights[x] = bilinearWeight{i0: i0, i1: i1, f: p % fixedOne}
+	}
+
+	return weights
+}
+
+// bilinearAdd adds the premultiplied color s with the weight w to sum.
+// The colors are accumulated as c*a, so sum[0:3] / sum[3] is the straight color.
+func bilinearAdd(sum [4]int64, s []byte, w int64) [4]int64 {
+	s = s[:4:4]
+	a := int64(s[3])
+	wa := w * a
+
+	sum[0] += wa * int64(s[0])
+	sum[1] += wa * int64(s[1])
+	sum[2] += wa * int64(s[2])
+	sum[3] += wa
+
+	return sum
+}
+
+// ResizeBox scales m to a w x h *image.NRGBA image with a box filter: every
+// destination pixel is the area weighted average of all source pixels it
+// covers, including the fractional coverage of the pixels at its borders.
+// Unlike ResizeBilinear no source pixel is skipped, which makes it the
+// preferable filter for downscaling by large factors. Like ResizeBilinear the
+// colors are averaged premultiplied by their alpha value.
+// m is checked with Validate and DefaultMaxPixels first.
+// The returned image has its origin at (0, 0).
+func ResizeBox(m image.Image, w, h int) (*image.NRGBA, error) {
+	if w <= 0 || h <= 0 {
+		return nil, fmt.Errorf("invalid size: %dx%d", w, h)
+	}
+	if !validSize(image.Rect(0, 0, w, h)) {
+		return nil, fmt.Errorf("%w: %dx%d", ErrImageTooLarge, w, h)
+	}
+
+	if err := Validate(m, DefaultMaxPixels); err != nil {
+		return nil, err
+	}
+
+	src := ToNRGBA(m)
+	b := src.Bounds()
+
+	xs := boxWeights(b.Dx(), w)
+	ys := boxWeights(b.Dy(), h)
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+
+	//the sum of all weights of a destination pixel is the covered source area
+	area := int64(b.Dx()) * int64(b.Dy())
+	row := make([][4]int64, w)
+	sum := make([][4]int64, w)
+
+	for y, wy := range ys {
+		for x := range sum {
+			sum[x] = [4]int64{}
+		}
+
+		//reduce every covered source row horizontally and accumulate it vertically
+		for j, fy := range wy.w {
+			si := src.PixOffset(b.Min.X, b.Min.Y+wy.i0+j)
+			for x, wx := range xs {
+				var r [4]int64
+				for i, fx := range wx.w {
+					r = bilinearAdd(r, src.Pix[si+4*(wx.i0+i):si+4*(wx.i0+i)+4], fx)
+				}
+				row[x] = r
+			}
+			for x := range sum {
+				sum[x][0] += fy * row[x][0]
+				sum[x][1] += fy * row[x][1]
+				sum[x][2] += fy * row[x][2]
+				sum[x][3] += fy * row[x][3]
+			}
+		}
+
+		di := img.PixOffset(0, y)
+		for x := range sum {
+			d := img.Pix[di : di+4 : di+4]
+			if a := sum[x][3]; a > 0 {
+				d[0] = uint8((sum[x][0] + a/2) / a)
+				d[1] = uint8((sum[x][1] + a/2) / a)
+				d[2] = uint8((sum[x][2] + a/2) / a)
+				d[3] = uint8((a + area/2) / area)
+			}
+			di += 4
+		}
+	}
+
+	return img, nil
+}
+
+// boxWeight holds the first source pixel covered by a destination pixel and
+// the covered area of it and the following source pixels.
+type boxWeight struct {
+	i0 int
+	w  []int64
+}
+
+// boxWeights returns the coverage of the source pixels for every destination
+// pixel. The coordinates are scaled by srcSize*dstSize, so the source pixel i
+// spans [i*dstSize, (i+1)*dstSize) and the destination pixel x spans
+// [x*srcSize, (x+1)*srcSize), which keeps all weights integral.
+func boxWeights(srcSize, dstSize int) []boxWeight {
+	weights := make([]boxWeight, dstSize)
+
+	for x := range weights {
+		start, end := int64(x)*int64(srcSize), int64(x+1)*int64(srcSize)
+		i0 := int(start / int64(dstSize))
+		i1 := int((end + int64(dstSize) - 1) / int64(dstSize))
+
+		w := make([]int64, i1-i0)
+		for i := range w {
+			lo, hi := int64(i0+i)*int64(dstSize), int64(i0+i+1)*int64(dstSize)
+			if lo < start {
+				lo = start
+			}
+			if hi > end {
+				hi = end
+			}
+			w[i] = hi - lo
+		}
+		weights[x] = boxWeight{i0: i0, w: w}
+	}
+
+	return weights
+}
+
+// Thumbnail scales m down with ResizeBilinear to the largest size that fits
+// into maxW x maxH while preserving the aspect ratio. The fitted size is
+// rounded down, but each side is at least 1 pixel, so a 1x1000 image fitted
+// into 10x10 becomes 1x10. Images which already fit are never upscaled and
+// are returned as converted by ToNRGBA, keeping their bounds.
+// maxW and maxH must be positive, and m is checked with Validate and
+// DefaultMaxPixels.
+func Thumbnail(m image.Image, maxW, maxH int) (*image.NRGBA, error) {
+	if maxW <= 0 || maxH <= 0 {
+		return nil, fmt.Errorf("invalid size: %dx%d", maxW, maxH)
+	}
+	if err := Validate(m, DefaultMaxPixels); err != nil {
+		return nil, err
+	}
+
+	w, h := m.Bounds().Dx(), m.Bounds().Dy()
+	if w <= maxW && h <= maxH {
+		return ToNRGBA(m), nil
+	}
+
+	w, h = FitSize(w, h, maxW, maxH)
+
+	return ResizeBilinear(m, w, h)
+}
+
+// FitSize returns the size w x h scaled to fit into maxW x maxH, preserving
+// its aspect ratio, as used by Thumbnail. Both sides are at least 1.
+func FitSize(w, h, maxW, maxH int) (int, int) {
+	if int64(w)*int64(maxH) >= int64(h)*int64(maxW) {
+		h = int(int64(h) * int64(maxW) / int64(w))
+		w = maxW
+	} else {
+		w = int(int64(w) * int64(maxH) / int64(h))
+		h = maxH
+	}
+
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+
+	return w, h
+}
@@ -0,0 +1,495 @@
+package imgconv
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"testing"
+)
+
+func TestResizeNearest(t *testing.T) {
+	black := color.NRGBA{0, 0, 0, 255}
+	white := color.NRGBA{255, 255, 255, 255}
+
+	t.Run("should upscale a checkerboard exactly", func(t *testing.T) {
+		m := generateCheckerboardNRGBA(t, image.Rect(-2, 3, 4, 7), 1, black, white)
+
+		actual, err := ResizeNearest(m, 12, 8)
+		if err != nil {
+			t.Fatalf("ResizeNearest: unexpected error: %v\n", err)
+		}
+
+		expected := generateCheckerboardNRGBA(t, image.Rect(0, 0, 12, 8), 2, black, white)
+		assertConvertedImage(t, expected, actual, color.NRGBAModel, "\nResizeNearest(checkerboard, 12, 8)\n")
+	})
+
+	t.Run("should downscale to deterministic source pixels", func(t *testing.T) {
+		m := image.NewNRGBA(image.Rect(5, 5, 11, 9))
+		for y := 0; y < 4; y++ {
+			for x := 0; x < 6; x++ {
+				m.SetNRGBA(5+x, 5+y, color.NRGBA{uint8(x), uint8(y), 0, 255})
+			}
+		}
+
+		actual, err := ResizeNearest(m, 3, 2)
+		if err != nil {
+			t.Fatalf("ResizeNearest: unexpected error: %v\n", err)
+		}
+
+		// floor((x+0.5)*2) samples the columns 1, 3, 5 and the rows 1, 3.
+		for y, sy := range []uint8{1, 3} {
+			for x, sx := range []uint8{1, 3, 5} {
+				expected := color.NRGBA{sx, sy, 0, 255}
+				if c := actual.NRGBAAt(x, y); c != expected {
+					format := fmt.Sprintf("\n") +
+						fmt.Sprintf("ResizeNearest(%v, 3, 2)\n", m.Bounds()) +
+						fmt.Sprintf("Expected pixel at x=%d, y=%d:\t %v\n", x, y, expected) +
+						fmt.Sprintf("Actual pixel at x=%d, y=%d:\t %v\n", x, y, c)
+					t.Errorf(format)
+				}
+			}
+		}
+	})
+
+	t.Run("should reject invalid sizes", func(t *testing.T) {
+		m := generateRandomNRGBA(t, image.Rect(0, 0, 4, 4), 1)
+		for _, size := range []image.Point{{0, 4}, {4, 0}, {-1, 4}, {4, -1}, {maxPixels, 2}} {
+			if _, err := ResizeNearest(m, size.X, size.Y); err == nil {
+				t.Errorf("ResizeNearest(%dx%d): expected an error\n", size.X, size.Y)
+			}
+		}
+
+		if _, err := ResizeNearest(image.NewNRGBA(image.Rectangle{}), 4, 4); err == nil {
+			t.Errorf("ResizeNearest(empty image): expected an error\n")
+		}
+		if _, err := ResizeNearest(image.NewUniform(white), 4, 4); !errors.Is(err, ErrImageTooLarge) {
+			t.Errorf("ResizeNearest(uniform image): %v, expected ErrImageTooLarge\n", err)
+		}
+	})
+}
+
+func generateCheckerboardNRGBA(t testing.TB, r image.Rectangle, size int, c0, c1 color.NRGBA) *image.NRGBA {
+	t.Helper()
+
+	m := image.NewNRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := c0
+			if ((x-r.Min.X)/size+(y-r.Min.Y)/size)%2 == 1 {
+				c = c1
+			}
+			m.SetNRGBA(x, y, c)
+		}
+	}
+
+	return m
+}
+
+func BenchmarkResizeNearest(b *testing.B) {
+	m := generateRandomNRGBA(b, image.Rect(0, 0, 1000, 1000), 1)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = ResizeNearest(m, 640, 480)
+	}
+}
+
+func TestResizeBilinear(t *testing.T) {
+	black := color.NRGBA{0, 0, 0, 255}
+	white := color.NRGBA{255, 255, 255, 255}
+	red := color.NRGBA{255, 0, 0, 255}
+	transparent := color.NRGBA{0, 0, 0, 0}
+
+	tests := []struct {
+		name string
+		args struct {
+			m    image.Image
+			w, h int
+		}
+		expected []color.NRGBA
+	}{
+		{
+			name: "should interpolate between two pixels",
+			args: struct {
+				m    image.Image
+				w, h int
+			}{
+				m: generateStripesNRGBA(t, image.Rect(3, -1, 5, 0), []color.NRGBA{black, white}),
+				w: 4,
+				h: 1,
+			},
+			expected: []color.NRGBA{black, {64, 64, 64, 255}, {191, 191, 191, 255}, white},
+		},
+		{
+			name: "should average four pixels",
+			args: struct {
+				m    image.Image
+				w, h int
+			}{
+				m: generateCheckerboardNRGBA(t, image.Rect(0, 0, 2, 2), 1, black, white),
+				w: 1,
+				h: 1,
+			},
+			expected: []color.NRGBA{{128, 128, 128, 255}},
+		},
+		{
+			name: "should interpolate premultiplied colors",
+			args: struct {
+				m    image.Image
+				w, h int
+			}{
+				m: generateStripesNRGBA(t, image.Rect(0, 0, 2, 1), []color.NRGBA{red, transparent}),
+				w: 4,
+				h: 1,
+			},
+			expected: []color.NRGBA{red, {255, 0, 0, 191}, {255, 0, 0, 64}, transparent},
+		},
+		{
+			name: "should keep a uniform image",
+			args: struct {
+				m    image.Image
+				w, h int
+			}{
+				m: UniformNRGBA(color.NRGBA{10, 20, 30, 40}, image.Rect(0, 0, 3, 5)),
+				w: 2,
+				h: 2,
+			},
+			expected: []color.NRGBA{{10, 20, 30, 40}, {10, 20, 30, 40}, {10, 20, 30, 40}, {10, 20, 30, 40}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := ResizeBilinear(test.args.m, test.args.w, test.args.h)
+			if err != nil {
+				t.Fatalf("ResizeBilinear: unexpected error: %v\n", err)
+			}
+
+			if actual.Bounds() != image.Rect(0, 0, test.args.w, test.args.h) {
+				t.Fatalf("ResizeBilinear: expected bounds %v, got %v\n", image.Rect(0, 0, test.args.w, test.args.h), actual.Bounds())
+			}
+
+			for i, expected := range test.expected {
+				x, y := i%test.args.w, i/test.args.w
+				if c := actual.NRGBAAt(x, y); c != expected {
+					format := fmt.Sprintf("\n") +
+						fmt.Sprintf("ResizeBilinear(%v, %d, %d)\n", test.args.m.Bounds(), test.args.w, test.args.h) +
+						fmt.Sprintf("Expected pixel at x=%d, y=%d:\t %v\n", x, y, expected) +
+						fmt.Sprintf("Actual pixel at x=%d, y=%d:\t %v\n", x, y, c)
+					t.Errorf(format)
+				}
+			}
+		})
+	}
+}
+
+func TestResizeBilinearDice(t *testing.T) {
+	pngFile, err := os.Open("../testdata/dice.png")
+	if err != nil {
+		t.Fatalf("could not open file: %v\n", err)
+	}
+	defer pngFile.Close()
+
+	m, err := png.Decode(pngFile)
+	if err != nil {
+		t.Fatalf("could not decode png: %v\n", err)
+	}
+
+	w, h := m.Bounds().Dx()/2, m.Bounds().Dy()/2
+	actual, err := ResizeBilinear(m, w, h)
+	if err != nil {
+		t.Fatalf("ResizeBilinear: unexpected error: %v\n", err)
+	}
+
+	if actual.Bounds() != image.Rect(0, 0, w, h) {
+		t.Fatalf("ResizeBilinear: expected bounds %v, got %v\n", image.Rect(0, 0, w, h), actual.Bounds())
+	}
+
+	// halving the size averages 2x2 blocks, so the mean color must stay about the same.
+	expected, got := meanNRGBA(ToNRGBA(m)), meanNRGBA(actual)
+	for i := range expected {
+		if d := expected[i] - got[i]; d < -2 || d > 2 {
+			t.Fatalf("ResizeBilinear: mean channel %d changed from %.2f to %.2f\n", i, expected[i], got[i])
+		}
+	}
+}
+
+func TestResizeBilinearInvalidSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		m        image.Image
+		expected error
+	}{
+		{name: "nil", m: nil, expected: ErrNilImage},
+		{name: "empty", m: image.NewNRGBA(image.Rectangle{}), expected: ErrEmptyImage},
+		{name: "uniform", m: image.NewUniform(color.White), expected: ErrImageTooLarge},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ResizeBilinear(test.m, 4, 4); !errors.Is(err, test.expected) {
+				t.Errorf("ResizeBilinear(%s image): %v, expected %v\n", test.name, err, test.expected)
+			}
+		})
+	}
+}
+
+func meanNRGBA(m *image.NRGBA) [4]float64 {
+	var sum [4]float64
+	b := m.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		i := m.PixOffset(b.Min.X, y)
+		for x := b.Min.X; x < b.Max.X; x, i = x+1, i+4 {
+			a := float64(m.Pix[i+3])
+			sum[0] += float64(m.Pix[i+0]) * a / 255
+			sum[1] += float64(m.Pix[i+1]) * a / 255
+			sum[2] += float64(m.Pix[i+2]) * a / 255
+			sum[3] += a
+		}
+	}
+
+	n := float64(b.Dx() * b.Dy())
+	for i := range sum {
+		sum[i] /= n
+	}
+
+	return sum
+}
+
+func BenchmarkResizeBilinear(b *testing.B) {
+	m := generateRandomNRGBA(b, image.Rect(0, 0, 1000, 1000), 1)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = ResizeBilinear(m, 640, 480)
+	}
+}
+
+func TestResizeBox(t *testing.T) {
+	black := color.NRGBA{0, 0, 0, 255}
+	white := color.NRGBA{255, 255, 255, 255}
+	transparent := color.NRGBA{0, 0, 0, 0}
+
+	t.Run("should average a checkerboard to mid-gray", func(t *testing.T) {
+		for _, factor := range []int{2, 4, 8, 16} {
+			m := generateCheckerboardNRGBA(t, image.Rect(-7, 3, 9, 19), 1, black, white)
+
+			actual, err := ResizeBox(m, 16/factor, 16/factor)
+			if err != nil {
+				t.Fatalf("ResizeBox: unexpected error: %v\n", err)
+			}
+
+			// every block holds as many black as white pixels: (255/2) rounded up.
+			expected := UniformNRGBA(color.NRGBA{128, 128, 128, 255}, image.Rect(0, 0, 16/factor, 16/factor))
+			assertConvertedImage(t, expected, actual, color.NRGBAModel, fmt.Sprintf("\nResizeBox(checkerboard, 1/%d)\n", factor))
+		}
+	})
+
+	t.Run("should weight fractional coverage", func(t *testing.T) {
+		// three columns into two: the middle column is split between both pixels.
+		m := generateStripesNRGBA(t, image.Rect(0, 0, 3, 1), []color.NRGBA{black, white, black})
+
+		actual, err := ResizeBox(m, 2, 1)
+		if err != nil {
+			t.Fatalf("ResizeBox: unexpected error: %v\n", err)
+		}
+
+		// (0*2 + 255*1) / 3 = 85
+		expected := color.NRGBA{85, 85, 85, 255}
+		for x := 0; x < 2; x++ {
+			if c := actual.NRGBAAt(x, 0); c != expected {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("ResizeBox(black-white-black, 2, 1)\n") +
+					fmt.Sprintf("Expected pixel at x=%d:\t %v\n", x, expected) +
+					fmt.Sprintf("Actual pixel at x=%d:\t %v\n", x, c)
+				t.Errorf(format)
+			}
+		}
+	})
+
+	t.Run("should not bleed transparent colors", func(t *testing.T) {
+		red := color.NRGBA{255, 0, 0, 255}
+		m := generateStripesNRGBA(t, image.Rect(0, 0, 2, 1), []color.NRGBA{red, transparent})
+
+		actual, err := ResizeBox(m, 1, 1)
+		if err != nil {
+			t.Fatalf("ResizeBox: unexpected error: %v\n", err)
+		}
+
+		if expected, c := (color.NRGBA{255, 0, 0, 128}), actual.NRGBAAt(0, 0); c != expected {
+			t.Errorf("ResizeBox(red-transparent, 1, 1) = %v, expected %v\n", c, expected)
+		}
+	})
+
+	t.Run("should keep the image when the size does not change", func(t *testing.T) {
+		m := generateRandomNRGBA(t, image.Rect(3, 4, 20, 13), 1)
+
+		actual, err := ResizeBox(m, 17, 9)
+		if err != nil {
+			t.Fatalf("ResizeBox: unexpected error: %v\n", err)
+		}
+
+		for y := 0; y < 9; y++ {
+			for x := 0; x < 17; x++ {
+				expected := m.NRGBAAt(3+x, 4+y)
+				if expected.A == 0 {
+					expected = color.NRGBA{}
+				}
+				if c := actual.NRGBAAt(x, y); c != expected {
+					t.Fatalf("ResizeBox(same size) at x=%d, y=%d = %v, expected %v\n", x, y, c, expected)
+				}
+			}
+		}
+	})
+
+	t.Run("should reject invalid sizes", func(t *testing.T) {
+		m := generateRandomNRGBA(t, image.Rect(0, 0, 4, 4), 1)
+		for _, size := range []image.Point{{0, 4}, {4, 0}, {-1, 4}, {maxPixels, 2}} {
+			if _, err := ResizeBox(m, size.X, size.Y); err == nil {
+				t.Errorf("ResizeBox(%dx%d): expected an error\n", size.X, size.Y)
+			}
+		}
+
+		if _, err := ResizeBox(image.NewNRGBA(image.Rectangle{}), 4, 4); err == nil {
+			t.Errorf("ResizeBox(empty image): expected an error\n")
+		}
+		if _, err := ResizeBox(image.NewUniform(white), 4, 4); !errors.Is(err, ErrImageTooLarge) {
+			t.Errorf("ResizeBox(uniform image): %v, expected ErrImageTooLarge\n", err)
+		}
+	})
+}
+
+func BenchmarkResizeBox(b *testing.B) {
+	m := generateRandomNRGBA(b, image.Rect(0, 0, 1000, 1000), 1)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = ResizeBox(m, 125, 125)
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		name string
+		args struct {
+			r          image.Rectangle
+			maxW, maxH int
+		}
+		expected image.Rectangle
+	}{
+		{
+			name: "should fit a landscape image",
+			args: struct {
+				r          image.Rectangle
+				maxW, maxH int
+			}{r: image.Rect(0, 0, 1000, 500), maxW: 256, maxH: 256},
+			expected: image.Rect(0, 0, 256, 128),
+		},
+		{
+			name: "should fit a portrait image",
+			args: struct {
+				r          image.Rectangle
+				maxW, maxH int
+			}{r: image.Rect(-5, 7, 295, 407), maxW: 256, maxH: 256},
+			expected: image.Rect(0, 0, 192, 256),
+		},
+		{
+			name: "should round down so the box is not exceeded",
+			args: struct {
+				r          image.Rectangle
+				maxW, maxH int
+			}{r: image.Rect(0, 0, 300, 200), maxW: 100, maxH: 100},
+			expected: image.Rect(0, 0, 100, 66),
+		},
+		{
+			name: "should keep at least one pixel",
+			args: struct {
+				r          image.Rectangle
+				maxW, maxH int
+			}{r: image.Rect(0, 0, 1000, 1), maxW: 10, maxH: 10},
+			expected: image.Rect(0, 0, 10, 1),
+		},
+		{
+			name: "should scale a 1xN image",
+			args: struct {
+				r          image.Rectangle
+				maxW, maxH int
+			}{r: image.Rect(0, 0, 1, 1000), maxW: 10, maxH: 10},
+			expected: image.Rect(0, 0, 1, 10),
+		},
+		{
+			name: "should not upscale",
+			args: struct {
+				r          image.Rectangle
+				maxW, maxH int
+			}{r: image.Rect(3, 4, 13, 9), maxW: 256, maxH: 256},
+			expected: image.Rect(3, 4, 13, 9),
+		},
+		{
+			name: "should keep an image with the exact size",
+			args: struct {
+				r          image.Rectangle
+				maxW, maxH int
+			}{r: image.Rect(0, 0, 256, 100), maxW: 256, maxH: 256},
+			expected: image.Rect(0, 0, 256, 100),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := generateRandomNRGBA(t, test.args.r, 1)
+
+			actual, err := Thumbnail(m, test.args.maxW, test.args.maxH)
+			if err != nil {
+				t.Fatalf("Thumbnail: unexpected error: %v\n", err)
+			}
+
+			if actual.Bounds() != test.expected {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Thumbnail(%v, %d, %d)\n", test.args.r, test.args.maxW, test.args.maxH) +
+					fmt.Sprintf("Expected bounds:\t %v\n", test.expected) +
+					fmt.Sprintf("Actual bounds:\t %v\n", actual.Bounds())
+				t.Fatalf(format)
+			}
+
+			if test.expected == test.args.r && actual != m {
+				t.Errorf("Thumbnail(%v, %d, %d): expected the original image\n", test.args.r, test.args.maxW, test.args.maxH)
+			}
+		})
+	}
+}
+
+func TestThumbnailInvalidSize(t *testing.T) {
+	m := generateRandomNRGBA(t, image.Rect(0, 0, 4, 4), 1)
+	for _, size := range []image.Point{{0, 10}, {10, 0}, {0, 0}, {-1, 10}} {
+		if _, err := Thumbnail(m, size.X, size.Y); err == nil {
+			t.Errorf("Thumbnail(%dx%d): expected an error\n", size.X, size.Y)
+		}
+	}
+}
+
+func TestThumbnailInvalidSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		m        image.Image
+		expected error
+	}{
+		{name: "nil", m: nil, expected: ErrNilImage},
+		{name: "empty", m: image.NewNRGBA(image.Rectangle{}), expected: ErrEmptyImage},
+		{name: "uniform", m: image.NewUniform(color.White), expected: ErrImageTooLarge},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Thumbnail(test.m, 10, 10); !errors.Is(err, test.expected) {
+				t.Errorf("Thumbnail(%s image): %v, expected %v\n", test.name, err, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package imgconv
+
+import (
+	"image"
+	"math"
+	"sync"
+)
+
+var (
+	toLinearOnce sync.Once
+	toLinearLUT  *[256]uint16
+	toSRGBOnce   sync.Once
+	toSRGBLUT    *[65536]uint8
+)
+
+// srgbToLinearTable returns a lookup table mapping 8-bit sRGB values to
+// 16-bit linear values with the piecewise sRGB transfer function.
+func srgbToLinearTable() *[256]uint16 {
+	toLinearOnce.Do(func() {
+		toLinearLUT = new([256]uint16)
+		for v := range toLinearLUT {
+			c := float64(v) / 0xff
+			if c <= 0.04045 {
+				c /= 12.92
+			} else {
+				c = math.Pow((c+0.055)/1.055, 2.4)
+			}
+			toLinearLUT[v] = uint16(math.Round(c * 0xffff))
+		}
+	})
+
+	return toLinearLUT
+}
+
+// linearToSRGBTable returns a lookup table mapping 16-bit linear values to
+// 8-bit sRGB values with the inverse of the piecewise sRGB transfer function.
+func linearToSRGBTable() *[65536]uint8 {
+	toSRGBOnce.Do(func() {
+		toSRGBLUT = new([65536]uint8)
+		for v := range toSRGBLUT {
+			c := float64(v) / 0xffff
+			if c <= 0.0031308 {
+				c *= 12.92
+			} else {
+				c = 1.055*math.Pow(c, 1/2.4) - 0.055
+			}
+			toSRGBLUT[v] = uint8(math.Round(c * 0xff))
+		}
+	})
+
+	return toSRGBLUT
+}
+
+// SRGBToLinear converts any image m with sRGB encoded colors to an
+// *image.NRGBA64 image with linear colors. The alpha values are not
+// transformed, only widened to 16 bits.
+// Converting the result back with LinearToSRGB is lossless for 8-bit images.
+func SRGBToLinear(m image.Image) *image.NRGBA64 {
+	lut := srgbToLinearTable()
+	src := ToNRGBA(m)
+	b := src.Bounds()
+	img := image.NewNRGBA64(b)
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		si := src.PixOffset(b.Min.X, y)
+		di := img.PixOffset(b.Min.X, y)
+		for x := b.Min.X; x < b.Max.X; x, si, di = x+1, si+4, di+8 {
+			s := src.Pix[si : si+4 : si+4]
+			d := img.Pix[di : di+8 : di+8]
+			r, g, b := lut[s[0]], lut[s[1]], lut[s[2]]
+			d[0], d[1] = uint8(r>>8), uint8(r)
+			d[2], d[3] = uint8(g>>8), uint8(g)
+			d[4], d[5] = uint8(b>>8), uint8(b)
+			d[6], d[7] = s[3], s[3]
+		}
+	}
+
+	return img
+}
+
+// LinearToSRGB converts the image m with linear colors to an *image.NRGBA image
+// with sRGB encoded colors. The alpha values are not transformed, only rounded to 8 bits.
+func LinearToSRGB(m *image.NRGBA64) *image.NRGBA {
+	lut := linearToSRGBTable()
+	b := m.Bounds()
+	img := image.NewNRGBA(b)
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		si := m.PixOffset(b.Min.X, y)
+		di := img.PixOffset(b.Min.X, y)
+		for x := b.Min.X; x < b.Max.X; x, si, di = x+1, si+8, di+4 {
+			s := m.Pix[si : si+8 : si+8]
+			d := img.Pix[di : di+4 : di+4]
+			d[0] = lut[uint16(s[0])<<8|uint16(s[1])]
+			d[1] = lut[uint16(s[2])<<8|uint16(s[3])]
+			d[2] = lut[uint16(s[4])<<8|uint16(s[5])]
+			d[3] = round16To8(uint32(s[6])<<8 | uint32(s[7]))
+		}
+	}
+
+	return img
+}
@@ -0,0 +1,77 @@
+package imgconv
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSRGBToLinear(t *testing.T) {
+	tests := []struct {
+		name     string
+		c        color.NRGBA
+		expected color.NRGBA64
+	}{
+		{name: "should keep black and white", c: color.NRGBA{0, 255, 0, 255}, expected: color.NRGBA64{0, 0xffff, 0, 0xffff}},
+		{name: "should use the linear segment", c: color.NRGBA{1, 10, 0, 0}, expected: color.NRGBA64{20, 199, 0, 0}},
+		{name: "should use the power segment", c: color.NRGBA{128, 188, 11, 128}, expected: color.NRGBA64{14146, 32957, 219, 0x8080}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := SRGBToLinear(UniformNRGBA(test.c, image.Rect(0, 0, 1, 1))).NRGBA64At(0, 0)
+			if actual != test.expected {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("SRGBToLinear(%v)\n", test.c) +
+					fmt.Sprintf("Expected value:\t %v\n", test.expected) +
+					fmt.Sprintf("Actual value:\t %v\n", actual)
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+func TestSRGBRoundTrip(t *testing.T) {
+	//every 8-bit value in every channel, with varying alpha
+	m := image.NewNRGBA(image.Rect(-3, 4, 253, 7))
+	for y := m.Rect.Min.Y; y < m.Rect.Max.Y; y++ {
+		for x := m.Rect.Min.X; x < m.Rect.Max.X; x++ {
+			v := uint8(x - m.Rect.Min.X)
+			m.SetNRGBA(x, y, color.NRGBA{v, 255 - v, v / 2, uint8(y) * v})
+		}
+	}
+
+	linear := SRGBToLinear(m)
+	if linear.Bounds() != m.Bounds() {
+		t.Fatalf("SRGBToLinear: expected bounds %v, got %v\n", m.Bounds(), linear.Bounds())
+	}
+
+	for x := m.Rect.Min.X + 1; x < m.Rect.Max.X; x++ {
+		if linear.NRGBA64At(x, 4).R <= linear.NRGBA64At(x-1, 4).R {
+			t.Fatalf("SRGBToLinear: linear values are not strictly increasing at x=%d\n", x)
+		}
+	}
+
+	actual := LinearToSRGB(linear)
+	for y := m.Rect.Min.Y; y < m.Rect.Max.Y; y++ {
+		for x := m.Rect.Min.X; x < m.Rect.Max.X; x++ {
+			if actual.NRGBAAt(x, y) != m.NRGBAAt(x, y) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("LinearToSRGB(SRGBToLinear(%v))\n", m.NRGBAAt(x, y)) +
+					fmt.Sprintf("Actual value:\t %v\n", actual.NRGBAAt(x, y))
+				t.Fatalf(format)
+			}
+		}
+	}
+}
+
+func BenchmarkSRGBToLinear(b *testing.B) {
+	m := generateRandomNRGBA(b, image.Rect(0, 0, 1000, 1000), 1)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		SRGBToLinear(m)
+	}
+}
@@ -0,0 +1,76 @@
+package imgconv
+
+import (
+	"image"
+)
+
+// ToBlackWhite converts any image m to an *image.Gray image containing only
+// black and white. Pixels with a luminance below threshold become 0 and all
+// others 255, so a threshold of 0 results in a white image.
+// The luminance is computed like ToGray.
+func ToBlackWhite(m image.Image, threshold uint8) *image.Gray {
+	return binarize(ToGray(m), threshold)
+}
+
+// ToBlackWhiteOtsu is like ToBlackWhite, but computes the threshold with
+// OtsuThreshold from the luminance histogram of m.
+func ToBlackWhiteOtsu(m image.Image) *image.Gray {
+	gray := ToGray(m)
+	return binarize(gray, otsu(&Histogram(gray).Y))
+}
+
+// OtsuThreshold returns the threshold for ToBlackWhite which separates the
+// luminance values of m into two classes with the largest between-class
+// variance (Otsu's method). If several thresholds are equally good, the lowest
+// one is returned. Images with a single luminance value return 128.
+func OtsuThreshold(m image.Image) uint8 {
+	return otsu(&Histogram(m).Y)
+}
+
+// binarize returns a new image with the pixels of src below threshold set to 0 and the others to 255.
+func binarize(src *image.Gray, threshold uint8) *image.Gray {
+	b := src.Bounds()
+	img := image.NewGray(b)
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		si := src.PixOffset(b.Min.X, y)
+		di := img.PixOffset(b.Min.X, y)
+		for x := b.Min.X; x < b.Max.X; x, si, di = x+1, si+1, di+1 {
+			if src.Pix[si] >= threshold {
+				img.Pix[di] = 0xff
+			}
+		}
+	}
+
+	return img
+}
+
+// otsu returns the first value t maximizing the between-class variance of
+// the classes [0, t) and [t, 255] of hist.
+func otsu(hist *[256]int) uint8 {
+	var total, sum float64
+	for v, n := range hist {
+		total += float64(n)
+		sum += float64(v) * float64(n)
+	}
+
+	threshold, best := 128, 0.0
+	var w0, sum0 float64
+	for t := 1; t < 256; t++ {
+		w0 += float64(hist[t-1])
+		sum0 += float64(t-1) * float64(hist[t-1])
+
+		w1 := total - w0
+		if w0 == 0 || w1 == 0 {
+			continue
+		}
+
+		//between-class variance up to the constant factor 1/total²
+		d := sum0/w0 - (sum-sum0)/w1
+		if v := w0 * w1 * d * d; v > best {
+			threshold, best = t, v
+		}
+	}
+
+	return uint8(threshold)
+}
@@ -0,0 +1,106 @@
+package imgconv
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+func TestToBlackWhite(t *testing.T) {
+	//every gray value once
+	m := image.NewGray(image.Rect(-5, 2, 251, 3))
+	for i := range m.Pix {
+		m.Pix[i] = uint8(i)
+	}
+
+	for _, threshold := range []uint8{0, 1, 128, 254, 255} {
+		t.Run(fmt.Sprintf("threshold %d", threshold), func(t *testing.T) {
+			actual := ToBlackWhite(m, threshold)
+			if actual.Bounds() != m.Bounds() {
+				t.Fatalf("ToBlackWhite: expected bounds %v, got %v\n", m.Bounds(), actual.Bounds())
+			}
+
+			for x := m.Rect.Min.X; x < m.Rect.Max.X; x++ {
+				v := m.GrayAt(x, 2).Y
+				expected := uint8(0)
+				if v >= threshold {
+					expected = 255
+				}
+				if c := actual.GrayAt(x, 2).Y; c != expected {
+					t.Fatalf("ToBlackWhite(%d, %d) = %d, expected %d\n", v, threshold, c, expected)
+				}
+			}
+		})
+	}
+
+	if actual := ToBlackWhite(m, 0); actual == m {
+		t.Errorf("ToBlackWhite(*image.Gray) returned the source image\n")
+	}
+}
+
+func TestToBlackWhiteOtsu(t *testing.T) {
+	//dark text around 40 on a light background around 200
+	m := image.NewNRGBA(image.Rect(3, 3, 67, 35))
+	rng := rand.New(rand.NewSource(1))
+	for y := m.Rect.Min.Y; y < m.Rect.Max.Y; y++ {
+		for x := m.Rect.Min.X; x < m.Rect.Max.X; x++ {
+			v := 190 + rng.Intn(21)
+			if (x/4+y/4)%3 == 0 {
+				v = 30 + rng.Intn(21)
+			}
+			m.SetNRGBA(x, y, color.NRGBA{uint8(v), uint8(v), uint8(v), 255})
+		}
+	}
+
+	threshold := OtsuThreshold(m)
+	if threshold <= 50 || threshold > 190 {
+		t.Fatalf("OtsuThreshold = %d, expected a threshold between 50 and 190\n", threshold)
+	}
+
+	actual := ToBlackWhiteOtsu(m)
+	for y := m.Rect.Min.Y; y < m.Rect.Max.Y; y++ {
+		for x := m.Rect.Min.X; x < m.Rect.Max.X; x++ {
+			expected := uint8(255)
+			if (x/4+y/4)%3 == 0 {
+				expected = 0
+			}
+			if c := actual.GrayAt(x, y).Y; c != expected {
+				t.Fatalf("ToBlackWhiteOtsu: pixel at x=%d, y=%d is %d, expected %d\n", x, y, c, expected)
+			}
+		}
+	}
+}
+
+func TestOtsuThreshold(t *testing.T) {
+	tests := []struct {
+		name     string
+		m        image.Image
+		expected uint8
+	}{
+		{
+			name:     "should separate two values",
+			m:        generateStripesNRGBA(t, image.Rect(0, 0, 10, 10), []color.NRGBA{{10, 10, 10, 255}, {250, 250, 250, 255}}),
+			expected: 11,
+		},
+		{
+			name:     "should return 128 for uniform images",
+			m:        UniformNRGBA(color.NRGBA{77, 77, 77, 255}, image.Rect(0, 0, 10, 10)),
+			expected: 128,
+		},
+		{
+			name:     "should return 128 for empty images",
+			m:        image.NewGray(image.Rectangle{}),
+			expected: 128,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := OtsuThreshold(test.m); actual != test.expected {
+				t.Errorf("OtsuThreshold = %d, expected %d\n", actual, test.expected)
+			}
+		})
+	}
+}
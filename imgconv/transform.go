@@ -0,0 +1,127 @@
+package imgconv
+
+import (
+	"image"
+)
+
+// FlipH returns a copy of any image m as an *image.NRGBA image mirrored
+// horizontally, so the left column becomes the right column.
+// The returned image has the same bounds as m.
+func FlipH(m image.Image) *image.NRGBA {
+	img := CloneNRGBA(m)
+	FlipHInPlace(img)
+
+	return img
+}
+
+// FlipV returns a copy of any image m as an *image.NRGBA image mirrored
+// vertically, so the top row becomes the bottom row.
+// The returned image has the same bounds as m.
+func FlipV(m image.Image) *image.NRGBA {
+	img := CloneNRGBA(m)
+	FlipVInPlace(img)
+
+	return img
+}
+
+// FlipHInPlace mirrors m horizontally by swapping its pixels within m.Pix.
+func FlipHInPlace(m *image.NRGBA) {
+	b := m.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		i := m.PixOffset(b.Min.X, y)
+		j := m.PixOffset(b.Max.X-1, y)
+		for ; i < j; i, j = i+4, j-4 {
+			p := m.Pix[i : i+4 : i+4]
+			q := m.Pix[j : j+4 : j+4]
+			p[0], q[0] = q[0], p[0]
+			p[1], q[1] = q[1], p[1]
+			p[2], q[2] = q[2], p[2]
+			p[3], q[3] = q[3], p[3]
+		}
+	}
+}
+
+// FlipVInPlace mirrors m vertically by swapping its rows within m.Pix.
+func FlipVInPlace(m *image.NRGBA) {
+	b := m.Bounds()
+	rowSize := 4 * b.Dx()
+	tmp := make([]byte, rowSize)
+
+	for top, bottom := b.Min.Y, b.Max.Y-1; top < bottom; top, bottom = top+1, bottom-1 {
+		t := m.Pix[m.PixOffset(b.Min.X, top):][:rowSize]
+		u := m.Pix[m.PixOffset(b.Min.X, bottom):][:rowSize]
+		copy(tmp, t)
+		copy(t, u)
+		copy(u, tmp)
+	}
+}
+
+// rotateBlock is the edge length of the blocks copied by the quarter turn
+// rotations, so both the source and the destination rows stay in the cache.
+const rotateBlock = 32
+
+// Rotate90 returns a copy of any image m as an *image.NRGBA image rotated by
+// 90 degrees counter-clockwise. The returned image has its origin at (0, 0)
+// and the width and height of m swapped.
+func Rotate90(m image.Image) *image.NRGBA {
+	return rotateQuarter(ToNRGBA(m), false)
+}
+
+// Rotate180 returns a copy of any image m as an *image.NRGBA image rotated by
+// 180 degrees. The returned image has its origin at (0, 0).
+func Rotate180(m image.Image) *image.NRGBA {
+	src := ToNRGBA(m)
+	b := src.Bounds()
+	img := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		si := src.PixOffset(b.Min.X, y)
+		di := img.PixOffset(b.Dx()-1, b.Max.Y-1-y)
+		for x := b.Min.X; x < b.Max.X; x, si, di = x+1, si+4, di-4 {
+			copy(img.Pix[di:di+4:di+4], src.Pix[si:si+4:si+4])
+		}
+	}
+
+	return img
+}
+
+// Rotate270 returns a copy of any image m as an *image.NRGBA image rotated by
+// 270 degrees counter-clockwise, which is 90 degrees clockwise. The returned
+// image has its origin at (0, 0) and the width and height of m swapped.
+func Rotate270(m image.Image) *image.NRGBA {
+	return rotateQuarter(ToNRGBA(m), true)
+}
+
+// rotateQuarter rotates src by 90 degrees clockwise if cw is true,
+// otherwise counter-clockwise, copying blocks of rotateBlock x rotateBlock pixels.
+func rotateQuarter(src *image.NRGBA, cw bool) *image.NRGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	img := image.NewNRGBA(image.Rect(0, 0, h, w))
+
+	for by := 0; by < h; by += rotateBlock {
+		for bx := 0; bx < w; bx += rotateBlock {
+			maxY, maxX := by+rotateBlock, bx+rotateBlock
+			if maxY > h {
+				maxY = h
+			}
+			if maxX > w {
+				maxX = w
+			}
+
+			for y := by; y < maxY; y++ {
+				si := src.PixOffset(b.Min.X+bx, b.Min.Y+y)
+				for x := bx; x < maxX; x, si = x+1, si+4 {
+					dx, dy := y, w-1-x
+					if cw {
+						dx, dy = h-1-y, x
+					}
+					di := img.PixOffset(dx, dy)
+					copy(img.Pix[di:di+4:di+4], src.Pix[si:si+4:si+4])
+				}
+			}
+		}
+	}
+
+	return img
+}
@@ -0,0 +1,177 @@
+package imgconv
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFlip(t *testing.T) {
+	tests := []struct {
+		name string
+		args struct {
+			m image.Image
+		}
+	}{
+		{
+			name: "should flip an image with odd dimensions",
+			args: struct{ m image.Image }{
+				m: generateRandomNRGBA(t, image.Rect(-3, 2, 4, 7), 1),
+			},
+		},
+		{
+			name: "should flip an image with even dimensions",
+			args: struct{ m image.Image }{
+				m: generateRandomNRGBA(t, image.Rect(0, 0, 8, 6), 2),
+			},
+		},
+		{
+			name: "should flip a sub image",
+			args: struct{ m image.Image }{
+				m: generateRandomNRGBA(t, image.Rect(0, 0, 16, 16), 3).SubImage(image.Rect(3, 5, 12, 10)),
+			},
+		},
+		{
+			name: "should flip an rgba image",
+			args: struct{ m image.Image }{
+				m: generateRandomRGBA(t, image.Rect(1, 1, 6, 4), 4),
+			},
+		},
+		{
+			name: "should flip a single pixel",
+			args: struct{ m image.Image }{
+				m: generateRandomNRGBA(t, image.Rect(0, 0, 1, 1), 5),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := test.args.m.Bounds()
+			src := CloneNRGBA(test.args.m)
+
+			h := FlipH(test.args.m)
+			v := FlipV(test.args.m)
+			if h.Bounds() != b || v.Bounds() != b {
+				t.Fatalf("Flip(%v): expected bounds %v, got %v and %v\n", b, b, h.Bounds(), v.Bounds())
+			}
+
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				for x := b.Min.X; x < b.Max.X; x++ {
+					mx, my := b.Max.X-1-(x-b.Min.X), b.Max.Y-1-(y-b.Min.Y)
+					if h.NRGBAAt(x, y) != src.NRGBAAt(mx, y) {
+						t.Fatalf("FlipH(%v): pixel at x=%d, y=%d is not mirrored from x=%d\n", b, x, y, mx)
+					}
+					if v.NRGBAAt(x, y) != src.NRGBAAt(x, my) {
+						t.Fatalf("FlipV(%v): pixel at x=%d, y=%d is not mirrored from y=%d\n", b, x, y, my)
+					}
+				}
+			}
+
+			FlipHInPlace(h)
+			FlipVInPlace(v)
+			assertConvertedImage(t, src, h, color.NRGBAModel, fmt.Sprintf("\nFlipH(FlipH(%v))\n", b))
+			assertConvertedImage(t, src, v, color.NRGBAModel, fmt.Sprintf("\nFlipV(FlipV(%v))\n", b))
+		})
+	}
+}
+
+func TestFlipInPlaceKeepsPadding(t *testing.T) {
+	parent := generateRandomNRGBA(t, image.Rect(0, 0, 9, 9), 1)
+	expected := CloneNRGBA(parent)
+	m := parent.SubImage(image.Rect(2, 2, 7, 6)).(*image.NRGBA)
+
+	FlipHInPlace(m)
+	FlipVInPlace(m)
+
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 9; x++ {
+			if (image.Point{x, y}).In(m.Bounds()) {
+				if parent.NRGBAAt(x, y) != expected.NRGBAAt(8-x, 7-y) {
+					t.Fatalf("Flip in place: pixel at x=%d, y=%d was not rotated\n", x, y)
+				}
+				continue
+			}
+			if parent.NRGBAAt(x, y) != expected.NRGBAAt(x, y) {
+				t.Fatalf("Flip in place: pixel at x=%d, y=%d outside of the sub image was changed\n", x, y)
+			}
+		}
+	}
+}
+
+func BenchmarkFlipVInPlace(b *testing.B) {
+	m := generateRandomNRGBA(b, image.Rect(0, 0, 1000, 1000), 1)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		FlipVInPlace(m)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	// a b c
+	// d e f
+	m := image.NewNRGBA(image.Rect(4, -2, 7, 0))
+	for i, c := range []color.NRGBA{{'a', 0, 0, 255}, {'b', 0, 0, 255}, {'c', 0, 0, 255}, {'d', 0, 0, 255}, {'e', 0, 0, 255}, {'f', 0, 0, 255}} {
+		m.SetNRGBA(4+i%3, -2+i/3, c)
+	}
+
+	tests := []struct {
+		name     string
+		rotate   func(image.Image) *image.NRGBA
+		expected []string
+	}{
+		{name: "Rotate90", rotate: Rotate90, expected: []string{"cf", "be", "ad"}},
+		{name: "Rotate180", rotate: Rotate180, expected: []string{"fed", "cba"}},
+		{name: "Rotate270", rotate: Rotate270, expected: []string{"da", "eb", "fc"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := test.rotate(m)
+
+			expectedBounds := image.Rect(0, 0, len(test.expected[0]), len(test.expected))
+			if actual.Bounds() != expectedBounds {
+				t.Fatalf("%s: expected bounds %v, got %v\n", test.name, expectedBounds, actual.Bounds())
+			}
+
+			for y, row := range test.expected {
+				for x := range row {
+					if c := actual.NRGBAAt(x, y); c.R != row[x] {
+						format := fmt.Sprintf("\n") +
+							fmt.Sprintf("%s(%v)\n", test.name, m.Bounds()) +
+							fmt.Sprintf("Expected pixel at x=%d, y=%d:\t %c\n", x, y, row[x]) +
+							fmt.Sprintf("Actual pixel at x=%d, y=%d:\t %c\n", x, y, c.R)
+						t.Errorf(format)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRotateFourTimes(t *testing.T) {
+	for _, r := range []image.Rectangle{image.Rect(-3, 5, 70, 40), image.Rect(0, 0, 1, 33), image.Rect(0, 0, 64, 64)} {
+		m := generateRandomNRGBA(t, r, 1)
+		expected := generateRandomNRGBA(t, image.Rect(0, 0, r.Dx(), r.Dy()), 1)
+		format := fmt.Sprintf("\nRotate four times (%v)\n", r)
+
+		assertConvertedImage(t, expected, Rotate90(Rotate90(Rotate90(Rotate90(m)))), color.NRGBAModel, format)
+		assertConvertedImage(t, expected, Rotate270(Rotate270(Rotate270(Rotate270(m)))), color.NRGBAModel, format)
+		assertConvertedImage(t, expected, Rotate180(Rotate180(m)), color.NRGBAModel, format)
+		assertConvertedImage(t, expected, Rotate270(Rotate90(m)), color.NRGBAModel, format)
+		assertConvertedImage(t, Rotate180(m), Rotate90(Rotate90(m)), color.NRGBAModel, format)
+	}
+}
+
+func BenchmarkRotate90(b *testing.B) {
+	m := generateRandomNRGBA(b, image.Rect(0, 0, 1000, 1000), 1)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Rotate90(m)
+	}
+}
@@ -0,0 +1,220 @@
+package imgconv
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/LukiDS/image/internal/atomicfile"
+)
+
+// TreeOptions are the parameters of ConvertTree.
+type TreeOptions struct {
+	// Extensions are the extensions of the files to convert, including the
+	// dot, e.g. ".png" or ".raw.png". They match case-insensitively and the
+	// first matching one is replaced by OutputExt. Empty means ".png".
+	Extensions []string
+
+	// Encode writes a converted image. It must not be nil.
+	Encode func(w io.Writer, m image.Image) error
+
+	// OutputExt replaces the extension of the converted files, e.g. ".qoi".
+	// It must not be empty.
+	OutputExt string
+
+	// Workers is the number of files converted at the same time. Zero or
+	// less means runtime.GOMAXPROCS(0).
+	Workers int
+
+	// Force converts files even if their output is up to date.
+	Force bool
+}
+
+// TreeReport lists the files handled by ConvertTree by their slash-separated
+// path in the source tree, each list sorted by path.
+type TreeReport struct {
+	// Converted are the files which were written.
+	Converted []string
+	// Skipped are the files whose output was up to date.
+	Skipped []string
+	// Failed are the files which could not be converted.
+	Failed []FileError
+}
+
+// FileError is the failure to convert a file.
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (e FileError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e FileError) Unwrap() error {
+	return e.Err
+}
+
+// ConvertTree converts the files of src with one of opts.Extensions into
+// dstDir, keeping the directory structure. The files are decoded with
+// image.Decode, so their formats must be registered, and written with
+// opts.Encode. A file is skipped if its output is at least as new as the
+// file itself, unless opts.Force is set. Outputs are written to a temporary
+// file which is renamed when complete, so failures never leave partial files.
+//
+// Files which cannot be converted are listed in the report and do not stop
+// the conversion of the other files. The returned error is only set for
+// invalid options, an unreadable tree, or ctx.Err() if ctx is done before
+// all files are converted; the report then lists the files handled so far.
+func ConvertTree(ctx context.Context, src fs.FS, dstDir string, opts TreeOptions) (TreeReport, error) {
+	if opts.Encode == nil || opts.OutputExt == "" {
+		return TreeReport{}, fmt.Errorf("convert tree: missing encoder or output extension")
+	}
+
+	exts := opts.Extensions
+	if len(exts) == 0 {
+		exts = []string{".png"}
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	t := tree{src: src, dstDir: dstDir, opts: opts}
+	jobs := make(chan treeJob)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				t.convert(job)
+			}
+		}()
+	}
+
+	err := fs.WalkDir(src, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ext := matchExtension(p, exts)
+		if d.IsDir() || ext == "" {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			t.fail(p, err)
+			return nil
+		}
+
+		select {
+		case jobs <- treeJob{path: p, ext: ext, info: info}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	close(jobs)
+	wg.Wait()
+
+	sort.Strings(t.report.Converted)
+	sort.Strings(t.report.Skipped)
+	sort.Slice(t.report.Failed, func(i, j int) bool { return t.report.Failed[i].Path < t.report.Failed[j].Path })
+
+	if err != nil {
+		return t.report, fmt.Errorf("convert tree: %w", err)
+	}
+
+	return t.report, nil
+}
+
+// matchExtension returns the first of exts p ends with, or "" if none.
+func matchExtension(p string, exts []string) string {
+	for _, e := range exts {
+		if len(p) > len(e) && strings.EqualFold(p[len(p)-len(e):], e) {
+			return p[len(p)-len(e):]
+		}
+	}
+
+	return ""
+}
+
+type treeJob struct {
+	path string
+	ext  string
+	info fs.FileInfo
+}
+
+// tree holds the state of a ConvertTree call shared by its workers.
+type tree struct {
+	src    fs.FS
+	dstDir string
+	opts   TreeOptions
+
+	mu     sync.Mutex
+	report TreeReport
+}
+
+func (t *tree) fail(p string, err error) {
+	t.mu.Lock()
+	t.report.Failed = append(t.report.Failed, FileError{Path: p, Err: err})
+	t.mu.Unlock()
+}
+
+// convert converts the file of job unless its output is up to date.
+func (t *tree) convert(job treeJob) {
+	dst := filepath.Join(t.dstDir, filepath.FromSlash(strings.TrimSuffix(job.path, job.ext)+t.opts.OutputExt))
+
+	if !t.opts.Force {
+		if info, err := os.Stat(dst); err == nil && !info.ModTime().Before(job.info.ModTime()) {
+			t.mu.Lock()
+			t.report.Skipped = append(t.report.Skipped, job.path)
+			t.mu.Unlock()
+			return
+		}
+	}
+
+	if err := t.convertFile(job.path, dst); err != nil {
+		t.fail(job.path, err)
+		return
+	}
+
+	t.mu.Lock()
+	t.report.Converted = append(t.report.Converted, job.path)
+	t.mu.Unlock()
+}
+
+func (t *tree) convertFile(src, dst string) error {
+	f, err := t.src.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	m, _, err := image.Decode(f)
+	if err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o777); err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(dst, func(w io.Writer) error {
+		if err := t.opts.Encode(w, m); err != nil {
+			return fmt.Errorf("encode: %w", err)
+		}
+		return nil
+	})
+}
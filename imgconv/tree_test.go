@@ -0,0 +1,151 @@
+package imgconv
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// generatePNG returns a w x h PNG image.
+func generatePNG(t testing.TB, w, h int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatalf("could not encode image: %v\n", err)
+	}
+
+	return buf.Bytes()
+}
+
+// encodeSize writes the size of m as text.
+func encodeSize(w io.Writer, m image.Image) error {
+	if m.Bounds().Dx() == 13 {
+		return errors.New("unlucky width")
+	}
+
+	_, err := fmt.Fprintf(w, "%dx%d", m.Bounds().Dx(), m.Bounds().Dy())
+	return err
+}
+
+func TestConvertTree(t *testing.T) {
+	modTime := time.Now().Add(-time.Hour)
+	src := fstest.MapFS{
+		"a.png":             {Data: generatePNG(t, 1, 2), ModTime: modTime},
+		"b.PNG":             {Data: generatePNG(t, 3, 4), ModTime: modTime},
+		"sub/c.png":         {Data: generatePNG(t, 5, 6), ModTime: modTime},
+		"sub/deeper/d.png":  {Data: generatePNG(t, 7, 8), ModTime: modTime},
+		"sub/notes.txt":     {Data: []byte("skip me"), ModTime: modTime},
+		"sub/corrupt.png":   {Data: []byte("not a png"), ModTime: modTime},
+		"sub/unlucky.png":   {Data: generatePNG(t, 13, 1), ModTime: modTime},
+		"other/e.gif.png":   {Data: generatePNG(t, 9, 10), ModTime: modTime},
+		"other/ignored.bmp": {Data: []byte("BM"), ModTime: modTime},
+	}
+	dst := t.TempDir()
+	opts := TreeOptions{Encode: encodeSize, OutputExt: ".size", Workers: 3}
+
+	report, err := ConvertTree(context.Background(), src, dst, opts)
+	if err != nil {
+		t.Fatalf("ConvertTree: unexpected error: %v\n", err)
+	}
+
+	converted := []string{"a.png", "b.PNG", "other/e.gif.png", "sub/c.png", "sub/deeper/d.png"}
+	if !reflect.DeepEqual(report.Converted, converted) || len(report.Skipped) != 0 {
+		t.Errorf("ConvertTree converted %q and skipped %q, expected to convert %q\n", report.Converted, report.Skipped, converted)
+	}
+	if len(report.Failed) != 2 || report.Failed[0].Path != "sub/corrupt.png" || report.Failed[1].Path != "sub/unlucky.png" ||
+		!strings.Contains(report.Failed[0].Error(), "decode") || !strings.Contains(report.Failed[1].Error(), "unlucky width") {
+		t.Errorf("ConvertTree failed %v, expected sub/corrupt.png and sub/unlucky.png\n", report.Failed)
+	}
+
+	expected := map[string]string{
+		"a.size":            "1x2",
+		"b.size":            "3x4",
+		"other/e.gif.size":  "9x10",
+		"sub/c.size":        "5x6",
+		"sub/deeper/d.size": "7x8",
+	}
+	for name, content := range expected {
+		data, err := os.ReadFile(filepath.Join(dst, filepath.FromSlash(name)))
+		if err != nil || string(data) != content {
+			t.Errorf("output %s = %q, %v, expected %q\n", name, data, err, content)
+		}
+	}
+
+	//failed files leave no output or temporary file behind
+	var files []string
+	filepath.Walk(dst, func(p string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if len(files) != len(expected) {
+		t.Errorf("output directory holds %q, expected %d files\n", files, len(expected))
+	}
+
+	//a second run skips the up to date outputs and retries the failures
+	report, err = ConvertTree(context.Background(), src, dst, opts)
+	if err != nil || len(report.Converted) != 0 || !reflect.DeepEqual(report.Skipped, converted) || len(report.Failed) != 2 {
+		t.Errorf("ConvertTree() again = %+v, %v, expected to skip %q\n", report, err, converted)
+	}
+
+	//modified sources are converted again
+	src["sub/c.png"] = &fstest.MapFile{Data: generatePNG(t, 11, 12), ModTime: time.Now().Add(time.Hour)}
+	report, err = ConvertTree(context.Background(), src, dst, opts)
+	if err != nil || !reflect.DeepEqual(report.Converted, []string{"sub/c.png"}) || len(report.Skipped) != 4 {
+		t.Errorf("ConvertTree() after a change = %+v, %v, expected to convert sub/c.png\n", report, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, "sub", "c.size")); string(data) != "11x12" {
+		t.Errorf("output sub/c.size = %q, expected 11x12\n", data)
+	}
+
+	opts.Force = true
+	opts.Extensions = []string{".txt", ".gif.png"}
+	report, err = ConvertTree(context.Background(), src, dst, opts)
+	if err != nil || !reflect.DeepEqual(report.Converted, []string{"other/e.gif.png"}) || len(report.Failed) != 1 || report.Failed[0].Path != "sub/notes.txt" {
+		t.Errorf("ConvertTree(force, extensions) = %+v, %v, expected to convert other/e.gif.png and fail sub/notes.txt\n", report, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, "other", "e.size")); string(data) != "9x10" {
+		t.Errorf("output other/e.size = %q, expected the whole extension .gif.png to be replaced\n", data)
+	}
+}
+
+func TestConvertTreeErrors(t *testing.T) {
+	src := fstest.MapFS{"a.png": {Data: generatePNG(t, 1, 1)}}
+
+	if _, err := ConvertTree(context.Background(), src, t.TempDir(), TreeOptions{OutputExt: ".x"}); err == nil {
+		t.Errorf("ConvertTree() without encoder returned no error\n")
+	}
+	if _, err := ConvertTree(context.Background(), src, t.TempDir(), TreeOptions{Encode: encodeSize}); err == nil {
+		t.Errorf("ConvertTree() without output extension returned no error\n")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report, err := ConvertTree(ctx, src, t.TempDir(), TreeOptions{Encode: encodeSize, OutputExt: ".x"})
+	if !errors.Is(err, context.Canceled) || len(report.Converted) != 0 {
+		t.Errorf("ConvertTree(canceled) = %+v, %v, expected context.Canceled\n", report, err)
+	}
+
+	//files in the destination cannot be replaced by directories
+	dst := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dst, "sub"), nil, 0o666); err != nil {
+		t.Fatalf("could not write file: %v\n", err)
+	}
+	report, err = ConvertTree(context.Background(), fstest.MapFS{"sub/a.png": {Data: generatePNG(t, 1, 1)}}, dst, TreeOptions{Encode: encodeSize, OutputExt: ".x"})
+	if err != nil || len(report.Failed) != 1 {
+		t.Errorf("ConvertTree(blocked output) = %+v, %v, expected a failed file\n", report, err)
+	}
+}
@@ -0,0 +1,104 @@
+package imgconv
+
+import (
+	"image"
+	"image/color"
+)
+
+// ToYCbCr converts any image m to an *image.YCbCr image with the chroma
+// subsampling ratio. The colors are converted like color.YCbCrModel, so
+// transparent colors are converted as if composited over black.
+// Subsampled chroma values are the rounded average of the pixels of each
+// chroma block, counting only the pixels inside the bounds of m.
+// If m already is an *image.YCbCr with the same ratio, m itself is returned without copying.
+func ToYCbCr(m image.Image, ratio image.YCbCrSubsampleRatio) *image.YCbCr {
+	if img, ok := m.(*image.YCbCr); ok && img.SubsampleRatio == ratio {
+		return img
+	}
+
+	//color.YCbCrModel converts the alpha-premultiplied colors
+	src := ToRGBA(m)
+	b := src.Bounds()
+	img := image.NewYCbCr(b, ratio)
+	convertYCbCr(img, src.Pix, src.Stride, src.PixOffset(b.Min.X, b.Min.Y))
+
+	return img
+}
+
+// ToNYCbCrA converts any image m to an *image.NYCbCrA image with the chroma
+// subsampling ratio and a full resolution alpha plane. Unlike ToYCbCr, the
+// luma and chroma planes hold the colors before alpha-premultiplication,
+// so they equal ToYCbCr of m made opaque.
+// If m already is an *image.NYCbCrA with the same ratio, m itself is returned without copying.
+func ToNYCbCrA(m image.Image, ratio image.YCbCrSubsampleRatio) *image.NYCbCrA {
+	if img, ok := m.(*image.NYCbCrA); ok && img.SubsampleRatio == ratio {
+		return img
+	}
+
+	src := ToNRGBA(m)
+	b := src.Bounds()
+	img := image.NewNYCbCrA(b, ratio)
+	convertYCbCr(&img.YCbCr, src.Pix, src.Stride, src.PixOffset(b.Min.X, b.Min.Y))
+
+	switch m.(type) {
+	case *image.Gray, *image.Gray16, *image.YCbCr, *image.CMYK:
+		fill(img.A, 0xff)
+
+	default:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			si := src.PixOffset(b.Min.X, y) + 3
+			ai := img.AOffset(b.Min.X, y)
+			for x := b.Min.X; x < b.Max.X; x, si, ai = x+1, si+4, ai+1 {
+				img.A[ai] = src.Pix[si]
+			}
+		}
+	}
+
+	return img
+}
+
+// convertYCbCr fills the planes of img from the 4 bytes per pixel RGB values in pix,
+// where offset is the index of the pixel at img.Rect.Min.
+func convertYCbCr(img *image.YCbCr, pix []byte, stride, offset int) {
+	b := img.Rect
+
+	if img.SubsampleRatio == image.YCbCrSubsampleRatio444 {
+		for y, row := b.Min.Y, offset; y < b.Max.Y; y, row = y+1, row+stride {
+			si := row
+			yi := img.YOffset(b.Min.X, y)
+			ci := img.COffset(b.Min.X, y)
+			for x := b.Min.X; x < b.Max.X; x, si, yi, ci = x+1, si+4, yi+1, ci+1 {
+				s := pix[si : si+3 : si+3]
+				img.Y[yi], img.Cb[ci], img.Cr[ci] = color.RGBToYCbCr(s[0], s[1], s[2])
+			}
+		}
+		return
+	}
+
+	//sum the chroma values of every block to average over the pixels inside the bounds
+	cb := make([]uint32, len(img.Cb))
+	cr := make([]uint32, len(img.Cr))
+	n := make([]uint32, len(img.Cb))
+
+	for y, row := b.Min.Y, offset; y < b.Max.Y; y, row = y+1, row+stride {
+		si := row
+		yi := img.YOffset(b.Min.X, y)
+		for x := b.Min.X; x < b.Max.X; x, si, yi = x+1, si+4, yi+1 {
+			s := pix[si : si+3 : si+3]
+			yy, u, v := color.RGBToYCbCr(s[0], s[1], s[2])
+			img.Y[yi] = yy
+
+			ci := img.COffset(x, y)
+			cb[ci] += uint32(u)
+			cr[ci] += uint32(v)
+			n[ci]++
+		}
+	}
+
+	for ci, count := range n {
+		if count > 0 {
+			img.Cb[ci] = uint8((cb[ci] + count/2) / count)
+			img.Cr[ci] = uint8((cr[ci] + count/2) / count)
+		}
+	}
+}
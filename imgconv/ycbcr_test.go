@@ -0,0 +1,143 @@
+package imgconv
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+var subsampleRatios = []image.YCbCrSubsampleRatio{
+	image.YCbCrSubsampleRatio444,
+	image.YCbCrSubsampleRatio422,
+	image.YCbCrSubsampleRatio420,
+	image.YCbCrSubsampleRatio440,
+	image.YCbCrSubsampleRatio411,
+	image.YCbCrSubsampleRatio410,
+}
+
+func TestToYCbCr(t *testing.T) {
+	rects := []image.Rectangle{
+		image.Rect(0, 0, 16, 16),
+		image.Rect(0, 0, 13, 7),
+		image.Rect(-3, -5, 10, 4),
+		image.Rect(1, 1, 2, 2),
+	}
+
+	for _, ratio := range subsampleRatios {
+		for idx, r := range rects {
+			m := generateRandomRGBA(t, r, int64(idx))
+
+			t.Run(fmt.Sprintf("%v %v", ratio, r), func(t *testing.T) {
+				actual := ToYCbCr(m, ratio)
+				if actual.Bounds() != r || actual.SubsampleRatio != ratio {
+					t.Fatalf("ToYCbCr: expected %v %v, got %v %v\n", r, ratio, actual.Bounds(), actual.SubsampleRatio)
+				}
+
+				//average the full resolution chroma values of every chroma sample
+				cb := make([]int, len(actual.Cb))
+				cr := make([]int, len(actual.Cr))
+				n := make([]int, len(actual.Cb))
+				for y := r.Min.Y; y < r.Max.Y; y++ {
+					for x := r.Min.X; x < r.Max.X; x++ {
+						c := color.YCbCrModel.Convert(m.At(x, y)).(color.YCbCr)
+						if actual.Y[actual.YOffset(x, y)] != c.Y {
+							t.Fatalf("ToYCbCr: different luma at x=%d, y=%d: Expected: %d - Actual: %d\n", x, y, c.Y, actual.Y[actual.YOffset(x, y)])
+						}
+
+						ci := actual.COffset(x, y)
+						cb[ci] += int(c.Cb)
+						cr[ci] += int(c.Cr)
+						n[ci]++
+					}
+				}
+
+				for ci := range n {
+					if n[ci] == 0 {
+						continue
+					}
+					if expected := uint8((cb[ci] + n[ci]/2) / n[ci]); actual.Cb[ci] != expected {
+						t.Fatalf("ToYCbCr: different Cb at index %d: Expected: %d - Actual: %d\n", ci, expected, actual.Cb[ci])
+					}
+					if expected := uint8((cr[ci] + n[ci]/2) / n[ci]); actual.Cr[ci] != expected {
+						t.Fatalf("ToYCbCr: different Cr at index %d: Expected: %d - Actual: %d\n", ci, expected, actual.Cr[ci])
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestToYCbCrEdgeBlock(t *testing.T) {
+	//the last 4:2:2 block only contains the blue pixel
+	m := generateStripesNRGBA(t, image.Rect(0, 0, 3, 1), []color.NRGBA{{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255}})
+
+	actual := ToYCbCr(m, image.YCbCrSubsampleRatio422)
+	_, cb, cr := color.RGBToYCbCr(0, 0, 255)
+	if actual.Cb[1] != cb || actual.Cr[1] != cr {
+		t.Errorf("ToYCbCr: expected chroma %d, %d for the edge block, got %d, %d\n", cb, cr, actual.Cb[1], actual.Cr[1])
+	}
+
+	_, cb0, cr0 := color.RGBToYCbCr(255, 0, 0)
+	_, cb1, cr1 := color.RGBToYCbCr(0, 255, 0)
+	if expected := uint8((int(cb0) + int(cb1) + 1) / 2); actual.Cb[0] != expected {
+		t.Errorf("ToYCbCr: expected Cb %d for the first block, got %d\n", expected, actual.Cb[0])
+	}
+	if expected := uint8((int(cr0) + int(cr1) + 1) / 2); actual.Cr[0] != expected {
+		t.Errorf("ToYCbCr: expected Cr %d for the first block, got %d\n", expected, actual.Cr[0])
+	}
+}
+
+func TestToYCbCrReturnsYCbCrImage(t *testing.T) {
+	m := image.NewYCbCr(image.Rect(0, 0, 2, 2), image.YCbCrSubsampleRatio420)
+
+	if actual := ToYCbCr(m, image.YCbCrSubsampleRatio420); actual != m {
+		t.Errorf("ToYCbCr(*image.YCbCr) = %p, expected %p\n", actual, m)
+	}
+	if actual := ToYCbCr(m, image.YCbCrSubsampleRatio444); actual == m {
+		t.Errorf("ToYCbCr(*image.YCbCr) with a different ratio returned the same image\n")
+	}
+}
+
+func TestToNYCbCrA(t *testing.T) {
+	bounds := image.Rect(-3, 2, 14, 13)
+
+	for _, ratio := range subsampleRatios {
+		for _, src := range []image.Image{
+			generateRandomNRGBA(t, bounds, 1),
+			generateRandomRGBA(t, bounds, 2),
+			generateRandomYCbCr(t, bounds, image.YCbCrSubsampleRatio420, 3),
+			UniformNRGBA(color.NRGBA{10, 20, 30, 255}, bounds),
+		} {
+			t.Run(fmt.Sprintf("%v %T", ratio, src), func(t *testing.T) {
+				actual := ToNYCbCrA(src, ratio)
+
+				//the planes hold the colors before premultiplication
+				opaque := CloneNRGBA(src)
+				for i := 3; i < len(opaque.Pix); i += 4 {
+					opaque.Pix[i] = 0xff
+				}
+				expected := ToYCbCr(opaque, ratio)
+
+				if !bytes.Equal(actual.Y, expected.Y) || !bytes.Equal(actual.Cb, expected.Cb) || !bytes.Equal(actual.Cr, expected.Cr) {
+					t.Fatalf("ToNYCbCrA(%T, %v): planes differ from ToYCbCr\n", src, ratio)
+				}
+
+				if alpha := ExtractAlpha(src); !bytes.Equal(actual.A, alpha.Pix) {
+					t.Fatalf("ToNYCbCrA(%T, %v): alpha plane differs from ExtractAlpha\n", src, ratio)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkToYCbCr420(b *testing.B) {
+	m := generateRandomNRGBA(b, image.Rect(0, 0, 1920, 1080), 1)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ToYCbCr(m, image.YCbCrSubsampleRatio420)
+	}
+}
@@ -0,0 +1,88 @@
+package imgtest
+
+import (
+	"bytes"
+	"image"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// DecodeCorrupt decodes count randomly corrupted copies of every file
+// matching pattern with decode. Corrupt files must make decode return an
+// error or an image, but never panic; the pixels of the returned images are
+// all read with At, so lazily decoding images must not panic either.
+//
+// Every copy has 1 to 4 random bytes replaced and is truncated at a random
+// length. If header is positive, every replaced byte lies within the first
+// header bytes with a probability of one half, which exercises the parsing of
+// headers and offset tables more often than the pixel data. The random
+// numbers are seeded with 1, so every run corrupts the files the same way.
+func DecodeCorrupt(t testing.TB, pattern string, count, header int, decode func(io.Reader) (image.Image, error)) {
+	t.Helper()
+
+	DecodeAllCorrupt(t, pattern, count, header, func(r io.Reader) ([]image.Image, error) {
+		m, err := decode(r)
+		return []image.Image{m}, err
+	})
+}
+
+// DecodeAllCorrupt is like DecodeCorrupt for formats holding several images,
+// like icons or animations, whose pixels are all read.
+func DecodeAllCorrupt(t testing.TB, pattern string, count, header int, decodeAll func(io.Reader) ([]image.Image, error)) {
+	t.Helper()
+
+	filenames, err := filepath.Glob(pattern)
+	if err != nil || len(filenames) == 0 {
+		t.Fatalf("could not find files %s: %v\n", pattern, err)
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	for _, name := range filenames {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("could not read file: %v\n", err)
+		}
+
+		for i := 0; i < count; i++ {
+			images, err := decodeAll(bytes.NewReader(corrupt(rnd, data, header)))
+			if err != nil {
+				continue
+			}
+			for _, m := range images {
+				readPixels(m)
+			}
+		}
+	}
+}
+
+// corrupt returns a copy of data with 1 to 4 random bytes replaced, truncated
+// at a random length.
+func corrupt(rnd *rand.Rand, data []byte, header int) []byte {
+	c := append([]byte(nil), data...)
+	if len(c) == 0 {
+		return c
+	}
+
+	for n := rnd.Intn(4) + 1; n > 0; n-- {
+		size := len(c)
+		if header > 0 && rnd.Intn(2) == 0 && size > header {
+			size = header
+		}
+		c[rnd.Intn(size)] = byte(rnd.Intn(256))
+	}
+
+	return c[:rnd.Intn(len(c)+1)]
+}
+
+// readPixels reads every pixel of m.
+func readPixels(m image.Image) {
+	b := m.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			_ = m.At(x, y)
+		}
+	}
+}
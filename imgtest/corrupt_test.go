@@ -0,0 +1,60 @@
+package imgtest
+
+import (
+	"bytes"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDecodeCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	data := make([]byte, 4096)
+	for _, name := range []string{"a.bin", "b.bin"} {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatalf("could not write file: %v\n", err)
+		}
+	}
+
+	calls, changed, header := 0, 0, 0
+	DecodeCorrupt(t, filepath.Join(dir, "*.bin"), 100, 16, func(r io.Reader) (image.Image, error) {
+		c, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("could not read corrupt data: %v\n", err)
+		}
+		calls++
+
+		if i := bytes.IndexFunc(c, func(r rune) bool { return r != 0 }); i >= 0 {
+			changed++
+			//the files are much larger than the header
+			if i < 16 {
+				header++
+			}
+		}
+		if len(c) > len(data) {
+			t.Errorf("corrupt data grew to %d bytes\n", len(c))
+		}
+
+		return image.NewNRGBA(image.Rect(0, 0, 2, 2)), nil
+	})
+
+	if calls != 200 {
+		t.Errorf("decode called %d times, expected 200\n", calls)
+	}
+	if changed == 0 || header < changed/4 {
+		t.Errorf("%d of %d corrupt copies changed within the header, expected about half\n", header, changed)
+	}
+}
+
+func TestDecodeCorruptNoFiles(t *testing.T) {
+	r := &recorder{TB: t}
+	DecodeCorrupt(r, filepath.Join(t.TempDir(), "*.bin"), 1, 0, func(io.Reader) (image.Image, error) {
+		return nil, nil
+	})
+
+	if !r.fatal {
+		t.Errorf("DecodeCorrupt: expected a failure for a pattern without files\n")
+	}
+}
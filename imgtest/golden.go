@@ -0,0 +1,86 @@
+package imgtest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// goldenMaxGrowth is the factor by which -update may grow or shrink a golden
+// file. Larger changes are most likely a broken encoder, not an intended one.
+const goldenMaxGrowth = 2
+
+// Golden reports an error if got differs from the golden file name, a path
+// relative to the package directory of the test, e.g. "../testdata/qoi/x.qoi".
+//
+// If update is set, the file is rewritten with got instead and the update is
+// logged; missing files and directories are created. To guard against
+// accidental mass rewrites, the update fails if the size of the file would
+// change by more than a factor of two. Delete the file to regenerate it anyway.
+//
+// imgtest registers no flags. Tests usually pass the value of their own
+// -update flag, declared in a _test.go file of their package:
+//
+//	var update = flag.Bool("update", false, "rewrite the golden files")
+func Golden(t testing.TB, name string, got []byte, update bool) {
+	t.Helper()
+
+	want, err := os.ReadFile(name)
+	if err != nil && !(update && errors.Is(err, fs.ErrNotExist)) {
+		t.Fatalf("could not read golden file: %v\n", err)
+		return
+	}
+
+	if update {
+		updateGolden(t, name, want, got, err == nil)
+		return
+	}
+
+	if !bytes.Equal(want, got) {
+		i := 0
+		for i < len(want) && i < len(got) && want[i] == got[i] {
+			i++
+		}
+
+		format := fmt.Sprintf("\n") +
+			fmt.Sprintf("Golden file:\t %s\n", name) +
+			fmt.Sprintf("Expected length:\t %d\n", len(want)) +
+			fmt.Sprintf("Actual length:\t %d\n", len(got)) +
+			fmt.Sprintf("First difference:\t byte %d\n", i) +
+			fmt.Sprintf("Update the golden file, e.g. with go test -update, if the change is intended.\n")
+
+		t.Errorf(format)
+	}
+}
+
+// updateGolden writes got to the golden file name, which holds want if it exists.
+func updateGolden(t testing.TB, name string, want, got []byte, exists bool) {
+	t.Helper()
+
+	if exists && bytes.Equal(want, got) {
+		return
+	}
+	if exists && (len(got) > goldenMaxGrowth*len(want) || len(want) > goldenMaxGrowth*len(got)) {
+		t.Fatalf("refusing to update golden file %s from %d to %d bytes, delete it to regenerate it\n", name, len(want), len(got))
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(name), 0o777); err != nil {
+		t.Fatalf("could not create golden file: %v\n", err)
+		return
+	}
+	if err := os.WriteFile(name, got, 0o644); err != nil {
+		t.Fatalf("could not write golden file: %v\n", err)
+		return
+	}
+
+	if exists {
+		t.Logf("golden: updated %s (%d -> %d bytes)\n", name, len(want), len(got))
+	} else {
+		t.Logf("golden: created %s (%d bytes)\n", name, len(got))
+	}
+}
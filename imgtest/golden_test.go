@@ -0,0 +1,124 @@
+package imgtest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGolden(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "golden.bin")
+	if err := os.WriteFile(name, []byte("abcdef"), 0o644); err != nil {
+		t.Fatalf("could not write file: %v\n", err)
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		got      []byte
+		expected []string
+	}{
+		{name: "equal", file: name, got: []byte("abcdef")},
+		{
+			name:     "different",
+			file:     name,
+			got:      []byte("abcxef"),
+			expected: []string{"\nGolden file:\t " + name + "\nExpected length:\t 6\nActual length:\t 6\nFirst difference:\t byte 3\n"},
+		},
+		{
+			name:     "longer",
+			file:     name,
+			got:      []byte("abcdefg"),
+			expected: []string{"\nGolden file:\t " + name + "\nExpected length:\t 6\nActual length:\t 7\nFirst difference:\t byte 6\n"},
+		},
+		{
+			name:     "missing file",
+			file:     filepath.Join(dir, "missing.bin"),
+			got:      []byte("abc"),
+			expected: []string{"could not read golden file: open " + filepath.Join(dir, "missing.bin") + ": no such file or directory\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{TB: t}
+			Golden(r, tt.file, tt.got, false)
+
+			if len(r.errors) != len(tt.expected) {
+				t.Fatalf("\nExpected failures:\t %q\nActual failures:\t %q\n", tt.expected, r.errors)
+			}
+			for i, e := range tt.expected {
+				if !strings.HasPrefix(r.errors[i], e) {
+					t.Errorf("\nExpected failure:\t %q\nActual failure:\t %q\n", e, r.errors[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGoldenUpdate(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		existing []byte
+		got      []byte
+		expected []byte
+		failure  string
+	}{
+		{name: "create", got: []byte("abc"), expected: []byte("abc")},
+		{name: "unchanged", existing: []byte("abc"), got: []byte("abc"), expected: []byte("abc")},
+		{name: "rewrite", existing: []byte("abcd"), got: []byte("abcdefgh"), expected: []byte("abcdefgh")},
+		{
+			name:     "grows too much",
+			existing: []byte("abcd"),
+			got:      []byte("abcdefghi"),
+			expected: []byte("abcd"),
+			failure:  "refusing to update golden file %s from 4 to 9 bytes, delete it to regenerate it\n",
+		},
+		{
+			name:     "shrinks too much",
+			existing: []byte("abcdefghi"),
+			got:      []byte("abcd"),
+			expected: []byte("abcdefghi"),
+			failure:  "refusing to update golden file %s from 9 to 4 bytes, delete it to regenerate it\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := filepath.Join(dir, tt.name, "golden.bin")
+			if tt.existing != nil {
+				if err := os.MkdirAll(filepath.Dir(name), 0o777); err != nil {
+					t.Fatalf("could not create directory: %v\n", err)
+				}
+				if err := os.WriteFile(name, tt.existing, 0o644); err != nil {
+					t.Fatalf("could not write file: %v\n", err)
+				}
+			}
+
+			r := &recorder{TB: t}
+			Golden(r, name, tt.got, true)
+
+			var expected []string
+			if tt.failure != "" {
+				expected = []string{fmt.Sprintf(tt.failure, name)}
+			}
+			if fmt.Sprintf("%q", r.errors) != fmt.Sprintf("%q", expected) {
+				t.Errorf("\nExpected failures:\t %q\nActual failures:\t %q\n", expected, r.errors)
+			}
+
+			data, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatalf("could not read file: %v\n", err)
+			}
+			if !bytes.Equal(data, tt.expected) {
+				t.Errorf("\nExpected file:\t %q\nActual file:\t %q\n", tt.expected, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+// Package imgtest provides helpers to test image encoders and decoders, for
+// the packages of this module as well as for third-party ones.
+//
+// The helpers accept a testing.TB and report failures with the same messages
+// as the tests of this module, which name the first differing pixel.
+package imgtest
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"testing"
+
+	"github.com/LukiDS/image/imgcmp"
+	"github.com/LukiDS/image/imgconv"
+)
+
+// AssertEqual reports an error if got does not hold the same image as want.
+// The images are compared like imgcmp.Equal, so their color models may
+// differ as long as the colors are the same, at 16 bits if either image is
+// deeper than 8 bits. Two nil images are equal.
+// The message of a failure names the first differing pixel, the number of
+// differing pixels and the largest channel deltas.
+func AssertEqual(t testing.TB, want, got image.Image) {
+	t.Helper()
+
+	if msg := mismatch(want, got, 0); msg != "" {
+		t.Errorf(msg)
+	}
+}
+
+// mismatch returns the failure message for got differing from want by more
+// than tolerance in any channel, or "" if it does not.
+func mismatch(want, got image.Image, tolerance uint8) string {
+	equal, report := imgcmp.EqualTolerant(want, got, tolerance)
+	if equal {
+		return ""
+	}
+
+	switch {
+	case isNil(want):
+		return "Assert image:\t unexpected image\n"
+	case isNil(got):
+		return "Assert image:\t unexpected nil image\n"
+	case want.Bounds() != got.Bounds():
+		return fmt.Sprintf("Assert image:\t different image dimensions: Expected: %+v - Actual: %+v\n", want.Bounds(), got.Bounds())
+	case report.Differing == 0:
+		return fmt.Sprintf("Assert image:\t images cannot be compared: %v\n", want.Bounds())
+	}
+
+	//colors differing only beyond 8 bits are shown with 16 bits
+	x, y := report.First.X, report.First.Y
+	var wc, gc color.Color = nrgbaAt(want, x, y), nrgbaAt(got, x, y)
+	if wc == gc {
+		wc, gc = color.NRGBA64Model.Convert(want.At(x, y)), color.NRGBA64Model.Convert(got.At(x, y))
+	}
+	return fmt.Sprintf("Assert image:\t different pixel at x=%d, y=%d: Expected: %+v - Actual: %+v\n", x, y, wc, gc) +
+		fmt.Sprintf("Assert image:\t %d differing pixels in %v, max delta %v\n", report.Differing, report.Bounds, report.MaxDelta)
+}
+
+// isNil reports whether m is nil, including nil pointers of image types.
+func isNil(m image.Image) bool {
+	return errors.Is(imgconv.Validate(m, imgconv.DefaultMaxPixels), imgconv.ErrNilImage)
+}
+
+func nrgbaAt(m image.Image, x, y int) color.NRGBA {
+	return color.NRGBAModel.Convert(m.At(x, y)).(color.NRGBA)
+}
+
+// RequireDecodes decodes r with image.Decode and returns the image. It stops
+// the test with t.Fatalf if r cannot be decoded, so the decoders of the
+// expected format must be registered, e.g. by importing the formats package.
+func RequireDecodes(t testing.TB, r io.Reader) image.Image {
+	t.Helper()
+
+	m, _, err := image.Decode(r)
+	if err != nil {
+		t.Fatalf("could not decode image: %v\n", err)
+	}
+
+	return m
+}
+
+// RequireFormat is like RequireDecodes, but also stops the test if image.Decode
+// does not detect r as the format name or the image does not have the bounds.
+// Decoder packages use it to check their registration with image.Decode.
+func RequireFormat(t testing.TB, r io.Reader, format string, bounds image.Rectangle) image.Image {
+	t.Helper()
+
+	m, name, err := image.Decode(r)
+	if err != nil || name != format || m.Bounds() != bounds {
+		t.Fatalf("image.Decode() = (%T, %q, %v), expected a %v %s image\n", m, name, err, bounds, format)
+	}
+
+	return m
+}
+
+// AssertColorModel reports an error if got does not have the color model
+// want. AssertEqual accepts any color model, so decoder tests use it to check
+// the type of the decoded image as well. Palettes are equal if they hold the
+// same colors.
+func AssertColorModel(t testing.TB, want color.Model, got image.Image) {
+	t.Helper()
+
+	if !isNil(got) && !sameModel(want, got.ColorModel()) {
+		t.Errorf("Assert image:\t different color model: %T does not have the expected model\n", got)
+	}
+}
+
+func sameModel(a, b color.Model) bool {
+	pa, ok := a.(color.Palette)
+	if !ok {
+		_, ok = b.(color.Palette)
+		return !ok && a == b
+	}
+	pb, ok := b.(color.Palette)
+	if !ok || len(pa) != len(pb) {
+		return false
+	}
+	for i := range pa {
+		if pa[i] != pb[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// NRGBA returns a width x height *image.NRGBA image holding the pixels pix.
+// It stops the test with t.Fatalf if pix does not hold 4*width*height bytes.
+func NRGBA(t testing.TB, width, height int, pix []byte) *image.NRGBA {
+	t.Helper()
+
+	if width < 0 || height < 0 || len(pix) != 4*width*height {
+		t.Fatalf("%d bytes do not hold %dx%d NRGBA pixels\n", len(pix), width, height)
+	}
+
+	m := image.NewNRGBA(image.Rect(0, 0, width, height))
+	m.Pix = pix
+
+	return m
+}
@@ -0,0 +1,189 @@
+package imgtest
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// recorder records the failures reported to it instead of failing the test.
+type recorder struct {
+	testing.TB
+	errors []string
+	fatal  bool
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+	r.fatal = true
+}
+
+func TestAssertEqual(t *testing.T) {
+	a := NRGBA(t, 3, 1, []byte{1, 2, 3, 0xff, 4, 5, 6, 0xff, 7, 8, 9, 0xff})
+	b := NRGBA(t, 3, 1, []byte{1, 2, 3, 0xff, 4, 5, 0, 0xff, 7, 8, 0, 0xff})
+	gray := image.NewGray(image.Rect(0, 0, 3, 1))
+	gray.Pix = []byte{10, 20, 30}
+	var nilImage *image.NRGBA
+
+	tests := []struct {
+		name      string
+		want, got image.Image
+		expected  []string
+	}{
+		{name: "equal images", want: a, got: NRGBA(t, 3, 1, append([]byte(nil), a.Pix...))},
+		{name: "different color models", want: gray, got: NRGBA(t, 3, 1, []byte{10, 10, 10, 0xff, 20, 20, 20, 0xff, 30, 30, 30, 0xff})},
+		{name: "nil images", want: nil, got: nilImage},
+		{
+			name: "different pixels",
+			want: a,
+			got:  b,
+			expected: []string{
+				"Assert image:\t different pixel at x=1, y=0: Expected: {R:4 G:5 B:6 A:255} - Actual: {R:4 G:5 B:0 A:255}\n" +
+					"Assert image:\t 2 differing pixels in (1,0)-(3,1), max delta [0 0 9 0]\n",
+			},
+		},
+		{
+			name:     "different bounds",
+			want:     a,
+			got:      image.NewNRGBA(image.Rect(0, 0, 1, 3)),
+			expected: []string{"Assert image:\t different image dimensions: Expected: (0,0)-(3,1) - Actual: (0,0)-(1,3)\n"},
+		},
+		{
+			name: "different low bytes",
+			want: &image.Gray16{Pix: []byte{1, 2}, Stride: 2, Rect: image.Rect(0, 0, 1, 1)},
+			got:  &image.Gray16{Pix: []byte{1, 3}, Stride: 2, Rect: image.Rect(0, 0, 1, 1)},
+			expected: []string{
+				"Assert image:\t different pixel at x=0, y=0: Expected: {R:258 G:258 B:258 A:65535} - Actual: {R:259 G:259 B:259 A:65535}\n" +
+					"Assert image:\t 1 differing pixels in (0,0)-(1,1), max delta [1 1 1 0]\n",
+			},
+		},
+		{name: "unexpected image", want: nil, got: a, expected: []string{"Assert image:\t unexpected image\n"}},
+		{name: "unexpected nil image", want: a, got: nilImage, expected: []string{"Assert image:\t unexpected nil image\n"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{TB: t}
+			AssertEqual(r, tt.want, tt.got)
+
+			if fmt.Sprintf("%q", r.errors) != fmt.Sprintf("%q", tt.expected) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("AssertEqual(%s)\n", tt.name) +
+					fmt.Sprintf("Expected failures:\t %q\n", tt.expected) +
+					fmt.Sprintf("Actual failures:\t %q\n", r.errors)
+				t.Errorf(format)
+			}
+			if r.fatal {
+				t.Errorf("\nAssertEqual(%s) stopped the test\n", tt.name)
+			}
+		})
+	}
+}
+
+func TestRequireDecodes(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	m.SetNRGBA(1, 1, color.NRGBA{1, 2, 3, 4})
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+
+	AssertEqual(t, m, RequireDecodes(t, &buf))
+
+	r := &recorder{TB: t}
+	RequireDecodes(r, strings.NewReader("not an image"))
+	if !r.fatal || len(r.errors) != 1 || !strings.HasPrefix(r.errors[0], "could not decode image: ") {
+		t.Errorf("\nRequireDecodes of invalid data: fatal %t, failures %q\n", r.fatal, r.errors)
+	}
+}
+
+func TestRequireFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 2, 1))); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	RequireFormat(t, bytes.NewReader(data), "png", image.Rect(0, 0, 2, 1))
+
+	for _, tt := range []struct {
+		format string
+		bounds image.Rectangle
+	}{{"qoi", image.Rect(0, 0, 2, 1)}, {"png", image.Rect(0, 0, 1, 2)}} {
+		r := &recorder{TB: t}
+		RequireFormat(r, bytes.NewReader(data), tt.format, tt.bounds)
+		expected := fmt.Sprintf("image.Decode() = (*image.Gray, \"png\", <nil>), expected a %v %s image\n", tt.bounds, tt.format)
+		if !r.fatal || fmt.Sprintf("%q", r.errors) != fmt.Sprintf("%q", []string{expected}) {
+			t.Errorf("\nRequireFormat(%s, %v): fatal %t, failures %q\n", tt.format, tt.bounds, r.fatal, r.errors)
+		}
+	}
+}
+
+func TestAssertColorModel(t *testing.T) {
+	gray := image.NewGray(image.Rect(0, 0, 1, 1))
+	AssertColorModel(t, color.GrayModel, gray)
+
+	r := &recorder{TB: t}
+	AssertColorModel(r, color.Gray16Model, gray)
+	expected := []string{"Assert image:\t different color model: *image.Gray does not have the expected model\n"}
+	if fmt.Sprintf("%q", r.errors) != fmt.Sprintf("%q", expected) {
+		t.Errorf("\nAssertColorModel(Gray16, *image.Gray): failures %q, expected %q\n", r.errors, expected)
+	}
+
+	palette := color.Palette{color.Black, color.White}
+	paletted := image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.Black, color.White})
+	AssertColorModel(t, palette, paletted)
+
+	r = &recorder{TB: t}
+	AssertColorModel(r, palette[:1], paletted)
+	AssertColorModel(r, color.GrayModel, paletted)
+	if len(r.errors) != 2 {
+		t.Errorf("\nAssertColorModel(Paletted): %d failures, expected 2\n", len(r.errors))
+	}
+}
+
+func TestNRGBA(t *testing.T) {
+	r := &recorder{TB: t}
+	NRGBA(r, 2, 2, make([]byte, 15))
+	if !r.fatal {
+		t.Errorf("\nNRGBA accepted 15 bytes for 2x2 pixels\n")
+	}
+}
+
+func TestQOIStream(t *testing.T) {
+	h := QOIHeader{Width: 0x01020304, Height: 0xffffffff, Channels: 3, Colorspace: 1}
+	data := []byte{0xfe, 1, 2, 3}
+
+	header := "qoif\x01\x02\x03\x04\xff\xff\xff\xff\x03\x01"
+	marker := "\x00\x00\x00\x00\x00\x00\x00\x01"
+
+	tests := []struct {
+		name     string
+		actual   []byte
+		expected string
+	}{
+		{name: "QOIStream", actual: QOIStream(h, data), expected: header + "\xfe\x01\x02\x03" + marker},
+		{name: "QOIStreamWithoutPadding", actual: QOIStreamWithoutPadding(h, data), expected: header + "\xfe\x01\x02\x03"},
+		{name: "QOIStreamWithoutHeader", actual: QOIStreamWithoutHeader(data), expected: "\xfe\x01\x02\x03" + marker},
+		{name: "empty QOIStreamWithoutHeader", actual: QOIStreamWithoutHeader(nil), expected: marker},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if string(tt.actual) != tt.expected {
+				t.Errorf("\nExpected:\t %q\nActual:\t %q\n", tt.expected, tt.actual)
+			}
+		})
+	}
+}
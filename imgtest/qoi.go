@@ -0,0 +1,37 @@
+package imgtest
+
+// qoiEndMarker terminates every QOI stream.
+var qoiEndMarker = []byte{0, 0, 0, 0, 0, 0, 0, 1}
+
+// QOIHeader holds the fields of a QOI header. They are written as they are,
+// so tests can build headers which no encoder would write.
+type QOIHeader struct {
+	Width, Height uint32
+	Channels      uint8
+	Colorspace    uint8
+}
+
+// QOIStream returns a QOI stream of the header h, the encoded pixels data and
+// the end marker.
+func QOIStream(h QOIHeader, data []byte) []byte {
+	return append(QOIStreamWithoutPadding(h, data), qoiEndMarker...)
+}
+
+// QOIStreamWithoutPadding is like QOIStream, but leaves out the end marker.
+func QOIStreamWithoutPadding(h QOIHeader, data []byte) []byte {
+	stream := make([]byte, 0, 14+len(data)+len(qoiEndMarker))
+	stream = append(stream, "qoif"...)
+	stream = append(stream, byte(h.Width>>24), byte(h.Width>>16), byte(h.Width>>8), byte(h.Width))
+	stream = append(stream, byte(h.Height>>24), byte(h.Height>>16), byte(h.Height>>8), byte(h.Height))
+	stream = append(stream, h.Channels, h.Colorspace)
+
+	return append(stream, data...)
+}
+
+// QOIStreamWithoutHeader is like QOIStream, but leaves out the header.
+func QOIStreamWithoutHeader(data []byte) []byte {
+	stream := make([]byte, 0, len(data)+len(qoiEndMarker))
+	stream = append(stream, data...)
+
+	return append(stream, qoiEndMarker...)
+}
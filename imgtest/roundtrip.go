@@ -0,0 +1,72 @@
+package imgtest
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+	"testing"
+
+	"github.com/LukiDS/image/imgconv"
+	"github.com/LukiDS/image/testimg"
+)
+
+// RoundTrip checks the property dec(enc(m)) == m of a lossless codec for every
+// image m of imgs, or for DefaultImages if imgs is empty. The decoded image
+// must have the size and the colors of m, compared like AssertEqual; its
+// origin may differ, since image formats do not store it. Every failing image
+// is reported with its index, bounds and first differing pixel.
+func RoundTrip(t testing.TB, enc func(io.Writer, image.Image) error, dec func(io.Reader) (image.Image, error), imgs ...image.Image) {
+	t.Helper()
+
+	RoundTripTolerant(t, enc, dec, 0, imgs...)
+}
+
+// RoundTripTolerant is like RoundTrip for lossy codecs: the channels of the
+// decoded pixels may differ by up to maxDelta from the original ones.
+func RoundTripTolerant(t testing.TB, enc func(io.Writer, image.Image) error, dec func(io.Reader) (image.Image, error), maxDelta uint8, imgs ...image.Image) {
+	t.Helper()
+
+	if len(imgs) == 0 {
+		imgs = DefaultImages()
+	}
+
+	for i, m := range imgs {
+		prefix := fmt.Sprintf("RoundTrip image %d %T %v:\n", i, m, m.Bounds())
+
+		var buf bytes.Buffer
+		if err := enc(&buf, m); err != nil {
+			t.Errorf(prefix+"could not encode image: %v\n", err)
+			continue
+		}
+
+		got, err := dec(&buf)
+		if err != nil {
+			t.Errorf(prefix+"could not decode image: %v\n", err)
+			continue
+		}
+
+		want := image.Image(imgconv.NormalizeBounds(m))
+		if got != nil && got.Bounds().Size() == want.Bounds().Size() {
+			got = imgconv.NormalizeBounds(got)
+		}
+		if msg := mismatch(want, got, maxDelta); msg != "" {
+			t.Errorf(prefix + msg)
+		}
+	}
+}
+
+// DefaultImages returns the synthetic images RoundTrip checks by default: the
+// generators of the testimg package in a few sizes, from a single pixel to
+// sizes which are no multiple of the run lengths and block sizes of codecs.
+func DefaultImages() []image.Image {
+	return []image.Image{
+		testimg.Gradient(1, 1),
+		testimg.Noise(1, 7, 1),
+		testimg.Gradient(67, 31),
+		testimg.Checkerboard(64, 64, 3),
+		testimg.Noise(33, 17, 2),
+		testimg.SolidRuns(129, 65),
+		testimg.AlphaRamp(256, 3),
+	}
+}
@@ -0,0 +1,109 @@
+package imgtest
+
+import (
+	"image"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/LukiDS/image/imgconv"
+	"github.com/LukiDS/image/testimg"
+)
+
+// encodeQuantized is a lossy PNG encoder which clears the low 4 bits of the
+// color channels, so the colors of the decoded pixels differ by up to 15.
+func encodeQuantized(w io.Writer, m image.Image) error {
+	n := imgconv.CloneNRGBA(m)
+	for i := range n.Pix {
+		if i%4 != 3 {
+			n.Pix[i] &= 0xf0
+		}
+	}
+
+	return png.Encode(w, n)
+}
+
+func TestRoundTrip(t *testing.T) {
+	//a sub image keeps the origin of its parent
+	sub := testimg.Gradient(16, 16).SubImage(image.Rect(3, 5, 11, 9))
+
+	RoundTrip(t, png.Encode, png.Decode)
+	RoundTrip(t, png.Encode, png.Decode, sub, image.NewGray(image.Rect(0, 0, 3, 3)))
+	RoundTripTolerant(t, encodeQuantized, png.Decode, 15)
+}
+
+func TestRoundTripFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		enc      func(io.Writer, image.Image) error
+		dec      func(io.Reader) (image.Image, error)
+		maxDelta uint8
+		imgs     []image.Image
+		expected []string
+	}{
+		{
+			name: "lossy encoder",
+			enc:  encodeQuantized,
+			dec:  png.Decode,
+			imgs: []image.Image{testimg.Checkerboard(4, 4, 2), testimg.Gradient(4, 1)},
+			expected: []string{
+				"RoundTrip image 0 *image.NRGBA (0,0)-(4,4):\nAssert image:\t different pixel at x=2, y=0: Expected: {R:255 G:255 B:255 A:255} - Actual: {R:240 G:240 B:240 A:255}\n",
+				"RoundTrip image 1 *image.NRGBA (0,0)-(4,1):\nAssert image:\t different pixel at x=1, y=0: Expected: {R:85 G:0 B:85 A:255} - Actual: {R:80 G:0 B:80 A:255}\n",
+			},
+		},
+		{
+			name:     "too little tolerance",
+			enc:      encodeQuantized,
+			dec:      png.Decode,
+			maxDelta: 14,
+			imgs:     []image.Image{testimg.Gradient(4, 1)},
+			expected: []string{"RoundTrip image 0 *image.NRGBA (0,0)-(4,1):\nAssert image:\t different pixel at x=3, y=0: Expected: {R:255 G:0 B:255 A:255} - Actual: {R:240 G:0 B:240 A:255}\n"},
+		},
+		{
+			name: "failing encoder",
+			enc: func(w io.Writer, m image.Image) error {
+				return io.ErrShortWrite
+			},
+			dec:      png.Decode,
+			imgs:     []image.Image{testimg.Gradient(2, 2)},
+			expected: []string{"RoundTrip image 0 *image.NRGBA (0,0)-(2,2):\ncould not encode image: short write\n"},
+		},
+		{
+			name: "failing decoder",
+			enc:  png.Encode,
+			dec: func(r io.Reader) (image.Image, error) {
+				return nil, io.ErrUnexpectedEOF
+			},
+			imgs:     []image.Image{testimg.Gradient(2, 2)},
+			expected: []string{"RoundTrip image 0 *image.NRGBA (0,0)-(2,2):\ncould not decode image: unexpected EOF\n"},
+		},
+		{
+			name: "cropping decoder",
+			enc:  png.Encode,
+			dec: func(r io.Reader) (image.Image, error) {
+				m, err := png.Decode(r)
+				return imgconv.ToNRGBA(m).SubImage(image.Rect(0, 0, 1, 1)), err
+			},
+			imgs:     []image.Image{testimg.Gradient(2, 2)},
+			expected: []string{"RoundTrip image 0 *image.NRGBA (0,0)-(2,2):\nAssert image:\t different image dimensions: Expected: (0,0)-(2,2) - Actual: (0,0)-(1,1)\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{TB: t}
+			RoundTripTolerant(r, tt.enc, tt.dec, tt.maxDelta, tt.imgs...)
+
+			if len(r.errors) != len(tt.expected) {
+				t.Fatalf("\nExpected failures:\t %q\nActual failures:\t %q\n", tt.expected, r.errors)
+			}
+			for i, e := range tt.expected {
+				//the message ends with the statistics of all differing pixels
+				if !strings.HasPrefix(r.errors[i], e) {
+					t.Errorf("\nExpected failure:\t %q\nActual failure:\t %q\n", e, r.errors[i])
+				}
+			}
+		})
+	}
+}
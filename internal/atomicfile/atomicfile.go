@@ -0,0 +1,43 @@
+// Package atomicfile writes files which are never left partially written.
+package atomicfile
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WriteFile calls write with a buffered writer to a temporary file in the
+// directory of name, and renames the file to name once write and the flush
+// succeeded. On any error the temporary file is removed, and an existing file
+// name is left unchanged. The file gets the permissions 0o644, like a file
+// created by os.Create under the usual umask.
+func WriteFile(name string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	//CreateTemp makes the file readable by its owner only, which is right
+	//for temporary data, but not for the file replacing name
+	err = tmp.Chmod(0o644)
+	if err == nil {
+		w := bufio.NewWriter(tmp)
+		if err = write(w); err == nil {
+			err = w.Flush()
+		}
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package atomicfile
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "out.txt")
+
+	err := WriteFile(name, func(w io.Writer) error {
+		_, err := io.WriteString(w, "data")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WriteFile: unexpected error: %v\n", err)
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil || string(data) != "data" {
+		t.Errorf("file holds %q, error %v, expected \"data\"\n", data, err)
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatalf("could not stat file: %v\n", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o044 != 0o044 {
+		t.Errorf("file mode %v, expected a file readable by everyone\n", info.Mode())
+	}
+	assertOnlyFile(t, dir, "out.txt")
+}
+
+func TestWriteFileError(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "out.txt")
+	if err := os.WriteFile(name, []byte("old"), 0o644); err != nil {
+		t.Fatalf("could not write file: %v\n", err)
+	}
+
+	failure := errors.New("write failed")
+	err := WriteFile(name, func(w io.Writer) error {
+		io.WriteString(w, "partial")
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("WriteFile: %v, expected %v\n", err, failure)
+	}
+
+	//the existing file is kept and the temporary file removed
+	if data, err := os.ReadFile(name); err != nil || string(data) != "old" {
+		t.Errorf("file holds %q, error %v, expected \"old\"\n", data, err)
+	}
+	assertOnlyFile(t, dir, "out.txt")
+}
+
+// assertOnlyFile reports an error if dir holds other files than name.
+func assertOnlyFile(t testing.TB, dir, name string) {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("could not read directory: %v\n", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != name {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("directory holds %v, expected only %s\n", names, name)
+	}
+}
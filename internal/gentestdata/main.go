@@ -0,0 +1,402 @@
+// Command gentestdata writes QOI images and their PNG references which
+// exercise every chunk type of the QOI format together with its boundary
+// values: runs of exactly 62 pixels and back-to-back maximal runs crossing
+// row ends, index hits and hash collisions, and the extremes of the DIFF and
+// LUMA deltas and of alpha changes.
+//
+// Usage:
+//
+//	gentestdata [-dir directory]
+//
+// The images are encoded by a minimal encoder written after the QOI
+// specification, independent of the qoi package, which makes them a cross
+// check for both its decoder and its encoder. Run go generate in this
+// directory to rewrite testdata/qoi/opcodes, where the file tests of the qoi
+// package pick them up.
+package main
+
+//go:generate go run . -dir ../../testdata/qoi/opcodes
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+)
+
+const (
+	qoiMagic      = "qoif"
+	qoiHeaderSize = 14
+	qoiMaxRunSize = 62
+)
+
+var qoiEndMarker = []byte{0, 0, 0, 0, 0, 0, 0, 1}
+
+// the chunk tags, the two bit ones in their upper bits
+const (
+	tagINDEX byte = 0x00
+	tagDIFF  byte = 0x40
+	tagLUMA  byte = 0x80
+	tagRUN   byte = 0xc0
+	tagRGB   byte = 0xfe
+	tagRGBA  byte = 0xff
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run writes the images to the directory given by args and returns the exit status.
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("gentestdata", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	dir := flags.String("dir", ".", "directory the images are written to")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: gentestdata [-dir directory]\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+
+	if err := os.MkdirAll(*dir, 0o777); err != nil {
+		fmt.Fprintf(stderr, "gentestdata: %v\n", err)
+		return 1
+	}
+	for _, s := range scenarios() {
+		if err := write(*dir, s); err != nil {
+			fmt.Fprintf(stderr, "gentestdata: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "%s: %dx%d\n", s.name, s.m.Rect.Dx(), s.m.Rect.Dy())
+	}
+
+	return 0
+}
+
+// write writes the QOI image of s and its PNG reference into dir.
+func write(dir string, s scenario) error {
+	var ref bytes.Buffer
+	if err := png.Encode(&ref, s.m); err != nil {
+		return err
+	}
+
+	base := filepath.Join(dir, s.name)
+	if err := os.WriteFile(base+".qoi", encode(s.m), 0o644); err != nil {
+		return err
+	}
+
+	return os.WriteFile(base+".png", ref.Bytes(), 0o644)
+}
+
+// scenario is an image built to produce the chunk types in chunks.
+type scenario struct {
+	name   string
+	m      *image.NRGBA
+	chunks []byte
+}
+
+// pixels builds the pixel sequence of a scenario.
+type pixels []color.NRGBA
+
+// last returns the previous pixel of the encoder, which starts as opaque black.
+func (p pixels) last() color.NRGBA {
+	if len(p) == 0 {
+		return color.NRGBA{0, 0, 0, 0xff}
+	}
+
+	return p[len(p)-1]
+}
+
+// repeat appends n copies of c.
+func (p pixels) repeat(c color.NRGBA, n int) pixels {
+	for i := 0; i < n; i++ {
+		p = append(p, c)
+	}
+
+	return p
+}
+
+// delta appends the last pixel with the channels changed by dr, dg and db,
+// wrapping around like the QOI deltas do.
+func (p pixels) delta(dr, dg, db int) pixels {
+	c := p.last()
+
+	return append(p, color.NRGBA{c.R + byte(dr), c.G + byte(dg), c.B + byte(db), c.A})
+}
+
+// image returns the pixels as an image of the given width, repeating the
+// last pixel to fill the last row.
+func (p pixels) image(width int) *image.NRGBA {
+	if n := len(p) % width; n != 0 {
+		p = p.repeat(p.last(), width-n)
+	}
+
+	m := image.NewNRGBA(image.Rect(0, 0, width, len(p)/width))
+	for i, c := range p {
+		m.Pix[4*i], m.Pix[4*i+1], m.Pix[4*i+2], m.Pix[4*i+3] = c.R, c.G, c.B, c.A
+	}
+
+	return m
+}
+
+// scenarios returns the images written by gentestdata.
+func scenarios() []scenario {
+	return []scenario{
+		{name: "op_run", m: runPixels().image(50), chunks: []byte{tagRUN, tagRGB}},
+		{name: "op_index", m: indexPixels().image(7), chunks: []byte{tagINDEX, tagRGB}},
+		{name: "op_diff", m: diffPixels().image(16), chunks: []byte{tagDIFF}},
+		{name: "op_luma", m: lumaPixels().image(16), chunks: []byte{tagLUMA}},
+		{name: "op_rgb", m: rgbPixels().image(4), chunks: []byte{tagRGB}},
+		{name: "op_rgba", m: rgbaPixels().image(5), chunks: []byte{tagRGBA, tagINDEX}},
+		{name: "op_mixed", m: mixedPixels().image(37), chunks: []byte{tagINDEX, tagDIFF, tagLUMA, tagRUN, tagRGB, tagRGBA}},
+	}
+}
+
+// runPixels starts with a run of the initial previous pixel, followed by runs
+// of exactly 62, 63 and 124 pixels, i.e. back-to-back maximal runs, and a run
+// ending at the last pixel. A row holds 50 pixels, so most runs cross row ends.
+func runPixels() pixels {
+	var p pixels
+	p = p.repeat(color.NRGBA{0, 0, 0, 0xff}, 5)
+	p = p.repeat(color.NRGBA{0x80, 0x10, 0xf0, 0xff}, 1+62)
+	p = p.repeat(color.NRGBA{0x10, 0xf0, 0x80, 0xff}, 1+63)
+	p = p.repeat(color.NRGBA{0xf0, 0x80, 0x10, 0xff}, 1+124)
+	p = p.repeat(color.NRGBA{0x40, 0xc0, 0x40, 0xff}, 1+61)
+	p = p.repeat(color.NRGBA{0xc0, 0x40, 0xc0, 0xff}, 1+2*qoiMaxRunSize)
+
+	return p
+}
+
+// indexPixels starts with transparent black, which hits the zeroed index, and
+// revisits colors from the index directly and after a run. a and b share the
+// same hash, so alternating them evicts each other and never hits the index.
+func indexPixels() pixels {
+	a := color.NRGBA{10, 0, 0, 0xff}
+	b := color.NRGBA{74, 0, 0, 0xff}
+	c := color.NRGBA{0x30, 0xa0, 0x60, 0xff}
+	d := color.NRGBA{0xe0, 0x20, 0x90, 0xff}
+
+	p := pixels{{0, 0, 0, 0}}
+	p = append(p, c, d, c, d, c)
+	p = p.repeat(d, 5)
+	p = append(p, c, a, b, a, b, a, b, c, a, d, b)
+
+	return p
+}
+
+// diffPixels applies every combination of the DIFF deltas -2 to 1, starting
+// at black, so the first negative deltas wrap around.
+func diffPixels() pixels {
+	var p pixels
+	for dr := -2; dr <= 1; dr++ {
+		for dg := -2; dg <= 1; dg++ {
+			for db := -2; db <= 1; db++ {
+				p = p.delta(dr, dg, db)
+			}
+		}
+	}
+
+	return p
+}
+
+// lumaPixels applies the extremes of the LUMA deltas: a green delta of -32
+// and 31, and red and blue deltas of -8 and 7 relative to it, as well as
+// green deltas just outside of the DIFF range.
+func lumaPixels() pixels {
+	var p pixels
+	for _, dg := range []int{-32, 31, -3, 2, 0} {
+		for _, drg := range []int{-8, 7} {
+			for _, dbg := range []int{-8, 7} {
+				p = p.delta(dg+drg, dg, dg+dbg)
+			}
+		}
+	}
+
+	return p
+}
+
+// rgbPixels applies deltas just outside of the LUMA range, which need a full
+// RGB chunk.
+func rgbPixels() pixels {
+	var p pixels
+	p = p.delta(0, 32, 0)
+	p = p.delta(0, -33, 0)
+	p = p.delta(8, 0, 0)
+	p = p.delta(0, 0, -9)
+	p = p.delta(-9, 0, 7)
+	p = p.delta(100, -100, 50)
+	p = p.delta(128, 128, 128)
+
+	return p
+}
+
+// rgbaPixels changes the alpha by the smallest and the largest steps, with
+// and without changing the color, and revisits transparent pixels.
+func rgbaPixels() pixels {
+	p := pixels{{0, 0, 0, 0xfe}}
+	p = append(p, color.NRGBA{0, 0, 0, 0})
+	p = append(p, color.NRGBA{0, 0, 0, 0xff})
+	p = append(p, color.NRGBA{1, 2, 3, 1})
+	p = append(p, color.NRGBA{0xff, 0xff, 0xff, 0})
+	p = append(p, color.NRGBA{0xff, 0xff, 0xff, 0x80})
+	p = append(p, color.NRGBA{0, 0, 0, 0})
+	p = append(p, color.NRGBA{0xff, 0xff, 0xff, 0x80})
+	p = p.delta(1, 1, 1)
+	p = append(p, color.NRGBA{0xff, 0xff, 0xff, 0})
+
+	return p
+}
+
+// mixedPixels is a deterministic random walk of small steps, alpha changes,
+// revisits and runs, which mixes all chunk types across row ends.
+func mixedPixels() pixels {
+	rnd := rand.New(rand.NewSource(434))
+
+	var p pixels
+	for len(p) < 37*23 {
+		c := p.last()
+		switch n := rnd.Intn(20); {
+		case n < 5:
+			p = p.delta(rnd.Intn(4)-2, rnd.Intn(4)-2, rnd.Intn(4)-2)
+		case n < 10:
+			dg := rnd.Intn(64) - 32
+			p = p.delta(dg+rnd.Intn(16)-8, dg, dg+rnd.Intn(16)-8)
+		case n < 12:
+			p = append(p, color.NRGBA{byte(rnd.Intn(256)), byte(rnd.Intn(256)), byte(rnd.Intn(256)), c.A})
+		case n < 14:
+			c.A = byte(rnd.Intn(256))
+			p = append(p, c)
+		case n < 16:
+			p = p.repeat(c, rnd.Intn(4))
+		case n < 17:
+			p = p.repeat(c, rnd.Intn(2*qoiMaxRunSize+10))
+		default:
+			//revisit one of the recent colors
+			if len(p) > 0 {
+				p = append(p, p[rnd.Intn(len(p))])
+			}
+		}
+	}
+
+	return p[:37*23]
+}
+
+// hash returns the index position of c.
+func hash(c color.NRGBA) byte {
+	return (c.R*3 + c.G*5 + c.B*7 + c.A*11) % 64
+}
+
+// encode returns the QOI stream of m, encoded like the reference encoder of
+// the specification does.
+func encode(m *image.NRGBA) []byte {
+	w, h := m.Rect.Dx(), m.Rect.Dy()
+
+	out := make([]byte, qoiHeaderSize, qoiHeaderSize+5*w*h+len(qoiEndMarker))
+	copy(out, qoiMagic)
+	binary.BigEndian.PutUint32(out[4:], uint32(w))
+	binary.BigEndian.PutUint32(out[8:], uint32(h))
+	out[12], out[13] = 4, 0
+
+	var index [64]color.NRGBA
+	prev := color.NRGBA{0, 0, 0, 0xff}
+	run := 0
+	for i := 0; i < w*h; i++ {
+		px := color.NRGBA{m.Pix[4*i], m.Pix[4*i+1], m.Pix[4*i+2], m.Pix[4*i+3]}
+
+		if px == prev {
+			run++
+			if run == qoiMaxRunSize || i == w*h-1 {
+				out = append(out, tagRUN|byte(run-1))
+				run = 0
+			}
+			continue
+		}
+		if run > 0 {
+			out = append(out, tagRUN|byte(run-1))
+			run = 0
+		}
+
+		pos := hash(px)
+		switch {
+		case index[pos] == px:
+			out = append(out, tagINDEX|pos)
+		case px.A != prev.A:
+			out = append(out, tagRGBA, px.R, px.G, px.B, px.A)
+		default:
+			vr := int(int8(px.R - prev.R))
+			vg := int(int8(px.G - prev.G))
+			vb := int(int8(px.B - prev.B))
+			vgr, vgb := vr-vg, vb-vg
+
+			switch {
+			case vr >= -2 && vr <= 1 && vg >= -2 && vg <= 1 && vb >= -2 && vb <= 1:
+				out = append(out, tagDIFF|byte(vr+2)<<4|byte(vg+2)<<2|byte(vb+2))
+			case vg >= -32 && vg <= 31 && vgr >= -8 && vgr <= 7 && vgb >= -8 && vgb <= 7:
+				out = append(out, tagLUMA|byte(vg+32), byte(vgr+8)<<4|byte(vgb+8))
+			default:
+				out = append(out, tagRGB, px.R, px.G, px.B)
+			}
+		}
+		index[pos] = px
+		prev = px
+	}
+
+	return append(out, qoiEndMarker...)
+}
+
+// forEachChunk calls fn with the tag and the bytes of every chunk of the QOI
+// stream data, in order. It returns an error if the stream is malformed.
+func forEachChunk(data []byte, fn func(tag byte, chunk []byte)) error {
+	if len(data) < qoiHeaderSize+len(qoiEndMarker) || string(data[:4]) != qoiMagic {
+		return fmt.Errorf("invalid qoi header")
+	}
+	total := int64(binary.BigEndian.Uint32(data[4:])) * int64(binary.BigEndian.Uint32(data[8:]))
+
+	chunks := data[qoiHeaderSize : len(data)-len(qoiEndMarker)]
+	for n := int64(0); n < total; {
+		if len(chunks) == 0 {
+			return fmt.Errorf("truncated qoi data after %d of %d pixels", n, total)
+		}
+
+		tag, size := chunks[0], 1
+		switch {
+		case tag == tagRGB:
+			size = 4
+		case tag == tagRGBA:
+			size = 5
+		case tag&0xc0 == tagLUMA:
+			tag, size = tagLUMA, 2
+		case tag&0xc0 == tagRUN:
+			n += int64(tag & 0x3f)
+			tag = tagRUN
+		default:
+			tag &= 0xc0
+		}
+		if len(chunks) < size {
+			return fmt.Errorf("truncated qoi chunk after %d of %d pixels", n, total)
+		}
+
+		fn(tag, chunks[:size])
+		chunks = chunks[size:]
+		n++
+	}
+
+	if len(chunks) != 0 || !bytes.Equal(data[len(data)-len(qoiEndMarker):], qoiEndMarker) {
+		return fmt.Errorf("invalid qoi end marker")
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LukiDS/image/imgtest"
+)
+
+// tagNames are the names of the chunk tags, as reported by forEachChunk.
+var tagNames = map[byte]string{
+	tagINDEX: "INDEX",
+	tagDIFF:  "DIFF",
+	tagLUMA:  "LUMA",
+	tagRUN:   "RUN",
+	tagRGB:   "RGB",
+	tagRGBA:  "RGBA",
+}
+
+// chunkCounts returns the number of chunks of each tag in the QOI stream data.
+func chunkCounts(t *testing.T, data []byte) map[byte]int {
+	counts := make(map[byte]int)
+	err := forEachChunk(data, func(tag byte, chunk []byte) {
+		counts[tag]++
+	})
+	if err != nil {
+		t.Fatalf("could not scan chunks: %v\n", err)
+	}
+
+	return counts
+}
+
+func TestScenarioChunks(t *testing.T) {
+	all := make(map[byte]int)
+
+	for _, s := range scenarios() {
+		t.Run(s.name, func(t *testing.T) {
+			counts := chunkCounts(t, encode(s.m))
+			for _, tag := range s.chunks {
+				if counts[tag] == 0 {
+					t.Errorf("\nScenario:\t %s\nExpected chunk:\t %s\nActual chunks:\t %v\n", s.name, tagNames[tag], counts)
+				}
+			}
+			for tag, n := range counts {
+				all[tag] += n
+			}
+		})
+	}
+
+	//the corpus must cover every chunk type
+	for tag, name := range tagNames {
+		if all[tag] == 0 {
+			t.Errorf("no %s chunk in any scenario\n", name)
+		}
+	}
+}
+
+func TestRunBoundaries(t *testing.T) {
+	var runs []int
+	err := forEachChunk(encode(runPixels().image(50)), func(tag byte, chunk []byte) {
+		if tag == tagRUN {
+			runs = append(runs, int(chunk[0]&0x3f)+1)
+		}
+	})
+	if err != nil {
+		t.Fatalf("could not scan chunks: %v\n", err)
+	}
+
+	expected := []int{5, 62, 62, 1, 62, 62, 61, 62, 62, 6}
+	if fmt.Sprint(runs) != fmt.Sprint(expected) {
+		t.Errorf("\nExpected runs:\t %v\nActual runs:\t %v\n", expected, runs)
+	}
+}
+
+func TestForEachChunkErrors(t *testing.T) {
+	valid := encode(diffPixels().image(16))
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "invalid magic", data: append([]byte("qoix"), valid[4:]...)},
+		{name: "truncated", data: append(append([]byte(nil), valid[:len(valid)/2]...), qoiEndMarker...)},
+		{name: "trailing chunks", data: append(append([]byte(nil), valid[:len(valid)-8]...), append([]byte{tagRUN}, qoiEndMarker...)...)},
+		{name: "invalid end marker", data: append(append([]byte(nil), valid[:len(valid)-1]...), 2)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := forEachChunk(tt.data, func(byte, []byte) {}); err == nil {
+				t.Errorf("forEachChunk(%s): expected an error\n", tt.name)
+			}
+		})
+	}
+}
+
+// TestTestdataUpToDate checks that go generate has been run after changing
+// the scenarios.
+func TestTestdataUpToDate(t *testing.T) {
+	dir := "../../testdata/qoi/opcodes"
+
+	for _, s := range scenarios() {
+		t.Run(s.name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join(dir, s.name+".qoi"))
+			if err != nil {
+				t.Fatalf("could not read file: %v\n", err)
+			}
+			if !bytes.Equal(data, encode(s.m)) {
+				t.Errorf("%s.qoi is out of date, run go generate\n", s.name)
+			}
+
+			f, err := os.Open(filepath.Join(dir, s.name+".png"))
+			if err != nil {
+				t.Fatalf("could not read file: %v\n", err)
+			}
+			defer f.Close()
+
+			ref, err := png.Decode(f)
+			if err != nil {
+				t.Fatalf("could not decode file: %v\n", err)
+			}
+			imgtest.AssertEqual(t, s.m, ref)
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	var stdout, stderr bytes.Buffer
+
+	if status := run([]string{"-dir", dir}, &stdout, &stderr); status != 0 {
+		t.Fatalf("run: exit status %d: %s\n", status, stderr.String())
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatalf("could not find files: %v\n", err)
+	}
+	if len(files) != 2*len(scenarios()) {
+		t.Errorf("run wrote %d files, expected %d\n", len(files), 2*len(scenarios()))
+	}
+
+	if status := run([]string{"extra"}, &stdout, &stderr); status != 2 {
+		t.Errorf("run(extra): exit status %d, expected 2\n", status)
+	}
+}
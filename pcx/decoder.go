@@ -0,0 +1,255 @@
+package pcx
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+type pcxHeader struct {
+	version      int
+	encoding     int
+	bpp          int
+	planes       int
+	width        int
+	height       int
+	bytesPerLine int
+}
+
+func (h pcxHeader) paletted() bool {
+	return h.bpp == 8 && h.planes == 1
+}
+
+type decoder struct {
+	m   image.Image
+	buf *bufio.Reader
+	h   pcxHeader
+	// run holds the remaining length and the value of a run continuing on
+	// the next scanline, which some encoders produce.
+	run   int
+	value byte
+	err   error
+}
+
+func (d *decoder) decodeHeader() {
+	h := make([]byte, pcxHeaderSize)
+	if _, err := io.ReadFull(d.buf, h); err != nil || h[0] != pcxManufacturer {
+		d.err = fmt.Errorf("image not valid pcx file")
+		return
+	}
+
+	d.h.version = int(h[1])
+	d.h.encoding = int(h[2])
+	d.h.bpp = int(h[3])
+	xMin := int(binary.LittleEndian.Uint16(h[4:6]))
+	yMin := int(binary.LittleEndian.Uint16(h[6:8]))
+	xMax := int(binary.LittleEndian.Uint16(h[8:10]))
+	yMax := int(binary.LittleEndian.Uint16(h[10:12]))
+	d.h.planes = int(h[65])
+	d.h.bytesPerLine = int(binary.LittleEndian.Uint16(h[66:68]))
+
+	switch d.h.version {
+	case 0, 2, 3, 4, 5:
+	default:
+		d.err = fmt.Errorf("image not valid pcx file: version %d", d.h.version)
+		return
+	}
+
+	if d.h.encoding != encodingRLE && d.h.encoding != encodingNone {
+		d.err = fmt.Errorf("image not valid pcx file: encoding %d", d.h.encoding)
+		return
+	}
+
+	switch {
+	case d.h.bpp == 8 && (d.h.planes == 1 || d.h.planes == 3):
+	case d.h.bpp == 1 && d.h.planes == 1:
+	default:
+		d.err = fmt.Errorf("unsupported pcx format: %d bits per pixel in %d planes", d.h.bpp, d.h.planes)
+		return
+	}
+
+	d.h.width, d.h.height = xMax-xMin+1, yMax-yMin+1
+	if d.h.width <= 0 || d.h.height <= 0 || d.h.width > pcxMaxPixels/d.h.height {
+		d.err = fmt.Errorf("invalid pcx image size: %dx%d, must be non-empty and at most %d pixels", d.h.width, d.h.height, pcxMaxPixels)
+		return
+	}
+
+	//scanlines may be longer than the visible width, but not shorter
+	if d.h.bytesPerLine < (d.h.width*d.h.bpp+7)/8 {
+		d.err = fmt.Errorf("invalid pcx header: %d bytes per line for a width of %d", d.h.bytesPerLine, d.h.width)
+		return
+	}
+}
+
+// readPalette reads the rest of the file, which ends with the marker of the
+// 256 color palette and the palette itself.
+func (d *decoder) readPalette() color.Palette {
+	rest, err := io.ReadAll(d.buf)
+	if err != nil {
+		d.err = err
+		return nil
+	}
+	if len(rest) < paletteSize+1 || rest[len(rest)-paletteSize-1] != paletteMarker {
+		d.err = fmt.Errorf("invalid pcx file: missing 256 color palette")
+		return nil
+	}
+
+	rest = rest[len(rest)-paletteSize:]
+	palette := make(color.Palette, 256)
+	for i := range palette {
+		palette[i] = color.RGBA{rest[3*i], rest[3*i+1], rest[3*i+2], 0xff}
+	}
+
+	return palette
+}
+
+func (d *decoder) decode() {
+	if d.err != nil {
+		return
+	}
+
+	r := image.Rect(0, 0, d.h.width, d.h.height)
+	line := make([]byte, d.h.bytesPerLine*d.h.planes)
+	bpl := d.h.bytesPerLine
+
+	switch {
+	case d.h.paletted():
+		m := image.NewPaletted(r, nil)
+		for y := 0; y < d.h.height; y++ {
+			if d.readScanline(line); d.err != nil {
+				return
+			}
+			copy(m.Pix[y*m.Stride:], line[:d.h.width])
+		}
+		if m.Palette = d.readPalette(); d.err != nil {
+			return
+		}
+		d.m = m
+
+	case d.h.planes == 3:
+		m := image.NewNRGBA(r)
+		for y := 0; y < d.h.height; y++ {
+			if d.readScanline(line); d.err != nil {
+				return
+			}
+
+			i := y * m.Stride
+			for x := 0; x < d.h.width; x, i = x+1, i+4 {
+				p := m.Pix[i : i+4 : i+4]
+				p[0], p[1], p[2], p[3] = line[x], line[bpl+x], line[2*bpl+x], 0xff
+			}
+		}
+		d.m = m
+
+	default:
+		m := image.NewGray(r)
+		for y := 0; y < d.h.height; y++ {
+			if d.readScanline(line); d.err != nil {
+				return
+			}
+
+			i := y * m.Stride
+			for x := 0; x < d.h.width; x, i = x+1, i+1 {
+				if line[x/8]&(0x80>>(x%8)) != 0 {
+					m.Pix[i] = 0xff
+				}
+			}
+		}
+		d.m = m
+	}
+}
+
+// readScanline reads all planes of one scanline into line. In run-length
+// encoded files, bytes with the two high bits set repeat the next byte as
+// often as their lower six bits say; all other bytes are literals.
+func (d *decoder) readScanline(line []byte) {
+	if d.h.encoding == encodingNone {
+		if _, err := io.ReadFull(d.buf, line); err != nil {
+			d.err = fmt.Errorf("truncated pcx pixel data: %w", io.ErrUnexpectedEOF)
+		}
+		return
+	}
+
+	for i := 0; i < len(line); {
+		if d.run == 0 {
+			c, err := d.buf.ReadByte()
+			if err != nil {
+				d.err = fmt.Errorf("truncated pcx pixel data: %w", io.ErrUnexpectedEOF)
+				return
+			}
+
+			d.run, d.value = 1, c
+			if c&0xc0 == 0xc0 {
+				if d.value, err = d.buf.ReadByte(); err != nil {
+					d.err = fmt.Errorf("truncated pcx pixel data: %w", io.ErrUnexpectedEOF)
+					return
+				}
+				d.run = int(c & 0x3f)
+			}
+		}
+
+		n := d.run
+		if n > len(line)-i {
+			n = len(line) - i
+		}
+		for j := i; j < i+n; j++ {
+			line[j] = d.value
+		}
+		i += n
+		d.run -= n
+	}
+}
+
+// DecodeConfig returns the color model and dimensions of a PCX image without
+// decoding the pixels. The palette of 8 bit paletted images is at the end of
+// the file, so the whole file is read for them.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	d := decoder{
+		buf: bufio.NewReader(r),
+	}
+
+	d.decodeHeader()
+	if d.err != nil {
+		return image.Config{}, d.err
+	}
+
+	var model color.Model
+	switch {
+	case d.h.paletted():
+		model = d.readPalette()
+	case d.h.planes == 3:
+		model = color.NRGBAModel
+	default:
+		model = color.GrayModel
+	}
+	if d.err != nil {
+		return image.Config{}, d.err
+	}
+
+	return image.Config{
+		ColorModel: model,
+		Width:      d.h.width,
+		Height:     d.h.height,
+	}, nil
+}
+
+// Decode reads a PCX image from r and returns it as an *image.Paletted image
+// for 8 bit paletted files, as an *image.NRGBA image for 24 bit files and as
+// an *image.Gray image for 1 bit files.
+func Decode(r io.Reader) (image.Image, error) {
+	d := decoder{
+		buf: bufio.NewReader(r),
+	}
+
+	d.decodeHeader()
+	d.decode()
+
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	return d.m, nil
+}
@@ -0,0 +1,253 @@
+package pcx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/LukiDS/image/imgtest"
+)
+
+// pcxFile describes a PCX file for generatePCX. A zero version, encoding or
+// planes value is replaced by 5, RLE or 1; raw selects uncompressed pixels.
+type pcxFile struct {
+	manufacturer byte
+	version      byte
+	encoding     byte
+	raw          bool
+	bpp          byte
+	planes       byte
+	width        int
+	height       int
+	bytesPerLine int
+	pixels       []byte
+}
+
+// generatePCX writes the header of f followed by its pixels.
+func generatePCX(t testing.TB, f pcxFile) []byte {
+	t.Helper()
+
+	if f.manufacturer == 0 {
+		f.manufacturer = pcxManufacturer
+	}
+	if f.version == 0 {
+		f.version = 5
+	}
+	if f.encoding == 0 && !f.raw {
+		f.encoding = encodingRLE
+	}
+	if f.planes == 0 {
+		f.planes = 1
+	}
+
+	h := make([]byte, pcxHeaderSize)
+	h[0], h[1], h[2], h[3] = f.manufacturer, f.version, f.encoding, f.bpp
+	binary.LittleEndian.PutUint16(h[4:], 5)
+	binary.LittleEndian.PutUint16(h[6:], 7)
+	binary.LittleEndian.PutUint16(h[8:], uint16(5+f.width-1))
+	binary.LittleEndian.PutUint16(h[10:], uint16(7+f.height-1))
+	h[65] = f.planes
+	binary.LittleEndian.PutUint16(h[66:], uint16(f.bytesPerLine))
+
+	return append(h, f.pixels...)
+}
+
+func TestDecodeWithTestFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected image.Image
+	}{
+		{name: "mono1.pcx", expected: &image.Gray{}},
+		{name: "pal8.pcx", expected: &image.Paletted{}},
+		{name: "rgb24.pcx", expected: &image.NRGBA{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			name := filepath.Join("../testdata/pcx", test.name)
+			pcxFile, err := os.Open(name)
+			if err != nil {
+				t.Fatalf("could not read file: %v\n", err)
+			}
+			defer pcxFile.Close()
+
+			img, err := Decode(pcxFile)
+			if err != nil {
+				t.Fatalf("could not decode file: %v\n", err)
+			}
+
+			pngFile, err := os.Open(strings.TrimSuffix(name, filepath.Ext(name)) + ".png")
+			if err != nil {
+				t.Fatalf("could not read file: %v\n", err)
+			}
+			defer pngFile.Close()
+
+			ref, err := png.Decode(pngFile)
+			if err != nil {
+				t.Fatalf("could not decode file: %v\n", err)
+			}
+
+			if fmt.Sprintf("%T", img) != fmt.Sprintf("%T", test.expected) {
+				t.Fatalf("File %s: expected an %T image, got %T\n", name, test.expected, img)
+			}
+			imgtest.AssertEqual(t, ref, img)
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     pcxFile
+		expected []uint8 //gray values
+		size     image.Point
+	}{
+		{
+			name: "should skip the padding of 1 bit scanlines",
+			file: pcxFile{bpp: 1, width: 3, height: 2, bytesPerLine: 2, pixels: []byte{
+				0xc1, 0xa0, 0xc1, 0xff,
+				0x40, 0xc1, 0xff,
+			}},
+			expected: []uint8{0xff, 0, 0xff, 0, 0xff, 0},
+			size:     image.Pt(3, 2),
+		},
+		{
+			name: "should continue runs on the next scanline",
+			file: pcxFile{bpp: 1, width: 8, height: 3, bytesPerLine: 1, pixels: []byte{
+				0xc2, 0xf0, 0x0f,
+			}},
+			expected: []uint8{
+				0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0,
+				0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0,
+				0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff,
+			},
+			size: image.Pt(8, 3),
+		},
+		{
+			name: "should decode uncompressed scanlines",
+			file: pcxFile{raw: true, bpp: 1, width: 2, height: 2, bytesPerLine: 2, pixels: []byte{
+				0xc0, 0xff, 0x40, 0x00,
+			}},
+			expected: []uint8{0xff, 0xff, 0, 0xff},
+			size:     image.Pt(2, 2),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := Decode(bytes.NewReader(generatePCX(t, test.file)))
+			if err != nil {
+				t.Fatalf("Decode: unexpected error: %v\n", err)
+			}
+
+			expected := &image.Gray{Pix: test.expected, Stride: test.size.X, Rect: image.Rectangle{Max: test.size}}
+			imgtest.AssertEqual(t, expected, actual)
+		})
+	}
+}
+
+func TestDecodeRGB(t *testing.T) {
+	//two pixels in planes of 4 bytes per line
+	data := generatePCX(t, pcxFile{bpp: 8, planes: 3, width: 2, height: 1, bytesPerLine: 4, pixels: []byte{
+		0x10, 0x20, 0xc2, 0xee,
+		0xc4, 0x30,
+		0x40, 0x50, 0x00, 0x00,
+	}})
+
+	actual, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Decode: unexpected error: %v\n", err)
+	}
+
+	expected := &image.NRGBA{Pix: []byte{0x10, 0x30, 0x40, 0xff, 0x20, 0x30, 0x50, 0xff}, Stride: 8, Rect: image.Rect(0, 0, 2, 1)}
+	imgtest.AssertEqual(t, expected, actual)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	pixels := []byte{0, 0, 0, 0}
+
+	tests := []struct {
+		name  string
+		data  []byte
+		error string
+	}{
+		{name: "empty file", data: nil, error: "not valid pcx"},
+		{name: "truncated header", data: generatePCX(t, pcxFile{bpp: 8, width: 1, height: 1, bytesPerLine: 2})[:100], error: "not valid pcx"},
+		{name: "wrong manufacturer", data: generatePCX(t, pcxFile{manufacturer: 0x0b, bpp: 8, width: 1, height: 1, bytesPerLine: 2, pixels: pixels}), error: "not valid pcx"},
+		{name: "unknown version", data: generatePCX(t, pcxFile{version: 6, bpp: 8, width: 1, height: 1, bytesPerLine: 2, pixels: pixels}), error: "version 6"},
+		{name: "unknown encoding", data: generatePCX(t, pcxFile{encoding: 2, bpp: 8, width: 1, height: 1, bytesPerLine: 2, pixels: pixels}), error: "encoding 2"},
+		{name: "4 bit EGA", data: generatePCX(t, pcxFile{bpp: 1, planes: 4, width: 1, height: 1, bytesPerLine: 2, pixels: pixels}), error: "unsupported pcx format"},
+		{name: "2 bits per pixel", data: generatePCX(t, pcxFile{bpp: 2, width: 1, height: 1, bytesPerLine: 2, pixels: pixels}), error: "unsupported pcx format"},
+		{name: "negative width", data: generatePCX(t, pcxFile{bpp: 8, width: -1, height: 1, bytesPerLine: 2, pixels: pixels}), error: "image size"},
+		{name: "absurd size", data: generatePCX(t, pcxFile{bpp: 8, width: 65535, height: 65535, bytesPerLine: 65535, pixels: pixels}), error: "image size"},
+		{name: "short scanlines", data: generatePCX(t, pcxFile{bpp: 8, width: 4, height: 1, bytesPerLine: 3, pixels: pixels}), error: "bytes per line"},
+		{name: "truncated run", data: generatePCX(t, pcxFile{bpp: 1, width: 8, height: 2, bytesPerLine: 1, pixels: []byte{0x0f, 0xc1}}), error: "truncated"},
+		{name: "truncated scanlines", data: generatePCX(t, pcxFile{bpp: 1, width: 8, height: 2, bytesPerLine: 1, pixels: []byte{0xff}}), error: "truncated"},
+		{name: "missing palette", data: generatePCX(t, pcxFile{bpp: 8, width: 2, height: 1, bytesPerLine: 2, pixels: pixels}), error: "palette"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Decode(bytes.NewReader(test.data))
+			if err == nil || !strings.Contains(err.Error(), test.error) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Decode(%s)\n", test.name) +
+					fmt.Sprintf("Expected error:\t containing %q\n", test.error) +
+					fmt.Sprintf("Actual error:\t %v\n", err)
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+func TestDecodeConfig(t *testing.T) {
+	data := generatePCX(t, pcxFile{bpp: 8, planes: 3, width: 640, height: 480, bytesPerLine: 640})
+	actual, err := DecodeConfig(bytes.NewReader(data))
+	expected := image.Config{ColorModel: color.NRGBAModel, Width: 640, Height: 480}
+	if err != nil || actual != expected {
+		t.Errorf("DecodeConfig() = (%+v, %v), expected %+v\n", actual, err, expected)
+	}
+
+	data, err = os.ReadFile("../testdata/pcx/pal8.pcx")
+	if err != nil {
+		t.Fatalf("could not read file: %v\n", err)
+	}
+	actual, err = DecodeConfig(bytes.NewReader(data))
+	palette, ok := actual.ColorModel.(color.Palette)
+	if err != nil || !ok || len(palette) != 256 || palette[1] != (color.RGBA{1, 254, 7, 0xff}) || actual.Width != 21 || actual.Height != 15 {
+		t.Errorf("DecodeConfig(pal8.pcx) = (%+v, %v), expected a 21x15 image with a 256 color palette\n", actual, err)
+	}
+
+	imgtest.RequireFormat(t, bytes.NewReader(data), "pcx", image.Rect(0, 0, 21, 15))
+
+	//uncompressed files are detected as well
+	data = generatePCX(t, pcxFile{raw: true, bpp: 1, width: 2, height: 1, bytesPerLine: 2, pixels: []byte{0x40, 0x00}})
+	m := imgtest.RequireFormat(t, bytes.NewReader(data), "pcx", image.Rect(0, 0, 2, 1))
+	imgtest.AssertEqual(t, &image.Gray{Pix: []uint8{0, 0xff}, Stride: 2, Rect: image.Rect(0, 0, 2, 1)}, m)
+}
+
+func TestDecodeCorruptFiles(t *testing.T) {
+	imgtest.DecodeCorrupt(t, "../testdata/pcx/*.pcx", 2000, 0, Decode)
+}
+
+func BenchmarkDecode(b *testing.B) {
+	data, err := os.ReadFile("../testdata/pcx/rgb24.pcx")
+	if err != nil {
+		b.Fatalf("could not read file: %v\n", err)
+	}
+
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Decode(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+// Package pcx implements a decoder for ZSoft PCX images.
+//
+// Run-length encoded and uncompressed files with 8 bit paletted pixels
+// (256 colors in the palette at the end of the file), 24 bit pixels (8 bits
+// in each of three color planes) and 1 bit pixels are supported. They are
+// decoded into *image.Paletted, *image.NRGBA and *image.Gray images, where
+// 1 bit pixels become 0 for black and 255 for white.
+package pcx
+
+import (
+	"image"
+)
+
+const (
+	// pcxMaxPixels guards against allocating huge images for corrupt headers.
+	pcxMaxPixels = 400_000_000
+
+	pcxManufacturer = 0x0a
+	pcxHeaderSize   = 128 //size in bytes
+
+	// paletteSize is the size of the 256 color palette following the marker
+	// at the end of the file.
+	paletteSize   = 3 * 256
+	paletteMarker = 0x0c
+)
+
+// encodings
+const (
+	encodingNone = 0
+	encodingRLE  = 1
+)
+
+func init() {
+	//the version byte is 0, 2, 3, 4 or 5, followed by the encoding
+	for _, version := range []byte{0, 2, 3, 4, 5} {
+		for _, encoding := range []byte{encodingNone, encodingRLE} {
+			image.RegisterFormat("pcx", string([]byte{pcxManufacturer, version, encoding}), Decode, DecodeConfig)
+		}
+	}
+}
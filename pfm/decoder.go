@@ -0,0 +1,224 @@
+package pfm
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+)
+
+type pfmHeader struct {
+	channels int
+	width    int
+	height   int
+	order    binary.ByteOrder
+}
+
+func (h pfmHeader) colorModel() color.Model {
+	if h.channels == 1 {
+		return color.Gray16Model
+	}
+
+	return color.NRGBA64Model
+}
+
+type decoder struct {
+	m   image.Image
+	buf *bufio.Reader
+	h   pfmHeader
+	err error
+}
+
+func (d *decoder) decodeHeader() {
+	magic := make([]byte, 2)
+	if _, err := io.ReadFull(d.buf, magic); err != nil {
+		d.err = fmt.Errorf("image not valid pfm file")
+		return
+	}
+
+	switch string(magic) {
+	case "PF":
+		d.h.channels = 3
+	case "Pf":
+		d.h.channels = 1
+	default:
+		d.err = fmt.Errorf("image not valid pfm file")
+		return
+	}
+
+	width, height := d.readToken("width"), d.readToken("height")
+	scale := d.readToken("scale")
+	if d.err != nil {
+		return
+	}
+
+	var err error
+	if d.h.width, err = strconv.Atoi(width); err != nil {
+		d.err = fmt.Errorf("invalid pfm header: width %q", width)
+		return
+	}
+	if d.h.height, err = strconv.Atoi(height); err != nil {
+		d.err = fmt.Errorf("invalid pfm header: height %q", height)
+		return
+	}
+
+	s, err := strconv.ParseFloat(scale, 64)
+	switch {
+	case err != nil || s == 0 || math.IsNaN(s):
+		d.err = fmt.Errorf("invalid pfm header: scale %q", scale)
+		return
+	case s < 0:
+		d.h.order = binary.LittleEndian
+	default:
+		d.h.order = binary.BigEndian
+	}
+
+	if d.h.width <= 0 || d.h.height <= 0 || d.h.width > pfmMaxPixels/d.h.height {
+		d.err = fmt.Errorf("invalid pfm image size: %dx%d, must be non-empty and at most %d pixels", d.h.width, d.h.height, pfmMaxPixels)
+		return
+	}
+}
+
+// readToken returns the next whitespace separated header token. The single
+// whitespace character terminating the token is consumed, which ends the
+// header after the scale.
+func (d *decoder) readToken(name string) string {
+	if d.err != nil {
+		return ""
+	}
+
+	var token []byte
+	for {
+		c, err := d.buf.ReadByte()
+		switch {
+		case err != nil:
+			d.err = fmt.Errorf("invalid pfm header: missing %s", name)
+			return ""
+		case isSpace(c) && len(token) > 0:
+			return string(token)
+		case isSpace(c):
+		case len(token) == maxTokenLength:
+			d.err = fmt.Errorf("invalid pfm header: %s too long", name)
+			return ""
+		default:
+			token = append(token, c)
+		}
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\v' || c == '\f' || c == '\r'
+}
+
+func (d *decoder) decode() {
+	if d.err != nil {
+		return
+	}
+
+	//the rows are appended as they are stored, bottom-up, to a buffer which
+	//grows with the decoded data
+	size := 2 //bytes per image pixel
+	if d.h.channels == 3 {
+		size = 8
+	}
+	capacity, chunk := d.h.width*d.h.height, d.h.width
+	if capacity > pfmInitialPixels {
+		capacity = pfmInitialPixels
+	}
+	if chunk > pfmInitialPixels {
+		chunk = pfmInitialPixels
+	}
+	pix := make([]byte, 0, size*capacity)
+	buf := make([]byte, 4*d.h.channels*chunk)
+	for y := 0; y < d.h.height; y++ {
+		for x := 0; x < d.h.width; x += chunk {
+			n := d.h.width - x
+			if n > chunk {
+				n = chunk
+			}
+			src := buf[:4*d.h.channels*n]
+			if _, err := io.ReadFull(d.buf, src); err != nil {
+				d.err = fmt.Errorf("truncated pfm pixel data: %w", io.ErrUnexpectedEOF)
+				return
+			}
+
+			for si := 0; si < len(src); si += 4 {
+				v := toUint16(math.Float32frombits(d.h.order.Uint32(src[si : si+4])))
+				pix = append(pix, uint8(v>>8), uint8(v))
+
+				//opaque alpha after every color pixel
+				if d.h.channels == 3 && (si/4)%3 == 2 {
+					pix = append(pix, 0xff, 0xff)
+				}
+			}
+		}
+	}
+
+	stride := size * d.h.width
+	for top, bottom := 0, d.h.height-1; top < bottom; top, bottom = top+1, bottom-1 {
+		a, b := pix[top*stride:(top+1)*stride], pix[bottom*stride:(bottom+1)*stride]
+		for i := range a {
+			a[i], b[i] = b[i], a[i]
+		}
+	}
+
+	r := image.Rect(0, 0, d.h.width, d.h.height)
+	if d.h.channels == 1 {
+		d.m = &image.Gray16{Pix: pix, Stride: stride, Rect: r}
+	} else {
+		d.m = &image.NRGBA64{Pix: pix, Stride: stride, Rect: r}
+	}
+}
+
+// toUint16 clamps the sample v to [0, 1] and scales it to 16 bits.
+func toUint16(v float32) uint16 {
+	switch {
+	case v >= 1:
+		return 0xffff
+	case v > 0:
+		return uint16(v*0xffff + 0.5)
+	default:
+		//also covers NaN
+		return 0
+	}
+}
+
+// DecodeConfig returns the color model and dimensions of a PFM image without
+// decoding the entire image.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	d := decoder{
+		buf: bufio.NewReader(r),
+	}
+
+	d.decodeHeader()
+	if d.err != nil {
+		return image.Config{}, d.err
+	}
+
+	return image.Config{
+		ColorModel: d.h.colorModel(),
+		Width:      d.h.width,
+		Height:     d.h.height,
+	}, nil
+}
+
+// Decode reads a PFM image from r and returns it as an *image.Gray16 image
+// for grayscale (Pf) and as an *image.NRGBA64 image for color (PF) files.
+func Decode(r io.Reader) (image.Image, error) {
+	d := decoder{
+		buf: bufio.NewReader(r),
+	}
+
+	d.decodeHeader()
+	d.decode()
+
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	return d.m, nil
+}
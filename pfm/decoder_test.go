@@ -0,0 +1,169 @@
+package pfm
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/LukiDS/image/imgtest"
+)
+
+func TestDecodeWithTestFiles(t *testing.T) {
+	//the fixtures hold NaN and infinite samples next to regular ones
+	rgb := &image.NRGBA64{Stride: 24, Rect: image.Rect(0, 0, 3, 2)}
+	for _, c := range []color.NRGBA64{
+		{0xffff, 0x8000, 0, 0xffff}, {0, 0x4000, 0xffff, 0xffff}, {0, 0xffff, 0, 0xffff},
+		{0, 0, 0xffff, 0xffff}, {0xbfff, 0x2000, 0x5555, 0xffff}, {0, 0xffff, 0xffff, 0xffff},
+	} {
+		rgb.Pix = append(rgb.Pix, uint8(c.R>>8), uint8(c.R), uint8(c.G>>8), uint8(c.G), uint8(c.B>>8), uint8(c.B), uint8(c.A>>8), uint8(c.A))
+	}
+
+	gray := &image.Gray16{Stride: 8, Rect: image.Rect(0, 0, 4, 3)}
+	for _, v := range []uint16{0, 0x4000, 0x8000, 0xbfff, 0xffff, 0, 0, 0xffff, 0x8000, 0x8000, 0x8000, 0x8000} {
+		gray.Pix = append(gray.Pix, uint8(v>>8), uint8(v))
+	}
+
+	tests := []struct {
+		name     string
+		expected image.Image
+	}{
+		{name: "color_le.pfm", expected: rgb},
+		{name: "gray_be.pfm", expected: gray},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("../testdata/pfm", test.name))
+			if err != nil {
+				t.Fatalf("could not read file: %v\n", err)
+			}
+
+			actual, err := Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("could not decode file: %v\n", err)
+			}
+
+			imgtest.AssertEqual(t, test.expected, actual)
+
+			imgtest.AssertColorModel(t, test.expected.ColorModel(), actual)
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		error string
+	}{
+		{name: "empty file", data: "", error: "not valid pfm"},
+		{name: "wrong magic", data: "P6\n1 1\n255\n", error: "not valid pfm"},
+		{name: "missing height", data: "PF\n1 ", error: "missing height"},
+		{name: "missing scale", data: "PF\n1 1\n", error: "missing scale"},
+		{name: "invalid width", data: "PF\nx 1\n-1\n", error: "width"},
+		{name: "invalid height", data: "PF\n1 1.5\n-1\n", error: "height"},
+		{name: "zero scale", data: "PF\n1 1\n0\n", error: "scale"},
+		{name: "NaN scale", data: "PF\n1 1\nNaN\n", error: "scale"},
+		{name: "token too long", data: "PF\n" + strings.Repeat("1", 100) + " 1\n-1\n", error: "too long"},
+		{name: "zero width", data: "PF\n0 1\n-1\n", error: "image size"},
+		{name: "negative height", data: "PF\n1 -1\n-1\n", error: "image size"},
+		{name: "absurd size", data: "PF\n100000 100000\n-1\n", error: "image size"},
+		{name: "truncated pixels", data: "Pf\n2 1\n-1\n\x00\x00\x00\x00\x00", error: "truncated"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Decode(strings.NewReader(test.data))
+			if err == nil || !strings.Contains(err.Error(), test.error) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Decode(%s)\n", test.name) +
+					fmt.Sprintf("Expected error:\t containing %q\n", test.error) +
+					fmt.Sprintf("Actual error:\t %v\n", err)
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+func TestDecodeTruncatedLargeImage(t *testing.T) {
+	//images of 400M pixels taking 3.2GB, with wide and tall rows, but without pixels
+	for _, data := range []string{"PF\n20000 20000\n-1\n", "PF\n400000000 1\n-1\n"} {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, err := Decode(strings.NewReader(data))
+		runtime.ReadMemStats(&after)
+
+		if err == nil || !strings.Contains(err.Error(), "truncated") {
+			t.Errorf("Decode(%q): %v, expected a truncated error\n", data, err)
+		}
+
+		//the image grows with the decoded rows
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 64<<20 {
+			t.Errorf("Decode(%q) allocated %d bytes for a %d byte input\n", data, allocated, len(data))
+		}
+	}
+}
+
+func TestDecodeWideRows(t *testing.T) {
+	//rows longer than the chunks they are read in
+	expected := image.NewGray16(image.Rect(0, 0, pfmInitialPixels+3, 2))
+	for i := range expected.Pix {
+		expected.Pix[i] = uint8(i % 251)
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, expected); err != nil {
+		t.Fatalf("could not encode image: %v\n", err)
+	}
+
+	actual, err := Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode: unexpected error: %v\n", err)
+	}
+	imgtest.AssertEqual(t, expected, actual)
+}
+
+func TestDecodeConfig(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected image.Config
+	}{
+		{data: "PF\n640 480\n-1.0\n", expected: image.Config{ColorModel: color.NRGBA64Model, Width: 640, Height: 480}},
+		{data: "Pf 64 48 1.0\n", expected: image.Config{ColorModel: color.Gray16Model, Width: 64, Height: 48}},
+	}
+
+	for _, test := range tests {
+		actual, err := DecodeConfig(strings.NewReader(test.data))
+		if err != nil || actual != test.expected {
+			t.Errorf("DecodeConfig(%q) = (%+v, %v), expected %+v\n", test.data, actual, err, test.expected)
+		}
+	}
+
+	m := imgtest.RequireFormat(t, strings.NewReader("Pf\n1 1\n-1\n\x00\x00\x80\x3f"), "pfm", image.Rect(0, 0, 1, 1))
+	imgtest.AssertEqual(t, &image.Gray16{Pix: []byte{0xff, 0xff}, Stride: 2, Rect: image.Rect(0, 0, 1, 1)}, m)
+}
+
+func TestDecodeCorruptFiles(t *testing.T) {
+	imgtest.DecodeCorrupt(t, "../testdata/pfm/*.pfm", 2000, 0, Decode)
+}
+
+func BenchmarkDecode(b *testing.B) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, image.NewNRGBA64(image.Rect(0, 0, 1024, 768))); err != nil {
+		b.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Decode(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
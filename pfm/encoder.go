@@ -0,0 +1,105 @@
+package pfm
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"math"
+
+	"github.com/LukiDS/image/imgconv"
+)
+
+type encoder struct {
+	w   *bufio.Writer
+	m   image.Image
+	err error
+}
+
+// Encode writes the image m to w in PFM format with little-endian samples.
+// *image.Gray and *image.Gray16 images are written as grayscale (Pf), any
+// other image as color (PF). The samples are the 16 bit values of m divided
+// by 65535, so decoding the result gives m back. PFM has no alpha channel,
+// so transparent pixels are composited over black.
+func Encode(w io.Writer, m image.Image) error {
+	width := m.Bounds().Dx()
+	height := m.Bounds().Dy()
+	if width <= 0 || height <= 0 || width > pfmMaxPixels/height {
+		return fmt.Errorf("invalid image size: %dx%d, must be non-empty and at most %d pixels", width, height, pfmMaxPixels)
+	}
+
+	e := encoder{
+		w: bufio.NewWriter(w),
+	}
+
+	switch src := m.(type) {
+	case *image.Gray16:
+		e.m = src
+	case *image.Gray:
+		e.m = imgconv.ToGray16(src)
+	default:
+		//the premultiplied colors are the colors composited over black
+		e.m = imgconv.ToRGBA64(m)
+	}
+
+	e.encodeHeader()
+	e.encode()
+
+	if e.err != nil {
+		return e.err
+	}
+
+	return e.w.Flush()
+}
+
+func (e *encoder) encodeHeader() {
+	magic := "PF"
+	if _, ok := e.m.(*image.Gray16); ok {
+		magic = "Pf"
+	}
+
+	b := e.m.Bounds()
+	_, e.err = fmt.Fprintf(e.w, "%s\n%d %d\n-1.0\n", magic, b.Dx(), b.Dy())
+}
+
+func (e *encoder) encode() {
+	if e.err != nil {
+		return
+	}
+
+	b := e.m.Bounds()
+	var row []byte
+	for y := b.Max.Y - 1; y >= b.Min.Y; y-- {
+		row = row[:0]
+		switch m := e.m.(type) {
+		case *image.Gray16:
+			i := m.PixOffset(b.Min.X, y)
+			for x := b.Min.X; x < b.Max.X; x, i = x+1, i+2 {
+				row = appendSample(row, m.Pix[i], m.Pix[i+1])
+			}
+
+		case *image.RGBA64:
+			i := m.PixOffset(b.Min.X, y)
+			for x := b.Min.X; x < b.Max.X; x, i = x+1, i+8 {
+				s := m.Pix[i : i+8 : i+8]
+				row = appendSample(row, s[0], s[1])
+				row = appendSample(row, s[2], s[3])
+				row = appendSample(row, s[4], s[5])
+			}
+		}
+
+		if _, e.err = e.w.Write(row); e.err != nil {
+			return
+		}
+	}
+}
+
+// appendSample appends the big-endian 16 bit sample hi, lo as a little-endian float32 in [0, 1].
+func appendSample(b []byte, hi, lo uint8) []byte {
+	v := math.Float32bits(float32(uint32(hi)<<8|uint32(lo)) / 0xffff)
+
+	var s [4]byte
+	binary.LittleEndian.PutUint32(s[:], v)
+	return append(b, s[:]...)
+}
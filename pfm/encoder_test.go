@@ -0,0 +1,114 @@
+package pfm
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	img := image.NewGray16(image.Rect(0, 0, 2, 2))
+	img.SetGray16(0, 0, color.Gray16{0xffff})
+	img.SetGray16(1, 1, color.Gray16{0x8000})
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, img); err != nil {
+		t.Fatalf("Encode: unexpected error: %v\n", err)
+	}
+
+	//the bottom row comes first, 0x8000/0xffff is 0x3f000080 as float32
+	expected := "Pf\n2 2\n-1.0\n" +
+		"\x00\x00\x00\x00\x80\x00\x00\x3f" +
+		"\x00\x00\x80\x3f\x00\x00\x00\x00"
+	if buf.String() != expected {
+		t.Errorf("Encode() = %q, expected %q\n", buf.String(), expected)
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	rgba64 := image.NewNRGBA64(image.Rect(0, 0, 17, 5))
+	gray16 := image.NewGray16(image.Rect(-3, 2, 30, 9))
+	for i := range rgba64.Pix {
+		rgba64.Pix[i] = uint8(i * 37)
+	}
+	for i := 7; i < len(rgba64.Pix); i += 8 {
+		rgba64.Pix[i-1], rgba64.Pix[i] = 0xff, 0xff
+	}
+	for i := range gray16.Pix {
+		gray16.Pix[i] = uint8(i * 101)
+	}
+
+	gray := image.NewGray(image.Rect(0, 0, 16, 16))
+	for i := range gray.Pix {
+		gray.Pix[i] = uint8(i)
+	}
+
+	transparent := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	transparent.SetNRGBA(0, 0, color.NRGBA{0xff, 0x80, 0, 0x80})
+	r, g, b, _ := transparent.At(0, 0).RGBA()
+	blended := image.NewNRGBA64(image.Rect(0, 0, 1, 1))
+	blended.SetNRGBA64(0, 0, color.NRGBA64{uint16(r), uint16(g), uint16(b), 0xffff})
+
+	tests := []struct {
+		name     string
+		img      image.Image
+		expected image.Image
+	}{
+		{name: "opaque NRGBA64", img: rgba64, expected: rgba64},
+		{name: "Gray16 with offset bounds", img: gray16, expected: gray16},
+		{name: "Gray", img: gray, expected: gray},
+		{name: "transparent NRGBA", img: transparent, expected: blended},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Encode(&buf, test.img); err != nil {
+				t.Fatalf("Encode: unexpected error: %v\n", err)
+			}
+
+			actual, err := Decode(&buf)
+			if err != nil {
+				t.Fatalf("Decode: unexpected error: %v\n", err)
+			}
+
+			//the decoded image starts at the origin
+			b := test.expected.Bounds()
+			if actual.Bounds().Size() != b.Size() {
+				t.Fatalf("Decode(Encode(%s)): size %v, expected %v\n", test.name, actual.Bounds().Size(), b.Size())
+			}
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				for x := b.Min.X; x < b.Max.X; x++ {
+					e := color.NRGBA64Model.Convert(test.expected.At(x, y))
+					a := color.NRGBA64Model.Convert(actual.At(x-b.Min.X, y-b.Min.Y))
+					if e != a {
+						format := fmt.Sprintf("\n") +
+							fmt.Sprintf("Decode(Encode(%s)) at x=%d, y=%d\n", test.name, x, y) +
+							fmt.Sprintf("Expected:\t %v\n", e) +
+							fmt.Sprintf("Actual:\t %v\n", a)
+						t.Fatalf(format)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeEmptyImage(t *testing.T) {
+	if err := Encode(&bytes.Buffer{}, image.NewGray16(image.Rect(0, 0, 0, 3))); err == nil {
+		t.Errorf("Encode(empty image): expected an error\n")
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	img := image.NewNRGBA64(image.Rect(0, 0, 1024, 768))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := Encode(&bytes.Buffer{}, img); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// Package pfm implements a decoder and an encoder for Portable FloatMap
+// (PFM) images.
+//
+// PFM images store one (Pf) or three (PF) float32 samples per pixel, in
+// rows from bottom to top. The sign of the scale in the header gives the
+// byte order: negative for little-endian, positive for big-endian.
+//
+// Color images are decoded into *image.NRGBA64 and grayscale images into
+// *image.Gray16 images. Samples are clamped to [0, 1] and scaled to 16 bits:
+// samples below 0 and -Inf become 0, samples above 1 and +Inf become 65535,
+// and NaN becomes 0. Every sample is clamped on its own, so invalid samples
+// never affect other samples or pixels. The absolute value of the scale is
+// not applied to the samples.
+package pfm
+
+import (
+	"image"
+)
+
+const (
+	// pfmMaxPixels limits the size of the images claimed by headers.
+	pfmMaxPixels = 400_000_000
+
+	// pfmInitialPixels caps the pixels allocated before any data is decoded,
+	// so a crafted header cannot claim gigabytes for a file of a few bytes.
+	// The pixels are also read in chunks of at most this many.
+	pfmInitialPixels = 1 << 20
+
+	// maxTokenLength limits the length of the numbers in the header.
+	maxTokenLength = 64
+)
+
+func init() {
+	for _, magic := range []string{"PF", "Pf"} {
+		image.RegisterFormat("pfm", magic, Decode, DecodeConfig)
+	}
+}
@@ -0,0 +1,590 @@
+package pnm
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type pnmHeader struct {
+	magic    string
+	width    int
+	height   int
+	depth    int
+	maxval   int
+	tupltype string
+}
+
+// bitmap reports whether the header belongs to a PBM image, which has no maxval.
+func (h pnmHeader) bitmap() bool {
+	return h.magic == "P1" || h.magic == "P4"
+}
+
+// plain reports whether the raster is stored as ASCII decimal numbers.
+func (h pnmHeader) plain() bool {
+	return h.magic == "P1" || h.magic == "P2" || h.magic == "P3"
+}
+
+// deep reports whether the samples are stored with 16 bits.
+func (h pnmHeader) deep() bool {
+	return h.maxval > 0xff
+}
+
+func (h pnmHeader) colorModel() color.Model {
+	switch {
+	case h.depth > 1 && h.deep():
+		return color.NRGBA64Model
+	case h.depth > 1:
+		return color.NRGBAModel
+	case h.deep():
+		return color.Gray16Model
+	default:
+		return color.GrayModel
+	}
+}
+
+// pamTupleTypes maps the supported PAM tuple types to their depth.
+var pamTupleTypes = map[string]int{
+	"GRAYSCALE":       1,
+	"GRAYSCALE_ALPHA": 2,
+	"RGB":             3,
+	"RGB_ALPHA":       4,
+}
+
+type decoder struct {
+	m   image.Image
+	buf *bufio.Reader
+	h   pnmHeader
+	err error
+}
+
+func (d *decoder) decodeHeader() {
+	magic := make([]byte, 2)
+	if _, err := io.ReadFull(d.buf, magic); err != nil {
+		d.err = fmt.Errorf("invalid pnm header: missing magic number")
+		return
+	}
+
+	d.h.magic = string(magic)
+	switch d.h.magic {
+	case "P1", "P2", "P4", "P5":
+		d.h.depth = 1
+	case "P3", "P6":
+		d.h.depth = 3
+	case "P7":
+		d.decodePAMHeader()
+	default:
+		d.err = fmt.Errorf("invalid pnm header: unsupported magic number %q", d.h.magic)
+		return
+	}
+
+	if d.h.magic != "P7" {
+		d.h.width = d.readInt("width")
+		d.h.height = d.readInt("height")
+		d.h.maxval = 1
+		if !d.h.bitmap() {
+			d.h.maxval = d.readInt("maxval")
+		}
+		if d.err != nil {
+			return
+		}
+
+		//exactly one whitespace character separates the header from the raster
+		if c, err := d.buf.ReadByte(); err != nil || !isSpace(c) {
+			d.err = fmt.Errorf("invalid pnm header: missing whitespace after the header")
+			return
+		}
+	}
+	if d.err != nil {
+		return
+	}
+
+	if d.h.width <= 0 || d.h.height <= 0 || d.h.width > pnmMaxPixels/d.h.height {
+		d.err = fmt.Errorf("invalid pnm header: image size %dx%d, must be non-empty and at most %d pixels", d.h.width, d.h.height, pnmMaxPixels)
+		return
+	}
+
+	if d.h.maxval <= 0 || d.h.maxval > pnmMaxValue {
+		d.err = fmt.Errorf("invalid pnm header: maxval %d, must be between 1 and %d", d.h.maxval, pnmMaxValue)
+		return
+	}
+}
+
+// decodePAMHeader reads the header lines of a PAM image up to ENDHDR.
+// Every line holds a keyword and its value; a missing TUPLTYPE is derived
+// from DEPTH.
+func (d *decoder) decodePAMHeader() {
+	fields := map[string]*int{"WIDTH": &d.h.width, "HEIGHT": &d.h.height, "DEPTH": &d.h.depth, "MAXVAL": &d.h.maxval}
+	seen := map[string]bool{}
+
+	for {
+		if d.skipSpace(); d.err != nil {
+			d.err = fmt.Errorf("invalid pam header: missing ENDHDR")
+			return
+		}
+		_ = d.buf.UnreadByte()
+
+		line, err := d.buf.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			d.err = fmt.Errorf("invalid pam header: line too long")
+			return
+		} else if err != nil {
+			d.err = fmt.Errorf("invalid pam header: missing ENDHDR")
+			return
+		}
+
+		tokens := strings.Fields(string(line))
+		keyword := tokens[0]
+		switch {
+		case keyword == "ENDHDR":
+			d.validatePAMHeader(seen)
+			return
+
+		case keyword == "TUPLTYPE":
+			//multiple TUPLTYPE lines are concatenated
+			d.h.tupltype = strings.TrimSpace(d.h.tupltype + " " + strings.Join(tokens[1:], " "))
+
+		case fields[keyword] != nil:
+			if len(tokens) != 2 {
+				d.err = fmt.Errorf("invalid pam header: %s needs exactly one value", keyword)
+				return
+			}
+			v, err := strconv.Atoi(tokens[1])
+			if err != nil || v <= 0 {
+				d.err = fmt.Errorf("invalid pam header: invalid %s %q", keyword, tokens[1])
+				return
+			}
+			*fields[keyword] = v
+			seen[keyword] = true
+
+		default:
+			d.err = fmt.Errorf("invalid pam header: unknown keyword %q", keyword)
+			return
+		}
+	}
+}
+
+func (d *decoder) validatePAMHeader(seen map[string]bool) {
+	for _, keyword := range []string{"WIDTH", "HEIGHT", "DEPTH", "MAXVAL"} {
+		if !seen[keyword] {
+			d.err = fmt.Errorf("invalid pam header: missing %s", keyword)
+			return
+		}
+	}
+
+	if d.h.tupltype == "" {
+		for tupltype, depth := range pamTupleTypes {
+			if depth == d.h.depth {
+				d.h.tupltype = tupltype
+			}
+		}
+		if d.h.tupltype == "" {
+			d.err = fmt.Errorf("unsupported pam depth %d without TUPLTYPE, must be between 1 and 4", d.h.depth)
+		}
+		return
+	}
+
+	depth, ok := pamTupleTypes[d.h.tupltype]
+	if !ok {
+		d.err = fmt.Errorf("unsupported pam tuple type %q, must be GRAYSCALE, GRAYSCALE_ALPHA, RGB or RGB_ALPHA", d.h.tupltype)
+		return
+	}
+	if depth != d.h.depth {
+		d.err = fmt.Errorf("invalid pam header: tuple type %s needs depth %d, got %d", d.h.tupltype, depth, d.h.depth)
+		return
+	}
+}
+
+// readInt reads the next decimal header token, skipping whitespace and comments.
+func (d *decoder) readInt(name string) int {
+	if d.err != nil {
+		return 0
+	}
+
+	c := d.skipSpace()
+	if d.err != nil {
+		d.err = fmt.Errorf("invalid pnm header: missing %s", name)
+		return 0
+	}
+
+	v, digits := 0, 0
+	for ; c >= '0' && c <= '9'; digits++ {
+		if v > pnmMaxPixels {
+			d.err = fmt.Errorf("invalid pnm header: %s out of range", name)
+			return 0
+		}
+		v = 10*v + int(c-'0')
+
+		var err error
+		if c, err = d.buf.ReadByte(); err != nil {
+			//the token is not terminated, so the raster is missing anyway
+			d.err = fmt.Errorf("invalid pnm header: missing whitespace after %s", name)
+			return 0
+		}
+	}
+
+	if digits == 0 || !(isSpace(c) || c == '#') {
+		d.err = fmt.Errorf("invalid pnm header: invalid %s", name)
+		return 0
+	}
+
+	//the terminating whitespace after maxval is the last byte of the header
+	_ = d.buf.UnreadByte()
+
+	return v
+}
+
+// skipSpace skips whitespace and comments and returns the first other byte.
+func (d *decoder) skipSpace() byte {
+	for {
+		c, err := d.buf.ReadByte()
+		if err != nil {
+			d.err = err
+			return 0
+		}
+
+		switch {
+		case c == '#':
+			//comments run until the end of the line
+			for c != '\n' && c != '\r' {
+				if c, err = d.buf.ReadByte(); err != nil {
+					d.err = err
+					return 0
+				}
+			}
+
+		case !isSpace(c):
+			return c
+		}
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\v' || c == '\f' || c == '\r'
+}
+
+func (d *decoder) decode() {
+	if d.err != nil {
+		return
+	}
+
+	switch {
+	case d.h.bitmap():
+		d.decodeBitmap()
+	case d.h.depth == 1 && d.h.deep():
+		d.decodeGray16()
+	case d.h.depth == 1:
+		d.decodeGray()
+	case d.h.deep():
+		d.decodeColor64()
+	default:
+		d.decodeColor()
+	}
+}
+
+// decodeBitmap decodes a PBM raster, where 1 is black and 0 is white, into an
+// *image.Gray image with the values 0 and 255. Binary rows are packed with 8
+// pixels per byte, most significant bit first, and padded to whole bytes.
+func (d *decoder) decodeBitmap() {
+	m := image.NewGray(image.Rect(0, 0, d.h.width, d.h.height))
+	row := make([]byte, (d.h.width+7)/8)
+
+	for y := 0; y < d.h.height; y++ {
+		p := m.Pix[m.PixOffset(0, y):m.PixOffset(d.h.width, y)]
+
+		if d.h.plain() {
+			for x := range p {
+				p[x] = d.readBit()
+				if d.err != nil {
+					return
+				}
+			}
+			continue
+		}
+
+		if _, err := io.ReadFull(d.buf, row); err != nil {
+			d.err = fmt.Errorf("truncated pnm raster at row %d: %w", y, io.ErrUnexpectedEOF)
+			return
+		}
+		for x := range p {
+			if row[x/8]&(0x80>>(x%8)) == 0 {
+				p[x] = 0xff
+			}
+		}
+	}
+
+	d.m = m
+}
+
+// readBit reads the next pixel of a plain PBM raster. The pixels are single
+// characters, which do not need to be separated by whitespace.
+func (d *decoder) readBit() byte {
+	c := d.skipSpace()
+	if d.err != nil {
+		d.err = fmt.Errorf("truncated pnm raster: %w", io.ErrUnexpectedEOF)
+		return 0
+	}
+
+	switch c {
+	case '0':
+		return 0xff
+	case '1':
+		return 0
+	default:
+		d.err = fmt.Errorf("invalid pnm raster: unexpected character %q", c)
+		return 0
+	}
+}
+
+func (d *decoder) decodeGray() {
+	scale := scaleTable(d.h.maxval)
+	m := image.NewGray(image.Rect(0, 0, d.h.width, d.h.height))
+
+	for y := 0; y < d.h.height; y++ {
+		d.readSamples(m.Pix[m.PixOffset(0, y):m.PixOffset(d.h.width, y)], scale, y)
+		if d.err != nil {
+			return
+		}
+	}
+
+	d.m = m
+}
+
+// decodeColor decodes 8 bit rasters with 2 (gray and alpha), 3 (RGB) or
+// 4 (RGB and alpha) samples per pixel into an *image.NRGBA image.
+func (d *decoder) decodeColor() {
+	scale := scaleTable(d.h.maxval)
+	m := image.NewNRGBA(image.Rect(0, 0, d.h.width, d.h.height))
+	row := make([]byte, d.h.depth*d.h.width)
+
+	for y := 0; y < d.h.height; y++ {
+		p := m.Pix[m.PixOffset(0, y):m.PixOffset(d.h.width, y)]
+		if d.h.depth == 4 {
+			d.readSamples(p, scale, y)
+			if d.err != nil {
+				return
+			}
+			continue
+		}
+
+		d.readSamples(row, scale, y)
+		if d.err != nil {
+			return
+		}
+
+		for si, di := 0, 0; si < len(row); si, di = si+d.h.depth, di+4 {
+			q := p[di : di+4 : di+4]
+			if d.h.depth == 2 {
+				q[0], q[1], q[2], q[3] = row[si], row[si], row[si], row[si+1]
+			} else {
+				q[0], q[1], q[2], q[3] = row[si], row[si+1], row[si+2], 0xff
+			}
+		}
+	}
+
+	d.m = m
+}
+
+// readSamples reads the row y of 8 bit samples into samples and scales them
+// from 0..maxval to 0..255.
+func (d *decoder) readSamples(samples []byte, scale *[256]uint8, y int) {
+	if d.h.plain() {
+		d.readASCII(samples)
+	} else if _, err := io.ReadFull(d.buf, samples); err != nil {
+		d.err = fmt.Errorf("truncated pnm raster at row %d: %w", y, io.ErrUnexpectedEOF)
+	}
+	if d.err != nil {
+		return
+	}
+
+	for i, s := range samples {
+		if int(s) > d.h.maxval {
+			d.err = fmt.Errorf("invalid pnm raster: sample %d at row %d exceeds maxval %d", s, y, d.h.maxval)
+			return
+		}
+		samples[i] = scale[s]
+	}
+}
+
+func (d *decoder) decodeGray16() {
+	m := image.NewGray16(image.Rect(0, 0, d.h.width, d.h.height))
+
+	for y := 0; y < d.h.height; y++ {
+		d.readSamples16(m.Pix[m.PixOffset(0, y):m.PixOffset(d.h.width, y)], y)
+		if d.err != nil {
+			return
+		}
+	}
+
+	d.m = m
+}
+
+// decodeColor64 is like decodeColor for 16 bit rasters and returns an
+// *image.NRGBA64 image.
+func (d *decoder) decodeColor64() {
+	m := image.NewNRGBA64(image.Rect(0, 0, d.h.width, d.h.height))
+	row := make([]byte, 2*d.h.depth*d.h.width)
+
+	for y := 0; y < d.h.height; y++ {
+		p := m.Pix[m.PixOffset(0, y):m.PixOffset(d.h.width, y)]
+		if d.h.depth == 4 {
+			d.readSamples16(p, y)
+			if d.err != nil {
+				return
+			}
+			continue
+		}
+
+		d.readSamples16(row, y)
+		if d.err != nil {
+			return
+		}
+
+		for si, di := 0, 0; si < len(row); si, di = si+2*d.h.depth, di+8 {
+			s := row[si : si+2*d.h.depth : si+2*d.h.depth]
+			q := p[di : di+8 : di+8]
+			if d.h.depth == 2 {
+				q[0], q[1], q[2], q[3], q[4], q[5] = s[0], s[1], s[0], s[1], s[0], s[1]
+				q[6], q[7] = s[2], s[3]
+			} else {
+				q[0], q[1], q[2], q[3], q[4], q[5] = s[0], s[1], s[2], s[3], s[4], s[5]
+				q[6], q[7] = 0xff, 0xff
+			}
+		}
+	}
+
+	d.m = m
+}
+
+// readSamples16 reads the row y of 16 bit samples into samples, which holds two
+// big-endian bytes per sample, and scales them from 0..maxval to 0..65535.
+func (d *decoder) readSamples16(samples []byte, y int) {
+	if d.h.plain() {
+		for i := 0; i < len(samples); i += 2 {
+			v := d.readSample()
+			if d.err != nil {
+				return
+			}
+			samples[i], samples[i+1] = byte(v>>8), byte(v)
+		}
+	} else if _, err := io.ReadFull(d.buf, samples); err != nil {
+		d.err = fmt.Errorf("truncated pnm raster at row %d: %w", y, io.ErrUnexpectedEOF)
+		return
+	}
+
+	maxval := uint32(d.h.maxval)
+	for i := 0; i < len(samples); i += 2 {
+		v := uint32(samples[i])<<8 | uint32(samples[i+1])
+		if v > maxval {
+			d.err = fmt.Errorf("invalid pnm raster: sample %d at row %d exceeds maxval %d", v, y, maxval)
+			return
+		}
+
+		v = (v*0xffff + maxval/2) / maxval
+		samples[i], samples[i+1] = byte(v>>8), byte(v)
+	}
+}
+
+// readASCII reads the decimal samples of a plain raster into samples.
+func (d *decoder) readASCII(samples []byte) {
+	for i := range samples {
+		samples[i] = byte(d.readSample())
+		if d.err != nil {
+			return
+		}
+	}
+}
+
+// readSample reads the next decimal sample of a plain raster.
+func (d *decoder) readSample() int {
+	c := d.skipSpace()
+	if d.err != nil {
+		d.err = fmt.Errorf("truncated pnm raster: %w", io.ErrUnexpectedEOF)
+		return 0
+	}
+	if c < '0' || c > '9' {
+		d.err = fmt.Errorf("invalid pnm raster: unexpected character %q", c)
+		return 0
+	}
+
+	v := 0
+	for c >= '0' && c <= '9' {
+		if v <= d.h.maxval {
+			v = 10*v + int(c-'0')
+		}
+
+		next, err := d.buf.ReadByte()
+		if err == io.EOF {
+			//the last sample may end the file
+			next = '\n'
+		} else if err != nil {
+			d.err = err
+			return 0
+		}
+		c = next
+	}
+
+	switch {
+	case c == '#':
+		_ = d.buf.UnreadByte()
+	case !isSpace(c):
+		d.err = fmt.Errorf("invalid pnm raster: unexpected character %q", c)
+		return 0
+	}
+
+	if v > d.h.maxval {
+		d.err = fmt.Errorf("invalid pnm raster: sample %d exceeds maxval %d", v, d.h.maxval)
+		return 0
+	}
+
+	return v
+}
+
+// scaleTable returns a lookup table scaling the samples 0..maxval to 0..255.
+func scaleTable(maxval int) *[256]uint8 {
+	var t [256]uint8
+	for v := 0; v <= maxval && v < len(t); v++ {
+		t[v] = uint8((v*0xff + maxval/2) / maxval)
+	}
+
+	return &t
+}
+
+// DecodeConfig returns the color model and dimensions of a PNM image without
+// decoding the entire image.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	d := decoder{
+		buf: bufio.NewReader(r),
+	}
+
+	d.decodeHeader()
+	if d.err != nil {
+		return image.Config{}, d.err
+	}
+
+	return image.Config{
+		ColorModel: d.h.colorModel(),
+		Width:      d.h.width,
+		Height:     d.h.height,
+	}, nil
+}
+
+// Decode reads a PNM image from r and returns it as an image.Image.
+func Decode(r io.Reader) (image.Image, error) {
+	d := decoder{
+		buf: bufio.NewReader(r),
+	}
+
+	d.decodeHeader()
+	d.decode()
+
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	return d.m, nil
+}
@@ -0,0 +1,332 @@
+package pnm
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/LukiDS/image/imgtest"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		expectError bool
+		expected    []color.NRGBA
+		size        image.Point
+	}{
+		{
+			name:     "should decode a minimal P6 image",
+			data:     "P6\n2 1\n255\n\xff\x00\x00\x00\x80\xff",
+			expected: []color.NRGBA{{255, 0, 0, 255}, {0, 128, 255, 255}},
+			size:     image.Pt(2, 1),
+		},
+		{
+			name:     "should skip comments and arbitrary whitespace",
+			data:     "P6 # a comment\n\t1\r\n# another comment\n  2 #comment directly after a token\n255 \x01\x02\x03\x04\x05\x06",
+			expected: []color.NRGBA{{1, 2, 3, 255}, {4, 5, 6, 255}},
+			size:     image.Pt(1, 2),
+		},
+		{
+			name:     "should accept a comment right after the magic number",
+			data:     "P6#comment\n1 1 255\n\x0a\x0b\x0c",
+			expected: []color.NRGBA{{10, 11, 12, 255}},
+			size:     image.Pt(1, 1),
+		},
+		{
+			name:     "should scale samples with a maxval below 255",
+			data:     "P6 3 1 15\n\x00\x00\x00\x07\x08\x0f\x0f\x0f\x0f",
+			expected: []color.NRGBA{{0, 0, 0, 255}, {119, 136, 255, 255}, {255, 255, 255, 255}},
+			size:     image.Pt(3, 1),
+		},
+		{
+			name:     "should treat a raster byte that looks like whitespace as a sample",
+			data:     "P6 1 1 255\n\n\n\n",
+			expected: []color.NRGBA{{10, 10, 10, 255}},
+			size:     image.Pt(1, 1),
+		},
+		{
+			name:     "should ignore trailing data",
+			data:     "P6 1 1 255\n\x01\x02\x03P6 1 1 255\n",
+			expected: []color.NRGBA{{1, 2, 3, 255}},
+			size:     image.Pt(1, 1),
+		},
+		{
+			name:     "should decode a plain P3 image",
+			data:     "P3\n# plain\n2 2\n255\n255 0 0   0 255 0\n0 0 255 #blue\n 1 2\n3",
+			expected: []color.NRGBA{{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255}, {1, 2, 3, 255}},
+			size:     image.Pt(2, 2),
+		},
+		{
+			name:     "should scale plain samples with a maxval below 255",
+			data:     "P3 1 1 1\n1 0 1\n",
+			expected: []color.NRGBA{{255, 0, 255, 255}},
+			size:     image.Pt(1, 1),
+		},
+		{name: "should return an error if a plain sample exceeds maxval", data: "P3 1 1 255\n256 0 0\n", expectError: true},
+		{name: "should return an error if a plain sample is not a number", data: "P3 1 1 255\n1 x 0\n", expectError: true},
+		{name: "should return an error if a plain sample is negative", data: "P3 1 1 255\n1 -2 0\n", expectError: true},
+		{name: "should return an error if the plain raster is truncated", data: "P3 1 1 255\n1 2", expectError: true},
+		{name: "should return an error for an empty reader", data: "", expectError: true},
+		{name: "should return an error if a plain bitmap pixel is not 0 or 1", data: "P1 2 1\n0 2\n", expectError: true},
+		{name: "should return an error if a binary bitmap is truncated", data: "P4 9 2\n\x00\x00\x00", expectError: true},
+		{name: "should return an error if a gray sample exceeds maxval", data: "P5 2 1 100\n\x00\x65", expectError: true},
+		{name: "should return an error if a gray raster is truncated", data: "P2 2 2 255\n1 2 3", expectError: true},
+		{name: "should return an error for an unknown magic number", data: "P9 1 1 255\n\x00\x00\x00", expectError: true},
+		{name: "should return an error if the width is missing", data: "P6\n", expectError: true},
+		{name: "should return an error if the height is missing", data: "P6 1 # 1 255\n", expectError: true},
+		{name: "should return an error if the maxval is missing", data: "P6 1 1", expectError: true},
+		{name: "should return an error if the width is not a number", data: "P6 x 1 255\n\x00\x00\x00", expectError: true},
+		{name: "should return an error if the width is negative", data: "P6 -1 1 255\n\x00\x00\x00", expectError: true},
+		{name: "should return an error if the width is zero", data: "P6 0 1 255\n", expectError: true},
+		{name: "should return an error if the height is zero", data: "P6 1 0 255\n", expectError: true},
+		{name: "should return an error if the size is too large", data: "P6 100000 100000 255\n", expectError: true},
+		{name: "should return an error if a token overflows", data: "P6 99999999999999999999999 1 255\n", expectError: true},
+		{name: "should return an error if maxval is zero", data: "P6 1 1 0\n\x00\x00\x00", expectError: true},
+		{name: "should return an error if maxval is too large", data: "P6 1 1 65536\n\x00\x00\x00", expectError: true},
+		{name: "should return an error if the header is not terminated", data: "P6 1 1 255", expectError: true},
+		{name: "should return an error if the raster is truncated", data: "P6 2 1 255\n\x00\x00\x00\x00", expectError: true},
+		{name: "should return an error if the raster is missing", data: "P6 2 2 255\n", expectError: true},
+		{name: "should return an error if a sample exceeds maxval", data: "P6 1 1 15\n\x00\x10\x00", expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := Decode(strings.NewReader(test.data))
+			if actualError := err != nil; actualError != test.expectError {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Decode(%q) = (%v, %v)\n", test.data, actual, err) +
+					fmt.Sprintf("Expected error:\t %t\n", test.expectError) +
+					fmt.Sprintf("Actual error:\t %t\n", actualError)
+				t.Fatalf(format)
+			}
+			if test.expectError {
+				return
+			}
+
+			expected := image.NewNRGBA(image.Rectangle{Max: test.size})
+			for i, c := range test.expected {
+				expected.SetNRGBA(i%test.size.X, i/test.size.X, c)
+			}
+			imgtest.AssertEqual(t, expected, actual)
+			imgtest.AssertColorModel(t, expected.ColorModel(), actual)
+		})
+	}
+}
+
+func TestDecodeGray(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected *image.Gray
+	}{
+		{
+			// as written by pbmmake -black 10 2: the rows are padded to 2 bytes
+			name:     "should decode a binary P4 bitmap",
+			data:     "P4\n10 2\n\xff\xc0\xff\xc0",
+			expected: &image.Gray{Pix: make([]byte, 20), Stride: 10, Rect: image.Rect(0, 0, 10, 2)},
+		},
+		{
+			name: "should decode a binary P4 bitmap with padding bits",
+			data: "P4 3 3\n\xa0\x5f\xe0",
+			expected: &image.Gray{Pix: []byte{
+				0x00, 0xff, 0x00,
+				0xff, 0x00, 0xff,
+				0x00, 0x00, 0x00,
+			}, Stride: 3, Rect: image.Rect(0, 0, 3, 3)},
+		},
+		{
+			name: "should decode a binary P4 bitmap wider than one byte",
+			data: "P4 12 1\n\x81\x8f",
+			expected: &image.Gray{Pix: []byte{
+				0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0xff, 0xff, 0xff,
+			}, Stride: 12, Rect: image.Rect(0, 0, 12, 1)},
+		},
+		{
+			// as written by pnmtoplainpnm
+			name:     "should decode a plain P1 bitmap",
+			data:     "P1\n3 2\n101\n010\n",
+			expected: &image.Gray{Pix: []byte{0x00, 0xff, 0x00, 0xff, 0x00, 0xff}, Stride: 3, Rect: image.Rect(0, 0, 3, 2)},
+		},
+		{
+			name:     "should decode a plain P1 bitmap with whitespace and comments between pixels",
+			data:     "P1 # bitmap\n3 2 1 0\n# first row done\n1\t0 1 1",
+			expected: &image.Gray{Pix: []byte{0x00, 0xff, 0x00, 0xff, 0x00, 0x00}, Stride: 3, Rect: image.Rect(0, 0, 3, 2)},
+		},
+		{
+			name:     "should decode a binary P5 graymap",
+			data:     "P5\n3 1\n255\n\x00\x80\xff",
+			expected: &image.Gray{Pix: []byte{0x00, 0x80, 0xff}, Stride: 3, Rect: image.Rect(0, 0, 3, 1)},
+		},
+		{
+			name:     "should scale a binary P5 graymap with a maxval below 255",
+			data:     "P5 4 1 3\n\x00\x01\x02\x03",
+			expected: &image.Gray{Pix: []byte{0, 85, 170, 255}, Stride: 4, Rect: image.Rect(0, 0, 4, 1)},
+		},
+		{
+			// as written by pnmtoplainpnm
+			name:     "should decode a plain P2 graymap",
+			data:     "P2\n2 2\n15\n0 15\n# comment\n7 8\n",
+			expected: &image.Gray{Pix: []byte{0, 255, 119, 136}, Stride: 2, Rect: image.Rect(0, 0, 2, 2)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := Decode(strings.NewReader(test.data))
+			if err != nil {
+				t.Fatalf("Decode(%q): unexpected error: %v\n", test.data, err)
+			}
+
+			imgtest.AssertEqual(t, test.expected, actual)
+
+			imgtest.AssertColorModel(t, test.expected.ColorModel(), actual)
+		})
+	}
+}
+
+func TestDecode16(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected image.Image
+	}{
+		{
+			name:     "should decode a binary P5 graymap with maxval 65535",
+			data:     "P5\n3 1\n65535\n\x00\x00\x12\x34\xff\xff",
+			expected: &image.Gray16{Pix: []byte{0x00, 0x00, 0x12, 0x34, 0xff, 0xff}, Stride: 6, Rect: image.Rect(0, 0, 3, 1)},
+		},
+		{
+			// 1023 is 10 bit data: 512 -> 512*65535/1023 = 32800.0 and 1 -> 64.06
+			name:     "should scale a binary P5 graymap with maxval 1023",
+			data:     "P5\n4 1\n1023\n\x00\x00\x00\x01\x02\x00\x03\xff",
+			expected: &image.Gray16{Pix: []byte{0x00, 0x00, 0x00, 0x40, 0x80, 0x20, 0xff, 0xff}, Stride: 8, Rect: image.Rect(0, 0, 4, 1)},
+		},
+		{
+			name:     "should decode a plain P2 graymap with maxval 1023",
+			data:     "P2 4 1 1023\n0 1 512\n1023\n",
+			expected: &image.Gray16{Pix: []byte{0x00, 0x00, 0x00, 0x40, 0x80, 0x20, 0xff, 0xff}, Stride: 8, Rect: image.Rect(0, 0, 4, 1)},
+		},
+		{
+			name: "should decode a binary P6 pixmap with maxval 65535",
+			data: "P6 1 1 65535\n\x12\x34\x00\x01\xab\xcd",
+			expected: &image.NRGBA64{Pix: []byte{
+				0x12, 0x34, 0x00, 0x01, 0xab, 0xcd, 0xff, 0xff,
+			}, Stride: 8, Rect: image.Rect(0, 0, 1, 1)},
+		},
+		{
+			// 256 of 4095 -> 256*65535/4095 = 4096.94
+			name: "should scale a plain P3 pixmap with maxval 4095",
+			data: "P3 2 1 4095\n4095 0 256\n1 2 3",
+			expected: &image.NRGBA64{Pix: []byte{
+				0xff, 0xff, 0x00, 0x00, 0x10, 0x01, 0xff, 0xff,
+				0x00, 0x10, 0x00, 0x20, 0x00, 0x30, 0xff, 0xff,
+			}, Stride: 16, Rect: image.Rect(0, 0, 2, 1)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := Decode(strings.NewReader(test.data))
+			if err != nil {
+				t.Fatalf("Decode(%q): unexpected error: %v\n", test.data, err)
+			}
+
+			imgtest.AssertEqual(t, test.expected, actual)
+
+			imgtest.AssertColorModel(t, test.expected.ColorModel(), actual)
+		})
+	}
+
+	for _, data := range []string{
+		"P5 1 1 1023\n\x04\x00",
+		"P5 2 1 65535\n\x00\x00\x00",
+		"P3 1 1 1000\n1 1001 1",
+	} {
+		if _, err := Decode(strings.NewReader(data)); err == nil {
+			t.Errorf("Decode(%q): expected an error\n", data)
+		}
+	}
+}
+
+func TestDecodeConfig(t *testing.T) {
+	tests := []struct {
+		name           string
+		data           string
+		expectError    bool
+		expectedConfig image.Config
+	}{
+		{
+			name:           "should return the config of a P6 image",
+			data:           "P6\n# created by hand\n640 480\n255\n",
+			expectedConfig: image.Config{ColorModel: color.NRGBAModel, Width: 640, Height: 480},
+		},
+		{
+			name:           "should return the config of a P4 bitmap",
+			data:           "P4\n17 3\n",
+			expectedConfig: image.Config{ColorModel: color.GrayModel, Width: 17, Height: 3},
+		},
+		{
+			name:           "should return the config of a P2 graymap",
+			data:           "P2 5 6 100\n",
+			expectedConfig: image.Config{ColorModel: color.GrayModel, Width: 5, Height: 6},
+		},
+		{
+			name:           "should return the config of a 16 bit P5 graymap",
+			data:           "P5 5 6 1023\n",
+			expectedConfig: image.Config{ColorModel: color.Gray16Model, Width: 5, Height: 6},
+		},
+		{
+			name:           "should return the config of a 16 bit P6 pixmap",
+			data:           "P6 5 6 65535\n",
+			expectedConfig: image.Config{ColorModel: color.NRGBA64Model, Width: 5, Height: 6},
+		},
+		{name: "should return an error for an invalid header", data: "P6 640", expectError: true},
+		{name: "should return an error for an invalid maxval", data: "P6 640 480 0\n", expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actualConfig, err := DecodeConfig(strings.NewReader(test.data))
+			if actualError := err != nil; actualError != test.expectError {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("DecodeConfig(%q) = (%+v,%v)\n", test.data, actualConfig, err) +
+					fmt.Sprintf("Expected error:\t %t\n", test.expectError) +
+					fmt.Sprintf("Actual error:\t %t\n", actualError)
+				t.Errorf(format)
+			}
+
+			if actualConfig != test.expectedConfig {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("DecodeConfig(%q) = (%+v,%v)\n", test.data, actualConfig, err) +
+					fmt.Sprintf("Expected config:\t %+v\n", test.expectedConfig) +
+					fmt.Sprintf("Actual config:\t %+v\n", actualConfig)
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+func TestDecodeRegistered(t *testing.T) {
+	m := imgtest.RequireFormat(t, strings.NewReader("P6 1 1 255\n\x01\x02\x03"), "pnm", image.Rect(0, 0, 1, 1))
+	imgtest.AssertEqual(t, imgtest.NRGBA(t, 1, 1, []byte{1, 2, 3, 255}), m)
+}
+
+func BenchmarkDecode(b *testing.B) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "P6\n%d %d\n255\n", 1024, 768)
+	buf.Write(make([]byte, 3*1024*768))
+	data := buf.Bytes()
+
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Decode(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
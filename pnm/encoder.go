@@ -0,0 +1,231 @@
+package pnm
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strconv"
+
+	"github.com/LukiDS/image/imgconv"
+)
+
+// Options are the encoding parameters.
+type Options struct {
+	// ASCII selects the plain (ASCII) variant of the format, e.g. P3 instead
+	// of P6. Plain files are much larger, but readable in a text editor and diff.
+	ASCII bool
+
+	// PAM selects the PAM format (P7), which keeps the alpha channel:
+	// *image.Gray and *image.Gray16 images are written with the tuple type
+	// GRAYSCALE, any other image as RGB_ALPHA. Background is ignored and
+	// PAM has no plain variant, so ASCII must not be set.
+	PAM bool
+
+	// Background is the color transparent pixels are composited over, since
+	// PPM images have no alpha channel. Nil means black, which matches the
+	// premultiplied colors returned by the RGBA method of color.Color.
+	Background color.Color
+}
+
+type encoder struct {
+	w        *bufio.Writer
+	m        image.Image
+	magic    string
+	maxval   int
+	tupltype string
+	bg       color.NRGBA64
+	opts     Options
+	err      error
+}
+
+// Encode writes the image m to w in binary netpbm format, or in the plain
+// variant if opts.ASCII is set. *image.Gray and *image.Gray16 images are
+// written as PGM (P5, P2); any other image is written as PPM (P6, P3), with
+// transparent pixels composited over opts.Background. *image.Gray16,
+// *image.NRGBA64 and *image.RGBA64 images are written with 16 bit samples
+// and a maxval of 65535, all other images with 8 bit samples.
+// If opts.PAM is set, m is written as PAM (P7) including its alpha channel.
+func Encode(w io.Writer, m image.Image, opts Options) error {
+	width := m.Bounds().Dx()
+	height := m.Bounds().Dy()
+	if width <= 0 || height <= 0 || width > pnmMaxPixels/height {
+		return fmt.Errorf("invalid image size: %dx%d, must be non-empty and at most %d pixels", width, height, pnmMaxPixels)
+	}
+
+	e := encoder{
+		w:    bufio.NewWriter(w),
+		opts: opts,
+	}
+
+	if opts.PAM {
+		if opts.ASCII {
+			return fmt.Errorf("pam has no plain variant")
+		}
+		e.initPAM(m)
+	} else {
+		e.initPNM(m)
+	}
+
+	e.encodeHeader()
+	e.encode()
+
+	if e.err != nil {
+		return e.err
+	}
+
+	return e.w.Flush()
+}
+
+// initPNM selects the PGM or PPM format for m.
+func (e *encoder) initPNM(m image.Image) {
+	bg := e.opts.Background
+	if bg == nil {
+		bg = color.Black
+	}
+
+	switch src := m.(type) {
+	case *image.Gray:
+		e.m, e.magic, e.maxval = src, "P5", 0xff
+	case *image.Gray16:
+		e.m, e.magic, e.maxval = src, "P5", 0xffff
+	case *image.NRGBA64, *image.RGBA64:
+		e.m, e.magic, e.maxval = imgconv.ToNRGBA64(m), "P6", 0xffff
+		e.bg = color.NRGBA64Model.Convert(bg).(color.NRGBA64)
+	default:
+		e.m, e.magic, e.maxval = imgconv.Flatten(m, bg), "P6", 0xff
+	}
+
+	if e.opts.ASCII {
+		//the plain variants are three numbers below the binary ones
+		e.magic = string([]byte{'P', e.magic[1] - 3})
+	}
+}
+
+// initPAM selects the PAM tuple type for m.
+func (e *encoder) initPAM(m image.Image) {
+	e.magic = "P7"
+	switch src := m.(type) {
+	case *image.Gray:
+		e.m, e.maxval, e.tupltype = src, 0xff, "GRAYSCALE"
+	case *image.Gray16:
+		e.m, e.maxval, e.tupltype = src, 0xffff, "GRAYSCALE"
+	case *image.NRGBA64, *image.RGBA64:
+		e.m, e.maxval, e.tupltype = imgconv.ToNRGBA64(m), 0xffff, "RGB_ALPHA"
+	default:
+		e.m, e.maxval, e.tupltype = imgconv.ToNRGBA(m), 0xff, "RGB_ALPHA"
+	}
+}
+
+func (e *encoder) encodeHeader() {
+	if e.magic == "P7" {
+		_, e.err = fmt.Fprintf(e.w, "P7\nWIDTH %d\nHEIGHT %d\nDEPTH %d\nMAXVAL %d\nTUPLTYPE %s\nENDHDR\n",
+			e.m.Bounds().Dx(), e.m.Bounds().Dy(), pamTupleTypes[e.tupltype], e.maxval, e.tupltype)
+		return
+	}
+
+	_, e.err = fmt.Fprintf(e.w, "%s\n%d %d\n%d\n", e.magic, e.m.Bounds().Dx(), e.m.Bounds().Dy(), e.maxval)
+}
+
+func (e *encoder) encode() {
+	if e.err != nil {
+		return
+	}
+
+	b := e.m.Bounds()
+	row := make([]byte, 0, 6*b.Dx())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		switch m := e.m.(type) {
+		case *image.Gray:
+			row = m.Pix[m.PixOffset(b.Min.X, y):m.PixOffset(b.Max.X, y)]
+
+		case *image.Gray16:
+			row = m.Pix[m.PixOffset(b.Min.X, y):m.PixOffset(b.Max.X, y)]
+
+		case *image.NRGBA:
+			if e.magic == "P7" {
+				row = m.Pix[m.PixOffset(b.Min.X, y):m.PixOffset(b.Max.X, y)]
+				break
+			}
+
+			row = row[:0]
+			i := m.PixOffset(b.Min.X, y)
+			for x := b.Min.X; x < b.Max.X; x, i = x+1, i+4 {
+				row = append(row, m.Pix[i+0], m.Pix[i+1], m.Pix[i+2])
+			}
+
+		case *image.NRGBA64:
+			if e.magic == "P7" {
+				row = m.Pix[m.PixOffset(b.Min.X, y):m.PixOffset(b.Max.X, y)]
+				break
+			}
+
+			row = row[:0]
+			i := m.PixOffset(b.Min.X, y)
+			for x := b.Min.X; x < b.Max.X; x, i = x+1, i+8 {
+				s := m.Pix[i : i+8 : i+8]
+				a := uint32(s[6])<<8 | uint32(s[7])
+				r := blend16(uint32(s[0])<<8|uint32(s[1]), uint32(e.bg.R), a)
+				g := blend16(uint32(s[2])<<8|uint32(s[3]), uint32(e.bg.G), a)
+				b := blend16(uint32(s[4])<<8|uint32(s[5]), uint32(e.bg.B), a)
+				row = append(row, byte(r>>8), byte(r), byte(g>>8), byte(g), byte(b>>8), byte(b))
+			}
+		}
+
+		if e.opts.ASCII {
+			e.writeASCII(row)
+		} else {
+			_, e.err = e.w.Write(row)
+		}
+
+		if e.err != nil {
+			return
+		}
+	}
+}
+
+// blend16 returns the 16 bit color fg with alpha a composited over the opaque color bg.
+func blend16(fg, bg, a uint32) uint32 {
+	return uint32((uint64(fg)*uint64(a) + uint64(bg)*uint64(0xffff-a) + 0x7fff) / 0xffff)
+}
+
+// maxLineLength is the longest line allowed in plain netpbm files.
+const maxLineLength = 70
+
+// writeASCII writes the samples of one row as decimal numbers, wrapping lines
+// before they exceed maxLineLength characters. The samples are single bytes
+// for a maxval up to 255 and two big-endian bytes otherwise.
+func (e *encoder) writeASCII(samples []byte) {
+	size := 1
+	if e.maxval > 0xff {
+		size = 2
+	}
+
+	line := make([]byte, 0, maxLineLength+1)
+	for i := 0; i < len(samples); i += size {
+		v := uint64(samples[i])
+		if size == 2 {
+			v = v<<8 | uint64(samples[i+1])
+		}
+
+		n := len(line)
+		if n > 0 {
+			line = append(line, ' ')
+		}
+		line = strconv.AppendUint(line, v, 10)
+
+		if n > 0 && len(line) > maxLineLength {
+			//move the sample to the next line
+			next := append([]byte(nil), line[n+1:]...)
+			line = append(line[:n], '\n')
+			if _, e.err = e.w.Write(line); e.err != nil {
+				return
+			}
+			line = append(line[:0], next...)
+		}
+	}
+
+	line = append(line, '\n')
+	_, e.err = e.w.Write(line)
+}
@@ -0,0 +1,193 @@
+package pnm
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/LukiDS/image/imgconv"
+	"github.com/LukiDS/image/imgtest"
+)
+
+func TestEncode(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	m.SetNRGBA(0, 0, color.NRGBA{255, 0, 0, 255})
+	m.SetNRGBA(1, 0, color.NRGBA{0, 128, 255, 255})
+	m.SetNRGBA(0, 1, color.NRGBA{1, 2, 3, 255})
+	m.SetNRGBA(1, 1, color.NRGBA{200, 100, 50, 0})
+
+	tests := []struct {
+		name     string
+		m        image.Image
+		opts     Options
+		expected string
+	}{
+		{
+			name:     "should encode binary P6",
+			m:        m,
+			expected: "P6\n2 2\n255\n\xff\x00\x00\x00\x80\xff\x01\x02\x03\x00\x00\x00",
+		},
+		{
+			name:     "should encode plain P3",
+			m:        m,
+			opts:     Options{ASCII: true},
+			expected: "P3\n2 2\n255\n255 0 0 0 128 255\n1 2 3 0 0 0\n",
+		},
+		{
+			name:     "should composite transparent pixels over the background",
+			m:        m.SubImage(image.Rect(1, 1, 2, 2)),
+			opts:     Options{ASCII: true, Background: color.White},
+			expected: "P3\n1 1\n255\n255 255 255\n",
+		},
+		{
+			name:     "should composite translucent pixels over black by default",
+			m:        imgconv.UniformNRGBA(color.NRGBA{255, 100, 0, 128}, image.Rect(0, 0, 1, 1)),
+			opts:     Options{ASCII: true},
+			expected: "P3\n1 1\n255\n128 50 0\n",
+		},
+		{
+			name:     "should encode any image type as PPM",
+			m:        image.NewCMYK(image.Rect(3, 3, 5, 4)),
+			expected: "P6\n2 1\n255\n\xff\xff\xff\xff\xff\xff",
+		},
+		{
+			name:     "should encode gray images as binary PGM",
+			m:        &image.Gray{Pix: []byte{0, 1, 2, 9, 0x80, 0xff}, Stride: 3, Rect: image.Rect(-1, 0, 2, 2)},
+			expected: "P5\n3 2\n255\n\x00\x01\x02\x09\x80\xff",
+		},
+		{
+			name:     "should encode gray images as plain PGM",
+			m:        (&image.Gray{Pix: []byte{0, 1, 2, 9, 0x80, 0xff}, Stride: 3, Rect: image.Rect(0, 0, 3, 2)}).SubImage(image.Rect(1, 0, 3, 2)),
+			opts:     Options{ASCII: true},
+			expected: "P2\n2 2\n255\n1 2\n128 255\n",
+		},
+		{
+			name:     "should encode 16 bit gray images as binary PGM",
+			m:        &image.Gray16{Pix: []byte{0x00, 0x01, 0x12, 0x34, 0xff, 0xff}, Stride: 6, Rect: image.Rect(0, 0, 3, 1)},
+			expected: "P5\n3 1\n65535\n\x00\x01\x12\x34\xff\xff",
+		},
+		{
+			name:     "should encode 16 bit gray images as plain PGM",
+			m:        &image.Gray16{Pix: []byte{0x00, 0x01, 0x12, 0x34, 0xff, 0xff}, Stride: 6, Rect: image.Rect(0, 0, 3, 1)},
+			opts:     Options{ASCII: true},
+			expected: "P2\n3 1\n65535\n1 4660 65535\n",
+		},
+		{
+			name:     "should encode 16 bit color images as binary PPM",
+			m:        &image.NRGBA64{Pix: []byte{0x12, 0x34, 0x00, 0x01, 0xab, 0xcd, 0xff, 0xff}, Stride: 8, Rect: image.Rect(0, 0, 1, 1)},
+			expected: "P6\n1 1\n65535\n\x12\x34\x00\x01\xab\xcd",
+		},
+		{
+			name:     "should composite 16 bit color images over the background",
+			m:        &image.RGBA64{Pix: []byte{0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x80, 0x00}, Stride: 8, Rect: image.Rect(0, 0, 1, 1)},
+			opts:     Options{ASCII: true, Background: color.White},
+			expected: "P3\n1 1\n65535\n65535 32767 32767\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Encode(&buf, test.m, test.opts); err != nil {
+				t.Fatalf("Encode: unexpected error: %v\n", err)
+			}
+
+			if actual := buf.String(); actual != test.expected {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Encode(%v, %+v)\n", test.m.Bounds(), test.opts) +
+					fmt.Sprintf("Expected output:\t %q\n", test.expected) +
+					fmt.Sprintf("Actual output:\t %q\n", actual)
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+func TestEncodeLineLength(t *testing.T) {
+	m := imgconv.UniformNRGBA(color.NRGBA{255, 255, 255, 255}, image.Rect(0, 0, 100, 3))
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, m, Options{ASCII: true}); err != nil {
+		t.Fatalf("Encode: unexpected error: %v\n", err)
+	}
+
+	for i, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if len(line) > maxLineLength {
+			t.Fatalf("Encode: line %d has %d characters, expected at most %d\n", i, len(line), maxLineLength)
+		}
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(-3, 5, 30, 22))
+	rand.New(rand.NewSource(1)).Read(m.Pix)
+	for i := 3; i < len(m.Pix); i += 4 {
+		m.Pix[i] = 0xff
+	}
+
+	gray := image.NewGray(image.Rect(4, -2, 21, 9))
+	rand.New(rand.NewSource(2)).Read(gray.Pix)
+
+	gray16 := image.NewGray16(image.Rect(0, 0, 13, 7))
+	rand.New(rand.NewSource(3)).Read(gray16.Pix)
+
+	rgb64 := image.NewNRGBA64(image.Rect(-8, -8, 5, 3))
+	rand.New(rand.NewSource(4)).Read(rgb64.Pix)
+	for i := 6; i < len(rgb64.Pix); i += 8 {
+		rgb64.Pix[i], rgb64.Pix[i+1] = 0xff, 0xff
+	}
+
+	for _, src := range []image.Image{m, gray, gray16, rgb64} {
+		for _, opts := range []Options{{}, {ASCII: true}} {
+			t.Run(fmt.Sprintf("%T/ASCII=%t", src, opts.ASCII), func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := Encode(&buf, src, opts); err != nil {
+					t.Fatalf("Encode: unexpected error: %v\n", err)
+				}
+
+				actual, err := Decode(&buf)
+				if err != nil {
+					t.Fatalf("Decode: unexpected error: %v\n", err)
+				}
+
+				var expected image.Image
+				switch src := src.(type) {
+				case *image.Gray:
+					expected = &image.Gray{Pix: src.Pix, Stride: src.Stride, Rect: src.Rect.Sub(src.Rect.Min)}
+				case *image.Gray16:
+					expected = &image.Gray16{Pix: src.Pix, Stride: src.Stride, Rect: src.Rect.Sub(src.Rect.Min)}
+				case *image.NRGBA64:
+					expected = &image.NRGBA64{Pix: src.Pix, Stride: src.Stride, Rect: src.Rect.Sub(src.Rect.Min)}
+				default:
+					expected, _ = imgconv.Crop(src, src.Bounds())
+				}
+				imgtest.AssertEqual(t, expected, actual)
+				imgtest.AssertColorModel(t, expected.ColorModel(), actual)
+			})
+		}
+	}
+}
+
+func TestEncodeEmptyImage(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 0, 5)), Options{}); err == nil {
+		t.Errorf("Encode(empty image): expected an error\n")
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	m := image.NewNRGBA(image.Rect(0, 0, 1024, 768))
+	rand.New(rand.NewSource(1)).Read(m.Pix)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := Encode(io.Discard, m, Options{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package pnm
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/LukiDS/image/imgconv"
+	"github.com/LukiDS/image/imgtest"
+)
+
+func TestDecodePAM(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected image.Image
+	}{
+		{
+			// as written by pamtopam from a 2x1 RGBA image
+			name:     "should decode RGB_ALPHA",
+			data:     "P7\nWIDTH 2\nHEIGHT 1\nDEPTH 4\nMAXVAL 255\nTUPLTYPE RGB_ALPHA\nENDHDR\n\xff\x00\x00\x80\x01\x02\x03\x00",
+			expected: &image.NRGBA{Pix: []byte{0xff, 0x00, 0x00, 0x80, 0x01, 0x02, 0x03, 0x00}, Stride: 8, Rect: image.Rect(0, 0, 2, 1)},
+		},
+		{
+			name:     "should decode RGB",
+			data:     "P7\nWIDTH 1\nHEIGHT 2\nDEPTH 3\nMAXVAL 255\nTUPLTYPE RGB\nENDHDR\n\x01\x02\x03\x04\x05\x06",
+			expected: &image.NRGBA{Pix: []byte{1, 2, 3, 0xff, 4, 5, 6, 0xff}, Stride: 4, Rect: image.Rect(0, 0, 1, 2)},
+		},
+		{
+			name:     "should decode GRAYSCALE",
+			data:     "P7\nWIDTH 3\nHEIGHT 1\nDEPTH 1\nMAXVAL 255\nTUPLTYPE GRAYSCALE\nENDHDR\n\x00\x80\xff",
+			expected: &image.Gray{Pix: []byte{0x00, 0x80, 0xff}, Stride: 3, Rect: image.Rect(0, 0, 3, 1)},
+		},
+		{
+			name:     "should decode GRAYSCALE_ALPHA",
+			data:     "P7\nWIDTH 2\nHEIGHT 1\nDEPTH 2\nMAXVAL 255\nTUPLTYPE GRAYSCALE_ALPHA\nENDHDR\n\x40\xff\x80\x10",
+			expected: &image.NRGBA{Pix: []byte{0x40, 0x40, 0x40, 0xff, 0x80, 0x80, 0x80, 0x10}, Stride: 8, Rect: image.Rect(0, 0, 2, 1)},
+		},
+		{
+			name: "should decode 16 bit GRAYSCALE_ALPHA",
+			data: "P7\nWIDTH 1\nHEIGHT 1\nDEPTH 2\nMAXVAL 65535\nTUPLTYPE GRAYSCALE_ALPHA\nENDHDR\n\x12\x34\x80\x00",
+			expected: &image.NRGBA64{Pix: []byte{
+				0x12, 0x34, 0x12, 0x34, 0x12, 0x34, 0x80, 0x00,
+			}, Stride: 8, Rect: image.Rect(0, 0, 1, 1)},
+		},
+		{
+			name: "should decode 16 bit RGB_ALPHA with a maxval of 1023",
+			data: "P7\nWIDTH 1\nHEIGHT 1\nDEPTH 4\nMAXVAL 1023\nTUPLTYPE RGB_ALPHA\nENDHDR\n\x03\xff\x00\x00\x02\x00\x00\x01",
+			expected: &image.NRGBA64{Pix: []byte{
+				0xff, 0xff, 0x00, 0x00, 0x80, 0x20, 0x00, 0x40,
+			}, Stride: 8, Rect: image.Rect(0, 0, 1, 1)},
+		},
+		{
+			name:     "should accept comments, any keyword order and derive the tuple type from the depth",
+			data:     "P7\n# written by hand\nMAXVAL 3\n  DEPTH 1\nHEIGHT 1\n#WIDTH 5\nWIDTH 2\nENDHDR\n\x01\x03",
+			expected: &image.Gray{Pix: []byte{85, 255}, Stride: 2, Rect: image.Rect(0, 0, 2, 1)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := Decode(strings.NewReader(test.data))
+			if err != nil {
+				t.Fatalf("Decode(%q): unexpected error: %v\n", test.data, err)
+			}
+
+			imgtest.AssertEqual(t, test.expected, actual)
+
+			imgtest.AssertColorModel(t, test.expected.ColorModel(), actual)
+		})
+	}
+}
+
+func TestDecodePAMErrors(t *testing.T) {
+	const valid = "WIDTH 1\nHEIGHT 1\nDEPTH 3\nMAXVAL 255\n"
+
+	tests := []struct {
+		name  string
+		data  string
+		error string
+	}{
+		{name: "missing ENDHDR", data: "P7\n" + valid + "TUPLTYPE RGB\n", error: "missing ENDHDR"},
+		{name: "missing WIDTH", data: "P7\nHEIGHT 1\nDEPTH 3\nMAXVAL 255\nENDHDR\n\x00\x00\x00", error: "missing WIDTH"},
+		{name: "missing MAXVAL", data: "P7\nWIDTH 1\nHEIGHT 1\nDEPTH 3\nENDHDR\n\x00\x00\x00", error: "missing MAXVAL"},
+		{name: "invalid WIDTH", data: "P7\nWIDTH x\nHEIGHT 1\nDEPTH 3\nMAXVAL 255\nENDHDR\n", error: "invalid WIDTH"},
+		{name: "zero DEPTH", data: "P7\nWIDTH 1\nHEIGHT 1\nDEPTH 0\nMAXVAL 255\nENDHDR\n", error: "invalid DEPTH"},
+		{name: "WIDTH with two values", data: "P7\nWIDTH 1 2\nHEIGHT 1\nDEPTH 3\nMAXVAL 255\nENDHDR\n", error: "exactly one value"},
+		{name: "unknown keyword", data: "P7\n" + valid + "COLORS 3\nENDHDR\n", error: "unknown keyword \"COLORS\""},
+		{name: "unknown tuple type", data: "P7\n" + valid + "TUPLTYPE CMYK\nENDHDR\n", error: "unsupported pam tuple type \"CMYK\""},
+		{name: "depth mismatch", data: "P7\n" + valid + "TUPLTYPE RGB_ALPHA\nENDHDR\n", error: "tuple type RGB_ALPHA needs depth 4, got 3"},
+		{name: "unsupported depth", data: "P7\nWIDTH 1\nHEIGHT 1\nDEPTH 5\nMAXVAL 255\nENDHDR\n", error: "unsupported pam depth 5"},
+		{name: "maxval too large", data: "P7\nWIDTH 1\nHEIGHT 1\nDEPTH 3\nMAXVAL 65536\nENDHDR\n", error: "maxval 65536"},
+		{name: "truncated raster", data: "P7\n" + valid + "TUPLTYPE RGB\nENDHDR\n\x00\x00", error: "truncated"},
+		{name: "sample exceeds maxval", data: "P7\nWIDTH 1\nHEIGHT 1\nDEPTH 1\nMAXVAL 10\nENDHDR\n\x0b", error: "exceeds maxval"},
+		{name: "line too long", data: "P7\n#" + strings.Repeat(" ", 5000) + "\nWIDTH " + strings.Repeat("1", 5000) + "\n", error: "line too long"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Decode(strings.NewReader(test.data))
+			if err == nil || !strings.Contains(err.Error(), test.error) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Decode(%q)\n", test.data) +
+					fmt.Sprintf("Expected error:\t containing %q\n", test.error) +
+					fmt.Sprintf("Actual error:\t %v\n", err)
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+func TestDecodePAMConfig(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected image.Config
+	}{
+		{data: "P7\nWIDTH 3\nHEIGHT 2\nDEPTH 4\nMAXVAL 255\nTUPLTYPE RGB_ALPHA\nENDHDR\n", expected: image.Config{ColorModel: color.NRGBAModel, Width: 3, Height: 2}},
+		{data: "P7\nWIDTH 3\nHEIGHT 2\nDEPTH 2\nMAXVAL 4095\nTUPLTYPE GRAYSCALE_ALPHA\nENDHDR\n", expected: image.Config{ColorModel: color.NRGBA64Model, Width: 3, Height: 2}},
+		{data: "P7\nWIDTH 3\nHEIGHT 2\nDEPTH 1\nMAXVAL 65535\nTUPLTYPE GRAYSCALE\nENDHDR\n", expected: image.Config{ColorModel: color.Gray16Model, Width: 3, Height: 2}},
+	}
+
+	for _, test := range tests {
+		actual, err := DecodeConfig(strings.NewReader(test.data))
+		if err != nil || actual != test.expected {
+			t.Errorf("DecodeConfig(%q) = (%+v, %v), expected %+v\n", test.data, actual, err, test.expected)
+		}
+	}
+}
+
+func TestEncodePAM(t *testing.T) {
+	m := &image.NRGBA{Pix: []byte{0xff, 0x00, 0x00, 0x80, 0x01, 0x02, 0x03, 0x00}, Stride: 8, Rect: image.Rect(0, 0, 2, 1)}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, m, Options{PAM: true}); err != nil {
+		t.Fatalf("Encode: unexpected error: %v\n", err)
+	}
+
+	expected := "P7\nWIDTH 2\nHEIGHT 1\nDEPTH 4\nMAXVAL 255\nTUPLTYPE RGB_ALPHA\nENDHDR\n\xff\x00\x00\x80\x01\x02\x03\x00"
+	if actual := buf.String(); actual != expected {
+		format := fmt.Sprintf("\n") +
+			fmt.Sprintf("Encode(%v, {PAM: true})\n", m.Bounds()) +
+			fmt.Sprintf("Expected output:\t %q\n", expected) +
+			fmt.Sprintf("Actual output:\t %q\n", actual)
+		t.Errorf(format)
+	}
+
+	if err := Encode(&buf, m, Options{PAM: true, ASCII: true}); err == nil {
+		t.Errorf("Encode({PAM: true, ASCII: true}): expected an error\n")
+	}
+}
+
+func TestEncodePAMRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	nrgba := image.NewNRGBA(image.Rect(-2, 3, 15, 12))
+	rnd.Read(nrgba.Pix)
+
+	nrgba64 := image.NewNRGBA64(image.Rect(0, 0, 9, 4))
+	rnd.Read(nrgba64.Pix)
+
+	gray := image.NewGray(image.Rect(1, 1, 8, 5))
+	rnd.Read(gray.Pix)
+
+	gray16 := image.NewGray16(image.Rect(0, 0, 6, 6))
+	rnd.Read(gray16.Pix)
+
+	rgba := image.NewRGBA(image.Rect(0, 0, 5, 5))
+	for i := 0; i < len(rgba.Pix); i += 4 {
+		a := byte(rnd.Intn(256))
+		rgba.Pix[i+0], rgba.Pix[i+1], rgba.Pix[i+2], rgba.Pix[i+3] = a/2, a/3, a, a
+	}
+
+	tests := []struct {
+		m        image.Image
+		expected image.Image
+	}{
+		{m: nrgba, expected: &image.NRGBA{Pix: nrgba.Pix, Stride: nrgba.Stride, Rect: nrgba.Rect.Sub(nrgba.Rect.Min)}},
+		{m: nrgba64, expected: nrgba64},
+		{m: gray, expected: &image.Gray{Pix: gray.Pix, Stride: gray.Stride, Rect: gray.Rect.Sub(gray.Rect.Min)}},
+		{m: gray16, expected: gray16},
+		{m: rgba, expected: imgconv.ToNRGBA(rgba)},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%T", test.m), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Encode(&buf, test.m, Options{PAM: true}); err != nil {
+				t.Fatalf("Encode: unexpected error: %v\n", err)
+			}
+
+			actual, err := Decode(&buf)
+			if err != nil {
+				t.Fatalf("Decode: unexpected error: %v\n", err)
+			}
+
+			imgtest.AssertEqual(t, test.expected, actual)
+
+			imgtest.AssertColorModel(t, test.expected.ColorModel(), actual)
+		})
+	}
+}
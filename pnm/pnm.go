@@ -0,0 +1,32 @@
+// Package pnm implements a decoder and an encoder for the netpbm image formats.
+//
+// All six formats are supported in their binary and plain (ASCII) variant:
+// PPM images (P6, P3) are decoded into *image.NRGBA images, PGM images
+// (P5, P2) into *image.Gray images and PBM bitmaps (P4, P1) into *image.Gray
+// images with the values 0 for black and 255 for white. PPM and PGM images
+// with a maxval above 255 store 16 bit samples and are decoded into
+// *image.NRGBA64 and *image.Gray16 images. Samples are scaled from 0..maxval
+// to the full range of the returned image type.
+//
+// PAM images (P7) with the tuple types GRAYSCALE, GRAYSCALE_ALPHA, RGB and
+// RGB_ALPHA are decoded into *image.Gray, *image.Gray16, *image.NRGBA or
+// *image.NRGBA64 images, depending on their depth and maxval.
+package pnm
+
+import (
+	"image"
+)
+
+const (
+	// pnmMaxPixels guards against allocating huge images for corrupt headers.
+	pnmMaxPixels = 400_000_000
+
+	// pnmMaxValue is the largest maxval allowed by the netpbm formats.
+	pnmMaxValue = 65535
+)
+
+func init() {
+	for _, magic := range []string{"P1", "P2", "P3", "P4", "P5", "P6", "P7"} {
+		image.RegisterFormat("pnm", magic, Decode, DecodeConfig)
+	}
+}
@@ -17,19 +17,107 @@ type qoiHeader struct {
 	colorspace uint8
 }
 
+// DecodeOptions are the decoding parameters of DecodeWithOptions. The zero
+// value decodes like Decode.
+type DecodeOptions struct {
+	// MapColor, if not nil, replaces every decoded color by its result, e.g.
+	// to swap a palette at load time. Each row is mapped as soon as it is
+	// decoded, while the decoder keeps using the original colors of the
+	// stream. MapColor must depend on its argument only: it is called once
+	// for equal neighboring pixels.
+	MapColor func(color.NRGBA) color.NRGBA
+
+	// MaxPaletteColors, if greater than zero, makes DecodeWithOptions return
+	// images with at most that many distinct colors as *image.Paletted, with
+	// the colors in the order of their first pixel. Images with more colors
+	// are returned as *image.NRGBA. Values above 256 mean 256.
+	MaxPaletteColors int
+
+	// PreferGray makes DecodeWithOptions return opaque gray images, whose
+	// pixels all have equal red, green and blue values and an alpha of 255,
+	// as *image.Gray, which needs a quarter of the memory of *image.NRGBA.
+	// The property is checked for every row as it is decoded; all other
+	// images are returned as without PreferGray. Gray images are returned
+	// as *image.Gray even if MaxPaletteColors is set.
+	PreferGray bool
+
+	// IgnoreAlpha sets the alpha of every decoded pixel to 255, before
+	// MapColor and the other options see it, while the decoder keeps using
+	// the alpha values of the stream. Rows are only changed from the first
+	// translucent pixel on, so opaque images cost almost nothing extra.
+	IgnoreAlpha bool
+
+	// Recover makes DecodeWithOptions return corrupt images instead of
+	// failing, with a *RecoveryReport wrapping ErrCorrupt as error. The
+	// image ends with the row of the failure, whose remaining pixels are set
+	// to RecoverColor, so a crafted header cannot make the decoder fill
+	// gigabytes; if the failure is at the first pixel, only the report is
+	// returned. Since every byte is a valid QOI chunk, corruption is only
+	// detected where the data ends early, where the end marker appears
+	// inside the pixel data, and where it is missing after the last pixel
+	// or follows a run beyond it; the pixels before the failure may be
+	// wrong as well. Data following the end marker is ignored.
+	Recover bool
+
+	// RecoverColor is the color of the pixels which could not be decoded
+	// with Recover. The pixels are decoded like the others, i.e. MapColor
+	// and IgnoreAlpha apply to them as well.
+	RecoverColor color.NRGBA
+
+	// FlipVertical stores the rows of the image from the bottom up, as
+	// OpenGL expects texture data. *image.NRGBA images are decoded into their
+	// flipped rows directly; the other image types are flipped in place
+	// after decoding.
+	FlipVertical bool
+
+	// RowAlignment, if greater than one, makes every row of the image start
+	// at a multiple of that many bytes, as e.g. Vulkan expects for texture
+	// uploads. The Stride of the image is rounded up accordingly, and the
+	// padding bytes after each row are zero. *image.NRGBA images are decoded
+	// into their aligned rows directly; the other image types are copied
+	// after decoding. RowAlignment must be at most 65536.
+	RowAlignment int
+}
+
+// qoiState is the state of the decoder between two chunks.
+type qoiState struct {
+	index [qoiMaxBufferSize]color.NRGBA
+	prev  color.NRGBA
+	run   uint8
+	// alpha is the AND of all decoded alpha values, below 255 after the
+	// first translucent pixel.
+	alpha uint8
+}
+
 type decoder struct {
-	m   *image.NRGBA
-	buf *bufio.Reader
-	h   qoiHeader
-	err error
+	m     image.Image
+	buf   *bufio.Reader
+	h     qoiHeader
+	opts  DecodeOptions
+	state qoiState
+	// strictPalette makes images which do not fit into a compact
+	// representation an error instead of decoding them as *image.NRGBA.
+	strictPalette bool
+	// nrgba64 decodes the image as *image.NRGBA64.
+	nrgba64 bool
+	// thumbnail scales the image down on decoding if maxMem is set.
+	thumbnail thumbnailLimits
+	// count counts the bytes read for the offsets of a RecoveryReport.
+	count *countingReader
+	// concatenated allows data following the end marker.
+	concatenated bool
+	// pixels is the number of pixels decoded so far, and report the
+	// failure of a corrupt image with opts.Recover.
+	pixels int
+	report *RecoveryReport
+	err    error
 }
 
 func (d *decoder) decodeHeader() {
 	h := make([]byte, qoiHeaderSize)
 
-	_, err := d.buf.Read(h)
-	if err != nil {
-		d.err = err
+	if _, err := io.ReadFull(d.buf, h); err != nil {
+		d.err = fmt.Errorf("image not valid qoi file")
 		return
 	}
 
@@ -44,7 +132,7 @@ func (d *decoder) decodeHeader() {
 		return
 	}
 
-	if d.h.width <= 0 || d.h.height <= 0 || d.h.width*d.h.height > qoiMaxPixels {
+	if d.h.width <= 0 || d.h.height <= 0 || d.h.width > qoiMaxPixels/d.h.height {
 		d.err = fmt.Errorf("image size invalid")
 		return
 	}
@@ -55,50 +143,149 @@ func (d *decoder) decode() {
 		return
 	}
 
-	d.m = image.NewNRGBA(image.Rect(0, 0, d.h.width, d.h.height))
+	if d.opts.RowAlignment > maxRowAlignment {
+		d.err = fmt.Errorf("invalid row alignment %d, must be at most %d", d.opts.RowAlignment, maxRowAlignment)
+		return
+	}
 
-	colorBuffer := [qoiMaxBufferSize]color.NRGBA{}
-	pxPrev := color.NRGBA{0, 0, 0, 255}
+	d.state = qoiState{prev: color.NRGBA{0, 0, 0, 255}, alpha: 0xff}
 
-	run := uint8(0)
-	maxPixelPos := d.h.width * d.h.height
-	for pxPos := 0; pxPos < maxPixelPos; pxPos++ {
-		if d.err != nil {
-			return
+	if d.thumbnail.maxMem > 0 {
+		d.decodeThumbnail()
+		return
+	}
+
+	var sinks []rowSink
+	if d.opts.PreferGray {
+		sinks = append(sinks, &graySink{width: d.h.width, pix: make([]byte, 0, d.initialPixels())})
+	}
+	if d.opts.MaxPaletteColors > 0 {
+		sinks = append(sinks, d.newPaletteSink())
+	}
+	if d.nrgba64 {
+		sinks = append(sinks, &nrgba64Sink{width: d.h.width, pix: make([]byte, 0, 8*d.initialPixels())})
+	}
+	if len(sinks) > 0 {
+		d.decodeRows(sinks)
+		if d.err == nil {
+			d.layOut(d.m)
+		}
+		return
+	}
+
+	if d.opts.FlipVertical || d.opts.RowAlignment > 1 {
+		d.decodeLaidOut()
+		return
+	}
+
+	pix := d.decodeNRGBA(nil, d.h.height)
+	if d.err != nil {
+		return
+	}
+
+	d.m = d.nrgba(pix)
+}
+
+// decodeNRGBA appends the next rows of NRGBA pixels to pix, applying
+// opts.IgnoreAlpha and opts.MapColor. It allocates pix if it is nil.
+func (d *decoder) decodeNRGBA(pix []byte, rows int) []byte {
+	if pix == nil {
+		pix = make([]byte, 0, 4*d.initialPixels())
+	}
+
+	if d.opts.MapColor == nil && !d.opts.IgnoreAlpha {
+		return d.decodePixels(pix, rows*d.h.width)
+	}
+
+	//change every row as soon as it is decoded, while it is still cached
+	for y := 0; y < rows && d.err == nil; y++ {
+		row := len(pix)
+		pix = d.decodePixels(pix, d.h.width)
+		if d.opts.IgnoreAlpha && d.state.alpha != 0xff {
+			for i := row + 3; i < len(pix); i += 4 {
+				pix[i] = 0xff
+			}
+		}
+		if d.opts.MapColor != nil {
+			mapColors(pix[row:], d.opts.MapColor)
 		}
+	}
+
+	return pix
+}
+
+// nrgba returns the image of the decoded pixels pix.
+func (d *decoder) nrgba(pix []byte) *image.NRGBA {
+	return &image.NRGBA{
+		Pix:    pix,
+		Stride: 4 * d.h.width,
+		Rect:   image.Rect(0, 0, d.h.width, d.h.height),
+	}
+}
+
+// decodePixels appends the next count decoded pixels to pix, continuing from
+// and updating d.state. With opts.Recover, the image ends with the row of a
+// corruption, whose remaining pixels are set to opts.RecoverColor, and no
+// more pixels are appended.
+func (d *decoder) decodePixels(pix []byte, count int) []byte {
+	if d.report != nil {
+		return pix
+	}
+
+	n := len(pix)
+	pix = d.decodeChunks(pix, count)
+	decoded := (len(pix) - n) / 4
+	if d.err != nil && d.opts.Recover && isCorrupt(d.err) {
+		pixel := d.pixels + decoded
+		d.recover(pixel, d.offset())
+		pix = d.cutRecovered(pix, pixel)
+	}
+	d.pixels += decoded
+
+	return pix
+}
 
-		x := pxPos % d.h.width
-		y := pxPos / d.h.width
+// decodeChunks appends the next count pixels decoded from the chunks to pix.
+func (d *decoder) decodeChunks(pix []byte, count int) []byte {
+	colorBuffer := d.state.index
+	pxPrev := d.state.prev
+	run := d.state.run
+	alpha := d.state.alpha
+
+	for n := 0; n < count; n++ {
+		if d.err != nil {
+			return pix
+		}
 
 		if run > 0 {
 			run--
-			d.m.SetNRGBA(x, y, pxPrev)
+			pix = append(pix, pxPrev.R, pxPrev.G, pxPrev.B, pxPrev.A)
 
 			continue
 		}
 
 		b1, err := d.buf.ReadByte()
 		if err != nil {
-			d.err = err
-			return
+			d.err = truncated(err)
+			return pix
 		}
 
 		switch {
 		case b1 == opRGB:
 			r, err := d.buf.ReadByte()
 			if err != nil {
-				d.err = err
-				return
+				d.err = truncated(err)
+				return pix
 			}
 			g, err := d.buf.ReadByte()
 			if err != nil {
-				d.err = err
-				return
+				d.err = truncated(err)
+				return pix
 			}
 			b, err := d.buf.ReadByte()
 			if err != nil {
-				d.err = err
-				return
+				d.err = truncated(err)
+				return pix
 			}
 
 			pxPrev.R = r
@@ -108,32 +295,40 @@ func (d *decoder) decode() {
 		case b1 == opRGBA:
 			r, err := d.buf.ReadByte()
 			if err != nil {
-				d.err = err
-				return
+				d.err = truncated(err)
+				return pix
 			}
 			g, err := d.buf.ReadByte()
 			if err != nil {
-				d.err = err
-				return
+				d.err = truncated(err)
+				return pix
 			}
 			b, err := d.buf.ReadByte()
 			if err != nil {
-				d.err = err
-				return
+				d.err = truncated(err)
+				return pix
 			}
 			a, err := d.buf.ReadByte()
 			if err != nil {
-				d.err = err
-				return
+				d.err = truncated(err)
+				return pix
 			}
 
 			pxPrev.R = r
 			pxPrev.G = g
 			pxPrev.B = b
 			pxPrev.A = a
+			//only RGBA and INDEX chunks change the alpha, the others copy it
+			alpha &= a
 
 		case (b1 & maskOP) == opINDEX:
+			//encoders write a run for repeated pixels, not a chunk 0 after chunk 0
+			if b1 == 0 && d.opts.Recover && d.atEndMarker() {
+				d.err = errEarlyEndMarker
+				return pix
+			}
 			pxPrev = colorBuffer[(b1 & mask6)]
+			alpha &= pxPrev.A
 
 		case (b1 & maskOP) == opDIFF:
 			pxPrev.R += ((b1 >> 4) & mask2) - 2
@@ -143,8 +338,8 @@ func (d *decoder) decode() {
 		case (b1 & maskOP) == opLUMA:
 			b2, err := d.buf.ReadByte()
 			if err != nil {
-				d.err = err
-				return
+				d.err = truncated(err)
+				return pix
 			}
 
 			vg := (b1 & mask6) - 32
@@ -158,29 +353,75 @@ func (d *decoder) decode() {
 		}
 
 		colorBuffer[hash(pxPrev)] = pxPrev
-		d.m.SetNRGBA(x, y, pxPrev)
+		pix = append(pix, pxPrev.R, pxPrev.G, pxPrev.B, pxPrev.A)
+	}
+
+	d.state = qoiState{index: colorBuffer, prev: pxPrev, run: run, alpha: alpha}
+
+	return pix
+}
+
+// mapColors replaces the NRGBA colors in pix by their result of mapColor,
+// calling it once for equal neighboring colors.
+func mapColors(pix []byte, mapColor func(color.NRGBA) color.NRGBA) {
+	var c, out color.NRGBA
+	for i := 0; i < len(pix); i += 4 {
+		p := pix[i : i+4 : i+4]
+		if px := (color.NRGBA{p[0], p[1], p[2], p[3]}); i == 0 || px != c {
+			c, out = px, mapColor(px)
+		}
+		p[0], p[1], p[2], p[3] = out.R, out.G, out.B, out.A
 	}
 }
 
+// truncated returns err, replacing io.EOF by an error wrapping
+// io.ErrUnexpectedEOF since the data ended in the middle of the image.
+func truncated(err error) error {
+	if err == io.EOF {
+		return fmt.Errorf("truncated qoi data: %w", io.ErrUnexpectedEOF)
+	}
+
+	return err
+}
+
+func (d *decoder) decodeAll() {
+	d.decodeHeader()
+	d.decode()
+	d.decodePadding()
+}
+
 func (d *decoder) decodePadding() {
 	if d.err != nil {
 		return
 	}
-
-	padding := make([]byte, len(qoiEndMarker))
-	_, err := d.buf.Read(padding)
-	if err != nil {
-		d.err = err
+	if d.report != nil {
+		d.skipEndMarker()
 		return
 	}
 
-	if !bytes.Equal(padding, qoiEndMarker) {
+	padding, err := d.buf.Peek(len(qoiEndMarker))
+	switch {
+	case err != nil:
+		d.err = fmt.Errorf("truncated qoi end marker: %w", io.ErrUnexpectedEOF)
+	case !bytes.Equal(padding, qoiEndMarker):
 		d.err = fmt.Errorf("unexpected EOF")
+	case d.opts.Recover && d.state.run > 0:
+		d.err = errLongRun
+	}
+	if d.err != nil {
+		if d.opts.Recover {
+			d.recover(d.h.width*d.h.height, d.offset())
+			d.skipEndMarker()
+		}
+		return
+	}
+	d.buf.Discard(len(qoiEndMarker))
+
+	if d.opts.Recover || d.concatenated {
 		return
 	}
 
-	_, err = d.buf.ReadByte()
-	if err != io.EOF {
+	if _, err := d.buf.ReadByte(); err != io.EOF {
 		d.err = fmt.Errorf("invalid qoi size")
 		return
 	}
@@ -204,17 +445,54 @@ func DecodeConfig(r io.Reader) (image.Config, error) {
 }
 
 func Decode(r io.Reader) (image.Image, error) {
-	d := decoder{
-		buf: bufio.NewReader(r),
+	return DecodeWithOptions(r, DecodeOptions{})
+}
+
+// DecodeWithOptions reads a QOI image from r like Decode, applying opts.
+func DecodeWithOptions(r io.Reader, opts DecodeOptions) (image.Image, error) {
+	return decodeWith(r, &decoder{opts: opts})
+}
+
+// DecodeNRGBA64 reads a QOI image from r and returns it as an
+// *image.NRGBA64 image, with every 8 bit value v widened to v<<8|v like
+// color.NRGBA64Model does. The pixels are widened row by row as they are
+// decoded, so no *image.NRGBA image is allocated on the way.
+func DecodeNRGBA64(r io.Reader) (*image.NRGBA64, error) {
+	m, err := decodeWith(r, &decoder{nrgba64: true})
+	if err != nil {
+		return nil, err
 	}
 
-	d.decodeHeader()
-	d.decode()
-	d.decodePadding()
+	return m.(*image.NRGBA64), nil
+}
 
+// decodeWith decodes the image of r with the preset decoder d.
+func decodeWith(r io.Reader, d *decoder) (image.Image, error) {
+	if o := loadObserver(); o != nil {
+		return observeDecode(o, r, d)
+	}
+
+	if d.opts.Recover {
+		d.count = &countingReader{r: r}
+		r = d.count
+	}
+	d.buf = bufio.NewReader(r)
+	d.decodeAll()
+
+	return d.result()
+}
+
+// result returns the decoded image and the error of d.
+func (d *decoder) result() (image.Image, error) {
 	if d.err != nil {
 		return nil, d.err
 	}
+	if d.report != nil {
+		if d.h.height == 0 {
+			return nil, d.report
+		}
+		return d.m, d.report
+	}
 
 	return d.m, nil
 }
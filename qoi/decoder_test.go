@@ -14,14 +14,27 @@ import (
 	"testing"
 
 	"github.com/LukiDS/image/imgconv"
+	"github.com/LukiDS/image/imgtest"
 )
 
-func TestDecodeWithTestFiles(t *testing.T) {
-	filenames, err := filepath.Glob("../testdata/*.qoi")
-	if err != nil {
-		t.Fatalf("could not find files: %v\n", err)
+// testFiles returns the QOI test files which have a PNG reference: the images
+// of the QOI test suite and the chunk type images of internal/gentestdata.
+func testFiles(t testing.TB) []string {
+	var filenames []string
+	for _, pattern := range []string{"../testdata/*.qoi", "../testdata/qoi/opcodes/*.qoi"} {
+		names, err := filepath.Glob(pattern)
+		if err != nil || len(names) == 0 {
+			t.Fatalf("could not find files: %v\n", err)
+		}
+		filenames = append(filenames, names...)
 	}
 
+	return filenames
+}
+
+func TestDecodeWithTestFiles(t *testing.T) {
+	filenames := testFiles(t)
+
 	for _, name := range filenames {
 		t.Run(filepath.Base(name), func(t *testing.T) {
 			qoiFile, err := os.Open(name)
@@ -51,8 +64,8 @@ func TestDecodeWithTestFiles(t *testing.T) {
 				ref = imgconv.ToNRGBA(ref)
 			}
 
-			format := fmt.Sprintf("\nFile:\t %s\n", name)
-			assertEqualImage(t, ref, img, format)
+			assertNRGBAModel(t, img)
+			imgtest.AssertEqual(t, ref, img)
 		})
 	}
 }
@@ -153,8 +166,8 @@ func TestDecode(t *testing.T) {
 				t.Errorf(format)
 			}
 
-			format := getImageFormatMsg(test.expectedImage, actualImage, err)
-			assertEqualImage(t, test.expectedImage, actualImage, format)
+			assertNRGBAModel(t, actualImage)
+			imgtest.AssertEqual(t, test.expectedImage, actualImage)
 		})
 	}
 }
@@ -275,8 +288,8 @@ func TestDecodeIndex(t *testing.T) {
 				t.Errorf(format)
 			}
 
-			format := getImageFormatMsg(test.expectedImage, actualImage, err)
-			assertEqualImage(t, test.expectedImage, actualImage, format)
+			assertNRGBAModel(t, actualImage)
+			imgtest.AssertEqual(t, test.expectedImage, actualImage)
 
 		})
 	}
@@ -322,8 +335,8 @@ func TestDecodeRun(t *testing.T) {
 				t.Errorf(format)
 			}
 
-			format := getImageFormatMsg(test.expectedImage, actualImage, err)
-			assertEqualImage(t, test.expectedImage, actualImage, format)
+			assertNRGBAModel(t, actualImage)
+			imgtest.AssertEqual(t, test.expectedImage, actualImage)
 
 		})
 	}
@@ -362,8 +375,8 @@ func TestDecodeDiff(t *testing.T) {
 				t.Errorf(format)
 			}
 
-			format := getImageFormatMsg(test.expectedImage, actualImage, err)
-			assertEqualImage(t, test.expectedImage, actualImage, format)
+			assertNRGBAModel(t, actualImage)
+			imgtest.AssertEqual(t, test.expectedImage, actualImage)
 
 		})
 	}
@@ -402,8 +415,8 @@ func TestDecodeLuma(t *testing.T) {
 				t.Errorf(format)
 			}
 
-			format := getImageFormatMsg(test.expectedImage, actualImage, err)
-			assertEqualImage(t, test.expectedImage, actualImage, format)
+			assertNRGBAModel(t, actualImage)
+			imgtest.AssertEqual(t, test.expectedImage, actualImage)
 
 		})
 	}
@@ -442,8 +455,8 @@ func TestDecodeRGB(t *testing.T) {
 				t.Errorf(format)
 			}
 
-			format := getImageFormatMsg(test.expectedImage, actualImage, err)
-			assertEqualImage(t, test.expectedImage, actualImage, format)
+			assertNRGBAModel(t, actualImage)
+			imgtest.AssertEqual(t, test.expectedImage, actualImage)
 
 		})
 	}
@@ -483,8 +496,8 @@ func TestDecodeRGBA(t *testing.T) {
 				t.Errorf(format)
 			}
 
-			format := getImageFormatMsg(test.expectedImage, actualImage, err)
-			assertEqualImage(t, test.expectedImage, actualImage, format)
+			assertNRGBAModel(t, actualImage)
+			imgtest.AssertEqual(t, test.expectedImage, actualImage)
 
 		})
 	}
@@ -494,13 +507,6 @@ func TestDecodeRGBA(t *testing.T) {
 	Utils, Stubs, Asserts
 */
 
-func getImageFormatMsg(expected, actual image.Image, err error) string {
-	return fmt.Sprintf("\n") +
-		fmt.Sprintf("Decode(r io.Reader) = (%+v,%v)\n", actual, err) +
-		fmt.Sprintf("Expected image:\t %+v\n", expected) +
-		fmt.Sprintf("Actual image:\t %+v\n", actual)
-}
-
 func getErrorFormatMsg(expected, actual bool, actualImage image.Image, actualError error) string {
 	return fmt.Sprintf("\n") +
 		fmt.Sprintf("Decode(r io.Reader) = (%+v,%v)\n", actualImage, actualError) +
@@ -508,55 +514,23 @@ func getErrorFormatMsg(expected, actual bool, actualImage image.Image, actualErr
 		fmt.Sprintf("Actual error:\t %t\n", actual)
 }
 
-func generateImageStub(t testing.TB, h qoiHeader, testdata []byte) image.Image {
+// assertNRGBAModel reports an error if the decoded image m does not have the
+// NRGBA color model, which Decode always returns. A nil image is accepted.
+func assertNRGBAModel(t testing.TB, m image.Image) {
 	t.Helper()
 
-	m := image.NewNRGBA(image.Rect(0, 0, int(h.width), int(h.height)))
-	m.Pix = testdata
-
-	return m
+	if m != nil && m.ColorModel() != color.NRGBAModel {
+		format := fmt.Sprintf("\n") +
+			fmt.Sprintf("Decode(r io.Reader) = (%T,nil)\n", m) +
+			fmt.Sprintf("Expected color model:\t NRGBA\n")
+		t.Errorf(format)
+	}
 }
 
-func assertEqualImage(t testing.TB, expected, actual image.Image, format string) {
+func generateImageStub(t testing.TB, h qoiHeader, testdata []byte) image.Image {
 	t.Helper()
 
-	if expected == nil && actual == nil {
-		return
-	}
-
-	if expected == nil {
-		f := fmt.Sprintf("%s", format) +
-			fmt.Sprintf("Assert image:\t unexpected image")
-		t.Fatalf(f)
-	}
-
-	if actual == nil {
-		f := fmt.Sprintf("%s", format) +
-			fmt.Sprintf("Assert image:\t unexpected nil image")
-		t.Fatalf(f)
-	}
-
-	if expected.Bounds() != actual.Bounds() {
-		f := fmt.Sprintf("%s", format) +
-			fmt.Sprintf("Assert image:\t different image dimensions: Expected: %+v - Actual: %+v\n", expected.Bounds(), actual.Bounds())
-		t.Fatalf(f)
-	}
-
-	if expected.ColorModel() != actual.ColorModel() {
-		f := fmt.Sprintf("%s", format) +
-			fmt.Sprintf("Assert image:\t different color model\n")
-		t.Fatalf(f)
-	}
-
-	for x := expected.Bounds().Min.X; x < expected.Bounds().Max.X; x++ {
-		for y := expected.Bounds().Min.Y; y < expected.Bounds().Max.Y; y++ {
-			if expected.At(x, y) != actual.At(x, y) {
-				f := fmt.Sprintf("%s", format) +
-					fmt.Sprintf("Assert image:\t different pixel at x=%d, y=%d: Expected: %+v - Actual: %+v\n", x, y, expected.At(x, y), actual.At(x, y))
-				t.Fatalf(f)
-			}
-		}
-	}
+	return imgtest.NRGBA(t, h.width, h.height, testdata)
 }
 
 func BenchmarkDecodeFromFile(b *testing.B) {
@@ -619,3 +593,23 @@ func BenchmarkDecodeFromMemory(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkDecodeContent(b *testing.B) {
+	for _, c := range contentImages() {
+		b.Run(c.name, func(b *testing.B) {
+			buf := bytes.NewBuffer(nil)
+			if err := Encode(buf, c.m); err != nil {
+				b.Fatalf("could not encode image: %v\n", err)
+			}
+			data := buf.Bytes()
+
+			b.SetBytes(int64(len(c.m.Pix)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := Decode(bytes.NewReader(data)); err != nil {
+					b.Fatalf("could not decode image: %v\n", err)
+				}
+			}
+		})
+	}
+}
@@ -11,24 +11,90 @@ import (
 
 type encoder struct {
 	m      image.Image
+	opts   EncodeOptions
 	err    error
 	buf    []byte
 	width  int
 	height int
 }
 
+// EncodeOptions are the encoding parameters of EncodeWithOptions. The zero
+// value encodes like Encode.
+type EncodeOptions struct {
+	// FlipVertical encodes the rows of the image from the bottom up, for
+	// pixels stored with the bottom row first, as e.g. glReadPixels returns
+	// them. The rows are read in reverse order, so it costs nothing extra.
+	FlipVertical bool
+
+	// ChannelOrder is the order of the four bytes of each pixel of an
+	// *image.NRGBA image, for encoding buffers of other byte orders, like
+	// the BGRA surfaces of Windows captures, without converting them first.
+	// Wrap such a buffer in an *image.NRGBA and set its order here; the
+	// pixels must not be premultiplied. Other image types can only be
+	// encoded with RGBA, the zero value.
+	ChannelOrder ChannelOrder
+
+	// ColorKey, if not nil, turns every pixel of that color into a fully
+	// transparent pixel (0, 0, 0, 0) before it is encoded, for sprites
+	// whose transparency is marked by a key color like magenta. A pixel
+	// matches if each of its channels, alpha included, differs from the key
+	// by at most ColorKeyTolerance.
+	ColorKey          *color.NRGBA
+	ColorKeyTolerance uint8
+
+	// Quantize, if greater than zero, reduces the colors of the image to an
+	// adaptive palette of at most that many colors, 1 to 256, with the median
+	// cut of imgconv.ToPalettedDithered before it is encoded. This is lossy:
+	// the colors of the encoded image differ from the original ones. Alpha is
+	// not quantized, every pixel keeps its alpha and fully transparent pixels
+	// are kept unchanged. Fewer colors make the runs and the index chunks of
+	// QOI much more effective, e.g. for screenshots. The other options are
+	// applied before quantizing.
+	Quantize int
+
+	// QuantizeDither is the dithering of Quantize. DitherNone, the zero
+	// value, gives the smallest files, since dithering breaks up runs.
+	QuantizeDither imgconv.Dither
+}
+
+// ChannelOrder is the byte order of the channels of a pixel.
+type ChannelOrder int
+
+// The channel orders of EncodeOptions.ChannelOrder.
+const (
+	RGBA ChannelOrder = iota
+	BGRA
+	ARGB
+	ABGR
+)
+
 // Encode writes the Image m to w in QOI format. Any Image may be
 // encoded, but images that are not image.NRGBA might be encoded lossily.
 func Encode(w io.Writer, m image.Image) error {
+	return EncodeWithOptions(w, m, EncodeOptions{})
+}
+
+// EncodeWithOptions writes the Image m to w in QOI format like Encode,
+// applying opts.
+func EncodeWithOptions(w io.Writer, m image.Image, opts EncodeOptions) error {
+	if o := loadObserver(); o != nil {
+		return observeEncode(o, w, m, opts)
+	}
+
+	return encodeImage(w, m, opts)
+}
+
+func encodeImage(w io.Writer, m image.Image, opts EncodeOptions) error {
 	width := m.Bounds().Dx()
 	height := m.Bounds().Dy()
-	if width <= 0 || height <= 0 || width*height > qoiMaxPixels {
-		return fmt.Errorf("invalid image size")
+	if width <= 0 || height <= 0 || width > qoiMaxPixels/height {
+		return fmt.Errorf("invalid image size: %dx%d, must be non-empty and at most %d pixels", width, height, qoiMaxPixels)
 	}
 
 	maxSize := qoiHeaderSize + (width * height * int(qoiDefaultChannel+1)) + len(qoiEndMarker) //worst case -> [header-size + (op--r--g--b--{a} * pixels) + padding-size]
 	e := encoder{
 		m:      m,
+		opts:   opts,
 		buf:    make([]byte, 0, maxSize),
 		width:  width,
 		height: height,
@@ -62,69 +128,199 @@ func (e *encoder) encode() {
 		return
 	}
 
+	if e.opts.ChannelOrder < RGBA || e.opts.ChannelOrder > ABGR {
+		e.err = fmt.Errorf("invalid channel order %d", e.opts.ChannelOrder)
+		return
+	}
+	if _, ok := e.m.(*image.NRGBA); !ok && e.opts.ChannelOrder != RGBA {
+		e.err = fmt.Errorf("channel order %d of a %T image, must be RGBA for other images than *image.NRGBA", e.opts.ChannelOrder, e.m)
+		return
+	}
+	if e.opts.Quantize < 0 || e.opts.Quantize > 256 {
+		e.err = fmt.Errorf("invalid quantization to %d colors, must be 1 to 256", e.opts.Quantize)
+		return
+	}
+
 	img := imgconv.ToNRGBA(e.m)
+	if e.opts.Quantize > 0 {
+		img = e.quantize(img)
+	}
 
 	colorBuffer := [64]color.NRGBA{}
 	pxPrev := color.NRGBA{0, 0, 0, 255}
 
+	scratch := e.scratch()
+
 	run := uint8(0)
-	maxPixelPos := e.width * e.height
-	for pxPos := 0; pxPos < maxPixelPos; pxPos++ {
-		x := pxPos % e.width
-		y := pxPos / e.width
-		px := img.NRGBAAt(x, y)
-
-		if px == pxPrev {
-			run++
-			if run == qoiMaxRunSize || pxPos == maxPixelPos-1 {
+	for y := 0; y < e.height; y++ {
+		row := e.row(img, y, scratch)
+
+		for x := 0; x < len(row); x += 4 {
+			px := color.NRGBA{row[x], row[x+1], row[x+2], row[x+3]}
+
+			if px == pxPrev {
+				run++
+				if run == qoiMaxRunSize {
+					e.buf = append(e.buf, opRUN|run-1)
+					run = 0
+				}
+				continue
+			}
+
+			if run > 0 {
 				e.buf = append(e.buf, opRUN|run-1)
 				run = 0
 			}
-			continue
-		}
 
-		if run > 0 {
-			e.buf = append(e.buf, opRUN|run-1)
-			run = 0
-		}
+			idx := hash(px)
+			if colorBuffer[idx] == px {
+				e.buf = append(e.buf, opINDEX|idx)
+				pxPrev = px
+				continue
+			}
+			colorBuffer[idx] = px
+
+			if px.A != pxPrev.A {
+				e.buf = append(e.buf, opRGBA, px.R, px.G, px.B, px.A)
+				pxPrev = px
+				continue
+			}
+
+			vr := int8(px.R - pxPrev.R)
+			vg := int8(px.G - pxPrev.G)
+			vb := int8(px.B - pxPrev.B)
+
+			if isValidDiff(vr, vg, vb) {
+				chunk := opDIFF | (uint8(vr+2) << 4) | (uint8(vg+2) << 2) | uint8(vb+2)
+				e.buf = append(e.buf, chunk)
+				pxPrev = px
+				continue
+			}
+
+			vgR := vr - vg
+			vgB := vb - vg
 
-		idx := hash(px)
-		if colorBuffer[idx] == px {
-			e.buf = append(e.buf, opINDEX|idx)
+			if isValidLuma(vgR, vg, vgB) {
+				e.buf = append(e.buf, opLUMA|uint8(vg+32), (uint8(vgR+8)<<4)|uint8(vgB+8))
+				pxPrev = px
+				continue
+			}
+
+			e.buf = append(e.buf, opRGB, px.R, px.G, px.B)
 			pxPrev = px
-			continue
 		}
-		colorBuffer[idx] = px
+	}
 
-		if px.A != pxPrev.A {
-			e.buf = append(e.buf, opRGBA, px.R, px.G, px.B, px.A)
-			pxPrev = px
-			continue
+	if run > 0 {
+		e.buf = append(e.buf, opRUN|run-1)
+	}
+}
+
+// scratch returns the buffer of row, nil if the rows are not changed.
+func (e *encoder) scratch() []byte {
+	if e.opts.ChannelOrder == RGBA && e.opts.ColorKey == nil {
+		return nil
+	}
+
+	return make([]byte, 4*e.width)
+}
+
+// row returns the RGBA pixels of the row y of the encoded image, read from
+// img with e.opts.FlipVertical, e.opts.ChannelOrder and e.opts.ColorKey.
+// Changed rows are written to scratch.
+func (e *encoder) row(img *image.NRGBA, y int, scratch []byte) []byte {
+	if e.opts.FlipVertical {
+		y = e.height - 1 - y
+	}
+	origin := img.Bounds().Min
+	i := img.PixOffset(origin.X, origin.Y+y)
+	row := img.Pix[i : i+4*e.width : i+4*e.width]
+
+	if scratch != nil {
+		row = swizzle(scratch, row, e.opts.ChannelOrder)
+		if e.opts.ColorKey != nil {
+			keyColor(row, *e.opts.ColorKey, e.opts.ColorKeyTolerance)
 		}
+	}
 
-		vr := int8(px.R - pxPrev.R)
-		vg := int8(px.G - pxPrev.G)
-		vb := int8(px.B - pxPrev.B)
+	return row
+}
 
-		if isValidDiff(vr, vg, vb) {
-			chunk := opDIFF | (uint8(vr+2) << 4) | (uint8(vg+2) << 2) | uint8(vb+2)
-			e.buf = append(e.buf, chunk)
-			pxPrev = px
-			continue
+// quantize returns the rows of img, as read by row, with their colors reduced
+// to e.opts.Quantize colors, and clears the options applied by row.
+func (e *encoder) quantize(img *image.NRGBA) *image.NRGBA {
+	src := image.NewNRGBA(image.Rect(0, 0, e.width, e.height))
+	scratch := e.scratch()
+	for y := 0; y < e.height; y++ {
+		copy(src.Pix[y*src.Stride:], e.row(img, y, scratch))
+	}
+
+	e.opts.FlipVertical = false
+	e.opts.ChannelOrder = RGBA
+	e.opts.ColorKey = nil
+
+	//only the colors are quantized, the pixels keep their alpha
+	opaque := imgconv.CloneNRGBA(src)
+	for i := 3; i < len(opaque.Pix); i += 4 {
+		opaque.Pix[i] = 0xff
+	}
+	dst := imgconv.ToNRGBA(imgconv.ToPalettedDithered(opaque, e.opts.Quantize, e.opts.QuantizeDither))
+	for i := 0; i < len(dst.Pix); i += 4 {
+		if a := src.Pix[i+3]; a == 0 {
+			copy(dst.Pix[i:i+4], src.Pix[i:i+4])
+		} else {
+			dst.Pix[i+3] = a
 		}
+	}
 
-		vgR := vr - vg
-		vgB := vb - vg
+	return dst
+}
 
-		if isValidLuma(vgR, vg, vgB) {
-			e.buf = append(e.buf, opLUMA|uint8(vg+32), (uint8(vgR+8)<<4)|uint8(vgB+8))
-			pxPrev = px
-			continue
+// swizzle writes the pixels of row, whose channels are in the given order,
+// to dst in RGBA order and returns dst.
+func swizzle(dst, row []byte, order ChannelOrder) []byte {
+	switch order {
+	case RGBA:
+		copy(dst, row)
+	case BGRA:
+		for i := 0; i < len(row); i += 4 {
+			s, d := row[i:i+4:i+4], dst[i:i+4:i+4]
+			d[0], d[1], d[2], d[3] = s[2], s[1], s[0], s[3]
+		}
+	case ARGB:
+		for i := 0; i < len(row); i += 4 {
+			s, d := row[i:i+4:i+4], dst[i:i+4:i+4]
+			d[0], d[1], d[2], d[3] = s[1], s[2], s[3], s[0]
+		}
+	case ABGR:
+		for i := 0; i < len(row); i += 4 {
+			s, d := row[i:i+4:i+4], dst[i:i+4:i+4]
+			d[0], d[1], d[2], d[3] = s[3], s[2], s[1], s[0]
 		}
+	}
+
+	return dst
+}
+
+// keyColor sets the pixels of row whose channels all differ from key by at
+// most tolerance to (0, 0, 0, 0).
+func keyColor(row []byte, key color.NRGBA, tolerance uint8) {
+	for i := 0; i < len(row); i += 4 {
+		p := row[i : i+4 : i+4]
+		if near(p[0], key.R, tolerance) && near(p[1], key.G, tolerance) &&
+			near(p[2], key.B, tolerance) && near(p[3], key.A, tolerance) {
+			p[0], p[1], p[2], p[3] = 0, 0, 0, 0
+		}
+	}
+}
 
-		e.buf = append(e.buf, opRGB, px.R, px.G, px.B)
-		pxPrev = px
+// near reports whether a and b differ by at most tolerance.
+func near(a, b, tolerance uint8) bool {
+	if a < b {
+		a, b = b, a
 	}
+
+	return a-b <= tolerance
 }
 
 func (e *encoder) encodePadding() {
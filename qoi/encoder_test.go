@@ -3,22 +3,28 @@ package qoi
 import (
 	"bufio"
 	"bytes"
+	"flag"
 	"fmt"
 	"image"
 	"image/color"
 	"image/png"
 	"io"
+	"math/rand"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/LukiDS/image/imgconv"
+	"github.com/LukiDS/image/imgtest"
+	"github.com/LukiDS/image/testimg"
 )
 
+// update is the -update flag rewriting the golden files of the encoder tests.
+var update = flag.Bool("update", false, "rewrite the golden files in ../testdata/qoi")
+
 func TestEncodeWithTestFiles(t *testing.T) {
-	filenames, err := filepath.Glob("../testdata/*.qoi")
-	if err != nil {
-		t.Fatalf("could not find files: %v\n", err)
-	}
+	filenames := testFiles(t)
 
 	for _, name := range filenames {
 		t.Run(filepath.Base(name), func(t *testing.T) {
@@ -87,6 +93,30 @@ func TestEncodeWithTestFiles(t *testing.T) {
 	}
 }
 
+func TestEncodeGolden(t *testing.T) {
+	tests := []struct {
+		name string
+		m    image.Image
+	}{
+		{name: "gradient", m: testimg.Gradient(67, 31)},
+		{name: "checkerboard", m: testimg.Checkerboard(64, 64, 3)},
+		{name: "noise", m: testimg.Noise(33, 17, 2)},
+		{name: "solid_runs", m: testimg.SolidRuns(129, 65)},
+		{name: "alpha_ramp", m: testimg.AlphaRamp(256, 3)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Encode(&buf, test.m); err != nil {
+				t.Fatalf("could not encode image: %v\n", err)
+			}
+
+			imgtest.Golden(t, filepath.Join("../testdata/qoi", test.name+".qoi"), buf.Bytes(), *update)
+		})
+	}
+}
+
 func TestEncode(t *testing.T) {
 	tests := []struct {
 		name string
@@ -119,6 +149,17 @@ func TestEncode(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "should return an error for unbounded images",
+			args: struct {
+				w io.Writer
+				m image.Image
+			}{
+				w: io.Discard,
+				m: image.NewUniform(color.NRGBA{255, 0, 255, 255}),
+			},
+			expectError: true,
+		},
 		{
 			name: "should return encoded index",
 			args: struct {
@@ -185,6 +226,17 @@ func TestEncode(t *testing.T) {
 			},
 			expectedData: generateEncodeStub(t, qoiHeader{width: 1, height: 1, channels: 4, colorspace: 0}, []byte{opRGBA, 10, 20, 30, 100}).Bytes(),
 		},
+		{
+			name: "should encode custom image types with nrgba color model",
+			args: struct {
+				w io.Writer
+				m image.Image
+			}{
+				w: bytes.NewBuffer(nil),
+				m: customImage{generateImageStub(t, qoiHeader{width: 1, height: 1}, []byte{10, 20, 30, 100})},
+			},
+			expectedData: generateEncodeStub(t, qoiHeader{width: 1, height: 1, channels: 4, colorspace: 0}, []byte{opRGBA, 10, 20, 30, 100}).Bytes(),
+		},
 	}
 
 	for _, test := range tests {
@@ -213,70 +265,50 @@ func TestEncode(t *testing.T) {
 	}
 }
 
-func generateEncodeStub(t testing.TB, h qoiHeader, data []byte) *bytes.Buffer {
-	t.Helper()
-
-	header := make([]byte, 0, qoiHeaderSize)
-	header = append(header, qoiMagic...)
-	header = append(header, byte(h.width>>24), byte(h.width>>16), byte(h.width>>8), byte(h.width))
-	header = append(header, byte(h.height>>24), byte(h.height>>16), byte(h.height>>8), byte(h.height))
-	header = append(header, h.channels, h.colorspace)
-
-	if len(header) != qoiHeaderSize {
-		t.Fatalf("invalid header size")
+func TestEncodeRoundTrip(t *testing.T) {
+	gray := image.NewGray(image.Rect(0, 0, 5, 3))
+	for i := range gray.Pix {
+		gray.Pix[i] = byte(i * 17)
 	}
-
-	buf := bytes.NewBuffer(header)
-	_, err := buf.Write(data)
-	if err != nil {
-		t.Fatal(err)
+	paletted := image.NewPaletted(image.Rect(0, 0, 4, 4), color.Palette{color.Black, color.White, color.NRGBA{0x10, 0x80, 0xf0, 0x40}})
+	for i := range paletted.Pix {
+		paletted.Pix[i] = byte(i % 3)
 	}
 
-	_, err = buf.Write(qoiEndMarker)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	return buf
+	imgtest.RoundTrip(t, Encode, Decode)
+	imgtest.RoundTrip(t, Encode, Decode,
+		gray,
+		paletted,
+		testimg.Noise(16, 16, 3).SubImage(image.Rect(5, 7, 13, 10)),
+		customImage{testimg.AlphaRamp(32, 2)},
+	)
 }
 
-func generateEncodeStubWithoutPadding(t testing.TB, h qoiHeader, data []byte) *bytes.Buffer {
+func generateEncodeStub(t testing.TB, h qoiHeader, data []byte) *bytes.Buffer {
 	t.Helper()
 
-	header := make([]byte, 0, qoiHeaderSize)
-	header = append(header, qoiMagic...)
-	header = append(header, byte(h.width>>24), byte(h.width>>16), byte(h.width>>8), byte(h.width))
-	header = append(header, byte(h.height>>24), byte(h.height>>16), byte(h.height>>8), byte(h.height))
-	header = append(header, h.channels, h.colorspace)
-
-	if len(header) != qoiHeaderSize {
-		t.Fatalf("invalid header size")
-	}
+	return bytes.NewBuffer(imgtest.QOIStream(toTestHeader(h), data))
+}
 
-	buf := bytes.NewBuffer(header)
-	_, err := buf.Write(data)
-	if err != nil {
-		t.Fatal(err)
-	}
+func generateEncodeStubWithoutPadding(t testing.TB, h qoiHeader, data []byte) *bytes.Buffer {
+	t.Helper()
 
-	return buf
+	return bytes.NewBuffer(imgtest.QOIStreamWithoutPadding(toTestHeader(h), data))
 }
 
 func generateEncodeStubWithoutHeader(t testing.TB, data []byte) *bytes.Buffer {
 	t.Helper()
 
-	buf := bytes.NewBuffer(nil)
-	_, err := buf.Write(data)
-	if err != nil {
-		t.Fatal(err)
-	}
+	return bytes.NewBuffer(imgtest.QOIStreamWithoutHeader(data))
+}
 
-	_, err = buf.Write(qoiEndMarker)
-	if err != nil {
-		t.Fatal(err)
+func toTestHeader(h qoiHeader) imgtest.QOIHeader {
+	return imgtest.QOIHeader{
+		Width:      uint32(h.width),
+		Height:     uint32(h.height),
+		Channels:   h.channels,
+		Colorspace: h.colorspace,
 	}
-
-	return buf
 }
 
 func BenchmarkEncodeToFile(b *testing.B) {
@@ -370,3 +402,308 @@ func BenchmarkEncodeToMemory(b *testing.B) {
 		b.StartTimer()
 	}
 }
+
+// contentImages returns synthetic 512x512 images from the best to the worst
+// case of the encoder, to benchmark the operations separately.
+func contentImages() []struct {
+	name string
+	m    *image.NRGBA
+} {
+	return []struct {
+		name string
+		m    *image.NRGBA
+	}{
+		{name: "Runs", m: testimg.SolidRuns(512, 512)},
+		{name: "Checkerboard", m: testimg.Checkerboard(512, 512, 8)},
+		{name: "Gradient", m: testimg.Gradient(512, 512)},
+		{name: "AlphaRamp", m: testimg.AlphaRamp(512, 512)},
+		{name: "Noise", m: testimg.Noise(512, 512, 1)},
+	}
+}
+
+func BenchmarkEncodeContent(b *testing.B) {
+	for _, c := range contentImages() {
+		b.Run(c.name, func(b *testing.B) {
+			buf := bytes.NewBuffer(nil)
+			b.SetBytes(int64(len(c.m.Pix)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				buf.Reset()
+				if err := Encode(buf, c.m); err != nil {
+					b.Fatalf("could not encode image: %v\n", err)
+				}
+			}
+		})
+	}
+}
+
+// customImage wraps an image so that it reports the color model of a
+// stdlib image without being of the same concrete type.
+type customImage struct {
+	m image.Image
+}
+
+func (c customImage) ColorModel() color.Model { return c.m.ColorModel() }
+func (c customImage) Bounds() image.Rectangle { return c.m.Bounds() }
+func (c customImage) At(x, y int) color.Color { return c.m.At(x, y) }
+
+func TestEncodeFlipVertical(t *testing.T) {
+	gray := image.NewGray(image.Rect(0, 0, 5, 3))
+	for i := range gray.Pix {
+		gray.Pix[i] = byte(i * 17)
+	}
+
+	images := map[string]image.Image{
+		"gray":     gray,
+		"subimage": testimg.Noise(16, 16, 3).SubImage(image.Rect(5, 7, 13, 10)),
+		"runs":     testimg.SolidRuns(77, 20),
+	}
+	for _, name := range testFiles(t) {
+		images[filepath.Base(name)] = decodeNRGBA(t, readTestFile(t, name))
+	}
+
+	for name, m := range images {
+		t.Run(name, func(t *testing.T) {
+			//a bottom-up buffer, which keeps the bounds of m
+			flipped := imgconv.FlipV(m)
+
+			var buf bytes.Buffer
+			if err := EncodeWithOptions(&buf, flipped, EncodeOptions{FlipVertical: true}); err != nil {
+				t.Fatalf("could not encode image: %v\n", err)
+			}
+			if expected := encodeTestImage(t, m); !bytes.Equal(buf.Bytes(), expected) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Image:\t %s\n", name) +
+					fmt.Sprintf("Expected length:\t %d\n", len(expected)) +
+					fmt.Sprintf("Actual length:\t %d\n", buf.Len())
+
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+// reorder returns a copy of the NRGBA image m with the channels of its
+// pixels moved to the given bytes of each pixel, e.g. {2, 1, 0, 3} for BGRA.
+func reorder(m *image.NRGBA, order [4]int) *image.NRGBA {
+	out := imgconv.CloneNRGBA(m)
+	for i := 0; i < len(out.Pix); i += 4 {
+		p := [4]byte{out.Pix[i], out.Pix[i+1], out.Pix[i+2], out.Pix[i+3]}
+		for c, from := range order {
+			out.Pix[i+from] = p[c]
+		}
+	}
+
+	return out
+}
+
+func TestEncodeChannelOrder(t *testing.T) {
+	orders := []struct {
+		name  string
+		order ChannelOrder
+		bytes [4]int
+	}{
+		{name: "RGBA", order: RGBA, bytes: [4]int{0, 1, 2, 3}},
+		{name: "BGRA", order: BGRA, bytes: [4]int{2, 1, 0, 3}},
+		{name: "ARGB", order: ARGB, bytes: [4]int{1, 2, 3, 0}},
+		{name: "ABGR", order: ABGR, bytes: [4]int{3, 2, 1, 0}},
+	}
+
+	images := map[string]*image.NRGBA{
+		"alpha ramp": testimg.AlphaRamp(40, 9),
+		"subimage":   testimg.Noise(16, 16, 3).SubImage(image.Rect(5, 7, 13, 10)).(*image.NRGBA),
+	}
+	for _, name := range testFiles(t) {
+		images[filepath.Base(name)] = decodeNRGBA(t, readTestFile(t, name))
+	}
+
+	for name, m := range images {
+		expected := encodeTestImage(t, m)
+
+		for _, o := range orders {
+			for _, flip := range []bool{false, true} {
+				t.Run(fmt.Sprintf("%s/%s/%t", name, o.name, flip), func(t *testing.T) {
+					src := reorder(m, o.bytes)
+					if flip {
+						src = imgconv.FlipV(src)
+					}
+
+					var buf bytes.Buffer
+					if err := EncodeWithOptions(&buf, src, EncodeOptions{ChannelOrder: o.order, FlipVertical: flip}); err != nil {
+						t.Fatalf("could not encode image: %v\n", err)
+					}
+					if !bytes.Equal(buf.Bytes(), expected) {
+						t.Errorf("%s pixels encoded with ChannelOrder %s differ from the RGBA encoding\n", name, o.name)
+					}
+				})
+			}
+		}
+	}
+
+	gray := image.NewGray(image.Rect(0, 0, 2, 2))
+	if err := EncodeWithOptions(io.Discard, gray, EncodeOptions{ChannelOrder: BGRA}); err == nil {
+		t.Errorf("expected an error for a BGRA *image.Gray\n")
+	}
+	if err := EncodeWithOptions(io.Discard, testimg.AlphaRamp(2, 2), EncodeOptions{ChannelOrder: ABGR + 1}); err == nil {
+		t.Errorf("expected an error for an invalid channel order\n")
+	}
+}
+
+func TestEncodeColorKey(t *testing.T) {
+	magenta := color.NRGBA{0xff, 0x00, 0xff, 0xff}
+	nearMagenta := color.NRGBA{0xf7, 0x04, 0xff, 0xff}
+
+	//the transparent color of the sprite becomes the key, a few pixels almost the key
+	sprite := sprite()
+	for i := 0; i < len(sprite.Pix); i += 4 {
+		if sprite.Pix[i+3] == 0 {
+			copy(sprite.Pix[i:i+4], []byte{magenta.R, magenta.G, magenta.B, magenta.A})
+		}
+	}
+	for _, p := range []image.Point{{0, 0}, {23, 19}, {12, 3}} {
+		sprite.SetNRGBA(p.X, p.Y, nearMagenta)
+	}
+
+	testCases := []struct {
+		name      string
+		tolerance uint8
+		order     ChannelOrder
+		bytes     [4]int
+		keyed     []color.NRGBA
+	}{
+		{name: "exact", keyed: []color.NRGBA{magenta}, bytes: [4]int{0, 1, 2, 3}},
+		{name: "tolerance", tolerance: 7, keyed: []color.NRGBA{magenta}, bytes: [4]int{0, 1, 2, 3}},
+		{name: "tolerance covering", tolerance: 8, keyed: []color.NRGBA{magenta, nearMagenta}, bytes: [4]int{0, 1, 2, 3}},
+		{name: "BGRA", order: BGRA, keyed: []color.NRGBA{magenta}, bytes: [4]int{2, 1, 0, 3}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := EncodeOptions{ColorKey: &magenta, ColorKeyTolerance: tc.tolerance, ChannelOrder: tc.order}
+			if err := EncodeWithOptions(&buf, reorder(sprite, tc.bytes), opts); err != nil {
+				t.Fatalf("could not encode image: %v\n", err)
+			}
+			m := decodeNRGBA(t, buf.Bytes())
+
+			for y := 0; y < sprite.Rect.Dy(); y++ {
+				for x := 0; x < sprite.Rect.Dx(); x++ {
+					expected := sprite.NRGBAAt(x, y)
+					for _, c := range tc.keyed {
+						if expected == c {
+							expected = color.NRGBA{}
+						}
+					}
+
+					if actual := m.NRGBAAt(x, y); actual != expected {
+						t.Fatalf("pixel %d,%d: %v, expected %v\n", x, y, actual, expected)
+					}
+				}
+			}
+		})
+	}
+}
+
+// screenshot returns a w x h image of flat panels in a few colors with slight
+// noise, like a captured user interface.
+func screenshot(w, h int) *image.NRGBA {
+	panels := []color.NRGBA{{0xf0, 0xf0, 0xf0, 0xff}, {0x20, 0x60, 0xc0, 0xff}, {0x30, 0x30, 0x30, 0xff}, {0xe0, 0x40, 0x40, 0xff}}
+	rnd := rand.New(rand.NewSource(7))
+
+	m := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := panels[(x/40+y/25)%len(panels)]
+			m.SetNRGBA(x, y, color.NRGBA{c.R - byte(rnd.Intn(8)), c.G - byte(rnd.Intn(8)), c.B - byte(rnd.Intn(8)), c.A})
+		}
+	}
+
+	return m
+}
+
+// distinctColors returns the number of distinct colors of m.
+func distinctColors(m *image.NRGBA) int {
+	colors := make(map[color.NRGBA]bool)
+	for i := 0; i < len(m.Pix); i += 4 {
+		colors[color.NRGBA{m.Pix[i], m.Pix[i+1], m.Pix[i+2], m.Pix[i+3]}] = true
+	}
+
+	return len(colors)
+}
+
+func TestEncodeQuantize(t *testing.T) {
+	m := screenshot(320, 200)
+	size := len(encodeTestImage(t, m))
+	//the largest quantized sizes, much smaller with few colors
+	limits := map[int]int{2: size / 10, 16: size / 2, 256: size - 1}
+
+	for _, n := range []int{2, 16, 256} {
+		for _, dither := range []imgconv.Dither{imgconv.DitherNone, imgconv.DitherFloydSteinberg} {
+			t.Run(fmt.Sprintf("%d/%d", n, dither), func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := EncodeWithOptions(&buf, m, EncodeOptions{Quantize: n, QuantizeDither: dither}); err != nil {
+					t.Fatalf("could not encode image: %v\n", err)
+				}
+
+				decoded := decodeNRGBA(t, buf.Bytes())
+				if colors := distinctColors(decoded); colors > n {
+					t.Errorf("%d colors, expected at most %d\n", colors, n)
+				}
+				for i := 3; i < len(decoded.Pix); i += 4 {
+					if decoded.Pix[i] != 0xff {
+						t.Fatalf("translucent pixel %d in the quantized opaque image\n", i/4)
+					}
+				}
+
+				if buf.Len() > limits[n] {
+					t.Errorf("quantized size %d of %d, expected at most %d\n", buf.Len(), size, limits[n])
+				}
+			})
+		}
+	}
+
+	//the options are applied before quantizing
+	key := color.NRGBA{0xf0, 0xf0, 0xf0, 0xff}
+	var buf bytes.Buffer
+	opts := EncodeOptions{Quantize: 16, FlipVertical: true, ColorKey: &key, ColorKeyTolerance: 7}
+	if err := EncodeWithOptions(&buf, imgconv.FlipV(m), opts); err != nil {
+		t.Fatalf("could not encode image: %v\n", err)
+	}
+	decoded := decodeNRGBA(t, buf.Bytes())
+	if c := decoded.NRGBAAt(0, 0); c != (color.NRGBA{}) {
+		t.Errorf("keyed pixel 0,0: %v, expected transparent\n", c)
+	}
+	if c := decoded.NRGBAAt(40, 0); c.A != 0xff {
+		t.Errorf("pixel 40,0: %v, expected opaque\n", c)
+	}
+
+	//alpha is not quantized, and transparent pixels are kept
+	translucent := imgconv.CloneNRGBA(m)
+	for i := 3; i < len(translucent.Pix); i += 4 {
+		translucent.Pix[i] = uint8(i / 4 % 7 * 40)
+	}
+	for _, dither := range []imgconv.Dither{imgconv.DitherNone, imgconv.DitherFloydSteinberg} {
+		buf.Reset()
+		if err := EncodeWithOptions(&buf, translucent, EncodeOptions{Quantize: 4, QuantizeDither: dither}); err != nil {
+			t.Fatalf("could not encode image: %v\n", err)
+		}
+		decoded := decodeNRGBA(t, buf.Bytes())
+		colors := map[[3]uint8]bool{}
+		for i := 0; i < len(decoded.Pix); i += 4 {
+			p, q := decoded.Pix[i:i+4], translucent.Pix[i:i+4]
+			if p[3] != q[3] || (q[3] == 0 && !bytes.Equal(p, q)) {
+				t.Fatalf("dither %d: quantized pixel %d: %v, expected the alpha of %v\n", dither, i/4, p, q)
+			}
+			if q[3] != 0 {
+				colors[[3]uint8{p[0], p[1], p[2]}] = true
+			}
+		}
+		if len(colors) > 4 {
+			t.Errorf("dither %d: %d colors, expected at most 4\n", dither, len(colors))
+		}
+	}
+
+	if err := EncodeWithOptions(io.Discard, m, EncodeOptions{Quantize: 257}); err == nil {
+		t.Errorf("expected an error for quantizing to 257 colors\n")
+	}
+}
@@ -0,0 +1,243 @@
+package qoi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/LukiDS/image/imgtest"
+)
+
+// addSeeds adds the small testdata files and a few hand-made streams to the
+// corpus of f. The large files would slow down every fuzzing iteration.
+func addSeeds(f *testing.F) {
+	filenames := testFiles(f)
+	for _, name := range filenames {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			f.Fatalf("could not read file: %v\n", err)
+		}
+		if len(data) <= 1<<16 {
+			f.Add(data)
+		}
+	}
+
+	header := []byte{'q', 'o', 'i', 'f', 0, 0, 0, 4, 0, 0, 0, 2, 4, 0}
+	f.Add(append(append(header[:len(header):len(header)], opRGBA, 1, 2, 3, 4, opDIFF|0x2a, opLUMA|40, 0x88, opINDEX|17, opRUN|3), qoiEndMarker...))
+	f.Add(append(append(header[:len(header):len(header)], opRGB, 255, 0, 0, opRUN|61), qoiEndMarker...))
+}
+
+func FuzzDecode(f *testing.F) {
+	addSeeds(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		m, err := Decode(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+
+		config, err := DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("DecodeConfig failed for a decodable image: %v\n", err)
+		}
+		if b := m.Bounds(); b.Dx() != config.Width || b.Dy() != config.Height {
+			t.Fatalf("Decode returned a %v image, DecodeConfig a %dx%d image\n", b, config.Width, config.Height)
+		}
+	})
+}
+
+func FuzzDecodeConfig(f *testing.F) {
+	addSeeds(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		config, err := DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+
+		if config.Width <= 0 || config.Height <= 0 || config.Width > qoiMaxPixels/config.Height {
+			t.Fatalf("DecodeConfig returned the invalid size %dx%d\n", config.Width, config.Height)
+		}
+	})
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	addSeeds(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		m, err := Decode(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := Encode(&buf, m); err != nil {
+			t.Fatalf("Encode failed for a decoded image: %v\n", err)
+		}
+
+		actual, err := Decode(&buf)
+		if err != nil {
+			t.Fatalf("Decode failed for an encoded image: %v\n", err)
+		}
+		imgtest.AssertEqual(t, m, actual)
+	})
+}
+
+func FuzzDecodeRecover(f *testing.F) {
+	addSeeds(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		m, err := DecodeWithOptions(bytes.NewReader(data), DecodeOptions{Recover: true})
+
+		var report *RecoveryReport
+		if !errors.As(err, &report) {
+			return
+		}
+		if m == nil {
+			if report.X != 0 || report.Y != 0 {
+				t.Fatalf("no image returned for a failure at pixel %d,%d\n", report.X, report.Y)
+			}
+			return
+		}
+
+		//recovered images end with the row of the failure
+		rows := report.Y
+		if report.X > 0 {
+			rows++
+		}
+		config, err := DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("DecodeConfig failed for a recovered image: %v\n", err)
+		}
+		if b := m.Bounds(); b.Dx() != config.Width || b.Dy() != rows || rows > config.Height {
+			t.Fatalf("recovered a %v image for a failure at %d,%d of a %dx%d image\n", b, report.X, report.Y, config.Width, config.Height)
+		}
+	})
+}
+
+/*
+	Regressions found by fuzzing
+*/
+
+func TestDecodeShortReads(t *testing.T) {
+	filenames := testFiles(t)
+
+	for _, name := range filenames {
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			data, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatalf("could not read file: %v\n", err)
+			}
+
+			expected, err := Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("could not decode file: %v\n", err)
+			}
+
+			actual, err := Decode(iotest.OneByteReader(bytes.NewReader(data)))
+			format := fmt.Sprintf("\nFile:\t %s\n", name)
+			if err != nil {
+				t.Fatalf(format+"Decode with one byte reads failed: %v\n", err)
+			}
+			imgtest.AssertEqual(t, expected, actual)
+		})
+	}
+}
+
+func TestDecodeCraftedHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		header qoiHeader
+		data   []byte
+	}{
+		{
+			//the product of width and height overflows a 32 bit int
+			name:   "Overflowing size",
+			header: qoiHeader{width: 0xffffffff, height: 0xffffffff, channels: 4},
+			data:   []byte{opRUN | 61},
+		},
+		{
+			name:   "Too many pixels",
+			header: qoiHeader{width: 20001, height: 20000, channels: 4},
+			data:   []byte{opRUN | 61},
+		},
+		{
+			name:   "Zero height",
+			header: qoiHeader{width: 1, height: 0, channels: 4},
+			data:   []byte{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := generateEncodeStub(t, tt.header, tt.data)
+			config, err := DecodeConfig(bytes.NewReader(r.Bytes()))
+			if err == nil {
+				t.Errorf("\nDecodeConfig(r io.Reader) = (%+v,%v)\nExpected error:\t true\n", config, err)
+			}
+
+			m, err := Decode(r)
+			if err == nil {
+				t.Errorf(getErrorFormatMsg(true, false, m, err))
+			}
+		})
+	}
+}
+
+func TestDecodeTruncatedLargeImage(t *testing.T) {
+	//a header claiming 20000x20000 pixels, followed by a single run
+	r := generateEncodeStubWithoutPadding(t, qoiHeader{width: 20000, height: 20000, channels: 4}, []byte{opRUN | 61})
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	m, err := Decode(r)
+	runtime.ReadMemStats(&after)
+
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		format := fmt.Sprintf("\n") +
+			fmt.Sprintf("Decode(r io.Reader) = (%+v,%v)\n", m, err) +
+			fmt.Sprintf("Expected error:\t %v\n", io.ErrUnexpectedEOF)
+		t.Errorf(format)
+	}
+
+	//decoding the whole image would take 1.6GB
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 64<<20 {
+		t.Errorf("\nDecode allocated %d bytes for 62 pixels\n", allocated)
+	}
+}
+
+func TestDecodeRecoverTruncatedLargeImage(t *testing.T) {
+	data, err := os.ReadFile("testdata/fuzz/FuzzDecodeRecover/huge_header_truncated")
+	if err != nil {
+		t.Fatalf("could not read file: %v\n", err)
+	}
+	//the corpus file holds the data as a Go string literal on its second line
+	lines := bytes.Split(data, []byte("\n"))
+	literal, err := strconv.Unquote(string(bytes.TrimSuffix(bytes.TrimPrefix(lines[1], []byte("[]byte(")), []byte(")"))))
+	if err != nil {
+		t.Fatalf("could not parse corpus file: %v\n", err)
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	m, err := DecodeWithOptions(strings.NewReader(literal), DecodeOptions{Recover: true})
+	runtime.ReadMemStats(&after)
+
+	var report *RecoveryReport
+	if !errors.As(err, &report) || m == nil || m.Bounds().Dy() != 1 {
+		t.Errorf("\nDecodeWithOptions = (%v,%v)\nExpected:\t one recovered row and a *RecoveryReport\n", m, err)
+	}
+
+	//filling the whole image would take 1.6GB
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 64<<20 {
+		t.Errorf("\nDecodeWithOptions allocated %d bytes for a 26 byte input\n", allocated)
+	}
+}
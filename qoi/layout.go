@@ -0,0 +1,112 @@
+package qoi
+
+import (
+	"image"
+)
+
+// maxRowAlignment is the largest DecodeOptions.RowAlignment, so a crafted
+// header cannot make a few rows claim gigabytes.
+const maxRowAlignment = 1 << 16
+
+// stride returns the number of bytes of a row of size bytes, rounded up to
+// opts.RowAlignment.
+func (d *decoder) stride(size int) int {
+	if a := d.opts.RowAlignment; a > 1 && size%a != 0 {
+		size += a - size%a
+	}
+
+	return size
+}
+
+// decodeLaidOut decodes the pixels into the rows of an *image.NRGBA image
+// laid out by opts.RowAlignment and opts.FlipVertical. Like the buffer of
+// decodeNRGBA, the pixels start with at most qoiInitialPixels and grow with
+// the decoded data; flipped rows are kept at the end of the buffer.
+func (d *decoder) decodeLaidOut() {
+	size := 4 * d.h.width
+	stride := d.stride(size)
+	rows := d.initialPixels() / d.h.width
+	if rows == 0 {
+		rows = 1
+	}
+	pix := make([]byte, rows*stride)
+
+	for y := 0; y < d.h.height; y++ {
+		if y == rows {
+			if rows *= 2; rows > d.h.height {
+				rows = d.h.height
+			}
+			grown := make([]byte, rows*stride)
+			if d.opts.FlipVertical {
+				copy(grown[len(grown)-len(pix):], pix)
+			} else {
+				copy(grown, pix)
+			}
+			pix = grown
+		}
+
+		i := y * stride
+		if d.opts.FlipVertical {
+			i = (rows - 1 - y) * stride
+		}
+		d.decodeNRGBA(pix[i:i:i+size], 1)
+		if d.err != nil {
+			return
+		}
+	}
+
+	//a corrupt image decoded with opts.Recover may end early
+	if d.opts.FlipVertical {
+		pix = pix[len(pix)-d.h.height*stride:]
+	} else {
+		pix = pix[:d.h.height*stride]
+	}
+
+	d.m = &image.NRGBA{
+		Pix:    pix,
+		Stride: stride,
+		Rect:   image.Rect(0, 0, d.h.width, d.h.height),
+	}
+}
+
+// layOut lays out the rows of the decoded image m like decodeLaidOut,
+// flipping them in place and copying them to aligned rows.
+func (d *decoder) layOut(m image.Image) {
+	switch m := m.(type) {
+	case *image.NRGBA:
+		m.Pix, m.Stride = d.layOutRows(m.Pix, m.Stride, 4*m.Rect.Dx(), m.Rect.Dy())
+	case *image.NRGBA64:
+		m.Pix, m.Stride = d.layOutRows(m.Pix, m.Stride, 8*m.Rect.Dx(), m.Rect.Dy())
+	case *image.Gray:
+		m.Pix, m.Stride = d.layOutRows(m.Pix, m.Stride, m.Rect.Dx(), m.Rect.Dy())
+	case *image.Paletted:
+		m.Pix, m.Stride = d.layOutRows(m.Pix, m.Stride, m.Rect.Dx(), m.Rect.Dy())
+	}
+}
+
+// layOutRows returns the height rows of size bytes in pix, which start every
+// stride bytes, laid out like decodeLaidOut, and their stride.
+func (d *decoder) layOutRows(pix []byte, stride, size, height int) ([]byte, int) {
+	if d.opts.FlipVertical {
+		tmp := make([]byte, size)
+		for top, bottom := 0, height-1; top < bottom; top, bottom = top+1, bottom-1 {
+			t := pix[top*stride : top*stride+size]
+			b := pix[bottom*stride : bottom*stride+size]
+			copy(tmp, t)
+			copy(t, b)
+			copy(b, tmp)
+		}
+	}
+
+	aligned := d.stride(size)
+	if aligned == stride {
+		return pix, stride
+	}
+
+	out := make([]byte, height*aligned)
+	for y := 0; y < height; y++ {
+		copy(out[y*aligned:y*aligned+size], pix[y*stride:y*stride+size])
+	}
+
+	return out, aligned
+}
@@ -0,0 +1,141 @@
+package qoi
+
+import (
+	"bufio"
+	"image"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// Op is the operation reported to an Observer.
+type Op int
+
+const (
+	OpDecode Op = iota
+	OpEncode
+)
+
+func (op Op) String() string {
+	switch op {
+	case OpDecode:
+		return "decode"
+	case OpEncode:
+		return "encode"
+	}
+
+	return "unknown"
+}
+
+// Event describes a finished call of Decode or Encode.
+type Event struct {
+	Op Op
+
+	// Width and Height are the dimensions of the image, or the ones claimed
+	// by the header for a failed decode. They are 0 if the header was not read.
+	Width, Height int
+
+	// Bytes is the number of bytes read from the reader passed to Decode,
+	// which may include data buffered beyond the end of the image, or the
+	// number of bytes written by Encode.
+	Bytes int64
+
+	Duration time.Duration
+
+	// Err is the error returned to the caller, nil on success.
+	Err error
+}
+
+// An Observer is notified at the start and the end of every call of Decode
+// and Encode, e.g. to collect metrics. The methods are called synchronously
+// from the decoding or encoding goroutine, so they must be fast and safe for
+// concurrent use.
+type Observer interface {
+	Start(op Op)
+	Done(e Event)
+}
+
+// observerBox wraps the Observer, since an atomic.Value cannot store nil.
+type observerBox struct {
+	o Observer
+}
+
+var observer atomic.Value
+
+// SetObserver installs o as the Observer of all subsequent calls of Decode and
+// Encode, replacing the previous one. A nil Observer disables the hooks.
+// It is safe to call SetObserver while images are decoded or encoded.
+func SetObserver(o Observer) {
+	observer.Store(observerBox{o})
+}
+
+func loadObserver() Observer {
+	box, _ := observer.Load().(observerBox)
+	return box.o
+}
+
+func observeDecode(o Observer, r io.Reader, d *decoder) (image.Image, error) {
+	o.Start(OpDecode)
+	start := time.Now()
+
+	cr := &countingReader{r: r}
+	d.buf = bufio.NewReader(cr)
+	d.count = cr
+
+	d.decodeAll()
+	m, err := d.result()
+
+	o.Done(Event{
+		Op:       OpDecode,
+		Width:    d.h.width,
+		Height:   d.h.height,
+		Bytes:    cr.n,
+		Duration: time.Since(start),
+		Err:      err,
+	})
+
+	return m, err
+}
+
+func observeEncode(o Observer, w io.Writer, m image.Image, opts EncodeOptions) error {
+	o.Start(OpEncode)
+	start := time.Now()
+
+	cw := &countingWriter{w: w}
+	err := encodeImage(cw, m, opts)
+
+	o.Done(Event{
+		Op:       OpEncode,
+		Width:    m.Bounds().Dx(),
+		Height:   m.Bounds().Dy(),
+		Bytes:    cw.n,
+		Duration: time.Since(start),
+		Err:      err,
+	})
+
+	return err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+
+	return n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+
+	return n, err
+}
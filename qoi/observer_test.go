@@ -0,0 +1,122 @@
+package qoi
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"sync"
+	"testing"
+)
+
+type recordingObserver struct {
+	mu     sync.Mutex
+	starts []Op
+	events []Event
+}
+
+func (o *recordingObserver) Start(op Op) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.starts = append(o.starts, op)
+}
+
+func (o *recordingObserver) Done(e Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.events = append(o.events, e)
+}
+
+type nopObserver struct{}
+
+func (nopObserver) Start(op Op)  {}
+func (nopObserver) Done(e Event) {}
+
+func TestObserver(t *testing.T) {
+	o := &recordingObserver{}
+	SetObserver(o)
+	defer SetObserver(nil)
+
+	m := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	var buf bytes.Buffer
+	if err := Encode(&buf, m); err != nil {
+		t.Fatalf("could not encode image: %v\n", err)
+	}
+	size := int64(buf.Len())
+
+	if _, err := Decode(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("could not decode image: %v\n", err)
+	}
+
+	//the end marker is missing
+	truncated := buf.Bytes()[:buf.Len()-1]
+	_, decodeErr := Decode(bytes.NewReader(truncated))
+	if decodeErr == nil {
+		t.Fatalf("decoded a truncated image\n")
+	}
+
+	encodeErr := Encode(&buf, image.NewNRGBA(image.Rectangle{}))
+	if encodeErr == nil {
+		t.Fatalf("encoded an empty image\n")
+	}
+
+	expectedStarts := []Op{OpEncode, OpDecode, OpDecode, OpEncode}
+	expectedEvents := []Event{
+		{Op: OpEncode, Width: 3, Height: 2, Bytes: size},
+		{Op: OpDecode, Width: 3, Height: 2, Bytes: size},
+		{Op: OpDecode, Width: 3, Height: 2, Bytes: size - 1, Err: decodeErr},
+		{Op: OpEncode, Err: encodeErr},
+	}
+
+	if fmt.Sprint(o.starts) != fmt.Sprint(expectedStarts) {
+		t.Errorf("\nExpected starts:\t %v\nActual starts:\t %v\n", expectedStarts, o.starts)
+	}
+	if len(o.events) != len(expectedEvents) {
+		t.Fatalf("\nExpected %d events, got %d: %+v\n", len(expectedEvents), len(o.events), o.events)
+	}
+	for i, actual := range o.events {
+		if actual.Duration < 0 {
+			t.Errorf("\nEvent %d:\t negative duration %v\n", i, actual.Duration)
+		}
+		actual.Duration = 0
+		if actual != expectedEvents[i] {
+			t.Errorf("\nEvent %d\nExpected:\t %+v\nActual:\t %+v\n", i, expectedEvents[i], actual)
+		}
+	}
+}
+
+func TestObserverUnset(t *testing.T) {
+	o := &recordingObserver{}
+	SetObserver(o)
+	SetObserver(nil)
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatalf("could not encode image: %v\n", err)
+	}
+	if _, err := Decode(&buf); err != nil {
+		t.Fatalf("could not decode image: %v\n", err)
+	}
+
+	if len(o.starts) != 0 || len(o.events) != 0 {
+		t.Errorf("\nremoved observer was notified: %v %+v\n", o.starts, o.events)
+	}
+}
+
+func BenchmarkDecodeWithObserver(b *testing.B) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 256, 256))); err != nil {
+		b.Fatalf("could not encode image: %v\n", err)
+	}
+
+	SetObserver(nopObserver{})
+	defer SetObserver(nil)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Decode(bytes.NewReader(buf.Bytes())); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,544 @@
+package qoi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LukiDS/image/imgconv"
+	"github.com/LukiDS/image/imgtest"
+	"github.com/LukiDS/image/testimg"
+)
+
+// readTestFile returns the contents of the test file name.
+func readTestFile(t testing.TB, name string) []byte {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("could not read file: %v\n", err)
+	}
+
+	return data
+}
+
+// decodeNRGBA decodes data with Decode.
+func decodeNRGBA(t testing.TB, data []byte) *image.NRGBA {
+	m, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("could not decode file: %v\n", err)
+	}
+
+	return m.(*image.NRGBA)
+}
+
+// swapRedBlue swaps the red and the blue channel of c.
+func swapRedBlue(c color.NRGBA) color.NRGBA {
+	return color.NRGBA{c.B, c.G, c.R, c.A}
+}
+
+func TestDecodeMapColor(t *testing.T) {
+	for _, name := range testFiles(t) {
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			data := readTestFile(t, name)
+
+			//remapping a plain decode must give the same image
+			expected := decodeNRGBA(t, data)
+			for i := 0; i < len(expected.Pix); i += 4 {
+				expected.Pix[i], expected.Pix[i+2] = expected.Pix[i+2], expected.Pix[i]
+			}
+
+			m, err := DecodeWithOptions(bytes.NewReader(data), DecodeOptions{MapColor: swapRedBlue})
+			if err != nil {
+				t.Fatalf("could not decode file: %v\n", err)
+			}
+			imgtest.AssertEqual(t, expected, m)
+		})
+	}
+}
+
+func TestDecodeMapColorObserved(t *testing.T) {
+	o := &recordingObserver{}
+	SetObserver(o)
+	defer SetObserver(nil)
+
+	data := readTestFile(t, "../testdata/qoi_logo.qoi")
+	m, err := DecodeWithOptions(bytes.NewReader(data), DecodeOptions{MapColor: func(color.NRGBA) color.NRGBA {
+		return color.NRGBA{1, 2, 3, 4}
+	}})
+	if err != nil {
+		t.Fatalf("could not decode file: %v\n", err)
+	}
+
+	if c := m.(*image.NRGBA).NRGBAAt(7, 9); c != (color.NRGBA{1, 2, 3, 4}) {
+		t.Errorf("DecodeWithOptions with an observer ignored MapColor: pixel %v\n", c)
+	}
+	if len(o.events) != 1 || o.events[0].Op != OpDecode {
+		t.Errorf("DecodeWithOptions: observed events %v, expected one decode\n", o.events)
+	}
+}
+
+func BenchmarkDecodeMapColor(b *testing.B) {
+	data := readTestFile(b, "../testdata/kodim23.qoi")
+	opts := DecodeOptions{MapColor: swapRedBlue}
+
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := DecodeWithOptions(bytes.NewReader(data), opts); err != nil {
+			b.Fatalf("could not decode file: %v\n", err)
+		}
+	}
+}
+
+// sprite returns a 24x20 image with 16 colors, one of them transparent,
+// whose colors appear in the order of their index.
+func sprite() *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, 24, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 24; x++ {
+			i := (x/6 + 4*(y/5)) % 16
+			c := color.NRGBA{byte(i * 17), byte(255 - i*13), byte(i * i), 0xff}
+			if i == 5 {
+				c = color.NRGBA{}
+			}
+			m.SetNRGBA(x, y, c)
+		}
+	}
+
+	return m
+}
+
+// encodeTestImage returns m encoded with Encode.
+func encodeTestImage(t testing.TB, m image.Image) []byte {
+	var buf bytes.Buffer
+	if err := Encode(&buf, m); err != nil {
+		t.Fatalf("could not encode image: %v\n", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestDecodePaletted(t *testing.T) {
+	src := sprite()
+	data := encodeTestImage(t, src)
+
+	m, err := DecodePaletted(bytes.NewReader(data), 16)
+	if err != nil {
+		t.Fatalf("DecodePaletted: unexpected error: %v\n", err)
+	}
+	imgtest.AssertEqual(t, src, m)
+
+	if len(m.Palette) != 16 {
+		t.Fatalf("DecodePaletted: %d palette colors, expected 16\n", len(m.Palette))
+	}
+	//the colors are in the order of their first pixel
+	for i, c := range m.Palette {
+		x, y := 6*(i%4), 5*(i/4)
+		if c != src.NRGBAAt(x, y) {
+			t.Errorf("\nPalette color %d:\nExpected:\t %v\nActual:\t %v\n", i, src.NRGBAAt(x, y), c)
+		}
+	}
+
+	if _, err := DecodePaletted(bytes.NewReader(data), 15); !errors.Is(err, ErrTooManyColors) {
+		t.Errorf("DecodePaletted with 15 colors: error %v, expected ErrTooManyColors\n", err)
+	}
+}
+
+func TestDecodePalettedErrors(t *testing.T) {
+	photo := readTestFile(t, "../testdata/kodim23.qoi")
+
+	tests := []struct {
+		name      string
+		data      []byte
+		maxColors int
+		target    error
+	}{
+		{name: "photo", data: photo, maxColors: 256, target: ErrTooManyColors},
+		{name: "zero colors", data: photo, maxColors: 0},
+		{name: "too many allowed colors", data: photo, maxColors: 257},
+		{name: "truncated", data: encodeTestImage(t, sprite())[:40], maxColors: 16, target: io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := DecodePaletted(bytes.NewReader(tt.data), tt.maxColors)
+			if err == nil || m != nil {
+				t.Fatalf("DecodePaletted(%s) = %v, %v, expected an error\n", tt.name, m, err)
+			}
+			if tt.target != nil && !errors.Is(err, tt.target) {
+				t.Errorf("DecodePaletted(%s): error %v, expected %v\n", tt.name, err, tt.target)
+			}
+		})
+	}
+}
+
+func TestDecodeMaxPaletteColors(t *testing.T) {
+	//colors beyond the palette only appear in the last rows
+	mixed := sprite()
+	draw.Draw(mixed, image.Rect(0, 15, 24, 20), testimg.Noise(24, 5, 1), image.Point{}, draw.Src)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		opts     DecodeOptions
+		paletted bool
+	}{
+		{name: "sprite", data: encodeTestImage(t, sprite()), opts: DecodeOptions{MaxPaletteColors: 16}, paletted: true},
+		{name: "sprite above 256", data: encodeTestImage(t, sprite()), opts: DecodeOptions{MaxPaletteColors: 1000}, paletted: true},
+		{name: "sprite with too few colors", data: encodeTestImage(t, sprite()), opts: DecodeOptions{MaxPaletteColors: 4}},
+		{name: "late colors", data: encodeTestImage(t, mixed), opts: DecodeOptions{MaxPaletteColors: 16}},
+		{name: "late colors mapped", data: encodeTestImage(t, mixed), opts: DecodeOptions{MaxPaletteColors: 16, MapColor: swapRedBlue}},
+		{name: "sprite mapped", data: encodeTestImage(t, sprite()), opts: DecodeOptions{MaxPaletteColors: 16, MapColor: swapRedBlue}, paletted: true},
+		{name: "photo", data: readTestFile(t, "../testdata/kodim23.qoi"), opts: DecodeOptions{MaxPaletteColors: 256}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected, err := DecodeWithOptions(bytes.NewReader(tt.data), DecodeOptions{MapColor: tt.opts.MapColor})
+			if err != nil {
+				t.Fatalf("could not decode image: %v\n", err)
+			}
+
+			m, err := DecodeWithOptions(bytes.NewReader(tt.data), tt.opts)
+			if err != nil {
+				t.Fatalf("DecodeWithOptions(%s): unexpected error: %v\n", tt.name, err)
+			}
+			if _, ok := m.(*image.Paletted); ok != tt.paletted {
+				t.Errorf("DecodeWithOptions(%s) returned %T, expected a paletted image: %v\n", tt.name, m, tt.paletted)
+			}
+			imgtest.AssertEqual(t, expected, m)
+		})
+	}
+}
+
+func TestDecodeMaxPaletteColorsWithTestFiles(t *testing.T) {
+	for _, name := range testFiles(t) {
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			data := readTestFile(t, name)
+
+			m, err := DecodeWithOptions(bytes.NewReader(data), DecodeOptions{MaxPaletteColors: 256})
+			if err != nil {
+				t.Fatalf("could not decode file: %v\n", err)
+			}
+			imgtest.AssertEqual(t, decodeNRGBA(t, data), m)
+		})
+	}
+}
+
+// grayImage returns an opaque gray image with a gradient and runs.
+func grayImage() *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, 40, 30))
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 40; x++ {
+			v := byte(x * y / 5)
+			m.SetNRGBA(x, y, color.NRGBA{v, v, v, 0xff})
+		}
+	}
+
+	return m
+}
+
+func TestDecodePreferGray(t *testing.T) {
+	gray := grayImage()
+
+	colored := grayImage()
+	colored.SetNRGBA(17, 21, color.NRGBA{0x10, 0x11, 0x10, 0xff})
+
+	transparent := grayImage()
+	transparent.SetNRGBA(39, 29, color.NRGBA{0x10, 0x10, 0x10, 0xfe})
+
+	tests := []struct {
+		name     string
+		m        image.Image
+		opts     DecodeOptions
+		expected string
+	}{
+		{name: "gray", m: gray, opts: DecodeOptions{PreferGray: true}, expected: "*image.Gray"},
+		{name: "single colored pixel", m: colored, opts: DecodeOptions{PreferGray: true}, expected: "*image.NRGBA"},
+		{name: "last pixel transparent", m: transparent, opts: DecodeOptions{PreferGray: true}, expected: "*image.NRGBA"},
+		{name: "colored first pixel", m: testimg.Gradient(20, 20), opts: DecodeOptions{PreferGray: true}, expected: "*image.NRGBA"},
+		{name: "gray before palette", m: gray, opts: DecodeOptions{PreferGray: true, MaxPaletteColors: 256}, expected: "*image.Gray"},
+		{name: "colored to palette", m: colored, opts: DecodeOptions{PreferGray: true, MaxPaletteColors: 256}, expected: "*image.Paletted"},
+		{name: "colored beyond palette", m: colored, opts: DecodeOptions{PreferGray: true, MaxPaletteColors: 8}, expected: "*image.NRGBA"},
+		{name: "gray mapped", m: testimg.Gradient(20, 20), opts: DecodeOptions{PreferGray: true, MapColor: func(c color.NRGBA) color.NRGBA {
+			return color.NRGBA{c.G, c.G, c.G, 0xff}
+		}}, expected: "*image.Gray"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := encodeTestImage(t, tt.m)
+
+			expected, err := DecodeWithOptions(bytes.NewReader(data), DecodeOptions{MapColor: tt.opts.MapColor})
+			if err != nil {
+				t.Fatalf("could not decode image: %v\n", err)
+			}
+
+			m, err := DecodeWithOptions(bytes.NewReader(data), tt.opts)
+			if err != nil {
+				t.Fatalf("DecodeWithOptions(%s): unexpected error: %v\n", tt.name, err)
+			}
+			if fmt.Sprintf("%T", m) != tt.expected {
+				t.Errorf("DecodeWithOptions(%s) returned %T, expected %s\n", tt.name, m, tt.expected)
+			}
+			imgtest.AssertEqual(t, expected, m)
+		})
+	}
+}
+
+func TestDecodePreferGrayWithTestFiles(t *testing.T) {
+	for _, name := range testFiles(t) {
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			data := readTestFile(t, name)
+
+			m, err := DecodeWithOptions(bytes.NewReader(data), DecodeOptions{PreferGray: true})
+			if err != nil {
+				t.Fatalf("could not decode file: %v\n", err)
+			}
+			imgtest.AssertEqual(t, decodeNRGBA(t, data), m)
+		})
+	}
+}
+
+func TestDecodeIgnoreAlpha(t *testing.T) {
+	for _, name := range append(testFiles(t), "../testdata/qoi/alpha_ramp.qoi") {
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			data := readTestFile(t, name)
+
+			expected := decodeNRGBA(t, data)
+			for i := 3; i < len(expected.Pix); i += 4 {
+				expected.Pix[i] = 0xff
+			}
+
+			m, err := DecodeWithOptions(bytes.NewReader(data), DecodeOptions{IgnoreAlpha: true})
+			if err != nil {
+				t.Fatalf("could not decode file: %v\n", err)
+			}
+			if !m.(*image.NRGBA).Opaque() {
+				t.Errorf("DecodeWithOptions(%s) with IgnoreAlpha returned a translucent image\n", name)
+			}
+			imgtest.AssertEqual(t, expected, m)
+		})
+	}
+}
+
+func TestDecodeIgnoreAlphaWithOptions(t *testing.T) {
+	//a gray image which is only translucent in its last row
+	m := grayImage()
+	m.SetNRGBA(3, 29, color.NRGBA{0x20, 0x20, 0x20, 0x40})
+	data := encodeTestImage(t, m)
+
+	gray, err := DecodeWithOptions(bytes.NewReader(data), DecodeOptions{IgnoreAlpha: true, PreferGray: true})
+	if err != nil {
+		t.Fatalf("could not decode image: %v\n", err)
+	}
+	if _, ok := gray.(*image.Gray); !ok {
+		t.Errorf("DecodeWithOptions with IgnoreAlpha and PreferGray returned %T, expected *image.Gray\n", gray)
+	}
+
+	//MapColor sees the opaque colors
+	var translucent bool
+	_, err = DecodeWithOptions(bytes.NewReader(readTestFile(t, "../testdata/testcard_rgba.qoi")), DecodeOptions{
+		IgnoreAlpha: true,
+		MapColor: func(c color.NRGBA) color.NRGBA {
+			translucent = translucent || c.A != 0xff
+			return c
+		},
+	})
+	if err != nil {
+		t.Fatalf("could not decode file: %v\n", err)
+	}
+	if translucent {
+		t.Errorf("DecodeWithOptions with IgnoreAlpha passed translucent colors to MapColor\n")
+	}
+}
+
+func TestDecodeNRGBA64(t *testing.T) {
+	for _, name := range testFiles(t) {
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			data := readTestFile(t, name)
+			expected := imgconv.ToNRGBA64(decodeNRGBA(t, data))
+
+			m, err := DecodeNRGBA64(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("could not decode file: %v\n", err)
+			}
+			if m.Rect != expected.Rect || m.Stride != expected.Stride || !bytes.Equal(m.Pix, expected.Pix) {
+				t.Errorf("DecodeNRGBA64(%s) differs from imgconv.ToNRGBA64 of Decode\n", name)
+			}
+		})
+	}
+
+	if _, err := DecodeNRGBA64(bytes.NewReader(encodeTestImage(t, sprite())[:40])); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("DecodeNRGBA64 of truncated data: error %v, expected io.ErrUnexpectedEOF\n", err)
+	}
+}
+
+func TestDecodeFlipVertical(t *testing.T) {
+	files := map[string][]byte{
+		//runs of 250 pixels across rows of 77, and more rows than allocated at first
+		"runs": encodeTestImage(t, testimg.SolidRuns(77, 20)),
+		"tall": encodeTestImage(t, testimg.SolidRuns(1000, qoiInitialPixels/1000+100)),
+	}
+	for _, name := range testFiles(t) {
+		files[filepath.Base(name)] = readTestFile(t, name)
+	}
+
+	for name, data := range files {
+		t.Run(name, func(t *testing.T) {
+			expected := imgconv.FlipV(decodeNRGBA(t, data))
+
+			m, err := DecodeWithOptions(bytes.NewReader(data), DecodeOptions{FlipVertical: true})
+			if err != nil {
+				t.Fatalf("could not decode file: %v\n", err)
+			}
+			if m := m.(*image.NRGBA); m.Rect != expected.Rect || !bytes.Equal(m.Pix, expected.Pix) {
+				t.Errorf("DecodeWithOptions(%s) with FlipVertical differs from imgconv.FlipV\n", name)
+			}
+		})
+	}
+}
+
+func TestDecodeFlipVerticalWithOptions(t *testing.T) {
+	data := encodeTestImage(t, sprite())
+	gray := encodeTestImage(t, grayImage())
+
+	testCases := []struct {
+		name string
+		data []byte
+		opts DecodeOptions
+	}{
+		{name: "MapColor", data: data, opts: DecodeOptions{MapColor: swapRedBlue}},
+		{name: "IgnoreAlpha", data: data, opts: DecodeOptions{IgnoreAlpha: true}},
+		{name: "MaxPaletteColors", data: data, opts: DecodeOptions{MaxPaletteColors: 16}},
+		{name: "MaxPaletteColors exceeded", data: data, opts: DecodeOptions{MaxPaletteColors: 4}},
+		{name: "PreferGray", data: gray, opts: DecodeOptions{PreferGray: true}},
+		{name: "Recover", data: data[:len(data)/2], opts: DecodeOptions{Recover: true, RecoverColor: recoverColor}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expected, expectedErr := DecodeWithOptions(bytes.NewReader(tc.data), tc.opts)
+
+			tc.opts.FlipVertical = true
+			m, err := DecodeWithOptions(bytes.NewReader(tc.data), tc.opts)
+			if fmt.Sprint(err) != fmt.Sprint(expectedErr) {
+				t.Fatalf("error %v, expected %v\n", err, expectedErr)
+			}
+
+			if fmt.Sprintf("%T", m) != fmt.Sprintf("%T", expected) {
+				t.Errorf("image type %T, expected %T\n", m, expected)
+			}
+			if !bytes.Equal(imgconv.ToNRGBA(m).Pix, imgconv.FlipV(expected).Pix) {
+				t.Errorf("image with FlipVertical differs from imgconv.FlipV\n")
+			}
+		})
+	}
+
+	m, err := DecodeNRGBA64(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("could not decode file: %v\n", err)
+	}
+	flipped, err := decodeWith(bytes.NewReader(data), &decoder{nrgba64: true, opts: DecodeOptions{FlipVertical: true}})
+	if err != nil {
+		t.Fatalf("could not decode file: %v\n", err)
+	}
+	if !bytes.Equal(imgconv.ToNRGBA(flipped).Pix, imgconv.FlipV(m).Pix) {
+		t.Errorf("*image.NRGBA64 with FlipVertical differs from imgconv.FlipV\n")
+	}
+}
+
+func TestDecodeRowAlignment(t *testing.T) {
+	for _, width := range []int{1, 3, 63, 64, 65, 100} {
+		data := encodeTestImage(t, testimg.Gradient(width, 7))
+		expected := decodeNRGBA(t, data)
+
+		for _, alignment := range []int{0, 1, 4, 8, 256, 7} {
+			for _, flip := range []bool{false, true} {
+				t.Run(fmt.Sprintf("%d/%d/%t", width, alignment, flip), func(t *testing.T) {
+					want := expected
+					if flip {
+						want = imgconv.FlipV(expected)
+					}
+
+					m, err := DecodeWithOptions(bytes.NewReader(data), DecodeOptions{RowAlignment: alignment, FlipVertical: flip})
+					if err != nil {
+						t.Fatalf("could not decode file: %v\n", err)
+					}
+					checkAlignedRows(t, m.(*image.NRGBA), want, alignment)
+				})
+			}
+		}
+	}
+}
+
+// checkAlignedRows reports an error if the rows of m are not aligned to
+// alignment with zero padding, or their pixels differ from expected.
+func checkAlignedRows(t testing.TB, m, expected *image.NRGBA, alignment int) {
+	t.Helper()
+
+	size := 4 * expected.Rect.Dx()
+	if alignment < 4 {
+		alignment = 4
+	}
+	stride := (size + alignment - 1) / alignment * alignment
+
+	if m.Rect != expected.Rect || m.Stride != stride || len(m.Pix) != stride*expected.Rect.Dy() {
+		format := fmt.Sprintf("\n") +
+			fmt.Sprintf("Expected bounds:\t %v\n", expected.Rect) +
+			fmt.Sprintf("Actual bounds:\t %v\n", m.Rect) +
+			fmt.Sprintf("Expected stride:\t %d\n", stride) +
+			fmt.Sprintf("Actual stride:\t %d\n", m.Stride) +
+			fmt.Sprintf("Pixel bytes:\t %d\n", len(m.Pix))
+
+		t.Fatalf(format)
+	}
+
+	for y := 0; y < expected.Rect.Dy(); y++ {
+		row := m.Pix[y*stride : (y+1)*stride]
+		if !bytes.Equal(row[:size], expected.Pix[y*expected.Stride:][:size]) {
+			t.Errorf("row %d differs from the reference\n", y)
+		}
+		if !bytes.Equal(row[size:], make([]byte, stride-size)) {
+			t.Errorf("padding of row %d is not zero\n", y)
+		}
+	}
+}
+
+func TestDecodeRowAlignmentWithOptions(t *testing.T) {
+	data := encodeTestImage(t, sprite())
+
+	//the 16 colors of the sprite fit into a palette, but not into one of 4 colors
+	paletted, err := DecodeWithOptions(bytes.NewReader(data), DecodeOptions{MaxPaletteColors: 16, RowAlignment: 32})
+	if err != nil {
+		t.Fatalf("could not decode file: %v\n", err)
+	}
+	if p := paletted.(*image.Paletted); p.Stride != 32 || len(p.Pix) != 32*p.Rect.Dy() {
+		t.Errorf("MaxPaletteColors: stride %d of %d bytes, expected 32\n", p.Stride, len(p.Pix))
+	}
+	checkAlignedRows(t, imgconv.CloneNRGBA(paletted), decodeNRGBA(t, data), 4)
+
+	m, err := DecodeWithOptions(bytes.NewReader(data), DecodeOptions{MaxPaletteColors: 4, RowAlignment: 64})
+	if err != nil {
+		t.Fatalf("could not decode file: %v\n", err)
+	}
+	checkAlignedRows(t, m.(*image.NRGBA), decodeNRGBA(t, data), 64)
+
+	gray, err := DecodeWithOptions(bytes.NewReader(encodeTestImage(t, grayImage())), DecodeOptions{PreferGray: true, RowAlignment: 256})
+	if err != nil {
+		t.Fatalf("could not decode file: %v\n", err)
+	}
+	if g := gray.(*image.Gray); g.Stride != 256 || len(g.Pix) != 256*g.Rect.Dy() {
+		t.Errorf("PreferGray: stride %d of %d bytes, expected 256\n", g.Stride, len(g.Pix))
+	}
+
+	if _, err := DecodeWithOptions(bytes.NewReader(data), DecodeOptions{RowAlignment: maxRowAlignment + 1}); err == nil {
+		t.Errorf("expected an error for the row alignment %d\n", maxRowAlignment+1)
+	}
+}
@@ -0,0 +1,112 @@
+package qoi
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// ErrTooManyColors is returned by DecodePaletted for images with more
+// distinct colors than allowed.
+var ErrTooManyColors = errors.New("too many colors for a paletted image")
+
+// DecodePaletted reads a QOI image from r and returns it as an
+// *image.Paletted image, which needs a quarter of the memory of an
+// *image.NRGBA one. The palette holds the colors in the order of their first
+// pixel. If the image has more than maxColors distinct colors, which must be
+// 1 to 256, the returned error wraps ErrTooManyColors; see
+// DecodeOptions.MaxPaletteColors to decode such images as *image.NRGBA instead.
+func DecodePaletted(r io.Reader, maxColors int) (*image.Paletted, error) {
+	if maxColors < 1 || maxColors > 256 {
+		return nil, fmt.Errorf("invalid maximum of %d palette colors, must be 1 to 256", maxColors)
+	}
+
+	m, err := decodeWith(r, &decoder{
+		opts:          DecodeOptions{MaxPaletteColors: maxColors},
+		strictPalette: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m.(*image.Paletted), nil
+}
+
+// maxPaletteColors returns the number of palette colors allowed by d.opts.
+func (d *decoder) maxPaletteColors() int {
+	if d.opts.MaxPaletteColors > 256 {
+		return 256
+	}
+
+	return d.opts.MaxPaletteColors
+}
+
+// newPaletteSink returns an empty paletteSink for the image of d.
+func (d *decoder) newPaletteSink() *paletteSink {
+	return &paletteSink{
+		width:     d.h.width,
+		maxColors: d.maxPaletteColors(),
+		colors:    make(map[color.NRGBA]uint8),
+		pix:       make([]byte, 0, d.initialPixels()),
+	}
+}
+
+// paletteSink stores rows with at most maxColors distinct colors as
+// *image.Paletted pixels, with the colors in the order of their first pixel.
+type paletteSink struct {
+	width     int
+	maxColors int
+	palette   color.Palette
+	colors    map[color.NRGBA]uint8
+	pix       []byte
+}
+
+func (s *paletteSink) add(row []byte) bool {
+	n := len(s.pix)
+
+	var last color.NRGBA
+	var index uint8
+	for i := 0; i < len(row); i += 4 {
+		c := color.NRGBA{row[i], row[i+1], row[i+2], row[i+3]}
+		if i > 0 && c == last {
+			s.pix = append(s.pix, index)
+			continue
+		}
+
+		var ok bool
+		if index, ok = s.colors[c]; !ok {
+			if len(s.palette) == s.maxColors {
+				//a sink is not used after a failure, so the palette may keep the new colors
+				s.pix = s.pix[:n]
+				return false
+			}
+			index = uint8(len(s.palette))
+			s.colors[c] = index
+			s.palette = append(s.palette, c)
+		}
+		last = c
+		s.pix = append(s.pix, index)
+	}
+
+	return true
+}
+
+func (s *paletteSink) appendRow(pix []byte, y int) []byte {
+	for _, i := range s.pix[y*s.width : (y+1)*s.width] {
+		c := s.palette[i].(color.NRGBA)
+		pix = append(pix, c.R, c.G, c.B, c.A)
+	}
+
+	return pix
+}
+
+func (s *paletteSink) image(width, height int) image.Image {
+	return &image.Paletted{
+		Pix:     s.pix,
+		Stride:  width,
+		Rect:    image.Rect(0, 0, width, height),
+		Palette: s.palette,
+	}
+}
@@ -22,6 +22,10 @@ const (
 	qoiHeaderSize    = 14 //size in bytes
 	qoiMaxBufferSize = 64
 	qoiMaxRunSize    = 62
+
+	// qoiInitialPixels caps the pixels allocated before any data is decoded,
+	// so a crafted header cannot claim gigabytes for a file of a few bytes.
+	qoiInitialPixels = 1 << 20
 )
 
 var qoiEndMarker = []byte{0, 0, 0, 0, 0, 0, 0, 1}
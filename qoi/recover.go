@@ -0,0 +1,163 @@
+package qoi
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+)
+
+// ErrCorrupt is wrapped by the *RecoveryReport of a corrupt image decoded
+// with DecodeOptions.Recover.
+var ErrCorrupt = errors.New("corrupt qoi data")
+
+// errEarlyEndMarker is the failure of an image whose end marker appears
+// inside the pixel data, e.g. because bytes of it were lost.
+var errEarlyEndMarker = errors.New("qoi end marker inside the pixel data")
+
+// errLongRun is the failure of an image whose last run is longer than the
+// remaining pixels.
+var errLongRun = errors.New("qoi run beyond the last pixel")
+
+// A RecoveryReport is returned as the error of a corrupt image decoded with
+// DecodeOptions.Recover, together with the image. It wraps ErrCorrupt.
+type RecoveryReport struct {
+	// X and Y are the first pixel which could not be decoded, set to
+	// DecodeOptions.RecoverColor unless X is 0. They are 0 and the image
+	// height if the failure is detected at the end marker.
+	X, Y int
+	// Offset is the byte offset in the data at which the failure was
+	// detected, counted from the start of the reader.
+	Offset int64
+	// Err is the failure, e.g. an error wrapping io.ErrUnexpectedEOF.
+	Err error
+}
+
+func (r *RecoveryReport) Error() string {
+	return fmt.Sprintf("%v at pixel %d,%d, byte offset %d: %v", ErrCorrupt, r.X, r.Y, r.Offset, r.Err)
+}
+
+func (r *RecoveryReport) Unwrap() error {
+	return r.Err
+}
+
+// Is reports whether target is ErrCorrupt.
+func (r *RecoveryReport) Is(target error) bool {
+	return target == ErrCorrupt
+}
+
+// DecodeConcatenated reads the QOI images stored one after another in r up
+// to its end, applying opts.
+//
+// Without opts.Recover, it stops at the first error and returns the images
+// decoded before it together with the error. With opts.Recover, corrupt
+// images are returned like DecodeWithOptions does, decoding continues after
+// the next end marker, and the reports of the corrupt images are returned
+// with the index of their image. The returned error is then only set for
+// failures which cannot be recovered from, like an invalid header.
+func DecodeConcatenated(r io.Reader, opts DecodeOptions) ([]image.Image, map[int]*RecoveryReport, error) {
+	cr := &countingReader{r: r}
+	buf := bufio.NewReader(cr)
+
+	var images []image.Image
+	reports := make(map[int]*RecoveryReport)
+	for {
+		if _, err := buf.Peek(1); err == io.EOF {
+			return images, reports, nil
+		}
+
+		d := &decoder{buf: buf, count: cr, opts: opts, concatenated: true}
+		d.decodeAll()
+		if d.err != nil {
+			return images, reports, fmt.Errorf("image %d: %w", len(images), d.err)
+		}
+
+		if d.report != nil {
+			reports[len(images)] = d.report
+		}
+		images = append(images, d.m)
+	}
+}
+
+// isCorrupt reports whether the decoding failure err is caused by the data
+// rather than by the reader.
+func isCorrupt(err error) bool {
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, errEarlyEndMarker)
+}
+
+// recover turns the failure d.err into d.report, detected at the pixel with
+// the index pixel and the byte offset.
+func (d *decoder) recover(pixel int, offset int64) {
+	d.report = &RecoveryReport{
+		X:      pixel % d.h.width,
+		Y:      pixel / d.h.width,
+		Offset: offset,
+		Err:    d.err,
+	}
+	d.err = nil
+}
+
+// offset returns the byte offset of the next byte of d.buf.
+func (d *decoder) offset() int64 {
+	return d.count.n - int64(d.buf.Buffered())
+}
+
+// cutRecovered ends the image with the row of the pixel with the index
+// pixel, at which decoding failed, filling the rest of the row in pix with
+// opts.RecoverColor.
+func (d *decoder) cutRecovered(pix []byte, pixel int) []byte {
+	rows := pixel / d.h.width
+	if x := pixel % d.h.width; x > 0 {
+		c := d.opts.RecoverColor
+		for ; x < d.h.width; x++ {
+			pix = append(pix, c.R, c.G, c.B, c.A)
+		}
+		rows++
+	}
+	d.h.height = rows
+
+	return pix
+}
+
+// atEndMarker reports whether the chunk 0 just read starts the end marker,
+// in which case it is unread.
+func (d *decoder) atEndMarker() bool {
+	if err := d.buf.UnreadByte(); err != nil {
+		return false
+	}
+
+	p, _ := d.buf.Peek(len(qoiEndMarker))
+	if bytes.Equal(p, qoiEndMarker) {
+		return true
+	}
+
+	d.buf.ReadByte()
+	return false
+}
+
+// skipEndMarker skips the data up to and including the next end marker, or
+// up to the end of the data.
+func (d *decoder) skipEndMarker() {
+	zeros := 0
+	for {
+		b, err := d.buf.ReadByte()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			d.err = err
+			return
+		}
+
+		switch {
+		case b == 0:
+			zeros++
+		case b == 1 && zeros >= len(qoiEndMarker)-1:
+			return
+		default:
+			zeros = 0
+		}
+	}
+}
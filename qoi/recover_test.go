@@ -0,0 +1,244 @@
+package qoi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"testing"
+)
+
+var recoverColor = color.NRGBA{255, 0, 255, 255}
+
+// decodeRecover decodes data with DecodeOptions.Recover. The image is nil
+// if the failure is at its first pixel.
+func decodeRecover(t testing.TB, data []byte) (*image.NRGBA, *RecoveryReport) {
+	m, err := DecodeWithOptions(bytes.NewReader(data), DecodeOptions{Recover: true, RecoverColor: recoverColor})
+	if err == nil {
+		return m.(*image.NRGBA), nil
+	}
+
+	var report *RecoveryReport
+	if !errors.As(err, &report) || !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("could not decode file: %v, expected a *RecoveryReport\n", err)
+	}
+	if first := report.X == 0 && report.Y == 0; (m == nil) != first {
+		t.Fatalf("image returned: %t, failure at the first pixel: %t, error %v\n", m != nil, first, err)
+	}
+	if m == nil {
+		return nil, report
+	}
+
+	return m.(*image.NRGBA), report
+}
+
+// checkRecovered reports an error if m does not end with the row of the
+// report, or its pixels before the report differ from expected, or the
+// pixels after it from recoverColor.
+func checkRecovered(t testing.TB, expected, m *image.NRGBA, report *RecoveryReport) {
+	t.Helper()
+
+	rows := report.Y
+	if report.X > 0 {
+		rows++
+	}
+	if rows == 0 {
+		if m != nil {
+			t.Errorf("image %v returned for a failure at the first pixel\n", m.Rect)
+		}
+		return
+	}
+	if bounds := image.Rect(0, 0, expected.Rect.Dx(), rows); m.Rect != bounds {
+		t.Fatalf("recovered image bounds %v, expected %v\n", m.Rect, bounds)
+	}
+
+	failure := report.Y*expected.Rect.Dx() + report.X
+	if !bytes.Equal(m.Pix[:4*failure], expected.Pix[:4*failure]) {
+		t.Errorf("pixels before %d,%d differ from the reference\n", report.X, report.Y)
+	}
+	for i := failure; i < len(m.Pix)/4; i++ {
+		if c := (color.NRGBA{m.Pix[4*i], m.Pix[4*i+1], m.Pix[4*i+2], m.Pix[4*i+3]}); c != recoverColor {
+			t.Errorf("pixel %d after the failure: %v, expected %v\n", i, c, recoverColor)
+			break
+		}
+	}
+}
+
+// chunkStart returns the offset of the first chunk of data starting at or
+// after the offset n.
+func chunkStart(data []byte, n int) int {
+	i := qoiHeaderSize
+	for i < n {
+		switch b := data[i]; {
+		case b == opRGB:
+			i += 4
+		case b == opRGBA:
+			i += 5
+		case b&maskOP == opLUMA:
+			i += 2
+		default:
+			i++
+		}
+	}
+
+	return i
+}
+
+func TestDecodeRecoverTruncated(t *testing.T) {
+	data := readTestFile(t, "../testdata/kodim23.qoi")
+	expected := decodeNRGBA(t, data)
+
+	for _, offset := range []int{qoiHeaderSize, qoiHeaderSize + 1, 1000, len(data) / 2, len(data) - len(qoiEndMarker) - 1} {
+		t.Run(fmt.Sprint(offset), func(t *testing.T) {
+			m, report := decodeRecover(t, data[:offset])
+			if report == nil {
+				t.Fatalf("no report for data truncated at %d\n", offset)
+			}
+			if report.Offset != int64(offset) || !errors.Is(report, io.ErrUnexpectedEOF) {
+				t.Errorf("report %v, expected offset %d and io.ErrUnexpectedEOF\n", report, offset)
+			}
+
+			checkRecovered(t, expected, m, report)
+		})
+	}
+}
+
+func TestDecodeRecoverLostBytes(t *testing.T) {
+	data := readTestFile(t, "../testdata/kodim23.qoi")
+	expected := decodeNRGBA(t, data)
+
+	//the data from the chunk at offset to the end marker is lost
+	for _, offset := range []int{qoiHeaderSize, chunkStart(data, 1000), chunkStart(data, len(data)/2)} {
+		t.Run(fmt.Sprint(offset), func(t *testing.T) {
+			lost := append(append([]byte(nil), data[:offset]...), qoiEndMarker...)
+
+			m, report := decodeRecover(t, lost)
+			if report == nil {
+				t.Fatalf("no report for data lost from %d\n", offset)
+			}
+			if report.Offset != int64(offset) || !errors.Is(report, errEarlyEndMarker) {
+				t.Errorf("report %v, expected offset %d and the early end marker\n", report, offset)
+			}
+
+			checkRecovered(t, expected, m, report)
+		})
+	}
+}
+
+func TestDecodeRecoverCorruptByte(t *testing.T) {
+	data := readTestFile(t, "../testdata/kodim23.qoi")
+	expected := decodeNRGBA(t, data)
+
+	//a corrupt color only changes colors, a corrupt chunk type loses the chunks
+	for _, offset := range []int{chunkStart(data, 100), chunkStart(data, 5000), chunkStart(data, len(data)/2), chunkStart(data, len(data)-100)} {
+		t.Run(fmt.Sprint(offset), func(t *testing.T) {
+			//the pixels decoded before the corrupt chunk match
+			_, before := decodeRecover(t, data[:offset])
+
+			corrupt := append([]byte(nil), data...)
+			if corrupt[offset] == opRGBA {
+				corrupt[offset] = opRGB
+			} else {
+				corrupt[offset] = opRGBA
+			}
+
+			m, report := decodeRecover(t, corrupt)
+			if report == nil {
+				t.Fatalf("no report for the corrupt byte at %d\n", offset)
+			}
+			if report.Offset < int64(offset) {
+				t.Errorf("report %v before the corrupt byte at %d\n", report, offset)
+			}
+
+			prefix := 4 * (before.Y*expected.Rect.Dx() + before.X)
+			if !bytes.Equal(m.Pix[:prefix], expected.Pix[:prefix]) {
+				t.Errorf("pixels before the corrupt byte differ from the reference\n")
+			}
+		})
+	}
+}
+
+func TestDecodeRecoverEndMarker(t *testing.T) {
+	data := readTestFile(t, "../testdata/dice.qoi")
+	expected := decodeNRGBA(t, data)
+	end := len(data) - len(qoiEndMarker)
+
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{name: "missing", data: data[:end]},
+		{name: "truncated", data: data[:end+3]},
+		{name: "wrong", data: append(append([]byte(nil), data[:end]...), 1, 2, 3, 4, 5, 6, 7, 8)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, report := decodeRecover(t, tc.data)
+			if report == nil {
+				t.Fatalf("no report for the %s end marker\n", tc.name)
+			}
+			if report.X != 0 || report.Y != expected.Rect.Dy() || report.Offset != int64(end) {
+				t.Errorf("report %v, expected pixel 0,%d and offset %d\n", report, expected.Rect.Dy(), end)
+			}
+			if !bytes.Equal(m.Pix, expected.Pix) {
+				t.Errorf("image with the %s end marker differs from the reference\n", tc.name)
+			}
+		})
+	}
+
+	//valid data is decoded without a report, ignoring data following it
+	if _, report := decodeRecover(t, append(append([]byte(nil), data...), 1, 2, 3)); report != nil {
+		t.Errorf("report %v for valid data\n", report)
+	}
+}
+
+func TestDecodeConcatenated(t *testing.T) {
+	names := []string{"../testdata/dice.qoi", "../testdata/qoi_logo.qoi", "../testdata/testcard.qoi"}
+	var files [][]byte
+	var expected []*image.NRGBA
+	for _, name := range names {
+		data := readTestFile(t, name)
+		files = append(files, data)
+		expected = append(expected, decodeNRGBA(t, data))
+	}
+
+	valid := bytes.Join(files, nil)
+	images, reports, err := DecodeConcatenated(bytes.NewReader(valid), DecodeOptions{})
+	if err != nil || len(images) != len(files) || len(reports) != 0 {
+		t.Fatalf("DecodeConcatenated: %d images, %d reports, error %v\n", len(images), len(reports), err)
+	}
+	for i, m := range images {
+		if !bytes.Equal(m.(*image.NRGBA).Pix, expected[i].Pix) {
+			t.Errorf("image %d differs from the reference\n", i)
+		}
+	}
+
+	//the second image loses the data from its middle to its end marker
+	lost := files[1][:len(files[1])/2]
+	corrupt := bytes.Join([][]byte{files[0], lost, qoiEndMarker, files[2]}, nil)
+
+	if images, _, err := DecodeConcatenated(bytes.NewReader(corrupt), DecodeOptions{}); err == nil || len(images) != 1 {
+		t.Errorf("DecodeConcatenated without Recover: %d images, error %v, expected 1 image and an error\n", len(images), err)
+	}
+
+	images, reports, err = DecodeConcatenated(bytes.NewReader(corrupt), DecodeOptions{Recover: true, RecoverColor: recoverColor})
+	if err != nil || len(images) != len(files) {
+		t.Fatalf("DecodeConcatenated with Recover: %d images, error %v\n", len(images), err)
+	}
+	if len(reports) != 1 || reports[1] == nil {
+		t.Fatalf("DecodeConcatenated with Recover: reports %v, expected one for image 1\n", reports)
+	}
+	if offset := int64(len(files[0]) + len(lost)); reports[1].Offset != offset {
+		t.Errorf("report %v, expected offset %d\n", reports[1], offset)
+	}
+
+	checkRecovered(t, expected[1], images[1].(*image.NRGBA), reports[1])
+	for _, i := range []int{0, 2} {
+		if !bytes.Equal(images[i].(*image.NRGBA).Pix, expected[i].Pix) {
+			t.Errorf("image %d differs from the reference\n", i)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package qoi
+
+import (
+	"fmt"
+	"image"
+)
+
+// rowSink stores decoded rows of NRGBA pixels in another representation
+// than *image.NRGBA, e.g. a more compact one which cannot hold every image.
+type rowSink interface {
+	// add stores the NRGBA pixels of the next row. It reports false and
+	// stores nothing if the representation cannot hold them.
+	add(row []byte) bool
+	// appendRow appends the NRGBA pixels of the stored row y to pix.
+	appendRow(pix []byte, y int) []byte
+	// image returns the image of the stored rows, which are all rows.
+	image(width, height int) image.Image
+}
+
+// decodeRows decodes the pixels row by row into the first of sinks which
+// can hold them. If a row does not fit, the rows stored so far move to
+// the next sink, and after the last one to an *image.NRGBA image, unless
+// d.strictPalette is set.
+func (d *decoder) decodeRows(sinks []rowSink) {
+	if d.err != nil {
+		return
+	}
+
+	row := make([]byte, 0, 4*d.h.width)
+
+	for y := 0; y < d.h.height; y++ {
+		row = d.decodeNRGBA(row[:0], 1)
+		if d.err != nil {
+			return
+		}
+
+		for !sinks[0].add(row) {
+			from := sinks[0]
+			if sinks = d.moveRows(from, sinks[1:], y); len(sinks) == 0 {
+				d.expandRows(from, row, y)
+				return
+			}
+		}
+	}
+
+	d.m = sinks[0].image(d.h.width, d.h.height)
+}
+
+// initialPixels returns the number of pixels to allocate before any data
+// is decoded: a crafted header must not make us allocate the whole image, so
+// the pixels are appended to buffers which grow with the decoded data.
+func (d *decoder) initialPixels() int {
+	capacity := d.h.width * d.h.height
+	if capacity > qoiInitialPixels {
+		capacity = qoiInitialPixels
+	}
+
+	return capacity
+}
+
+// moveRows stores the first rows of from in the first of sinks which can
+// hold them all and returns the sinks starting with it.
+func (d *decoder) moveRows(from rowSink, sinks []rowSink, rows int) []rowSink {
+	row := make([]byte, 0, 4*d.h.width)
+
+	for len(sinks) > 0 {
+		ok := true
+		for y := 0; y < rows && ok; y++ {
+			row = from.appendRow(row[:0], y)
+			ok = sinks[0].add(row)
+		}
+		if ok {
+			return sinks
+		}
+		sinks = sinks[1:]
+	}
+
+	return nil
+}
+
+// expandRows continues decoding as an *image.NRGBA image after the row y,
+// whose pixels are in row, did not fit into the last sink. The rows before it
+// are taken from from.
+func (d *decoder) expandRows(from rowSink, row []byte, y int) {
+	if d.strictPalette {
+		d.err = fmt.Errorf("%w: more than %d", ErrTooManyColors, d.maxPaletteColors())
+		return
+	}
+
+	capacity := 2 * (y + 1) * d.h.width
+	if initial := d.initialPixels(); capacity < initial {
+		capacity = initial
+	}
+	if total := d.h.width * d.h.height; capacity > total {
+		capacity = total
+	}
+
+	pix := make([]byte, 0, 4*capacity)
+	for i := 0; i < y; i++ {
+		pix = from.appendRow(pix, i)
+	}
+	pix = append(pix, row...)
+
+	pix = d.decodeNRGBA(pix, d.h.height-y-1)
+	if d.err != nil {
+		return
+	}
+
+	d.m = d.nrgba(pix)
+}
+
+// graySink stores opaque gray rows, whose pixels have equal red, green and
+// blue values and an alpha of 255, as *image.Gray pixels.
+type graySink struct {
+	width int
+	pix   []byte
+}
+
+func (s *graySink) add(row []byte) bool {
+	for i := 0; i < len(row); i += 4 {
+		p := row[i : i+4 : i+4]
+		if p[0] != p[1] || p[0] != p[2] || p[3] != 0xff {
+			return false
+		}
+	}
+
+	for i := 0; i < len(row); i += 4 {
+		s.pix = append(s.pix, row[i])
+	}
+
+	return true
+}
+
+func (s *graySink) appendRow(pix []byte, y int) []byte {
+	for _, v := range s.pix[y*s.width : (y+1)*s.width] {
+		pix = append(pix, v, v, v, 0xff)
+	}
+
+	return pix
+}
+
+func (s *graySink) image(width, height int) image.Image {
+	return &image.Gray{
+		Pix:    s.pix,
+		Stride: width,
+		Rect:   image.Rect(0, 0, width, height),
+	}
+}
+
+// nrgba64Sink stores rows as *image.NRGBA64 pixels, replicating every 8 bit
+// value v to the 16 bit value v<<8|v like color.NRGBA64Model does.
+type nrgba64Sink struct {
+	width int
+	pix   []byte
+}
+
+func (s *nrgba64Sink) add(row []byte) bool {
+	for _, v := range row {
+		s.pix = append(s.pix, v, v)
+	}
+
+	return true
+}
+
+func (s *nrgba64Sink) appendRow(pix []byte, y int) []byte {
+	row := s.pix[8*y*s.width : 8*(y+1)*s.width]
+	for i := 0; i < len(row); i += 2 {
+		pix = append(pix, row[i])
+	}
+
+	return pix
+}
+
+func (s *nrgba64Sink) image(width, height int) image.Image {
+	return &image.NRGBA64{
+		Pix:    s.pix,
+		Stride: 8 * width,
+		Rect:   image.Rect(0, 0, width, height),
+	}
+}
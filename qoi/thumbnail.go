@@ -0,0 +1,205 @@
+package qoi
+
+import (
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/LukiDS/image/imgconv"
+)
+
+// thumbnailScales are the scale denominators of DecodeThumbnail.
+var thumbnailScales = []int{1, 2, 4, 8}
+
+// DecodeThumbnail reads a QOI image from r and returns it scaled down to fit
+// into maxDim x maxDim pixels, preserving its aspect ratio like
+// imgconv.Thumbnail. Images which already fit are returned as decoded.
+//
+// The image is scaled by 1/2, 1/4 or 1/8 while it is decoded, averaging the
+// blocks of pixels, and then resized exactly with imgconv.ResizeBilinear. The
+// denominator is the largest one which still gives an image of at least the
+// thumbnail size, or a smaller one if the estimated memory of the decoding,
+// including the thumbnail, would exceed maxMem bytes otherwise. If even the
+// 1/8 scale exceeds maxMem, DecodeThumbnail fails after reading the header
+// with an error wrapping imgconv.ErrImageTooLarge.
+func DecodeThumbnail(r io.Reader, maxDim int, maxMem int64) (*image.NRGBA, error) {
+	if maxDim <= 0 || maxMem <= 0 {
+		return nil, fmt.Errorf("invalid thumbnail size %d or memory limit %d, must be positive", maxDim, maxMem)
+	}
+
+	d := &decoder{thumbnail: thumbnailLimits{maxDim: maxDim, maxMem: maxMem}}
+	m, err := decodeWith(r, d)
+	if err != nil {
+		return nil, err
+	}
+
+	//the size of the source image, since the scaled one is rounded up
+	w, h := d.thumbnailSize()
+	if m.Bounds().Dx() == w && m.Bounds().Dy() == h {
+		return imgconv.ToNRGBA(m), nil
+	}
+
+	return imgconv.ResizeBilinear(m, w, h)
+}
+
+// thumbnailLimits are the parameters of DecodeThumbnail.
+type thumbnailLimits struct {
+	maxDim int
+	maxMem int64
+}
+
+// thumbnailScale returns the scale denominator for decoding the image of d
+// as a thumbnail, and an error if every denominator exceeds the memory limit.
+func (d *decoder) thumbnailScale() (int, error) {
+	w, h := d.h.width, d.h.height
+	tw, th := d.thumbnailSize()
+
+	denom := 0
+	for _, s := range thumbnailScales {
+		sw, sh := (w+s-1)/s, (h+s-1)/s
+		if thumbnailMemory(w, sw, sh, tw, th, s) > d.thumbnail.maxMem {
+			continue
+		}
+		//scaling below the thumbnail size loses detail
+		if denom == 0 || sw >= tw && sh >= th {
+			denom = s
+		}
+	}
+
+	if denom == 0 {
+		s := thumbnailScales[len(thumbnailScales)-1]
+		return 0, fmt.Errorf("%w: %dx%d needs %d bytes at 1/%d scale, more than %d", imgconv.ErrImageTooLarge,
+			w, h, thumbnailMemory(w, (w+s-1)/s, (h+s-1)/s, tw, th, s), s, d.thumbnail.maxMem)
+	}
+
+	return denom, nil
+}
+
+// thumbnailSize returns the size of the thumbnail of the image of d.
+func (d *decoder) thumbnailSize() (int, int) {
+	w, h := d.h.width, d.h.height
+	if w <= d.thumbnail.maxDim && h <= d.thumbnail.maxDim {
+		return w, h
+	}
+
+	return imgconv.FitSize(w, h, d.thumbnail.maxDim, d.thumbnail.maxDim)
+}
+
+// decodeThumbnail decodes the image of d scaled down by the denominator of
+// thumbnailScale. The memory limit was checked, so the pixels are allocated
+// at once.
+func (d *decoder) decodeThumbnail() {
+	denom, err := d.thumbnailScale()
+	if err != nil {
+		d.err = err
+		return
+	}
+
+	if denom == 1 {
+		pix := d.decodeNRGBA(make([]byte, 0, 4*d.h.width*d.h.height), d.h.height)
+		if d.err != nil {
+			return
+		}
+		d.m = d.nrgba(pix)
+		return
+	}
+
+	d.decodeRows([]rowSink{newScaleSink(d.h.width, d.h.height, denom)})
+}
+
+// thumbnailMemory returns the estimated memory in bytes needed to decode an
+// image of width w at the scale 1/s into sw x sh pixels and to resize those
+// to tw x th pixels.
+func thumbnailMemory(w, sw, sh, tw, th, s int) int64 {
+	mem := 4*int64(sw)*int64(sh) + 4*int64(tw)*int64(th)
+	if s > 1 {
+		//the decoded row and the sums of the scaled row
+		mem += 4*int64(w) + 32*int64(sw)
+	}
+
+	return mem
+}
+
+// scaleSink stores rows scaled by 1/denom, averaging each block of
+// denom x denom pixels, or the part of it inside the image, premultiplied by
+// alpha like imgconv.ResizeBox.
+type scaleSink struct {
+	denom int
+	m     *image.NRGBA
+	// sums are the sums of the pixels of the current blocks, and rows the
+	// number of source rows added to them.
+	sums [][4]uint64
+	rows int
+	y    int
+	// width is the width of the source rows.
+	width int
+}
+
+func newScaleSink(width, height, denom int) *scaleSink {
+	sw, sh := (width+denom-1)/denom, (height+denom-1)/denom
+
+	return &scaleSink{
+		denom: denom,
+		m:     image.NewNRGBA(image.Rect(0, 0, sw, sh)),
+		sums:  make([][4]uint64, sw),
+		width: width,
+	}
+}
+
+func (s *scaleSink) add(row []byte) bool {
+	for x := 0; x < len(row)/4; x++ {
+		p := row[4*x : 4*x+4 : 4*x+4]
+		a := uint64(p[3])
+		sum := &s.sums[x/s.denom]
+		sum[0] += uint64(p[0]) * a
+		sum[1] += uint64(p[1]) * a
+		sum[2] += uint64(p[2]) * a
+		sum[3] += a
+	}
+
+	if s.rows++; s.rows == s.denom {
+		s.flush()
+	}
+
+	return true
+}
+
+// flush writes the averages of the current blocks to the next row of s.m.
+func (s *scaleSink) flush() {
+	di := s.m.PixOffset(0, s.y)
+	for x := range s.sums {
+		cols := s.denom
+		if rest := s.width - x*s.denom; rest < cols {
+			cols = rest
+		}
+		area := uint64(cols * s.rows)
+
+		sum := &s.sums[x]
+		d := s.m.Pix[di : di+4 : di+4]
+		if a := sum[3]; a > 0 {
+			d[0] = uint8((sum[0] + a/2) / a)
+			d[1] = uint8((sum[1] + a/2) / a)
+			d[2] = uint8((sum[2] + a/2) / a)
+			d[3] = uint8((a + area/2) / area)
+		}
+		*sum = [4]uint64{}
+		di += 4
+	}
+
+	s.rows = 0
+	s.y++
+}
+
+// appendRow is never called, since add accepts every row. The scaled rows
+// cannot be turned back into source rows.
+func (s *scaleSink) appendRow(pix []byte, y int) []byte {
+	panic("qoi: scaled rows cannot be read back")
+}
+
+func (s *scaleSink) image(width, height int) image.Image {
+	if s.rows > 0 {
+		s.flush()
+	}
+
+	return s.m
+}
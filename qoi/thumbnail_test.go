@@ -0,0 +1,138 @@
+package qoi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"path/filepath"
+	"testing"
+
+	"github.com/LukiDS/image/imgconv"
+)
+
+func TestDecodeThumbnail(t *testing.T) {
+	const maxDim = 64
+
+	for _, name := range testFiles(t) {
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			data := readTestFile(t, name)
+			full := decodeNRGBA(t, data)
+			w, h := full.Rect.Dx(), full.Rect.Dy()
+
+			m, err := DecodeThumbnail(bytes.NewReader(data), maxDim, 1<<30)
+			if err != nil {
+				t.Fatalf("could not decode thumbnail: %v\n", err)
+			}
+
+			if w <= maxDim && h <= maxDim {
+				if m.Rect != full.Rect || !bytes.Equal(m.Pix, full.Pix) {
+					t.Errorf("DecodeThumbnail(%s) of a fitting image differs from Decode\n", name)
+				}
+				return
+			}
+
+			ew, eh := imgconv.FitSize(w, h, maxDim, maxDim)
+			if m.Rect.Dx() != ew || m.Rect.Dy() != eh {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Source size:\t %dx%d\n", w, h) +
+					fmt.Sprintf("Expected size:\t %dx%d\n", ew, eh) +
+					fmt.Sprintf("Actual size:\t %dx%d\n", m.Rect.Dx(), m.Rect.Dy())
+
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+func TestDecodeThumbnailMemory(t *testing.T) {
+	data := readTestFile(t, "../testdata/kodim23.qoi")
+	full := decodeNRGBA(t, data)
+	w, h := full.Rect.Dx(), full.Rect.Dy()
+	tw, th := imgconv.FitSize(w, h, 32, 32)
+
+	for _, s := range thumbnailScales {
+		mem := thumbnailMemory(w, (w+s-1)/s, (h+s-1)/s, tw, th, s)
+
+		d := &decoder{h: qoiHeader{width: w, height: h}, thumbnail: thumbnailLimits{maxDim: 32, maxMem: mem}}
+		denom, err := d.thumbnailScale()
+		if err != nil || denom < s {
+			t.Errorf("thumbnailScale with the memory of scale 1/%d: denominator %d, error %v\n", s, denom, err)
+		}
+
+		if _, err := DecodeThumbnail(bytes.NewReader(data), 32, mem); err != nil {
+			t.Errorf("DecodeThumbnail with the memory of scale 1/%d: %v\n", s, err)
+		}
+	}
+
+	//at most the thumbnail size is decoded if memory allows it
+	d := &decoder{h: qoiHeader{width: w, height: h}, thumbnail: thumbnailLimits{maxDim: w / 3, maxMem: 1 << 30}}
+	if denom, err := d.thumbnailScale(); err != nil || denom != 2 {
+		t.Errorf("thumbnailScale for a third of the size: denominator %d, error %v, expected 2\n", denom, err)
+	}
+
+	s := thumbnailScales[len(thumbnailScales)-1]
+	mem := thumbnailMemory(w, (w+s-1)/s, (h+s-1)/s, tw, th, s) - 1
+	if _, err := DecodeThumbnail(bytes.NewReader(data), 32, mem); !errors.Is(err, imgconv.ErrImageTooLarge) {
+		t.Errorf("DecodeThumbnail over the memory limit: error %v, expected imgconv.ErrImageTooLarge\n", err)
+	}
+
+	//the limit is checked before the pixels are read
+	if _, err := DecodeThumbnail(bytes.NewReader(data[:qoiHeaderSize]), 32, mem); !errors.Is(err, imgconv.ErrImageTooLarge) {
+		t.Errorf("DecodeThumbnail of a header over the memory limit: error %v, expected imgconv.ErrImageTooLarge\n", err)
+	}
+}
+
+func TestDecodeThumbnailErrors(t *testing.T) {
+	data := readTestFile(t, "../testdata/kodim23.qoi")
+
+	testCases := []struct {
+		name   string
+		data   []byte
+		maxDim int
+		maxMem int64
+	}{
+		{name: "zero size", data: data, maxDim: 0, maxMem: 1 << 30},
+		{name: "negative memory", data: data, maxDim: 32, maxMem: -1},
+		{name: "truncated", data: data[:len(data)/2], maxDim: 32, maxMem: 1 << 30},
+		{name: "invalid", data: []byte("qoif"), maxDim: 32, maxMem: 1 << 30},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if m, err := DecodeThumbnail(bytes.NewReader(tc.data), tc.maxDim, tc.maxMem); err == nil {
+				t.Errorf("DecodeThumbnail: image %v, expected an error\n", m.Rect)
+			}
+		})
+	}
+}
+
+func TestScaleSink(t *testing.T) {
+	//a 3x3 image, scaled by 1/2 into 2x2 blocks of 4, 2, 2 and 1 pixels
+	m := image.NewNRGBA(image.Rect(0, 0, 3, 3))
+	m.SetNRGBA(0, 0, color.NRGBA{200, 0, 0, 255})
+	m.SetNRGBA(1, 0, color.NRGBA{100, 0, 0, 255})
+	m.SetNRGBA(0, 1, color.NRGBA{0, 40, 0, 255})
+	m.SetNRGBA(1, 1, color.NRGBA{0, 0, 0, 0})
+	m.SetNRGBA(2, 0, color.NRGBA{0, 0, 90, 255})
+	m.SetNRGBA(2, 1, color.NRGBA{0, 0, 30, 255})
+	m.SetNRGBA(0, 2, color.NRGBA{10, 20, 30, 128})
+	m.SetNRGBA(1, 2, color.NRGBA{10, 20, 30, 0})
+
+	s := newScaleSink(3, 3, 2)
+	for y := 0; y < 3; y++ {
+		s.add(m.Pix[y*m.Stride : (y+1)*m.Stride])
+	}
+	got := s.image(3, 3).(*image.NRGBA)
+
+	expected := []color.NRGBA{
+		{100, 13, 0, 191}, {0, 0, 60, 255},
+		{10, 20, 30, 64}, {0, 0, 0, 0},
+	}
+	for i, c := range expected {
+		if actual := got.NRGBAAt(i%2, i/2); actual != c {
+			t.Errorf("pixel %d,%d: %v, expected %v\n", i%2, i/2, actual, c)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package qoi
+
+import (
+	"fmt"
+	"image/png"
+	"io"
+
+	"github.com/LukiDS/image/imgconv"
+)
+
+// FromPNG reads a PNG image from r and writes it to w in QOI format.
+func FromPNG(w io.Writer, r io.Reader) error {
+	m, err := png.Decode(r)
+	if err != nil {
+		return fmt.Errorf("png decode: %w", err)
+	}
+
+	err = Encode(w, imgconv.ToNRGBA(m))
+	if err != nil {
+		return fmt.Errorf("qoi encode: %w", err)
+	}
+
+	return nil
+}
+
+// ToPNG reads a QOI image from r and writes it to w in PNG format.
+func ToPNG(w io.Writer, r io.Reader) error {
+	m, err := Decode(r)
+	if err != nil {
+		return fmt.Errorf("qoi decode: %w", err)
+	}
+
+	err = png.Encode(w, m)
+	if err != nil {
+		return fmt.Errorf("png encode: %w", err)
+	}
+
+	return nil
+}
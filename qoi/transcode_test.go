@@ -0,0 +1,127 @@
+package qoi
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/LukiDS/image/imgconv"
+	"github.com/LukiDS/image/imgtest"
+)
+
+func TestFromPNGWithTestFiles(t *testing.T) {
+	filenames, err := filepath.Glob("../testdata/*.png")
+	if err != nil {
+		t.Fatalf("could not find files: %v\n", err)
+	}
+
+	for _, name := range filenames {
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			pngData, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatalf("could not read file: %v\n", err)
+			}
+
+			encoded := bytes.NewBuffer(nil)
+			err = FromPNG(encoded, bytes.NewReader(pngData))
+			if err != nil {
+				t.Fatalf("could not transcode file: %v\n", err)
+			}
+
+			img, err := Decode(encoded)
+			if err != nil {
+				t.Fatalf("could not decode file: %v\n", err)
+			}
+
+			ref, err := png.Decode(bytes.NewReader(pngData))
+			if err != nil {
+				t.Fatalf("could not decode file: %v\n", err)
+			}
+
+			if ref.ColorModel() != color.NRGBAModel {
+				ref = imgconv.ToNRGBA(ref)
+			}
+
+			imgtest.AssertEqual(t, ref, img)
+		})
+	}
+}
+
+func TestToPNGWithTestFiles(t *testing.T) {
+	filenames := testFiles(t)
+
+	for _, name := range filenames {
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			qoiFile, err := os.Open(name)
+			if err != nil {
+				t.Fatalf("could not read file: %v\n", err)
+			}
+			defer qoiFile.Close()
+
+			encoded := bytes.NewBuffer(nil)
+			err = ToPNG(encoded, bufio.NewReader(qoiFile))
+			if err != nil {
+				t.Fatalf("could not transcode file: %v\n", err)
+			}
+
+			img, err := png.Decode(encoded)
+			if err != nil {
+				t.Fatalf("could not decode file: %v\n", err)
+			}
+
+			pngFile, err := os.Open(strings.TrimSuffix(name, filepath.Ext(name)) + ".png")
+			if err != nil {
+				t.Fatalf("could not read file: %v\n", err)
+			}
+			defer pngFile.Close()
+
+			ref, err := png.Decode(bufio.NewReader(pngFile))
+			if err != nil {
+				t.Fatalf("could not decode file: %v\n", err)
+			}
+
+			imgtest.AssertEqual(t, ref, img)
+		})
+	}
+}
+
+func TestTranscodeErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		transcode func() error
+		stage     string
+	}{
+		{
+			name: "should return a png decode error for invalid png data",
+			transcode: func() error {
+				return FromPNG(bytes.NewBuffer(nil), bytes.NewBufferString("not a png"))
+			},
+			stage: "png decode",
+		},
+		{
+			name: "should return a qoi decode error for invalid qoi data",
+			transcode: func() error {
+				return ToPNG(bytes.NewBuffer(nil), bytes.NewBufferString("not a qoi file"))
+			},
+			stage: "qoi decode",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.transcode()
+			if err == nil || !strings.HasPrefix(err.Error(), test.stage) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Expected stage:\t %s\n", test.stage) +
+					fmt.Sprintf("Actual error:\t %v\n", err)
+				t.Errorf(format)
+			}
+		})
+	}
+}
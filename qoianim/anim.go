@@ -0,0 +1,160 @@
+// Package qoianim implements a simple animation container that stores a
+// sequence of QOI encoded frames.
+//
+// A file starts with a 16 byte header (magic, width, height and frame count,
+// all big endian uint32 values after the magic), followed by every frame as
+// [delay in milliseconds][size of the QOI data][QOI data].
+package qoianim
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"time"
+
+	"github.com/LukiDS/image/qoi"
+)
+
+const (
+	animMagic       = "qoia"
+	animHeaderSize  = 16 //size in bytes
+	frameHeaderSize = 8  //size in bytes
+
+	//worst case per pixel: op--r--g--b--a
+	maxBytesPerPixel = 5
+	qoiOverhead      = 14 + 8
+
+	//same limit as the qoi package
+	maxPixels = 400_000_000
+)
+
+// EncodeAll writes the frames to w in the qoianim format. Every frame is
+// shown for the duration with the same index in delays. All frames must
+// share the same dimensions.
+func EncodeAll(w io.Writer, frames []image.Image, delays []time.Duration) error {
+	if len(frames) == 0 {
+		return fmt.Errorf("no frames to encode")
+	}
+
+	if len(frames) != len(delays) {
+		return fmt.Errorf("frame and delay count differ")
+	}
+
+	size := frames[0].Bounds().Size()
+	for idx, frame := range frames {
+		if frame.Bounds().Size() != size {
+			return fmt.Errorf("frame %d: size %v differs from %v", idx, frame.Bounds().Size(), size)
+		}
+		if delays[idx] < 0 || delays[idx].Milliseconds() > int64(^uint32(0)) {
+			return fmt.Errorf("frame %d: delay out of range", idx)
+		}
+	}
+
+	header := make([]byte, 0, animHeaderSize)
+	header = append(header, animMagic...)
+	header = appendUint32(header, uint32(size.X))
+	header = appendUint32(header, uint32(size.Y))
+	header = appendUint32(header, uint32(len(frames)))
+
+	_, err := w.Write(header)
+	if err != nil {
+		return err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	for idx, frame := range frames {
+		buf.Reset()
+
+		err := qoi.Encode(buf, frame)
+		if err != nil {
+			return fmt.Errorf("frame %d: %w", idx, err)
+		}
+
+		frameHeader := make([]byte, 0, frameHeaderSize)
+		frameHeader = appendUint32(frameHeader, uint32(delays[idx].Milliseconds()))
+		frameHeader = appendUint32(frameHeader, uint32(buf.Len()))
+
+		_, err = w.Write(frameHeader)
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(buf.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DecodeAll reads a qoianim animation from r and returns its frames and
+// their delays.
+//
+// Decoding is lenient: if a frame is corrupt, all frames before it are
+// returned together with the error.
+func DecodeAll(r io.Reader) ([]image.Image, []time.Duration, error) {
+	header := make([]byte, animHeaderSize)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !bytes.Equal(header[:4], []byte(animMagic)) {
+		return nil, nil, fmt.Errorf("image not valid qoianim file")
+	}
+
+	width := int(binary.BigEndian.Uint32(header[4:8]))
+	height := int(binary.BigEndian.Uint32(header[8:12]))
+	count := int(binary.BigEndian.Uint32(header[12:16]))
+
+	if width <= 0 || height <= 0 || count <= 0 || uint64(width)*uint64(height) > maxPixels {
+		return nil, nil, fmt.Errorf("animation size invalid")
+	}
+
+	maxFrameSize := uint64(width)*uint64(height)*maxBytesPerPixel + qoiOverhead
+
+	frames := make([]image.Image, 0)
+	delays := make([]time.Duration, 0)
+	frameHeader := make([]byte, frameHeaderSize)
+	for idx := 0; idx < count; idx++ {
+		_, err := io.ReadFull(r, frameHeader)
+		if err != nil {
+			return frames, delays, fmt.Errorf("frame %d: %w", idx, err)
+		}
+
+		delay := time.Duration(binary.BigEndian.Uint32(frameHeader[0:4])) * time.Millisecond
+		size := uint64(binary.BigEndian.Uint32(frameHeader[4:8]))
+		if size > maxFrameSize {
+			return frames, delays, fmt.Errorf("frame %d: invalid frame size", idx)
+		}
+
+		//the frame is decoded while it is read, so a crafted size cannot
+		//allocate more memory than the data present
+		data := &io.LimitedReader{R: r, N: int64(size)}
+		frame, err := qoi.Decode(data)
+		if err != nil {
+			return frames, delays, fmt.Errorf("frame %d: %w", idx, err)
+		}
+
+		//qoi.Decode reads up to EOF, which is early if r ends within the frame
+		if data.N > 0 {
+			return frames, delays, fmt.Errorf("frame %d: %w", idx, io.ErrUnexpectedEOF)
+		}
+
+		if frame.Bounds().Dx() != width || frame.Bounds().Dy() != height {
+			return frames, delays, fmt.Errorf("frame %d: size differs from animation size", idx)
+		}
+
+		frames = append(frames, frame)
+		delays = append(delays, delay)
+	}
+
+	return frames, delays, nil
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
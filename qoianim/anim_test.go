@@ -0,0 +1,213 @@
+package qoianim
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/LukiDS/image/imgtest"
+)
+
+func TestEncodeAllDecodeAll(t *testing.T) {
+	frames := []image.Image{
+		generateFrameStub(t, 3, 2, color.NRGBA{255, 0, 0, 255}),
+		generateFrameStub(t, 3, 2, color.NRGBA{0, 255, 0, 128}),
+		generateFrameStub(t, 3, 2, color.NRGBA{0, 0, 255, 0}),
+	}
+	delays := []time.Duration{100 * time.Millisecond, 0, 2 * time.Second}
+
+	buf := bytes.NewBuffer(nil)
+	err := EncodeAll(buf, frames, delays)
+	if err != nil {
+		t.Fatalf("could not encode animation: %v\n", err)
+	}
+
+	actualFrames, actualDelays, err := DecodeAll(buf)
+	if err != nil {
+		t.Fatalf("could not decode animation: %v\n", err)
+	}
+
+	if len(actualFrames) != len(frames) || len(actualDelays) != len(delays) {
+		t.Fatalf("expected %d frames, got %d frames and %d delays\n", len(frames), len(actualFrames), len(actualDelays))
+	}
+
+	for idx := range frames {
+		if actualDelays[idx] != delays[idx] {
+			t.Errorf("frame %d: expected delay %v, got %v\n", idx, delays[idx], actualDelays[idx])
+		}
+
+		imgtest.AssertEqual(t, frames[idx], actualFrames[idx])
+	}
+}
+
+func TestEncodeAll(t *testing.T) {
+	tests := []struct {
+		name   string
+		frames []image.Image
+		delays []time.Duration
+	}{
+		{
+			name:   "should return an error if there are no frames",
+			frames: []image.Image{},
+			delays: []time.Duration{},
+		},
+		{
+			name: "should return an error if frame and delay count differ",
+			frames: []image.Image{
+				generateFrameStub(t, 1, 1, color.NRGBA{}),
+			},
+			delays: []time.Duration{0, 0},
+		},
+		{
+			name: "should return an error if frame sizes differ",
+			frames: []image.Image{
+				generateFrameStub(t, 2, 2, color.NRGBA{}),
+				generateFrameStub(t, 2, 3, color.NRGBA{}),
+			},
+			delays: []time.Duration{0, 0},
+		},
+		{
+			name: "should return an error if a delay is negative",
+			frames: []image.Image{
+				generateFrameStub(t, 1, 1, color.NRGBA{}),
+			},
+			delays: []time.Duration{-time.Second},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := EncodeAll(bytes.NewBuffer(nil), test.frames, test.delays)
+			if err == nil {
+				t.Errorf("expected an error, got nil\n")
+			}
+		})
+	}
+}
+
+func TestDecodeAllRecoversFrames(t *testing.T) {
+	frames := []image.Image{
+		generateFrameStub(t, 2, 2, color.NRGBA{10, 20, 30, 255}),
+		generateFrameStub(t, 2, 2, color.NRGBA{40, 50, 60, 255}),
+	}
+	delays := []time.Duration{time.Millisecond, time.Millisecond}
+
+	buf := bytes.NewBuffer(nil)
+	err := EncodeAll(buf, frames, delays)
+	if err != nil {
+		t.Fatalf("could not encode animation: %v\n", err)
+	}
+
+	//cut off the end of the last frame
+	data := buf.Bytes()[:buf.Len()-4]
+
+	actualFrames, actualDelays, err := DecodeAll(bytes.NewReader(data))
+	if err == nil {
+		t.Fatalf("expected an error for a truncated frame\n")
+	}
+
+	if len(actualFrames) != 1 || len(actualDelays) != 1 {
+		t.Fatalf("expected 1 recovered frame, got %d frames and %d delays\n", len(actualFrames), len(actualDelays))
+	}
+
+	imgtest.AssertEqual(t, frames[0], actualFrames[0])
+}
+
+func TestDecodeAll(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{
+			name: "should return an error if the header is truncated",
+			data: []byte{'q', 'o', 'i', 'a', 0, 0},
+		},
+		{
+			name: "should return an error if the magic is wrong",
+			data: []byte{'q', 'o', 'i', 'f', 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1},
+		},
+		{
+			name: "should return an error if the frame count is zero",
+			data: []byte{'q', 'o', 'i', 'a', 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 0},
+		},
+		{
+			name: "should return an error if a frame size is too large",
+			data: []byte{'q', 'o', 'i', 'a', 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 0, 255, 255, 255, 255},
+		},
+		{
+			name: "should return an error if the frame data is shorter than its size",
+			data: []byte{'q', 'o', 'i', 'a', 0, 0, 78, 32, 0, 0, 78, 32, 0, 0, 0, 1, 0, 0, 0, 0, 255, 255, 255, 240},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			frames, _, err := DecodeAll(bytes.NewReader(test.data))
+			if err == nil {
+				t.Errorf("expected an error, got nil\n")
+			}
+			if len(frames) != 0 {
+				t.Errorf("expected no frames, got %d\n", len(frames))
+			}
+		})
+	}
+}
+
+func TestDecodeAllLargeFrameSize(t *testing.T) {
+	//a 20000x20000 animation with a frame of 4GB, but no frame data
+	data := []byte{'q', 'o', 'i', 'a', 0, 0, 78, 32, 0, 0, 78, 32, 0, 0, 0, 1, 0, 0, 0, 0, 255, 255, 255, 240}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, _, err := DecodeAll(bytes.NewReader(data))
+	runtime.ReadMemStats(&after)
+
+	if err == nil {
+		t.Errorf("expected an error for the missing frame data\n")
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 64<<20 {
+		t.Errorf("DecodeAll allocated %d bytes for a %d byte input\n", allocated, len(data))
+	}
+}
+
+func TestDecodeAllFrameSize(t *testing.T) {
+	frames := []image.Image{generateFrameStub(t, 2, 2, color.NRGBA{10, 20, 30, 255})}
+	buf := bytes.NewBuffer(nil)
+	err := EncodeAll(buf, frames, []time.Duration{time.Millisecond})
+	if err != nil {
+		t.Fatalf("could not encode animation: %v\n", err)
+	}
+
+	//the frame size claims 3 bytes more than the file holds
+	data := buf.Bytes()
+	size := animHeaderSize + 4
+	binary.BigEndian.PutUint32(data[size:], binary.BigEndian.Uint32(data[size:])+3)
+
+	actualFrames, _, err := DecodeAll(bytes.NewReader(data))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("DecodeAll(truncated frame): %v, expected io.ErrUnexpectedEOF\n", err)
+	}
+	if len(actualFrames) != 0 {
+		t.Errorf("expected no frames, got %d\n", len(actualFrames))
+	}
+}
+
+func generateFrameStub(t testing.TB, width, height int, c color.NRGBA) image.Image {
+	t.Helper()
+
+	m := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			m.SetNRGBA(x, y, c)
+		}
+	}
+	m.SetNRGBA(0, 0, color.NRGBA{c.R ^ 0xFF, c.G, c.B, 255})
+
+	return m
+}
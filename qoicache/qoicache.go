@@ -0,0 +1,223 @@
+// Package qoicache caches decoded QOI images in memory, for programs which
+// decode the same files again and again, like editors reopening textures.
+//
+// A Cache holds the most recently used images within a byte budget. The
+// images it returns are shared by all callers and must not be modified,
+// unless the cache is created with Options.Copy.
+package qoicache
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"io"
+	"sync"
+
+	"github.com/LukiDS/image/qoi"
+)
+
+// DefaultMaxBytes is the byte budget of a Cache if Options.MaxBytes is zero.
+const DefaultMaxBytes = 256 << 20
+
+// Options are the parameters of a Cache.
+type Options struct {
+	// MaxBytes is the budget of the pixel memory of the cached images. The
+	// least recently used images are evicted to stay within it, and images
+	// larger than the budget are not cached at all. Zero means
+	// DefaultMaxBytes.
+	MaxBytes int64
+
+	// Copy makes every call return a copy of the cached image, which the
+	// caller may modify. Without it, the returned images are shared.
+	Copy bool
+}
+
+// Stats are the counters of a Cache.
+type Stats struct {
+	// Hits are the calls served from the cache, including calls which
+	// waited for another call decoding the same key.
+	Hits uint64
+	// Misses are the calls which decoded an image.
+	Misses uint64
+	// Evictions are the images removed to stay within the budget.
+	Evictions uint64
+	// Images and Bytes are the number and the pixel memory of the cached
+	// images.
+	Images int
+	Bytes  int64
+}
+
+// A Cache is an LRU cache of decoded QOI images, keyed by strings chosen by
+// the caller, e.g. file names, or by the content of the files. It is safe for
+// concurrent use; concurrent calls for the same missing key decode it once.
+type Cache struct {
+	opts Options
+
+	mu      sync.Mutex
+	lru     *list.List //of *entry, the most recently used first
+	entries map[string]*list.Element
+	pending map[string]*call
+	stats   Stats
+}
+
+type entry struct {
+	key  string
+	m    *image.NRGBA
+	size int64
+}
+
+// call is a decoding in progress, whose result is set before done is closed.
+type call struct {
+	done chan struct{}
+	m    *image.NRGBA
+	err  error
+}
+
+// New returns an empty Cache.
+func New(opts Options) *Cache {
+	if opts.MaxBytes == 0 {
+		opts.MaxBytes = DefaultMaxBytes
+	}
+
+	return &Cache{
+		opts:    opts,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+		pending: make(map[string]*call),
+	}
+}
+
+// Get returns the image cached for key. If there is none, it decodes the
+// image from the reader returned by open and caches it; a reader which is
+// also an io.Closer is closed afterwards. Errors are not cached.
+func (c *Cache) Get(key string, open func() (io.Reader, error)) (image.Image, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(e)
+		c.stats.Hits++
+		m := e.Value.(*entry).m
+		c.mu.Unlock()
+		return c.result(m), nil
+	}
+	if p, ok := c.pending[key]; ok {
+		c.stats.Hits++
+		c.mu.Unlock()
+		<-p.done
+		if p.err != nil {
+			return nil, p.err
+		}
+		return c.result(p.m), nil
+	}
+
+	p := &call{done: make(chan struct{})}
+	c.pending[key] = p
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	p.m, p.err = decode(open)
+
+	c.mu.Lock()
+	delete(c.pending, key)
+	if p.err == nil {
+		c.add(key, p.m)
+	}
+	c.mu.Unlock()
+	close(p.done)
+
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	return c.result(p.m), nil
+}
+
+// Decode returns the image of the QOI file data, keyed by its content: the
+// image is decoded only once for all equal files.
+func (c *Cache) Decode(data []byte) (image.Image, error) {
+	sum := sha256.Sum256(data)
+
+	return c.Get(hex.EncodeToString(sum[:]), func() (io.Reader, error) {
+		return bytes.NewReader(data), nil
+	})
+}
+
+// Remove removes the image cached for key, if any.
+func (c *Cache) Remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+}
+
+// Stats returns the current counters of c.
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.stats
+}
+
+// decode decodes the image of the reader returned by open.
+func decode(open func() (io.Reader, error)) (*image.NRGBA, error) {
+	r, err := open()
+	if err != nil {
+		return nil, err
+	}
+	if closer, ok := r.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	m, err := qoi.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.(*image.NRGBA), nil
+}
+
+// add caches m for key, evicting the least recently used images to stay
+// within the budget. c.mu must be held.
+func (c *Cache) add(key string, m *image.NRGBA) {
+	size := int64(len(m.Pix))
+	if size > c.opts.MaxBytes {
+		return
+	}
+	if e, ok := c.entries[key]; ok {
+		//the key was removed and added again while m was decoded
+		c.remove(e)
+	}
+
+	for c.stats.Bytes+size > c.opts.MaxBytes {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+
+	c.entries[key] = c.lru.PushFront(&entry{key: key, m: m, size: size})
+	c.stats.Images++
+	c.stats.Bytes += size
+}
+
+// remove removes the cached image e. c.mu must be held.
+func (c *Cache) remove(e *list.Element) {
+	en := c.lru.Remove(e).(*entry)
+	delete(c.entries, en.key)
+	c.stats.Images--
+	c.stats.Bytes -= en.size
+}
+
+// result returns m, or a copy of it if c.opts.Copy is set.
+func (c *Cache) result(m *image.NRGBA) image.Image {
+	if !c.opts.Copy {
+		return m
+	}
+
+	return &image.NRGBA{
+		Pix:    append([]byte(nil), m.Pix...),
+		Stride: m.Stride,
+		Rect:   m.Rect,
+	}
+}
@@ -0,0 +1,241 @@
+package qoicache
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/LukiDS/image/imgtest"
+	"github.com/LukiDS/image/qoi"
+	"github.com/LukiDS/image/testimg"
+)
+
+// encoded returns a QOI file of a w x h image.
+func encoded(t testing.TB, w, h int) []byte {
+	var buf bytes.Buffer
+	if err := qoi.Encode(&buf, testimg.Gradient(w, h)); err != nil {
+		t.Fatalf("could not encode image: %v\n", err)
+	}
+
+	return buf.Bytes()
+}
+
+// opener returns an open function for data, which counts its calls in n.
+func opener(data []byte, n *int64) func() (io.Reader, error) {
+	return func() (io.Reader, error) {
+		atomic.AddInt64(n, 1)
+		return bytes.NewReader(data), nil
+	}
+}
+
+func TestGet(t *testing.T) {
+	data := encoded(t, 10, 10)
+	c := New(Options{})
+
+	var opened int64
+	for i := 0; i < 3; i++ {
+		m, err := c.Get("a", opener(data, &opened))
+		if err != nil {
+			t.Fatalf("Get: unexpected error: %v\n", err)
+		}
+		imgtest.AssertEqual(t, testimg.Gradient(10, 10), m)
+	}
+
+	if opened != 1 {
+		t.Errorf("Get opened the file %d times, expected once\n", opened)
+	}
+	expected := Stats{Hits: 2, Misses: 1, Images: 1, Bytes: 400}
+	if s := c.Stats(); s != expected {
+		t.Errorf("\nExpected stats:\t %+v\nActual stats:\t %+v\n", expected, s)
+	}
+}
+
+func TestGetErrors(t *testing.T) {
+	c := New(Options{})
+	errOpen := errors.New("open failed")
+
+	if _, err := c.Get("a", func() (io.Reader, error) { return nil, errOpen }); !errors.Is(err, errOpen) {
+		t.Errorf("Get with failing open: error %v, expected %v\n", err, errOpen)
+	}
+	if _, err := c.Get("a", opener([]byte("qoif"), new(int64))); err == nil {
+		t.Errorf("Get of an invalid file: expected an error\n")
+	}
+
+	//errors are not cached
+	var opened int64
+	if _, err := c.Get("a", opener(encoded(t, 2, 2), &opened)); err != nil || opened != 1 {
+		t.Errorf("Get after errors = %v, opened %d times, expected a decoded image\n", err, opened)
+	}
+	expected := Stats{Misses: 3, Images: 1, Bytes: 16}
+	if s := c.Stats(); s != expected {
+		t.Errorf("\nExpected stats:\t %+v\nActual stats:\t %+v\n", expected, s)
+	}
+}
+
+func TestGetClosesReader(t *testing.T) {
+	f, err := os.Open("../testdata/qoi_logo.qoi")
+	if err != nil {
+		t.Fatalf("could not open file: %v\n", err)
+	}
+
+	if _, err := New(Options{}).Get("logo", func() (io.Reader, error) { return f, nil }); err != nil {
+		t.Fatalf("Get: unexpected error: %v\n", err)
+	}
+	if err := f.Close(); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Get did not close the file\n")
+	}
+}
+
+func TestEviction(t *testing.T) {
+	//every image needs 400 bytes, the budget holds three of them
+	data := encoded(t, 10, 10)
+	c := New(Options{MaxBytes: 1200})
+
+	get := func(key string) {
+		if _, err := c.Get(key, opener(data, new(int64))); err != nil {
+			t.Fatalf("Get(%s): unexpected error: %v\n", key, err)
+		}
+	}
+
+	get("a")
+	get("b")
+	get("c")
+	get("a") //b is now the least recently used image
+	get("d")
+
+	var cached []string
+	for e := c.lru.Front(); e != nil; e = e.Next() {
+		cached = append(cached, e.Value.(*entry).key)
+	}
+	if fmt.Sprint(cached) != "[d a c]" {
+		t.Errorf("cached images %v, expected [d a c]\n", cached)
+	}
+
+	expected := Stats{Hits: 1, Misses: 4, Evictions: 1, Images: 3, Bytes: 1200}
+	if s := c.Stats(); s != expected {
+		t.Errorf("\nExpected stats:\t %+v\nActual stats:\t %+v\n", expected, s)
+	}
+
+	//an image beyond the budget is returned, but not cached
+	m, err := c.Get("large", opener(encoded(t, 20, 20), new(int64)))
+	if err != nil || m.Bounds().Dx() != 20 {
+		t.Fatalf("Get(large) = %v, %v, expected the image\n", m, err)
+	}
+	c.Remove("a")
+	expected = Stats{Hits: 1, Misses: 5, Evictions: 1, Images: 2, Bytes: 800}
+	if s := c.Stats(); s != expected {
+		t.Errorf("\nExpected stats:\t %+v\nActual stats:\t %+v\n", expected, s)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	data := encoded(t, 4, 4)
+
+	for _, copied := range []bool{false, true} {
+		c := New(Options{Copy: copied})
+		a, _ := c.Decode(data)
+		b, _ := c.Decode(data)
+
+		a.(*image.NRGBA).Pix[0] = 0xab
+		if shared := b.(*image.NRGBA).Pix[0] == 0xab; shared == copied {
+			t.Errorf("Copy %v: images shared: %v\n", copied, shared)
+		}
+		if s := c.Stats(); s.Hits != 1 || s.Misses != 1 {
+			t.Errorf("Copy %v: stats %+v, expected one hit and one miss\n", copied, s)
+		}
+	}
+}
+
+func TestConcurrentGet(t *testing.T) {
+	files := [][]byte{encoded(t, 10, 10), encoded(t, 10, 11), encoded(t, 10, 12), encoded(t, 10, 13)}
+	c := New(Options{MaxBytes: 3 * 500})
+
+	var wg sync.WaitGroup
+	var opened int64
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				n := (g + i) % len(files)
+				m, err := c.Get(fmt.Sprint(n), opener(files[n], &opened))
+				if err != nil {
+					t.Errorf("Get: unexpected error: %v\n", err)
+					return
+				}
+				if h := m.Bounds().Dy(); h != 10+n {
+					t.Errorf("Get(%d) returned an image of height %d\n", n, h)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	s := c.Stats()
+	if s.Hits+s.Misses != 8*200 || s.Misses != uint64(opened) || s.Bytes > 1500 {
+		t.Errorf("stats %+v after %d opened files, expected 1600 calls within the budget\n", s, opened)
+	}
+}
+
+func TestConcurrentMissDecodesOnce(t *testing.T) {
+	data := encoded(t, 10, 10)
+	c := New(Options{})
+
+	release := make(chan struct{})
+	var opened int64
+	open := func() (io.Reader, error) {
+		atomic.AddInt64(&opened, 1)
+		<-release
+		return bytes.NewReader(data), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Get("a", open); err != nil {
+				t.Errorf("Get: unexpected error: %v\n", err)
+			}
+		}()
+	}
+
+	//wait until all calls are waiting for the first one
+	for {
+		c.mu.Lock()
+		s := c.stats
+		c.mu.Unlock()
+		if s.Hits+s.Misses == 4 {
+			break
+		}
+		runtime.Gosched()
+	}
+	close(release)
+	wg.Wait()
+
+	if s := c.Stats(); opened != 1 || s.Misses != 1 || s.Hits != 3 {
+		t.Errorf("concurrent misses opened the file %d times, stats %+v, expected once\n", opened, s)
+	}
+}
+
+func BenchmarkGetHit(b *testing.B) {
+	data := encoded(b, 64, 64)
+	c := New(Options{})
+	open := opener(data, new(int64))
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := c.Get("a", open); err != nil {
+				b.Fatalf("Get: unexpected error: %v\n", err)
+			}
+		}
+	})
+}
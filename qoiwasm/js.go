@@ -0,0 +1,121 @@
+//go:build js && wasm
+
+package qoiwasm
+
+import (
+	"fmt"
+	"syscall/js"
+)
+
+// DecodeToImageData decodes the QOI image in v, a Uint8Array or an
+// ArrayBuffer, into an ImageData. Outside of browsers, where the ImageData
+// constructor is missing (e.g. in Node.js), it returns a plain object with the
+// same data, width and height properties.
+func DecodeToImageData(v js.Value) (js.Value, error) {
+	data, err := bytesFromJS(v)
+	if err != nil {
+		return js.Undefined(), err
+	}
+
+	pix, width, height, err := DecodeRGBA(data)
+	if err != nil {
+		return js.Undefined(), err
+	}
+
+	array := js.Global().Get("Uint8ClampedArray").New(len(pix))
+	js.CopyBytesToJS(array, pix)
+
+	if ctor := js.Global().Get("ImageData"); ctor.Type() == js.TypeFunction {
+		return ctor.New(array, width, height), nil
+	}
+
+	return js.ValueOf(map[string]interface{}{
+		"data":   array,
+		"width":  width,
+		"height": height,
+	}), nil
+}
+
+// EncodeFromImageData encodes v, an ImageData or any object with data, width
+// and height properties, as a QOI image and returns it as a Uint8Array.
+func EncodeFromImageData(v js.Value) (js.Value, error) {
+	if v.Type() != js.TypeObject {
+		return js.Undefined(), fmt.Errorf("qoiwasm: expected an ImageData, got %s", v.Type())
+	}
+
+	pix, err := bytesFromJS(v.Get("data"))
+	if err != nil {
+		return js.Undefined(), err
+	}
+
+	width, height := v.Get("width"), v.Get("height")
+	if width.Type() != js.TypeNumber || height.Type() != js.TypeNumber {
+		return js.Undefined(), fmt.Errorf("qoiwasm: expected numeric width and height")
+	}
+
+	data, err := EncodeRGBA(pix, width.Int(), height.Int())
+	if err != nil {
+		return js.Undefined(), err
+	}
+
+	array := js.Global().Get("Uint8Array").New(len(data))
+	js.CopyBytesToJS(array, data)
+
+	return array, nil
+}
+
+// bytesFromJS copies the bytes of a Uint8Array, a Uint8ClampedArray or an
+// ArrayBuffer into Go memory.
+func bytesFromJS(v js.Value) ([]byte, error) {
+	switch {
+	case v.InstanceOf(js.Global().Get("Uint8Array")), v.InstanceOf(js.Global().Get("Uint8ClampedArray")):
+	case v.InstanceOf(js.Global().Get("ArrayBuffer")):
+		v = js.Global().Get("Uint8Array").New(v)
+	default:
+		return nil, fmt.Errorf("qoiwasm: expected a Uint8Array or an ArrayBuffer")
+	}
+
+	data := make([]byte, v.Get("length").Int())
+	js.CopyBytesToGo(data, v)
+
+	return data, nil
+}
+
+// Register defines the global JavaScript object qoi with the functions
+// decode(bytes) and encode(imageData). Both return a Promise, which resolves
+// to the result of DecodeToImageData or EncodeFromImageData and rejects with
+// an Error holding the message of the Go error.
+func Register() {
+	qoi := js.Global().Get("Object").New()
+	qoi.Set("decode", promiseFunc(DecodeToImageData))
+	qoi.Set("encode", promiseFunc(EncodeFromImageData))
+	js.Global().Set("qoi", qoi)
+}
+
+// promiseFunc wraps f into a JavaScript function returning a Promise.
+// The returned function is never released, it lives as long as the program.
+func promiseFunc(f func(js.Value) (js.Value, error)) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		arg := js.Undefined()
+		if len(args) > 0 {
+			arg = args[0]
+		}
+
+		executor := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			resolve, reject := args[0], args[1]
+
+			v, err := f(arg)
+			if err != nil {
+				reject.Invoke(js.Global().Get("Error").New(err.Error()))
+				return nil
+			}
+			resolve.Invoke(v)
+
+			return nil
+		})
+		//the executor runs synchronously inside the Promise constructor
+		defer executor.Release()
+
+		return js.Global().Get("Promise").New(executor)
+	})
+}
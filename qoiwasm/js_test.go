@@ -0,0 +1,97 @@
+//go:build js && wasm
+
+package qoiwasm
+
+import (
+	"os"
+	"syscall/js"
+	"testing"
+)
+
+// The tests run with a wasm runner, e.g.
+//
+//	GOOS=js GOARCH=wasm go test -exec="$(go env GOROOT)/lib/wasm/go_js_wasm_exec" ./qoiwasm
+
+func TestImageDataRoundTrip(t *testing.T) {
+	data, err := os.ReadFile("../testdata/qoi_logo.qoi")
+	if err != nil {
+		t.Fatalf("could not read file: %v\n", err)
+	}
+
+	array := js.Global().Get("Uint8Array").New(len(data))
+	js.CopyBytesToJS(array, data)
+
+	for _, v := range []js.Value{array, array.Get("buffer")} {
+		imageData, err := DecodeToImageData(v)
+		if err != nil {
+			t.Fatalf("DecodeToImageData failed: %v\n", err)
+		}
+
+		expected, width, height, err := DecodeRGBA(data)
+		if err != nil {
+			t.Fatalf("could not decode file: %v\n", err)
+		}
+		if imageData.Get("width").Int() != width || imageData.Get("height").Int() != height {
+			t.Fatalf("\nExpected size:\t %dx%d\nActual size:\t %dx%d\n", width, height, imageData.Get("width").Int(), imageData.Get("height").Int())
+		}
+		if !imageData.Get("data").InstanceOf(js.Global().Get("Uint8ClampedArray")) {
+			t.Fatalf("\ndata is not a Uint8ClampedArray\n")
+		}
+
+		encoded, err := EncodeFromImageData(imageData)
+		if err != nil {
+			t.Fatalf("EncodeFromImageData failed: %v\n", err)
+		}
+
+		actual := make([]byte, encoded.Get("length").Int())
+		js.CopyBytesToGo(actual, encoded)
+		pix, _, _, err := DecodeRGBA(actual)
+		if err != nil {
+			t.Fatalf("could not decode the encoded image: %v\n", err)
+		}
+		if string(pix) != string(expected) {
+			t.Errorf("\nthe round trip changed the pixels\n")
+		}
+	}
+}
+
+func TestImageDataErrors(t *testing.T) {
+	if _, err := DecodeToImageData(js.ValueOf("qoif")); err == nil {
+		t.Errorf("\nDecodeToImageData of a string:\nExpected error:\t true\n")
+	}
+	if _, err := DecodeToImageData(js.Global().Get("Uint8Array").New(3)); err == nil {
+		t.Errorf("\nDecodeToImageData of 3 bytes:\nExpected error:\t true\n")
+	}
+
+	v := js.ValueOf(map[string]interface{}{
+		"data":   js.Global().Get("Uint8ClampedArray").New(7),
+		"width":  1,
+		"height": 2,
+	})
+	if _, err := EncodeFromImageData(v); err == nil {
+		t.Errorf("\nEncodeFromImageData of 7 bytes for 1x2 pixels:\nExpected error:\t true\n")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register()
+
+	done := make(chan string, 2)
+	onResolve := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		done <- "resolved"
+		return nil
+	})
+	defer onResolve.Release()
+	onReject := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		done <- "rejected: " + args[0].Get("message").String()
+		return nil
+	})
+	defer onReject.Release()
+
+	invalid := js.Global().Get("Uint8Array").New(3)
+	js.Global().Get("qoi").Call("decode", invalid).Call("then", onResolve, onReject)
+
+	if result := <-done; result == "resolved" {
+		t.Errorf("\nqoi.decode of 3 bytes resolved\n")
+	}
+}
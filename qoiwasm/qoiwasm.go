@@ -0,0 +1,66 @@
+// Package qoiwasm exposes the QOI decoder and encoder to JavaScript when
+// compiled with GOOS=js GOARCH=wasm.
+//
+// The pixels are exchanged in the layout of the ImageData of a canvas: tightly
+// packed, non-premultiplied RGBA samples, which is exactly the layout of the
+// *image.NRGBA images of the qoi package. DecodeRGBA and EncodeRGBA therefore
+// never convert or copy the pixels themselves. Only the copies between the
+// JavaScript and the WebAssembly memory remain, one per call.
+//
+// The marshalling in this file builds on every platform and is tested natively.
+// The syscall/js bindings are in the files with the js build tag.
+package qoiwasm
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+
+	"github.com/LukiDS/image/imgconv"
+	"github.com/LukiDS/image/qoi"
+)
+
+// DecodeRGBA decodes the QOI image data and returns its pixels as tightly
+// packed, non-premultiplied RGBA samples.
+func DecodeRGBA(data []byte) (pix []byte, width, height int, err error) {
+	m, err := qoi.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	b := m.Bounds()
+
+	return packedRGBA(m), b.Dx(), b.Dy(), nil
+}
+
+// packedRGBA returns the pixels of m as tightly packed NRGBA samples, without
+// a copy if m already has that layout.
+func packedRGBA(m image.Image) []byte {
+	n, ok := m.(*image.NRGBA)
+	if !ok || n.Rect.Min != (image.Point{}) || n.Stride != 4*n.Rect.Dx() {
+		n = imgconv.ToNRGBA(m)
+	}
+
+	return n.Pix[:4*n.Rect.Dx()*n.Rect.Dy()]
+}
+
+// EncodeRGBA encodes width x height tightly packed, non-premultiplied RGBA
+// samples as a QOI image. pix must hold exactly 4*width*height bytes.
+func EncodeRGBA(pix []byte, width, height int) ([]byte, error) {
+	if width <= 0 || height <= 0 || width > len(pix)/4/height || len(pix) != 4*width*height {
+		return nil, fmt.Errorf("qoiwasm: %d bytes do not hold %dx%d RGBA pixels", len(pix), width, height)
+	}
+
+	m := &image.NRGBA{
+		Pix:    pix,
+		Stride: 4 * width,
+		Rect:   image.Rect(0, 0, width, height),
+	}
+
+	var buf bytes.Buffer
+	if err := qoi.Encode(&buf, m); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
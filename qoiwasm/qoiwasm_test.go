@@ -0,0 +1,105 @@
+package qoiwasm
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"testing"
+
+	"github.com/LukiDS/image/qoi"
+)
+
+func TestDecodeRGBA(t *testing.T) {
+	data, err := os.ReadFile("../testdata/qoi_logo.qoi")
+	if err != nil {
+		t.Fatalf("could not read file: %v\n", err)
+	}
+
+	expected, err := qoi.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("could not decode file: %v\n", err)
+	}
+
+	pix, width, height, err := DecodeRGBA(data)
+	if err != nil {
+		t.Fatalf("DecodeRGBA failed: %v\n", err)
+	}
+
+	if b := expected.Bounds(); width != b.Dx() || height != b.Dy() {
+		t.Fatalf("\nExpected size:\t %v\nActual size:\t %dx%d\n", b.Size(), width, height)
+	}
+	if !bytes.Equal(pix, expected.(*image.NRGBA).Pix) {
+		t.Errorf("\nDecodeRGBA returned different pixels\n")
+	}
+
+	if _, _, _, err := DecodeRGBA(data[:len(data)/2]); err == nil {
+		t.Errorf("\nDecodeRGBA of a truncated file:\nExpected error:\t true\nActual error:\t false\n")
+	}
+}
+
+func TestEncodeRGBA(t *testing.T) {
+	pix := []byte{
+		0xff, 0x00, 0x00, 0xff, 0x00, 0xff, 0x00, 0x80,
+		0x00, 0x00, 0xff, 0x00, 0x10, 0x20, 0x30, 0x40,
+		0x01, 0x02, 0x03, 0x04, 0x01, 0x02, 0x03, 0x04,
+	}
+
+	tests := []struct {
+		name          string
+		pix           []byte
+		width, height int
+		expectedError bool
+	}{
+		{name: "2x3 pixels", pix: pix, width: 2, height: 3},
+		{name: "3x2 pixels", pix: pix, width: 3, height: 2},
+		{name: "Too few bytes", pix: pix[:20], width: 2, height: 3, expectedError: true},
+		{name: "Too many bytes", pix: pix, width: 2, height: 2, expectedError: true},
+		{name: "Zero width", pix: nil, width: 0, height: 3, expectedError: true},
+		{name: "Negative height", pix: pix, width: -2, height: -3, expectedError: true},
+		{name: "Overflowing size", pix: pix, width: 1 << 62, height: 1 << 62, expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := EncodeRGBA(tt.pix, tt.width, tt.height)
+			if (err != nil) != tt.expectedError {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("EncodeRGBA(%d bytes, %d, %d) = (%d bytes,%v)\n", len(tt.pix), tt.width, tt.height, len(data), err) +
+					fmt.Sprintf("Expected error:\t %t\n", tt.expectedError)
+				t.Fatalf(format)
+			}
+			if err != nil {
+				return
+			}
+
+			actual, width, height, err := DecodeRGBA(data)
+			if err != nil {
+				t.Fatalf("could not decode the encoded image: %v\n", err)
+			}
+			if width != tt.width || height != tt.height || !bytes.Equal(actual, tt.pix) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Expected:\t %dx%d %v\n", tt.width, tt.height, tt.pix) +
+					fmt.Sprintf("Actual:\t %dx%d %v\n", width, height, actual)
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+func TestPackedRGBA(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	m.SetNRGBA(1, 1, color.NRGBA{1, 2, 3, 4})
+
+	if pix := packedRGBA(m); &pix[0] != &m.Pix[0] {
+		t.Errorf("\npackedRGBA copied a packed image\n")
+	}
+
+	//a sub image has a stride wider than its rows
+	sub := m.SubImage(image.Rect(1, 1, 3, 3))
+	expected := []byte{1, 2, 3, 4, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	if pix := packedRGBA(sub); !bytes.Equal(pix, expected) {
+		t.Errorf("\nExpected pixels:\t %v\nActual pixels:\t %v\n", expected, pix)
+	}
+}
@@ -0,0 +1,317 @@
+package sgi
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+type sgiHeader struct {
+	storage  int
+	bpc      int //bytes per sample
+	width    int
+	height   int
+	channels int
+}
+
+type decoder struct {
+	m   image.Image
+	buf *bufio.Reader
+	h   sgiHeader
+	err error
+}
+
+func (d *decoder) decodeHeader() {
+	h := make([]byte, sgiHeaderSize)
+	if _, err := io.ReadFull(d.buf, h); err != nil || string(h[:2]) != sgiMagic {
+		d.err = fmt.Errorf("image not valid sgi file")
+		return
+	}
+
+	d.h.storage = int(h[2])
+	d.h.bpc = int(h[3])
+	dimension := binary.BigEndian.Uint16(h[4:6])
+	d.h.width = int(binary.BigEndian.Uint16(h[6:8]))
+	d.h.height = int(binary.BigEndian.Uint16(h[8:10]))
+	d.h.channels = int(binary.BigEndian.Uint16(h[10:12]))
+	colormap := binary.BigEndian.Uint32(h[104:108])
+
+	if d.h.storage != storageVerbatim && d.h.storage != storageRLE {
+		d.err = fmt.Errorf("invalid sgi header: storage format %d", d.h.storage)
+		return
+	}
+	if d.h.bpc != 1 && d.h.bpc != 2 {
+		d.err = fmt.Errorf("invalid sgi header: %d bytes per sample, must be 1 or 2", d.h.bpc)
+		return
+	}
+
+	//one dimensional images are a single row, two dimensional ones a single channel
+	switch dimension {
+	case 1:
+		d.h.height, d.h.channels = 1, 1
+	case 2:
+		d.h.channels = 1
+	case 3:
+	default:
+		d.err = fmt.Errorf("invalid sgi header: dimension %d", dimension)
+		return
+	}
+
+	if d.h.channels < 1 || d.h.channels > 4 {
+		d.err = fmt.Errorf("unsupported sgi format: %d channels", d.h.channels)
+		return
+	}
+	if colormap != 0 {
+		d.err = fmt.Errorf("unsupported sgi format: colormap mode %d", colormap)
+		return
+	}
+
+	if d.h.width <= 0 || d.h.height <= 0 || d.h.width > sgiMaxPixels/d.h.height {
+		d.err = fmt.Errorf("invalid sgi image size: %dx%d, must be non-empty and at most %d pixels", d.h.width, d.h.height, sgiMaxPixels)
+		return
+	}
+}
+
+// colorModel returns the color model of the decoded image.
+func (d *decoder) colorModel() color.Model {
+	switch {
+	case d.h.channels == 1 && d.h.bpc == 1:
+		return color.GrayModel
+	case d.h.channels == 1:
+		return color.Gray16Model
+	case d.h.bpc == 1:
+		return color.NRGBAModel
+	default:
+		return color.NRGBA64Model
+	}
+}
+
+func (d *decoder) decode() {
+	if d.err != nil {
+		return
+	}
+
+	r := image.Rect(0, 0, d.h.width, d.h.height)
+	var pix []byte
+	var stride int
+	switch d.colorModel() {
+	case color.GrayModel:
+		m := image.NewGray(r)
+		d.m, pix, stride = m, m.Pix, m.Stride
+	case color.Gray16Model:
+		m := image.NewGray16(r)
+		d.m, pix, stride = m, m.Pix, m.Stride
+	case color.NRGBAModel:
+		m := image.NewNRGBA(r)
+		d.m, pix, stride = m, m.Pix, m.Stride
+	default:
+		m := image.NewNRGBA64(r)
+		d.m, pix, stride = m, m.Pix, m.Stride
+	}
+
+	//RGB files have no alpha channel, set it to opaque
+	if d.h.channels == 3 {
+		for i := 3 * d.h.bpc; i < len(pix); i += 4 * d.h.bpc {
+			pix[i] = 0xff
+			pix[i+d.h.bpc-1] = 0xff
+		}
+	}
+
+	if d.h.storage == storageRLE {
+		d.decodeRLE(pix, stride)
+	} else {
+		d.decodeVerbatim(pix, stride)
+	}
+}
+
+// components returns the components of a decoded pixel the samples of
+// channel c are stored in. Gray and alpha images use the gray value for all
+// three colors.
+func (d *decoder) components(c int) []int {
+	switch {
+	case d.h.channels == 1:
+		return []int{0}
+	case d.h.channels == 2 && c == 0:
+		return []int{0, 1, 2}
+	case d.h.channels == 2:
+		return []int{3}
+	default:
+		return []int{c}
+	}
+}
+
+// setScanline stores the big-endian samples of channel c of the file row r,
+// which counts from the bottom of the image.
+func (d *decoder) setScanline(pix []byte, stride int, scanline []byte, c, r int) {
+	bpc := d.h.bpc
+	size := bpc
+	if d.h.channels > 1 {
+		size *= 4
+	}
+
+	row := pix[(d.h.height-1-r)*stride:]
+	for _, comp := range d.components(c) {
+		i := comp * bpc
+		for x := 0; x < d.h.width; x, i = x+1, i+size {
+			copy(row[i:i+bpc], scanline[x*bpc:x*bpc+bpc])
+		}
+	}
+}
+
+// decodeVerbatim reads the uncompressed scanlines, which are stored channel
+// after channel, each channel from the bottom row to the top row.
+func (d *decoder) decodeVerbatim(pix []byte, stride int) {
+	scanline := make([]byte, d.h.width*d.h.bpc)
+
+	for c := 0; c < d.h.channels; c++ {
+		for r := 0; r < d.h.height; r++ {
+			if _, err := io.ReadFull(d.buf, scanline); err != nil {
+				d.err = fmt.Errorf("truncated sgi pixel data: %w", io.ErrUnexpectedEOF)
+				return
+			}
+			d.setScanline(pix, stride, scanline, c, r)
+		}
+	}
+}
+
+// decodeRLE reads the tables with the offset and length of every run-length
+// encoded scanline, which may be stored anywhere after the tables and in any
+// order, and expands the scanlines.
+func (d *decoder) decodeRLE(pix []byte, stride int) {
+	n := d.h.height * d.h.channels
+	tables := make([]byte, 8*n)
+	if _, err := io.ReadFull(d.buf, tables); err != nil {
+		d.err = fmt.Errorf("truncated sgi scanline tables: %w", io.ErrUnexpectedEOF)
+		return
+	}
+
+	dataStart := uint64(sgiHeaderSize + len(tables))
+	offsets := make([]uint64, n)
+	lengths := make([]uint64, n)
+	end := dataStart
+	for i := range offsets {
+		offsets[i] = uint64(binary.BigEndian.Uint32(tables[4*i:]))
+		lengths[i] = uint64(binary.BigEndian.Uint32(tables[4*(n+i):]))
+		if offsets[i] < dataStart {
+			d.err = fmt.Errorf("invalid sgi scanline table: offset %d inside the header", offsets[i])
+			return
+		}
+		if offsets[i]+lengths[i] > end {
+			end = offsets[i] + lengths[i]
+		}
+	}
+
+	//read up to the end of the last scanline, the limit keeps corrupt tables
+	//from allocating more than the file holds
+	data, err := io.ReadAll(io.LimitReader(d.buf, int64(end-dataStart)))
+	if err != nil {
+		d.err = err
+		return
+	}
+	if uint64(len(data)) < end-dataStart {
+		d.err = fmt.Errorf("truncated sgi scanline data: %w", io.ErrUnexpectedEOF)
+		return
+	}
+
+	scanline := make([]byte, d.h.width*d.h.bpc)
+	for c := 0; c < d.h.channels; c++ {
+		for r := 0; r < d.h.height; r++ {
+			i := c*d.h.height + r
+			start := offsets[i] - dataStart
+			if d.expandRLE(scanline, data[start:start+lengths[i]]); d.err != nil {
+				return
+			}
+			d.setScanline(pix, stride, scanline, c, r)
+		}
+	}
+}
+
+// expandRLE expands the run-length encoded scanline src into dst. Every run
+// starts with a count item of the size of a sample: the low 7 bits hold the
+// number of samples, where 0 ends the scanline, and the high bit selects
+// whether the samples follow literally or a single sample is repeated.
+func (d *decoder) expandRLE(dst, src []byte) {
+	bpc := d.h.bpc
+	o := 0
+
+	for i := 0; ; {
+		if i+bpc > len(src) {
+			d.err = fmt.Errorf("truncated sgi scanline: %w", io.ErrUnexpectedEOF)
+			return
+		}
+		count := src[i+bpc-1]
+		i += bpc
+
+		n := int(count&0x7f) * bpc
+		if n == 0 {
+			break
+		}
+		if o+n > len(dst) {
+			d.err = fmt.Errorf("invalid sgi scanline: run of %d samples exceeds the width of %d", n/bpc, d.h.width)
+			return
+		}
+
+		if count&0x80 != 0 {
+			if i+n > len(src) {
+				d.err = fmt.Errorf("truncated sgi scanline: %w", io.ErrUnexpectedEOF)
+				return
+			}
+			copy(dst[o:o+n], src[i:i+n])
+			i += n
+		} else {
+			if i+bpc > len(src) {
+				d.err = fmt.Errorf("truncated sgi scanline: %w", io.ErrUnexpectedEOF)
+				return
+			}
+			for j := o; j < o+n; j += bpc {
+				copy(dst[j:j+bpc], src[i:i+bpc])
+			}
+			i += bpc
+		}
+		o += n
+	}
+
+	if o != len(dst) {
+		d.err = fmt.Errorf("invalid sgi scanline: %d samples for a width of %d", o/bpc, d.h.width)
+	}
+}
+
+// DecodeConfig returns the color model and dimensions of a SGI image without
+// decoding the entire image.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	d := decoder{
+		buf: bufio.NewReader(r),
+	}
+
+	d.decodeHeader()
+	if d.err != nil {
+		return image.Config{}, d.err
+	}
+
+	return image.Config{
+		ColorModel: d.colorModel(),
+		Width:      d.h.width,
+		Height:     d.h.height,
+	}, nil
+}
+
+// Decode reads a SGI image from r and returns it as an *image.Gray or
+// *image.Gray16 image for single channel files and as an *image.NRGBA or
+// *image.NRGBA64 image otherwise, depending on the sample size.
+func Decode(r io.Reader) (image.Image, error) {
+	d := decoder{
+		buf: bufio.NewReader(r),
+	}
+
+	d.decodeHeader()
+	d.decode()
+
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	return d.m, nil
+}
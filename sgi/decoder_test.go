@@ -0,0 +1,310 @@
+package sgi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/LukiDS/image/imgtest"
+)
+
+// sgiFile describes a SGI file for generateSGI. A zero dimension is replaced
+// by 3; a nil magic by the SGI magic.
+type sgiFile struct {
+	magic     []byte
+	storage   byte
+	bpc       byte
+	dimension uint16
+	width     uint16
+	height    uint16
+	channels  uint16
+	colormap  uint32
+	data      []byte
+}
+
+// generateSGI writes the header of f followed by its data.
+func generateSGI(t testing.TB, f sgiFile) []byte {
+	t.Helper()
+
+	if f.magic == nil {
+		f.magic = []byte(sgiMagic)
+	}
+	if f.dimension == 0 {
+		f.dimension = 3
+	}
+
+	h := make([]byte, sgiHeaderSize)
+	copy(h, f.magic)
+	h[2], h[3] = f.storage, f.bpc
+	binary.BigEndian.PutUint16(h[4:], f.dimension)
+	binary.BigEndian.PutUint16(h[6:], f.width)
+	binary.BigEndian.PutUint16(h[8:], f.height)
+	binary.BigEndian.PutUint16(h[10:], f.channels)
+	binary.BigEndian.PutUint32(h[104:], f.colormap)
+
+	return append(h, f.data...)
+}
+
+// rleTables returns the scanline tables for the given offsets and lengths.
+func rleTables(offsets, lengths []uint32) []byte {
+	b := make([]byte, 0, 4*len(offsets)+4*len(lengths))
+	for _, v := range append(offsets, lengths...) {
+		b = append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	}
+
+	return b
+}
+
+func TestDecodeWithTestFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected image.Image
+	}{
+		{name: "gray8_verbatim.sgi", expected: &image.Gray{}},
+		{name: "gray8_rle.sgi", expected: &image.Gray{}},
+		{name: "graya8_rle.sgi", expected: &image.NRGBA{}},
+		{name: "rgb8_verbatim.sgi", expected: &image.NRGBA{}},
+		{name: "rgb8_rle.sgi", expected: &image.NRGBA{}},
+		{name: "rgba8_verbatim.sgi", expected: &image.NRGBA{}},
+		{name: "rgba8_rle.sgi", expected: &image.NRGBA{}},
+		{name: "gray16_verbatim.sgi", expected: &image.Gray16{}},
+		{name: "rgb16_rle.sgi", expected: &image.NRGBA64{}},
+		{name: "rgba16_verbatim.sgi", expected: &image.NRGBA64{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			name := filepath.Join("../testdata/sgi", test.name)
+			sgiFile, err := os.Open(name)
+			if err != nil {
+				t.Fatalf("could not read file: %v\n", err)
+			}
+			defer sgiFile.Close()
+
+			img, err := Decode(sgiFile)
+			if err != nil {
+				t.Fatalf("could not decode file: %v\n", err)
+			}
+
+			pngFile, err := os.Open(strings.TrimSuffix(name, filepath.Ext(name)) + ".png")
+			if err != nil {
+				t.Fatalf("could not read file: %v\n", err)
+			}
+			defer pngFile.Close()
+
+			ref, err := png.Decode(pngFile)
+			if err != nil {
+				t.Fatalf("could not decode file: %v\n", err)
+			}
+
+			if fmt.Sprintf("%T", img) != fmt.Sprintf("%T", test.expected) {
+				t.Fatalf("File %s: expected an %T image, got %T\n", name, test.expected, img)
+			}
+			imgtest.AssertEqual(t, ref, img)
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     sgiFile
+		expected image.Image
+	}{
+		{
+			name: "should flip the bottom-up rows",
+			file: sgiFile{bpc: 1, dimension: 2, width: 2, height: 2, data: []byte{
+				1, 2,
+				3, 4,
+			}},
+			expected: &image.Gray{Pix: []byte{3, 4, 1, 2}, Stride: 2, Rect: image.Rect(0, 0, 2, 2)},
+		},
+		{
+			name: "should decode a one dimensional image as a single row",
+			file: sgiFile{bpc: 1, dimension: 1, width: 3, height: 7, channels: 3, data: []byte{
+				1, 2, 3,
+			}},
+			expected: &image.Gray{Pix: []byte{1, 2, 3}, Stride: 3, Rect: image.Rect(0, 0, 3, 1)},
+		},
+		{
+			name: "should use the first of two channels as gray value",
+			file: sgiFile{bpc: 1, width: 1, height: 1, channels: 2, data: []byte{
+				0x40, 0x80,
+			}},
+			expected: &image.NRGBA{Pix: []byte{0x40, 0x40, 0x40, 0x80}, Stride: 4, Rect: image.Rect(0, 0, 1, 1)},
+		},
+		{
+			name: "should decode big-endian 16 bit samples",
+			file: sgiFile{bpc: 2, width: 1, height: 1, channels: 3, data: []byte{
+				0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc,
+			}},
+			expected: &image.NRGBA64{Pix: []byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xff, 0xff}, Stride: 8, Rect: image.Rect(0, 0, 1, 1)},
+		},
+		{
+			name: "should expand runs of the scanlines in any order and share scanlines",
+			file: sgiFile{storage: storageRLE, bpc: 1, dimension: 2, width: 4, height: 3, data: append(
+				//the file rows 1 and 2 share the first scanline
+				rleTables([]uint32{542, 536, 536}, []uint32{3, 6, 6}),
+				0x82, 5, 6, 2, 7, 0,
+				0x04, 9, 0,
+			)},
+			expected: &image.Gray{Pix: []byte{
+				5, 6, 7, 7,
+				5, 6, 7, 7,
+				9, 9, 9, 9,
+			}, Stride: 4, Rect: image.Rect(0, 0, 4, 3)},
+		},
+		{
+			name: "should expand 16 bit runs",
+			file: sgiFile{storage: storageRLE, bpc: 2, dimension: 2, width: 3, height: 1, data: append(
+				rleTables([]uint32{520}, []uint32{10}),
+				0x00, 0x81, 0xab, 0xcd, 0x00, 0x02, 0x01, 0x02, 0x00, 0x00,
+			)},
+			expected: &image.Gray16{Pix: []byte{0xab, 0xcd, 0x01, 0x02, 0x01, 0x02}, Stride: 6, Rect: image.Rect(0, 0, 3, 1)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := Decode(bytes.NewReader(generateSGI(t, test.file)))
+			if err != nil {
+				t.Fatalf("Decode: unexpected error: %v\n", err)
+			}
+
+			if fmt.Sprintf("%T", actual) != fmt.Sprintf("%T", test.expected) {
+				t.Fatalf("Decode(%s): expected an %T image, got %T\n", test.name, test.expected, actual)
+			}
+			//the expected images have the decoded type, which keeps 16 bit samples
+			if !reflect.DeepEqual(actual, test.expected) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Decode(%s)\n", test.name) +
+					fmt.Sprintf("Expected:\t %+v\n", test.expected) +
+					fmt.Sprintf("Actual:\t %+v\n", actual)
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	rle := func(offsets, lengths []uint32, data ...byte) []byte {
+		return append(rleTables(offsets, lengths), data...)
+	}
+
+	tests := []struct {
+		name  string
+		data  []byte
+		error string
+	}{
+		{name: "empty file", data: nil, error: "not valid sgi"},
+		{name: "truncated header", data: generateSGI(t, sgiFile{bpc: 1, width: 1, height: 1, channels: 1})[:100], error: "not valid sgi"},
+		{name: "wrong magic", data: generateSGI(t, sgiFile{magic: []byte{0xda, 0x01}, bpc: 1, width: 1, height: 1, channels: 1, data: []byte{0}}), error: "not valid sgi"},
+		{name: "unknown storage", data: generateSGI(t, sgiFile{storage: 2, bpc: 1, width: 1, height: 1, channels: 1, data: []byte{0}}), error: "storage format 2"},
+		{name: "4 bytes per sample", data: generateSGI(t, sgiFile{bpc: 4, width: 1, height: 1, channels: 1, data: []byte{0, 0, 0, 0}}), error: "bytes per sample"},
+		{name: "unknown dimension", data: generateSGI(t, sgiFile{bpc: 1, dimension: 4, width: 1, height: 1, channels: 1, data: []byte{0}}), error: "dimension 4"},
+		{name: "no channels", data: generateSGI(t, sgiFile{bpc: 1, width: 1, height: 1, data: []byte{0}}), error: "0 channels"},
+		{name: "5 channels", data: generateSGI(t, sgiFile{bpc: 1, width: 1, height: 1, channels: 5, data: []byte{0, 0, 0, 0, 0}}), error: "5 channels"},
+		{name: "colormap file", data: generateSGI(t, sgiFile{bpc: 1, width: 1, height: 1, channels: 1, colormap: 3, data: []byte{0}}), error: "colormap mode 3"},
+		{name: "empty image", data: generateSGI(t, sgiFile{bpc: 1, width: 0, height: 1, channels: 1}), error: "image size"},
+		{name: "absurd size", data: generateSGI(t, sgiFile{bpc: 1, width: 65535, height: 65535, channels: 1}), error: "image size"},
+		{name: "truncated pixels", data: generateSGI(t, sgiFile{bpc: 1, width: 2, height: 2, channels: 1, data: []byte{0, 0, 0}}), error: "truncated"},
+		{name: "truncated tables", data: generateSGI(t, sgiFile{storage: storageRLE, bpc: 1, width: 1, height: 2, channels: 1, data: []byte{0, 0, 2}}), error: "truncated sgi scanline tables"},
+		{
+			name:  "offset inside the header",
+			data:  generateSGI(t, sgiFile{storage: storageRLE, bpc: 1, width: 1, height: 1, channels: 1, data: rle([]uint32{519}, []uint32{3}, 0x81, 0, 0)}),
+			error: "inside the header",
+		},
+		{
+			name:  "scanline beyond the end of the file",
+			data:  generateSGI(t, sgiFile{storage: storageRLE, bpc: 1, width: 1, height: 1, channels: 1, data: rle([]uint32{520}, []uint32{0xffffffff}, 0x81, 0, 0)}),
+			error: "truncated sgi scanline data",
+		},
+		{
+			name:  "run exceeds the width",
+			data:  generateSGI(t, sgiFile{storage: storageRLE, bpc: 1, width: 1, height: 1, channels: 1, data: rle([]uint32{520}, []uint32{3}, 0x02, 0, 0)}),
+			error: "exceeds the width",
+		},
+		{
+			name:  "short scanline",
+			data:  generateSGI(t, sgiFile{storage: storageRLE, bpc: 1, width: 2, height: 1, channels: 1, data: rle([]uint32{520}, []uint32{3}, 0x01, 0, 0)}),
+			error: "1 samples for a width of 2",
+		},
+		{
+			name:  "truncated literal run",
+			data:  generateSGI(t, sgiFile{storage: storageRLE, bpc: 1, width: 2, height: 1, channels: 1, data: rle([]uint32{520}, []uint32{2}, 0x82, 0, 0)}),
+			error: "truncated sgi scanline",
+		},
+		{
+			name:  "missing end of scanline",
+			data:  generateSGI(t, sgiFile{storage: storageRLE, bpc: 1, width: 1, height: 1, channels: 1, data: rle([]uint32{520}, []uint32{2}, 0x01, 0)}),
+			error: "truncated sgi scanline",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Decode(bytes.NewReader(test.data))
+			if err == nil || !strings.Contains(err.Error(), test.error) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Decode(%s)\n", test.name) +
+					fmt.Sprintf("Expected error:\t containing %q\n", test.error) +
+					fmt.Sprintf("Actual error:\t %v\n", err)
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+func TestDecodeConfig(t *testing.T) {
+	tests := []struct {
+		file     sgiFile
+		expected image.Config
+	}{
+		{file: sgiFile{bpc: 1, width: 640, height: 480, channels: 1}, expected: image.Config{ColorModel: color.GrayModel, Width: 640, Height: 480}},
+		{file: sgiFile{bpc: 2, width: 640, height: 480, channels: 1}, expected: image.Config{ColorModel: color.Gray16Model, Width: 640, Height: 480}},
+		{file: sgiFile{bpc: 1, width: 640, height: 480, channels: 2}, expected: image.Config{ColorModel: color.NRGBAModel, Width: 640, Height: 480}},
+		{file: sgiFile{bpc: 2, width: 640, height: 480, channels: 4}, expected: image.Config{ColorModel: color.NRGBA64Model, Width: 640, Height: 480}},
+		{file: sgiFile{bpc: 2, dimension: 1, width: 640, height: 480, channels: 4}, expected: image.Config{ColorModel: color.Gray16Model, Width: 640, Height: 1}},
+	}
+
+	for _, test := range tests {
+		actual, err := DecodeConfig(bytes.NewReader(generateSGI(t, test.file)))
+		if err != nil || actual != test.expected {
+			t.Errorf("DecodeConfig(%+v) = (%+v, %v), expected %+v\n", test.file, actual, err, test.expected)
+		}
+	}
+
+	data, err := os.ReadFile("../testdata/sgi/rgb8_rle.sgi")
+	if err != nil {
+		t.Fatalf("could not read file: %v\n", err)
+	}
+	imgtest.RequireFormat(t, bytes.NewReader(data), "sgi", image.Rect(0, 0, 150, 40))
+}
+
+func TestDecodeCorruptFiles(t *testing.T) {
+	//hit the header and the scanline tables more often than the pixels
+	imgtest.DecodeCorrupt(t, "../testdata/sgi/*.sgi", 2000, 2048, Decode)
+}
+
+func BenchmarkDecode(b *testing.B) {
+	data, err := os.ReadFile("../testdata/sgi/rgba8_rle.sgi")
+	if err != nil {
+		b.Fatalf("could not read file: %v\n", err)
+	}
+
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Decode(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
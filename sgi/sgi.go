@@ -0,0 +1,31 @@
+// Package sgi implements a decoder for SGI RGB images (.sgi, .rgb, .bw).
+//
+// Uncompressed (VERBATIM) and run-length encoded files with 1 to 4 channels
+// of 8 or 16 bit samples are supported. Files with one channel are decoded
+// into *image.Gray or *image.Gray16 images, files with two channels (gray and
+// alpha), three channels (RGB) or four channels (RGBA) into *image.NRGBA or
+// *image.NRGBA64 images. Only the normal colormap mode is supported; the
+// obsolete dithered, screen and colormap files are rejected.
+package sgi
+
+import (
+	"image"
+)
+
+const (
+	// sgiMaxPixels guards against allocating huge images for corrupt headers.
+	sgiMaxPixels = 400_000_000
+
+	sgiMagic      = "\x01\xda"
+	sgiHeaderSize = 512 //size in bytes
+)
+
+// storage formats
+const (
+	storageVerbatim = 0
+	storageRLE      = 1
+)
+
+func init() {
+	image.RegisterFormat("sgi", sgiMagic, Decode, DecodeConfig)
+}
@@ -0,0 +1,199 @@
+package sixel
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strconv"
+
+	"github.com/LukiDS/image/imgconv"
+)
+
+// Options are the encoding parameters.
+type Options struct {
+	// MaxColors is the maximum number of colors of the palette, between 1 and
+	// 256. 0 means 256. Some terminals support fewer color registers.
+	MaxColors int
+
+	// Dither is the dithering algorithm used when mapping the pixels to the palette.
+	Dither imgconv.Dither
+}
+
+type encoder struct {
+	w   *bufio.Writer
+	m   *image.Paletted
+	err error
+}
+
+// Encode writes the image m to w as sixel graphics, enclosed in the control
+// sequences which make a terminal display it at the cursor position.
+//
+// m is converted with imgconv.ToPalettedDithered to at most opts.MaxColors
+// colors. Pixels mapped to a palette color with an alpha value below 128 are
+// not drawn and keep the background of the terminal.
+func Encode(w io.Writer, m image.Image, opts Options) error {
+	width := m.Bounds().Dx()
+	height := m.Bounds().Dy()
+	if width <= 0 || height <= 0 || width > sixelMaxPixels/height {
+		return fmt.Errorf("invalid image size: %dx%d, must be non-empty and at most %d pixels", width, height, sixelMaxPixels)
+	}
+
+	colors := opts.MaxColors
+	switch {
+	case colors == 0:
+		colors = maxColors
+	case colors < 0 || colors > maxColors:
+		return fmt.Errorf("invalid sixel palette size %d, must be between 1 and %d", colors, maxColors)
+	}
+
+	e := encoder{
+		w: bufio.NewWriter(w),
+		m: imgconv.ToPalettedDithered(m, colors, opts.Dither),
+	}
+
+	e.encodeHeader()
+	e.encode()
+
+	if e.err != nil {
+		return e.err
+	}
+
+	if _, err := e.w.WriteString(st); err != nil {
+		return err
+	}
+
+	return e.w.Flush()
+}
+
+// encodeHeader writes the control sequence starting the sixel data, the
+// raster attributes with the image size and the color registers. Colors are
+// given as RGB percentages.
+func (e *encoder) encodeHeader() {
+	b := e.m.Bounds()
+	h := []byte(dcs)
+	h = append(h, fmt.Sprintf("\"1;1;%d;%d", b.Dx(), b.Dy())...)
+
+	for i, c := range e.m.Palette {
+		n := color.NRGBAModel.Convert(c).(color.NRGBA)
+		h = append(h, '#')
+		h = strconv.AppendInt(h, int64(i), 10)
+		h = append(h, ";2;"...)
+		h = strconv.AppendInt(h, percent(n.R), 10)
+		h = append(h, ';')
+		h = strconv.AppendInt(h, percent(n.G), 10)
+		h = append(h, ';')
+		h = strconv.AppendInt(h, percent(n.B), 10)
+	}
+
+	_, e.err = e.w.Write(h)
+}
+
+// percent returns the 8 bit value v as a rounded percentage.
+func percent(v uint8) int64 {
+	return (int64(v)*100 + 0x7f) / 0xff
+}
+
+// encode writes the bands of sixels. Every band holds one line of sixels
+// for each color used in the band, separated by "$", which returns to the
+// start of the band, and is terminated by "-", which moves to the next band.
+func (e *encoder) encode() {
+	if e.err != nil {
+		return
+	}
+
+	b := e.m.Bounds()
+	visible := make([]bool, len(e.m.Palette))
+	for i, c := range e.m.Palette {
+		_, _, _, a := c.RGBA()
+		visible[i] = a >= 0x8000
+	}
+
+	//sixels[c][x] holds the bits of color c in column x of the current band
+	sixels := make([][]byte, len(e.m.Palette))
+	used := make([]bool, len(e.m.Palette))
+	var line []byte
+
+	for y := b.Min.Y; y < b.Max.Y; y += bandHeight {
+		for c := range used {
+			used[c] = false
+		}
+
+		for dy := 0; dy < bandHeight && y+dy < b.Max.Y; dy++ {
+			i := e.m.PixOffset(b.Min.X, y+dy)
+			for x := 0; x < b.Dx(); x, i = x+1, i+1 {
+				c := e.m.Pix[i]
+				if int(c) >= len(visible) || !visible[c] {
+					continue
+				}
+				if !used[c] {
+					if sixels[c] == nil {
+						sixels[c] = make([]byte, b.Dx())
+					} else {
+						for j := range sixels[c] {
+							sixels[c][j] = 0
+						}
+					}
+					used[c] = true
+				}
+				sixels[c][x] |= 1 << dy
+			}
+		}
+
+		line = line[:0]
+		first := true
+		for c, ok := range used {
+			if !ok {
+				continue
+			}
+			if !first {
+				line = append(line, '$')
+			}
+			first = false
+
+			line = append(line, '#')
+			line = strconv.AppendInt(line, int64(c), 10)
+			line = appendSixels(line, sixels[c])
+		}
+		if y+bandHeight < b.Max.Y {
+			line = append(line, '-')
+		}
+
+		if _, e.err = e.w.Write(line); e.err != nil {
+			return
+		}
+	}
+}
+
+// appendSixels appends the sixel characters for bits, whose lowest bit is
+// the top pixel. Runs of more than three equal sixels are written as "!"
+// followed by the count and the character, and empty trailing sixels are
+// omitted.
+func appendSixels(b []byte, bits []byte) []byte {
+	n := len(bits)
+	for n > 0 && bits[n-1] == 0 {
+		n--
+	}
+
+	for i := 0; i < n; {
+		j := i + 1
+		for j < n && bits[j] == bits[i] {
+			j++
+		}
+
+		c := '?' + bits[i]
+		if run := j - i; run > 3 {
+			b = append(b, '!')
+			b = strconv.AppendInt(b, int64(run), 10)
+			b = append(b, c)
+		} else {
+			for ; run > 0; run-- {
+				b = append(b, c)
+			}
+		}
+		i = j
+	}
+
+	return b
+}
@@ -0,0 +1,243 @@
+package sixel
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/LukiDS/image/imgconv"
+)
+
+func TestEncode(t *testing.T) {
+	red := color.NRGBA{0xff, 0, 0, 0xff}
+	green := color.NRGBA{0, 0xff, 0, 0xff}
+	blue := color.NRGBA{0, 0, 0xff, 0xff}
+
+	//two bands: a red block with a blue column, then a green row with a transparent pixel
+	img := image.NewNRGBA(image.Rect(0, 0, 5, 7))
+	for y := 0; y < 7; y++ {
+		for x := 0; x < 5; x++ {
+			switch {
+			case y == 6 && x == 2:
+			case y == 6:
+				img.SetNRGBA(x, y, green)
+			case x == 4:
+				img.SetNRGBA(x, y, blue)
+			default:
+				img.SetNRGBA(x, y, red)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, img, Options{}); err != nil {
+		t.Fatalf("Encode: unexpected error: %v\n", err)
+	}
+
+	expected := "\x1bP0;1;0q\"1;1;5;7" +
+		"#0;2;100;0;0#1;2;0;0;100#2;2;0;100;0#3;2;0;0;0" +
+		"#0!4~$#1!4?~-" +
+		"#2@@?@@" +
+		"\x1b\\"
+	if buf.String() != expected {
+		format := fmt.Sprintf("\n") +
+			fmt.Sprintf("Encode()\n") +
+			fmt.Sprintf("Expected:\t %q\n", expected) +
+			fmt.Sprintf("Actual:\t %q\n", buf.String())
+		t.Errorf(format)
+	}
+}
+
+func TestAppendSixels(t *testing.T) {
+	tests := []struct {
+		bits     []byte
+		expected string
+	}{
+		{bits: []byte{}, expected: ""},
+		{bits: []byte{0, 0, 0}, expected: ""},
+		{bits: []byte{1, 2, 4, 8, 16, 32}, expected: "@ACGO_"},
+		{bits: []byte{63, 63, 63}, expected: "~~~"},
+		{bits: []byte{63, 63, 63, 63}, expected: "!4~"},
+		{bits: []byte{0, 0, 0, 0, 0, 1, 0, 0}, expected: "!5?@"},
+	}
+
+	for _, test := range tests {
+		if actual := string(appendSixels(nil, test.bits)); actual != test.expected {
+			t.Errorf("appendSixels(%v) = %q, expected %q\n", test.bits, actual, test.expected)
+		}
+	}
+}
+
+// TestEncodeStructure checks that the output of larger images is well-formed
+// and paints every visible pixel exactly once.
+func TestEncodeStructure(t *testing.T) {
+	tests := []struct {
+		name string
+		size image.Point
+		opts Options
+	}{
+		{name: "single band", size: image.Pt(40, 6), opts: Options{}},
+		{name: "partial last band", size: image.Pt(33, 20), opts: Options{MaxColors: 16}},
+		{name: "dithered", size: image.Pt(64, 31), opts: Options{MaxColors: 8, Dither: imgconv.DitherFloydSteinberg}},
+		{name: "two colors", size: image.Pt(10, 13), opts: Options{MaxColors: 2}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			img := image.NewNRGBA(image.Rectangle{Max: test.size})
+			for y := 0; y < test.size.Y; y++ {
+				for x := 0; x < test.size.X; x++ {
+					img.SetNRGBA(x, y, color.NRGBA{uint8(x * 7), uint8(y * 13), uint8(x * y), 0xff})
+				}
+			}
+
+			var buf bytes.Buffer
+			if err := Encode(&buf, img, test.opts); err != nil {
+				t.Fatalf("Encode: unexpected error: %v\n", err)
+			}
+
+			painted := parseSixels(t, buf.String(), test.size)
+			for i, n := range painted {
+				if n != 1 {
+					t.Fatalf("Encode(%s): pixel %d painted %d times, expected once\n", test.name, i, n)
+				}
+			}
+		})
+	}
+}
+
+// parseSixels checks the control sequences, raster attributes and color
+// registers of s and returns how often every pixel is painted.
+func parseSixels(t testing.TB, s string, size image.Point) []int {
+	t.Helper()
+
+	if !strings.HasPrefix(s, dcs) || !strings.HasSuffix(s, st) {
+		t.Fatalf("sixel data not enclosed in DCS and ST: %q\n", s)
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, dcs), st)
+
+	raster := fmt.Sprintf("\"1;1;%d;%d", size.X, size.Y)
+	if !strings.HasPrefix(s, raster) {
+		t.Fatalf("missing raster attributes %q: %q\n", raster, s)
+	}
+	s = strings.TrimPrefix(s, raster)
+
+	//number reads the decimal number at the start of s
+	number := func() int {
+		i := 0
+		for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+			i++
+		}
+		v, err := strconv.Atoi(s[:i])
+		if err != nil {
+			t.Fatalf("missing number: %q\n", s)
+		}
+		s = s[i:]
+		return v
+	}
+
+	registers := map[int]bool{}
+	painted := make([]int, size.X*size.Y)
+	band, x, current := 0, 0, -1
+	for len(s) > 0 {
+		c := s[0]
+		s = s[1:]
+
+		switch {
+		case c == '#':
+			r := number()
+			if strings.HasPrefix(s, ";2;") {
+				//color definition with three percentages, each after a ";"
+				s = s[len(";2"):]
+				for i := 0; i < 3; i++ {
+					s = s[1:]
+					if v := number(); v > 100 {
+						t.Fatalf("color register %d: percentage %d above 100\n", r, v)
+					}
+				}
+				if r >= maxColors {
+					t.Fatalf("color register %d out of range\n", r)
+				}
+				registers[r] = true
+				continue
+			}
+			if !registers[r] {
+				t.Fatalf("color register %d used before its definition\n", r)
+			}
+			current = r
+
+		case c == '$':
+			x = 0
+
+		case c == '-':
+			band, x = band+1, 0
+
+		case c == '!' || ('?' <= c && c <= '~'):
+			run := 1
+			if c == '!' {
+				run = number()
+				c, s = s[0], s[1:]
+			}
+			if current < 0 {
+				t.Fatalf("sixels without a selected color\n")
+			}
+			for ; run > 0; run, x = run-1, x+1 {
+				for dy := 0; dy < bandHeight; dy++ {
+					if (c-'?')&(1<<dy) == 0 {
+						continue
+					}
+					y := band*bandHeight + dy
+					if x >= size.X || y >= size.Y {
+						t.Fatalf("sixel at x=%d, y=%d outside the image\n", x, y)
+					}
+					painted[y*size.X+x]++
+				}
+			}
+
+		default:
+			t.Fatalf("unexpected character %q in sixel data\n", c)
+		}
+	}
+
+	if bands := (size.Y + bandHeight - 1) / bandHeight; band != bands-1 {
+		t.Fatalf("%d bands, expected %d\n", band+1, bands)
+	}
+
+	return painted
+}
+
+func TestEncodeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		img  image.Image
+		opts Options
+	}{
+		{name: "empty image", img: image.NewNRGBA(image.Rect(0, 0, 0, 1))},
+		{name: "negative palette size", img: image.NewNRGBA(image.Rect(0, 0, 1, 1)), opts: Options{MaxColors: -1}},
+		{name: "palette size above 256", img: image.NewNRGBA(image.Rect(0, 0, 1, 1)), opts: Options{MaxColors: 257}},
+	}
+
+	for _, test := range tests {
+		if err := Encode(&bytes.Buffer{}, test.img, test.opts); err == nil {
+			t.Errorf("Encode(%s): expected an error\n", test.name)
+		}
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	img := image.NewNRGBA(image.Rect(0, 0, 320, 240))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 13)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := Encode(&bytes.Buffer{}, img, Options{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
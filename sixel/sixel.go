@@ -0,0 +1,27 @@
+// Package sixel implements an encoder for DEC sixel graphics, which many
+// terminal emulators, e.g. xterm, mlterm and iTerm2, display inline.
+//
+// Images are quantized to at most 256 colors, which are defined as color
+// registers, and written in bands of six pixel rows. Runs of equal sixels
+// are compressed with the repeat introducer.
+package sixel
+
+const (
+	// sixelMaxPixels guards against writing huge images by accident.
+	sixelMaxPixels = 400_000_000
+
+	// bandHeight is the number of pixel rows in a band of sixels.
+	bandHeight = 6
+
+	// maxColors is the largest number of color registers used.
+	maxColors = 256
+)
+
+// control sequences
+const (
+	// dcs starts the sixel data: pixel aspect ratio 1:1 (0), pixels
+	// without a color keep the background (1) and the default grid size (0).
+	dcs = "\x1bP0;1;0q"
+	// st ends the sixel data.
+	st = "\x1b\\"
+)
@@ -0,0 +1,201 @@
+package termimg
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strconv"
+
+	"github.com/LukiDS/image/imgconv"
+)
+
+// Options are the encoding parameters.
+type Options struct {
+	// Width is the maximum number of columns. Wider images are scaled down
+	// with imgconv.ResizeBox, preserving their aspect ratio; narrower images
+	// are not scaled up. 0 means no limit.
+	Width int
+
+	// Colors256 maps the colors to the nearest color of the 256 color
+	// palette of xterm instead of writing 24 bit colors. The 16 system colors
+	// are not used, since terminals define them differently.
+	Colors256 bool
+
+	// Background is the color transparent pixels are composited over.
+	// Nil means black.
+	Background color.Color
+}
+
+type encoder struct {
+	w    *bufio.Writer
+	m    *image.NRGBA
+	opts Options
+	err  error
+}
+
+// Encode writes the image m to w as lines of upper half block characters
+// with ANSI color escape sequences, two pixel rows per line. Every line ends
+// with a sequence resetting the colors and a newline. If the height is odd,
+// the lower half of the last line keeps the default background color of the
+// terminal.
+func Encode(w io.Writer, m image.Image, opts Options) error {
+	width := m.Bounds().Dx()
+	height := m.Bounds().Dy()
+	if width <= 0 || height <= 0 || width > termMaxPixels/height {
+		return fmt.Errorf("invalid image size: %dx%d, must be non-empty and at most %d pixels", width, height, termMaxPixels)
+	}
+
+	if opts.Width < 0 {
+		return fmt.Errorf("invalid width %d, must not be negative", opts.Width)
+	}
+
+	bg := opts.Background
+	if bg == nil {
+		bg = color.Black
+	}
+
+	e := encoder{
+		w:    bufio.NewWriter(w),
+		opts: opts,
+	}
+
+	if opts.Width > 0 && width > opts.Width {
+		//round the scaled height to nearest, but keep at least one row
+		h := int((int64(height)*int64(opts.Width)*2 + int64(width)) / (2 * int64(width)))
+		if h < 1 {
+			h = 1
+		}
+
+		var err error
+		if m, err = imgconv.ResizeBox(m, opts.Width, h); err != nil {
+			return err
+		}
+	}
+	e.m = imgconv.Flatten(m, bg)
+
+	e.encode()
+
+	if e.err != nil {
+		return e.err
+	}
+
+	return e.w.Flush()
+}
+
+func (e *encoder) encode() {
+	b := e.m.Bounds()
+	var line []byte
+	for y := b.Min.Y; y < b.Max.Y; y += 2 {
+		line = line[:0]
+
+		//colors are only written when they change within a line
+		fg, bg := -1, -1
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if c := e.color(x, y); c != fg {
+				line = e.appendColor(line, "38", c)
+				fg = c
+			}
+			if y+1 < b.Max.Y {
+				if c := e.color(x, y+1); c != bg {
+					line = e.appendColor(line, "48", c)
+					bg = c
+				}
+			}
+			line = append(line, upperHalfBlock...)
+		}
+		line = append(line, reset+"\n"...)
+
+		if _, e.err = e.w.Write(line); e.err != nil {
+			return
+		}
+	}
+}
+
+// color returns the pixel at (x, y) as 24 bit RGB value, or as palette
+// index in 256 color mode.
+func (e *encoder) color(x, y int) int {
+	i := e.m.PixOffset(x, y)
+	s := e.m.Pix[i : i+3 : i+3]
+	if e.opts.Colors256 {
+		return nearest256(s[0], s[1], s[2])
+	}
+
+	return int(s[0])<<16 | int(s[1])<<8 | int(s[2])
+}
+
+// appendColor appends the escape sequence setting the foreground (38) or
+// background (48) color to c.
+func (e *encoder) appendColor(b []byte, layer string, c int) []byte {
+	b = append(b, "\x1b["...)
+	b = append(b, layer...)
+
+	if e.opts.Colors256 {
+		b = append(b, ";5;"...)
+		b = strconv.AppendInt(b, int64(c), 10)
+		return append(b, 'm')
+	}
+
+	b = append(b, ";2;"...)
+	b = strconv.AppendInt(b, int64(c>>16), 10)
+	b = append(b, ';')
+	b = strconv.AppendInt(b, int64(c>>8&0xff), 10)
+	b = append(b, ';')
+	b = strconv.AppendInt(b, int64(c&0xff), 10)
+	return append(b, 'm')
+}
+
+// cubeLevels are the channel values of the 6x6x6 color cube, which occupies
+// the palette indices 16 to 231. The indices 232 to 255 are 24 grays from
+// 8 to 238 in steps of 10.
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+// nearest256 returns the index of the cube or gray color of the 256 color
+// palette nearest to r, g, b by squared euclidean distance.
+func nearest256(r, g, b uint8) int {
+	ri, gi, bi := nearestLevel(r), nearestLevel(g), nearestLevel(b)
+	cube := 16 + 36*ri + 6*gi + bi
+	cubeDist := dist(r, g, b, cubeLevels[ri], cubeLevels[gi], cubeLevels[bi])
+
+	//the gray nearest to the average of the channels
+	avg := (int(r) + int(g) + int(b)) / 3
+	gi = (avg - 3) / 10
+	if gi < 0 {
+		gi = 0
+	}
+	if gi > 23 {
+		gi = 23
+	}
+	v := 8 + 10*gi
+	if grayDist := dist(r, g, b, v, v, v); grayDist < cubeDist {
+		return 232 + gi
+	}
+
+	return cube
+}
+
+// nearestLevel returns the index of the cube level nearest to v.
+func nearestLevel(v uint8) int {
+	best := 0
+	for i, l := range cubeLevels {
+		if abs(int(v)-l) < abs(int(v)-cubeLevels[best]) {
+			best = i
+		}
+	}
+
+	return best
+}
+
+func dist(r, g, b uint8, r2, g2, b2 int) int {
+	dr, dg, db := int(r)-r2, int(g)-g2, int(b)-b2
+	return dr*dr + dg*dg + db*db
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+
+	return v
+}
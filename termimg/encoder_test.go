@@ -0,0 +1,144 @@
+package termimg
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// testImage returns a 3x3 image: a red and a blue row, then a row with a
+// gray, a transparent and a white pixel.
+func testImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 3))
+	for x := 0; x < 3; x++ {
+		img.SetNRGBA(x, 0, color.NRGBA{0xff, 0, 0, 0xff})
+		img.SetNRGBA(x, 1, color.NRGBA{0, 0, 0xff, 0xff})
+	}
+	img.SetNRGBA(0, 2, color.NRGBA{0x80, 0x80, 0x80, 0xff})
+	img.SetNRGBA(2, 2, color.NRGBA{0xff, 0xff, 0xff, 0xff})
+
+	return img
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name     string
+		img      image.Image
+		opts     Options
+		expected string
+	}{
+		{
+			name: "24 bit colors with an odd height",
+			img:  testImage(),
+			opts: Options{},
+			expected: "\x1b[38;2;255;0;0m\x1b[48;2;0;0;255m▀▀▀\x1b[0m\n" +
+				"\x1b[38;2;128;128;128m▀\x1b[38;2;0;0;0m▀\x1b[38;2;255;255;255m▀\x1b[0m\n",
+		},
+		{
+			name: "256 colors",
+			img:  testImage(),
+			opts: Options{Colors256: true},
+			expected: "\x1b[38;5;196m\x1b[48;5;21m▀▀▀\x1b[0m\n" +
+				"\x1b[38;5;244m▀\x1b[38;5;16m▀\x1b[38;5;231m▀\x1b[0m\n",
+		},
+		{
+			name:     "background",
+			img:      testImage().SubImage(image.Rect(1, 2, 2, 3)),
+			opts:     Options{Background: color.NRGBA{1, 2, 3, 0xff}},
+			expected: "\x1b[38;2;1;2;3m▀\x1b[0m\n",
+		},
+		{
+			name:     "scaled to the width",
+			img:      testImage().SubImage(image.Rect(0, 0, 3, 2)),
+			opts:     Options{Width: 1},
+			expected: "\x1b[38;2;128;0;128m▀\x1b[0m\n",
+		},
+		{
+			name:     "narrower than the width",
+			img:      testImage().SubImage(image.Rect(0, 0, 1, 2)),
+			opts:     Options{Width: 80},
+			expected: "\x1b[38;2;255;0;0m\x1b[48;2;0;0;255m▀\x1b[0m\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Encode(&buf, test.img, test.opts); err != nil {
+				t.Fatalf("Encode: unexpected error: %v\n", err)
+			}
+
+			if buf.String() != test.expected {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Encode(%s)\n", test.name) +
+					fmt.Sprintf("Expected:\t %q\n", test.expected) +
+					fmt.Sprintf("Actual:\t %q\n", buf.String())
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+func TestEncodeSize(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 400, 301))
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, img, Options{Width: 100}); err != nil {
+		t.Fatalf("Encode: unexpected error: %v\n", err)
+	}
+
+	//301*100/400 = 75.25 rows round to 75, which need 38 lines
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 38 || strings.Count(lines[0], upperHalfBlock) != 100 {
+		t.Errorf("Encode(400x301, width 100) = %d lines of %d cells, expected 38 lines of 100 cells\n", len(lines), strings.Count(lines[0], upperHalfBlock))
+	}
+}
+
+func TestNearest256(t *testing.T) {
+	tests := []struct {
+		color    color.NRGBA
+		expected int
+	}{
+		{color: color.NRGBA{0, 0, 0, 0xff}, expected: 16},
+		{color: color.NRGBA{0xff, 0xff, 0xff, 0xff}, expected: 231},
+		{color: color.NRGBA{0xff, 0, 0, 0xff}, expected: 196},
+		{color: color.NRGBA{95, 135, 175, 0xff}, expected: 16 + 36*1 + 6*2 + 3},
+		{color: color.NRGBA{8, 8, 8, 0xff}, expected: 232},
+		{color: color.NRGBA{238, 238, 238, 0xff}, expected: 255},
+		{color: color.NRGBA{0x80, 0x80, 0x80, 0xff}, expected: 244},
+		{color: color.NRGBA{100, 102, 98, 0xff}, expected: 241},
+	}
+
+	for _, test := range tests {
+		if actual := nearest256(test.color.R, test.color.G, test.color.B); actual != test.expected {
+			t.Errorf("nearest256(%v) = %d, expected %d\n", test.color, actual, test.expected)
+		}
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	if err := Encode(&bytes.Buffer{}, image.NewNRGBA(image.Rect(0, 0, 0, 1)), Options{}); err == nil {
+		t.Errorf("Encode(empty image): expected an error\n")
+	}
+
+	if err := Encode(&bytes.Buffer{}, testImage(), Options{Width: -1}); err == nil {
+		t.Errorf("Encode(negative width): expected an error\n")
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	img := image.NewNRGBA(image.Rect(0, 0, 640, 480))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 13)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := Encode(&bytes.Buffer{}, img, Options{Width: 120}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
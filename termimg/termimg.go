@@ -0,0 +1,18 @@
+// Package termimg renders images as text for terminals without graphics
+// support, using ANSI colors and the upper half block character "▀".
+//
+// Every character cell shows two pixels: the upper one in the foreground
+// color and the lower one in the background color. Colors are written as
+// 24 bit colors, or mapped to the 256 color palette of xterm for terminals
+// without true color support.
+package termimg
+
+const (
+	// termMaxPixels guards against writing huge images by accident.
+	termMaxPixels = 400_000_000
+
+	upperHalfBlock = "▀"
+
+	// reset restores the default colors at the end of every line.
+	reset = "\x1b[0m"
+)
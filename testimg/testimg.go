@@ -0,0 +1,126 @@
+// Package testimg generates deterministic synthetic images for tests and
+// benchmarks. Every generator returns the same pixels for the same arguments,
+// including the seed of Noise, on every platform.
+//
+// All images are *image.NRGBA images with their origin at (0, 0). Like
+// image.NewNRGBA, the generators panic for sizes which overflow.
+package testimg
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+)
+
+// Gradient returns a w x h image whose red channel rises from 0 at the left to
+// 255 at the right edge, whose green channel rises from the top to the bottom
+// edge and whose blue channel rises along the diagonal. All pixels are opaque.
+func Gradient(w, h int) *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		p := m.Pix[m.PixOffset(0, y):m.PixOffset(w, y)]
+		for x, i := 0, 0; i < len(p); x, i = x+1, i+4 {
+			p[i+0] = ramp(x, w)
+			p[i+1] = ramp(y, h)
+			p[i+2] = ramp(x+y, w+h-1)
+			p[i+3] = 0xff
+		}
+	}
+
+	return m
+}
+
+// ramp returns v of [0, n) scaled to [0, 255].
+func ramp(v, n int) uint8 {
+	if n <= 1 {
+		return 0
+	}
+
+	return uint8(v * 0xff / (n - 1))
+}
+
+// Checkerboard returns a w x h image of opaque black and white squares of
+// cell x cell pixels, starting with a black square at the top left.
+// A cell size below 1 is treated as 1.
+func Checkerboard(w, h, cell int) *image.NRGBA {
+	if cell < 1 {
+		cell = 1
+	}
+
+	m := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		p := m.Pix[m.PixOffset(0, y):m.PixOffset(w, y)]
+		for x, i := 0, 0; i < len(p); x, i = x+1, i+4 {
+			v := uint8(0)
+			if (x/cell+y/cell)%2 == 1 {
+				v = 0xff
+			}
+			p[i+0], p[i+1], p[i+2], p[i+3] = v, v, v, 0xff
+		}
+	}
+
+	return m
+}
+
+// Noise returns a w x h image of opaque pixels with uniformly distributed
+// random colors, which no lossless encoder can compress well. The pixels are
+// determined by seed.
+func Noise(w, h int, seed int64) *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, w, h))
+
+	//a seeded math/rand source returns the same values in every Go release
+	r := rand.New(rand.NewSource(seed))
+	r.Read(m.Pix)
+	for i := 3; i < len(m.Pix); i += 4 {
+		m.Pix[i] = 0xff
+	}
+
+	return m
+}
+
+// runLength is the length of the spans of SolidRuns. It exceeds the 62 pixels
+// a single QOI run can hold and is no divisor of common widths, so the spans
+// also continue across rows.
+const runLength = 250
+
+// runColors are the colors of the spans of SolidRuns.
+var runColors = []color.NRGBA{
+	{0xff, 0xff, 0xff, 0xff},
+	{0x20, 0x40, 0x80, 0xff},
+	{0x20, 0x40, 0x80, 0x80},
+	{0x00, 0x00, 0x00, 0x00},
+	{0xe0, 0x30, 0x10, 0xff},
+	{0x10, 0xa0, 0x30, 0xff},
+	{0x00, 0x00, 0x00, 0xff},
+}
+
+// SolidRuns returns a w x h image made of spans of 250 pixels in row order,
+// each of a single color, cycling through a few opaque and translucent colors.
+// It is the best case of run-length encoding.
+func SolidRuns(w, h int) *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, w, h))
+
+	for i, n := 0, 0; i < len(m.Pix); i, n = i+4, n+1 {
+		c := runColors[(n/runLength)%len(runColors)]
+		m.Pix[i+0], m.Pix[i+1], m.Pix[i+2], m.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+
+	return m
+}
+
+// AlphaRamp returns a w x h image whose alpha channel rises from 0 at the left
+// to 255 at the right edge, over colors which change from row to row.
+func AlphaRamp(w, h int) *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		p := m.Pix[m.PixOffset(0, y):m.PixOffset(w, y)]
+		r, g, b := ramp(y, h), uint8(0x80), 0xff-ramp(y, h)
+		for x, i := 0, 0; i < len(p); x, i = x+1, i+4 {
+			p[i+0], p[i+1], p[i+2], p[i+3] = r, g, b, ramp(x, w)
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,160 @@
+package testimg
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestDeterminism(t *testing.T) {
+	tests := []struct {
+		name     string
+		generate func() *image.NRGBA
+	}{
+		{name: "Gradient", generate: func() *image.NRGBA { return Gradient(37, 23) }},
+		{name: "Checkerboard", generate: func() *image.NRGBA { return Checkerboard(37, 23, 4) }},
+		{name: "Noise", generate: func() *image.NRGBA { return Noise(37, 23, 42) }},
+		{name: "SolidRuns", generate: func() *image.NRGBA { return SolidRuns(37, 23) }},
+		{name: "AlphaRamp", generate: func() *image.NRGBA { return AlphaRamp(37, 23) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := tt.generate(), tt.generate()
+			if a.Rect != image.Rect(0, 0, 37, 23) {
+				t.Errorf("\nExpected bounds:\t %v\nActual bounds:\t %v\n", image.Rect(0, 0, 37, 23), a.Rect)
+			}
+			if !bytes.Equal(a.Pix, b.Pix) {
+				t.Errorf("\n%s returned different pixels for the same arguments\n", tt.name)
+			}
+		})
+	}
+}
+
+func TestNoiseSeed(t *testing.T) {
+	if bytes.Equal(Noise(16, 16, 1).Pix, Noise(16, 16, 2).Pix) {
+		t.Errorf("\nNoise returned the same pixels for different seeds\n")
+	}
+
+	//the pixels must not change between Go releases or platforms
+	expected := []uint8{0x52, 0xfd, 0xfc, 0xff, 0x21, 0x82, 0x65, 0xff}
+	if actual := Noise(16, 16, 1).Pix[:8]; !bytes.Equal(actual, expected) {
+		t.Errorf("\nExpected pixels:\t %#v\nActual pixels:\t %#v\n", expected, actual)
+	}
+}
+
+func TestNoiseStatistics(t *testing.T) {
+	m := Noise(256, 256, 7)
+	n := float64(len(m.Pix) / 4)
+
+	for c, name := range []string{"R", "G", "B"} {
+		var hist [256]float64
+		var sum, sumNeighbors float64
+		for i := c; i < len(m.Pix); i += 4 {
+			v := float64(m.Pix[i])
+			hist[m.Pix[i]]++
+			sum += v
+			if i+4 < len(m.Pix) {
+				sumNeighbors += (v - 127.5) * (float64(m.Pix[i+4]) - 127.5)
+			}
+		}
+
+		//the standard deviation of the mean of n uniform bytes is 73.9/sqrt(n)
+		if mean := sum / n; math.Abs(mean-127.5) > 2 {
+			t.Errorf("\nChannel %s:\t mean %v, expected about 127.5\n", name, mean)
+		}
+
+		//the chi-squared statistic of 255 degrees of freedom has a standard
+		//deviation of about 22.6
+		var chi2 float64
+		for _, count := range hist {
+			d := count - n/256
+			chi2 += d * d / (n / 256)
+		}
+		if chi2 > 255+5*22.6 {
+			t.Errorf("\nChannel %s:\t chi-squared %v of the histogram, not uniform\n", name, chi2)
+		}
+
+		//neighboring samples are uncorrelated
+		variance := (256*256 - 1) / 12.0
+		if corr := sumNeighbors / (n - 1) / variance; math.Abs(corr) > 0.02 {
+			t.Errorf("\nChannel %s:\t correlation %v of neighboring pixels\n", name, corr)
+		}
+	}
+
+	for i := 3; i < len(m.Pix); i += 4 {
+		if m.Pix[i] != 0xff {
+			t.Fatalf("\nNoise pixel %d is not opaque\n", i/4)
+		}
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	gradient := Gradient(11, 6)
+	checkerboard := Checkerboard(10, 10, 3)
+	alphaRamp := AlphaRamp(11, 6)
+
+	tests := []struct {
+		name     string
+		m        *image.NRGBA
+		x, y     int
+		expected color.NRGBA
+	}{
+		{name: "Gradient top left", m: gradient, x: 0, y: 0, expected: color.NRGBA{0, 0, 0, 0xff}},
+		{name: "Gradient center", m: gradient, x: 5, y: 3, expected: color.NRGBA{0x7f, 0x99, 0x88, 0xff}},
+		{name: "Gradient bottom right", m: gradient, x: 10, y: 5, expected: color.NRGBA{0xff, 0xff, 0xff, 0xff}},
+		{name: "Checkerboard first cell", m: checkerboard, x: 2, y: 2, expected: color.NRGBA{0, 0, 0, 0xff}},
+		{name: "Checkerboard second cell", m: checkerboard, x: 3, y: 2, expected: color.NRGBA{0xff, 0xff, 0xff, 0xff}},
+		{name: "Checkerboard diagonal cell", m: checkerboard, x: 3, y: 5, expected: color.NRGBA{0, 0, 0, 0xff}},
+		{name: "Checkerboard partial cell", m: checkerboard, x: 9, y: 0, expected: color.NRGBA{0xff, 0xff, 0xff, 0xff}},
+		{name: "AlphaRamp left", m: alphaRamp, x: 0, y: 0, expected: color.NRGBA{0, 0x80, 0xff, 0}},
+		{name: "AlphaRamp right", m: alphaRamp, x: 10, y: 5, expected: color.NRGBA{0xff, 0x80, 0, 0xff}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := tt.m.NRGBAAt(tt.x, tt.y); actual != tt.expected {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("%s at x=%d, y=%d\n", tt.name, tt.x, tt.y) +
+					fmt.Sprintf("Expected:\t %+v\n", tt.expected) +
+					fmt.Sprintf("Actual:\t %+v\n", actual)
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+func TestSolidRuns(t *testing.T) {
+	m := SolidRuns(97, 31)
+
+	//all spans are runLength pixels long, except the last one
+	var spans []int
+	n := 0
+	for i := 0; i < len(m.Pix); i += 4 {
+		if i > 0 && !bytes.Equal(m.Pix[i:i+4], m.Pix[i-4:i]) {
+			spans = append(spans, n)
+			n = 0
+		}
+		n++
+	}
+
+	for _, s := range spans {
+		if s != runLength {
+			t.Fatalf("\nExpected spans of %d pixels, got %v\n", runLength, spans)
+		}
+	}
+	if last := 97*31 - len(spans)*runLength; n != last {
+		t.Errorf("\nExpected a last span of %d pixels, got %d\n", last, n)
+	}
+}
+
+func TestSmallSizes(t *testing.T) {
+	for _, m := range []*image.NRGBA{Gradient(1, 1), Checkerboard(1, 1, 0), Noise(1, 1, 0), SolidRuns(1, 1), AlphaRamp(1, 1), Gradient(0, 5)} {
+		if len(m.Pix) != 4*m.Rect.Dx()*m.Rect.Dy() {
+			t.Errorf("\n%v image with %d bytes\n", m.Rect, len(m.Pix))
+		}
+	}
+}
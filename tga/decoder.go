@@ -0,0 +1,311 @@
+package tga
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+type tgaHeader struct {
+	idLength     int
+	colorMapType int
+	imageType    int
+	colorMapSize int //size of the color map in bytes
+	width        int
+	height       int
+	depth        int
+	alphaBits    int
+	rightToLeft  bool
+	topToBottom  bool
+}
+
+func (h tgaHeader) gray() bool {
+	return h.imageType == typeGray || h.imageType == typeRLEGray
+}
+
+func (h tgaHeader) rle() bool {
+	return h.imageType == typeRLETrueColor || h.imageType == typeRLEGray
+}
+
+func (h tgaHeader) colorModel() color.Model {
+	if h.gray() && h.depth == 8 {
+		return color.GrayModel
+	}
+
+	return color.NRGBAModel
+}
+
+type decoder struct {
+	m   image.Image
+	buf *bufio.Reader
+	h   tgaHeader
+	err error
+}
+
+func (d *decoder) decodeHeader() {
+	h := make([]byte, tgaHeaderSize)
+	if _, err := io.ReadFull(d.buf, h); err != nil {
+		d.err = fmt.Errorf("image not valid tga file")
+		return
+	}
+
+	d.h.idLength = int(h[0])
+	d.h.colorMapType = int(h[1])
+	d.h.imageType = int(h[2])
+	mapLength := int(binary.LittleEndian.Uint16(h[5:7]))
+	mapEntrySize := int(h[7])
+	d.h.width = int(binary.LittleEndian.Uint16(h[12:14]))
+	d.h.height = int(binary.LittleEndian.Uint16(h[14:16]))
+	d.h.depth = int(h[16])
+	d.h.alphaBits = int(h[17] & descriptorAlphaBits)
+	d.h.rightToLeft = h[17]&descriptorRightToLeft != 0
+	d.h.topToBottom = h[17]&descriptorTopToBottom != 0
+
+	switch d.h.imageType {
+	case typeTrueColor, typeGray, typeRLETrueColor, typeRLEGray:
+	default:
+		d.err = fmt.Errorf("unsupported tga image type %d", d.h.imageType)
+		return
+	}
+
+	//a color map may be present, but is unused by true-color and grayscale images
+	switch {
+	case d.h.colorMapType == 0:
+	case d.h.colorMapType == 1 && (mapEntrySize == 15 || mapEntrySize == 16 || mapEntrySize == 24 || mapEntrySize == 32):
+		d.h.colorMapSize = mapLength * ((mapEntrySize + 7) / 8)
+	default:
+		d.err = fmt.Errorf("invalid tga header: color map type %d with %d bit entries", d.h.colorMapType, mapEntrySize)
+		return
+	}
+
+	switch {
+	case d.h.gray() && (d.h.depth == 8 || d.h.depth == 16):
+	case !d.h.gray() && (d.h.depth == 16 || d.h.depth == 24 || d.h.depth == 32):
+	default:
+		d.err = fmt.Errorf("invalid tga header: %d bits per pixel for image type %d", d.h.depth, d.h.imageType)
+		return
+	}
+
+	if d.h.alphaBits > d.h.depth/2 || h[17]&descriptorInterleave != 0 {
+		d.err = fmt.Errorf("invalid tga header: image descriptor %#02x", h[17])
+		return
+	}
+
+	if d.h.width <= 0 || d.h.height <= 0 || d.h.width > tgaMaxPixels/d.h.height {
+		d.err = fmt.Errorf("invalid tga image size: %dx%d, must be non-empty and at most %d pixels", d.h.width, d.h.height, tgaMaxPixels)
+		return
+	}
+}
+
+func (d *decoder) decode() {
+	if d.err != nil {
+		return
+	}
+
+	//skip the image ID and the color map
+	if _, err := d.buf.Discard(d.h.idLength + d.h.colorMapSize); err != nil {
+		d.err = fmt.Errorf("truncated tga file: %w", io.ErrUnexpectedEOF)
+		return
+	}
+
+	size := (d.h.depth + 7) / 8
+	read := d.readRaw
+	if d.h.rle() {
+		rle := &rleReader{buf: d.buf, size: size, left: d.h.width * d.h.height}
+		read = rle.read
+	}
+
+	//the rows are converted as they are read, without staging the whole file
+	//raster, and the image is only allocated once the first row is present
+	row := make([]byte, size*d.h.width)
+	if err := read(row); err != nil {
+		d.err = err
+		return
+	}
+
+	r := image.Rect(0, 0, d.h.width, d.h.height)
+	if d.h.gray() && d.h.depth == 8 {
+		m := image.NewGray(r)
+		d.decodeRows(m.Pix, m.Stride, 1, row, size, read, func(dst, src []byte) {
+			dst[0] = src[0]
+		})
+		d.m = m
+		return
+	}
+
+	m := image.NewNRGBA(r)
+	d.decodeRows(m.Pix, m.Stride, 4, row, size, read, d.pixelFunc())
+	d.m = m
+}
+
+// readRaw reads the next row of uncompressed pixels into row.
+func (d *decoder) readRaw(row []byte) error {
+	if _, err := io.ReadFull(d.buf, row); err != nil {
+		return fmt.Errorf("truncated tga pixel data: %w", io.ErrUnexpectedEOF)
+	}
+
+	return nil
+}
+
+// rleReader decompresses run-length encoded pixels row by row. Every packet
+// starts with a byte holding the pixel count minus one in its low 7 bits; run
+// packets, marked by the high bit, repeat the following pixel, raw packets are
+// followed by their pixels. Packets may continue on the next row.
+type rleReader struct {
+	buf  *bufio.Reader
+	size int //size of a pixel in bytes
+	// left is the number of image pixels not covered by a packet yet.
+	left int
+	// n is the number of pixels of the current packet which are not read yet,
+	// and pixel the pixel repeated by a run packet.
+	n     int
+	run   bool
+	pixel [4]byte
+}
+
+// read reads the next row of pixels into row.
+func (r *rleReader) read(row []byte) error {
+	for i := 0; i < len(row); {
+		if r.n == 0 {
+			c, err := r.buf.ReadByte()
+			if err != nil {
+				return fmt.Errorf("truncated tga RLE data: %w", io.ErrUnexpectedEOF)
+			}
+
+			r.n, r.run = int(c&0x7f)+1, c&0x80 != 0
+			if r.n > r.left {
+				return fmt.Errorf("invalid tga RLE data: packet of %d pixels exceeds the image", r.n)
+			}
+			r.left -= r.n
+
+			if r.run {
+				if _, err := io.ReadFull(r.buf, r.pixel[:r.size]); err != nil {
+					return fmt.Errorf("truncated tga RLE data: %w", io.ErrUnexpectedEOF)
+				}
+			}
+		}
+
+		n := (len(row) - i) / r.size
+		if r.n < n {
+			n = r.n
+		}
+		r.n -= n
+
+		if !r.run {
+			if _, err := io.ReadFull(r.buf, row[i:i+n*r.size]); err != nil {
+				return fmt.Errorf("truncated tga RLE data: %w", io.ErrUnexpectedEOF)
+			}
+			i += n * r.size
+			continue
+		}
+
+		for end := i + n*r.size; i < end; i += r.size {
+			copy(row[i:i+r.size], r.pixel[:r.size])
+		}
+	}
+
+	return nil
+}
+
+// pixelFunc returns the conversion of a single pixel to NRGBA.
+func (d *decoder) pixelFunc() func(dst, src []byte) {
+	switch {
+	case d.h.gray():
+		return func(dst, src []byte) {
+			dst[0], dst[1], dst[2], dst[3] = src[0], src[0], src[0], src[1]
+		}
+
+	case d.h.depth == 16:
+		//little-endian ARRRRRGG GGGBBBBB
+		alpha := d.h.alphaBits == 1
+		return func(dst, src []byte) {
+			v := uint32(src[0]) | uint32(src[1])<<8
+			dst[0] = uint8(((v >> 10) & 0x1f) * 0xff / 0x1f)
+			dst[1] = uint8(((v >> 5) & 0x1f) * 0xff / 0x1f)
+			dst[2] = uint8((v & 0x1f) * 0xff / 0x1f)
+			dst[3] = 0xff
+			if alpha && v&0x8000 == 0 {
+				dst[3] = 0
+			}
+		}
+
+	case d.h.depth == 32 && d.h.alphaBits > 0:
+		return func(dst, src []byte) {
+			dst[0], dst[1], dst[2], dst[3] = src[2], src[1], src[0], src[3]
+		}
+
+	default:
+		return func(dst, src []byte) {
+			dst[0], dst[1], dst[2], dst[3] = src[2], src[1], src[0], 0xff
+		}
+	}
+}
+
+// decodeRows converts the rows of pixels in file order with f into the image
+// pixels dst, honoring the origin given by the image descriptor. The default
+// origin is the bottom left corner. row holds the first row, the following
+// ones are read with read.
+func (d *decoder) decodeRows(dst []byte, stride, dstSize int, row []byte, size int, read func(row []byte) error, f func(dst, src []byte)) {
+	for r := 0; r < d.h.height; r++ {
+		if r > 0 {
+			if err := read(row); err != nil {
+				d.err = err
+				return
+			}
+		}
+
+		y := d.h.height - 1 - r
+		if d.h.topToBottom {
+			y = r
+		}
+
+		for c, si := 0, 0; c < d.h.width; c, si = c+1, si+size {
+			x := c
+			if d.h.rightToLeft {
+				x = d.h.width - 1 - c
+			}
+
+			di := y*stride + x*dstSize
+			f(dst[di:di+dstSize:di+dstSize], row[si:si+size:si+size])
+		}
+	}
+}
+
+// DecodeConfig returns the color model and dimensions of a TGA image without
+// decoding the entire image.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	d := decoder{
+		buf: bufio.NewReader(r),
+	}
+
+	d.decodeHeader()
+	if d.err != nil {
+		return image.Config{}, d.err
+	}
+
+	return image.Config{
+		ColorModel: d.h.colorModel(),
+		Width:      d.h.width,
+		Height:     d.h.height,
+	}, nil
+}
+
+// Decode reads a TGA image from r and returns it as an *image.Gray image for
+// 8 bit grayscale images and as an *image.NRGBA image otherwise.
+func Decode(r io.Reader) (image.Image, error) {
+	d := decoder{
+		buf: bufio.NewReader(r),
+	}
+
+	d.decodeHeader()
+	d.decode()
+
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	return d.m, nil
+}
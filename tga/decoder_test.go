@@ -0,0 +1,278 @@
+package tga
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/LukiDS/image/imgtest"
+)
+
+// tgaFile describes a TGA file for generateTGA.
+type tgaFile struct {
+	id           string
+	colorMapType byte
+	imageType    byte
+	mapLength    uint16
+	mapEntrySize byte
+	width        uint16
+	height       uint16
+	depth        byte
+	descriptor   byte
+	colorMap     []byte
+	pixels       []byte
+}
+
+// generateTGA writes the header of f followed by its image ID, color map and pixels.
+func generateTGA(t testing.TB, f tgaFile) []byte {
+	t.Helper()
+
+	h := make([]byte, tgaHeaderSize)
+	h[0] = byte(len(f.id))
+	h[1] = f.colorMapType
+	h[2] = f.imageType
+	binary.LittleEndian.PutUint16(h[5:], f.mapLength)
+	h[7] = f.mapEntrySize
+	binary.LittleEndian.PutUint16(h[12:], f.width)
+	binary.LittleEndian.PutUint16(h[14:], f.height)
+	h[16] = f.depth
+	h[17] = f.descriptor
+
+	var buf bytes.Buffer
+	buf.Write(h)
+	buf.WriteString(f.id)
+	buf.Write(f.colorMap)
+	buf.Write(f.pixels)
+
+	return buf.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+	green := color.NRGBA{0, 255, 0, 255}
+	blue := color.NRGBA{0, 0, 255, 255}
+	gray := color.NRGBA{1, 2, 3, 255}
+
+	tests := []struct {
+		name     string
+		file     tgaFile
+		expected []color.NRGBA
+		size     image.Point
+	}{
+		{
+			name: "should decode a bottom-left 24 bit image",
+			file: tgaFile{imageType: typeTrueColor, width: 3, height: 2, depth: 24, pixels: []byte{
+				/* bottom row */ 0x03, 0x02, 0x01, 0x00, 0x00, 0xff, 0x00, 0xff, 0x00,
+				/* top row    */ 0xff, 0x00, 0x00, 0x00, 0x00, 0xff, 0x03, 0x02, 0x01,
+			}},
+			expected: []color.NRGBA{blue, red, gray, gray, red, green},
+			size:     image.Pt(3, 2),
+		},
+		{
+			name: "should decode a top-left 24 bit image",
+			file: tgaFile{imageType: typeTrueColor, width: 1, height: 2, depth: 24, descriptor: descriptorTopToBottom, pixels: []byte{
+				0x00, 0x00, 0xff,
+				0x00, 0xff, 0x00,
+			}},
+			expected: []color.NRGBA{red, green},
+			size:     image.Pt(1, 2),
+		},
+		{
+			name: "should decode a top-right 24 bit image",
+			file: tgaFile{imageType: typeTrueColor, width: 2, height: 1, depth: 24, descriptor: descriptorTopToBottom | descriptorRightToLeft, pixels: []byte{
+				0x00, 0x00, 0xff, 0x00, 0xff, 0x00,
+			}},
+			expected: []color.NRGBA{green, red},
+			size:     image.Pt(2, 1),
+		},
+		{
+			name: "should decode 32 bit BGRA with 8 alpha bits",
+			file: tgaFile{imageType: typeTrueColor, width: 2, height: 1, depth: 32, descriptor: 8, pixels: []byte{
+				0x00, 0x00, 0xff, 0x80, 0x03, 0x02, 0x01, 0x00,
+			}},
+			expected: []color.NRGBA{{255, 0, 0, 128}, {1, 2, 3, 0}},
+			size:     image.Pt(2, 1),
+		},
+		{
+			name: "should decode 32 bit BGRX without alpha bits as opaque",
+			file: tgaFile{imageType: typeTrueColor, width: 2, height: 1, depth: 32, pixels: []byte{
+				0x00, 0x00, 0xff, 0x00, 0x03, 0x02, 0x01, 0x00,
+			}},
+			expected: []color.NRGBA{red, gray},
+			size:     image.Pt(2, 1),
+		},
+		{
+			name: "should decode 16 bit ARGB1555",
+			file: tgaFile{imageType: typeTrueColor, width: 3, height: 1, depth: 16, descriptor: 1, pixels: []byte{
+				0x00, 0xfc, 0xe0, 0x03, 0x1f, 0x00,
+			}},
+			expected: []color.NRGBA{red, {0, 255, 0, 0}, {0, 0, 255, 0}},
+			size:     image.Pt(3, 1),
+		},
+		{
+			name: "should decode 16 bit RGB555 without alpha bits as opaque",
+			file: tgaFile{imageType: typeTrueColor, width: 2, height: 1, depth: 16, pixels: []byte{
+				0x1f, 0x00, 0x10, 0x42,
+			}},
+			expected: []color.NRGBA{blue, {131, 131, 131, 255}},
+			size:     image.Pt(2, 1),
+		},
+		{
+			name: "should decode 16 bit grayscale with alpha",
+			file: tgaFile{imageType: typeGray, width: 2, height: 1, depth: 16, descriptor: 8, pixels: []byte{
+				0x10, 0xff, 0x20, 0x40,
+			}},
+			expected: []color.NRGBA{{0x10, 0x10, 0x10, 0xff}, {0x20, 0x20, 0x20, 0x40}},
+			size:     image.Pt(2, 1),
+		},
+		{
+			name: "should skip the image ID and an unused color map",
+			file: tgaFile{id: "image id", colorMapType: 1, mapLength: 2, mapEntrySize: 24, colorMap: make([]byte, 6),
+				imageType: typeTrueColor, width: 1, height: 1, depth: 24, pixels: []byte{0x03, 0x02, 0x01},
+			},
+			expected: []color.NRGBA{gray},
+			size:     image.Pt(1, 1),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := generateTGA(t, test.file)
+			actual, err := Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("Decode: unexpected error: %v\n", err)
+			}
+
+			expected := image.NewNRGBA(image.Rectangle{Max: test.size})
+			for i, c := range test.expected {
+				expected.SetNRGBA(i%test.size.X, i/test.size.X, c)
+			}
+			imgtest.AssertEqual(t, expected, actual)
+			imgtest.AssertColorModel(t, expected.ColorModel(), actual)
+		})
+	}
+}
+
+func TestDecodeGray(t *testing.T) {
+	data := generateTGA(t, tgaFile{imageType: typeGray, width: 2, height: 2, depth: 8, pixels: []byte{
+		/* bottom row */ 0x30, 0x40,
+		/* top row    */ 0x10, 0x20,
+	}})
+
+	actual, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Decode: unexpected error: %v\n", err)
+	}
+
+	expected := &image.Gray{Pix: []byte{0x10, 0x20, 0x30, 0x40}, Stride: 2, Rect: image.Rect(0, 0, 2, 2)}
+	imgtest.AssertEqual(t, expected, actual)
+	imgtest.AssertColorModel(t, expected.ColorModel(), actual)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	pixels := []byte{0, 0, 0, 0}
+
+	tests := []struct {
+		name  string
+		data  []byte
+		error string
+	}{
+		{name: "empty file", data: nil, error: "not valid tga"},
+		{name: "truncated header", data: generateTGA(t, tgaFile{imageType: typeTrueColor, width: 1, height: 1, depth: 24})[:10], error: "not valid tga"},
+		{name: "color-mapped image", data: generateTGA(t, tgaFile{colorMapType: 1, mapLength: 1, mapEntrySize: 24, imageType: 1, width: 1, height: 1, depth: 8, pixels: pixels}), error: "image type 1"},
+		{name: "no image data", data: generateTGA(t, tgaFile{width: 1, height: 1, depth: 24, pixels: pixels}), error: "image type 0"},
+		{name: "unknown color map type", data: generateTGA(t, tgaFile{colorMapType: 2, imageType: typeTrueColor, width: 1, height: 1, depth: 24, pixels: pixels}), error: "color map type 2"},
+		{name: "invalid color map entry size", data: generateTGA(t, tgaFile{colorMapType: 1, mapEntrySize: 7, imageType: typeTrueColor, width: 1, height: 1, depth: 24, pixels: pixels}), error: "7 bit entries"},
+		{name: "8 bit true-color", data: generateTGA(t, tgaFile{imageType: typeTrueColor, width: 1, height: 1, depth: 8, pixels: pixels}), error: "8 bits per pixel"},
+		{name: "24 bit grayscale", data: generateTGA(t, tgaFile{imageType: typeRLEGray, width: 1, height: 1, depth: 24, pixels: pixels}), error: "24 bits per pixel"},
+		{name: "too many alpha bits", data: generateTGA(t, tgaFile{imageType: typeTrueColor, width: 1, height: 1, depth: 24, descriptor: 15, pixels: pixels}), error: "image descriptor"},
+		{name: "interleaved", data: generateTGA(t, tgaFile{imageType: typeTrueColor, width: 1, height: 1, depth: 24, descriptor: 0x40, pixels: pixels}), error: "image descriptor"},
+		{name: "zero width", data: generateTGA(t, tgaFile{imageType: typeTrueColor, width: 0, height: 1, depth: 24, pixels: pixels}), error: "image size"},
+		{name: "zero height", data: generateTGA(t, tgaFile{imageType: typeTrueColor, width: 1, height: 0, depth: 24, pixels: pixels}), error: "image size"},
+		{name: "truncated image ID", data: generateTGA(t, tgaFile{imageType: typeTrueColor, width: 1, height: 1, depth: 24})[:0x14], error: "truncated"},
+		{name: "truncated pixels", data: generateTGA(t, tgaFile{imageType: typeTrueColor, width: 2, height: 2, depth: 24, pixels: make([]byte, 11)}), error: "truncated"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Decode(bytes.NewReader(test.data))
+			if err == nil || !strings.Contains(err.Error(), test.error) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Decode(%s)\n", test.name) +
+					fmt.Sprintf("Expected error:\t containing %q\n", test.error) +
+					fmt.Sprintf("Actual error:\t %v\n", err)
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+func TestDecodeTruncatedLargeImage(t *testing.T) {
+	//a 65535x6103 image, close to the pixel limit, without pixels
+	data := generateTGA(t, tgaFile{imageType: typeTrueColor, width: 65535, height: 6103, depth: 32})
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := Decode(bytes.NewReader(data))
+	runtime.ReadMemStats(&after)
+
+	if err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Errorf("Decode(truncated large image): %v, expected a truncated error\n", err)
+	}
+
+	//the image takes 1.6GB, and is only allocated once its first row is read
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 64<<20 {
+		t.Errorf("Decode allocated %d bytes for a %d byte input\n", allocated, len(data))
+	}
+}
+
+func TestDecodeConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     tgaFile
+		expected image.Config
+	}{
+		{
+			name:     "true-color",
+			file:     tgaFile{imageType: typeRLETrueColor, width: 640, height: 480, depth: 32},
+			expected: image.Config{ColorModel: color.NRGBAModel, Width: 640, Height: 480},
+		},
+		{
+			name:     "grayscale",
+			file:     tgaFile{imageType: typeGray, width: 64, height: 48, depth: 8},
+			expected: image.Config{ColorModel: color.GrayModel, Width: 64, Height: 48},
+		},
+		{
+			name:     "grayscale with alpha",
+			file:     tgaFile{imageType: typeRLEGray, width: 64, height: 48, depth: 16, descriptor: 8},
+			expected: image.Config{ColorModel: color.NRGBAModel, Width: 64, Height: 48},
+		},
+	}
+
+	for _, test := range tests {
+		actual, err := DecodeConfig(bytes.NewReader(generateTGA(t, test.file)))
+		if err != nil || actual != test.expected {
+			t.Errorf("DecodeConfig(%s) = (%+v, %v), expected %+v\n", test.name, actual, err, test.expected)
+		}
+	}
+
+	data := generateTGA(t, tgaFile{id: "id", imageType: typeTrueColor, width: 1, height: 1, depth: 24, pixels: []byte{1, 2, 3}})
+	imgtest.RequireFormat(t, bytes.NewReader(data), "tga", image.Rect(0, 0, 1, 1))
+}
+
+func BenchmarkDecode(b *testing.B) {
+	data := generateTGA(b, tgaFile{imageType: typeTrueColor, width: 1024, height: 768, depth: 24, pixels: make([]byte, 3*1024*768)})
+
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Decode(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package tga
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/LukiDS/image/imgtest"
+)
+
+func TestDecodeWithTestFiles(t *testing.T) {
+	filenames, err := filepath.Glob("../testdata/tga/*.tga")
+	if err != nil {
+		t.Fatalf("could not find files: %v\n", err)
+	}
+
+	for _, name := range filenames {
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			tgaFile, err := os.Open(name)
+			if err != nil {
+				t.Fatalf("could not read file: %v\n", err)
+			}
+			defer tgaFile.Close()
+
+			img, err := Decode(tgaFile)
+			if err != nil {
+				t.Fatalf("could not decode file: %v\n", err)
+			}
+
+			pngFile, err := os.Open(strings.TrimSuffix(name, filepath.Ext(name)) + ".png")
+			if err != nil {
+				t.Fatalf("could not read file: %v\n", err)
+			}
+			defer pngFile.Close()
+
+			ref, err := png.Decode(pngFile)
+			if err != nil {
+				t.Fatalf("could not decode file: %v\n", err)
+			}
+
+			imgtest.AssertEqual(t, ref, img)
+		})
+	}
+}
+
+func TestDecodeRLE(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+	blue := color.NRGBA{0, 0, 255, 255}
+	gray := color.NRGBA{1, 2, 3, 255}
+
+	tests := []struct {
+		name     string
+		file     tgaFile
+		expected []color.NRGBA
+		error    string
+	}{
+		{
+			name: "should decode run and raw packets",
+			file: tgaFile{imageType: typeRLETrueColor, width: 4, height: 1, depth: 24, pixels: []byte{
+				0x81, 0x00, 0x00, 0xff, 0x01, 0xff, 0x00, 0x00, 0x03, 0x02, 0x01,
+			}},
+			expected: []color.NRGBA{red, red, blue, gray},
+		},
+		{
+			name: "should decode packets crossing rows",
+			file: tgaFile{imageType: typeRLETrueColor, width: 2, height: 2, depth: 24, descriptor: descriptorTopToBottom, pixels: []byte{
+				0x82, 0x00, 0x00, 0xff, 0x00, 0x03, 0x02, 0x01,
+			}},
+			expected: []color.NRGBA{red, red, red, gray},
+		},
+		{
+			name: "should decode 32 bit runs with alpha",
+			file: tgaFile{imageType: typeRLETrueColor, width: 2, height: 1, depth: 32, descriptor: 8, pixels: []byte{
+				0x81, 0x03, 0x02, 0x01, 0x40,
+			}},
+			expected: []color.NRGBA{{1, 2, 3, 0x40}, {1, 2, 3, 0x40}},
+		},
+		{
+			name: "should fail on a run exceeding the image",
+			file: tgaFile{imageType: typeRLETrueColor, width: 2, height: 1, depth: 24, pixels: []byte{
+				0x82, 0x00, 0x00, 0xff,
+			}},
+			error: "exceeds the image",
+		},
+		{
+			name: "should fail on a raw packet exceeding the image",
+			file: tgaFile{imageType: typeRLETrueColor, width: 1, height: 1, depth: 24, pixels: []byte{
+				0x01, 0x00, 0x00, 0xff, 0x00, 0x00, 0xff,
+			}},
+			error: "exceeds the image",
+		},
+		{
+			name: "should fail on a missing packet",
+			file: tgaFile{imageType: typeRLETrueColor, width: 2, height: 1, depth: 24, pixels: []byte{
+				0x80, 0x00, 0x00, 0xff,
+			}},
+			error: "truncated",
+		},
+		{
+			name: "should fail on a truncated raw packet",
+			file: tgaFile{imageType: typeRLETrueColor, width: 2, height: 1, depth: 24, pixels: []byte{
+				0x01, 0x00, 0x00, 0xff, 0x00,
+			}},
+			error: "truncated",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := Decode(bytes.NewReader(generateTGA(t, test.file)))
+			if test.error != "" {
+				if err == nil || !strings.Contains(err.Error(), test.error) {
+					format := fmt.Sprintf("\n") +
+						fmt.Sprintf("Decode(%s)\n", test.name) +
+						fmt.Sprintf("Expected error:\t containing %q\n", test.error) +
+						fmt.Sprintf("Actual error:\t %v\n", err)
+					t.Errorf(format)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode: unexpected error: %v\n", err)
+			}
+
+			w := int(test.file.width)
+			expected := image.NewNRGBA(image.Rect(0, 0, w, int(test.file.height)))
+			for i, c := range test.expected {
+				expected.SetNRGBA(i%w, i/w, c)
+			}
+			imgtest.AssertEqual(t, expected, actual)
+			imgtest.AssertColorModel(t, expected.ColorModel(), actual)
+		})
+	}
+}
+
+func TestDecodeCorruptFiles(t *testing.T) {
+	imgtest.DecodeCorrupt(t, "../testdata/tga/*.tga", 1000, 0, Decode)
+}
@@ -0,0 +1,51 @@
+// Package tga implements a decoder for Truevision TGA (Targa) images.
+//
+// Uncompressed and RLE compressed true-color images with 16, 24 or 32 bits
+// per pixel are decoded into *image.NRGBA images, grayscale images with
+// 8 bits per pixel into *image.Gray images and grayscale images with an
+// 8 bit alpha channel into *image.NRGBA images. Color-mapped images are
+// not supported.
+//
+// TGA files have no magic number at their start, only an optional footer
+// at their end, which cannot be used to sniff the format. The format is
+// registered with image.RegisterFormat for headers without a color map, with
+// a zeroed color map specification and one of the supported image types, so
+// image.Decode recognizes most TGA files, and the header is validated
+// thoroughly before decoding.
+package tga
+
+import (
+	"image"
+)
+
+const (
+	// tgaMaxPixels guards against allocating huge images for corrupt headers.
+	tgaMaxPixels = 400_000_000
+
+	tgaHeaderSize = 18 //size in bytes
+)
+
+// image types
+const (
+	typeTrueColor    = 2
+	typeGray         = 3
+	typeRLETrueColor = 10
+	typeRLEGray      = 11
+)
+
+// image descriptor bits
+const (
+	descriptorAlphaBits   = 0x0f
+	descriptorRightToLeft = 0x10
+	descriptorTopToBottom = 0x20
+	descriptorInterleave  = 0xc0
+)
+
+func init() {
+	//the magic matches any image ID length, no color map, the image type and
+	//a zeroed color map specification. CUR files start with 0, 0, 2, 0 as
+	//well, the cur package imports this one to register it first
+	for _, t := range []byte{typeTrueColor, typeGray, typeRLETrueColor, typeRLEGray} {
+		image.RegisterFormat("tga", string([]byte{'?', 0, t, 0, 0, 0, 0, 0}), Decode, DecodeConfig)
+	}
+}
@@ -0,0 +1,145 @@
+package wbmp
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+type wbmpHeader struct {
+	width  int
+	height int
+}
+
+type decoder struct {
+	m   *image.Gray
+	buf *bufio.Reader
+	h   wbmpHeader
+	err error
+}
+
+func (d *decoder) decodeHeader() {
+	typ := d.readUint("type")
+	fix := d.readByte("fixed header")
+	d.h.width = d.readUint("width")
+	d.h.height = d.readUint("height")
+	if d.err != nil {
+		return
+	}
+
+	if typ != 0 {
+		d.err = fmt.Errorf("unsupported wbmp type %d", typ)
+		return
+	}
+
+	//type 0 has no extension headers
+	if fix != 0 {
+		d.err = fmt.Errorf("invalid wbmp header: fixed header field %#02x", fix)
+		return
+	}
+
+	if d.h.width <= 0 || d.h.height <= 0 || d.h.width > wbmpMaxPixels/d.h.height {
+		d.err = fmt.Errorf("invalid wbmp image size: %dx%d, must be non-empty and at most %d pixels", d.h.width, d.h.height, wbmpMaxPixels)
+		return
+	}
+}
+
+// readByte reads a single byte header field.
+func (d *decoder) readByte(name string) byte {
+	if d.err != nil {
+		return 0
+	}
+
+	c, err := d.buf.ReadByte()
+	if err != nil {
+		d.err = fmt.Errorf("invalid wbmp header: missing %s", name)
+	}
+
+	return c
+}
+
+// readUint reads a multi-byte integer: 7 bits per byte, most significant
+// bits first, with the high bit set on every byte except the last.
+func (d *decoder) readUint(name string) int {
+	if d.err != nil {
+		return 0
+	}
+
+	v := 0
+	for i := 0; i < maxUintLength; i++ {
+		c, err := d.buf.ReadByte()
+		if err != nil {
+			d.err = fmt.Errorf("invalid wbmp header: missing %s", name)
+			return 0
+		}
+
+		v = v<<7 | int(c&0x7f)
+		if c&0x80 == 0 {
+			return v
+		}
+	}
+
+	d.err = fmt.Errorf("invalid wbmp header: %s longer than %d bytes", name, maxUintLength)
+	return 0
+}
+
+// decode reads the rows of the bitmap, which are packed with 8 pixels per
+// byte, most significant bit first, and padded to whole bytes. Set bits are white.
+func (d *decoder) decode() {
+	if d.err != nil {
+		return
+	}
+
+	d.m = image.NewGray(image.Rect(0, 0, d.h.width, d.h.height))
+	row := make([]byte, (d.h.width+7)/8)
+	for y := 0; y < d.h.height; y++ {
+		if _, err := io.ReadFull(d.buf, row); err != nil {
+			d.err = fmt.Errorf("truncated wbmp pixel data: %w", io.ErrUnexpectedEOF)
+			return
+		}
+
+		i := d.m.PixOffset(0, y)
+		for x := 0; x < d.h.width; x, i = x+1, i+1 {
+			if row[x/8]&(0x80>>(x%8)) != 0 {
+				d.m.Pix[i] = 0xff
+			}
+		}
+	}
+}
+
+// DecodeConfig returns the color model and dimensions of a WBMP image without
+// decoding the entire image.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	d := decoder{
+		buf: bufio.NewReader(r),
+	}
+
+	d.decodeHeader()
+	if d.err != nil {
+		return image.Config{}, d.err
+	}
+
+	return image.Config{
+		ColorModel: color.GrayModel,
+		Width:      d.h.width,
+		Height:     d.h.height,
+	}, nil
+}
+
+// Decode reads a WBMP image from r and returns it as an *image.Gray image.
+func Decode(r io.Reader) (image.Image, error) {
+	d := decoder{
+		buf: bufio.NewReader(r),
+	}
+
+	d.decodeHeader()
+	d.decode()
+
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	return d.m, nil
+}
@@ -0,0 +1,107 @@
+package wbmp
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/LukiDS/image/imgtest"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		expected *image.Gray
+	}{
+		{
+			name: "should decode padded rows",
+			data: []byte{0, 0, 10, 2, 0xa5, 0x40, 0x00, 0xff},
+			expected: &image.Gray{Stride: 10, Rect: image.Rect(0, 0, 10, 2), Pix: []byte{
+				0xff, 0, 0xff, 0, 0, 0xff, 0, 0xff, 0, 0xff,
+				0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff,
+			}},
+		},
+		{
+			name:     "should decode a width of 128 as two bytes",
+			data:     append([]byte{0, 0, 0x81, 0x00, 1, 0x80}, make([]byte, 15)...),
+			expected: &image.Gray{Stride: 128, Rect: image.Rect(0, 0, 128, 1), Pix: append([]byte{0xff}, make([]byte, 127)...)},
+		},
+		{
+			name:     "should decode a height of 16384 as three bytes",
+			data:     append([]byte{0, 0, 1, 0x81, 0x80, 0x00}, bytes.Repeat([]byte{0x80}, 16384)...),
+			expected: &image.Gray{Stride: 1, Rect: image.Rect(0, 0, 1, 16384), Pix: bytes.Repeat([]byte{0xff}, 16384)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := Decode(bytes.NewReader(test.data))
+			if err != nil {
+				t.Fatalf("Decode: unexpected error: %v\n", err)
+			}
+
+			imgtest.AssertEqual(t, test.expected, actual)
+
+			imgtest.AssertColorModel(t, test.expected.ColorModel(), actual)
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  []byte
+		error string
+	}{
+		{name: "empty file", data: nil, error: "missing type"},
+		{name: "missing height", data: []byte{0, 0, 1}, error: "missing height"},
+		{name: "unterminated width", data: []byte{0, 0, 0x81}, error: "missing width"},
+		{name: "type 1", data: []byte{1, 0, 1, 1, 0}, error: "type 1"},
+		{name: "extension headers", data: []byte{0, 0x80, 0, 1, 1, 0}, error: "fixed header"},
+		{name: "width too long", data: []byte{0, 0, 0x81, 0x80, 0x80, 0x80, 0x00, 1, 0}, error: "longer than 4 bytes"},
+		{name: "zero width", data: []byte{0, 0, 0, 1}, error: "image size"},
+		{name: "absurd size", data: []byte{0, 0, 0xff, 0xff, 0xff, 0x7f, 0xff, 0xff, 0xff, 0x7f}, error: "image size"},
+		{name: "truncated pixels", data: []byte{0, 0, 9, 2, 0, 0, 0}, error: "truncated"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Decode(bytes.NewReader(test.data))
+			if err == nil || !strings.Contains(err.Error(), test.error) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Decode(%s)\n", test.name) +
+					fmt.Sprintf("Expected error:\t containing %q\n", test.error) +
+					fmt.Sprintf("Actual error:\t %v\n", err)
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+func TestDecodeConfig(t *testing.T) {
+	actual, err := DecodeConfig(bytes.NewReader([]byte{0, 0, 0x85, 0x00, 0x83, 0x60}))
+	expected := image.Config{ColorModel: color.GrayModel, Width: 640, Height: 480}
+	if err != nil || actual != expected {
+		t.Errorf("DecodeConfig() = (%+v, %v), expected %+v\n", actual, err, expected)
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, image.NewGray(image.Rect(0, 0, 1024, 768))); err != nil {
+		b.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Decode(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
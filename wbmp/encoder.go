@@ -0,0 +1,93 @@
+package wbmp
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/LukiDS/image/imgconv"
+)
+
+type encoder struct {
+	w   *bufio.Writer
+	m   *image.Gray
+	err error
+}
+
+// Encode writes the image m to w as a WBMP image of type 0.
+//
+// m is converted with imgconv.ToBlackWhite: pixels with a luminance below 128
+// become black, all others white, so an *image.Gray image which is already
+// black and white is written unchanged.
+func Encode(w io.Writer, m image.Image) error {
+	width := m.Bounds().Dx()
+	height := m.Bounds().Dy()
+	if width <= 0 || height <= 0 || width > wbmpMaxPixels/height {
+		return fmt.Errorf("invalid image size: %dx%d, must be non-empty and at most %d pixels", width, height, wbmpMaxPixels)
+	}
+
+	e := encoder{
+		w: bufio.NewWriter(w),
+		m: imgconv.ToBlackWhite(m, threshold),
+	}
+
+	e.encodeHeader()
+	e.encode()
+
+	if e.err != nil {
+		return e.err
+	}
+
+	return e.w.Flush()
+}
+
+func (e *encoder) encodeHeader() {
+	b := e.m.Bounds()
+
+	//type 0 and the fixed header field without extension headers
+	h := []byte{0, 0}
+	h = appendUint(h, b.Dx())
+	h = appendUint(h, b.Dy())
+
+	_, e.err = e.w.Write(h)
+}
+
+// appendUint appends v as a multi-byte integer.
+func appendUint(b []byte, v int) []byte {
+	n := 1
+	for v>>(7*n) > 0 {
+		n++
+	}
+
+	for i := n - 1; i > 0; i-- {
+		b = append(b, byte(v>>(7*i))&0x7f|0x80)
+	}
+
+	return append(b, byte(v)&0x7f)
+}
+
+func (e *encoder) encode() {
+	if e.err != nil {
+		return
+	}
+
+	b := e.m.Bounds()
+	row := make([]byte, (b.Dx()+7)/8)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for i := range row {
+			row[i] = 0
+		}
+
+		i := e.m.PixOffset(b.Min.X, y)
+		for x := 0; x < b.Dx(); x, i = x+1, i+1 {
+			if e.m.Pix[i] != 0 {
+				row[x/8] |= 0x80 >> (x % 8)
+			}
+		}
+
+		if _, e.err = e.w.Write(row); e.err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package wbmp
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/LukiDS/image/imgtest"
+)
+
+func TestEncode(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(3, 4, 13, 6))
+	img.SetNRGBA(3, 4, color.NRGBA{0xff, 0xff, 0xff, 0xff})
+	img.SetNRGBA(5, 4, color.NRGBA{0x80, 0x80, 0x80, 0xff})
+	img.SetNRGBA(6, 4, color.NRGBA{0x7f, 0x7f, 0x7f, 0xff})
+	img.SetNRGBA(12, 5, color.NRGBA{0xff, 0xff, 0xff, 0xff})
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, img); err != nil {
+		t.Fatalf("Encode: unexpected error: %v\n", err)
+	}
+
+	expected := []byte{0, 0, 10, 2, 0xa0, 0x00, 0x00, 0x40}
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Errorf("Encode() = %#v, expected %#v\n", buf.Bytes(), expected)
+	}
+}
+
+func TestAppendUint(t *testing.T) {
+	tests := []struct {
+		v        int
+		expected []byte
+	}{
+		{v: 0, expected: []byte{0x00}},
+		{v: 127, expected: []byte{0x7f}},
+		{v: 128, expected: []byte{0x81, 0x00}},
+		{v: 300, expected: []byte{0x82, 0x2c}},
+		{v: 16383, expected: []byte{0xff, 0x7f}},
+		{v: 16384, expected: []byte{0x81, 0x80, 0x00}},
+	}
+
+	for _, test := range tests {
+		if actual := appendUint(nil, test.v); !bytes.Equal(actual, test.expected) {
+			t.Errorf("appendUint(%d) = %#v, expected %#v\n", test.v, actual, test.expected)
+		}
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		size image.Point
+	}{
+		{name: "narrow", size: image.Pt(7, 3)},
+		{name: "wider than 127 pixels", size: image.Pt(300, 2)},
+		{name: "taller than 127 pixels", size: image.Pt(2, 129)},
+		{name: "wider than 16383 pixels", size: image.Pt(16385, 1)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			img := image.NewGray(image.Rectangle{Max: test.size})
+			for i := range img.Pix {
+				if i%3 == 0 || i%7 == 0 {
+					img.Pix[i] = 0xff
+				}
+			}
+
+			var buf bytes.Buffer
+			if err := Encode(&buf, img); err != nil {
+				t.Fatalf("Encode: unexpected error: %v\n", err)
+			}
+
+			actual, err := Decode(&buf)
+			if err != nil {
+				t.Fatalf("Decode: unexpected error: %v\n", err)
+			}
+
+			imgtest.AssertEqual(t, img, actual)
+
+			imgtest.AssertColorModel(t, img.ColorModel(), actual)
+		})
+	}
+}
+
+func TestEncodeEmptyImage(t *testing.T) {
+	if err := Encode(&bytes.Buffer{}, image.NewGray(image.Rect(0, 0, 0, 3))); err == nil {
+		t.Errorf("Encode(empty image): expected an error\n")
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	img := image.NewGray(image.Rect(0, 0, 1024, 768))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := Encode(&bytes.Buffer{}, img); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
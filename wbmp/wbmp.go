@@ -0,0 +1,22 @@
+// Package wbmp implements a decoder and an encoder for Wireless Bitmap
+// (WBMP) images of type 0, the uncompressed monochrome bitmaps of WAP.
+//
+// Images are decoded into *image.Gray images with the values 0 for black
+// and 255 for white.
+//
+// WBMP files start with two zero bytes and have no magic number, which
+// would match many other formats, so the package does not register itself
+// with image.RegisterFormat. Use Decode and DecodeConfig directly.
+package wbmp
+
+const (
+	// wbmpMaxPixels guards against allocating huge images for corrupt headers.
+	wbmpMaxPixels = 400_000_000
+
+	// maxUintLength is the maximum number of bytes of a multi-byte integer,
+	// which allows values up to 2^28-1.
+	maxUintLength = 4
+
+	// threshold is the luminance from which pixels are white when encoding.
+	threshold = 0x80
+)
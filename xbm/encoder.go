@@ -0,0 +1,122 @@
+package xbm
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/LukiDS/image/imgconv"
+)
+
+type encoder struct {
+	w    *bufio.Writer
+	m    *image.Gray
+	name string
+	err  error
+}
+
+// Encode writes the image m to w as XBM C source code, using name as the
+// prefix of the identifiers name_width, name_height and name_bits. name must
+// be a valid C identifier.
+//
+// m is converted with imgconv.ToBlackWhite: pixels with a luminance below 128
+// become black, all others white, so an *image.Gray image which is already
+// black and white is written unchanged. The pixels of every row are packed
+// into bytes with the leftmost pixel in the least significant bit, and each
+// row is padded to a whole byte.
+func Encode(w io.Writer, m image.Image, name string) error {
+	width := m.Bounds().Dx()
+	height := m.Bounds().Dy()
+	if width <= 0 || height <= 0 || width > xbmMaxPixels/height {
+		return fmt.Errorf("invalid image size: %dx%d, must be non-empty and at most %d pixels", width, height, xbmMaxPixels)
+	}
+
+	if !validIdentifier(name) {
+		return fmt.Errorf("invalid xbm name %q, must be a C identifier", name)
+	}
+
+	e := encoder{
+		w:    bufio.NewWriter(w),
+		m:    imgconv.ToBlackWhite(m, threshold),
+		name: name,
+	}
+
+	e.encodeHeader()
+	e.encode()
+
+	if e.err != nil {
+		return e.err
+	}
+
+	return e.w.Flush()
+}
+
+func (e *encoder) encodeHeader() {
+	b := e.m.Bounds()
+	_, e.err = fmt.Fprintf(e.w, "#define %s_width %d\n#define %s_height %d\nstatic unsigned char %s_bits[] = {\n",
+		e.name, b.Dx(), e.name, b.Dy(), e.name)
+}
+
+func (e *encoder) encode() {
+	if e.err != nil {
+		return
+	}
+
+	b := e.m.Bounds()
+	total := (b.Dx() + 7) / 8 * b.Dy()
+	line := make([]byte, 0, 6*bytesPerLine+4)
+	n := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		i := e.m.PixOffset(b.Min.X, y)
+		for x := b.Min.X; x < b.Max.X; x += 8 {
+			var v byte
+			for bit := 0; bit < 8 && x+bit < b.Max.X; bit, i = bit+1, i+1 {
+				if e.m.Pix[i] == 0 {
+					v |= 1 << bit
+				}
+			}
+
+			if n%bytesPerLine == 0 {
+				line = append(line[:0], "  "...)
+			}
+			line = append(line, " 0x"...)
+			line = append(line, hexDigits[v>>4], hexDigits[v&0x0f])
+
+			n++
+			switch {
+			case n == total:
+				line = append(line, "};\n"...)
+			case n%bytesPerLine == 0:
+				line = append(line, ",\n"...)
+			default:
+				line = append(line, ',')
+				continue
+			}
+
+			if _, e.err = e.w.Write(line); e.err != nil {
+				return
+			}
+		}
+	}
+}
+
+const hexDigits = "0123456789abcdef"
+
+// validIdentifier reports whether name is a valid C identifier.
+func validIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for i, c := range name {
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
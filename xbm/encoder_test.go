@@ -0,0 +1,159 @@
+package xbm
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// glyph returns a black and white image from rows of '#' for black and '.' for white pixels.
+func glyph(rows ...string) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, len(rows[0]), len(rows)))
+	for y, row := range rows {
+		for x, c := range row {
+			if c == '.' {
+				img.SetGray(x, y, color.Gray{0xff})
+			}
+		}
+	}
+
+	return img
+}
+
+func TestEncodeGolden(t *testing.T) {
+	tests := []struct {
+		name string
+		img  image.Image
+	}{
+		{
+			name: "dot",
+			img:  glyph("#"),
+		},
+		{
+			name: "arrow",
+			img: glyph(
+				"..#..",
+				".###.",
+				"#.#.#",
+				"..#..",
+				"..#..",
+				"..#..",
+				"..#..",
+			),
+		},
+		{
+			name: "bell",
+			img: glyph(
+				"......##......",
+				".....####.....",
+				"....######....",
+				"...########...",
+				"...########...",
+				"...########...",
+				"..##########..",
+				".############.",
+				"##############",
+				"......##......",
+			),
+		},
+		{
+			name: "checker",
+			img: glyph(
+				"#.#.#.#.#.#.#.#.",
+				".#.#.#.#.#.#.#.#",
+				"#.#.#.#.#.#.#.#.",
+				".#.#.#.#.#.#.#.#",
+				"#.#.#.#.#.#.#.#.",
+				".#.#.#.#.#.#.#.#",
+				"#.#.#.#.#.#.#.#.",
+				".#.#.#.#.#.#.#.#",
+			),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Encode(&buf, test.img, test.name); err != nil {
+				t.Fatalf("Encode: unexpected error: %v\n", err)
+			}
+
+			golden, err := os.ReadFile(filepath.Join("../testdata/xbm", test.name+".xbm"))
+			if err != nil {
+				t.Fatalf("could not read file: %v\n", err)
+			}
+
+			if !bytes.Equal(buf.Bytes(), golden) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Encode(%s)\n", test.name) +
+					fmt.Sprintf("Expected:\n%s\n", golden) +
+					fmt.Sprintf("Actual:\n%s\n", buf.Bytes())
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+func TestEncodeThreshold(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(2, 3, 5, 4))
+	img.SetNRGBA(2, 3, color.NRGBA{0x7f, 0x7f, 0x7f, 0xff})
+	img.SetNRGBA(3, 3, color.NRGBA{0x80, 0x80, 0x80, 0xff})
+	img.SetNRGBA(4, 3, color.NRGBA{0xff, 0x00, 0x00, 0xff})
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, img, "t"); err != nil {
+		t.Fatalf("Encode: unexpected error: %v\n", err)
+	}
+
+	expected := "#define t_width 3\n#define t_height 1\nstatic unsigned char t_bits[] = {\n   0x05};\n"
+	if buf.String() != expected {
+		t.Errorf("Encode() = %q, expected %q\n", buf.String(), expected)
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		img   image.Image
+		ident string
+		error string
+	}{
+		{name: "empty image", img: image.NewGray(image.Rect(0, 0, 0, 1)), ident: "icon", error: "image size"},
+		{name: "empty name", img: glyph("#"), ident: "", error: "C identifier"},
+		{name: "leading digit", img: glyph("#"), ident: "1icon", error: "C identifier"},
+		{name: "dash", img: glyph("#"), ident: "my-icon", error: "C identifier"},
+		{name: "non-ASCII", img: glyph("#"), ident: "icön", error: "C identifier"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Encode(&bytes.Buffer{}, test.img, test.ident)
+			if err == nil || !strings.Contains(err.Error(), test.error) {
+				format := fmt.Sprintf("\n") +
+					fmt.Sprintf("Encode(%s)\n", test.name) +
+					fmt.Sprintf("Expected error:\t containing %q\n", test.error) +
+					fmt.Sprintf("Actual error:\t %v\n", err)
+				t.Errorf(format)
+			}
+		})
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	img := image.NewGray(image.Rect(0, 0, 1024, 768))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 7)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := Encode(&bytes.Buffer{}, img, "bench"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
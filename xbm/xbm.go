@@ -0,0 +1,19 @@
+// Package xbm implements an encoder for X BitMap (XBM) images.
+//
+// XBM images are monochrome bitmaps stored as C source code, which can be
+// included directly into C programs, e.g. as icons in firmware. The encoder
+// writes the X11 flavor of the format: two defines for the width and height
+// followed by a static array with the rows of the bitmap, where set bits are
+// black pixels.
+package xbm
+
+const (
+	// xbmMaxPixels guards against writing huge source files by accident.
+	xbmMaxPixels = 400_000_000
+
+	// threshold is the luminance below which pixels are black.
+	threshold = 0x80
+
+	// bytesPerLine is the number of array elements written per source line.
+	bytesPerLine = 12
+)